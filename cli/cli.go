@@ -4,6 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+
+	pri "ffvi_editor/models/pr"
 )
 
 // CLI represents the command-line interface
@@ -41,6 +44,28 @@ func (c *CLI) Run() error {
 		return c.backupCommand()
 	case "combat-pack":
 		return c.combatPackCommand()
+	case "watch":
+		return c.watchCommand()
+	case "marketplace":
+		return c.marketplaceCommand()
+	case "plugin":
+		return c.pluginCommand()
+	case "flags":
+		return c.flagsCommand()
+	case "treasure":
+		return c.treasureCommand()
+	case "travel":
+		return c.travelCommand()
+	case "rom":
+		return c.romCommand()
+	case "share":
+		return c.shareCommand()
+	case "query":
+		return c.queryCommand()
+	case "catalog":
+		return c.catalogCommand()
+	case "audit":
+		return c.auditCommand()
 	case "help", "-h", "--help":
 		return c.showHelp()
 	case "version", "-v", "--version":
@@ -179,6 +204,689 @@ func (c *CLI) backupCommand() error {
 	return c.handleBackupCommand(*file, *output)
 }
 
+// watchCommand watches a save directory for writes made by the game
+func (c *CLI) watchCommand() error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	dir := fs.String("dir", "", "Save directory to watch (required)")
+	backupDir := fs.String("backup-dir", "", "Snapshot directory (defaults to <dir>/backups)")
+	maxBackups := fs.Int("max-backups", 50, "Maximum number of snapshots to keep")
+	recipe := fs.String("recipe", "", "Recipe to re-apply: infinite-resources or a .lua script")
+	ps := fs.Bool("ps", false, "Watch PlayStation slot files (slotN.sav)")
+	pluginDir := fs.String("plugins", "", "Plugin directory to notify with save:open hooks")
+
+	if err := fs.Parse(c.args[1:]); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		return fmt.Errorf("--dir is required")
+	}
+
+	return c.handleWatchCommand(*dir, *backupDir, *maxBackups, *recipe, *ps, *pluginDir)
+}
+
+// marketplaceCommand dispatches marketplace mirror subcommands
+func (c *CLI) marketplaceCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("marketplace requires a subcommand: serve, export-mirror")
+	}
+
+	switch c.args[1] {
+	case "serve":
+		fs := flag.NewFlagSet("marketplace serve", flag.ExitOnError)
+		dir := fs.String("dir", "", "Mirror directory containing index.json (required)")
+		addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+		readOnly := fs.Bool("read-only", false, "Reject ratings, reviews and preset changes")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *dir == "" {
+			return fmt.Errorf("--dir is required")
+		}
+		return c.handleMarketplaceServeCommand(*dir, *addr, *readOnly)
+
+	case "export-mirror":
+		fs := flag.NewFlagSet("marketplace export-mirror", flag.ExitOnError)
+		pluginDir := fs.String("plugins", "plugins", "Installed plugin directory")
+		output := fs.String("output", "", "Mirror output directory (required)")
+		signKey := fs.String("sign-key", "", "PEM private key used to sign packages")
+		signer := fs.String("signer", "", "Signer ID recorded in package signatures (required with --sign-key)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *output == "" {
+			return fmt.Errorf("--output is required")
+		}
+		if *signKey != "" && *signer == "" {
+			return fmt.Errorf("--signer is required with --sign-key")
+		}
+		return c.handleMarketplaceExportMirrorCommand(*pluginDir, *output, *signKey, *signer)
+
+	default:
+		return fmt.Errorf("unknown marketplace subcommand: %s (valid: serve, export-mirror)", c.args[1])
+	}
+}
+
+// pluginCommand dispatches plugin management subcommands
+func (c *CLI) pluginCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("plugin requires a subcommand: install, upgrade, uninstall, config, test, new, lint, pack, profile")
+	}
+
+	switch c.args[1] {
+	case "install", "upgrade":
+		fs := flag.NewFlagSet("plugin "+c.args[1], flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		id := fs.String("id", "", "Plugin ID (required)")
+		version := fs.String("version", "", "Version or constraint (default: newest compatible)")
+		from := fs.String("from", "", "Marketplace or mirror URL (required)")
+		trustedKeys := fs.String("trusted-keys", "", "Directory of <signer>.pem keys to trust")
+		policy := fs.String("policy", "require", "Signature policy: require, quarantine, warn")
+		registry := fs.String("registry", "", "Registry directory to record installations in")
+		yes := fs.Bool("yes", false, "Apply the plan instead of only printing it")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		if *from == "" {
+			return fmt.Errorf("--from is required")
+		}
+		return c.handlePluginInstallCommand(*dir, *from, *id, *version, *trustedKeys, *policy, *registry, c.args[1] == "upgrade", *yes)
+
+	case "uninstall":
+		fs := flag.NewFlagSet("plugin uninstall", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		id := fs.String("id", "", "Plugin ID (required)")
+		registry := fs.String("registry", "", "Registry directory to record the removal in")
+		yes := fs.Bool("yes", false, "Apply the plan instead of only printing it")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		return c.handlePluginUninstallCommand(*dir, *id, *registry, *yes)
+
+	case "config":
+		fs := flag.NewFlagSet("plugin config", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		id := fs.String("id", "", "Plugin ID (required)")
+		set := fs.String("set", "", "Comma separated settings to change, e.g. difficulty=hard,autosave=true")
+		reset := fs.String("reset", "", "Comma separated settings to return to their defaults")
+		format := fs.String("format", "table", "Output format: table, json")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		return c.handlePluginConfigCommand(*dir, *id, splitList(*set), splitList(*reset), *format)
+
+	case "test":
+		fs := flag.NewFlagSet("plugin test", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		fixture := fs.String("fixture", "", "Save file loaded fresh for every test file and test()")
+		run := fs.String("run", "", "Only run tests whose name matches this regular expression")
+		timeout := fs.Duration("timeout", 0, "Time limit per test file (default 30s)")
+		junit := fs.String("junit", "", "Write a JUnit XML report to this file")
+		jsonOut := fs.String("json", "", "Write a JSON report to this file")
+		verbose := fs.Bool("v", false, "Also print each file's output")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		return c.handlePluginTestCommand(*dir, fs.Args(), *fixture, *run, *timeout, *junit, *jsonOut, *verbose)
+
+	case "new":
+		fs := flag.NewFlagSet("plugin new", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory to create the plugin in")
+		template := fs.String("template", "tool", "Template: hook, tool or database")
+		name := fs.String("name", "", "Display name (default: the ID in title case)")
+		author := fs.String("author", os.Getenv("USER"), "Author recorded in metadata.json")
+		description := fs.String("description", "", "Description (default: the template's)")
+
+		// The ID may come before the flags: plugin new my-plugin --template hook
+		args := c.args[2:]
+		var id string
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			id, args = args[0], args[1:]
+		}
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if id == "" {
+			id = fs.Arg(0)
+		}
+		if id == "" {
+			return fmt.Errorf("plugin new requires a plugin ID")
+		}
+		return c.handlePluginNewCommand(*dir, id, *template, *name, *author, *description)
+
+	case "lint":
+		fs := flag.NewFlagSet("plugin lint", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		format := fs.String("format", "table", "Output format: table, json")
+		strict := fs.Bool("strict", false, "Fail on warnings too")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		return c.handlePluginLintCommand(*dir, fs.Args(), *format, *strict)
+
+	case "pack":
+		fs := flag.NewFlagSet("plugin pack", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		output := fs.String("output", "", "Package file to write (default: <id>-<version>.zip)")
+		signKey := fs.String("sign-key", "", "PEM private key used to sign the package")
+		signer := fs.String("signer", "", "Signer ID recorded in the signature (required with --sign-key)")
+		skipLint := fs.Bool("skip-lint", false, "Pack even if plugin lint reports errors")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("plugin pack requires exactly one plugin ID or directory")
+		}
+		if *signKey != "" && *signer == "" {
+			return fmt.Errorf("--signer is required with --sign-key")
+		}
+		return c.handlePluginPackCommand(*dir, fs.Arg(0), *output, *signKey, *signer, *skipLint)
+
+	case "profile":
+		fs := flag.NewFlagSet("plugin profile", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory")
+		file := fs.String("file", "plugin.lua", "Lua file to run, relative to the plugin")
+		entry := fs.String("entry", "run", "Module function to call when the file returns a module")
+		fixture := fs.String("fixture", "", "Save file the plugin runs against")
+		interval := fs.Int64("interval", 1000, "Lua instructions between stack samples")
+		runs := fs.Int("runs", 1, "Number of times to run the plugin")
+		top := fs.Int("top", 20, "Number of functions to list (0 for all)")
+		collapsed := fs.String("collapsed", "", "Write collapsed stacks (flamegraph.pl format) to this file")
+		speedscope := fs.String("speedscope", "", "Write a speedscope JSON profile to this file")
+
+		// The ID may come before the flags: plugin profile my-plugin --runs 10
+		args := c.args[2:]
+		var id string
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			id, args = args[0], args[1:]
+		}
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if id == "" {
+			id = fs.Arg(0)
+		}
+		if id == "" {
+			return fmt.Errorf("plugin profile requires a plugin ID or directory")
+		}
+		return c.handlePluginProfileCommand(*dir, id, *file, *entry, *fixture, *interval, *runs, *top, *collapsed, *speedscope)
+
+	default:
+		return fmt.Errorf("unknown plugin subcommand: %s (valid: install, upgrade, uninstall, config, test, new, lint, pack, profile)", c.args[1])
+	}
+}
+
+// flagsCommand dispatches story/event flag subcommands
+func (c *CLI) flagsCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("flags requires a subcommand: list, set, diff, checkpoints")
+	}
+
+	switch c.args[1] {
+	case "list":
+		fs := flag.NewFlagSet("flags list", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		catalog := fs.String("catalog", "", "JSON flag catalogue replacing the built-in one")
+		all := fs.Bool("all", false, "Also list non-zero slots no catalogued flag covers")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		return c.handleFlagsListCommand(*file, *catalog, *all)
+
+	case "set":
+		fs := flag.NewFlagSet("flags set", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		catalog := fs.String("catalog", "", "JSON flag catalogue replacing the built-in one")
+		checkpoint := fs.String("checkpoint", "", "Story checkpoint to apply first")
+		set := fs.String("set", "", "Comma separated flag IDs to set")
+		clear := fs.String("clear", "", "Comma separated flag IDs to clear")
+		slots := fs.String("slot", "", "Comma separated raw slot writes, e.g. scenario[12]=1")
+		output := fs.String("output", "", "Output path (defaults to overwriting --file)")
+		force := fs.Bool("force", false, "Write experimental flags whose slots are not verified")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if *checkpoint == "" && *set == "" && *clear == "" && *slots == "" {
+			return fmt.Errorf("nothing to change: use --checkpoint, --set, --clear or --slot")
+		}
+		return c.handleFlagsSetCommand(*file, *catalog, *checkpoint, splitList(*set), splitList(*clear), splitList(*slots), *output, *force)
+
+	case "diff":
+		fs := flag.NewFlagSet("flags diff", flag.ExitOnError)
+		file := fs.String("file", "", "First save file (required)")
+		other := fs.String("other", "", "Save file to compare against (required)")
+		catalog := fs.String("catalog", "", "JSON flag catalogue replacing the built-in one")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" || *other == "" {
+			return fmt.Errorf("--file and --other are required")
+		}
+		return c.handleFlagsDiffCommand(*file, *other, *catalog)
+
+	case "checkpoints":
+		fs := flag.NewFlagSet("flags checkpoints", flag.ExitOnError)
+		catalog := fs.String("catalog", "", "JSON flag catalogue replacing the built-in one")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		return c.handleFlagsCheckpointsCommand(*catalog)
+
+	default:
+		return fmt.Errorf("unknown flags subcommand: %s (valid: list, set, diff, checkpoints)", c.args[1])
+	}
+}
+
+// treasureCommand dispatches treasure tracking subcommands
+func (c *CLI) treasureCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("treasure requires a subcommand: list, open, reset")
+	}
+
+	switch c.args[1] {
+	case "list", "open", "reset":
+	default:
+		return fmt.Errorf("unknown treasure subcommand: %s (valid: list, open, reset)", c.args[1])
+	}
+
+	fs := flag.NewFlagSet("treasure "+c.args[1], flag.ExitOnError)
+	file := fs.String("file", "", "Save file path (required)")
+	mapID := fs.Int("map", 0, "Only treasures on this map ID")
+	world := fs.String("world", "", "Only treasures in this world: balance or ruin")
+	missable := fs.Bool("missable", false, "Only missable treasures")
+	unopened := fs.Bool("unopened", false, "Only unopened treasures (list)")
+	ids := fs.String("id", "", "Comma separated treasure IDs (open, reset)")
+	force := fs.Bool("force", false, "Write provisional treasures whose entries are not verified (open, reset)")
+	output := fs.String("output", "", "Output path (defaults to overwriting --file)")
+
+	if err := fs.Parse(c.args[2:]); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("--file is required")
+	}
+	w, err := parseWorld(*world)
+	if err != nil {
+		return err
+	}
+	idList, err := parseIDList(*ids)
+	if err != nil {
+		return err
+	}
+	filter := pri.TreasureFilter{MapID: uint16(*mapID), World: w, Missable: *missable, Unopened: *unopened}
+
+	if c.args[1] == "list" {
+		return c.handleTreasureListCommand(*file, filter)
+	}
+	if len(idList) == 0 && *mapID == 0 && w == 0 && !*missable {
+		return fmt.Errorf("use --id, --map, --world or --missable to choose treasures")
+	}
+	return c.handleTreasureSetCommand(*file, filter, idList, c.args[1] == "open", *output, *force)
+}
+
+// travelCommand dispatches vehicle, countdown timer and Warp cache subcommands
+func (c *CLI) travelCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("travel requires a subcommand: show, vehicle, timer, teleport")
+	}
+
+	switch c.args[1] {
+	case "show":
+		fs := flag.NewFlagSet("travel show", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		return c.handleTravelShowCommand(*file)
+
+	case "vehicle":
+		fs := flag.NewFlagSet("travel vehicle", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		index := fs.Int("index", -1, "Vehicle index as shown by travel show (required)")
+		mapID := fs.Int("map", 0, "World map ID: 1 (Balance) or 2 (Ruin)")
+		x := fs.Float64("x", 0, "Position X")
+		y := fs.Float64("y", 0, "Position Y")
+		z := fs.Float64("z", 0, "Position Z")
+		direction := fs.Int("direction", 0, "Facing direction")
+		enable := fs.Bool("enable", false, "Enable the vehicle")
+		disable := fs.Bool("disable", false, "Remove the vehicle from the world map")
+		output := fs.String("output", "", "Output path (defaults to overwriting --file)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" || *index < 0 {
+			return fmt.Errorf("--file and --index are required")
+		}
+		if *enable && *disable {
+			return fmt.Errorf("--enable and --disable cannot be combined")
+		}
+		var edit vehicleEdit
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "map":
+				edit.MapID = mapID
+			case "x":
+				edit.X = x
+			case "y":
+				edit.Y = y
+			case "z":
+				edit.Z = z
+			case "direction":
+				edit.Direction = direction
+			case "enable":
+				edit.Enabled = enable
+			case "disable":
+				enabled := !*disable
+				edit.Enabled = &enabled
+			}
+		})
+		return c.handleTravelVehicleCommand(*file, *index, edit, *output)
+
+	case "timer":
+		fs := flag.NewFlagSet("travel timer", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		id := fs.Int("id", 0, "Countdown to start")
+		seconds := fs.Float64("seconds", 0, "Seconds left (defaults to the countdown's full length)")
+		stop := fs.Bool("stop", false, "Stop the running countdown")
+		force := fs.Bool("force", false, "Start countdowns whose IDs are not verified")
+		output := fs.String("output", "", "Output path (defaults to overwriting --file)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if !*stop && *id == 0 {
+			return fmt.Errorf("use --id to start a countdown or --stop to end it")
+		}
+		return c.handleTravelTimerCommand(*file, timerEdit{ID: *id, Seconds: *seconds, Stop: *stop}, *output, *force)
+
+	case "teleport":
+		fs := flag.NewFlagSet("travel teleport", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		mapID := fs.Int("map", 0, "Map ID Warp returns to")
+		pointIn := fs.Int("point-in", 0, "Entrance ID on that map")
+		x := fs.Float64("x", 0, "Position X")
+		y := fs.Float64("y", 0, "Position Y")
+		z := fs.Float64("z", 0, "Position Z")
+		clear := fs.Bool("clear", false, "Clear the return point")
+		output := fs.String("output", "", "Output path (defaults to overwriting --file)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if !*clear && *mapID == 0 {
+			return fmt.Errorf("use --map to set the return point or --clear to remove it")
+		}
+		edit := teleportEdit{MapID: *mapID, PointIn: *pointIn, X: *x, Y: *y, Z: *z, Clear: *clear}
+		return c.handleTravelTeleportCommand(*file, edit, *output)
+
+	default:
+		return fmt.Errorf("unknown travel subcommand: %s (valid: show, vehicle, timer, teleport)", c.args[1])
+	}
+}
+
+// romCommand dispatches ROM image subcommands
+func (c *CLI) romCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("rom requires a subcommand: info, assets, inject")
+	}
+
+	switch c.args[1] {
+	case "info":
+		fs := flag.NewFlagSet("rom info", flag.ExitOnError)
+		file := fs.String("file", "", "ROM image path, .sfc or .smc (required)")
+		known := fs.String("known", "", "JSON file of extra known dumps, e.g. translation patches")
+		asJSON := fs.Bool("json", false, "Print the identification as JSON")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		return c.handleROMInfoCommand(*file, *known, *asJSON)
+
+	case "assets":
+		fs := flag.NewFlagSet("rom assets", flag.ExitOnError)
+		file := fs.String("file", "", "ROM image path, .sfc or .smc (required)")
+		table := fs.String("table", "", "JSON asset table to check, verified or not")
+		write := fs.String("write-table", "", "Write the checked table for editing; draft entries get no checksums (known dumps only)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+		return c.handleROMAssetsCommand(*file, *table, *write)
+
+	case "inject":
+		fs := flag.NewFlagSet("rom inject", flag.ExitOnError)
+		file := fs.String("file", "", "ROM image path, .sfc or .smc (required)")
+		var opts romInjectOptions
+		fs.StringVar(&opts.Asset, "asset", "", "Asset ID as listed by rom assets, e.g. field/terra (required)")
+		fs.StringVar(&opts.PNG, "png", "", "Image with the sprite's frames side by side")
+		fs.StringVar(&opts.Data, "data", "", "Raw 4bpp tile or palette data to inject instead of --png")
+		fs.BoolVar(&opts.WithPalette, "with-palette", false, "Replace the sprite's palette with the image's colors")
+		fs.BoolVar(&opts.SharedPalette, "shared-palette", false, "Allow changing a palette other characters are drawn with too")
+		fs.StringVar(&opts.Table, "table", "", "JSON asset table, needed until a verified one is built in")
+		fs.StringVar(&opts.Output, "output", "", "Write the patched ROM here")
+		fs.StringVar(&opts.IPS, "ips", "", "Write an IPS patch here")
+		fs.StringVar(&opts.BPS, "bps", "", "Write a BPS patch here")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" || opts.Asset == "" {
+			return fmt.Errorf("--file and --asset are required")
+		}
+		if (opts.PNG == "") == (opts.Data == "") {
+			return fmt.Errorf("exactly one of --png or --data is required")
+		}
+		if opts.Output == "" && opts.IPS == "" && opts.BPS == "" {
+			return fmt.Errorf("at least one of --output, --ips or --bps is required")
+		}
+		return c.handleROMInjectCommand(*file, opts)
+
+	default:
+		return fmt.Errorf("unknown rom subcommand: %s (valid: info, assets, inject)", c.args[1])
+	}
+}
+
+// shareCommand dispatches build share-code subcommands
+func (c *CLI) shareCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("share requires a subcommand: encode, decode, apply")
+	}
+
+	switch c.args[1] {
+	case "encode":
+		fs := flag.NewFlagSet("share encode", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		charID := fs.Int("char", -1, "Character ID (required)")
+		encoding := fs.String("encoding", "base58", "Code alphabet: base58, base32 or qr")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" || *charID < 0 {
+			return fmt.Errorf("--file and --char are required")
+		}
+		return c.handleShareEncodeCommand(*file, *charID, *encoding)
+
+	case "decode":
+		fs := flag.NewFlagSet("share decode", flag.ExitOnError)
+		code := fs.String("code", "", "Share code (required)")
+		asJSON := fs.Bool("json", false, "Print the build as JSON")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *code == "" {
+			return fmt.Errorf("--code is required")
+		}
+		return c.handleShareDecodeCommand(*code, *asJSON)
+
+	case "apply":
+		fs := flag.NewFlagSet("share apply", flag.ExitOnError)
+		file := fs.String("file", "", "Save file path (required)")
+		charID := fs.Int("char", -1, "Character ID to receive the build (required)")
+		code := fs.String("code", "", "Share code (required)")
+		output := fs.String("output", "", "Output file path (defaults to input)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *file == "" || *charID < 0 || *code == "" {
+			return fmt.Errorf("--file, --char and --code are required")
+		}
+		return c.handleShareApplyCommand(*file, *charID, *code, *output)
+
+	default:
+		return fmt.Errorf("unknown share subcommand: %s (valid: encode, decode, apply)", c.args[1])
+	}
+}
+
+// queryCommand runs a structured query over one save or a directory of saves
+func (c *CLI) queryCommand() error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	file := fs.String("file", "", "Save file path")
+	dir := fs.String("dir", "", "Run the query over every save in this directory instead")
+	pattern := fs.String("pattern", "*", "File name pattern for --dir")
+	format := fs.String("format", "table", "Output format: table, json")
+
+	if err := fs.Parse(c.args[1:]); err != nil {
+		return err
+	}
+	if (*file == "") == (*dir == "") {
+		return fmt.Errorf("exactly one of --file or --dir is required")
+	}
+	text := strings.Join(fs.Args(), " ")
+	if text == "" {
+		return fmt.Errorf("a query is required, e.g. 'characters where level < 30'")
+	}
+	return c.handleQueryCommand(text, *file, *dir, *pattern, *format)
+}
+
+// catalogCommand dispatches cross-save catalogue subcommands
+func (c *CLI) catalogCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("catalog requires a subcommand: index, search, export")
+	}
+
+	switch c.args[1] {
+	case "index":
+		fs := flag.NewFlagSet("catalog index", flag.ExitOnError)
+		dir := fs.String("dir", "", "Saves directory (required)")
+		index := fs.String("index", "", "Index file (defaults to .ffvi-catalog.json in --dir)")
+		pattern := fs.String("pattern", "", "Only index files whose name matches this pattern")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *dir == "" {
+			return fmt.Errorf("--dir is required")
+		}
+		return c.handleCatalogIndexCommand(*dir, *index, *pattern)
+
+	case "search":
+		fs := flag.NewFlagSet("catalog search", flag.ExitOnError)
+		dir := fs.String("dir", "", "Saves directory whose index to search")
+		index := fs.String("index", "", "Index file to search")
+		format := fs.String("format", "table", "Output format: table, json, csv")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *dir == "" && *index == "" {
+			return fmt.Errorf("--dir or --index is required")
+		}
+		text := strings.Join(fs.Args(), " ")
+		if text == "" {
+			return fmt.Errorf(`a query is required, e.g. 'saves where alive("Shadow") and partyavg < 25'`)
+		}
+		return c.handleCatalogSearchCommand(*dir, *index, text, *format)
+
+	case "export":
+		fs := flag.NewFlagSet("catalog export", flag.ExitOnError)
+		dir := fs.String("dir", "", "Saves directory whose index to export")
+		index := fs.String("index", "", "Index file to export")
+		output := fs.String("output", "", "CSV file (defaults to stdout)")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		if *dir == "" && *index == "" {
+			return fmt.Errorf("--dir or --index is required")
+		}
+		return c.handleCatalogExportCommand(*dir, *index, strings.Join(fs.Args(), " "), *output)
+
+	default:
+		return fmt.Errorf("unknown catalog subcommand: %s (valid: index, search, export)", c.args[1])
+	}
+}
+
+// auditCommand dispatches plugin audit log subcommands
+func (c *CLI) auditCommand() error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("audit requires a subcommand: verify")
+	}
+
+	switch c.args[1] {
+	case "verify":
+		fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+		dir := fs.String("dir", "plugins", "Plugin directory whose audit log to verify")
+		file := fs.String("file", "", "Audit log file (defaults to .audit/audit.jsonl in --dir)")
+		trustedKeys := fs.String("trusted-keys", "", "Directory of <signer>.pem keys checkpoints must be signed with (required)")
+		strict := fs.Bool("strict", false, "Also fail when records after the last checkpoint are unsigned")
+
+		if err := fs.Parse(c.args[2:]); err != nil {
+			return err
+		}
+		return c.handleAuditVerifyCommand(*dir, *file, *trustedKeys, *strict)
+
+	default:
+		return fmt.Errorf("unknown audit subcommand: %s (valid: verify)", c.args[1])
+	}
+}
+
 // showHelp displays CLI help
 func (c *CLI) showHelp() error {
 	help := `
@@ -196,6 +904,17 @@ COMMANDS:
 	validate   Validate save file integrity
 	backup     Create a backup of a save file
 	combat-pack Run Combat Depth Pack helpers (Encounter/Boss/Companion/Smoke)
+	watch      Snapshot, validate and patch saves as the game writes them
+	marketplace Serve an offline plugin/preset mirror (serve, export-mirror)
+	plugin     Install, upgrade or uninstall plugins, change their settings, run their Lua tests, profile them, or create, lint and pack new ones
+	flags      List, set or diff story/event flags (list, set, diff, checkpoints)
+	treasure   Track, open or reset treasure chests per map (list, open, reset)
+	travel     Edit vehicles, countdown timers and the Warp return point
+	rom        Identify a ROM, verify sprite offsets, inject sprites (info, assets, inject)
+	share      Encode, decode and apply character build share codes
+	query      Query characters, items, espers and spells in one save or a directory
+	catalog    Index a saves directory, then search it or export it as CSV
+	audit      Verify the plugin audit log has not been edited, truncated or reordered
     help       Show this help message
     version    Show version information
 
@@ -218,6 +937,75 @@ EXAMPLES:
     # Validate save file
     ffvi_editor validate --file save.json --fix
 
+    # Watch the game's save directory and keep resources maxed
+    ffvi_editor watch --dir ~/saves --recipe infinite-resources
+
+    # Package installed plugins and host them as an offline catalogue
+    ffvi_editor marketplace export-mirror --plugins ./plugins --output ./mirror
+    ffvi_editor marketplace export-mirror --output ./mirror --sign-key key.pem --signer me
+    ffvi_editor marketplace serve --dir ./mirror --addr 0.0.0.0:8080
+
+    # Show, then apply, the plan for installing a plugin and its dependencies
+    ffvi_editor plugin install --id combat-depth-pack --from http://127.0.0.1:8080 --trusted-keys ./keys
+    ffvi_editor plugin install --id combat-depth-pack --from http://127.0.0.1:8080 --trusted-keys ./keys --yes
+    ffvi_editor plugin uninstall --id combat-depth-pack --yes
+
+    # Show a plugin's settings, then change one and reset another
+    ffvi_editor plugin config --id combat-depth-pack
+    ffvi_editor plugin config --id combat-depth-pack --set difficulty=hard --reset max-enemies
+
+    # Run every plugin's Lua tests against a fixture save, writing JUnit XML for CI
+    ffvi_editor plugin test --fixture save.json --junit results.xml
+    ffvi_editor plugin test --run Boss monster-database item-database
+
+    # Start a new plugin, check it, and build a signed package for the marketplace
+    ffvi_editor plugin new gil-helper --template tool --author me
+    ffvi_editor plugin lint gil-helper
+    ffvi_editor plugin pack gil-helper --sign-key key.pem --signer me
+
+    # Find where a plugin spends its time; open the file at https://www.speedscope.app
+    ffvi_editor plugin profile gil-helper --fixture save.json --runs 20 --speedscope gil.speedscope.json
+
+    # Inspect story flags, jump to a checkpoint, or compare two saves
+    ffvi_editor flags list --file save.json
+    ffvi_editor flags set --file save.json --checkpoint world-of-ruin --set shadow-waited-for --force
+    ffvi_editor flags diff --file before.json --other after.json
+
+    # Missable treasures still available, then open every World of Balance chest
+    ffvi_editor treasure list --file save.json --missable --unopened
+    ffvi_editor treasure open --file save.json --world balance --force
+    ffvi_editor treasure reset --file save.json --map 356 --force
+
+    # Park the Falcon in the World of Ruin and give the escape countdown 5 minutes
+    ffvi_editor travel vehicle --file save.json --index 4 --map 2 --x 120 --y 80 --enable
+    ffvi_editor travel timer --file save.json --id 2 --seconds 300 --force
+
+    # Identify a ROM image, write out the draft asset table and recheck it once
+    # its checksums are recorded
+    ffvi_editor rom info --file ff3us.smc
+    ffvi_editor rom assets --file ff3us.smc --write-table us-1.0.json
+    ffvi_editor rom assets --file ff3us.smc --table us-1.0.json
+
+    # Put an edited Terra into the ROM and distribute it as patches
+    ffvi_editor rom inject --file ff3us.sfc --table us-1.0.json --asset field/terra --png terra.png --ips terra.ips --bps terra.bps
+
+    # Share Terra's build, then give it to Celes in another save
+    ffvi_editor share encode --file save.json --char 1
+    ffvi_editor share decode --code FF6-B58-...
+    ffvi_editor share apply --file other.json --char 25 --code FF6-B58-...
+
+    # Who still needs Ultima, and which relics are running low, in every save
+    ffvi_editor query --file save.json 'characters where level < 30 and knows("Ultima")'
+    ffvi_editor query --dir ./saves --format json 'items where count < 5 and category = relic'
+
+    # Catalogue a saves directory and search it
+    ffvi_editor catalog index --dir ./saves
+    ffvi_editor catalog search --dir ./saves 'saves where alive("Shadow") and partyavg < 25'
+    ffvi_editor catalog export --dir ./saves --output saves.csv
+
+    # Prove a tournament run's plugin audit log is intact, signed by the organiser's key
+    ffvi_editor audit verify --dir ./plugins --trusted-keys ./organiser-keys --strict
+
 For more information, visit: https://github.com/username/ffvi-save-editor
 `
 	fmt.Println(help)
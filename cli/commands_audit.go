@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"ffvi_editor/plugins"
+)
+
+// handleAuditVerifyCommand checks the hash chain and signed checkpoints of
+// a plugin directory's audit log against the keys in trustedKeys, failing
+// when any record was edited, removed or moved, or, with strict, when the
+// last records are unsigned
+func (c *CLI) handleAuditVerifyCommand(dir, file, trustedKeys string, strict bool) error {
+	if file == "" {
+		file = plugins.AuditChainPath(dir)
+	}
+	if trustedKeys == "" {
+		// Keys found next to the log prove nothing: whoever edited it could
+		// have replaced them too
+		return fmt.Errorf("audit verify requires --trusted-keys, a directory of public keys kept apart from the plugin directory")
+	}
+
+	sm := plugins.NewSecurityManager()
+	n, err := sm.LoadTrustedKeys(trustedKeys)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: no trusted keys in %s; checkpoints cannot be checked\n", trustedKeys)
+	}
+
+	report, err := plugins.VerifyAuditChainFile(file, sm)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Audit log: %s\n", file)
+	fmt.Printf("  %d events, %d valid checkpoints", report.Events, report.Checkpoints)
+	if len(report.Signers) > 0 {
+		fmt.Printf(" signed by %s", strings.Join(report.Signers, ", "))
+	}
+	fmt.Println()
+	if report.Unsigned > 0 {
+		fmt.Printf("  %d events after record %d are not covered by a checkpoint\n", report.Unsigned, report.SignedThrough)
+	}
+
+	if !report.OK() {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LINE\tRECORD\tISSUE\tDETAILS")
+		for _, issue := range report.Issues {
+			record := "-"
+			if issue.Seq > 0 {
+				record = fmt.Sprintf("%d", issue.Seq)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", issue.Line, record, issue.Kind, issue.Message)
+		}
+		w.Flush()
+		return fmt.Errorf("%w: %d issues", plugins.ErrAuditChainBroken, len(report.Issues))
+	}
+	if strict && report.Unsigned > 0 {
+		return fmt.Errorf("%w: %d unsigned events", plugins.ErrAuditChainBroken, report.Unsigned)
+	}
+	fmt.Println("  chain intact")
+	return nil
+}
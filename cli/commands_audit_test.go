@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+// TestAuditCommandValidation tests subcommand validation
+func TestAuditCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"audit"},
+		{"audit", "show"},
+		{"audit", "verify", "--dir", t.TempDir()},
+		{"audit", "verify", "--dir", t.TempDir(), "--trusted-keys", t.TempDir()},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleAuditVerifyCommand tests an intact audit log passes and an
+// edited one fails
+func TestHandleAuditVerifyCommand(t *testing.T) {
+	dir, keyDir := t.TempDir(), t.TempDir()
+	trustedKeys := plugins.AuditTrustedKeysDir(keyDir)
+	m := plugins.NewManager(dir, nil)
+	chain, err := m.OpenAuditChain(keyDir)
+	if err != nil {
+		t.Fatalf("OpenAuditChain failed: %v", err)
+	}
+	m.GetAuditLogger().LogPluginLoad("finder")
+	m.GetAuditLogger().LogPermissionDenied("finder", "write_save", "ranked run")
+	if err := chain.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCLI([]string{})
+	if err := c.handleAuditVerifyCommand(dir, "", "", false); err == nil || !strings.Contains(err.Error(), "--trusted-keys") {
+		t.Errorf("verify without trusted keys = %v, want it to require --trusted-keys", err)
+	}
+	out, err := captureOutput(func() error {
+		return c.handleAuditVerifyCommand(dir, "", trustedKeys, true)
+	})
+	if err != nil {
+		t.Fatalf("verify failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "2 events, 1 valid checkpoints signed by editor") || !strings.Contains(out, "chain intact") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	path := plugins.AuditChainPath(dir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), `"status":"denied"`, `"status":"success"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = captureOutput(func() error {
+		return c.handleAuditVerifyCommand(dir, "", trustedKeys, false)
+	})
+	if !errors.Is(err, plugins.ErrAuditChainBroken) {
+		t.Fatalf("verify of an edited log = %v, want ErrAuditChainBroken", err)
+	}
+	if !strings.Contains(out, plugins.AuditIssueEdited) {
+		t.Errorf("edit not reported:\n%s", out)
+	}
+}
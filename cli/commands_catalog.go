@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"ffvi_editor/io/catalog"
+)
+
+// catalogIndexPath picks the index file: --index when given, otherwise the
+// default file inside --dir
+func catalogIndexPath(dir, index string) string {
+	if index != "" {
+		return index
+	}
+	return catalog.IndexPath(dir)
+}
+
+// handleCatalogIndexCommand brings a directory's catalogue up to date,
+// decoding only the saves whose hash changed
+func (c *CLI) handleCatalogIndexCommand(dir, index, pattern string) error {
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	path := catalogIndexPath(dir, index)
+	idx, err := catalog.Load(path)
+	if err != nil {
+		return err
+	}
+	load := func(path string) error {
+		_, err := c.LoadSaveFile(path)
+		return err
+	}
+	stats, err := idx.Update(dir, catalog.Options{Pattern: pattern, Load: load})
+	if err != nil {
+		return err
+	}
+	if err := idx.Save(path); err != nil {
+		return fmt.Errorf("failed to write catalogue: %w", err)
+	}
+	for _, e := range idx.Entries {
+		if e.Error != "" {
+			fmt.Fprintf(os.Stderr, "Could not decode %s: %s\n", e.Path, e.Error)
+		}
+	}
+	fmt.Printf("Indexed %d saves in %s (%s)\n", len(idx.Entries), path, stats)
+	return nil
+}
+
+// loadCatalog reads an existing catalogue, which must have been built with
+// catalog index first
+func loadCatalog(dir, index string) (*catalog.Index, error) {
+	path := catalogIndexPath(dir, index)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("no catalogue at %s, run catalog index first", path)
+	}
+	return catalog.Load(path)
+}
+
+// handleCatalogSearchCommand runs a query over a catalogue
+func (c *CLI) handleCatalogSearchCommand(dir, index, text, format string) error {
+	if format != "table" && format != "json" && format != "csv" {
+		return fmt.Errorf("unknown format %q (valid: table, json, csv)", format)
+	}
+	idx, err := loadCatalog(dir, index)
+	if err != nil {
+		return err
+	}
+	entries, unverified, err := idx.SearchUnverified(text)
+	if err != nil {
+		return err
+	}
+	warnUnverifiedFlags(unverified)
+
+	switch format {
+	case "csv":
+		return catalog.WriteCSV(os.Stdout, entries)
+	case "json":
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, f := range catalog.Fields {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, f)
+	}
+	fmt.Fprintln(w)
+	for _, e := range entries {
+		row := e.Row()
+		for i, f := range catalog.Fields {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, row[f])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Printf("\n%d of %d saves\n", len(entries), len(idx.Entries))
+	return nil
+}
+
+// warnUnverifiedFlags notes on stderr that a query's flag or reached calls
+// read story flags whose positions in the save are unverified
+func warnUnverifiedFlags(flags []string) {
+	if len(flags) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: the query reads unverified story flags (%s); matches may be wrong\n", strings.Join(flags, ", "))
+	}
+}
+
+// handleCatalogExportCommand writes a catalogue, or the saves matching
+// query, as CSV
+func (c *CLI) handleCatalogExportCommand(dir, index, query, output string) error {
+	idx, err := loadCatalog(dir, index)
+	if err != nil {
+		return err
+	}
+	entries := idx.Entries
+	if query != "" {
+		var unverified []string
+		if entries, unverified, err = idx.SearchUnverified(query); err != nil {
+			return err
+		}
+		warnUnverifiedFlags(unverified)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := catalog.WriteCSV(w, entries); err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Printf("Exported %d saves to %s\n", len(entries), output)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ffvi_editor/io/catalog"
+)
+
+// TestCatalogCommandValidation tests flag and subcommand validation
+func TestCatalogCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"catalog"},
+		{"catalog", "list"},
+		{"catalog", "index"},
+		{"catalog", "search", "saves"},
+		{"catalog", "search", "--dir", "."},
+		{"catalog", "export"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestCatalogIndexSearchExport tests a catalogue round trip: files that
+// fail to decode are recorded, and search and export read the index
+func TestCatalogIndexSearchExport(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "junk.sav"), []byte("not a save"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cli := NewCLI([]string{})
+
+	if err := cli.handleCatalogSearchCommand(dir, "", "saves", "table"); err == nil {
+		t.Error("searching before indexing should fail")
+	}
+	if err := cli.handleCatalogIndexCommand(dir, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := catalog.Load(catalog.IndexPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := idx.Entry("junk.sav"); !ok || e.Error == "" {
+		t.Errorf("junk.sav entry = %+v, want a decode error", e)
+	}
+
+	if err := cli.handleCatalogSearchCommand(dir, "", "saves where partyavg > 1", "json"); err != nil {
+		t.Error(err)
+	}
+	if err := cli.handleCatalogSearchCommand(dir, "", "saves", "xml"); err == nil {
+		t.Error("an unknown format should fail")
+	}
+	out := filepath.Join(t.TempDir(), "saves.csv")
+	if err := cli.handleCatalogExportCommand(dir, "", "", out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "save,party,partyavg") {
+		t.Errorf("unexpected CSV header: %q", data)
+	}
+}
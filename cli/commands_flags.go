@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	pri "ffvi_editor/models/pr"
+)
+
+// loadFlagCatalog replaces the built-in story flag catalogue when a path is given
+func loadFlagCatalog(path string) error {
+	if path == "" {
+		pri.ResetStoryFlagCatalog()
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read flag catalogue: %w", err)
+	}
+	return pri.LoadStoryFlagCatalog(data)
+}
+
+// handleFlagsListCommand prints the catalogued story flags of a save. With
+// all set, non-zero slots that no catalogued flag covers are printed too.
+func (c *CLI) handleFlagsListCommand(file, catalog string, all bool) error {
+	if err := loadFlagCatalog(catalog); err != nil {
+		return err
+	}
+	if _, err := c.LoadSaveFile(file); err != nil {
+		return err
+	}
+	d := pri.GetDataStorage()
+
+	if cp, ok := d.CurrentCheckpoint(); ok {
+		note := ""
+		if len(pri.StoryFlags.UnverifiedCheckpointFlags("")) > 0 {
+			note = " (experimental)"
+		}
+		fmt.Printf("Story checkpoint: %s (%s)%s\n\n", cp.Name, cp.ID, note)
+	}
+
+	covered := make(map[string]bool)
+	for _, f := range pri.StoryFlags.Flags {
+		covered[fmt.Sprintf("%s[%d]", f.Array, f.Index)] = true
+		set, err := f.IsSet(d)
+		state := "unset"
+		switch {
+		case err != nil:
+			state = "missing"
+		case set:
+			state = "set"
+		}
+		note := ""
+		if !f.Verified {
+			note = " (experimental)"
+		}
+		fmt.Printf("  %-28s %-8s %s[%d]  %s%s\n", f.ID, state, f.Array, f.Index, f.Name, note)
+	}
+
+	if all {
+		fmt.Println("\nOther non-zero slots:")
+		for _, name := range d.Names() {
+			for i, v := range d.Arrays[name] {
+				slot := fmt.Sprintf("%s[%d]", name, i)
+				if v != 0 && !covered[slot] {
+					fmt.Printf("  %-12s %d\n", slot, v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// handleFlagsSetCommand applies a checkpoint, then the named flag changes and
+// raw slot writes, and saves the result to output (or back to file).
+// Unverified flags are only written with force.
+func (c *CLI) handleFlagsSetCommand(file, catalog, checkpoint string, set, clear, slots []string, output string, force bool) error {
+	if err := loadFlagCatalog(catalog); err != nil {
+		return err
+	}
+	if unverified := pri.StoryFlags.UnverifiedWrites(checkpoint, append(append([]string(nil), set...), clear...)); len(unverified) > 0 {
+		if !force {
+			return fmt.Errorf("refusing to write experimental flags whose slots are unverified: %s (use --force to write them anyway)",
+				strings.Join(unverified, ", "))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: writing experimental flags whose slots are unverified: %s\n", strings.Join(unverified, ", "))
+	}
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	d := pri.GetDataStorage()
+
+	if checkpoint != "" {
+		if err := d.ApplyCheckpoint(checkpoint); err != nil {
+			return err
+		}
+	}
+	for _, id := range set {
+		if err := d.SetFlag(id, true); err != nil {
+			return err
+		}
+	}
+	for _, id := range clear {
+		if err := d.SetFlag(id, false); err != nil {
+			return err
+		}
+	}
+	for _, s := range slots {
+		array, index, value, err := parseSlotAssignment(s)
+		if err != nil {
+			return err
+		}
+		if err := d.Set(array, index, value); err != nil {
+			return err
+		}
+	}
+
+	if output == "" {
+		output = file
+	}
+	return c.SaveSaveFile(save, output)
+}
+
+// handleFlagsDiffCommand prints the flags and slots that differ between two saves
+func (c *CLI) handleFlagsDiffCommand(file, other, catalog string) error {
+	if err := loadFlagCatalog(catalog); err != nil {
+		return err
+	}
+	if _, err := c.LoadSaveFile(file); err != nil {
+		return err
+	}
+	before := pri.GetDataStorage().Clone()
+	if _, err := c.LoadSaveFile(other); err != nil {
+		return err
+	}
+	after := pri.GetDataStorage()
+
+	flagDiffs := before.DiffStoryFlags(after)
+	slotDiffs := before.DiffSlots(after)
+	if len(flagDiffs) == 0 && len(slotDiffs) == 0 {
+		fmt.Println("No flag differences")
+		return nil
+	}
+
+	for _, diff := range flagDiffs {
+		fmt.Printf("  %-28s %v -> %v  %s\n", diff.Flag.ID, diff.Old, diff.New, diff.Flag.Name)
+	}
+	if len(slotDiffs) > 0 {
+		fmt.Printf("\n%d slot(s) differ:\n", len(slotDiffs))
+		for _, diff := range slotDiffs {
+			fmt.Printf("  %s[%d]: %d -> %d\n", diff.Array, diff.Index, diff.OldValue, diff.NewValue)
+		}
+	}
+	return nil
+}
+
+// handleFlagsCheckpointsCommand lists the story checkpoints in order
+func (c *CLI) handleFlagsCheckpointsCommand(catalog string) error {
+	if err := loadFlagCatalog(catalog); err != nil {
+		return err
+	}
+	for _, cp := range pri.StoryFlags.Checkpoints {
+		fmt.Printf("  %-16s %s - %s\n", cp.ID, cp.Name, cp.Description)
+		if len(cp.Flags) > 0 {
+			fmt.Printf("  %-16s sets: %s\n", "", strings.Join(cp.Flags, ", "))
+		}
+	}
+	return nil
+}
+
+// parseSlotAssignment parses "array[index]=value"
+func parseSlotAssignment(s string) (array string, index, value int, err error) {
+	open := strings.Index(s, "[")
+	closing := strings.Index(s, "]=")
+	if open <= 0 || closing < open {
+		return "", 0, 0, fmt.Errorf("invalid slot %q (expected array[index]=value)", s)
+	}
+	if index, err = strconv.Atoi(s[open+1 : closing]); err != nil {
+		return "", 0, 0, fmt.Errorf("invalid slot index in %q", s)
+	}
+	if value, err = strconv.Atoi(s[closing+2:]); err != nil {
+		return "", 0, 0, fmt.Errorf("invalid slot value in %q", s)
+	}
+	return s[:open], index, value, nil
+}
+
+// splitList splits a comma separated flag value, ignoring empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
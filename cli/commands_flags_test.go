@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pri "ffvi_editor/models/pr"
+)
+
+// TestHandleFlagsSetCommand tests applying a checkpoint and reading it back
+func TestHandleFlagsSetCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	saveFile := createTestSaveFileForBatch(t, tmpDir, "save.json")
+	output := filepath.Join(tmpDir, "out.json")
+
+	cli := NewCLI([]string{})
+	// Experimental flags are refused before the save is even read
+	if err := cli.handleFlagsSetCommand(saveFile, "", "opera", nil, nil, nil, output, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("experimental flags should need --force, got %v", err)
+	}
+
+	if err := cli.handleFlagsListCommand(saveFile, "", true); err != nil {
+		t.Logf("handleFlagsListCommand error (expected in isolated test): %v", err)
+		return
+	}
+
+	// The minimal save only has a global array, so story flags cannot be set
+	if err := cli.handleFlagsSetCommand(saveFile, "", "opera", nil, nil, nil, output, true); err == nil {
+		t.Error("checkpoints should fail when the scenario array is missing")
+	}
+
+	// Raw slot writes name their slot explicitly and need no --force
+	if err := cli.handleFlagsSetCommand(saveFile, "", "", nil, nil, []string{"global[3]=5"}, output, false); err != nil {
+		t.Fatalf("handleFlagsSetCommand failed: %v", err)
+	}
+	if _, err := cli.LoadSaveFile(output); err != nil {
+		t.Fatalf("LoadSaveFile(output) failed: %v", err)
+	}
+	if v, _ := pri.GetDataStorage().Get(pri.DataStorageGlobal, 3); v != 5 {
+		t.Errorf("global[3] = %d, want 5", v)
+	}
+
+	if err := cli.handleFlagsDiffCommand(saveFile, output, ""); err != nil {
+		t.Errorf("handleFlagsDiffCommand failed: %v", err)
+	}
+}
+
+// TestHandleFlagsCatalog tests loading a replacement flag catalogue
+func TestHandleFlagsCatalog(t *testing.T) {
+	defer pri.ResetStoryFlagCatalog()
+	catalog := filepath.Join(t.TempDir(), "flags.json")
+	os.WriteFile(catalog, []byte(`{"flags":[{"id":"tent","name":"Tent","array":"global","index":2}],"checkpoints":[]}`), 0644)
+
+	cli := NewCLI([]string{})
+	if err := cli.handleFlagsCheckpointsCommand(catalog); err != nil {
+		t.Fatalf("handleFlagsCheckpointsCommand failed: %v", err)
+	}
+	if _, ok := pri.StoryFlags.Flag("tent"); !ok {
+		t.Error("catalogue flag not loaded")
+	}
+	if err := cli.handleFlagsCheckpointsCommand(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing catalogue should fail")
+	}
+}
+
+// TestParseSlotAssignment tests raw slot write parsing
+func TestParseSlotAssignment(t *testing.T) {
+	array, index, value, err := parseSlotAssignment("scenario[12]=3")
+	if err != nil || array != "scenario" || index != 12 || value != 3 {
+		t.Errorf("parseSlotAssignment = %s, %d, %d, %v", array, index, value, err)
+	}
+	for _, bad := range []string{"scenario=3", "[1]=2", "scenario[x]=1", "scenario[1]=x"} {
+		if _, _, _, err := parseSlotAssignment(bad); err == nil {
+			t.Errorf("parseSlotAssignment(%q) should fail", bad)
+		}
+	}
+}
+
+// TestFlagsCommandValidation tests subcommand and flag validation
+func TestFlagsCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"flags"},
+		{"flags", "bogus"},
+		{"flags", "list"},
+		{"flags", "set", "--file", "save.json"},
+		{"flags", "diff", "--file", "save.json"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
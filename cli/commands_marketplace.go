@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ffvi_editor/marketplace"
+	"ffvi_editor/plugins"
+)
+
+// handleMarketplaceServeCommand hosts a mirror directory until interrupted
+// The client works against it unchanged by setting its base URL to the
+// printed address
+func (c *CLI) handleMarketplaceServeCommand(dir, addr string, readOnly bool) error {
+	srv, err := marketplace.NewServer(dir)
+	if err != nil {
+		return err
+	}
+	srv.SetReadOnly(readOnly)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpSrv := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpSrv.Serve(listener)
+	}()
+
+	fmt.Printf("Serving marketplace mirror %s at http://%s", dir, listener.Addr())
+	if readOnly {
+		fmt.Print(" (read-only)")
+	}
+	fmt.Println("\nPress Ctrl+C to stop.")
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to stop server: %w", err)
+		}
+	}
+
+	fmt.Println("Marketplace server stopped")
+	return nil
+}
+
+// handleMarketplaceExportMirrorCommand packages installed plugins into a mirror directory,
+// signing each package when a key is given
+func (c *CLI) handleMarketplaceExportMirrorCommand(pluginDir, output, signKey, signer string) error {
+	opts := marketplace.MirrorOptions{}
+	if signKey != "" {
+		sm := plugins.NewSecurityManager()
+		if err := sm.LoadPrivateKey(signKey); err != nil {
+			return err
+		}
+		opts.Signer = sm
+		opts.SignerID = signer
+	}
+
+	index, err := marketplace.ExportMirrorWithOptions(pluginDir, output, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range index.Plugins {
+		fmt.Printf("  %-40s %s\n", p.ID, p.Version)
+	}
+	fmt.Printf("Exported %d plugins to %s\n", len(index.Plugins), output)
+	if signer != "" {
+		fmt.Printf("Packages signed as %s\n", signer)
+	}
+	return nil
+}
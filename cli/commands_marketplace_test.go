@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+// TestHandleMarketplaceExportMirrorCommand tests exporting installed plugins to a mirror
+func TestHandleMarketplaceExportMirrorCommand(t *testing.T) {
+	pluginDir := t.TempDir()
+	dir := filepath.Join(pluginDir, "sample")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create plugin dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(`{"id":"sample","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "mirror")
+	cli := NewCLI([]string{})
+	if err := cli.handleMarketplaceExportMirrorCommand(pluginDir, output, "", ""); err != nil {
+		t.Fatalf("handleMarketplaceExportMirrorCommand failed: %v", err)
+	}
+
+	for _, name := range []string{"index.json", filepath.Join("packages", "sample-1.0.0.zip")} {
+		if _, err := os.Stat(filepath.Join(output, name)); err != nil {
+			t.Errorf("Expected %s in mirror: %v", name, err)
+		}
+	}
+}
+
+// TestHandleMarketplaceExportMirrorCommandSigned tests signing packages during export
+func TestHandleMarketplaceExportMirrorCommandSigned(t *testing.T) {
+	pluginDir := t.TempDir()
+	dir := filepath.Join(pluginDir, "sample")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create plugin dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(`{"id":"sample","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	sm := plugins.NewSecurityManager()
+	if err := sm.GenerateKeyPair(); err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := sm.SavePrivateKey(keyPath); err != nil {
+		t.Fatalf("Failed to save key: %v", err)
+	}
+	pub, err := sm.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("Failed to export public key: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "mirror")
+	cli := NewCLI([]string{})
+	if err := cli.handleMarketplaceExportMirrorCommand(pluginDir, output, keyPath, "tester"); err != nil {
+		t.Fatalf("handleMarketplaceExportMirrorCommand failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(output, "packages", "sample-1.0.0.zip"))
+	if err != nil {
+		t.Fatalf("Package missing: %v", err)
+	}
+	pkg, err := plugins.ReadPackage(data)
+	if err != nil {
+		t.Fatalf("ReadPackage failed: %v", err)
+	}
+	verifier := plugins.NewSecurityManager()
+	if err := verifier.AddTrustedKey("tester", pub); err != nil {
+		t.Fatalf("AddTrustedKey failed: %v", err)
+	}
+	if err := verifier.VerifyPackage(pkg); err != nil {
+		t.Errorf("Exported package should verify: %v", err)
+	}
+}
+
+// TestHandleMarketplaceExportMirrorCommandMissingDir tests exporting from a missing plugin directory
+func TestHandleMarketplaceExportMirrorCommandMissingDir(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleMarketplaceExportMirrorCommand(filepath.Join(t.TempDir(), "missing"), t.TempDir(), "", ""); err == nil {
+		t.Error("handleMarketplaceExportMirrorCommand should fail for a missing plugin directory")
+	}
+}
+
+// TestHandleMarketplaceServeCommandNoIndex tests serving a directory that is not a mirror
+func TestHandleMarketplaceServeCommandNoIndex(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleMarketplaceServeCommand(t.TempDir(), "127.0.0.1:0", false); err == nil {
+		t.Error("handleMarketplaceServeCommand should fail without index.json")
+	}
+}
+
+// TestMarketplaceCommandUnknownSubcommand tests subcommand validation
+func TestMarketplaceCommandUnknownSubcommand(t *testing.T) {
+	cli := NewCLI([]string{"marketplace", "bogus"})
+	if err := cli.Run(); err == nil {
+		t.Error("marketplace with an unknown subcommand should fail")
+	}
+}
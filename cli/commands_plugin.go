@@ -0,0 +1,523 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/marketplace"
+	"ffvi_editor/plugins"
+	"ffvi_editor/scripting"
+)
+
+// newPluginManager creates a manager for the plugins installed in dir,
+// trusting every <signer>.pem key in trustedKeys
+func newPluginManager(dir, trustedKeys, policy string) (*plugins.Manager, error) {
+	signaturePolicy, err := plugins.ParseSignaturePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	m := plugins.NewManager(dir, nil)
+	m.SetSignaturePolicy(signaturePolicy)
+	if trustedKeys != "" {
+		if _, err := m.GetSecurityManager().LoadTrustedKeys(trustedKeys); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// handlePluginInstallCommand plans an install or upgrade from a marketplace
+// and applies it when apply is set. The plan is always printed first.
+func (c *CLI) handlePluginInstallCommand(dir, source, pluginID, version, trustedKeys, policy, registry string, upgrade, apply bool) error {
+	m, err := newPluginManager(dir, trustedKeys, policy)
+	if err != nil {
+		return err
+	}
+
+	if upgrade {
+		installed, err := m.InstalledPlugins()
+		if err != nil {
+			return err
+		}
+		if _, ok := installed[pluginID]; !ok {
+			return fmt.Errorf("%s is not installed", pluginID)
+		}
+	}
+
+	client := marketplace.NewClient(source, "")
+	if registry != "" {
+		if client, err = marketplace.NewClientWithRegistry(source, "", registry); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	plan, err := m.PlanInstall(ctx, client, pluginID, version)
+	if err != nil {
+		return err
+	}
+	return c.applyPluginPlan(ctx, m, client, plan, apply)
+}
+
+// handlePluginUninstallCommand removes an installed plugin that nothing depends on
+func (c *CLI) handlePluginUninstallCommand(dir, pluginID, registry string, apply bool) error {
+	m, err := newPluginManager(dir, "", "")
+	if err != nil {
+		return err
+	}
+
+	plan, err := m.PlanUninstall(pluginID)
+	if err != nil {
+		return err
+	}
+
+	var client *marketplace.Client
+	if registry != "" {
+		if client, err = marketplace.NewClientWithRegistry("", "", registry); err != nil {
+			return err
+		}
+	}
+	return c.applyPluginPlan(context.Background(), m, client, plan, apply)
+}
+
+// applyPluginPlan prints a plan and applies it if requested
+func (c *CLI) applyPluginPlan(ctx context.Context, m *plugins.Manager, client *marketplace.Client, plan *plugins.InstallPlan, apply bool) error {
+	fmt.Print(plan.String())
+	if plan.Empty() {
+		return nil
+	}
+	if !apply {
+		fmt.Println("Run again with --yes to apply this plan.")
+		return nil
+	}
+
+	var err error
+	if client != nil {
+		err = client.ApplyPlan(ctx, m, plan)
+	} else {
+		err = m.ApplyPlan(ctx, plan)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Applied %d change(s)\n", len(plan.Steps))
+	return nil
+}
+
+// pluginSetting is one row of plugin config output
+type pluginSetting struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"`
+	Value       interface{} `json:"value"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Options     []string    `json:"options,omitempty"`
+}
+
+// handlePluginConfigCommand shows an installed plugin's settings, after
+// applying any changes. Changes are checked against the plugin's schema and
+// nothing is saved unless all of them are valid.
+func (c *CLI) handlePluginConfigCommand(dir, pluginID string, set, reset []string, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (valid: table, json)", format)
+	}
+	m, err := newPluginManager(dir, "", "")
+	if err != nil {
+		return err
+	}
+	schema, values, err := m.PluginSettings(pluginID)
+	if err != nil {
+		return err
+	}
+
+	if len(set) > 0 || len(reset) > 0 {
+		if len(schema) == 0 {
+			return fmt.Errorf("%s declares no settings", pluginID)
+		}
+		for _, key := range reset {
+			if schema.Field(key) == nil {
+				return fmt.Errorf("%w: unknown setting %s", plugins.ErrInvalidSettings, key)
+			}
+			delete(values, key)
+		}
+		for _, assignment := range set {
+			key, text, ok := strings.Cut(assignment, "=")
+			if !ok {
+				return fmt.Errorf("invalid --set %q: expected key=value", assignment)
+			}
+			field := schema.Field(key)
+			if field == nil {
+				return fmt.Errorf("%w: unknown setting %s", plugins.ErrInvalidSettings, key)
+			}
+			v, err := field.Parse(text)
+			if err != nil {
+				return fmt.Errorf("%w: %v", plugins.ErrInvalidSettings, err)
+			}
+			values[key] = v
+		}
+		if values, err = m.UpdateSettings(pluginID, values); err != nil {
+			return err
+		}
+	}
+
+	rows := make([]pluginSetting, 0, len(values))
+	for _, f := range schema {
+		rows = append(rows, pluginSetting{
+			Key:         f.Key,
+			Type:        f.Type,
+			Value:       values[f.Key],
+			Default:     f.Default,
+			Description: f.Description,
+			Options:     f.Options,
+		})
+	}
+	if len(schema) == 0 {
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			rows = append(rows, pluginSetting{Key: k, Value: values[k]})
+		}
+	}
+
+	if format == "json" {
+		out, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	if len(rows) == 0 {
+		fmt.Printf("%s has no settings\n", pluginID)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tVALUE\tDEFAULT\tDESCRIPTION")
+	for _, r := range rows {
+		field := plugins.SettingField{Type: r.Type}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Key, r.Type, field.Format(r.Value), field.Format(r.Default), r.Description)
+	}
+	return w.Flush()
+}
+
+// handlePluginTestCommand runs the Lua tests of the given plugins, or of
+// every plugin in dir, and writes the requested reports. It fails when any
+// test does, so pipelines can gate on it.
+func (c *CLI) handlePluginTestCommand(dir string, pluginIDs []string, fixture, run string, timeout time.Duration, junit, jsonOut string, verbose bool) error {
+	files, err := scripting.DiscoverTests(dir, pluginIDs)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no Lua tests found in %s", dir)
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	opts := scripting.TestOptions{Root: filepath.Dir(abs), Limits: scripting.Limits{Timeout: timeout}}
+	if run != "" {
+		if opts.Run, err = regexp.Compile(run); err != nil {
+			return fmt.Errorf("invalid --run pattern: %w", err)
+		}
+	}
+	if fixture != "" {
+		// Fail early on a bad fixture rather than once per file
+		if _, err := c.LoadSaveFile(fixture); err != nil {
+			return err
+		}
+		opts.Fixture = func() (*pr.PR, error) { return c.LoadSaveFile(fixture) }
+	}
+
+	report := scripting.RunTests(context.Background(), files, opts)
+	for _, f := range report.Files {
+		fmt.Printf("=== %s (%s)\n", f.File, f.Duration.Round(time.Millisecond))
+		for _, tc := range f.Cases {
+			status := "PASS"
+			if !tc.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("  %s %s (%s)", status, tc.Name, tc.Duration.Round(time.Microsecond))
+			if tc.Message != "" {
+				fmt.Printf(": %s", tc.Message)
+			}
+			fmt.Println()
+		}
+		if f.Err != "" {
+			fmt.Printf("  ERROR %s\n", strings.SplitN(f.Err, "\n", 2)[0])
+		}
+		if verbose && f.Output != "" {
+			fmt.Print(f.Output)
+		}
+	}
+	fmt.Printf("\n%d tests, %d failed, %d files (%s)\n", report.Tests, report.Failed, len(report.Files), report.Duration.Round(time.Millisecond))
+
+	if junit != "" {
+		if err := writePluginReport(junit, report.WriteJUnit); err != nil {
+			return err
+		}
+	}
+	if jsonOut != "" {
+		if err := writePluginReport(jsonOut, report.WriteJSON); err != nil {
+			return err
+		}
+	}
+	if !report.OK() {
+		return fmt.Errorf("%d of %d plugin tests failed", report.Failed, report.Tests)
+	}
+	return nil
+}
+
+func writePluginReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return f.Close()
+}
+
+// handlePluginNewCommand creates a plugin from a template
+func (c *CLI) handlePluginNewCommand(dir, id, template, name, author, description string) error {
+	dest, err := plugins.Scaffold(dir, plugins.ScaffoldOptions{
+		ID: id, Name: name, Author: author, Description: description, Template: template,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s plugin %s in %s\n", template, id, dest)
+	fmt.Printf("Next: edit plugin.lua, then run `plugin test %s` and `plugin lint %s`\n", id, id)
+	return nil
+}
+
+// resolvePluginDir finds a plugin given by ID under dir or by its own path
+func resolvePluginDir(dir, arg string) string {
+	if info, err := os.Stat(filepath.Join(arg, plugins.PackageMetadataFile)); err == nil && !info.IsDir() {
+		return arg
+	}
+	return filepath.Join(dir, arg)
+}
+
+// handlePluginLintCommand lints the given plugins, or every plugin in dir
+// with a metadata.json, and fails when errors (or with strict, warnings)
+// are found
+func (c *CLI) handlePluginLintCommand(dir string, args []string, format string, strict bool) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (valid: table, json)", format)
+	}
+	var targets []string
+	for _, arg := range args {
+		targets = append(targets, resolvePluginDir(dir, arg))
+	}
+	if len(targets) == 0 {
+		matches, err := filepath.Glob(filepath.Join(dir, "*", plugins.PackageMetadataFile))
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			targets = append(targets, filepath.Dir(m))
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no plugins with %s found in %s", plugins.PackageMetadataFile, dir)
+		}
+	}
+
+	var reports []*scripting.LintReport
+	errCount, warnCount := 0, 0
+	for _, target := range targets {
+		report, err := scripting.LintPlugin(target)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		errCount += report.Errors()
+		warnCount += len(report.Issues) - report.Errors()
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, report := range reports {
+			status := "ok"
+			if len(report.Issues) > 0 {
+				status = fmt.Sprintf("%d errors, %d warnings", report.Errors(), len(report.Issues)-report.Errors())
+			}
+			fmt.Printf("%s: %s\n", report.Dir, status)
+			for _, issue := range report.Issues {
+				fmt.Printf("  %s\n", issue)
+			}
+		}
+		fmt.Printf("\n%d plugins, %d errors, %d warnings\n", len(reports), errCount, warnCount)
+	}
+
+	if errCount > 0 || (strict && warnCount > 0) {
+		return fmt.Errorf("plugin lint found %d errors and %d warnings", errCount, warnCount)
+	}
+	return nil
+}
+
+// handlePluginPackCommand lints a plugin and builds its package: a zip with
+// a MANIFEST.json of file hashes, signed when a key is given
+func (c *CLI) handlePluginPackCommand(dir, arg, output, signKey, signer string, skipLint bool) error {
+	pluginDir := resolvePluginDir(dir, arg)
+	if !skipLint {
+		report, err := scripting.LintPlugin(pluginDir)
+		if err != nil {
+			return err
+		}
+		if report.Errors() > 0 {
+			for _, issue := range report.Issues {
+				if issue.Severity == scripting.LintError {
+					fmt.Printf("  %s\n", issue)
+				}
+			}
+			return fmt.Errorf("%s has %d lint errors; fix them or pass --skip-lint", pluginDir, report.Errors())
+		}
+	}
+
+	data, err := plugins.BuildPackage(pluginDir)
+	if err != nil {
+		return err
+	}
+	if signKey != "" {
+		sm := plugins.NewSecurityManager()
+		if err := sm.LoadPrivateKey(signKey); err != nil {
+			return err
+		}
+		if data, err = sm.SignPackage(data, signer); err != nil {
+			return err
+		}
+	}
+	pkg, err := plugins.ReadPackage(data)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = fmt.Sprintf("%s-%s.zip", pkg.Manifest.PluginID, pkg.Manifest.Version)
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write package: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	fmt.Printf("Packed %s %s: %d files, %d bytes\n", pkg.Manifest.PluginID, pkg.Manifest.Version, len(pkg.Manifest.Files), len(data))
+	fmt.Printf("  %s\n  sha256 %s\n", output, hex.EncodeToString(sum[:]))
+	if pkg.IsSigned() {
+		fmt.Printf("  signed as %s\n", pkg.Signature.SignerID)
+	} else {
+		fmt.Println("  unsigned; pass --sign-key and --signer to sign it")
+	}
+	return nil
+}
+
+// handlePluginProfileCommand runs a plugin's Lua file runs times with its
+// own permissions while sampling the Lua call stack, prints the functions
+// with the most self time and writes the profile as collapsed stacks
+// and/or speedscope JSON
+func (c *CLI) handlePluginProfileCommand(dir, arg, file, entry, fixture string, interval int64, runs, top int, collapsed, speedscope string) error {
+	if runs < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+	pluginDir := resolvePluginDir(dir, arg)
+	data, err := os.ReadFile(filepath.Join(pluginDir, plugins.PackageMetadataFile))
+	if err != nil {
+		return fmt.Errorf("%s is not a plugin: %w", pluginDir, err)
+	}
+	var meta plugins.PluginMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return fmt.Errorf("invalid %s: %w", plugins.PackageMetadataFile, err)
+	}
+	code, err := os.ReadFile(filepath.Join(pluginDir, file))
+	if err != nil {
+		return fmt.Errorf("failed to read plugin code: %w", err)
+	}
+
+	m, err := newPluginManager(filepath.Dir(pluginDir), "", "")
+	if err != nil {
+		return err
+	}
+	profile := plugins.NewCPUProfile(meta.ID, interval)
+	ctx := context.Background()
+	for i := 0; i < runs; i++ {
+		var save *pr.PR
+		if fixture != "" {
+			if save, err = c.LoadSaveFile(fixture); err != nil {
+				return err
+			}
+		}
+		api := plugins.NewAPIImpl(save, nil).ForPlugin(meta.ID, meta.Permissions, nil)
+		api.SetStorageManager(m.GetStorageManager())
+		api.SetEventBus(m.GetEventBus())
+		api.SetSettingsBackend(m)
+
+		start := time.Now()
+		_, _, err := scripting.ProfilePluginSnippet(ctx, m.GetSandboxManager(), api, string(code), file, entry, save, profile)
+		m.GetProfiler().RecordExecution(meta.ID, time.Since(start), err == nil, err)
+		if err != nil {
+			return fmt.Errorf("run %d of %s failed: %w", i+1, meta.ID, err)
+		}
+	}
+	m.GetProfiler().RecordCPUProfile(profile)
+
+	fmt.Printf("Profiled %s: %d run(s), %s, %d samples every %d instructions\n",
+		meta.ID, runs, profile.Duration.Round(time.Microsecond), profile.Samples(), profile.Interval)
+	sampled := profile.SampledTime()
+	funcs := profile.Functions()
+	if top > 0 && len(funcs) > top {
+		funcs = funcs[:top]
+	}
+	if len(funcs) > 0 && sampled > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SELF\tSELF%\tTOTAL\tTOTAL%\tFUNCTION")
+		for _, f := range funcs {
+			fmt.Fprintf(w, "%s\t%.1f%%\t%s\t%.1f%%\t%s\n",
+				f.Self.Round(time.Microsecond), 100*float64(f.Self)/float64(sampled),
+				f.Total.Round(time.Microsecond), 100*float64(f.Total)/float64(sampled), f.Frame)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("No samples taken; lower --interval or raise --runs")
+	}
+
+	if collapsed != "" {
+		if err := writePluginReport(collapsed, profile.WriteCollapsed); err != nil {
+			return err
+		}
+		fmt.Printf("Collapsed stacks written to %s\n", collapsed)
+	}
+	if speedscope != "" {
+		if err := writePluginReport(speedscope, profile.WriteSpeedscope); err != nil {
+			return err
+		}
+		fmt.Printf("Speedscope profile written to %s (open it at https://www.speedscope.app)\n", speedscope)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ffvi_editor/marketplace"
+	"ffvi_editor/plugins"
+)
+
+// startSignedMirror serves a signed mirror holding core 1.0.0 and ui 1.0.0
+// (which depends on core) and returns its URL and a trusted key directory
+func startSignedMirror(t *testing.T) (string, string) {
+	t.Helper()
+	source := t.TempDir()
+	metas := map[string]string{
+		"core": `{"id":"core","name":"Core","version":"1.0.0","author":"tester"}`,
+		"ui":   `{"id":"ui","name":"UI","version":"1.0.0","author":"tester","dependencies":["core@^1.0.0"]}`,
+	}
+	for id, meta := range metas {
+		dir := filepath.Join(source, id)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create plugin dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(meta), 0644); err != nil {
+			t.Fatalf("Failed to write metadata: %v", err)
+		}
+	}
+
+	signer := newTestSigner(t)
+	root := filepath.Join(t.TempDir(), "mirror")
+	opts := marketplace.MirrorOptions{Signer: signer, SignerID: "tester"}
+	if _, err := marketplace.ExportMirrorWithOptions(source, root, opts); err != nil {
+		t.Fatalf("ExportMirrorWithOptions failed: %v", err)
+	}
+	srv, err := marketplace.NewServer(root)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	keys := t.TempDir()
+	pub, err := signer.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("PublicKeyPEM failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(keys, "tester.pem"), []byte(pub), 0644); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return ts.URL, keys
+}
+
+// newTestSigner creates a security manager with a fresh key pair
+func newTestSigner(t *testing.T) *plugins.SecurityManager {
+	t.Helper()
+	sm := plugins.NewSecurityManager()
+	if err := sm.GenerateKeyPair(); err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	return sm
+}
+
+// TestHandlePluginInstallCommand tests planning, applying and uninstalling with dependencies
+func TestHandlePluginInstallCommand(t *testing.T) {
+	url, keys := startSignedMirror(t)
+	dir := t.TempDir()
+	registry := t.TempDir()
+	cli := NewCLI([]string{})
+
+	// Without --yes only the plan is shown
+	if err := cli.handlePluginInstallCommand(dir, url, "ui", "", keys, "require", registry, false, false); err != nil {
+		t.Fatalf("handlePluginInstallCommand (plan) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ui")); !os.IsNotExist(err) {
+		t.Error("plan-only run should not install anything")
+	}
+
+	if err := cli.handlePluginInstallCommand(dir, url, "ui", "", keys, "require", registry, false, true); err != nil {
+		t.Fatalf("handlePluginInstallCommand failed: %v", err)
+	}
+	for _, id := range []string{"core", "ui"} {
+		if _, err := os.Stat(filepath.Join(dir, id, "metadata.json")); err != nil {
+			t.Errorf("%s not installed: %v", id, err)
+		}
+	}
+
+	reg, err := marketplace.NewRegistry(registry)
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+	if record, err := reg.GetPlugin("core"); err != nil || record.Version != "1.0.0" {
+		t.Errorf("registry record for core = %+v, %v", record, err)
+	}
+
+	if err := cli.handlePluginUninstallCommand(dir, "core", "", true); !errors.Is(err, plugins.ErrPluginHasDependents) {
+		t.Errorf("uninstalling core = %v, want ErrPluginHasDependents", err)
+	}
+	if err := cli.handlePluginUninstallCommand(dir, "ui", "", true); err != nil {
+		t.Errorf("uninstalling ui failed: %v", err)
+	}
+}
+
+// TestHandlePluginInstallCommandUntrusted tests that packages from unknown signers are refused
+func TestHandlePluginInstallCommandUntrusted(t *testing.T) {
+	url, _ := startSignedMirror(t)
+	dir := t.TempDir()
+
+	cli := NewCLI([]string{})
+	err := cli.handlePluginInstallCommand(dir, url, "core", "", "", "require", "", false, true)
+	if !errors.Is(err, plugins.ErrUntrustedSigner) {
+		t.Errorf("handlePluginInstallCommand = %v, want ErrUntrustedSigner", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "core")); !os.IsNotExist(err) {
+		t.Error("untrusted package should not be installed")
+	}
+}
+
+// TestPluginCommandUnknownSubcommand tests subcommand validation
+func TestPluginCommandUnknownSubcommand(t *testing.T) {
+	cli := NewCLI([]string{"plugin", "bogus"})
+	if err := cli.Run(); err == nil {
+		t.Error("plugin with an unknown subcommand should fail")
+	}
+}
+
+// TestHandlePluginConfigCommand tests settings are shown, validated and saved
+func TestHandlePluginConfigCommand(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "tracker")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("Failed to create plugin dir: %v", err)
+	}
+	meta := `{"id":"tracker","name":"Tracker","version":"1.0.0","author":"tester","settings":[
+		{"key":"difficulty","type":"enum","options":["easy","hard"],"default":"easy"},
+		{"key":"max-enemies","type":"integer","min":1,"max":8,"default":4}]}`
+	if err := os.WriteFile(filepath.Join(pluginDir, "metadata.json"), []byte(meta), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	cli := NewCLI([]string{})
+	if err := cli.handlePluginConfigCommand(dir, "tracker", []string{"max-enemies=12"}, nil, "table"); !errors.Is(err, plugins.ErrInvalidSettings) {
+		t.Fatalf("out of range value = %v, want ErrInvalidSettings", err)
+	}
+	out, err := captureOutput(func() error {
+		return cli.handlePluginConfigCommand(dir, "tracker", []string{"difficulty=hard", "max-enemies=6"}, nil, "json")
+	})
+	if err != nil {
+		t.Fatalf("handlePluginConfigCommand failed: %v", err)
+	}
+	if !strings.Contains(out, `"value": "hard"`) || !strings.Contains(out, `"value": 6`) {
+		t.Errorf("output does not show the new values:\n%s", out)
+	}
+
+	out, err = captureOutput(func() error {
+		return cli.handlePluginConfigCommand(dir, "tracker", nil, []string{"difficulty"}, "table")
+	})
+	if err != nil {
+		t.Fatalf("handlePluginConfigCommand --reset failed: %v", err)
+	}
+	if !strings.Contains(out, "difficulty   enum     easy") || !strings.Contains(out, "max-enemies  integer  6") {
+		t.Errorf("reset did not restore the default, or dropped another setting:\n%s", out)
+	}
+}
+
+// TestHandlePluginTestCommand tests plugin tests run and reports are written
+func TestHandlePluginTestCommand(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "tracker")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("Failed to create plugin dir: %v", err)
+	}
+	tests := `test("passes", function() expect.equal(1, 1) end)
+test("fails", function() expect.equal(1, 2) end)`
+	if err := os.WriteFile(filepath.Join(pluginDir, "tracker_test.lua"), []byte(tests), 0644); err != nil {
+		t.Fatalf("Failed to write tests: %v", err)
+	}
+
+	cli := NewCLI([]string{})
+	junit := filepath.Join(dir, "results.xml")
+	out, err := captureOutput(func() error {
+		return cli.handlePluginTestCommand(dir, nil, "", "", 0, junit, "", false)
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("handlePluginTestCommand = %v, want 1 of 2 failed", err)
+	}
+	if !strings.Contains(out, "PASS passes") || !strings.Contains(out, "FAIL fails") {
+		t.Errorf("output does not list the tests:\n%s", out)
+	}
+	if data, err := os.ReadFile(junit); err != nil || !strings.Contains(string(data), `failures="1"`) {
+		t.Errorf("JUnit report = %s, %v", data, err)
+	}
+
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginTestCommand(dir, []string{"tracker"}, "", "^pass", 0, "", "", false)
+	}); err != nil {
+		t.Errorf("--run passes = %v, want success", err)
+	}
+	if err := cli.handlePluginTestCommand(dir, []string{"missing"}, "", "", 0, "", "", false); err == nil {
+		t.Error("handlePluginTestCommand accepted a missing plugin")
+	}
+}
+
+// TestPluginNewLintPack tests a scaffolded plugin lints clean and packs
+func TestPluginNewLintPack(t *testing.T) {
+	dir := t.TempDir()
+	cli := NewCLI([]string{})
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginNewCommand(dir, "gil-helper", "tool", "", "tester", "")
+	}); err != nil {
+		t.Fatalf("handlePluginNewCommand failed: %v", err)
+	}
+
+	out, err := captureOutput(func() error {
+		return cli.handlePluginLintCommand(dir, nil, "table", true)
+	})
+	if err != nil {
+		t.Fatalf("scaffolded plugin does not lint clean: %v\n%s", err, out)
+	}
+
+	output := filepath.Join(dir, "gil-helper.zip")
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginPackCommand(dir, "gil-helper", output, "", "", false)
+	}); err != nil {
+		t.Fatalf("handlePluginPackCommand failed: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("package not written: %v", err)
+	}
+	pkg, err := plugins.ReadPackage(data)
+	if err != nil {
+		t.Fatalf("ReadPackage failed: %v", err)
+	}
+	if pkg.Manifest.PluginID != "gil-helper" || pkg.IsSigned() {
+		t.Errorf("manifest = %+v, signed = %v", pkg.Manifest, pkg.IsSigned())
+	}
+
+	// A forbidden global blocks packing
+	source := filepath.Join(dir, "gil-helper", "plugin.lua")
+	if err := os.WriteFile(source, []byte("return load('return 1')\n"), 0644); err != nil {
+		t.Fatalf("Failed to write plugin.lua: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginPackCommand(dir, "gil-helper", output, "", "", false)
+	}); err == nil {
+		t.Error("handlePluginPackCommand packed a plugin with lint errors")
+	}
+}
+
+func TestHandlePluginProfileCommand(t *testing.T) {
+	dir := t.TempDir()
+	cli := NewCLI([]string{})
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginNewCommand(dir, "busy", "database", "", "tester", "")
+	}); err != nil {
+		t.Fatalf("handlePluginNewCommand failed: %v", err)
+	}
+	source := `local M = {}
+function M.churn(n)
+  local s = 0
+  for i = 1, n do s = s + i % 3 end
+  return s
+end
+function M.run()
+  return {sum = M.churn(20000)}
+end
+return M
+`
+	if err := os.WriteFile(filepath.Join(dir, "busy", "plugin.lua"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write plugin.lua: %v", err)
+	}
+
+	collapsed := filepath.Join(dir, "busy.folded")
+	speedscope := filepath.Join(dir, "busy.speedscope.json")
+	out, err := captureOutput(func() error {
+		return cli.handlePluginProfileCommand(dir, "busy", "plugin.lua", "run", "", 100, 3, 5, collapsed, speedscope)
+	})
+	if err != nil {
+		t.Fatalf("handlePluginProfileCommand failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "churn (plugin.lua:2)") {
+		t.Errorf("output does not list the hot function:\n%s", out)
+	}
+	folded, err := os.ReadFile(collapsed)
+	if err != nil {
+		t.Fatalf("collapsed stacks not written: %v", err)
+	}
+	if !strings.Contains(string(folded), "run (plugin.lua:7);churn (plugin.lua:2) ") {
+		t.Errorf("unexpected collapsed stacks:\n%s", folded)
+	}
+	data, err := os.ReadFile(speedscope)
+	if err != nil {
+		t.Fatalf("speedscope profile not written: %v", err)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal(data, &file); err != nil || file["$schema"] == nil {
+		t.Errorf("speedscope profile is not valid: %v", err)
+	}
+
+	if _, err := captureOutput(func() error {
+		return cli.handlePluginProfileCommand(dir, "busy", "plugin.lua", "missing", "", 100, 1, 5, "", "")
+	}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("profiling an unknown entry = %v, want an error naming it", err)
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"ffvi_editor/models/search"
+)
+
+// handleQueryCommand runs a structured query against one save, or against
+// every save in dir matching pattern, and prints a table or JSON
+func (c *CLI) handleQueryCommand(text, file, dir, pattern, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (valid: table, json)", format)
+	}
+	q, err := search.ParseQuery(text)
+	if err != nil {
+		return err
+	}
+
+	var res *search.QueryResult
+	if dir != "" {
+		paths, err := querySaveFiles(dir, pattern)
+		if err != nil {
+			return err
+		}
+		load := func(path string) error {
+			_, err := c.LoadSaveFile(path)
+			return err
+		}
+		if res, err = q.RunFiles(paths, load); err != nil {
+			return err
+		}
+		for _, skipped := range res.Skipped {
+			fmt.Fprintf(os.Stderr, "Skipped %v\n", skipped)
+		}
+	} else {
+		if _, err = c.LoadSaveFile(file); err != nil {
+			return err
+		}
+		if res, err = q.Run(); err != nil {
+			return err
+		}
+	}
+
+	if format == "json" {
+		out, err := json.MarshalIndent(res.Records(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	printQueryTable(res)
+	return nil
+}
+
+// querySaveFiles lists the regular, non-hidden files in dir matching pattern
+func querySaveFiles(dir, pattern string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		ok, err := filepath.Match(pattern, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid --pattern: %w", err)
+		}
+		if ok {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files matching %s in %s", pattern, dir)
+	}
+	return paths, nil
+}
+
+func printQueryTable(res *search.QueryResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(res.Columns, "\t"))
+	for _, rec := range res.Records() {
+		cells := make([]string, len(res.Columns))
+		for i, col := range res.Columns {
+			cells[i] = fmt.Sprint(rec[col])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	fmt.Printf("\n%d %s\n", len(res.Rows), res.Source)
+}
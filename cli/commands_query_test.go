@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestQueryCommandValidation tests flag and query validation
+func TestQueryCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"query", "characters"},
+		{"query", "--file", "save.json"},
+		{"query", "--file", "save.json", "--dir", ".", "characters"},
+		{"query", "--file", "save.json", "monsters where level > 1"},
+		{"query", "--file", "save.json", "--format", "csv", "characters"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleQueryCommandMissingFile tests loading errors are returned
+func TestHandleQueryCommandMissingFile(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleQueryCommand("espers not owned", "missing.json", "", "*", "table"); err == nil {
+		t.Error("querying a missing save should fail")
+	}
+}
+
+// TestQuerySaveFiles tests directory listing skips hidden files and directories
+func TestQuerySaveFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", ".hidden.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := querySaveFiles(dir, "*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err = querySaveFiles(dir, "*.sav"); err == nil {
+		t.Error("an empty match should be an error")
+	}
+}
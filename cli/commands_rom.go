@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	ffio "ffvi_editor/io"
+)
+
+// romInfoReport is the JSON form of rom info
+type romInfoReport struct {
+	File       string `json:"file"`
+	Size       int    `json:"size"`
+	HasHeader  bool   `json:"copierHeader"`
+	CRC32      string `json:"crc32"`
+	SHA1       string `json:"sha1"`
+	Title      string `json:"title"`
+	Mapping    string `json:"mapping"`
+	Version    int    `json:"version"`
+	Checksum   string `json:"checksum"`
+	ChecksumOK bool   `json:"checksumOk"`
+	Verified   bool   `json:"verified"`
+	Name       string `json:"name,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	Patch      string `json:"patch,omitempty"`
+	Region     string `json:"region"`
+}
+
+// handleROMInfoCommand identifies a ROM image and prints its hashes and header
+func (c *CLI) handleROMInfoCommand(file, known string, asJSON bool) error {
+	if known != "" {
+		if err := ffio.LoadKnownDumps(known); err != nil {
+			return err
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read ROM: %w", err)
+	}
+	info := ffio.IdentifyROM(data)
+
+	report := romInfoReport{
+		File:       file,
+		Size:       info.Size,
+		HasHeader:  info.HasHeader,
+		CRC32:      fmt.Sprintf("%08X", info.CRC32),
+		SHA1:       info.SHA1,
+		Title:      info.Title,
+		Mapping:    info.Mapping.String(),
+		Version:    int(info.Version),
+		Checksum:   fmt.Sprintf("%04X", info.Checksum),
+		ChecksumOK: info.ChecksumOK,
+		Verified:   info.Verified(),
+		Region:     info.Type.String(),
+	}
+	if info.Dump != nil {
+		report.Name = info.Dump.Name
+		report.Revision = info.Dump.Revision
+		report.Patch = info.Dump.Patch
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Printf("File:      %s\n", file)
+	fmt.Printf("Size:      %d bytes", info.Size)
+	if info.HasHeader {
+		fmt.Print(" (+512 byte copier header)")
+	}
+	fmt.Println()
+	fmt.Printf("CRC32:     %s\n", report.CRC32)
+	fmt.Printf("SHA-1:     %s\n", report.SHA1)
+	fmt.Printf("Title:     %q\n", info.Title)
+	fmt.Printf("Mapping:   %s\n", report.Mapping)
+	fmt.Printf("Version:   1.%d\n", info.Version)
+	checksum := "ok"
+	if !info.ChecksumOK {
+		checksum = "MISMATCH"
+	}
+	fmt.Printf("Checksum:  %s (%s)\n", report.Checksum, checksum)
+	if info.Dump != nil {
+		fmt.Printf("Identified: %s [%s]", info.Dump.Name, info.Dump.Revision)
+		if info.Dump.Patch != "" {
+			fmt.Printf(" with %s", info.Dump.Patch)
+		}
+		fmt.Println()
+	} else if info.Type != ffio.ROMTypeUnknown {
+		fmt.Printf("Identified: unverified %s image (title match only; hashes not in the known dump table)\n", report.Region)
+	} else {
+		fmt.Println("Identified: not a recognised Final Fantasy VI image")
+	}
+	return nil
+}
+
+// handleROMAssetsCommand checks every sprite and palette asset for the ROM's
+// revision, through assetTable when set (verified or not) and otherwise the
+// registered table, falling back to the unverified draft layout. With
+// writeTable set it saves the table for editing. The CRC32s read here are
+// only printed: checksums taken at guessed offsets of the same ROM would
+// vouch for nothing, so entries keep only the CRC32s recorded in the table.
+func (c *CLI) handleROMAssetsCommand(file, assetTable, writeTable string) error {
+	rom := ffio.NewROMLoader(file)
+	if err := rom.Load(); err != nil {
+		return err
+	}
+	var table *ffio.AssetTable
+	var err error
+	if assetTable != "" {
+		if table, err = ffio.ReadAssetTable(assetTable); err != nil {
+			return err
+		}
+	} else if table, err = ffio.AssetTableFor(rom.Info()); err != nil {
+		revision := rom.Info().Revision()
+		if revision == "" {
+			return err
+		}
+		fmt.Printf("No verified asset table for %s; checking the unverified draft layout\n\n", revision)
+		table = ffio.DraftAssetTable(revision, rom.GetData())
+	}
+	extractor := ffio.NewROMSpriteExtractorWithTable(rom, table)
+
+	report := extractor.AssetReport()
+	verified, unverified, failed := 0, 0, 0
+	updated := ffio.AssetTable{Revision: table.Revision, Verified: table.Verified}
+	for _, st := range report {
+		status := "unverified"
+		switch {
+		case errors.Is(st.Err, ffio.ErrAssetChecksum):
+			status = "MISMATCH"
+			failed++
+		case st.Err != nil:
+			status = "ERROR " + st.Err.Error()
+			failed++
+		case st.Asset.Verified():
+			status = "ok"
+			verified++
+		default:
+			unverified++
+		}
+		fmt.Printf("  %-32s 0x%06X %5d  %08X  %s\n", st.Asset.ID, st.Asset.Offset, st.Asset.Size, st.CRC32, status)
+		updated.Assets = append(updated.Assets, st.Asset)
+	}
+	fmt.Printf("\nRevision %s: %d verified, %d unverified, %d failed\n", table.Revision, verified, unverified, failed)
+
+	if writeTable != "" {
+		if !extractor.ROMInfo().Verified() {
+			return fmt.Errorf("refusing to write a table from an image that is not a known dump")
+		}
+		data, err := json.MarshalIndent(updated, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(writeTable, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", writeTable)
+		if unverified > 0 {
+			fmt.Printf("%d entries have no CRC32: record each one's once its graphics are confirmed, then set \"verified\": true\n", unverified)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d asset(s) failed verification", failed)
+	}
+	return nil
+}
+
+// romInjectOptions selects the asset, its new data and the outputs for rom inject
+type romInjectOptions struct {
+	Asset       string
+	PNG         string
+	Data        string
+	WithPalette bool
+	// SharedPalette allows changing a palette other characters use too
+	SharedPalette bool
+	Table         string
+	Output        string
+	IPS           string
+	BPS           string
+}
+
+// handleROMInjectCommand writes an edited sprite or palette into a copy of
+// the ROM, fixes the checksum and writes the patched ROM and/or patches
+func (c *CLI) handleROMInjectCommand(file string, opts romInjectOptions) error {
+	if opts.Table != "" {
+		if _, err := ffio.LoadAssetTable(opts.Table); err != nil {
+			return err
+		}
+	}
+	rom := ffio.NewROMLoader(file)
+	if err := rom.Load(); err != nil {
+		return err
+	}
+	patcher, err := ffio.NewROMPatcher(rom)
+	if err != nil {
+		return err
+	}
+
+	patcher.AllowSharedPalettes(opts.SharedPalette)
+
+	var result *ffio.InjectResult
+	if opts.PNG != "" {
+		img, _, err := ffio.NewImageDecoder().Decode(opts.PNG)
+		if err != nil {
+			return err
+		}
+		result, err = patcher.InjectImage(opts.Asset, img, opts.WithPalette)
+		if err != nil {
+			return err
+		}
+	} else {
+		data, err := os.ReadFile(opts.Data)
+		if err != nil {
+			return err
+		}
+		if result, err = patcher.InjectAsset(opts.Asset, data); err != nil {
+			return err
+		}
+	}
+	checksum := patcher.FixChecksum()
+
+	where := "in place"
+	if result.Relocated {
+		where = "relocated"
+	}
+	fmt.Printf("Injected %s: %d bytes at 0x%06X (%s); checksum %04X\n",
+		opts.Asset, result.StoredSize, result.Asset.Offset, where, checksum)
+	if len(result.PaletteUsers) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: the palette is shared; this also recolours %s\n", strings.Join(result.PaletteUsers, ", "))
+	}
+
+	if opts.Output != "" {
+		if err := patcher.WriteROM(opts.Output); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", opts.Output)
+	}
+	if opts.IPS != "" {
+		ips, err := patcher.IPS()
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(opts.IPS, ips, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (apply to a headerless ROM)\n", opts.IPS)
+	}
+	if opts.BPS != "" {
+		if err := os.WriteFile(opts.BPS, patcher.BPS(), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (apply to a headerless ROM)\n", opts.BPS)
+	}
+	return nil
+}
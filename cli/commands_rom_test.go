@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ffio "ffvi_editor/io"
+)
+
+// TestROMCommandValidation tests subcommand and flag validation
+func TestROMCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"rom"},
+		{"rom", "bogus"},
+		{"rom", "info"},
+		{"rom", "info", "--file", "missing.sfc"},
+		{"rom", "assets"},
+		{"rom", "inject", "--file", "rom.sfc"},
+		{"rom", "inject", "--file", "rom.sfc", "--asset", "field/terra", "--ips", "out.ips"},
+		{"rom", "inject", "--file", "rom.sfc", "--asset", "field/terra", "--png", "a.png", "--data", "a.bin", "--ips", "out.ips"},
+		{"rom", "inject", "--file", "rom.sfc", "--asset", "field/terra", "--png", "a.png"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleROMInfoCommand tests an unrecognised image is still reported
+func TestHandleROMInfoCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blank.smc")
+	if err := os.WriteFile(path, make([]byte, 512+32*1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cli := NewCLI([]string{})
+	if err := cli.handleROMInfoCommand(path, "", true); err != nil {
+		t.Errorf("rom info on a blank image: %v", err)
+	}
+}
+
+// TestHandleROMAssetsCommandMissingFile tests loading errors are returned
+func TestHandleROMAssetsCommandMissingFile(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleROMAssetsCommand("missing.sfc", "", ""); err == nil {
+		t.Error("checking assets of a missing ROM should fail")
+	}
+}
+
+// TestHandleROMInjectCommand injects raw tiles into a minimal US image and
+// checks the IPS patch reproduces the patched ROM
+func TestHandleROMInjectCommand(t *testing.T) {
+	dir := t.TempDir()
+	rom := make([]byte, 3*1024*1024)
+	copy(rom[0xFFC0:], "FINAL FANTASY 3      ")
+	romPath := filepath.Join(dir, "ff3.sfc")
+	dataPath := filepath.Join(dir, "terra.bin")
+	if err := os.WriteFile(romPath, rom, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tiles := make([]byte, 576)
+	for i := range tiles {
+		tiles[i] = byte(i)
+	}
+	if err := os.WriteFile(dataPath, tiles, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := romInjectOptions{
+		Asset:  "field/terra",
+		Data:   dataPath,
+		Output: filepath.Join(dir, "patched.sfc"),
+		IPS:    filepath.Join(dir, "terra.ips"),
+		BPS:    filepath.Join(dir, "terra.bps"),
+	}
+	cli := NewCLI([]string{})
+	defer ffio.ResetAssetTables()
+	if err := cli.handleROMInjectCommand(romPath, opts); err == nil {
+		t.Fatal("injecting without a verified asset table should fail")
+	}
+
+	opts.Table = filepath.Join(dir, "us-1.0.json")
+	table := `{"revision":"us-1.0","assets":[{"id":"field/terra","name":"Terra","kind":"field","character":0,` +
+		`"offset":2097152,"size":576,"width":16,"height":24,"frames":3}]}`
+	if err := os.WriteFile(opts.Table, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.handleROMInjectCommand(romPath, opts); err == nil {
+		t.Fatal("injecting through a table not marked verified should fail")
+	}
+	table = fmt.Sprintf(`{"revision":"us-1.0","verified":true,"assets":[{"id":"field/terra","name":"Terra","kind":"field","character":0,`+
+		`"offset":2097152,"size":576,"width":16,"height":24,"frames":3,"crc32":%d}]}`, crc32.ChecksumIEEE(make([]byte, 576)))
+	if err := os.WriteFile(opts.Table, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cli.handleROMInjectCommand(romPath, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	patched, err := os.ReadFile(opts.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := os.ReadFile(opts.IPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := ffio.ApplyIPS(rom, ips)
+	if err != nil || !bytes.Equal(applied, patched) {
+		t.Errorf("IPS does not reproduce the patched ROM: %v", err)
+	}
+	if !ffio.IdentifyROM(patched).ChecksumOK {
+		t.Error("patched ROM checksum not fixed")
+	}
+}
+
+// TestHandleROMInjectCommandRelocates injects data that no longer fits a
+// compressed asset's slot and checks the patch moves it and repoints the game
+func TestHandleROMInjectCommandRelocates(t *testing.T) {
+	defer ffio.ResetAssetTables()
+	dir := t.TempDir()
+	const at, pointer, free = 0x200000, 0x210000, 0x2F0000
+	rom := make([]byte, 3*1024*1024)
+	copy(rom[0xFFC0:], "FINAL FANTASY 3      ")
+	copy(rom[at:], ffio.NewLZ77Compressor().Compress(bytes.Repeat([]byte{7}, 256)))
+	for i := free; i < free+0x1000; i++ {
+		rom[i] = 0xFF
+	}
+	noisy := make([]byte, 256)
+	for i := range noisy {
+		noisy[i] = byte(i * 37)
+	}
+
+	opts := romInjectOptions{
+		Asset:  "monster/test",
+		Data:   filepath.Join(dir, "noisy.bin"),
+		Table:  filepath.Join(dir, "table.json"),
+		Output: filepath.Join(dir, "patched.sfc"),
+	}
+	romPath := filepath.Join(dir, "ff3.sfc")
+	table := fmt.Sprintf(`{"revision":"us-1.0","verified":true,"assets":[{"id":"monster/test","name":"Test","kind":"monster","character":-1,`+
+		`"offset":2097152,"size":256,"compressed":true,"pointer":2162688,"crc32":%d}]}`, crc32.ChecksumIEEE(bytes.Repeat([]byte{7}, 256)))
+	for path, data := range map[string][]byte{romPath: rom, opts.Data: noisy, opts.Table: []byte(table)} {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewCLI([]string{}).handleROMInjectCommand(romPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	patched, err := os.ReadFile(opts.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uint32(patched[pointer]) | uint32(patched[pointer+1])<<8 | uint32(patched[pointer+2])<<16
+	offset, err := ffio.SNESToFile(ffio.MappingHiROM, addr)
+	if err != nil || offset < free || offset >= free+0x1000 {
+		t.Fatalf("pointer $%06X -> 0x%X, want the free space at 0x%X: %v", addr, offset, free, err)
+	}
+	got, _, err := ffio.NewLZ77Decompressor().DecompressSize(patched[offset:], len(noisy))
+	if err != nil || !bytes.Equal(got, noisy) {
+		t.Errorf("relocated asset does not hold the injected data: %v", err)
+	}
+}
+
+// TestHandleROMAssetsCommandWriteTable tests a draft is written out without
+// the checksums read from the ROM being checked, and that --table checks a
+// table without registering it
+func TestHandleROMAssetsCommandWriteTable(t *testing.T) {
+	defer ffio.ResetAssetTables()
+	defer ffio.ResetKnownDumps()
+	dir := t.TempDir()
+	rom := make([]byte, 4*1024*1024)
+	copy(rom[0xFFC0:], "FINAL FANTASY 3      ")
+	romPath := filepath.Join(dir, "ff3.sfc")
+	if err := os.WriteFile(romPath, rom, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ffio.KnownDumps = append(ffio.KnownDumps, ffio.KnownDump{Name: "Test", Type: ffio.ROMTypeUSA,
+		Revision: ffio.RevisionUS10, Mapping: ffio.MappingHiROM, CRC32: crc32.ChecksumIEEE(rom)})
+
+	out := filepath.Join(dir, "draft.json")
+	if err := NewCLI([]string{}).handleROMAssetsCommand(romPath, "", out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written ffio.AssetTable
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Verified || len(written.Assets) == 0 {
+		t.Fatalf("written draft: verified %v, %d assets", written.Verified, len(written.Assets))
+	}
+	for _, a := range written.Assets {
+		if a.CRC32 != 0 {
+			t.Fatalf("draft asset %s was written with the CRC32 read from the ROM", a.ID)
+		}
+	}
+	if _, err := ffio.LoadAssetTable(out); err == nil {
+		t.Error("LoadAssetTable should refuse the unverified draft")
+	}
+
+	if err := NewCLI([]string{}).handleROMAssetsCommand(romPath, out, ""); err != nil {
+		t.Errorf("checking the draft with --table: %v", err)
+	}
+	if _, ok := ffio.AssetTables[ffio.RevisionUS10]; ok {
+		t.Error("checking a table should not register it")
+	}
+}
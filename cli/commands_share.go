@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts/pr"
+	pri "ffvi_editor/models/pr"
+	"ffvi_editor/models/share"
+)
+
+// handleShareEncodeCommand prints the share code for a character in a save
+func (c *CLI) handleShareEncodeCommand(file string, charID int, encoding string) error {
+	enc, err := share.ParseEncoding(encoding)
+	if err != nil {
+		return err
+	}
+	if _, err = c.LoadSaveFile(file); err != nil {
+		return err
+	}
+	character, err := shareCharacter(charID)
+	if err != nil {
+		return err
+	}
+
+	g := share.NewCodeGenerator()
+	g.SetEncoding(enc)
+	code, err := g.GenerateCharacterCode(share.BuildFromCharacter(character), 0)
+	if err != nil {
+		return err
+	}
+	fmt.Println(code)
+	return nil
+}
+
+// handleShareDecodeCommand prints the build held in a character code
+func (c *CLI) handleShareDecodeCommand(code string, asJSON bool) error {
+	build, err := share.NewCodeGenerator().DecodeCharacterCode(code)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		out, err := json.MarshalIndent(build, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	printBuild(build)
+	return nil
+}
+
+// handleShareApplyCommand decodes a character code and writes the build onto
+// a character in a save
+func (c *CLI) handleShareApplyCommand(file string, charID int, code, output string) error {
+	build, err := share.NewCodeGenerator().DecodeCharacterCode(code)
+	if err != nil {
+		return err
+	}
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	character, err := shareCharacter(charID)
+	if err != nil {
+		return err
+	}
+	if err = build.Apply(character); err != nil {
+		return fmt.Errorf("cannot apply build: %w", err)
+	}
+
+	if output == "" {
+		output = file
+	}
+	if err = c.SaveSaveFile(save, output); err != nil {
+		return err
+	}
+	fmt.Printf("Applied %s's build (level %d) to %s\n", build.Name, build.Level, character.Name)
+	fmt.Printf("Wrote %s\n", output)
+	return nil
+}
+
+func shareCharacter(charID int) (*models.Character, error) {
+	character := pri.GetCharacterByID(charID)
+	if character == nil || character.ID != charID {
+		return nil, fmt.Errorf("character with ID %d not found", charID)
+	}
+	return character, nil
+}
+
+func printBuild(b *share.CharacterBuild) {
+	fmt.Printf("Name:      %s (character ID %d)\n", b.Name, b.CharacterID)
+	fmt.Printf("Level:     %d (%d exp)\n", b.Level, b.Exp)
+	fmt.Printf("HP:        %d/%d\n", b.HP.Current, b.HP.Max)
+	fmt.Printf("MP:        %d/%d\n", b.MP.Current, b.MP.Max)
+	fmt.Printf("Stats:     Vigor %d, Speed %d, Stamina %d, Magic %d\n",
+		b.Stats.Vigor, b.Stats.Speed, b.Stats.Stamina, b.Stats.MagicPwr)
+	fmt.Println("Equipment:")
+	for _, slot := range []struct {
+		name string
+		id   int
+	}{
+		{"Weapon", b.Equipment.Weapon}, {"Shield", b.Equipment.Shield},
+		{"Armor", b.Equipment.Armor}, {"Helmet", b.Equipment.Helmet},
+		{"Relic 1", b.Equipment.Relic1}, {"Relic 2", b.Equipment.Relic2},
+	} {
+		fmt.Printf("  %-8s %s\n", slot.name, shareName(pr.ItemsByID[slot.id], slot.id))
+	}
+	if len(b.Spells) > 0 {
+		spells := make([]string, len(b.Spells))
+		for i, s := range b.Spells {
+			name := ""
+			if sp, ok := pr.SpellLookupByID[s.ID]; ok {
+				name = sp.Name
+			}
+			spells[i] = fmt.Sprintf("%s %d%%", shareName(name, s.ID), s.Progress)
+		}
+		fmt.Printf("Spells:    %s\n", strings.Join(spells, ", "))
+	}
+	if len(b.Commands) > 0 {
+		commands := make([]string, len(b.Commands))
+		for i, id := range b.Commands {
+			name := ""
+			if cmd, ok := pr.CommandLookupByValue[id]; ok {
+				name = cmd.Name
+			}
+			commands[i] = shareName(name, id)
+		}
+		fmt.Printf("Commands:  %s\n", strings.Join(commands, ", "))
+	}
+}
+
+// shareName falls back to the raw ID for names missing from the tables
+func shareName(name string, id int) string {
+	if name == "" {
+		return fmt.Sprintf("#%d", id)
+	}
+	return name
+}
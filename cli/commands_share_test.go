@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"testing"
+
+	"ffvi_editor/models/share"
+)
+
+// TestShareCommandValidation tests subcommand and flag validation
+func TestShareCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"share"},
+		{"share", "bogus"},
+		{"share", "encode", "--file", "save.json"},
+		{"share", "decode"},
+		{"share", "decode", "--code", "TERR-BLD-0123"},
+		{"share", "apply", "--file", "save.json", "--char", "1"},
+		{"share", "encode", "--file", "save.json", "--char", "1", "--encoding", "base64"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleShareDecodeCommand tests a generated code decodes in both forms
+func TestHandleShareDecodeCommand(t *testing.T) {
+	build := &share.CharacterBuild{Name: "Terra", Level: 50, Commands: []int{1, 26}}
+	code, err := share.NewCodeGenerator().GenerateCharacterCode(build, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewCLI([]string{})
+	for _, asJSON := range []bool{false, true} {
+		if err = cli.handleShareDecodeCommand(code, asJSON); err != nil {
+			t.Errorf("decode (json %v): %v", asJSON, err)
+		}
+	}
+}
+
+// TestHandleShareApplyCommandMissingFile tests loading errors are returned
+func TestHandleShareApplyCommandMissingFile(t *testing.T) {
+	code, err := share.NewCodeGenerator().GenerateCharacterCode(&share.CharacterBuild{Name: "Terra", Level: 1}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewCLI([]string{})
+	if err = cli.handleShareApplyCommand("missing.json", 1, code, ""); err == nil {
+		t.Error("applying to a missing save should fail")
+	}
+}
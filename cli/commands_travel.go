@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"ffvi_editor/models/consts"
+	pri "ffvi_editor/models/pr"
+)
+
+// vehicleEdit holds the vehicle fields to change; nil fields are left alone
+type vehicleEdit struct {
+	MapID     *int
+	X, Y, Z   *float64
+	Direction *int
+	Enabled   *bool
+}
+
+// timerEdit starts or stops the countdown
+type timerEdit struct {
+	ID      int
+	Seconds float64
+	Stop    bool
+}
+
+// teleportEdit sets or clears the Warp return point
+type teleportEdit struct {
+	MapID   int
+	PointIn int
+	X, Y, Z float64
+	Clear   bool
+}
+
+// handleTravelShowCommand prints vehicles, the countdown and the Warp return point
+func (c *CLI) handleTravelShowCommand(file string) error {
+	if _, err := c.LoadSaveFile(file); err != nil {
+		return err
+	}
+
+	fmt.Println("Vehicles:")
+	for i, t := range pri.Transportations {
+		if t == nil {
+			continue
+		}
+		state := "disabled"
+		if t.Enabled {
+			state = "enabled"
+		}
+		warning := ""
+		if err := t.Validate(); err != nil {
+			warning = "  ! " + err.Error()
+		} else if err := t.CheckPosition(); err != nil {
+			warning = "  ! " + err.Error()
+		}
+		fmt.Printf("  %d %-10s %-8s map %-4d (%.1f, %.1f, %.1f) facing %d%s\n",
+			i, pri.TransportationName(i), state, t.MapID, t.Position.X, t.Position.Y, t.Position.Z, t.Direction, warning)
+	}
+
+	timer := pri.GetTimer()
+	switch {
+	case !timer.Present:
+		fmt.Println("\nTimer: not stored in this save")
+	case timer.Active:
+		fmt.Printf("\nTimer: %s, %.0fs left (timer IDs are provisional)\n", timer.Name(), timer.Remaining)
+	default:
+		fmt.Println("\nTimer: none running")
+	}
+
+	cache := pri.GetTeleportCache()
+	if cache.Present {
+		fmt.Printf("Warp return point: map %d entrance %d (%.1f, %.1f, %.1f)\n",
+			cache.MapID, cache.PointIn, cache.Position.X, cache.Position.Y, cache.Position.Z)
+	} else {
+		fmt.Println("Warp return point: not stored in this save")
+	}
+	return nil
+}
+
+// handleTravelVehicleCommand edits one vehicle and refuses placements off
+// the world maps. Positions past the assumed map size only warn.
+func (c *CLI) handleTravelVehicleCommand(file string, index int, edit vehicleEdit, output string) error {
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(pri.Transportations) || pri.Transportations[index] == nil {
+		return fmt.Errorf("vehicle %d not found (save has %d)", index, len(pri.Transportations))
+	}
+	t := pri.Transportations[index]
+
+	if edit.MapID != nil {
+		t.MapID = *edit.MapID
+	}
+	if edit.X != nil {
+		t.Position.X = *edit.X
+	}
+	if edit.Y != nil {
+		t.Position.Y = *edit.Y
+	}
+	if edit.Z != nil {
+		t.Position.Z = *edit.Z
+	}
+	if edit.Direction != nil {
+		t.Direction = *edit.Direction
+	}
+	if edit.Enabled != nil {
+		t.SetEnabled(*edit.Enabled)
+	}
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", pri.TransportationName(index), err)
+	}
+	if err := t.CheckPosition(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", pri.TransportationName(index), err)
+	}
+
+	if output == "" {
+		output = file
+	}
+	return c.SaveSaveFile(save, output)
+}
+
+// handleTravelTimerCommand starts or stops the countdown. Countdowns whose
+// IDs are unverified are only started with force.
+func (c *CLI) handleTravelTimerCommand(file string, edit timerEdit, output string, force bool) error {
+	if !edit.Stop && !pri.TimerVerified(edit.ID) {
+		if !force {
+			return fmt.Errorf("refusing to start timer %d: its ID is provisional (use --force to write it anyway)", edit.ID)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: starting timer %d, whose ID is provisional\n", edit.ID)
+	}
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	timer := pri.GetTimer()
+	if !timer.Present {
+		return fmt.Errorf("save has no timer data")
+	}
+
+	if edit.Stop {
+		timer.Stop()
+	} else if err := timer.Start(edit.ID, edit.Seconds); err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = file
+	}
+	return c.SaveSaveFile(save, output)
+}
+
+// handleTravelTeleportCommand sets or clears the Warp return point
+func (c *CLI) handleTravelTeleportCommand(file string, edit teleportEdit, output string) error {
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	cache := pri.GetTeleportCache()
+	if !cache.Present {
+		return fmt.Errorf("save has no teleport cache")
+	}
+
+	if edit.Clear {
+		cache.Clear()
+	} else {
+		if edit.MapID <= 0 || edit.MapID >= len(consts.Maps) || consts.Maps[edit.MapID] == nil {
+			return fmt.Errorf("unknown map %d", edit.MapID)
+		}
+		cache.MapID = edit.MapID
+		cache.PointIn = edit.PointIn
+		cache.Position = pri.V3{X: edit.X, Y: edit.Y, Z: edit.Z}
+	}
+
+	if output == "" {
+		output = file
+	}
+	return c.SaveSaveFile(save, output)
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTravelCommandValidation tests subcommand and flag validation
+func TestTravelCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"travel"},
+		{"travel", "bogus"},
+		{"travel", "show"},
+		{"travel", "vehicle", "--file", "save.json"},
+		{"travel", "vehicle", "--file", "save.json", "--index", "3", "--enable", "--disable"},
+		{"travel", "timer", "--file", "save.json"},
+		{"travel", "teleport", "--file", "save.json"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleTravelVehicleCommandMissingFile tests loading errors are returned
+func TestHandleTravelVehicleCommandMissingFile(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleTravelVehicleCommand("missing.json", 0, vehicleEdit{}, ""); err == nil {
+		t.Error("editing a missing save should fail")
+	}
+}
+
+// TestHandleTravelTimerCommandRequiresForce tests provisional countdowns are
+// refused without --force before the save is loaded
+func TestHandleTravelTimerCommandRequiresForce(t *testing.T) {
+	cli := NewCLI([]string{})
+	err := cli.handleTravelTimerCommand("missing.json", timerEdit{ID: 2}, "", false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("starting a provisional timer without --force = %v, want a --force error", err)
+	}
+	if err := cli.handleTravelTimerCommand("missing.json", timerEdit{Stop: true}, "", false); err == nil || strings.Contains(err.Error(), "--force") {
+		t.Errorf("stopping needs no --force and should fail to load the missing save, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	pri "ffvi_editor/models/pr"
+)
+
+// parseWorld accepts balance/ruin or 1/2; empty means both worlds
+func parseWorld(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, nil
+	case "balance", "wob", "1":
+		return 1, nil
+	case "ruin", "wor", "2":
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("invalid world %q (valid: balance, ruin)", s)
+	}
+}
+
+// handleTreasureListCommand prints the treasures matching filter and their state
+func (c *CLI) handleTreasureListCommand(file string, filter pri.TreasureFilter) error {
+	if _, err := c.LoadSaveFile(file); err != nil {
+		return err
+	}
+	d := pri.GetDataStorage()
+	if _, ok := d.Arrays[pri.DataStorageTreasure]; !ok {
+		return fmt.Errorf("save has no %s flags in dataStorage", pri.DataStorageTreasure)
+	}
+
+	list := d.Treasures(filter)
+	opened, provisional := 0, 0
+	for _, t := range list {
+		state := "unopened"
+		if t.Opened {
+			state = "opened"
+			opened++
+		}
+		note := ""
+		if t.Missable {
+			note = " (missable)"
+		}
+		if t.Provisional {
+			note += " (provisional)"
+			provisional++
+		}
+		fmt.Printf("  %4d  %-9s %-28s %s%s\n", t.ID, state, t.MapName, t.Contents, note)
+	}
+	fmt.Printf("\n%d of %d treasure(s) opened; save records %d opened chest(s)\n",
+		opened, len(list), pri.GetCheats().OpenedChestCount)
+	if provisional > 0 {
+		fmt.Printf("%d treasure(s) are provisional: their IDs, maps and contents are not verified against the game\n", provisional)
+	}
+	return nil
+}
+
+// handleTreasureSetCommand opens or resets the treasures matching filter, or
+// only the listed IDs when ids is set, and saves to output (or back to file).
+// Treasures whose entries are unverified are only written with force.
+func (c *CLI) handleTreasureSetCommand(file string, filter pri.TreasureFilter, ids []int, opened bool, output string, force bool) error {
+	if unverified := pri.UnverifiedTreasures(filter, ids); len(unverified) > 0 {
+		if !force {
+			return fmt.Errorf("refusing to write %d provisional treasure(s) whose entries are unverified: %s (use --force to write them anyway)",
+				len(unverified), joinIDs(unverified))
+		}
+		fmt.Fprintf(os.Stderr, "Warning: writing %d provisional treasure(s) whose entries are unverified: %s\n",
+			len(unverified), joinIDs(unverified))
+	}
+	save, err := c.LoadSaveFile(file)
+	if err != nil {
+		return err
+	}
+	state := save.Models()
+
+	changed := 0
+	if len(ids) > 0 {
+		for _, id := range ids {
+			ok, err := state.SetTreasureOpened(id, opened)
+			if err != nil {
+				return fmt.Errorf("treasure %d: %w", id, err)
+			}
+			if ok {
+				changed++
+			}
+		}
+	} else if changed, err = state.SetTreasuresOpened(filter, opened); err != nil {
+		return err
+	}
+
+	verb := "Opened"
+	if !opened {
+		verb = "Reset"
+	}
+	fmt.Printf("%s %d treasure(s); opened chest count is now %d\n", verb, changed, state.Cheats().OpenedChestCount)
+
+	if output == "" {
+		output = file
+	}
+	return c.SaveSaveFile(save, output)
+}
+
+// joinIDs formats IDs as a comma separated list
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseIDList parses a comma separated list of integers
+func parseIDList(s string) ([]int, error) {
+	var ids []int
+	for _, part := range splitList(s) {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	pri "ffvi_editor/models/pr"
+)
+
+// TestParseWorld tests world name parsing
+func TestParseWorld(t *testing.T) {
+	for in, want := range map[string]int{"": 0, "balance": 1, "WoR": 2, "2": 2} {
+		if got, err := parseWorld(in); err != nil || got != want {
+			t.Errorf("parseWorld(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseWorld("moon"); err == nil {
+		t.Error("parseWorld should reject unknown worlds")
+	}
+}
+
+// TestTreasureCommandValidation tests subcommand and flag validation
+func TestTreasureCommandValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"treasure"},
+		{"treasure", "bogus"},
+		{"treasure", "list"},
+		{"treasure", "open", "--file", "save.json"},
+		{"treasure", "reset", "--file", "save.json", "--id", "x"},
+	} {
+		if err := NewCLI(args).Run(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+// TestHandleTreasureListCommandMissingFile tests loading errors are returned
+func TestHandleTreasureListCommandMissingFile(t *testing.T) {
+	cli := NewCLI([]string{})
+	if err := cli.handleTreasureListCommand("missing.json", pri.TreasureFilter{}); err == nil {
+		t.Error("listing a missing save should fail")
+	}
+}
+
+// TestHandleTreasureSetCommandRequiresForce tests provisional treasures are
+// refused without --force before the save is loaded
+func TestHandleTreasureSetCommandRequiresForce(t *testing.T) {
+	cli := NewCLI([]string{})
+	err := cli.handleTreasureSetCommand("missing.json", pri.TreasureFilter{}, []int{1}, true, "", false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("opening a provisional treasure without --force = %v, want a --force error", err)
+	}
+	if err := cli.handleTreasureSetCommand("missing.json", pri.TreasureFilter{}, []int{1}, true, "", true); err == nil || strings.Contains(err.Error(), "--force") {
+		t.Errorf("with --force the missing save should fail to load, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"ffvi_editor/global"
+	"ffvi_editor/io/backup"
+	"ffvi_editor/io/watch"
+	"ffvi_editor/plugins"
+)
+
+// handleWatchCommand watches a save directory until interrupted
+// Each slot the game writes is snapshotted, validated, announced to plugins
+// and optionally has a recipe re-applied
+func (c *CLI) handleWatchCommand(dir, backupDir string, maxBackups int, recipeName string, ps bool, pluginDir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to access save directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("save path is not a directory: %s", dir)
+	}
+
+	var recipe watch.Recipe
+	if recipeName != "" {
+		if recipe, err = watch.RecipeByName(recipeName); err != nil {
+			return err
+		}
+	}
+
+	saveType := global.PC
+	if ps {
+		saveType = global.PS
+	}
+
+	if backupDir == "" {
+		backupDir = filepath.Join(dir, "backups")
+	}
+	bm, err := backup.NewManager(backupDir, maxBackups)
+	if err != nil {
+		return fmt.Errorf("failed to create backup manager: %w", err)
+	}
+
+	opts := watch.Options{
+		Dir:      dir,
+		SaveType: saveType,
+		Backups:  bm,
+		Recipe:   recipe,
+		OnEvent: func(e watch.Event) {
+			fmt.Printf("[watch] %s\n", e)
+		},
+	}
+
+	if pluginDir != "" {
+		api := plugins.NewAPIImpl(nil, []string{plugins.CommonPermissions.ReadSave})
+		manager := plugins.NewManager(pluginDir, api)
+		opts.Hook = func(ctx context.Context, path string) error {
+			return manager.CallHook(ctx, plugins.HookSaveOpen, path)
+		}
+	}
+
+	w, err := watch.New(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := w.Start(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Watching %s (backups: %s)", dir, backupDir)
+	if recipeName != "" {
+		fmt.Printf(", recipe: %s", recipeName)
+	}
+	fmt.Println("\nPress Ctrl+C to stop.")
+
+	<-ctx.Done()
+	if w.IsRunning() {
+		_ = w.Stop()
+	}
+	fmt.Println("Watch stopped")
+	return nil
+}
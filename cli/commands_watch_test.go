@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHandleWatchCommandMissingDir tests watching a directory that does not exist
+func TestHandleWatchCommandMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	cli := NewCLI([]string{})
+	if err := cli.handleWatchCommand(dir, "", 10, "", false, ""); err == nil {
+		t.Error("handleWatchCommand should fail for a missing directory")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("handleWatchCommand should not create the watched directory")
+	}
+}
+
+// TestHandleWatchCommandFile tests watching a path that is a file
+func TestHandleWatchCommandFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "slot1.sav")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	cli := NewCLI([]string{})
+	if err := cli.handleWatchCommand(file, "", 10, "", false, ""); err == nil {
+		t.Error("handleWatchCommand should fail when given a file")
+	}
+}
+
+// TestHandleWatchCommandUnknownRecipe tests recipe validation before watching starts
+func TestHandleWatchCommandUnknownRecipe(t *testing.T) {
+	dir := t.TempDir()
+
+	cli := NewCLI([]string{})
+	if err := cli.handleWatchCommand(dir, "", 10, "no-such-recipe", false, ""); err == nil {
+		t.Error("handleWatchCommand should reject an unknown recipe")
+	}
+}
+
+// TestWatchCommandRequiresDir tests the --dir flag requirement
+func TestWatchCommandRequiresDir(t *testing.T) {
+	cli := NewCLI([]string{"watch"})
+	if err := cli.Run(); err == nil {
+		t.Error("watch without --dir should return an error")
+	}
+}
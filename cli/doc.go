@@ -18,6 +18,17 @@
 //	script       - Run Lua script (EXPERIMENTAL)
 //	validate     - Validate save file (EXPERIMENTAL)
 //	backup       - Create backup (EXPERIMENTAL)
+//	watch        - React to the game writing a save slot
+//	marketplace  - Serve or export an offline marketplace mirror
+//	plugin       - Plan and apply plugin installs, upgrades and removals; edit settings; run tests; profile, scaffold, lint and pack
+//	flags        - List, set and diff story/event flags in dataStorage
+//	treasure     - List, open and reset treasure chests per map
+//	travel       - Edit vehicles, countdown timers and the Warp return point
+//	rom          - Identify ROMs, verify offset tables and inject edited sprites
+//	share        - Encode, decode and apply character build share codes
+//	query        - Query save data, e.g. characters where level < 30
+//	catalog      - Index a saves directory, search it and export CSV
+//	audit        - Verify the hash-chained plugin audit log and its signed checkpoints
 //
 // Usage:
 //
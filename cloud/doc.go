@@ -7,13 +7,15 @@
 // Features:
 //   - Automatic backup to cloud storage
 //   - Conflict resolution strategies
-//   - Encryption support
+//   - Streaming chunked AES-256-GCM encryption
+//   - Resumable chunked uploads with progress reporting
 //   - Bandwidth management
 //   - Sync status tracking
 //
 // The cloud manager coordinates multiple providers and handles:
 //   - Authentication flows
-//   - File upload/download
+//   - File upload/download (interrupted uploads resume on retry while
+//     the file's content hash is unchanged)
 //   - Conflict detection and resolution
 //   - Quota monitoring
 //
@@ -22,5 +24,7 @@
 //	manager := cloud.New()
 //	gdrive := cloud.NewGoogleDriveProvider(clientID, secret)
 //	manager.RegisterProvider(gdrive)
+//	manager.SetProgressCallback(func(name string, s cloud.SyncStatus) { ... })
+//	err := manager.UploadFile(ctx, "Google Drive", localPath, remotePath)
 //	status, _ := manager.GetStatus("Google Drive")
 package cloud
@@ -23,6 +23,7 @@ type GoogleDriveProvider struct {
 	status        *SyncStatus
 	mu            sync.RWMutex
 	fileIDCache   map[string]string // path -> ID cache
+	uploads       *uploadSessionStore
 }
 
 // NewGoogleDriveProvider creates a new Google Drive provider
@@ -31,6 +32,7 @@ func NewGoogleDriveProvider(clientID, clientSecret string) *GoogleDriveProvider
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		fileIDCache:  make(map[string]string),
+		uploads:      newUploadSessionStore(),
 		status: &SyncStatus{
 			Provider:   "Google Drive",
 			InProgress: false,
@@ -116,19 +118,65 @@ func (g *GoogleDriveProvider) Upload(ctx context.Context, filename string, reade
 	}
 
 	// In real implementation, use Google Drive API
-	// For now, simulate upload with file hash
-	data, err := io.ReadAll(reader)
+	// For now, simulate upload by hashing the streamed content
+	hasher := md5.New()
+	size, err := io.Copy(hasher, reader)
 	if err != nil {
 		return nil, fmt.Errorf("google drive: failed to read file: %w", err)
 	}
 
-	hash := fmt.Sprintf("%x", md5.Sum(data))
+	hash := fmt.Sprintf("%x", hasher.Sum(nil))
 	fileID := "gd_" + hash[:16]
 
 	return &FileMetadata{
 		ID:           fileID,
 		Name:         filename,
-		Size:         int64(len(data)),
+		Size:         size,
+		ModifiedTime: time.Now(),
+		Hash:         hash,
+		IsFolder:     false,
+	}, nil
+}
+
+// StartUploadSession begins a resumable chunked upload
+func (g *GoogleDriveProvider) StartUploadSession(ctx context.Context, filename string, size int64) (*UploadSession, error) {
+	if !g.IsAuthenticated() {
+		return nil, fmt.Errorf("google drive: not authenticated")
+	}
+	return g.uploads.start(g.GetName(), filename, size)
+}
+
+// UploadChunk appends a chunk at offset to an upload session
+func (g *GoogleDriveProvider) UploadChunk(ctx context.Context, session *UploadSession, offset int64, data []byte) error {
+	if !g.IsAuthenticated() {
+		return fmt.Errorf("google drive: not authenticated")
+	}
+	if err := g.uploads.append(session, offset, data); err != nil {
+		return fmt.Errorf("google drive: %w", err)
+	}
+	return nil
+}
+
+// QueryUploadSession returns the number of bytes committed to a session
+func (g *GoogleDriveProvider) QueryUploadSession(ctx context.Context, session *UploadSession) (int64, error) {
+	offset, err := g.uploads.query(session)
+	if err != nil {
+		return 0, fmt.Errorf("google drive: %w", err)
+	}
+	return offset, nil
+}
+
+// FinishUploadSession commits an upload session as a file
+func (g *GoogleDriveProvider) FinishUploadSession(ctx context.Context, session *UploadSession) (*FileMetadata, error) {
+	hash, size, err := g.uploads.finish(session)
+	if err != nil {
+		return nil, fmt.Errorf("google drive: %w", err)
+	}
+
+	return &FileMetadata{
+		ID:           "gd_" + hash[:16],
+		Name:         session.Filename,
+		Size:         size,
 		ModifiedTime: time.Now(),
 		Hash:         hash,
 		IsFolder:     false,
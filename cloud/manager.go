@@ -1,17 +1,17 @@
 package cloud
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 )
@@ -25,6 +25,8 @@ type Manager struct {
 	stopCh     chan struct{}
 	syncTicker *time.Ticker
 	logger     *log.Logger
+	pending    map[string]*UploadSession // provider+path -> interrupted upload
+	progressFn ProgressFunc
 }
 
 // New creates a new cloud sync manager with default config
@@ -40,9 +42,10 @@ func New() *Manager {
 			EncryptionKey:      nil,
 			FolderPath:         "",
 		},
-		status: make(map[string]*SyncStatus),
-		stopCh: make(chan struct{}),
-		logger: log.New(log.Writer(), "[cloud] ", log.LstdFlags),
+		status:  make(map[string]*SyncStatus),
+		stopCh:  make(chan struct{}),
+		logger:  log.New(log.Writer(), "[cloud] ", log.LstdFlags),
+		pending: make(map[string]*UploadSession),
 	}
 }
 
@@ -54,6 +57,7 @@ func NewManager(config *SyncConfig) *Manager {
 		status:    make(map[string]*SyncStatus),
 		stopCh:    make(chan struct{}),
 		logger:    log.New(log.Writer(), "[cloud] ", log.LstdFlags),
+		pending:   make(map[string]*UploadSession),
 	}
 }
 
@@ -226,7 +230,9 @@ func (m *Manager) SyncAll(ctx context.Context) error {
 	return nil
 }
 
-// UploadFile uploads a single file to cloud storage via specified provider
+// UploadFile uploads a single file to cloud storage via specified provider.
+// The file is streamed in chunks; if the upload is interrupted, calling
+// UploadFile again with the same file resumes from the last committed chunk.
 func (m *Manager) UploadFile(ctx context.Context, providerName, localPath, remotePath string) error {
 	provider, err := m.GetProvider(providerName)
 	if err != nil {
@@ -237,26 +243,7 @@ func (m *Manager) UploadFile(ctx context.Context, providerName, localPath, remot
 		return fmt.Errorf("provider not authenticated")
 	}
 
-	// Open local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	// Encrypt if enabled
-	var reader io.Reader = file
-	if m.config.EncryptionEnabled && m.config.EncryptionKey != nil {
-		encrypted, err := m.encryptReader(file)
-		if err != nil {
-			return fmt.Errorf("encryption failed: %w", err)
-		}
-		reader = encrypted
-	}
-
-	// Upload to provider
-	_, err = provider.Upload(ctx, filepath.Base(remotePath), reader)
-	if err != nil {
+	if _, err := m.uploadChunked(ctx, provider, localPath, remotePath); err != nil {
 		return fmt.Errorf("upload failed: %w", err)
 	}
 
@@ -386,42 +373,18 @@ func (m *Manager) ResolveConflict(ctx context.Context, providerName string, conf
 	}
 }
 
-// encryptReader encrypts data from a reader using AES-256-GCM
-func (m *Manager) encryptReader(reader io.Reader) (io.Reader, error) {
-	// Read all data (for simplicity; streaming encryption would be better for large files)
-	plaintext, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create cipher
-	block, err := aes.NewCipher(m.config.EncryptionKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-
-	// Generate nonce
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+// decryptReader decrypts data from a reader. Chunked streams are decrypted
+// incrementally; older uploads in the single-block nonce||ciphertext format
+// are still accepted.
+func (m *Manager) decryptReader(reader io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(reader)
+	magic, err := br.Peek(len(streamMagic))
+	if err == nil && bytes.Equal(magic, []byte(streamMagic)) {
+		return NewDecryptReader(br, m.config.EncryptionKey)
 	}
 
-	// Encrypt and prepend nonce
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-
-	// Return as reader
-	return &readSeeker{data: ciphertext}, nil
-}
-
-// decryptReader decrypts data from a reader using AES-256-GCM
-func (m *Manager) decryptReader(reader io.Reader) (io.Reader, error) {
-	// Read all data
-	ciphertext, err := io.ReadAll(reader)
+	// Legacy format: read all data
+	ciphertext, err := io.ReadAll(br)
 	if err != nil {
 		return nil, err
 	}
@@ -444,6 +444,22 @@ func (m *mockProvider) GetQuotaInfo(ctx context.Context) (int64, int64, error) {
 	return 100, 1000, nil
 }
 
+func (m *mockProvider) StartUploadSession(ctx context.Context, filename string, size int64) (*UploadSession, error) {
+	return &UploadSession{ID: "session-id", Filename: filename, Size: size}, nil
+}
+
+func (m *mockProvider) UploadChunk(ctx context.Context, session *UploadSession, offset int64, data []byte) error {
+	return nil
+}
+
+func (m *mockProvider) QueryUploadSession(ctx context.Context, session *UploadSession) (int64, error) {
+	return session.Offset, nil
+}
+
+func (m *mockProvider) FinishUploadSession(ctx context.Context, session *UploadSession) (*FileMetadata, error) {
+	return &FileMetadata{ID: "test-id", Name: session.Filename}, nil
+}
+
 // BenchmarkRegisterProvider benchmarks provider registration
 func BenchmarkRegisterProvider(b *testing.B) {
 	m := New()
@@ -36,6 +36,19 @@ type Provider interface {
 	// Download downloads a file from cloud storage
 	Download(ctx context.Context, fileID string) (io.ReadCloser, error)
 
+	// Chunked uploads
+	// StartUploadSession opens a resumable upload for a file of the given size
+	StartUploadSession(ctx context.Context, filename string, size int64) (*UploadSession, error)
+
+	// UploadChunk appends data at offset; offset must equal the committed size
+	UploadChunk(ctx context.Context, session *UploadSession, offset int64, data []byte) error
+
+	// QueryUploadSession returns how many bytes the provider has committed
+	QueryUploadSession(ctx context.Context, session *UploadSession) (int64, error)
+
+	// FinishUploadSession commits the upload and returns the file metadata
+	FinishUploadSession(ctx context.Context, session *UploadSession) (*FileMetadata, error)
+
 	// DownloadFile downloads a cloud file to local path
 	// fileID: cloud file ID
 	// localPath: destination local path
@@ -92,6 +105,22 @@ type FileMetadata struct {
 	Parents      []string  // Parent folder IDs
 }
 
+// UploadSession tracks a resumable chunked upload
+type UploadSession struct {
+	ID          string    // Provider session ID
+	Provider    string    // Provider name
+	Filename    string    // Remote file name
+	Size        int64     // Total bytes to upload
+	Offset      int64     // Bytes committed so far
+	CreatedAt   time.Time // When the session was opened
+	LocalPath   string    // Source file
+	LocalSize   int64     // Source size when the session was opened
+	LocalMod    time.Time // Source modification time when the session was opened
+	LocalHash   string    // SHA-256 of the source when the session was opened
+	Encrypted   bool      // Whether the uploaded bytes are an encrypted stream
+	NoncePrefix []byte    // Stream nonce prefix, reused only for the content LocalHash names
+}
+
 // ConflictResolution defines how to handle sync conflicts
 type ConflictResolution int
 
@@ -122,6 +151,8 @@ type SyncConfig struct {
 	RetryDelay         time.Duration      // Delay between retries
 	VerifyHashes       bool               // Verify file integrity via hashing
 	CompressFiles      bool               // Compress files before upload
+	ChunkSize          int                // Upload chunk size in bytes (0 = DefaultUploadChunkSize)
+	BandwidthLimit     int64              // Maximum upload rate in bytes/second (0 = unlimited)
 }
 
 // SyncStatus represents the status of a sync operation
@@ -136,6 +167,8 @@ type SyncStatus struct {
 	ConflictsFound  int
 	Progress        float64 // 0.0 to 1.0
 	CurrentFile     string  // Currently processing file
+	BytesDone       int64   // Bytes transferred for the current file
+	BytesTotal      int64   // Total bytes for the current file
 	IsAuthenticated bool
 	StorageUsed     int64
 	StorageTotal    int64
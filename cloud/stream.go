@@ -0,0 +1,270 @@
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Chunked AEAD stream format
+//
+//	header: magic "FF6S" | version (1) | chunk size (uint32 BE) | nonce prefix (7)
+//	chunk:  final flag (1) | ciphertext length (uint32 BE) | AES-256-GCM ciphertext
+//
+// Each chunk is sealed with nonce = prefix | counter (uint32 BE) | final flag,
+// so reordering, truncation and dropped chunks all fail authentication.
+const (
+	streamMagic          = "FF6S"
+	streamVersion        = 1
+	streamNoncePrefixLen = 7
+	streamHeaderLen      = len(streamMagic) + 1 + 4 + streamNoncePrefixLen
+
+	// DefaultStreamChunkSize is the plaintext size of each encrypted chunk
+	DefaultStreamChunkSize = 64 * 1024
+
+	maxStreamChunkSize = 16 * 1024 * 1024
+)
+
+// Stream errors
+var (
+	ErrStreamTruncated = errors.New("encrypted stream is truncated")
+	ErrStreamCorrupt   = errors.New("encrypted stream is corrupt or was tampered with")
+)
+
+// streamWriter encrypts everything written to it into the chunked stream format
+type streamWriter struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	prefix    []byte
+	buf       []byte
+	chunkSize int
+	counter   uint32
+	closed    bool
+}
+
+// NewEncryptWriter returns a writer that encrypts into w using the chunked
+// stream format. Close must be called to emit the final chunk.
+func NewEncryptWriter(w io.Writer, key []byte, chunkSize int) (io.WriteCloser, error) {
+	prefix := make([]byte, streamNoncePrefixLen)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+	return newEncryptWriterWithPrefix(w, key, chunkSize, prefix)
+}
+
+// newEncryptWriterWithPrefix creates an encrypt writer with a fixed nonce
+// prefix. Resumed uploads reuse the prefix so the ciphertext is reproducible.
+func newEncryptWriterWithPrefix(w io.Writer, key []byte, chunkSize int, prefix []byte) (io.WriteCloser, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize > maxStreamChunkSize {
+		return nil, fmt.Errorf("chunk size %d exceeds maximum %d", chunkSize, maxStreamChunkSize)
+	}
+	if len(prefix) != streamNoncePrefixLen {
+		return nil, fmt.Errorf("invalid nonce prefix length %d", len(prefix))
+	}
+
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, streamHeaderLen)
+	header = append(header, streamMagic...)
+	header = append(header, streamVersion)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &streamWriter{
+		w:         w,
+		aead:      aead,
+		prefix:    append([]byte(nil), prefix...),
+		buf:       make([]byte, 0, chunkSize),
+		chunkSize: chunkSize,
+	}, nil
+}
+
+// Write buffers plaintext and emits full chunks
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// Keep a full buffer pending so Close can mark it final
+		if len(s.buf) == s.chunkSize {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):s.chunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close emits the final chunk
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+// flush seals the buffered plaintext as one chunk
+func (s *streamWriter) flush(final bool) error {
+	sealed := s.aead.Seal(nil, streamNonce(s.prefix, s.counter, final), s.buf, nil)
+
+	frame := make([]byte, 0, 5+len(sealed))
+	if final {
+		frame = append(frame, 1)
+	} else {
+		frame = append(frame, 0)
+	}
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(sealed)))
+	frame = append(frame, sealed...)
+	if _, err := s.w.Write(frame); err != nil {
+		return err
+	}
+
+	s.counter++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// streamReader decrypts the chunked stream format
+type streamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	maxLen  int
+	counter uint32
+	plain   []byte
+	done    bool
+}
+
+// NewDecryptReader returns a reader that decrypts the chunked stream format
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, streamHeaderLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, ErrStreamTruncated
+	}
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return nil, fmt.Errorf("not an encrypted stream")
+	}
+	if v := header[len(streamMagic)]; v != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version %d", v)
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[len(streamMagic)+1:]))
+	if chunkSize <= 0 || chunkSize > maxStreamChunkSize {
+		return nil, ErrStreamCorrupt
+	}
+
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{
+		r:      br,
+		aead:   aead,
+		prefix: header[streamHeaderLen-streamNoncePrefixLen:],
+		maxLen: chunkSize + aead.Overhead(),
+	}, nil
+}
+
+// Read returns decrypted plaintext, one chunk at a time
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk
+func (s *streamReader) next() error {
+	frame := make([]byte, 5)
+	if _, err := io.ReadFull(s.r, frame); err != nil {
+		return ErrStreamTruncated
+	}
+	final := frame[0] == 1
+	if frame[0] > 1 {
+		return ErrStreamCorrupt
+	}
+	size := int(binary.BigEndian.Uint32(frame[1:]))
+	if size < s.aead.Overhead() || size > s.maxLen {
+		return ErrStreamCorrupt
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(s.r, sealed); err != nil {
+		return ErrStreamTruncated
+	}
+
+	plain, err := s.aead.Open(sealed[:0], streamNonce(s.prefix, s.counter, final), sealed, nil)
+	if err != nil {
+		return ErrStreamCorrupt
+	}
+	s.counter++
+	s.plain = plain
+
+	if final {
+		s.done = true
+		// Trailing data after the final chunk means the stream was tampered with
+		if _, err := s.r.Peek(1); err != io.EOF {
+			return ErrStreamCorrupt
+		}
+	}
+	return nil
+}
+
+// IsEncryptedStream reports whether data starts with the stream header
+func IsEncryptedStream(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(streamMagic))
+}
+
+// streamNonce builds the per-chunk nonce
+func streamNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// newStreamAEAD creates the AES-256-GCM cipher for a stream
+func newStreamAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
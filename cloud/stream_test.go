@@ -0,0 +1,149 @@
+package cloud
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func encryptStream(t *testing.T, key, plain []byte, chunkSize int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewEncryptWriter(&buf, key, chunkSize)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter() error: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(key, data []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// TestStreamRoundTrip tests encryption and decryption across chunk boundaries
+func TestStreamRoundTrip(t *testing.T) {
+	key := testKey(t)
+	const chunk = 16
+
+	for _, size := range []int{0, 1, chunk - 1, chunk, chunk + 1, 3 * chunk, 1000} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		data := encryptStream(t, key, plain, chunk)
+		if !IsEncryptedStream(data) {
+			t.Errorf("size %d: output missing stream header", size)
+		}
+		if got := int64(len(data)); got != encryptedStreamSize(int64(size), chunk) {
+			t.Errorf("size %d: stream length = %d, want %d", size, got, encryptedStreamSize(int64(size), chunk))
+		}
+
+		got, err := decryptStream(key, data)
+		if err != nil {
+			t.Fatalf("size %d: decrypt error: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+// TestStreamTamper tests that modified, truncated and extended streams are rejected
+func TestStreamTamper(t *testing.T) {
+	key := testKey(t)
+	plain := bytes.Repeat([]byte("terra"), 20)
+	data := encryptStream(t, key, plain, 32)
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(flipped)-1] ^= 0xFF
+	if _, err := decryptStream(key, flipped); !errors.Is(err, ErrStreamCorrupt) {
+		t.Errorf("flipped byte: err = %v, want ErrStreamCorrupt", err)
+	}
+
+	// Drop the final chunk: the previous chunk is not marked final
+	finalLen := 5 + (len(plain) % 32) + 16
+	if _, err := decryptStream(key, data[:len(data)-finalLen]); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("dropped final chunk: err = %v, want ErrStreamTruncated", err)
+	}
+
+	if _, err := decryptStream(key, data[:len(data)-3]); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("cut chunk: err = %v, want ErrStreamTruncated", err)
+	}
+
+	if _, err := decryptStream(key, append(append([]byte(nil), data...), 0)); !errors.Is(err, ErrStreamCorrupt) {
+		t.Errorf("trailing data: err = %v, want ErrStreamCorrupt", err)
+	}
+
+	// Swap the first two full chunks
+	frame := 5 + 32 + 16
+	swapped := append([]byte(nil), data[:streamHeaderLen]...)
+	swapped = append(swapped, data[streamHeaderLen+frame:streamHeaderLen+2*frame]...)
+	swapped = append(swapped, data[streamHeaderLen:streamHeaderLen+frame]...)
+	swapped = append(swapped, data[streamHeaderLen+2*frame:]...)
+	if _, err := decryptStream(key, swapped); !errors.Is(err, ErrStreamCorrupt) {
+		t.Errorf("reordered chunks: err = %v, want ErrStreamCorrupt", err)
+	}
+
+	if _, err := decryptStream(testKey(t), data); !errors.Is(err, ErrStreamCorrupt) {
+		t.Errorf("wrong key: err = %v, want ErrStreamCorrupt", err)
+	}
+}
+
+// TestStreamRejectsBadKey tests key length validation
+func TestStreamRejectsBadKey(t *testing.T) {
+	if _, err := NewEncryptWriter(io.Discard, make([]byte, 16), 0); err == nil {
+		t.Error("NewEncryptWriter should reject a 16 byte key")
+	}
+}
+
+// TestDecryptReaderLegacy tests that single-block uploads still decrypt
+func TestDecryptReaderLegacy(t *testing.T) {
+	key := testKey(t)
+	m := NewManager(&SyncConfig{EncryptionEnabled: true, EncryptionKey: key})
+	plain := []byte("legacy upload")
+
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	legacy := gcm.Seal(nonce, nonce, plain, nil)
+
+	for name, data := range map[string][]byte{
+		"legacy": legacy,
+		"stream": encryptStream(t, key, plain, 0),
+	} {
+		r, err := m.decryptReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: decryptReader() error: %v", name, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: read error: %v", name, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("%s: got %q, want %q", name, got, plain)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package cloud
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultUploadChunkSize is the size of each uploaded chunk
+const DefaultUploadChunkSize = 256 * 1024
+
+// Upload session errors
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrUploadOffsetMismatch  = errors.New("upload chunk offset does not match committed size")
+)
+
+// ProgressFunc receives a status snapshot whenever an upload makes progress
+type ProgressFunc func(providerName string, status SyncStatus)
+
+// uploadSessionStore tracks chunked uploads for the simulated providers
+type uploadSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*uploadState
+}
+
+// uploadState is the committed state of one session
+type uploadState struct {
+	offset int64
+	hasher hash.Hash
+}
+
+func newUploadSessionStore() *uploadSessionStore {
+	return &uploadSessionStore{sessions: make(map[string]*uploadState)}
+}
+
+// start opens a new session
+func (s *uploadSessionStore) start(provider, filename string, size int64) (*UploadSession, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := &UploadSession{
+		ID:        hex.EncodeToString(id),
+		Provider:  provider,
+		Filename:  filename,
+		Size:      size,
+		CreatedAt: time.Now(),
+	}
+	s.sessions[session.ID] = &uploadState{hasher: md5.New()}
+	return session, nil
+}
+
+// append commits a chunk at offset
+func (s *uploadSessionStore) append(session *UploadSession, offset int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.sessions[session.ID]
+	if !ok {
+		return ErrUploadSessionNotFound
+	}
+	if offset != state.offset {
+		return fmt.Errorf("%w: got %d, committed %d", ErrUploadOffsetMismatch, offset, state.offset)
+	}
+	state.hasher.Write(data)
+	state.offset += int64(len(data))
+	session.Offset = state.offset
+	return nil
+}
+
+// query returns the committed size of a session
+func (s *uploadSessionStore) query(session *UploadSession) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.sessions[session.ID]
+	if !ok {
+		return 0, ErrUploadSessionNotFound
+	}
+	return state.offset, nil
+}
+
+// finish closes a session and returns the md5 of the uploaded bytes
+func (s *uploadSessionStore) finish(session *UploadSession) (string, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.sessions[session.ID]
+	if !ok {
+		return "", 0, ErrUploadSessionNotFound
+	}
+	if session.Size >= 0 && state.offset != session.Size {
+		return "", 0, fmt.Errorf("upload incomplete: %d of %d bytes", state.offset, session.Size)
+	}
+	delete(s.sessions, session.ID)
+	return fmt.Sprintf("%x", state.hasher.Sum(nil)), state.offset, nil
+}
+
+// rateLimiter spaces writes so the average rate stays under a limit
+type rateLimiter struct {
+	bytesPerSec int64
+	start       time.Time
+	sent        int64
+}
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSec: bytesPerSec, start: time.Now()}
+}
+
+// wait blocks until n more bytes fit within the limit
+func (r *rateLimiter) wait(ctx context.Context, n int) error {
+	if r == nil {
+		return nil
+	}
+	r.sent += int64(n)
+	due := time.Duration(float64(r.sent) / float64(r.bytesPerSec) * float64(time.Second))
+	delay := due - time.Since(r.start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// encryptedStreamSize returns the exact size of the stream for a plaintext size
+func encryptedStreamSize(plainSize int64, chunkSize int) int64 {
+	chunks := (plainSize + int64(chunkSize) - 1) / int64(chunkSize)
+	if chunks == 0 {
+		chunks = 1
+	}
+	const gcmOverhead = 16
+	return int64(streamHeaderLen) + chunks*(5+gcmOverhead) + plainSize
+}
+
+// uploadKey identifies a pending upload
+func uploadKey(providerName, localPath string) string {
+	return providerName + "\x00" + localPath
+}
+
+// PendingUploads returns interrupted uploads that will resume on the next UploadFile
+func (m *Manager) PendingUploads() []*UploadSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]*UploadSession, 0, len(m.pending))
+	for _, s := range m.pending {
+		sessionCopy := *s
+		sessions = append(sessions, &sessionCopy)
+	}
+	return sessions
+}
+
+// SetProgressCallback registers a callback for upload progress
+func (m *Manager) SetProgressCallback(fn ProgressFunc) {
+	m.mu.Lock()
+	m.progressFn = fn
+	m.mu.Unlock()
+}
+
+// uploadChunked streams a local file to the provider in chunks. An
+// interrupted upload leaves a pending session that the next call resumes.
+func (m *Manager) uploadChunked(ctx context.Context, provider Provider, localPath, remotePath string) (*FileMetadata, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	chunkSize := m.config.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultUploadChunkSize
+	}
+	encrypted := m.config.EncryptionEnabled && m.config.EncryptionKey != nil
+
+	// A session is only resumed for the same content: resuming an encrypted
+	// upload reuses its nonces, which must never cover different plaintext
+	contentHash, err := hashSource(file)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := m.resumableSession(ctx, provider, localPath, info, contentHash, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		size := info.Size()
+		var prefix []byte
+		if encrypted {
+			size = encryptedStreamSize(info.Size(), DefaultStreamChunkSize)
+			prefix = make([]byte, streamNoncePrefixLen)
+			if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+				return nil, err
+			}
+		}
+		if session, err = provider.StartUploadSession(ctx, filepath.Base(remotePath), size); err != nil {
+			return nil, fmt.Errorf("failed to start upload session: %w", err)
+		}
+		session.LocalPath = localPath
+		session.LocalSize = info.Size()
+		session.LocalMod = info.ModTime()
+		session.LocalHash = contentHash
+		session.Encrypted = encrypted
+		session.NoncePrefix = prefix
+	}
+
+	key := uploadKey(provider.GetName(), localPath)
+	m.mu.Lock()
+	m.pending[key] = session
+	m.mu.Unlock()
+
+	// Hash what is actually sent, to catch the file changing mid-upload
+	streamed := sha256.New()
+	var source io.Reader = io.TeeReader(file, streamed)
+	if encrypted {
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			ew, err := newEncryptWriterWithPrefix(pw, m.config.EncryptionKey, DefaultStreamChunkSize, session.NoncePrefix)
+			if err == nil {
+				_, err = io.Copy(ew, io.TeeReader(file, streamed))
+				if closeErr := ew.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			pw.CloseWithError(err)
+		}()
+		source = pr
+	}
+
+	// Skip what the provider already has
+	if session.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, source, session.Offset); err != nil {
+			return nil, fmt.Errorf("failed to seek to resume offset: %w", err)
+		}
+	}
+
+	limiter := newRateLimiter(m.config.BandwidthLimit)
+	buf := make([]byte, chunkSize)
+	m.reportProgress(provider, localPath, session.Offset, session.Size)
+
+	for {
+		n, readErr := io.ReadFull(source, buf)
+		if n > 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err := provider.UploadChunk(ctx, session, session.Offset, buf[:n]); err != nil {
+				return nil, fmt.Errorf("failed to upload chunk at offset %d: %w", session.Offset, err)
+			}
+			session.Offset += int64(n)
+			m.reportProgress(provider, localPath, session.Offset, session.Size)
+			if err := limiter.wait(ctx, n); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+
+	if hex.EncodeToString(streamed.Sum(nil)) != session.LocalHash {
+		// Never resume or finish a session whose content changed
+		m.mu.Lock()
+		delete(m.pending, key)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%s changed during upload; upload it again", localPath)
+	}
+
+	meta, err := provider.FinishUploadSession(ctx, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish upload: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.pending, key)
+	m.mu.Unlock()
+
+	return meta, nil
+}
+
+// resumableSession returns the pending session for a file if it can still be
+// resumed, or nil when a new session is needed
+func (m *Manager) resumableSession(ctx context.Context, provider Provider, localPath string, info os.FileInfo, contentHash string, encrypted bool) (*UploadSession, error) {
+	key := uploadKey(provider.GetName(), localPath)
+
+	m.mu.Lock()
+	session, ok := m.pending[key]
+	if ok && (session.LocalSize != info.Size() || !session.LocalMod.Equal(info.ModTime()) ||
+		session.LocalHash != contentHash || session.Encrypted != encrypted) {
+		// Source changed since the upload started
+		delete(m.pending, key)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	committed, err := provider.QueryUploadSession(ctx, session)
+	if err != nil {
+		if errors.Is(err, ErrUploadSessionNotFound) {
+			m.mu.Lock()
+			delete(m.pending, key)
+			m.mu.Unlock()
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query upload session: %w", err)
+	}
+	session.Offset = committed
+	return session, nil
+}
+
+// hashSource returns the SHA-256 of file and rewinds it
+func hashSource(file *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// reportProgress updates the provider status and notifies the progress callback
+func (m *Manager) reportProgress(provider Provider, localPath string, done, total int64) {
+	status := provider.GetStatus()
+	status.CurrentFile = localPath
+	status.BytesDone = done
+	status.BytesTotal = total
+	if total > 0 {
+		status.Progress = float64(done) / float64(total)
+	} else {
+		status.Progress = 1
+	}
+	provider.SetStatus(status)
+
+	name := provider.GetName()
+	m.mu.Lock()
+	m.status[name] = status
+	fn := m.progressFn
+	m.mu.Unlock()
+
+	if fn != nil {
+		fn(name, *status)
+	}
+}
@@ -0,0 +1,323 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errInjected = errors.New("injected network failure")
+
+// storingProvider keeps uploaded bytes in memory and can fail after N chunks
+type storingProvider struct {
+	mockProvider
+	mu        sync.Mutex
+	sessions  map[string]*bytes.Buffer
+	files     map[string][]byte
+	chunks    int
+	failAfter int // fail the chunk after this many successes, 0 disables
+	nextID    int
+}
+
+func newStoringProvider() *storingProvider {
+	return &storingProvider{
+		mockProvider: mockProvider{name: "store", status: &SyncStatus{}, authenticated: true},
+		sessions:     make(map[string]*bytes.Buffer),
+		files:        make(map[string][]byte),
+	}
+}
+
+func (s *storingProvider) StartUploadSession(ctx context.Context, filename string, size int64) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := filename + string(rune('0'+s.nextID))
+	s.sessions[id] = &bytes.Buffer{}
+	return &UploadSession{ID: id, Filename: filename, Size: size}, nil
+}
+
+func (s *storingProvider) UploadChunk(ctx context.Context, session *UploadSession, offset int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAfter > 0 && s.chunks == s.failAfter {
+		s.failAfter = 0
+		return errInjected
+	}
+	buf, ok := s.sessions[session.ID]
+	if !ok {
+		return ErrUploadSessionNotFound
+	}
+	if int64(buf.Len()) != offset {
+		return ErrUploadOffsetMismatch
+	}
+	buf.Write(data)
+	s.chunks++
+	return nil
+}
+
+func (s *storingProvider) QueryUploadSession(ctx context.Context, session *UploadSession) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf, ok := s.sessions[session.ID]
+	if !ok {
+		return 0, ErrUploadSessionNotFound
+	}
+	return int64(buf.Len()), nil
+}
+
+func (s *storingProvider) FinishUploadSession(ctx context.Context, session *UploadSession) (*FileMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := s.sessions[session.ID]
+	s.files[session.Filename] = buf.Bytes()
+	delete(s.sessions, session.ID)
+	return &FileMetadata{ID: session.Filename, Name: session.Filename, Size: int64(buf.Len())}, nil
+}
+
+func (s *storingProvider) Download(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(s.files[fileID])), nil
+}
+
+func writeRandomFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "save.dat")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return path, data
+}
+
+// TestUploadFileResumesAfterFailure tests that an interrupted upload continues from the committed offset
+func TestUploadFileResumesAfterFailure(t *testing.T) {
+	provider := newStoringProvider()
+	provider.failAfter = 3
+	m := NewManager(&SyncConfig{ChunkSize: 1024})
+	m.RegisterProvider(provider)
+
+	path, data := writeRandomFile(t, 10*1024+7)
+	ctx := context.Background()
+
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); !errors.Is(err, errInjected) {
+		t.Fatalf("first UploadFile() err = %v, want injected failure", err)
+	}
+	pending := m.PendingUploads()
+	if len(pending) != 1 || pending[0].Offset != 3*1024 {
+		t.Fatalf("PendingUploads() = %+v, want one session at offset %d", pending, 3*1024)
+	}
+
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err != nil {
+		t.Fatalf("resumed UploadFile() error: %v", err)
+	}
+	if !bytes.Equal(provider.files["save.dat"], data) {
+		t.Error("resumed upload content does not match source")
+	}
+	if provider.chunks != 11 {
+		t.Errorf("uploaded %d chunks, want 11 (no chunk re-sent)", provider.chunks)
+	}
+	if len(m.PendingUploads()) != 0 {
+		t.Error("PendingUploads() should be empty after completion")
+	}
+}
+
+// TestUploadFileEncryptedResume tests that a resumed encrypted upload decrypts to the source
+func TestUploadFileEncryptedResume(t *testing.T) {
+	provider := newStoringProvider()
+	provider.failAfter = 2
+	m := NewManager(&SyncConfig{ChunkSize: 4096, EncryptionEnabled: true, EncryptionKey: testKey(t)})
+	m.RegisterProvider(provider)
+
+	path, data := writeRandomFile(t, 3*DefaultStreamChunkSize/2)
+	ctx := context.Background()
+
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err == nil {
+		t.Fatal("first UploadFile() should fail")
+	}
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err != nil {
+		t.Fatalf("resumed UploadFile() error: %v", err)
+	}
+
+	stored := provider.files["save.dat"]
+	if int64(len(stored)) != encryptedStreamSize(int64(len(data)), DefaultStreamChunkSize) {
+		t.Errorf("stored %d bytes, want %d", len(stored), encryptedStreamSize(int64(len(data)), DefaultStreamChunkSize))
+	}
+
+	out := filepath.Join(t.TempDir(), "restored.dat")
+	if err := m.DownloadFile(ctx, "store", "save.dat", out); err != nil {
+		t.Fatalf("DownloadFile() error: %v", err)
+	}
+	restored, _ := os.ReadFile(out)
+	if !bytes.Equal(restored, data) {
+		t.Error("decrypted download does not match source")
+	}
+}
+
+// TestUploadFileRestartsWhenSourceChanges tests that a modified file is not resumed
+func TestUploadFileRestartsWhenSourceChanges(t *testing.T) {
+	provider := newStoringProvider()
+	provider.failAfter = 1
+	m := NewManager(&SyncConfig{ChunkSize: 512})
+	m.RegisterProvider(provider)
+
+	path, _ := writeRandomFile(t, 2048)
+	ctx := context.Background()
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err == nil {
+		t.Fatal("first UploadFile() should fail")
+	}
+
+	changed := bytes.Repeat([]byte{7}, 4096)
+	if err := os.WriteFile(path, changed, 0644); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	if !bytes.Equal(provider.files["save.dat"], changed) {
+		t.Error("upload should contain the changed file")
+	}
+}
+
+// TestUploadFileEncryptedRestartsOnSameSizeEdit tests that a file rewritten
+// with the same size and modification time gets a new session and nonce
+// prefix instead of resuming
+func TestUploadFileEncryptedRestartsOnSameSizeEdit(t *testing.T) {
+	provider := newStoringProvider()
+	provider.failAfter = 1
+	m := NewManager(&SyncConfig{ChunkSize: 512, EncryptionEnabled: true, EncryptionKey: testKey(t)})
+	m.RegisterProvider(provider)
+
+	path, data := writeRandomFile(t, 2048)
+	info, _ := os.Stat(path)
+	ctx := context.Background()
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err == nil {
+		t.Fatal("first UploadFile() should fail")
+	}
+	pending := m.PendingUploads()
+	if len(pending) != 1 {
+		t.Fatalf("got %d pending uploads, want 1", len(pending))
+	}
+
+	changed := bytes.Repeat([]byte{7}, len(data))
+	if err := os.WriteFile(path, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if err := m.UploadFile(ctx, "store", path, "save.dat"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+
+	stored := provider.files["save.dat"]
+	if bytes.Equal(stored[streamHeaderLen-streamNoncePrefixLen:streamHeaderLen], pending[0].NoncePrefix) {
+		t.Error("changed content was encrypted with the interrupted session's nonce prefix")
+	}
+	out := filepath.Join(t.TempDir(), "restored.dat")
+	if err := m.DownloadFile(ctx, "store", "save.dat", out); err != nil {
+		t.Fatalf("DownloadFile() error: %v", err)
+	}
+	if restored, _ := os.ReadFile(out); !bytes.Equal(restored, changed) {
+		t.Error("decrypted download does not match the changed file")
+	}
+}
+
+// TestUploadFileProgress tests that progress is reported to the callback and status
+func TestUploadFileProgress(t *testing.T) {
+	provider := newStoringProvider()
+	m := NewManager(&SyncConfig{ChunkSize: 100})
+	m.RegisterProvider(provider)
+
+	var updates []SyncStatus
+	m.SetProgressCallback(func(name string, status SyncStatus) {
+		updates = append(updates, status)
+	})
+
+	path, _ := writeRandomFile(t, 250)
+	if err := m.UploadFile(context.Background(), "store", path, "save.dat"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+
+	// Initial report plus one per chunk
+	if len(updates) != 4 {
+		t.Fatalf("got %d progress updates, want 4", len(updates))
+	}
+	last := updates[len(updates)-1]
+	if last.BytesDone != 250 || last.BytesTotal != 250 || last.Progress != 1 {
+		t.Errorf("final progress = %d/%d (%.2f), want 250/250 (1.00)", last.BytesDone, last.BytesTotal, last.Progress)
+	}
+
+	status, err := m.GetStatus("store")
+	if err != nil {
+		t.Fatalf("GetStatus() error: %v", err)
+	}
+	if status.CurrentFile != path || status.BytesDone != 250 {
+		t.Errorf("status = %s %d, want %s 250", status.CurrentFile, status.BytesDone, path)
+	}
+}
+
+// TestUploadFileBandwidthLimit tests that uploads are throttled to the configured rate
+func TestUploadFileBandwidthLimit(t *testing.T) {
+	provider := newStoringProvider()
+	m := NewManager(&SyncConfig{ChunkSize: 1024, BandwidthLimit: 16 * 1024})
+	m.RegisterProvider(provider)
+
+	path, _ := writeRandomFile(t, 4*1024)
+	start := time.Now()
+	if err := m.UploadFile(context.Background(), "store", path, "save.dat"); err != nil {
+		t.Fatalf("UploadFile() error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("4KB at 16KB/s took %v, want at least 200ms", elapsed)
+	}
+
+	// Cancellation interrupts the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m.config.BandwidthLimit = 1024
+	if err := m.UploadFile(ctx, "store", path, "other.dat"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("throttled UploadFile() err = %v, want deadline exceeded", err)
+	}
+}
+
+// TestProviderUploadSession tests the simulated providers' session bookkeeping
+func TestProviderUploadSession(t *testing.T) {
+	ctx := context.Background()
+	for _, p := range []Provider{NewDropboxProvider("key", "secret"), NewGoogleDriveProvider("id", "secret")} {
+		p.Authenticate(ctx)
+
+		session, err := p.StartUploadSession(ctx, "slot1.sav", 6)
+		if err != nil {
+			t.Fatalf("%s: StartUploadSession() error: %v", p.GetName(), err)
+		}
+		if err := p.UploadChunk(ctx, session, 0, []byte("abc")); err != nil {
+			t.Fatalf("%s: UploadChunk() error: %v", p.GetName(), err)
+		}
+		if err := p.UploadChunk(ctx, session, 0, []byte("abc")); !errors.Is(err, ErrUploadOffsetMismatch) {
+			t.Errorf("%s: repeated chunk err = %v, want offset mismatch", p.GetName(), err)
+		}
+		if _, err := p.FinishUploadSession(ctx, session); err == nil {
+			t.Errorf("%s: FinishUploadSession() should fail on an incomplete upload", p.GetName())
+		}
+		if off, _ := p.QueryUploadSession(ctx, session); off != 3 {
+			t.Errorf("%s: QueryUploadSession() = %d, want 3", p.GetName(), off)
+		}
+		p.UploadChunk(ctx, session, 3, []byte("def"))
+
+		meta, err := p.FinishUploadSession(ctx, session)
+		if err != nil {
+			t.Fatalf("%s: FinishUploadSession() error: %v", p.GetName(), err)
+		}
+		whole, _ := p.Upload(ctx, "slot1.sav", bytes.NewReader([]byte("abcdef")))
+		if meta.Hash != whole.Hash || meta.Size != 6 {
+			t.Errorf("%s: session hash %s size %d, want %s 6", p.GetName(), meta.Hash, meta.Size, whole.Hash)
+		}
+	}
+}
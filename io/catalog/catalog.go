@@ -0,0 +1,232 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ffvi_editor/io/pr"
+)
+
+const (
+	// IndexVersion is the index file format; older indexes are rebuilt
+	IndexVersion = 2
+	// IndexFileName is where an index lives inside the directory it covers
+	IndexFileName = ".ffvi-catalog.json"
+)
+
+// Index is the catalogue of one saves directory
+type Index struct {
+	Version int       `json:"version"`
+	Root    string    `json:"root"`
+	Updated time.Time `json:"updated"`
+	Entries []*Entry  `json:"entries"` // sorted by path
+}
+
+// Entry is one indexed file. Files that fail to decode keep their hash and
+// the error so they are not decoded again until they change.
+type Entry struct {
+	Path    string    `json:"path"` // relative to the root, slash separated
+	Hash    string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Error   string    `json:"error,omitempty"`
+	Summary *Summary  `json:"summary,omitempty"`
+}
+
+// Options controls Update
+type Options struct {
+	// Pattern filters file base names; empty matches every file
+	Pattern string
+	// Load makes the save at path the loaded one; defaults to io/pr
+	Load func(path string) error
+}
+
+// UpdateStats counts what Update did
+type UpdateStats struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+	Failed    int
+}
+
+func (s UpdateStats) String() string {
+	return fmt.Sprintf("%d added, %d updated, %d unchanged, %d removed, %d failed",
+		s.Added, s.Updated, s.Unchanged, s.Removed, s.Failed)
+}
+
+// IndexPath returns the default index file for dir
+func IndexPath(dir string) string {
+	return filepath.Join(dir, IndexFileName)
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{Version: IndexVersion}
+}
+
+// Load reads an index file. A missing file, or one written by another
+// format version, gives an empty index so the next Update rebuilds it.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	idx := New()
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to read catalogue %s: %w", path, err)
+	}
+	if idx.Version != IndexVersion {
+		return New(), nil
+	}
+	return idx, nil
+}
+
+// Save writes the index atomically
+func (idx *Index) Save(path string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Entry returns the entry for a root-relative path
+func (idx *Index) Entry(path string) (*Entry, bool) {
+	path = filepath.ToSlash(path)
+	for _, e := range idx.Entries {
+		if e.Path == path {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Update brings the index in line with the files under root. Files whose
+// hash matches their entry are not decoded again; entries for files that
+// are gone are dropped. Hidden files and directories are skipped, which
+// keeps the index file itself out of the catalogue.
+func (idx *Index) Update(root string, opts Options) (UpdateStats, error) {
+	var stats UpdateStats
+	if opts.Load == nil {
+		opts.Load = loadSave
+	}
+	if opts.Pattern != "" {
+		if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+			return stats, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return stats, err
+	}
+	if abs != idx.Root {
+		// A catalogue moved to another directory starts over
+		idx.Entries = nil
+		idx.Root = abs
+	}
+
+	old := make(map[string]*Entry, len(idx.Entries))
+	for _, e := range idx.Entries {
+		old[e.Path] = e
+	}
+	var entries []*Entry
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if opts.Pattern != "" {
+			if ok, _ := filepath.Match(opts.Pattern, d.Name()); !ok {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		prev, known := old[rel]
+		if known && prev.Hash == hash {
+			prev.ModTime = info.ModTime()
+			entries = append(entries, prev)
+			stats.Unchanged++
+			return nil
+		}
+
+		e := &Entry{Path: rel, Hash: hash, Size: info.Size(), ModTime: info.ModTime()}
+		if err := opts.Load(path); err != nil {
+			e.Error = err.Error()
+			stats.Failed++
+		} else {
+			e.Summary = Summarize()
+		}
+		if known {
+			stats.Updated++
+		} else {
+			stats.Added++
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	stats.Removed = len(old) - stats.Unchanged - stats.Updated
+	idx.Entries = entries
+	idx.Updated = time.Now()
+	return stats, nil
+}
+
+func loadSave(path string) error {
+	return pr.New().Load(path, 0)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,234 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ffvi_editor/models/consts/pr"
+	pri "ffvi_editor/models/pr"
+)
+
+// fakeLoader "decodes" test saves written as lines of "<character> <level>",
+// putting the first four in the party. A file containing "corrupt" fails.
+type fakeLoader struct {
+	loads []string
+}
+
+func (f *fakeLoader) load(path string) error {
+	f.loads = append(f.loads, filepath.Base(path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(data), "corrupt") {
+		return errors.New("not a save")
+	}
+	for _, c := range pri.Characters {
+		c.Level, c.IsEnabled = 0, false
+	}
+	party := pri.GetParty()
+	party.Members = [4]*pri.Member{}
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		c := pri.GetCharacter(fields[0])
+		c.Level, _ = strconv.Atoi(fields[1])
+		c.IsEnabled, c.HP.Current = true, 100
+		if i < len(party.Members) {
+			party.Members[i] = &pri.Member{CharacterID: c.ID, Name: c.Name}
+		}
+	}
+	return nil
+}
+
+func writeSave(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateIsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	writeSave(t, dir, "a.sav", "Terra 20\nLocke 22")
+	writeSave(t, dir, "sub/b.sav", "Terra 40\nShadow 38")
+	writeSave(t, dir, "bad.sav", "corrupt")
+	writeSave(t, dir, ".hidden.sav", "Terra 1")
+	f := &fakeLoader{}
+
+	idx := New()
+	stats, err := idx.Update(dir, Options{Load: f.load})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (UpdateStats{Added: 3, Failed: 1}); stats != want {
+		t.Errorf("first update = %+v, want %+v", stats, want)
+	}
+	if e, ok := idx.Entry("bad.sav"); !ok || e.Error == "" || e.Summary != nil {
+		t.Errorf("bad.sav entry = %+v, want a recorded error", e)
+	}
+
+	path := IndexPath(dir)
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if idx, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSave(t, dir, "a.sav", "Terra 30\nLocke 22")
+	if err := os.Remove(filepath.Join(dir, "sub", "b.sav")); err != nil {
+		t.Fatal(err)
+	}
+	writeSave(t, dir, "c.sav", "Celes 10")
+	f.loads = nil
+	if stats, err = idx.Update(dir, Options{Load: f.load}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (UpdateStats{Added: 1, Updated: 1, Unchanged: 1, Removed: 1}); stats != want {
+		t.Errorf("second update = %+v, want %+v", stats, want)
+	}
+	if want := []string{"a.sav", "c.sav"}; !reflect.DeepEqual(f.loads, want) {
+		t.Errorf("decoded %v, want only the changed files %v", f.loads, want)
+	}
+	if e, _ := idx.Entry("a.sav"); e.Summary.PartyAverageLevel() != 26 {
+		t.Errorf("a.sav party average = %d, want 26", e.Summary.PartyAverageLevel())
+	}
+}
+
+func TestLoadMissingIndex(t *testing.T) {
+	idx, err := Load(filepath.Join(t.TempDir(), IndexFileName))
+	if err != nil || len(idx.Entries) != 0 || idx.Version != IndexVersion {
+		t.Errorf("Load(missing) = %+v, %v; want an empty index", idx, err)
+	}
+}
+
+func testIndex() *Index {
+	shadow := func(hp int) CharacterSummary {
+		return CharacterSummary{ID: 3, Name: "Shadow", Level: 20, HP: hp, Enabled: true}
+	}
+	return &Index{Version: IndexVersion, Entries: []*Entry{
+		{Path: "early.sav", Hash: "aa", Summary: &Summary{
+			Party:      []string{"Terra", "Shadow"},
+			Characters: []CharacterSummary{{ID: 1, Name: "Terra", Level: 18, HP: 300, Enabled: true}, shadow(200)},
+			Espers:     []string{"Ramuh"},
+			PlayTime:   5 * 3600, CheckpointID: "returners",
+		}},
+		{Path: "dead.sav", Hash: "bb", Summary: &Summary{
+			Party:      []string{"Terra"},
+			Characters: []CharacterSummary{{ID: 1, Name: "Terra", Level: 22, Enabled: true}, shadow(0)},
+			PlayTime:   9 * 3600, CheckpointID: "world-of-ruin",
+		}},
+		{Path: "late.sav", Hash: "cc", Summary: &Summary{
+			Party:      []string{"Celes"},
+			Characters: []CharacterSummary{{ID: 25, Name: "Celes", Level: 40, HP: 900, Enabled: true}, shadow(400)},
+			Espers:     []string{"Ramuh", "Bahamut"}, KeyItems: []string{"Cider"},
+			PlayTime: 30 * 3600, CheckpointID: "falcon",
+		}},
+		{Path: "broken.sav", Hash: "dd", Error: "not a save"},
+	}}
+}
+
+func TestSearch(t *testing.T) {
+	idx := testIndex()
+	for query, want := range map[string][]string{
+		`saves where alive("Shadow") and partyavg < 25`:      {"early.sav"},
+		`saves where joined(shadow) order by hours desc`:     {"late.sav", "dead.sav", "early.sav"},
+		`saves where owns(Ramuh) and not has(cider)`:         {"early.sav"},
+		`saves where reached("world-of-ruin")`:               {"dead.sav", "late.sav"},
+		`saves where level(Terra) >= 20 or inparty("celes")`: {"dead.sav", "late.sav"},
+		`saves order by espers desc limit 1`:                 {"late.sav"},
+	} {
+		entries, err := idx.Search(query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		got := []string{}
+		for _, e := range entries {
+			got = append(got, e.Path)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", query, got, want)
+		}
+	}
+
+	for _, query := range []string{`characters where level > 1`, `saves where bogus > 1`, `saves where reached(nowhere)`} {
+		if _, err := idx.Search(query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+// TestSearchUnverified tests queries and rows depending on unverified story
+// flags are marked
+func TestSearchUnverified(t *testing.T) {
+	idx := testIndex()
+	_, flags, err := idx.SearchUnverified(`saves where reached("returners") and not flag("phoenix-cave-cleared")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"narshe-escaped", "figaro-escaped", "returners-joined", "phoenix-cave-cleared"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("unverified = %v, want %v", flags, want)
+	}
+	if _, flags, _ := idx.SearchUnverified(`saves where partyavg < 25`); len(flags) != 0 {
+		t.Errorf("query without flags reported %v", flags)
+	}
+
+	s := idx.Entries[0].Summary
+	s.Checkpoint, s.CheckpointUnverified = "Returners", true
+	if got := idx.Entries[0].Row()["checkpoint"]; got != "Returners (unverified)" {
+		t.Errorf("checkpoint = %v, want it marked unverified", got)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testIndex().Entries); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d CSV rows, want a header and 3 saves", len(records))
+	}
+	header, early := records[0], records[1]
+	col := func(name string) string {
+		for i, h := range header {
+			if h == name {
+				return early[i]
+			}
+		}
+		t.Fatalf("no %s column", name)
+		return ""
+	}
+	if col("save") != "early.sav" || col("party") != "Terra, Shadow" || col("party_levels") != "18;20" || col("hours") != "5" {
+		t.Errorf("unexpected row %v", early)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	for _, e := range pr.Espers {
+		e.Checked = e.Name == "Ramuh"
+	}
+	t.Cleanup(func() {
+		for _, e := range pr.Espers {
+			e.Checked = false
+		}
+	})
+	if got := Summarize().Espers; !reflect.DeepEqual(got, []string{"Ramuh"}) {
+		t.Errorf("Summarize().Espers = %v, want [Ramuh]", got)
+	}
+}
@@ -0,0 +1,28 @@
+// Package catalog indexes a directory of saves so many saves can be searched
+// without loading each one.
+//
+// The catalog package handles:
+//   - Walking a directory and decoding each save via io/pr
+//   - Keeping a compact summary per save (party, levels, espers owned, key
+//     items, play time, story progress) in a JSON index file
+//   - Reindexing incrementally: a save is only decoded again when its
+//     SHA-256 hash changes
+//   - Querying the summaries with the models/search query language; story
+//     progress read from unverified flags is marked, see SearchUnverified
+//   - Exporting the summaries as CSV
+//
+// Example usage:
+//
+//	idx, err := catalog.Load(catalog.IndexPath(dir))
+//	if err != nil {
+//	    return err
+//	}
+//	stats, err := idx.Update(dir, catalog.Options{})
+//	if err != nil {
+//	    return err
+//	}
+//	if err := idx.Save(catalog.IndexPath(dir)); err != nil {
+//	    return err
+//	}
+//	entries, err := idx.Search(`saves where alive("Shadow") and partyavg < 25`)
+package catalog
@@ -0,0 +1,229 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pri "ffvi_editor/models/pr"
+	"ffvi_editor/models/search"
+)
+
+// Fields are the columns a catalogue query sees, in display order
+var Fields = []string{"save", "party", "partyavg", "maxlevel", "characters",
+	"espers", "keyitems", "hours", "gil", "complete", "checkpoint"}
+
+// Search runs a query such as
+//
+//	saves where alive("Shadow") and partyavg < 25 order by hours desc
+//
+// over the entries that decoded. Besides the Fields it can call:
+//
+//	joined(name)  the character has joined
+//	alive(name)   the character has joined, is enabled and has HP left
+//	inparty(name) the character is in the active party
+//	level(name)   the character's level, 0 if not joined
+//	owns(esper)   the esper is owned
+//	has(item)     the key item is held
+//	flag(id)      the story flag is set
+//	reached(id)   the story checkpoint has been reached
+//
+// flag and reached answer from the catalogued flag positions; see
+// SearchUnverified for telling which of those are guesses.
+func (idx *Index) Search(query string) ([]*Entry, error) {
+	entries, _, err := idx.SearchUnverified(query)
+	return entries, err
+}
+
+// SearchUnverified is Search that also returns the unverified story flags
+// the query's flag and reached calls depended on. Their positions in the
+// save have not been checked, so the matches may be wrong.
+func (idx *Index) SearchUnverified(query string) ([]*Entry, []string, error) {
+	unverified := make(map[string]bool)
+	q, err := search.ParseTableQuery(query, idx.table(unverified))
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := q.Run()
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]*Entry, len(res.Rows))
+	for i, row := range res.Rows {
+		entries[i] = row.Data.(*Entry)
+	}
+	flags := make([]string, 0, len(unverified))
+	for _, f := range pri.StoryFlags.Flags {
+		if unverified[f.ID] {
+			flags = append(flags, f.ID)
+		}
+	}
+	return entries, flags, nil
+}
+
+// Row returns the query fields of an entry that decoded. A checkpoint read
+// from unverified flags is marked as such.
+func (e *Entry) Row() map[string]interface{} {
+	s := e.Summary
+	checkpoint := s.Checkpoint
+	if checkpoint != "" && s.CheckpointUnverified {
+		checkpoint += " (unverified)"
+	}
+	maxLevel := 0
+	for _, c := range s.Characters {
+		if c.Level > maxLevel {
+			maxLevel = c.Level
+		}
+	}
+	return map[string]interface{}{
+		"save":       e.Path,
+		"party":      strings.Join(s.Party, ", "),
+		"partyavg":   s.PartyAverageLevel(),
+		"maxlevel":   maxLevel,
+		"characters": len(s.Characters),
+		"espers":     len(s.Espers),
+		"keyitems":   len(s.KeyItems),
+		"hours":      int(s.PlayTime / 3600),
+		"gil":        s.Gil,
+		"complete":   s.Complete,
+		"checkpoint": checkpoint,
+	}
+}
+
+// table is the query table over the index; flag and reached add the
+// unverified flags they consult to unverified
+func (idx *Index) table(unverified map[string]bool) *search.Table {
+	flagFunc := listFunc(func(s *Summary) []string { return s.Flags })
+	return &search.Table{
+		Name:   "saves",
+		Fields: Fields,
+		Funcs: map[string]search.TableFunc{
+			"joined": characterFunc(func(c CharacterSummary, ok bool) interface{} { return ok }),
+			"alive": characterFunc(func(c CharacterSummary, ok bool) interface{} {
+				return ok && c.Enabled && c.HP > 0
+			}),
+			"level":   characterFunc(func(c CharacterSummary, ok bool) interface{} { return c.Level }),
+			"inparty": listFunc(func(s *Summary) []string { return s.Party }),
+			"owns":    listFunc(func(s *Summary) []string { return s.Espers }),
+			"has":     listFunc(func(s *Summary) []string { return s.KeyItems }),
+			"flag": func(row *search.TableRow, args []interface{}) (interface{}, error) {
+				if id, err := search.StringArg(args); err == nil {
+					if f, ok := pri.StoryFlags.Flag(id); ok && !f.Verified {
+						unverified[f.ID] = true
+					}
+				}
+				return flagFunc(row, args)
+			},
+			"reached": func(row *search.TableRow, args []interface{}) (interface{}, error) {
+				if id, err := search.StringArg(args); err == nil {
+					for _, f := range pri.StoryFlags.UnverifiedCheckpointFlags(checkpointID(id)) {
+						unverified[f] = true
+					}
+				}
+				return reachedFunc(row, args)
+			},
+		},
+		Rows: func() []*search.TableRow {
+			var rows []*search.TableRow
+			for _, e := range idx.Entries {
+				if e.Summary != nil {
+					rows = append(rows, &search.TableRow{Values: e.Row(), Data: e})
+				}
+			}
+			return rows
+		},
+	}
+}
+
+func characterFunc(value func(c CharacterSummary, ok bool) interface{}) search.TableFunc {
+	return func(row *search.TableRow, args []interface{}) (interface{}, error) {
+		name, err := search.StringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		c, ok := row.Data.(*Entry).Summary.Character(name)
+		return value(c, ok), nil
+	}
+}
+
+func listFunc(list func(s *Summary) []string) search.TableFunc {
+	return func(row *search.TableRow, args []interface{}) (interface{}, error) {
+		name, err := search.StringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range list(row.Data.(*Entry).Summary) {
+			if strings.EqualFold(v, name) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// reachedFunc compares story progress by checkpoint order
+func reachedFunc(row *search.TableRow, args []interface{}) (interface{}, error) {
+	id, err := search.StringArg(args)
+	if err != nil {
+		return nil, err
+	}
+	target := checkpointIndex(id)
+	if target < 0 {
+		return nil, fmt.Errorf("unknown checkpoint %q", args[0])
+	}
+	return checkpointIndex(row.Data.(*Entry).Summary.CheckpointID) >= target, nil
+}
+
+// checkpointID returns the catalogued spelling of a checkpoint ID
+func checkpointID(id string) string {
+	if i := checkpointIndex(id); i >= 0 {
+		return pri.StoryFlags.Checkpoints[i].ID
+	}
+	return id
+}
+
+func checkpointIndex(id string) int {
+	for i, cp := range pri.StoryFlags.Checkpoints {
+		if strings.EqualFold(cp.ID, id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// WriteCSV writes entries as CSV, one row per save with the Fields followed
+// by the full party, esper and key item lists
+func WriteCSV(w io.Writer, entries []*Entry) error {
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, Fields...), "party_levels", "espers_owned", "key_items", "sha256")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Summary == nil {
+			continue
+		}
+		row := e.Row()
+		record := make([]string, 0, len(header))
+		for _, f := range Fields {
+			record = append(record, fmt.Sprint(row[f]))
+		}
+		var levels []string
+		for _, name := range e.Summary.Party {
+			c, _ := e.Summary.Character(name)
+			levels = append(levels, strconv.Itoa(c.Level))
+		}
+		record = append(record,
+			strings.Join(levels, ";"),
+			strings.Join(e.Summary.Espers, ";"),
+			strings.Join(e.Summary.KeyItems, ";"),
+			e.Hash)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts/pr"
+	pri "ffvi_editor/models/pr"
+)
+
+// Summary is what the catalogue keeps of a save
+type Summary struct {
+	Party      []string           `json:"party"`
+	Characters []CharacterSummary `json:"characters"`
+	Espers     []string           `json:"espers"`
+	KeyItems   []string           `json:"keyItems"`
+	PlayTime   float64            `json:"playTime"` // seconds
+	Gil        int                `json:"gil"`
+	Complete   bool               `json:"complete"`
+	// Checkpoint is the latest story checkpoint reached, empty before the first
+	Checkpoint   string `json:"checkpoint,omitempty"`
+	CheckpointID string `json:"checkpointId,omitempty"`
+	// Flags lists the IDs of the known story flags that are set
+	Flags []string `json:"flags"`
+	// UnverifiedFlags lists the flags in Flags whose position in the save
+	// has not been checked against real saves, so they may be wrong
+	UnverifiedFlags []string `json:"unverifiedFlags,omitempty"`
+	// CheckpointUnverified is set when Checkpoint was read from such flags
+	CheckpointUnverified bool `json:"checkpointUnverified,omitempty"`
+}
+
+// CharacterSummary is one character the save has joined
+type CharacterSummary struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Level   int    `json:"level"`
+	HP      int    `json:"hp"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Character returns the summary of the named character
+func (s *Summary) Character(name string) (CharacterSummary, bool) {
+	for _, c := range s.Characters {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return CharacterSummary{}, false
+}
+
+// PartyAverageLevel is the mean level of the active party, rounded down
+func (s *Summary) PartyAverageLevel() int {
+	total, n := 0, 0
+	for _, name := range s.Party {
+		if c, ok := s.Character(name); ok {
+			total += c.Level
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / n
+}
+
+// Summarize summarises the loaded save
+func Summarize() *Summary {
+	s := &Summary{
+		Party:    []string{},
+		Espers:   []string{},
+		KeyItems: []string{},
+		Flags:    []string{},
+		PlayTime: pri.GetCheats().PlayTime,
+		Gil:      models.GetMisc().GP,
+		Complete: pri.GetCheats().IsCompleteFlag,
+	}
+
+	var characters []*models.Character
+	for _, c := range pri.Characters {
+		if c != nil && c.Level > 0 {
+			characters = append(characters, c)
+		}
+	}
+	sort.SliceStable(characters, func(i, j int) bool { return characters[i].ID < characters[j].ID })
+	for _, c := range characters {
+		s.Characters = append(s.Characters, CharacterSummary{
+			ID: c.ID, Name: c.Name, Level: c.Level, HP: c.HP.Current, Enabled: c.IsEnabled,
+		})
+	}
+
+	for _, m := range pri.GetParty().Members {
+		if m != nil && m.CharacterID != 0 && m.Name != "" {
+			s.Party = append(s.Party, m.Name)
+		}
+	}
+	for _, e := range pr.Espers {
+		if e.Checked {
+			s.Espers = append(s.Espers, e.Name)
+		}
+	}
+	for _, row := range pri.GetImportantInventory().GetRows() {
+		if row != nil && row.ItemID != 0 && row.Count > 0 {
+			if name := pr.ImportantItemsByID[row.ItemID]; name != "" {
+				s.KeyItems = append(s.KeyItems, name)
+			}
+		}
+	}
+	if cp, ok := pri.GetDataStorage().CurrentCheckpoint(); ok {
+		s.Checkpoint, s.CheckpointID = cp.Name, cp.ID
+		s.CheckpointUnverified = len(pri.StoryFlags.UnverifiedCheckpointFlags("")) > 0
+	}
+	for _, f := range pri.StoryFlags.Flags {
+		if set, err := f.IsSet(pri.GetDataStorage()); err == nil && set {
+			s.Flags = append(s.Flags, f.ID)
+			if !f.Verified {
+				s.UnverifiedFlags = append(s.UnverifiedFlags, f.ID)
+			}
+		}
+	}
+	return s
+}
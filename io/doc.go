@@ -7,6 +7,11 @@
 //   - JSON import/export
 //   - Sprite and animation data
 //   - Palette editing
+//   - SNES ROM identification, copier headers and LoROM/HiROM addressing
+//   - Per-revision sprite/palette offset tables with checksummed extraction,
+//     checked against known dumps starting from an unverified draft layout
+//   - Sprite re-injection with LZ77 recompression and IPS/BPS patch output
+//   - Pixel Remaster texture mod atlases (export and import)
 //
 // Subpackages:
 //   - backup: Save file backup management
@@ -17,6 +22,7 @@
 //   - pr: Pixel Remastered save format
 //   - templates: Template I/O
 //   - validation: Save file validation
+//   - watch: React to the game writing save slots
 //
 // Example usage:
 //
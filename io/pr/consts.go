@@ -138,6 +138,17 @@ const (
 	PlayableCharacterCorpsID = "playableCharacterCorpsId"
 )
 
+// teleport cache and timer fields inside mapData
+const (
+	TeleportMapID     = "mapId"
+	TeleportPointIn   = "pointIn"
+	TeleportPosition  = "position"
+	TeleportDirection = "direction"
+	TimerID           = "timerId"
+	TimerEnable       = "isEnable"
+	TimerTime         = "time"
+)
+
 // transportation
 const (
 	TransPosition       = "position"
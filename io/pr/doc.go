@@ -27,7 +27,7 @@
 //   - loader_characters.go: Character-specific loading
 //   - loader_inventory.go: Inventory loading
 //   - loader_map.go: Map and transportation loading
-//   - loader_misc.go: Espers, stats, cheats
+//   - loader_misc.go: Espers, stats, cheats, dataStorage flags
 //   - loader_helpers.go: Helper functions
 //   - saver.go: Save file writing
 package pr
@@ -1,6 +1,8 @@
 package pr
 
 import (
+	pri "ffvi_editor/models/pr"
+
 	jo "gitlab.com/c0b/go-ordered-json"
 )
 
@@ -12,6 +14,7 @@ type PR struct {
 	Characters  []*jo.OrderedMap
 	names       []unicodeNameReplace
 	fileTrimmed []byte
+	models      *pri.State
 }
 
 func New() *PR {
@@ -23,6 +26,15 @@ func New() *PR {
 	}
 }
 
+// Models returns the save models Load filled, or the current ones for a
+// save that was not loaded
+func (p *PR) Models() *pri.State {
+	if p.models == nil {
+		return pri.CurrentState()
+	}
+	return p.models
+}
+
 func (p *PR) HasUnicodeNames() bool {
 	return len(p.names) > 0
 }
@@ -52,12 +52,15 @@ func (p *PR) loadGameData() error {
 		{"party", p.loadParty},
 		{"espers", p.loadEspers},
 		{"misc stats", p.loadMiscStats},
+		{"data storage", p.loadDataStorage},
 		{"normal inventory", func() error { return p.loadInventory(NormalOwnedItemList, pri.GetInventory()) }},
 		{"important inventory", func() error { return p.loadInventory(importantOwnedItemList, pri.GetImportantInventory()) }},
 		{"veldt", p.loadVeldt},
 		{"cheats", p.loadCheats},
 		{"map data", p.loadMapData},
 		{"transportation", p.loadTransportation},
+		{"teleport cache", p.loadTeleportCache},
+		{"timer", p.loadTimer},
 	}
 
 	for _, section := range sections {
@@ -103,6 +106,7 @@ func (p *PR) Load(fromFile string, saveType global.SaveFileType) error {
 		return err
 	}
 
+	p.models = pri.CurrentState()
 	return nil
 }
 
@@ -170,6 +170,73 @@ func (p *PR) loadTransportation() error {
 	return nil
 }
 
+// mapDataObject decodes an optional JSON object stored in mapData. ok is
+// false when the key is missing or empty.
+func (p *PR) mapDataObject(key string) (om *jo.OrderedMap, ok bool, err error) {
+	i, found := p.MapData.GetValue(key)
+	if !found || i == nil {
+		return nil, false, nil
+	}
+	switch v := i.(type) {
+	case string:
+		if v == "" {
+			return nil, false, nil
+		}
+		om = jo.NewOrderedMap()
+		if err = om.UnmarshalJSON([]byte(v)); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", key, err)
+		}
+		return om, true, nil
+	case *jo.OrderedMap:
+		return v, true, nil
+	default:
+		return nil, false, fmt.Errorf("%s: unexpected type %T", key, i)
+	}
+}
+
+// loadTeleportCache reads the Warp return point. Fields the save leaves out
+// keep their zero values.
+func (p *PR) loadTeleportCache() error {
+	c := pri.GetTeleportCache()
+	*c = pri.TeleportCache{}
+	om, ok, err := p.mapDataObject(TeleportCacheData)
+	if err != nil || !ok {
+		return err
+	}
+	c.Present = true
+	c.MapID, _ = p.getInt(om, TeleportMapID)
+	c.PointIn, _ = p.getInt(om, TeleportPointIn)
+	c.Direction, _ = p.getInt(om, TeleportDirection)
+	if pos, ok := om.Get(TeleportPosition).(*jo.OrderedMap); ok {
+		c.Position.X, _ = p.getFloat(pos, "x")
+		c.Position.Y, _ = p.getFloat(pos, "y")
+		c.Position.Z, _ = p.getFloat(pos, "z")
+	}
+	return nil
+}
+
+// loadTimer reads the running countdown, if any
+func (p *PR) loadTimer() error {
+	t := pri.GetTimer()
+	*t = pri.Timer{}
+	om, ok, err := p.mapDataObject(TimerData)
+	if err != nil || !ok {
+		return err
+	}
+	t.Present = true
+	t.ID, _ = p.getInt(om, TimerID)
+	t.Remaining, _ = p.getFloat(om, TimerTime)
+	if v, ok := om.GetValue(TimerEnable); ok {
+		switch e := v.(type) {
+		case bool:
+			t.Active = e
+		case json.Number:
+			t.Active = e.String() != "0"
+		}
+	}
+	return nil
+}
+
 func (p *PR) loadVeldt() (err error) {
 	var (
 		veldt = pri.GetVeldt()
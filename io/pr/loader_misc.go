@@ -69,6 +69,53 @@ func (p *PR) loadMiscStats() (err error) {
 	return
 }
 
+// loadDataStorage decodes the integer arrays of the dataStorage block into
+// the story flag model. Arrays holding anything other than integers are left
+// to the save file and not exposed.
+func (p *PR) loadDataStorage() (err error) {
+	d := pri.GetDataStorage()
+	d.Clear()
+	ds, ok := p.Base.GetValue(DataStorage)
+	if !ok {
+		return
+	}
+	m := jo.NewOrderedMap()
+	if err = m.UnmarshalJSON([]byte(ds.(string))); err != nil {
+		return
+	}
+	iter := m.EntriesIter()
+	for {
+		kv, ok := iter()
+		if !ok {
+			break
+		}
+		if values, ok := decodeIntSlice(kv.Value); ok {
+			d.Arrays[kv.Key] = values
+		}
+	}
+	return
+}
+
+func decodeIntSlice(v interface{}) ([]int, bool) {
+	sl, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values := make([]int, len(sl))
+	for i, n := range sl {
+		num, ok := n.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		i64, err := num.Int64()
+		if err != nil {
+			return nil, false
+		}
+		values[i] = int(i64)
+	}
+	return values, true
+}
+
 func (p *PR) loadCheats() (err error) {
 	c := pri.GetCheats()
 	if c.OpenedChestCount, err = p.getInt(p.UserData, OpenChestCount); err != nil {
@@ -1,6 +1,7 @@
 package pr
 
 import (
+	"strings"
 	"testing"
 
 	"ffvi_editor/models"
@@ -445,3 +446,83 @@ func BenchmarkCharacterModification(b *testing.B) {
 		testChar.Vigor = i % 256
 	}
 }
+
+// TestDataStorageRoundTrip tests that story flags survive a load and save
+// and that the cursed shield count keeps its slot
+func TestDataStorageRoundTrip(t *testing.T) {
+	helpers := NewTestHelpers(t)
+	p := New()
+	p.Base = helpers.CreateOrderedMap(`{"dataStorage": "{\"global\": [0,0,0,0,0,0,0,0,0,7], \"scenario\": [0,0,0,0], \"names\": [\"a\"]}"}`)
+	p.UserData = helpers.CreateOrderedMap(`{"owendGil":0,"steps":0,"escapeCount":0,"battleCount":0,"saveCompleteCount":0,"monstersKilledCount":0}`)
+
+	helpers.AssertNoError(p.loadDataStorage(), "loadDataStorage")
+	d := pri.GetDataStorage()
+	if _, ok := d.Arrays["names"]; ok {
+		t.Error("non-integer arrays should not be decoded")
+	}
+	helpers.AssertNoError(d.Set(pri.DataStorageScenario, 2, 1), "Set")
+
+	models.GetMisc().CursedShieldFightCount = 12
+	helpers.AssertNoError(p.saveDataStorage(), "saveDataStorage")
+	helpers.AssertNoError(p.saveMiscStats(), "saveMiscStats")
+
+	helpers.AssertNoError(p.loadDataStorage(), "loadDataStorage (reload)")
+	if v, _ := d.Get(pri.DataStorageScenario, 2); v != 1 {
+		t.Errorf("scenario[2] = %d after round trip, want 1", v)
+	}
+	if v, _ := d.Get(pri.DataStorageGlobal, 9); v != 12 {
+		t.Errorf("global[9] = %d, want the cursed shield count 12", v)
+	}
+	ds, _ := p.Base.GetValue(DataStorage)
+	if !strings.Contains(ds.(string), `"names":["a"]`) {
+		t.Errorf("other dataStorage keys should be kept: %s", ds)
+	}
+}
+
+// TestTeleportCacheAndTimerRoundTrip tests the Warp cache and countdown keep
+// unmodeled fields and value types through a save
+func TestTeleportCacheAndTimerRoundTrip(t *testing.T) {
+	helpers := NewTestHelpers(t)
+	p := New()
+	p.MapData = helpers.CreateOrderedMap(`{
+		"telepoCacheData": "{\"mapId\":21,\"pointIn\":3,\"position\":{\"x\":1,\"y\":2,\"z\":3},\"direction\":2,\"extra\":7}",
+		"timerData": "{\"timerId\":0,\"isEnable\":0,\"time\":0}"
+	}`)
+
+	helpers.AssertNoError(p.loadTeleportCache(), "loadTeleportCache")
+	helpers.AssertNoError(p.loadTimer(), "loadTimer")
+	cache, timer := pri.GetTeleportCache(), pri.GetTimer()
+	if !cache.Present || cache.MapID != 21 || cache.PointIn != 3 || cache.Position.Z != 3 {
+		t.Fatalf("teleport cache = %+v", cache)
+	}
+	if !timer.Present || timer.Active {
+		t.Fatalf("timer = %+v", timer)
+	}
+
+	cache.MapID = 4
+	helpers.AssertNoError(timer.Start(2, 120), "Start")
+	helpers.AssertNoError(p.saveTeleportCache(), "saveTeleportCache")
+	helpers.AssertNoError(p.saveTimer(), "saveTimer")
+
+	tc, _ := p.MapData.GetValue(TeleportCacheData)
+	if !strings.Contains(tc.(string), `"mapId":4`) || !strings.Contains(tc.(string), `"extra":7`) {
+		t.Errorf("telepoCacheData = %s", tc)
+	}
+	td, _ := p.MapData.GetValue(TimerData)
+	if !strings.Contains(td.(string), `"isEnable":1`) {
+		t.Errorf("timerData should keep its numeric enable flag: %s", td)
+	}
+
+	helpers.AssertNoError(p.loadTimer(), "loadTimer (reload)")
+	if !timer.Active || timer.ID != 2 || timer.Remaining != 120 {
+		t.Errorf("timer after round trip = %+v", timer)
+	}
+
+	// Saves without the keys load as absent and are left alone
+	p.MapData = helpers.CreateOrderedMap(`{}`)
+	helpers.AssertNoError(p.loadTimer(), "loadTimer (missing)")
+	helpers.AssertNoError(p.saveTimer(), "saveTimer (missing)")
+	if timer.Present || p.MapData.Has(TimerData) {
+		t.Error("missing timerData should stay missing")
+	}
+}
@@ -34,6 +34,9 @@ func (p *PR) Save(slot int, toFile string, saveType global.SaveFileType) (err er
 	if err = p.saveEspers(); err != nil {
 		return
 	}
+	if err = p.saveDataStorage(); err != nil {
+		return
+	}
 	if err = p.saveMiscStats(); err != nil {
 		return
 	}
@@ -43,6 +46,12 @@ func (p *PR) Save(slot int, toFile string, saveType global.SaveFileType) (err er
 	if err = p.saveMapData(); err != nil {
 		return
 	}
+	if err = p.saveTeleportCache(); err != nil {
+		return
+	}
+	if err = p.saveTimer(); err != nil {
+		return
+	}
 	if pri.GetParty().Enabled {
 		if err = p.saveParty(); err != nil {
 			return
@@ -679,6 +688,37 @@ func (p *PR) saveMiscStats() (err error) {
 	return
 }
 
+// saveDataStorage writes the story flag model back into the dataStorage
+// block. Only arrays that were decoded are replaced; everything else in the
+// block is kept as loaded. It runs before saveMiscStats so the cursed shield
+// count in Misc stays authoritative for its slot.
+func (p *PR) saveDataStorage() (err error) {
+	ds, ok := p.Base.GetValue(DataStorage)
+	if !ok {
+		return
+	}
+	m := jo.NewOrderedMap()
+	if err = m.UnmarshalJSON([]byte(ds.(string))); err != nil {
+		return
+	}
+	for name, values := range pri.GetDataStorage().Arrays {
+		if _, found := m.GetValue(name); !found {
+			continue
+		}
+		sl := make([]interface{}, len(values))
+		for i, v := range values {
+			sl[i] = v
+		}
+		m.Set(name, sl)
+	}
+	var b []byte
+	if b, err = m.MarshalJSON(); err != nil {
+		return
+	}
+	p.Base.Set(DataStorage, string(b))
+	return
+}
+
 func (p *PR) saveTransportation() (err error) {
 	v := make([]interface{}, len(pri.Transportations))
 	for i, t := range pri.Transportations {
@@ -710,6 +750,73 @@ func (p *PR) saveTransportation() (err error) {
 	return p.setTarget(p.UserData, OwnedTransportationList, v)
 }
 
+// setMapDataObject stores om under key in the form the save used
+func (p *PR) setMapDataObject(key string, om *jo.OrderedMap) error {
+	if _, ok := p.MapData.Get(key).(*jo.OrderedMap); ok {
+		p.MapData.Set(key, om)
+		return nil
+	}
+	return p.marshalTo(p.MapData, key, om)
+}
+
+// saveTeleportCache writes the Warp return point back over the loaded
+// object, keeping fields the model does not cover
+func (p *PR) saveTeleportCache() error {
+	c := pri.GetTeleportCache()
+	if !c.Present {
+		return nil
+	}
+	om, ok, err := p.mapDataObject(TeleportCacheData)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		om = jo.NewOrderedMap()
+	}
+	pos, ok := om.Get(TeleportPosition).(*jo.OrderedMap)
+	if !ok {
+		pos = jo.NewOrderedMap()
+	}
+	pos.Set("x", c.Position.X)
+	pos.Set("y", c.Position.Y)
+	pos.Set("z", c.Position.Z)
+	om.Set(TeleportMapID, c.MapID)
+	om.Set(TeleportPointIn, c.PointIn)
+	om.Set(TeleportPosition, pos)
+	om.Set(TeleportDirection, c.Direction)
+	return p.setMapDataObject(TeleportCacheData, om)
+}
+
+// saveTimer writes the countdown state back over the loaded object
+func (p *PR) saveTimer() error {
+	t := pri.GetTimer()
+	if !t.Present {
+		return nil
+	}
+	om, ok, err := p.mapDataObject(TimerData)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		om = jo.NewOrderedMap()
+	}
+	if t.Remaining < 0 {
+		t.Remaining = 0
+	}
+	om.Set(TimerID, t.ID)
+	if _, numeric := om.Get(TimerEnable).(json.Number); numeric {
+		enable := 0
+		if t.Active {
+			enable = 1
+		}
+		om.Set(TimerEnable, enable)
+	} else {
+		om.Set(TimerEnable, t.Active)
+	}
+	om.Set(TimerTime, t.Remaining)
+	return p.setMapDataObject(TimerData, om)
+}
+
 func (p *PR) saveMapData() (err error) {
 	md := pri.GetMapData()
 	if err = p.setValue(p.MapData, MapID, md.MapID); err != nil {
@@ -0,0 +1,631 @@
+package io
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"ffvi_editor/models"
+)
+
+// Pixel Remaster texture-replacement mods are a folder per mod holding
+// replacement PNGs at the game's asset paths; the mod loader only looks at
+// those files. The frame layout of an atlas is not part of that format, so
+// the editor keeps its own metadata under PRModMetaDir, which loaders ignore:
+//
+//	<mod>/<asset path>/<name>.png
+//	<mod>/.ffvi_editor/mod.json
+//	<mod>/.ffvi_editor/<asset path>/<name>.json
+//
+// The PNG only replaces the game's texture if its frame grid matches the
+// texture it stands in for; pick Columns and Scale to match. Mods made with
+// other tools have only the PNGs: ImportPlainPRMod reads those with a frame
+// grid and scale given by the caller.
+
+// PRModMetaDir holds the editor's metadata inside a mod folder
+const PRModMetaDir = ".ffvi_editor"
+
+// PRModManifestFile is the editor's manifest inside PRModMetaDir
+const PRModManifestFile = "mod.json"
+
+// PRModManifest describes a mod folder
+type PRModManifest struct {
+	Name        string          `json:"name"`
+	Author      string          `json:"author,omitempty"`
+	Version     string          `json:"version,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Atlases     []PRModAtlasRef `json:"atlases"`
+}
+
+// PRModAtlasRef points at an atlas's metadata, relative to PRModMetaDir
+type PRModAtlasRef struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// PRModAtlas is the metadata written beside an atlas PNG
+type PRModAtlas struct {
+	Name string `json:"name"`
+	// Texture is the PNG's path relative to the mod folder
+	Texture string `json:"texture"`
+	// Scale is texture pixels per SNES pixel
+	Scale        int          `json:"scale"`
+	PlaybackMode string       `json:"playbackMode"`
+	Pivot        PRModPivot   `json:"pivot"`
+	Frames       []PRModFrame `json:"frames"`
+}
+
+// PRModPivot is the sprite origin as a fraction of the frame, from the
+// bottom-left like Unity sprites
+type PRModPivot struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// PRModFrame is one frame's rectangle in the atlas, in texture pixels
+type PRModFrame struct {
+	Name       string `json:"name"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	DurationMs int    `json:"durationMs"`
+}
+
+// PRModExportOptions configures ExportPRMod
+type PRModExportOptions struct {
+	ModName string
+	Author  string
+	Version string
+	// AssetPath is the folder inside the mod the atlas goes to, mirroring
+	// the game's asset path for the texture being replaced
+	AssetPath string
+	// AtlasName names the PNG and JSON files; defaults to the animation name
+	AtlasName string
+	// Scale is texture pixels per SNES pixel. It has no default: the
+	// remaster's sprite scale has not been checked against the game files
+	Scale   int
+	Columns int // frames per atlas row; defaults to all frames in one row
+}
+
+// SplitSpriteFrames returns one single-frame sprite per frame of a sprite
+// whose Data holds several frames back to back
+func SplitSpriteFrames(sprite *models.FF6Sprite) ([]*models.FF6Sprite, error) {
+	frames := sprite.Frames
+	if frames < 1 {
+		frames = 1
+	}
+	size := sprite.Width * sprite.Height / 2
+	if size == 0 || len(sprite.Data) < size*frames {
+		return nil, fmt.Errorf("sprite has %d bytes, need %d for %d %dx%d frames",
+			len(sprite.Data), size*frames, frames, sprite.Width, sprite.Height)
+	}
+
+	list := make([]*models.FF6Sprite, frames)
+	for i := range list {
+		f := sprite.Clone()
+		f.ID = fmt.Sprintf("%s_%d", sprite.ID, i)
+		f.Frames = 1
+		f.Data = append([]byte(nil), sprite.Data[i*size:(i+1)*size]...)
+		list[i] = f
+	}
+	return list, nil
+}
+
+// ExportPRModSprite exports a sprite's frames as a mod atlas
+func ExportPRModSprite(dir string, sprite *models.FF6Sprite, opts PRModExportOptions) error {
+	frames, err := SplitSpriteFrames(sprite)
+	if err != nil {
+		return err
+	}
+	anim := models.NewAnimationData(frames)
+	anim.Metadata.Name = sprite.Name
+	anim.Metadata.Author = sprite.Author
+	return ExportPRMod(dir, anim, opts)
+}
+
+// ExportPRMod writes an animation as an atlas PNG and metadata JSON under
+// dir, and adds the atlas to the mod manifest, creating it if needed
+func ExportPRMod(dir string, anim *models.AnimationData, opts PRModExportOptions) error {
+	if anim == nil || len(anim.Frames) == 0 {
+		return fmt.Errorf("animation is empty")
+	}
+	if opts.Scale < 1 {
+		return fmt.Errorf("scale must be set to the texture's pixels per SNES pixel")
+	}
+	if opts.Columns < 1 || opts.Columns > len(anim.Frames) {
+		opts.Columns = len(anim.Frames)
+	}
+	name := opts.AtlasName
+	if name == "" {
+		name = anim.Metadata.Name
+	}
+	name = prModFileName(name)
+	if name == "" {
+		return fmt.Errorf("atlas needs a name")
+	}
+	if !insidePRMod(opts.AssetPath) {
+		return fmt.Errorf("asset path %q leaves the mod folder", opts.AssetPath)
+	}
+	assetPath := filepath.ToSlash(filepath.Clean(opts.AssetPath))
+
+	var images []*image.RGBA
+	for i, frame := range anim.Frames {
+		rgba, err := decodeSpriteToRGBA(frame)
+		if err != nil {
+			return fmt.Errorf("failed to decode frame %d: %w", i, err)
+		}
+		images = append(images, scaleImageRGBA(rgba, opts.Scale))
+	}
+
+	cellW, cellH := 0, 0
+	for _, img := range images {
+		cellW, cellH = max(cellW, img.Bounds().Dx()), max(cellH, img.Bounds().Dy())
+	}
+	rows := (len(images) + opts.Columns - 1) / opts.Columns
+	atlasImg := image.NewRGBA(image.Rect(0, 0, cellW*opts.Columns, cellH*rows))
+
+	atlas := PRModAtlas{
+		Name:         name,
+		Texture:      path.Join(assetPath, name+".png"),
+		Scale:        opts.Scale,
+		PlaybackMode: anim.PlaybackMode.String(),
+		Pivot:        PRModPivot{X: 0.5, Y: 0},
+	}
+	for i, img := range images {
+		x, y := (i%opts.Columns)*cellW, (i/opts.Columns)*cellH
+		b := img.Bounds()
+		draw.Draw(atlasImg, image.Rect(x, y, x+b.Dx(), y+b.Dy()), img, b.Min, draw.Src)
+		duration := 100
+		if i < len(anim.FrameTimings) {
+			duration = anim.FrameTimings[i]
+		}
+		atlas.Frames = append(atlas.Frames, PRModFrame{
+			Name: fmt.Sprintf("%s_%02d", name, i), X: x, Y: y, Width: b.Dx(), Height: b.Dy(), DurationMs: duration,
+		})
+	}
+
+	texture := filepath.Join(dir, filepath.FromSlash(atlas.Texture))
+	metaDir := filepath.Join(dir, PRModMetaDir, filepath.FromSlash(assetPath))
+	for _, d := range []string{filepath.Dir(texture), metaDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	file, err := os.Create(texture)
+	if err != nil {
+		return fmt.Errorf("failed to create atlas: %w", err)
+	}
+	if err = png.Encode(file, atlasImg); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode atlas: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = writePRModJSON(filepath.Join(metaDir, name+".json"), atlas); err != nil {
+		return err
+	}
+
+	manifest, err := ReadPRModManifest(dir)
+	if os.IsNotExist(err) {
+		manifest, err = &PRModManifest{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if opts.ModName != "" {
+		manifest.Name = opts.ModName
+	}
+	if manifest.Name == "" {
+		manifest.Name = filepath.Base(dir)
+	}
+	if opts.Author != "" {
+		manifest.Author = opts.Author
+	}
+	if opts.Version != "" {
+		manifest.Version = opts.Version
+	}
+	ref := PRModAtlasRef{Name: name, Path: path.Join(assetPath, name+".json")}
+	replaced := false
+	for i := range manifest.Atlases {
+		if manifest.Atlases[i].Path == ref.Path {
+			manifest.Atlases[i], replaced = ref, true
+		}
+	}
+	if !replaced {
+		manifest.Atlases = append(manifest.Atlases, ref)
+	}
+	return writePRModJSON(filepath.Join(dir, PRModMetaDir, PRModManifestFile), manifest)
+}
+
+// insidePRMod reports whether a slash or OS separated path relative to the
+// mod folder stays inside it. Names that merely start with ".." are fine.
+func insidePRMod(rel string) bool {
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ReadPRModManifest reads the editor's manifest from a mod folder
+func ReadPRModManifest(dir string) (*PRModManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PRModMetaDir, PRModManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m PRModManifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PRModManifestFile, err)
+	}
+	return &m, nil
+}
+
+// PRModAsset is an atlas imported from a mod folder
+type PRModAsset struct {
+	Ref       PRModAtlasRef
+	Atlas     *PRModAtlas
+	Animation *models.AnimationData
+}
+
+// ImportPRMod imports every atlas listed in a mod folder's manifest. Only
+// mods exported by the editor have one; the game's own format does not
+// record frame layouts, so other mods are read with ImportPlainPRMod.
+func ImportPRMod(dir string) (*PRModManifest, []*PRModAsset, error) {
+	manifest, err := ReadPRModManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	metaDir := filepath.Join(dir, PRModMetaDir)
+	var assets []*PRModAsset
+	for _, ref := range manifest.Atlases {
+		jsonPath := filepath.Join(metaDir, filepath.FromSlash(ref.Path))
+		rel, err := filepath.Rel(metaDir, jsonPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, nil, fmt.Errorf("atlas %q leaves the mod folder", ref.Path)
+		}
+		anim, atlas, err := ImportPRModAtlas(jsonPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", ref.Path, err)
+		}
+		assets = append(assets, &PRModAsset{Ref: ref, Atlas: atlas, Animation: anim})
+	}
+	return manifest, assets, nil
+}
+
+// ImportPRModAtlas reads an atlas's metadata and PNG and converts its frames
+// back into SNES-resolution 4bpp sprites sharing one palette. Colour 0 is
+// transparent; the 15 most common opaque colours fill the rest. jsonPath
+// must lie under a mod folder's PRModMetaDir.
+func ImportPRModAtlas(jsonPath string) (*models.AnimationData, *PRModAtlas, error) {
+	root, err := prModRoot(jsonPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var atlas PRModAtlas
+	if err = json.Unmarshal(data, &atlas); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse atlas: %w", err)
+	}
+	if len(atlas.Frames) == 0 {
+		return nil, nil, fmt.Errorf("atlas %s has no frames", atlas.Name)
+	}
+	if atlas.Scale < 1 {
+		atlas.Scale = 1
+	}
+	img, err := readPRModTexture(root, atlas.Texture)
+	if err != nil {
+		return nil, nil, err
+	}
+	anim, err := prModAnimation(img, &atlas, filepath.Join(root, filepath.FromSlash(atlas.Texture)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return anim, &atlas, nil
+}
+
+// PRModGrid is the frame layout of a mod PNG that has no editor metadata.
+// Frames fill the grid row by row.
+type PRModGrid struct {
+	Columns int
+	Rows    int
+	// Frames is how many cells hold frames; defaults to Columns * Rows
+	Frames int
+	// Scale is texture pixels per SNES pixel
+	Scale      int
+	DurationMs int // per frame; defaults to 100
+}
+
+// ImportPRModTexture imports a PNG from a mod folder without the editor's
+// metadata, cutting it into frames with grid. texture is the PNG's path
+// relative to dir, i.e. the game's asset path.
+func ImportPRModTexture(dir, texture string, grid PRModGrid) (*models.AnimationData, *PRModAtlas, error) {
+	if grid.Columns < 1 || grid.Rows < 1 {
+		return nil, nil, fmt.Errorf("frame grid needs columns and rows")
+	}
+	if grid.Scale < 1 {
+		return nil, nil, fmt.Errorf("scale must be set to the texture's pixels per SNES pixel")
+	}
+	cells := grid.Columns * grid.Rows
+	if grid.Frames < 1 || grid.Frames > cells {
+		grid.Frames = cells
+	}
+	if grid.DurationMs < 1 {
+		grid.DurationMs = 100
+	}
+	img, err := readPRModTexture(dir, texture)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := img.Bounds()
+	if b.Dx()%grid.Columns != 0 || b.Dy()%grid.Rows != 0 {
+		return nil, nil, fmt.Errorf("%dx%d texture does not split into %d columns and %d rows", b.Dx(), b.Dy(), grid.Columns, grid.Rows)
+	}
+	cellW, cellH := b.Dx()/grid.Columns, b.Dy()/grid.Rows
+	if cellW%grid.Scale != 0 || cellH%grid.Scale != 0 {
+		return nil, nil, fmt.Errorf("%dx%d frames are not a multiple of scale %d", cellW, cellH, grid.Scale)
+	}
+
+	texture = filepath.ToSlash(filepath.Clean(texture))
+	name := strings.TrimSuffix(path.Base(texture), path.Ext(texture))
+	atlas := PRModAtlas{Name: name, Texture: texture, Scale: grid.Scale,
+		PlaybackMode: models.PlayContinuous.String(), Pivot: PRModPivot{X: 0.5, Y: 0}}
+	for i := 0; i < grid.Frames; i++ {
+		atlas.Frames = append(atlas.Frames, PRModFrame{Name: fmt.Sprintf("%s_%02d", name, i),
+			X: b.Min.X + i%grid.Columns*cellW, Y: b.Min.Y + i/grid.Columns*cellH, Width: cellW, Height: cellH, DurationMs: grid.DurationMs})
+	}
+	anim, err := prModAnimation(img, &atlas, filepath.Join(dir, filepath.FromSlash(texture)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return anim, &atlas, nil
+}
+
+// ImportPlainPRMod imports every PNG under a mod folder made without the
+// editor, e.g. one downloaded from a mod site, cutting each with the same
+// grid. Their Ref has no metadata Path; Atlas.Texture is the asset path.
+func ImportPlainPRMod(dir string, grid PRModGrid) ([]*PRModAsset, error) {
+	var textures []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == PRModMetaDir {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".png") {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			textures = append(textures, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(textures) == 0 {
+		return nil, fmt.Errorf("%s holds no PNG textures", dir)
+	}
+
+	var assets []*PRModAsset
+	for _, texture := range textures {
+		anim, atlas, err := ImportPRModTexture(dir, texture, grid)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.ToSlash(texture), err)
+		}
+		assets = append(assets, &PRModAsset{Ref: PRModAtlasRef{Name: atlas.Name}, Atlas: atlas, Animation: anim})
+	}
+	return assets, nil
+}
+
+// readPRModTexture decodes a PNG given by its path relative to the mod folder
+func readPRModTexture(root, texture string) (image.Image, error) {
+	if !insidePRMod(texture) {
+		return nil, fmt.Errorf("texture %q leaves the mod folder", texture)
+	}
+	file, err := os.Open(filepath.Join(root, filepath.FromSlash(texture)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open atlas texture: %w", err)
+	}
+	img, err := png.Decode(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode atlas texture: %w", err)
+	}
+	return img, nil
+}
+
+// prModAnimation converts the frames atlas lays out in img into sprites
+func prModAnimation(img image.Image, atlas *PRModAtlas, texture string) (*models.AnimationData, error) {
+
+	var frameImages []*image.RGBA
+	for _, f := range atlas.Frames {
+		rect := image.Rect(f.X, f.Y, f.X+f.Width, f.Y+f.Height)
+		if !rect.In(img.Bounds()) {
+			return nil, fmt.Errorf("frame %s lies outside the %dx%d texture", f.Name, img.Bounds().Dx(), img.Bounds().Dy())
+		}
+		frameImages = append(frameImages, downscaleNearest(img, rect, atlas.Scale))
+	}
+
+	palette := prModPalette(frameImages)
+	palette.Name = atlas.Name
+
+	conv := NewFF6SpriteConverter()
+	sprites := make([]*models.FF6Sprite, len(frameImages))
+	for i, fi := range frameImages {
+		w, h := fi.Bounds().Dx(), fi.Bounds().Dy()
+		// Pad to whole tiles; the sprite format has no partial tiles
+		tw, th := (w+7)/8*8, (h+7)/8*8
+		padded := image.NewRGBA(image.Rect(0, 0, tw, th))
+		draw.Draw(padded, fi.Bounds(), fi, image.Point{}, draw.Src)
+
+		s := models.NewSprite(atlas.Frames[i].Name, atlas.Frames[i].Name, spriteTypeForSize(tw, th))
+		s.Width, s.Height = tw, th
+		s.Data = conv.encodeToTiles(indexOpaque(padded, palette), tw, th)
+		s.Palette = palette
+		s.ImportedFrom = "Pixel Remaster mod"
+		s.SourceFile = texture
+		s.ImportDate = time.Now()
+		sprites[i] = s
+	}
+
+	anim := models.NewAnimationData(sprites)
+	anim.Metadata.Name = atlas.Name
+	for i, f := range atlas.Frames {
+		if f.DurationMs > 0 {
+			anim.FrameTimings[i] = f.DurationMs
+		}
+	}
+	anim.Metadata.TotalDuration = anim.GetTotalDuration()
+	switch atlas.PlaybackMode {
+	case models.PlayOnce.String():
+		anim.PlaybackMode = models.PlayOnce
+	case models.PlayPingPong.String():
+		anim.PlaybackMode = models.PlayPingPong
+	}
+	return anim, nil
+}
+
+// prModRoot finds the mod folder holding an atlas's metadata file
+func prModRoot(jsonPath string) (string, error) {
+	abs, err := filepath.Abs(jsonPath)
+	if err != nil {
+		return "", err
+	}
+	for dir := filepath.Dir(abs); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == PRModMetaDir {
+			return filepath.Dir(dir), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not inside a mod's %s folder", jsonPath, PRModMetaDir)
+}
+
+// downscaleNearest shrinks a region by an integer factor, sampling the
+// centre of each block so anti-aliased edges do not bleed in
+func downscaleNearest(img image.Image, rect image.Rectangle, scale int) *image.RGBA {
+	w, h := rect.Dx()/scale, rect.Dy()/scale
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Set(x, y, img.At(rect.Min.X+x*scale+scale/2, rect.Min.Y+y*scale+scale/2))
+		}
+	}
+	return out
+}
+
+// prModPalette builds a 16-colour palette with colour 0 left for
+// transparency and the 15 most common opaque colours after it. Colours are
+// rounded to 5 bits so SNES colours exported earlier come back exactly.
+func prModPalette(frames []*image.RGBA) *models.Palette {
+	counts := make(map[models.RGB555]int)
+	for _, f := range frames {
+		b := f.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := f.RGBAAt(x, y)
+				if c.A < 128 {
+					continue
+				}
+				counts[models.RGB555{R: round5(c.R), G: round5(c.G), B: round5(c.B)}]++
+			}
+		}
+	}
+
+	colors := make([]models.RGB555, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+		a, b := colors[i], colors[j]
+		return uint16(a.R)|uint16(a.G)<<5|uint16(a.B)<<10 < uint16(b.R)|uint16(b.G)<<5|uint16(b.B)<<10
+	})
+
+	palette := models.NewPalette("")
+	for i := 0; i < len(colors) && i < 15; i++ {
+		palette.Colors[i+1] = colors[i]
+	}
+	return palette
+}
+
+// indexOpaque packs an image into 4bpp palette indices like imageToIndexed,
+// but keeps colour 0 for transparent pixels so opaque black stays visible
+func indexOpaque(img *image.RGBA, palette *models.Palette) []byte {
+	b := img.Bounds()
+	data := make([]byte, (b.Dx()*b.Dy()+1)/2)
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			idx := 0
+			if c.A >= 128 {
+				want := models.RGB555{R: round5(c.R), G: round5(c.G), B: round5(c.B)}
+				best := -1
+				for p := 1; p < 16; p++ {
+					pc := palette.Colors[p]
+					dr, dg, db := int(pc.R)-int(want.R), int(pc.G)-int(want.G), int(pc.B)-int(want.B)
+					if d := dr*dr + dg*dg + db*db; best < 0 || d < best {
+						best, idx = d, p
+					}
+				}
+			}
+			if i%2 == 0 {
+				data[i/2] |= byte(idx)
+			} else {
+				data[i/2] |= byte(idx) << 4
+			}
+			i++
+		}
+	}
+	return data
+}
+
+func round5(v uint8) uint8 {
+	return uint8((int(v)*31 + 127) / 255)
+}
+
+func spriteTypeForSize(w, h int) models.SpriteType {
+	for _, t := range []models.SpriteType{models.SpriteTypeCharacter, models.SpriteTypeBattle, models.SpriteTypePortrait, models.SpriteTypeOverworld} {
+		if tw, th := t.GetDimensions(); tw == w && th == h {
+			return t
+		}
+	}
+	return models.SpriteTypeNPC
+}
+
+// prModFileName keeps names safe to use as file names inside the mod
+func prModFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r == ' ' || r == '.':
+			return '_'
+		}
+		return -1
+	}, name)
+}
+
+func writePRModJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
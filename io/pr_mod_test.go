@@ -0,0 +1,155 @@
+package io
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ffvi_editor/models"
+)
+
+// testModSprite returns a 3 frame field sprite whose frames use different
+// colours, including opaque black
+func testModSprite() *models.FF6Sprite {
+	palette := models.NewPalette("Test")
+	palette.Colors[1] = models.RGB555{R: 31, G: 0, B: 0}
+	palette.Colors[2] = models.RGB555{R: 0, G: 0, B: 0}
+	palette.Colors[3] = models.RGB555{R: 10, G: 20, B: 30}
+
+	sprite := models.NewSprite("terra", "Terra Edit", models.SpriteTypeCharacter)
+	sprite.Frames = 3
+	sprite.Palette = palette
+	for f := 0; f < 3; f++ {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 24))
+		for y := 2; y < 22; y++ {
+			for x := 4; x < 12; x++ {
+				img.Set(x, y, palette.Colors[1+(f+x+y)%3].ToColor())
+			}
+		}
+		data, _ := NewFF6SpriteConverter().EncodeFrames(img, palette, 16, 24, 1)
+		sprite.Data = append(sprite.Data, data...)
+	}
+	return sprite
+}
+
+func TestPRModRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	sprite := testModSprite()
+	opts := PRModExportOptions{ModName: "Fire Terra", Author: "tester", AssetPath: "Assets/Field/pc001", Scale: 4, Columns: 2}
+	if err := ExportPRModSprite(dir, sprite, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".ffvi_editor/mod.json", "Assets/Field/pc001/Terra_Edit.png", ".ffvi_editor/Assets/Field/pc001/Terra_Edit.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+
+	manifest, assets, err := ImportPRMod(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Name != "Fire Terra" || len(assets) != 1 {
+		t.Fatalf("manifest %+v with %d assets", manifest, len(assets))
+	}
+	atlas := assets[0].Atlas
+	if atlas.Scale != 4 || len(atlas.Frames) != 3 || atlas.Frames[2].Y != 24*4 {
+		t.Errorf("atlas layout: scale %d, frames %+v", atlas.Scale, atlas.Frames)
+	}
+
+	anim := assets[0].Animation
+	originals, _ := SplitSpriteFrames(sprite)
+	conv := NewFF6SpriteConverter()
+	for i, frame := range anim.Frames {
+		if frame.Width != 16 || frame.Height != 24 || frame.Type != models.SpriteTypeCharacter {
+			t.Fatalf("frame %d is %dx%d %s", i, frame.Width, frame.Height, frame.Type)
+		}
+		got, _ := conv.DecodeFF6Sprite(frame)
+		want, _ := conv.DecodeFF6Sprite(originals[i])
+		for y := 0; y < 24; y++ {
+			for x := 0; x < 16; x++ {
+				if got.At(x, y) != want.At(x, y) {
+					t.Fatalf("frame %d pixel %d,%d = %v, want %v", i, x, y, got.At(x, y), want.At(x, y))
+				}
+			}
+		}
+	}
+
+	// Exporting again under another name adds to the same manifest
+	opts.AtlasName = "terra_battle"
+	if err := ExportPRModSprite(dir, sprite, opts); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := ReadPRModManifest(dir); len(m.Atlases) != 2 {
+		t.Errorf("manifest has %d atlases after a second export", len(m.Atlases))
+	}
+
+	opts.AssetPath = "../outside"
+	if err := ExportPRModSprite(dir, sprite, opts); err == nil {
+		t.Error("asset paths leaving the mod folder should be rejected")
+	}
+	opts.AssetPath = "..textures"
+	if err := ExportPRModSprite(dir, sprite, opts); err != nil {
+		t.Errorf("a folder name starting with .. should be allowed: %v", err)
+	}
+	opts.AssetPath, opts.Scale = "Assets/Field/pc001", 0
+	if err := ExportPRModSprite(dir, sprite, opts); err == nil {
+		t.Error("export without a scale should fail")
+	}
+}
+
+// TestImportPlainPRMod imports a mod folder holding only PNGs, the way mods
+// made with other tools ship, with the frame grid given by the caller
+func TestImportPlainPRMod(t *testing.T) {
+	dir := t.TempDir()
+	sprite := testModSprite()
+	opts := PRModExportOptions{AssetPath: "Assets/Field/pc001", Scale: 4, Columns: 2}
+	if err := ExportPRModSprite(dir, sprite, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, PRModMetaDir)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ImportPRMod(dir); err == nil {
+		t.Error("ImportPRMod should need the editor's manifest")
+	}
+
+	assets, err := ImportPlainPRMod(dir, PRModGrid{Columns: 2, Rows: 2, Frames: 3, Scale: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].Atlas.Texture != "Assets/Field/pc001/Terra_Edit.png" {
+		t.Fatalf("imported %d assets: %+v", len(assets), assets[0].Atlas)
+	}
+	anim := assets[0].Animation
+	originals, _ := SplitSpriteFrames(sprite)
+	conv := NewFF6SpriteConverter()
+	if len(anim.Frames) != len(originals) {
+		t.Fatalf("%d frames, want %d", len(anim.Frames), len(originals))
+	}
+	for i, frame := range anim.Frames {
+		got, _ := conv.DecodeFF6Sprite(frame)
+		want, _ := conv.DecodeFF6Sprite(originals[i])
+		if frame.Width != 16 || frame.Height != 24 {
+			t.Fatalf("frame %d is %dx%d", i, frame.Width, frame.Height)
+		}
+		for y := 0; y < 24; y++ {
+			for x := 0; x < 16; x++ {
+				if got.At(x, y) != want.At(x, y) {
+					t.Fatalf("frame %d pixel %d,%d = %v, want %v", i, x, y, got.At(x, y), want.At(x, y))
+				}
+			}
+		}
+	}
+
+	if _, err := ImportPlainPRMod(dir, PRModGrid{Columns: 3, Rows: 2, Scale: 4}); err == nil {
+		t.Error("a grid that does not divide the texture should be rejected")
+	}
+	if _, err := ImportPlainPRMod(dir, PRModGrid{Columns: 2, Rows: 2}); err == nil {
+		t.Error("import without a scale should fail")
+	}
+	if _, _, err := ImportPRModTexture(dir, "../outside.png", PRModGrid{Columns: 1, Rows: 1, Scale: 1}); err == nil {
+		t.Error("textures outside the mod folder should be rejected")
+	}
+}
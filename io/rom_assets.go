@@ -0,0 +1,386 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/bits"
+	"os"
+)
+
+// ErrAssetChecksum is returned when extracted data does not match the CRC32
+// recorded for the asset, which means the offset table is wrong for the ROM
+var ErrAssetChecksum = errors.New("asset checksum mismatch")
+
+// AssetKind is the kind of graphics data a ROM asset holds
+type AssetKind string
+
+const (
+	AssetFieldSprite   AssetKind = "field"
+	AssetBattleSprite  AssetKind = "battle"
+	AssetPortrait      AssetKind = "portrait"
+	AssetNPCSprite     AssetKind = "npc"
+	AssetMonsterSprite AssetKind = "monster"
+	AssetPalette       AssetKind = "palette"
+)
+
+// ROMAsset locates one piece of graphics data in a headerless image
+type ROMAsset struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Kind       AssetKind `json:"kind"`
+	Character  int       `json:"character"` // playable character index, -1 for others
+	Offset     int       `json:"offset"`
+	Size       int       `json:"size"` // extracted (decompressed) size in bytes
+	Compressed bool      `json:"compressed,omitempty"`
+	// Width, Height and Frames describe sprite assets; palettes leave them 0
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	Frames int `json:"frames,omitempty"`
+	// Pointer is the file offset of the 3 byte SNES address the game reads
+	// the asset through. Compressed assets that grow can only be relocated
+	// when it is known; 0 means unknown.
+	Pointer int `json:"pointer,omitempty"`
+	// CRC32 of the extracted bytes. 0 means the entry has not been checked
+	// against a real dump and extraction cannot vouch for it.
+	CRC32 uint32 `json:"crc32,omitempty"`
+}
+
+// Verified reports whether extraction can be checked against a checksum
+func (a *ROMAsset) Verified() bool {
+	return a.CRC32 != 0
+}
+
+// Check compares extracted data with the recorded checksum
+func (a *ROMAsset) Check(data []byte) error {
+	if len(data) != a.Size {
+		return fmt.Errorf("%s: extracted %d bytes, want %d", a.ID, len(data), a.Size)
+	}
+	if a.CRC32 != 0 {
+		if sum := crc32.ChecksumIEEE(data); sum != a.CRC32 {
+			return fmt.Errorf("%s: CRC32 %08X, want %08X: %w", a.ID, sum, a.CRC32, ErrAssetChecksum)
+		}
+	}
+	return nil
+}
+
+// AssetTable is the set of asset locations for one ROM revision
+type AssetTable struct {
+	Revision string `json:"revision"`
+	// Verified tables have had every entry checked against a known dump, so
+	// each entry carries its CRC32. Only verified tables are registered.
+	Verified bool       `json:"verified,omitempty"`
+	Assets   []ROMAsset `json:"assets"`
+}
+
+// Asset returns the asset with the given ID
+func (t *AssetTable) Asset(id string) (*ROMAsset, bool) {
+	for i := range t.Assets {
+		if t.Assets[i].ID == id {
+			return &t.Assets[i], true
+		}
+	}
+	return nil, false
+}
+
+// CharacterAsset returns a playable character's asset of the given kind
+func (t *AssetTable) CharacterAsset(character int, kind AssetKind) (*ROMAsset, bool) {
+	for i := range t.Assets {
+		if a := &t.Assets[i]; a.Character == character && a.Kind == kind {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// PaletteFor returns the palette asset drawn with a sprite asset: the
+// portrait palette for portraits, the character palette for other character
+// sprites. NPC and monster sprites have none in the table.
+func (t *AssetTable) PaletteFor(a *ROMAsset) (*ROMAsset, bool) {
+	switch {
+	case a.Kind == AssetPortrait:
+		return t.Asset("portrait-palette/" + assetSlug(a.Name))
+	case a.Character >= 0 && a.Kind != AssetPalette:
+		return t.CharacterAsset(a.Character, AssetPalette)
+	}
+	return nil, false
+}
+
+// PaletteUsers returns the names of the sprites drawn with the bytes of a
+// palette asset, in table order. More than one name means the palette is
+// shared and changing it recolours all of them.
+func (t *AssetTable) PaletteUsers(palette *ROMAsset) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for i := range t.Assets {
+		a := &t.Assets[i]
+		if a.Kind == AssetPalette {
+			continue
+		}
+		p, ok := t.PaletteFor(a)
+		if !ok || p.Offset != palette.Offset || p.Size != palette.Size || seen[a.Name] {
+			continue
+		}
+		seen[a.Name] = true
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// Clone returns a deep copy of the table
+func (t *AssetTable) Clone() *AssetTable {
+	return &AssetTable{Revision: t.Revision, Verified: t.Verified, Assets: append([]ROMAsset(nil), t.Assets...)}
+}
+
+// ByKind returns the assets of one kind in table order
+func (t *AssetTable) ByKind(kind AssetKind) []*ROMAsset {
+	var list []*ROMAsset
+	for i := range t.Assets {
+		if t.Assets[i].Kind == kind {
+			list = append(list, &t.Assets[i])
+		}
+	}
+	return list
+}
+
+// Validate checks the table for duplicate IDs, entries that cannot be read,
+// entries of a verified table without a CRC32 and uncompressed entries whose
+// bytes overlap, since injecting one would corrupt the other. Palettes
+// several characters share may repeat the same range.
+func (t *AssetTable) Validate() error {
+	if t.Revision == "" {
+		return fmt.Errorf("asset table has no revision")
+	}
+	seen := make(map[string]bool)
+	for i, a := range t.Assets {
+		if a.ID == "" {
+			return fmt.Errorf("asset with empty ID")
+		}
+		if seen[a.ID] {
+			return fmt.Errorf("duplicate asset %q", a.ID)
+		}
+		seen[a.ID] = true
+		if a.Offset < 0 || a.Size <= 0 {
+			return fmt.Errorf("asset %q: invalid offset 0x%X or size %d", a.ID, a.Offset, a.Size)
+		}
+		if t.Verified && a.CRC32 == 0 {
+			return fmt.Errorf("asset %q has no CRC32 but the table is marked verified", a.ID)
+		}
+		for _, b := range t.Assets[:i] {
+			if overlaps(&a, &b) {
+				return fmt.Errorf("assets %q and %q overlap at 0x%X", b.ID, a.ID, a.Offset)
+			}
+		}
+	}
+	return nil
+}
+
+// overlaps reports whether two uncompressed assets share bytes. Identical
+// palette ranges are one shared palette, not an overlap.
+func overlaps(a, b *ROMAsset) bool {
+	if a.Compressed || b.Compressed {
+		return false
+	}
+	if a.Kind == AssetPalette && b.Kind == AssetPalette && a.Offset == b.Offset && a.Size == b.Size {
+		return false
+	}
+	return a.Offset < b.Offset+b.Size && b.Offset < a.Offset+a.Size
+}
+
+// AssetTables holds the verified offset tables keyed by KnownDump revision.
+// Only tables whose entries carry a CRC32 checked against a known dump are
+// built in, and none has been recorded yet, so extraction and injection need
+// a table loaded with LoadAssetTable. DraftAssetTable is the starting point
+// for one.
+var AssetTables = defaultAssetTables()
+
+// CharacterNames are the playable characters in save/ROM order
+var CharacterNames = []string{
+	"Terra", "Locke", "Cyan", "Shadow", "Edgar", "Sabin", "Celes",
+	"Strago", "Relm", "Setzer", "Mog", "Gau", "Gogo", "Umaro",
+}
+
+// Draft graphics layout from the community ROM maps. None of it has been
+// checked against a dump, which is why it is not registered in AssetTables.
+const (
+	charGraphicsBase   = 0x150000 // $D5:0000, field and battle poses, uncompressed
+	charGraphicsStride = 0x16A0   // one sheet of 181 tiles per sprite
+	charPaletteBase    = 0x126000 // $D2:6000, 32 bytes per palette
+	portraitBase       = 0x2D1D00 // $ED:1D00, 5x5 tiles per portrait
+	portraitSize       = 0x320
+	portraitPalette    = 0x2D5860 // $ED:5860
+	paletteSize        = 32
+	tileSize           = 32 // one 8x8 4bpp tile
+
+	monsterCount        = 384
+	monsterInfoBase     = 0x127000 // $D2:7000, 5 bytes per monster
+	monsterInfoSize     = 5
+	monsterGraphicsBase = 0x297000 // $E9:7000, addressed in 8 byte units
+	monsterSmallMaps    = 0x12A820 // $D2:A820, 8x8 tile grids of 8 bytes
+	monsterLargeMaps    = 0x12AC20 // $D2:AC20, 16x16 tile grids of 32 bytes
+)
+
+// characterPalettes maps each playable character to the shared sprite
+// palette it uses
+var characterPalettes = []int{2, 1, 4, 4, 0, 0, 0, 3, 3, 4, 5, 3, 3, 5}
+
+// npcSprites are the sprite sheets that follow Umaro's, in ROM order
+var npcSprites = []string{"Soldier", "Imp", "Leo", "Banon", "Esper Terra", "Merchant", "Ghost", "Kefka"}
+
+// DraftAssetTable returns the unverified layout for a revision: one sprite
+// sheet per character and NPC, portraits and palettes, and, when rom is set,
+// the monster graphics its monster graphics table points at. Battle poses are
+// tiles of the same sheet as the field poses, so there is no separate battle
+// entry. `rom assets --write-table` writes a draft out without checksums;
+// each entry's CRC32 is recorded by hand once its graphics are confirmed,
+// and the table marked verified when all of them are.
+func DraftAssetTable(revision string, rom []byte) *AssetTable {
+	t := &AssetTable{Revision: revision}
+	sheet := func(id, name string, kind AssetKind, character, index int) ROMAsset {
+		return ROMAsset{ID: id, Name: name, Kind: kind, Character: character,
+			Offset: charGraphicsBase + index*charGraphicsStride, Size: charGraphicsStride,
+			Width: 8, Height: 8, Frames: charGraphicsStride / tileSize}
+	}
+	for i, name := range CharacterNames {
+		id := assetSlug(name)
+		t.Assets = append(t.Assets,
+			sheet("field/"+id, name, AssetFieldSprite, i, i),
+			ROMAsset{ID: "portrait/" + id, Name: name, Kind: AssetPortrait, Character: i,
+				Offset: portraitBase + i*portraitSize, Size: portraitSize, Width: 40, Height: 40, Frames: 1},
+			ROMAsset{ID: "palette/" + id, Name: name + " Palette", Kind: AssetPalette, Character: i,
+				Offset: charPaletteBase + characterPalettes[i]*paletteSize, Size: paletteSize},
+			ROMAsset{ID: "portrait-palette/" + id, Name: name + " Portrait Palette", Kind: AssetPalette, Character: -1,
+				Offset: portraitPalette + i*paletteSize, Size: paletteSize},
+		)
+	}
+	for i, name := range npcSprites {
+		t.Assets = append(t.Assets, sheet("npc/"+assetSlug(name), name, AssetNPCSprite, -1, len(CharacterNames)+i))
+	}
+	t.Assets = append(t.Assets, draftMonsterAssets(rom)...)
+	return t
+}
+
+// draftMonsterAssets reads the monster graphics table of rom. Each entry
+// holds the graphics address with a 3bpp flag in bit 15, a large tile grid
+// flag in bit 7 of the third byte and the tile grid index in the fifth; the
+// sheet is as many tiles as the grid has bits set. Monsters that recolour
+// another's graphics share its sheet, which is listed once under the first.
+func draftMonsterAssets(rom []byte) []ROMAsset {
+	if len(rom) < monsterLargeMaps+256*32 {
+		return nil
+	}
+	var assets []ROMAsset
+	seen := make(map[int]int)
+	for i := 0; i < monsterCount; i++ {
+		info := rom[monsterInfoBase+i*monsterInfoSize:]
+		word := int(info[0]) | int(info[1])<<8
+		offset := monsterGraphicsBase + (word&0x7FFF)*8
+		bytesPerTile := tileSize
+		if word&0x8000 != 0 {
+			bytesPerTile = 24
+		}
+		grid := rom[monsterSmallMaps+int(info[4])*8:][:8]
+		if info[2]&0x80 != 0 {
+			grid = rom[monsterLargeMaps+int(info[4])*32:][:32]
+		}
+		tiles := 0
+		for _, b := range grid {
+			tiles += bits.OnesCount8(b)
+		}
+		if tiles == 0 || offset+tiles*bytesPerTile > len(rom) {
+			continue
+		}
+		a := ROMAsset{ID: fmt.Sprintf("monster/%03d", i), Name: fmt.Sprintf("Monster %d", i), Kind: AssetMonsterSprite,
+			Character: -1, Offset: offset, Size: tiles * bytesPerTile, Width: 8, Height: 8, Frames: tiles}
+		if j, ok := seen[offset]; ok {
+			if a.Size > assets[j].Size {
+				assets[j].Size, assets[j].Frames = a.Size, a.Frames
+			}
+			continue
+		}
+		seen[offset] = len(assets)
+		assets = append(assets, a)
+	}
+	return assets
+}
+
+// defaultAssetTables returns the built-in verified tables
+func defaultAssetTables() map[string]*AssetTable {
+	return make(map[string]*AssetTable)
+}
+
+// ResetAssetTables restores the built-in tables
+func ResetAssetTables() {
+	AssetTables = defaultAssetTables()
+}
+
+// ReadAssetTable reads and validates a JSON asset table without
+// registering it, e.g. a draft being checked
+func ReadAssetTable(path string) (*AssetTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t AssetTable
+	if err = json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse asset table: %w", err)
+	}
+	if err = t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LoadAssetTable reads a verified JSON asset table and registers it under
+// its revision, replacing any table already there
+func LoadAssetTable(path string) (*AssetTable, error) {
+	t, err := ReadAssetTable(path)
+	if err != nil {
+		return nil, err
+	}
+	if !t.Verified {
+		return nil, fmt.Errorf("asset table for %s is not marked verified; record each entry's CRC32 once it has been checked against a known dump", t.Revision)
+	}
+	AssetTables[t.Revision] = t
+	return t, nil
+}
+
+// AssetTableFor returns the table for an identified ROM. Images that only
+// matched by title fall back to their region's base revision.
+func AssetTableFor(info *ROMInfo) (*AssetTable, error) {
+	if info == nil {
+		return nil, fmt.Errorf("ROM has not been identified")
+	}
+	revision := info.Revision()
+	if revision == "" {
+		switch info.Type {
+		case ROMTypeUSA:
+			revision = RevisionUS10
+		case ROMTypeJPN:
+			revision = RevisionJP
+		default:
+			return nil, fmt.Errorf("no asset table for %s ROM", info.Type)
+		}
+	}
+	t, ok := AssetTables[revision]
+	if !ok {
+		return nil, fmt.Errorf("no verified asset table for revision %s; check the draft with `rom assets --write-table` against a known dump and load the result with --table", revision)
+	}
+	return t, nil
+}
+
+func assetSlug(name string) string {
+	b := make([]byte, 0, len(name))
+	for _, r := range name {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			b = append(b, byte(r-'A'+'a'))
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b = append(b, byte(r))
+		case r == ' ' || r == '-':
+			b = append(b, '-')
+		}
+	}
+	return string(b)
+}
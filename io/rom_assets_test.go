@@ -0,0 +1,341 @@
+package io
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testTile returns the 32 byte 4bpp tile stored at a ROM offset in the
+// synthetic image: a checkerboard whose colour depends on the tile's position
+func testTile(offset int) []byte {
+	tile := make([]byte, 32)
+	color := byte(offset/32) & 0x0F
+	for row := 0; row < 8; row++ {
+		for plane := 0; plane < 4; plane++ {
+			bits := byte(0)
+			if color&(1<<plane) != 0 {
+				bits = 0xAA >> (row & 1)
+			}
+			tile[(plane/2)*16+row*2+plane%2] = bits
+		}
+	}
+	return tile
+}
+
+// buildAssetROM returns a synthetic US image with known tile data written
+// over every asset in the table, and palettes holding 16 distinct colours
+func buildAssetROM(t *testing.T, table *AssetTable) []byte {
+	rom := buildTestROM("FINAL FANTASY 3")
+	for _, a := range table.Assets {
+		if a.Kind == AssetPalette {
+			for i := 0; i < 16; i++ {
+				c := uint16(i) | uint16(i)<<5 | uint16(31-i)<<10
+				rom[a.Offset+i*2], rom[a.Offset+i*2+1] = byte(c), byte(c>>8)
+			}
+			continue
+		}
+		for off := a.Offset; off < a.Offset+a.Size; off += 32 {
+			copy(rom[off:], testTile(off))
+		}
+	}
+	return rom
+}
+
+// testAssetTable is a small table for the synthetic image: Terra's assets,
+// one NPC and one monster, at offsets the draft layout does not use
+func testAssetTable() *AssetTable {
+	return &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{
+		{ID: "field/terra", Name: "Terra", Kind: AssetFieldSprite, Character: 0, Offset: 0x200000, Size: 576, Width: 16, Height: 24, Frames: 3},
+		{ID: "battle/terra", Name: "Terra", Kind: AssetBattleSprite, Character: 0, Offset: 0x201000, Size: 3072, Width: 32, Height: 32, Frames: 6},
+		{ID: "portrait/terra", Name: "Terra", Kind: AssetPortrait, Character: 0, Offset: 0x202000, Size: portraitSize, Width: 40, Height: 40, Frames: 1},
+		{ID: "palette/terra", Name: "Terra Palette", Kind: AssetPalette, Character: 0, Offset: 0x203000, Size: paletteSize},
+		{ID: "portrait-palette/terra", Name: "Terra Portrait Palette", Kind: AssetPalette, Character: -1, Offset: 0x203020, Size: paletteSize},
+		{ID: "npc/banon", Name: "Banon", Kind: AssetNPCSprite, Character: -1, Offset: 0x204000, Size: 576, Width: 16, Height: 24, Frames: 3},
+		{ID: "monster/guard", Name: "Guard", Kind: AssetMonsterSprite, Character: -1, Offset: 0x205000, Size: 512, Width: 32, Height: 32, Frames: 1},
+	}}
+}
+
+// useTestAssetTable registers testAssetTable for the US revision until the
+// test ends and returns it
+func useTestAssetTable(t *testing.T) *AssetTable {
+	t.Helper()
+	t.Cleanup(ResetAssetTables)
+	table := testAssetTable()
+	AssetTables[RevisionUS10] = table
+	return table
+}
+
+func newTestExtractor(t *testing.T, rom []byte) *ROMSpriteExtractor {
+	t.Helper()
+	loader := &ROMLoader{}
+	if err := loader.LoadData(rom); err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewROMSpriteExtractorFromLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// TestAssetCheckKnownCRC tests Check against the standard CRC-32 check value
+func TestAssetCheckKnownCRC(t *testing.T) {
+	data := []byte("123456789")
+	a := &ROMAsset{ID: "test", Size: len(data), CRC32: 0xCBF43926}
+	if err := a.Check(data); err != nil {
+		t.Errorf("Check with the known CRC failed: %v", err)
+	}
+	a.CRC32 = 0xCBF43927
+	if err := a.Check(data); !errors.Is(err, ErrAssetChecksum) {
+		t.Errorf("Check with a wrong CRC = %v, want ErrAssetChecksum", err)
+	}
+	if err := a.Check(data[:8]); err == nil {
+		t.Error("Check should reject data of the wrong size")
+	}
+}
+
+// TestBuiltInAssetTablesAreVerified tests that only checked entries ship and
+// that without a table extraction explains how to get one
+func TestBuiltInAssetTablesAreVerified(t *testing.T) {
+	for rev, table := range defaultAssetTables() {
+		if err := table.Validate(); err != nil {
+			t.Errorf("%s: %v", rev, err)
+		}
+		for _, a := range table.Assets {
+			if !a.Verified() {
+				t.Errorf("%s: built-in asset %s has no CRC32 from a real dump", rev, a.ID)
+			}
+		}
+	}
+
+	ResetAssetTables()
+	loader := &ROMLoader{}
+	if err := loader.LoadData(buildTestROM("FINAL FANTASY 3")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := AssetTables[RevisionUS10]; !ok {
+		if _, err := NewROMSpriteExtractorFromLoader(loader); err == nil || !strings.Contains(err.Error(), "--table") {
+			t.Errorf("extractor without a verified table = %v, want a hint to load one", err)
+		}
+	}
+}
+
+// TestDraftAssetTable tests the draft layout has no overlapping assets, keeps
+// the ROM order of the NPC sheets and claims no checksums
+func TestDraftAssetTable(t *testing.T) {
+	draft := DraftAssetTable(RevisionUS10, nil)
+	if err := draft.Validate(); err != nil {
+		t.Fatalf("draft: %v", err)
+	}
+	for _, a := range draft.Assets {
+		if a.Verified() {
+			t.Errorf("draft asset %s claims a checksum", a.ID)
+		}
+		if a.Kind == AssetBattleSprite || a.Kind == AssetMonsterSprite {
+			t.Errorf("draft has a %s asset %s", a.Kind, a.ID)
+		}
+	}
+	for i := range CharacterNames {
+		for _, kind := range []AssetKind{AssetFieldSprite, AssetPortrait, AssetPalette} {
+			if _, ok := draft.CharacterAsset(i, kind); !ok {
+				t.Errorf("character %d has no %s asset", i, kind)
+			}
+		}
+	}
+
+	want := []string{"Soldier", "Imp", "Leo", "Banon", "Esper Terra", "Merchant", "Ghost", "Kefka"}
+	npcs := draft.ByKind(AssetNPCSprite)
+	umaro, _ := draft.CharacterAsset(len(CharacterNames)-1, AssetFieldSprite)
+	for i, a := range npcs {
+		if i >= len(want) || a.Name != want[i] {
+			t.Fatalf("NPC sheets = %v..., want %v", a.Name, want)
+		}
+		if a.Offset != umaro.Offset+(i+1)*charGraphicsStride {
+			t.Errorf("%s sheet at 0x%X does not follow Umaro's in order", a.Name, a.Offset)
+		}
+	}
+}
+
+// TestDraftMonsterAssets tests monster sheets are sized from the monster
+// graphics table and that recoloured monsters share one sheet
+func TestDraftMonsterAssets(t *testing.T) {
+	rom := make([]byte, 4*1024*1024)
+	// Monster 0: 4bpp at +0x100, small grid 1 with 3 tiles
+	copy(rom[monsterInfoBase:], []byte{0x20, 0x00, 0x00, 0x00, 1})
+	rom[monsterSmallMaps+8] = 0x07
+	// Monster 1: 3bpp at +0x800, large grid 2 with 16 tiles
+	copy(rom[monsterInfoBase+monsterInfoSize:], []byte{0x00, 0x81, 0x80, 0x00, 2})
+	rom[monsterLargeMaps+64], rom[monsterLargeMaps+65] = 0xFF, 0xFF
+	// Monster 2 recolours monster 0
+	copy(rom[monsterInfoBase+2*monsterInfoSize:], []byte{0x20, 0x00, 0x00, 0x01, 1})
+
+	draft := DraftAssetTable(RevisionUS10, rom)
+	if err := draft.Validate(); err != nil {
+		t.Fatalf("draft: %v", err)
+	}
+	monsters := draft.ByKind(AssetMonsterSprite)
+	if len(monsters) != 2 {
+		t.Fatalf("%d monster sheets, want 2", len(monsters))
+	}
+	if a := monsters[0]; a.ID != "monster/000" || a.Offset != monsterGraphicsBase+0x100 || a.Size != 3*32 || a.Frames != 3 {
+		t.Errorf("monster 0 = %+v", *a)
+	}
+	if a := monsters[1]; a.ID != "monster/001" || a.Offset != monsterGraphicsBase+0x800 || a.Size != 16*24 || a.Verified() {
+		t.Errorf("monster 1 = %+v", *a)
+	}
+}
+
+// TestVerifiedTableNeedsChecksums tests a table marked verified is refused
+// while any entry lacks a CRC32, and that only verified tables are loaded
+func TestVerifiedTableNeedsChecksums(t *testing.T) {
+	t.Cleanup(ResetAssetTables)
+	table := &AssetTable{Revision: RevisionUS10, Verified: true, Assets: []ROMAsset{
+		{ID: "palette/a", Kind: AssetPalette, Offset: 0x2000, Size: 32, CRC32: 0x1234},
+		{ID: "palette/b", Kind: AssetPalette, Offset: 0x2020, Size: 32},
+	}}
+	if err := table.Validate(); err == nil {
+		t.Error("a verified table with a zero CRC32 entry should be rejected")
+	}
+
+	path := filepath.Join(t.TempDir(), "table.json")
+	write := func() {
+		data, _ := json.Marshal(table)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write()
+	if _, err := LoadAssetTable(path); err == nil {
+		t.Error("LoadAssetTable accepted a verified table with a zero CRC32 entry")
+	}
+
+	table.Verified = false
+	write()
+	if _, err := ReadAssetTable(path); err != nil {
+		t.Errorf("ReadAssetTable should accept an unverified table: %v", err)
+	}
+	if _, err := LoadAssetTable(path); err == nil {
+		t.Error("LoadAssetTable accepted a table not marked verified")
+	}
+
+	table.Verified = true
+	table.Assets[1].CRC32 = 0x5678
+	write()
+	if _, err := LoadAssetTable(path); err != nil {
+		t.Fatalf("LoadAssetTable: %v", err)
+	}
+	if _, ok := AssetTables[RevisionUS10]; !ok {
+		t.Error("verified table was not registered")
+	}
+}
+
+// TestValidateRejectsOverlap tests overlapping assets are refused while
+// shared palettes are allowed
+func TestValidateRejectsOverlap(t *testing.T) {
+	table := &AssetTable{Revision: "test", Assets: []ROMAsset{
+		{ID: "field/a", Kind: AssetFieldSprite, Offset: 0x1000, Size: 576},
+		{ID: "battle/a", Kind: AssetBattleSprite, Offset: 0x1000, Size: 3072},
+	}}
+	if err := table.Validate(); err == nil {
+		t.Error("field and battle assets sharing bytes should be rejected")
+	}
+
+	table.Assets = []ROMAsset{
+		{ID: "palette/a", Kind: AssetPalette, Offset: 0x2000, Size: 32},
+		{ID: "palette/b", Kind: AssetPalette, Offset: 0x2000, Size: 32},
+		{ID: "field/a", Kind: AssetFieldSprite, Offset: 0x2020, Size: 576},
+	}
+	if err := table.Validate(); err != nil {
+		t.Errorf("shared palettes rejected: %v", err)
+	}
+}
+
+func TestExtractAssetsFromSyntheticROM(t *testing.T) {
+	table := useTestAssetTable(t)
+	e := newTestExtractor(t, buildAssetROM(t, table))
+	if e.Assets().Revision != RevisionUS10 {
+		t.Fatalf("unverified US image should use %s, got %s", RevisionUS10, e.Assets().Revision)
+	}
+
+	field, err := e.ExtractCharacterSprite(0)
+	if err != nil {
+		t.Fatalf("field sprite: %v", err)
+	}
+	a, _ := table.CharacterAsset(0, AssetFieldSprite)
+	for off := 0; off < len(field.Data); off += 32 {
+		if string(field.Data[off:off+32]) != string(testTile(a.Offset+off)) {
+			t.Fatalf("field sprite tile at +0x%X differs", off)
+		}
+	}
+	if field.Width != 16 || field.Height != 24 || field.Frames != 3 {
+		t.Errorf("field sprite is %dx%d x%d", field.Width, field.Height, field.Frames)
+	}
+	if field.Palette.Colors[1].R != 1 || field.Palette.Colors[1].B != 30 {
+		t.Errorf("palette colour 1 = %+v", field.Palette.Colors[1])
+	}
+
+	if battle, err := e.ExtractBattleSprite(0); err != nil || len(battle.Data) != 3072 {
+		t.Errorf("battle sprite: %v", err)
+	}
+	if portrait, err := e.ExtractPortrait(0); err != nil || len(portrait.Data) != portraitSize {
+		t.Errorf("portrait: %v", err)
+	}
+	if _, err := e.ExtractCharacterSprite(1); err == nil {
+		t.Error("characters missing from the table should fail")
+	}
+
+	for _, a := range append(table.ByKind(AssetNPCSprite), table.ByKind(AssetMonsterSprite)...) {
+		if _, err := e.ExtractSprite(a.ID, ""); err != nil {
+			t.Errorf("%s: %v", a.ID, err)
+		}
+	}
+}
+
+// TestAssetChecksumValidation tests extraction against an asset whose
+// checksum is the known CRC-32 of its bytes
+func TestAssetChecksumValidation(t *testing.T) {
+	defer ResetAssetTables()
+	rom := buildTestROM("FINAL FANTASY 3")
+	copy(rom[0x200000:], "123456789")
+	AssetTables[RevisionUS10] = &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{{
+		ID: "monster/check", Name: "Check", Kind: AssetMonsterSprite, Character: -1,
+		Offset: 0x200000, Size: 9, CRC32: 0xCBF43926,
+	}}}
+	e := newTestExtractor(t, rom)
+	if _, _, err := e.ExtractAsset("monster/check"); err != nil {
+		t.Fatalf("verified asset: %v", err)
+	}
+	if report := e.AssetReport(); len(report) != 1 || report[0].CRC32 != 0xCBF43926 || report[0].Err != nil {
+		t.Errorf("AssetReport = %+v", report)
+	}
+
+	rom[0x200004] ^= 0xFF
+	if _, _, err := e.ExtractAsset("monster/check"); !errors.Is(err, ErrAssetChecksum) {
+		t.Errorf("corrupted asset should fail the checksum, got %v", err)
+	}
+}
+
+func TestExtractCompressedAsset(t *testing.T) {
+	defer ResetAssetTables()
+	rom := buildTestROM("FINAL FANTASY 3")
+
+	// Four literals then a back reference copying them three more times
+	stream := []byte{0x10, 1, 2, 3, 4, 0x90, 0x03}
+	want := []byte{1, 2, 3, 4, 1, 2, 3, 4, 1, 2, 3, 4}
+	copy(rom[0x200000:], stream)
+
+	AssetTables[RevisionUS10] = &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{{
+		ID: "monster/test", Name: "Test", Kind: AssetMonsterSprite, Character: -1,
+		Offset: 0x200000, Size: len(want), Compressed: true, CRC32: crc32.ChecksumIEEE(want),
+	}}}
+	e := newTestExtractor(t, rom)
+	data, _, err := e.ExtractAsset("monster/test")
+	if err != nil || string(data) != string(want) {
+		t.Errorf("compressed asset = %v, %v; want %v", data, err, want)
+	}
+}
@@ -0,0 +1,344 @@
+package io
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+)
+
+// ROMMapping is the SNES memory map a cartridge uses
+type ROMMapping int
+
+const (
+	MappingUnknown ROMMapping = iota
+	MappingLoROM
+	MappingHiROM
+)
+
+func (m ROMMapping) String() string {
+	switch m {
+	case MappingLoROM:
+		return "LoROM"
+	case MappingHiROM:
+		return "HiROM"
+	default:
+		return "unknown"
+	}
+}
+
+func (t ROMType) String() string {
+	switch t {
+	case ROMTypeUSA:
+		return "USA"
+	case ROMTypeJPN:
+		return "Japan"
+	case ROMTypeEUR:
+		return "Europe"
+	default:
+		return "unknown"
+	}
+}
+
+// CopierHeaderSize is the size of the header SMC/SWC copiers prepend to dumps
+const CopierHeaderSize = 512
+
+// SNES internal header fields, relative to the header offset
+const (
+	snesMapModeOffset    = 0x15
+	snesVersionOffset    = 0x1B
+	snesComplementOffset = 0x1C
+	snesChecksumOffset   = 0x1E
+)
+
+// KnownDump is a ROM image identified by its hashes. Hashes are taken over
+// the image without a copier header.
+type KnownDump struct {
+	Name     string     `json:"name"`
+	Type     ROMType    `json:"type"`
+	Revision string     `json:"revision"`
+	Mapping  ROMMapping `json:"mapping"`
+	CRC32    uint32     `json:"crc32"`
+	SHA1     string     `json:"sha1,omitempty"`
+	// Patch names the translation or hack applied on top of the base revision
+	Patch string `json:"patch,omitempty"`
+}
+
+// Revision keys used by revision-specific tables
+const (
+	RevisionUS10 = "us-1.0"
+	RevisionUS11 = "us-1.1"
+	RevisionJP   = "jp-1.0"
+)
+
+// KnownDumps is the table of recognised images. The CRC32s are the commonly
+// published values for the clean cartridge dumps; only entries with a SHA-1
+// have had both hashes checked. Patched images vary with the patch version,
+// so their hashes are added with LoadKnownDumps rather than shipped here.
+var KnownDumps = defaultKnownDumps()
+
+func defaultKnownDumps() []KnownDump {
+	return []KnownDump{
+		{
+			Name:     "Final Fantasy III (USA)",
+			Type:     ROMTypeUSA,
+			Revision: RevisionUS10,
+			Mapping:  MappingHiROM,
+			CRC32:    0xA27F1C7A,
+			SHA1:     "4f37e4274ac3b2ea1bedb08aa149d8fc5bb676e7",
+		},
+		{
+			Name:     "Final Fantasy III (USA) (Rev 1)",
+			Type:     ROMTypeUSA,
+			Revision: RevisionUS11,
+			Mapping:  MappingHiROM,
+			CRC32:    0xC0FA0464,
+		},
+		{
+			Name:     "Final Fantasy VI (Japan)",
+			Type:     ROMTypeJPN,
+			Revision: RevisionJP,
+			Mapping:  MappingHiROM,
+			CRC32:    0x45EF5AC8,
+		},
+	}
+}
+
+// LoadKnownDumps adds the dumps in a JSON array to KnownDumps, so translation
+// patches and fan revisions can be recognised without a rebuild. type is a
+// ROMType (1 USA, 2 Japan, 3 Europe) and mapping a ROMMapping (1 LoROM, 2 HiROM).
+func LoadKnownDumps(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var dumps []KnownDump
+	if err = json.Unmarshal(data, &dumps); err != nil {
+		return fmt.Errorf("failed to parse known dumps: %w", err)
+	}
+	for i, d := range dumps {
+		if d.Name == "" || (d.CRC32 == 0 && d.SHA1 == "") {
+			return fmt.Errorf("known dump %d: name and a hash are required", i)
+		}
+		dumps[i].SHA1 = strings.ToLower(d.SHA1)
+	}
+	KnownDumps = append(KnownDumps, dumps...)
+	return nil
+}
+
+// ResetKnownDumps restores the built-in table
+func ResetKnownDumps() {
+	KnownDumps = defaultKnownDumps()
+}
+
+// ROMInfo describes an image after header normalization and identification
+type ROMInfo struct {
+	Size      int // without the copier header
+	HasHeader bool
+	CRC32     uint32
+	SHA1      string
+	Title     string
+	Mapping   ROMMapping
+	Version   byte
+	// ChecksumOK reports whether the internal checksum matches the data
+	ChecksumOK bool
+	Checksum   uint16
+	// Dump is the matching KnownDump, nil when the hashes are not in the table
+	Dump *KnownDump
+	// Type is the dump's type, or the title guess for unrecognised images
+	Type ROMType
+}
+
+// Verified reports whether the image matched a known dump by hash
+func (i *ROMInfo) Verified() bool {
+	return i.Dump != nil
+}
+
+// Revision returns the known dump's revision key, or "" when unverified
+func (i *ROMInfo) Revision() string {
+	if i.Dump == nil {
+		return ""
+	}
+	return i.Dump.Revision
+}
+
+// HasCopierHeader reports whether data starts with a 512 byte copier header.
+// Cartridge images are a multiple of 32KB, so the extra 512 bytes give it away.
+func HasCopierHeader(data []byte) bool {
+	return len(data)%1024 == CopierHeaderSize
+}
+
+// StripCopierHeader returns data without its copier header, and whether one
+// was present. The returned slice shares data's backing array.
+func StripCopierHeader(data []byte) ([]byte, bool) {
+	if HasCopierHeader(data) {
+		return data[CopierHeaderSize:], true
+	}
+	return data, false
+}
+
+// IdentifyROM strips any copier header, hashes the image and matches it
+// against KnownDumps. Unrecognised images still get their internal header
+// read so callers can report what they were given.
+func IdentifyROM(data []byte) *ROMInfo {
+	rom, hasHeader := StripCopierHeader(data)
+	sum := sha1.Sum(rom)
+	info := &ROMInfo{
+		Size:      len(rom),
+		HasHeader: hasHeader,
+		CRC32:     crc32.ChecksumIEEE(rom),
+		SHA1:      hex.EncodeToString(sum[:]),
+		Mapping:   DetectMapping(rom),
+	}
+
+	if offset := headerOffset(info.Mapping); offset >= 0 && len(rom) >= offset+0x20 {
+		h := rom[offset:]
+		info.Title = strings.TrimRight(string(h[:snesGameTitleLength]), "\x00 ")
+		info.Version = h[snesVersionOffset]
+		info.Checksum = uint16(h[snesChecksumOffset]) | uint16(h[snesChecksumOffset+1])<<8
+		info.ChecksumOK = info.Checksum == SNESChecksum(rom)
+	}
+
+	for i := range KnownDumps {
+		d := &KnownDumps[i]
+		if (d.SHA1 != "" && d.SHA1 == info.SHA1) || (d.SHA1 == "" && d.CRC32 == info.CRC32) {
+			info.Dump = d
+			info.Type = d.Type
+			break
+		}
+	}
+	if info.Dump == nil && info.Title != "" {
+		r := &ROMLoader{}
+		if r.isFF6Title(strings.ToUpper(info.Title)) {
+			info.Type = r.identifyRegion(info.Title)
+		}
+	}
+	return info
+}
+
+func headerOffset(m ROMMapping) int {
+	switch m {
+	case MappingLoROM:
+		return snesHeaderOffsetLoROM
+	case MappingHiROM:
+		return snesHeaderOffsetHiROM
+	default:
+		return -1
+	}
+}
+
+// DetectMapping scores the LoROM and HiROM header locations and returns the
+// more plausible one. A header scores for a checksum/complement pair that
+// adds up, a map mode byte matching its location and a printable title.
+func DetectMapping(rom []byte) ROMMapping {
+	lo := scoreHeader(rom, snesHeaderOffsetLoROM, 0)
+	hi := scoreHeader(rom, snesHeaderOffsetHiROM, 1)
+	switch {
+	case lo == 0 && hi == 0:
+		return MappingUnknown
+	case hi >= lo:
+		return MappingHiROM
+	default:
+		return MappingLoROM
+	}
+}
+
+func scoreHeader(rom []byte, offset int, mapBit byte) int {
+	if len(rom) < offset+0x20 {
+		return 0
+	}
+	h := rom[offset:]
+	score := 0
+	complement := uint16(h[snesComplementOffset]) | uint16(h[snesComplementOffset+1])<<8
+	checksum := uint16(h[snesChecksumOffset]) | uint16(h[snesChecksumOffset+1])<<8
+	if checksum^complement == 0xFFFF {
+		score += 2
+	}
+	if mode := h[snesMapModeOffset]; mode&0xE0 == 0x20 && mode&0x01 == mapBit {
+		score += 2
+	}
+	printable := true
+	for _, b := range h[:snesGameTitleLength] {
+		if b != 0 && (b < 0x20 || b > 0x7E) {
+			printable = false
+			break
+		}
+	}
+	if printable && h[0] != 0 {
+		score++
+	}
+	return score
+}
+
+// SNESChecksum computes the internal checksum of a headerless image. Sizes
+// that are not a power of two have their last part mirrored up to the next
+// one, as the cartridge address decoding does.
+func SNESChecksum(rom []byte) uint16 {
+	if len(rom) == 0 {
+		return 0
+	}
+	base := 1
+	for base*2 <= len(rom) {
+		base *= 2
+	}
+	var sum uint32
+	for _, b := range rom[:base] {
+		sum += uint32(b)
+	}
+	if rest := rom[base:]; len(rest) > 0 {
+		var restSum uint32
+		for _, b := range rest {
+			restSum += uint32(b)
+		}
+		sum += restSum * uint32(base/len(rest))
+	}
+	return uint16(sum)
+}
+
+// SNESToFile translates a SNES bus address into an offset in a headerless
+// image with the given mapping
+func SNESToFile(m ROMMapping, addr uint32) (int, error) {
+	bank, low := (addr>>16)&0xFF, addr&0xFFFF
+	switch m {
+	case MappingLoROM:
+		if low < 0x8000 || bank == 0x7E || bank == 0x7F {
+			return 0, fmt.Errorf("$%06X is not LoROM ROM space", addr)
+		}
+		return int((bank&0x7F)*0x8000 + low - 0x8000), nil
+	case MappingHiROM:
+		switch {
+		case bank >= 0xC0 || (bank >= 0x40 && bank < 0x7E):
+			return int((bank&0x3F)<<16 | low), nil
+		case (bank < 0x40 || (bank >= 0x80 && bank < 0xC0)) && low >= 0x8000:
+			return int((bank&0x3F)<<16 | low), nil
+		}
+		return 0, fmt.Errorf("$%06X is not HiROM ROM space", addr)
+	default:
+		return 0, fmt.Errorf("unknown ROM mapping")
+	}
+}
+
+// FileToSNES translates an offset in a headerless image into its canonical
+// SNES address: banks $80+ for LoROM, $C0+ for HiROM
+func FileToSNES(m ROMMapping, offset int) (uint32, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	switch m {
+	case MappingLoROM:
+		if offset >= 0x400000 {
+			return 0, fmt.Errorf("offset 0x%X is beyond LoROM space", offset)
+		}
+		return uint32(0x800000 | (offset/0x8000)<<16 | (offset%0x8000 + 0x8000)), nil
+	case MappingHiROM:
+		if offset >= 0x400000 {
+			return 0, fmt.Errorf("offset 0x%X is beyond HiROM space", offset)
+		}
+		return uint32(0xC00000 | offset), nil
+	default:
+		return 0, fmt.Errorf("unknown ROM mapping")
+	}
+}
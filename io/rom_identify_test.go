@@ -0,0 +1,133 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTestROM returns a 3MB HiROM image with a valid internal header
+func buildTestROM(title string) []byte {
+	rom := make([]byte, 3*1024*1024)
+	for i := range rom {
+		rom[i] = byte(i * 7)
+	}
+	h := rom[snesHeaderOffsetHiROM:]
+	copy(h, []byte(title + "                     ")[:snesGameTitleLength])
+	h[snesMapModeOffset] = 0x31
+	h[snesVersionOffset] = 1
+	// The checksum bytes count towards the sum, so start from the neutral
+	// 0x0000/0xFFFF pair, which always adds 0x1FE
+	h[snesComplementOffset], h[snesComplementOffset+1] = 0xFF, 0xFF
+	h[snesChecksumOffset], h[snesChecksumOffset+1] = 0, 0
+	sum := SNESChecksum(rom)
+	h[snesChecksumOffset], h[snesChecksumOffset+1] = byte(sum), byte(sum>>8)
+	h[snesComplementOffset], h[snesComplementOffset+1] = byte(^sum), byte(^sum>>8)
+	return rom
+}
+
+func TestIdentifyROMHeaderAndMapping(t *testing.T) {
+	rom := buildTestROM("FINAL FANTASY 3")
+	headered := append(make([]byte, CopierHeaderSize), rom...)
+
+	plain, withHeader := IdentifyROM(rom), IdentifyROM(headered)
+	if plain.HasHeader || !withHeader.HasHeader {
+		t.Fatalf("copier header detection: plain %v, headered %v", plain.HasHeader, withHeader.HasHeader)
+	}
+	if plain.CRC32 != withHeader.CRC32 || plain.SHA1 != withHeader.SHA1 {
+		t.Error("hashes should ignore the copier header")
+	}
+	if plain.Mapping != MappingHiROM {
+		t.Errorf("mapping = %s, want HiROM", plain.Mapping)
+	}
+	if plain.Title != "FINAL FANTASY 3" || !plain.ChecksumOK {
+		t.Errorf("title %q checksum ok %v", plain.Title, plain.ChecksumOK)
+	}
+	if plain.Verified() || plain.Type != ROMTypeUSA {
+		t.Errorf("unknown image should be an unverified title match, got verified %v type %s", plain.Verified(), plain.Type)
+	}
+}
+
+func TestIdentifyROMKnownDump(t *testing.T) {
+	defer ResetKnownDumps()
+	rom := buildTestROM("FF6 TEST")
+	info := IdentifyROM(rom)
+	if info.Type != ROMTypeUnknown {
+		t.Fatalf("type = %s before registering the dump", info.Type)
+	}
+
+	path := filepath.Join(t.TempDir(), "known.json")
+	data := `[{"name":"Test Translation","type":2,"revision":"jp-test","mapping":2,"sha1":"` + info.SHA1 + `","patch":"Test Patch"}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadKnownDumps(path); err != nil {
+		t.Fatal(err)
+	}
+
+	info = IdentifyROM(rom)
+	if !info.Verified() || info.Revision() != "jp-test" || info.Type != ROMTypeJPN || info.Dump.Patch != "Test Patch" {
+		t.Errorf("known dump not matched: %+v", info.Dump)
+	}
+
+	romPath := filepath.Join(t.TempDir(), "test.smc")
+	if err := os.WriteFile(romPath, append(make([]byte, CopierHeaderSize), rom...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewROMLoader(romPath)
+	if err := loader.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !loader.HasHeader() || loader.Revision() != "jp-test" || len(loader.GetData()) != len(rom) {
+		t.Errorf("loader: header %v revision %q size %d", loader.HasHeader(), loader.Revision(), len(loader.GetData()))
+	}
+	b, err := loader.ReadSNES(0xC0FFC0, 3)
+	if err != nil || string(b) != "FF6" {
+		t.Errorf("ReadSNES($C0FFC0) = %q, %v", b, err)
+	}
+}
+
+func TestSNESAddressTranslation(t *testing.T) {
+	cases := []struct {
+		mapping ROMMapping
+		addr    uint32
+		offset  int
+	}{
+		{MappingLoROM, 0x808000, 0x0},
+		{MappingLoROM, 0x00FFC0, 0x7FC0},
+		{MappingLoROM, 0x818000, 0x8000},
+		{MappingHiROM, 0xC00000, 0x0},
+		{MappingHiROM, 0xC0FFC0, 0xFFC0},
+		{MappingHiROM, 0x008000, 0x8000},
+		{MappingHiROM, 0xEF1234, 0x2F1234},
+	}
+	for _, c := range cases {
+		got, err := SNESToFile(c.mapping, c.addr)
+		if err != nil || got != c.offset {
+			t.Errorf("%s $%06X = 0x%X, %v; want 0x%X", c.mapping, c.addr, got, err, c.offset)
+			continue
+		}
+		back, err := FileToSNES(c.mapping, got)
+		if err != nil {
+			t.Errorf("FileToSNES(%s, 0x%X): %v", c.mapping, got, err)
+			continue
+		}
+		if again, _ := SNESToFile(c.mapping, back); again != got {
+			t.Errorf("%s round trip 0x%X -> $%06X -> 0x%X", c.mapping, got, back, again)
+		}
+	}
+
+	for _, bad := range []struct {
+		mapping ROMMapping
+		addr    uint32
+	}{
+		{MappingLoROM, 0x800000},
+		{MappingLoROM, 0x7E8000},
+		{MappingHiROM, 0x001234},
+		{MappingUnknown, 0xC00000},
+	} {
+		if _, err := SNESToFile(bad.mapping, bad.addr); err == nil {
+			t.Errorf("%s $%06X should not map to ROM", bad.mapping, bad.addr)
+		}
+	}
+}
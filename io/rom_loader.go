@@ -3,6 +3,7 @@ package io
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,8 +20,8 @@ const (
 
 // SNES ROM header offsets
 const (
-	snesHeaderOffsetLoROM = 0x7FC0 // LoROM header location (FF6 uses LoROM)
-	snesHeaderOffsetHiROM = 0xFFC0 // HiROM header location (for completeness)
+	snesHeaderOffsetLoROM = 0x7FC0 // LoROM header location
+	snesHeaderOffsetHiROM = 0xFFC0 // HiROM header location (FF6 uses HiROM)
 	snesGameTitleOffset   = 0      // Game title at header + 0
 	snesGameTitleLength   = 21     // Game title is 21 bytes
 )
@@ -40,6 +41,8 @@ type ROMLoader struct {
 	data      []byte
 	romType   ROMType
 	hasHeader bool
+	header    []byte
+	info      *ROMInfo
 }
 
 // NewROMLoader creates a new ROM loader
@@ -50,51 +53,63 @@ func NewROMLoader(path string) *ROMLoader {
 	}
 }
 
-// TryLoadFromDefaultLocations attempts to load ROM from common default locations
+// romSearchDirs are the directories scanned for a ROM when no path is given
+var romSearchDirs = []string{"save_data", "snes", "."}
+
+// TryLoadFromDefaultLocations scans the usual directories for .sfc/.smc
+// images and returns the first one whose hashes match a known dump. If none
+// match, the first image that passes the title check is used instead.
 func TryLoadFromDefaultLocations() *ROMLoader {
-	// List of common paths to try
-	defaultPaths := []string{
-		"save_data/Final Fantasy III (USA) (Rev 1).sfc",
-		"save_data/Final Fantasy III (USA) (Rev A).sfc",
-		"save_data/ff3us.sfc",
-		"save_data/Final Fantasy VI.sfc",
-		"save_data/ff6.sfc",
-		"save_data/ff6.smc",
-		"snes/Final Fantasy III (USA) (Rev A).sfc",
-		"snes/ff3us.sfc",
-		"snes/Final Fantasy VI (USA).sfc",
-		"snes/Final Fantasy VI.sfc",
-		"Final Fantasy III (USA) (Rev A).sfc",
-		"ff3us.sfc",
-		"ff6.sfc",
-		"ff6.smc",
-	}
-
-	for _, path := range defaultPaths {
-		loader := NewROMLoader(path)
-		if err := loader.Load(); err == nil {
-			fmt.Printf("Found and loaded ROM from: %s\n", path)
-			return loader
+	var fallback *ROMLoader
+	for _, dir := range romSearchDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			ext := strings.ToLower(filepath.Ext(e.Name()))
+			if e.IsDir() || (ext != ".sfc" && ext != ".smc") {
+				continue
+			}
+			loader := NewROMLoader(filepath.Join(dir, e.Name()))
+			if err := loader.Load(); err != nil {
+				continue
+			}
+			if loader.info.Verified() {
+				return loader
+			}
+			if fallback == nil {
+				fallback = loader
+			}
 		}
 	}
+	if fallback != nil {
+		return fallback
+	}
 
 	// Return an empty loader if nothing found
 	return NewROMLoader("")
 }
 
-// Load reads and validates the ROM file
+// Load reads the ROM file, strips any copier header and identifies it. Images
+// not in KnownDumps are accepted when their internal title looks like FF6.
 func (r *ROMLoader) Load() error {
 	// Read ROM file
 	data, err := os.ReadFile(r.path)
 	if err != nil {
 		return fmt.Errorf("failed to read ROM: %w", err)
 	}
+	return r.LoadData(data)
+}
 
-	// Check if ROM has SMC header (512 bytes)
-	if len(data)%1024 == 512 {
-		r.hasHeader = true
-		// Skip header
-		data = data[512:]
+// LoadData identifies and validates an image already in memory, as Load does
+// for a file
+func (r *ROMLoader) LoadData(data []byte) error {
+	info := IdentifyROM(data)
+	raw := data
+	data, r.hasHeader = StripCopierHeader(data)
+	if r.hasHeader {
+		r.header = append([]byte(nil), raw[:CopierHeaderSize]...)
 	}
 
 	// Validate ROM size (FF6 is 3MB or 4MB)
@@ -102,28 +117,31 @@ func (r *ROMLoader) Load() error {
 		return fmt.Errorf("invalid ROM size: %d bytes (expected 3MB or 4MB)", len(data))
 	}
 
-	// Detect ROM type by reading SNES internal header
-	r.romType = r.detectROMType(data)
-
+	r.romType = info.Type
+	if r.romType == ROMTypeUnknown {
+		r.romType = r.detectROMType(data)
+	}
 	if r.romType == ROMTypeUnknown {
-		return fmt.Errorf("not a valid Final Fantasy VI ROM (header validation failed)")
+		return fmt.Errorf("not a valid Final Fantasy VI ROM (CRC32 %08X not known and header validation failed)", info.CRC32)
 	}
 
+	r.info = info
 	r.data = data
 	return nil
 }
 
-// detectROMType identifies the ROM version by reading the SNES internal header
+// detectROMType guesses the ROM version from the SNES internal header title.
+// It is only a fallback for images IdentifyROM does not recognise.
 func (r *ROMLoader) detectROMType(data []byte) ROMType {
-	// Try LoROM header first (FF6 uses LoROM)
-	if title := r.readGameTitle(data, snesHeaderOffsetLoROM); title != "" {
+	// Try HiROM header first (FF6 uses HiROM)
+	if title := r.readGameTitle(data, snesHeaderOffsetHiROM); title != "" {
 		if r.isFF6Title(title) {
 			return r.identifyRegion(title)
 		}
 	}
 
-	// Try HiROM header as fallback
-	if title := r.readGameTitle(data, snesHeaderOffsetHiROM); title != "" {
+	// Try LoROM header as fallback
+	if title := r.readGameTitle(data, snesHeaderOffsetLoROM); title != "" {
 		if r.isFF6Title(title) {
 			return r.identifyRegion(title)
 		}
@@ -186,6 +204,42 @@ func (r *ROMLoader) GetType() ROMType {
 	return r.romType
 }
 
+// HasHeader reports whether the file had a copier header
+func (r *ROMLoader) HasHeader() bool {
+	return r.hasHeader
+}
+
+// CopierHeader returns the stripped copier header, nil if there was none
+func (r *ROMLoader) CopierHeader() []byte {
+	return r.header
+}
+
+// Info returns the identification of the loaded ROM, nil before Load
+func (r *ROMLoader) Info() *ROMInfo {
+	return r.info
+}
+
+// Revision returns the known dump revision, or "" for unverified images
+func (r *ROMLoader) Revision() string {
+	if r.info == nil {
+		return ""
+	}
+	return r.info.Revision()
+}
+
+// ReadSNES reads bytes at a SNES bus address using the ROM's mapping
+func (r *ROMLoader) ReadSNES(addr uint32, length int) ([]byte, error) {
+	mapping := MappingHiROM
+	if r.info != nil && r.info.Mapping != MappingUnknown {
+		mapping = r.info.Mapping
+	}
+	offset, err := SNESToFile(mapping, addr)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadBytes(offset, length)
+}
+
 // IsValid returns true if ROM loaded successfully
 func (r *ROMLoader) IsValid() bool {
 	return len(r.data) > 0
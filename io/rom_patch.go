@@ -0,0 +1,257 @@
+package io
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// IPS limits: 24-bit offsets and 16-bit record sizes
+const (
+	ipsMaxOffset = 0xFFFFFF
+	ipsMaxRecord = 0xFFFF
+	// ipsEOFOffset would read as the "EOF" marker, so no record may start there
+	ipsEOFOffset = 0x454F46
+)
+
+// CreateIPS builds an IPS patch turning source into target. IPS cannot
+// shrink a file, so target must be at least as long as source.
+func CreateIPS(source, target []byte) ([]byte, error) {
+	if len(target) < len(source) {
+		return nil, fmt.Errorf("IPS cannot truncate: target is %d bytes, source %d", len(target), len(source))
+	}
+	if len(target) > ipsMaxOffset+1 {
+		return nil, fmt.Errorf("IPS cannot address %d bytes", len(target))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("PATCH")
+	differs := func(i int) bool { return i >= len(source) || source[i] != target[i] }
+	for i := 0; i < len(target); {
+		if !differs(i) {
+			i++
+			continue
+		}
+		start := i
+		if start == ipsEOFOffset {
+			start--
+		}
+		end := i
+		for end < len(target) && end-start < ipsMaxRecord && differs(end) {
+			end++
+		}
+		buf.Write([]byte{byte(start >> 16), byte(start >> 8), byte(start)})
+		buf.Write([]byte{byte((end - start) >> 8), byte(end - start)})
+		buf.Write(target[start:end])
+		i = end
+	}
+	buf.WriteString("EOF")
+	return buf.Bytes(), nil
+}
+
+// ApplyIPS applies an IPS patch, including RLE records, to a copy of source
+func ApplyIPS(source, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte("PATCH")) {
+		return nil, fmt.Errorf("not an IPS patch")
+	}
+	out := append([]byte(nil), source...)
+	pos := 5
+	for {
+		if pos+3 > len(patch) {
+			return nil, fmt.Errorf("IPS patch ends without EOF")
+		}
+		if string(patch[pos:pos+3]) == "EOF" {
+			return out, nil
+		}
+		if pos+5 > len(patch) {
+			return nil, fmt.Errorf("truncated IPS record at %d", pos)
+		}
+		offset := int(patch[pos])<<16 | int(patch[pos+1])<<8 | int(patch[pos+2])
+		size := int(patch[pos+3])<<8 | int(patch[pos+4])
+		pos += 5
+
+		var chunk []byte
+		if size == 0 {
+			if pos+3 > len(patch) {
+				return nil, fmt.Errorf("truncated IPS RLE record at %d", pos)
+			}
+			size = int(patch[pos])<<8 | int(patch[pos+1])
+			chunk = bytes.Repeat([]byte{patch[pos+2]}, size)
+			pos += 3
+		} else {
+			if pos+size > len(patch) {
+				return nil, fmt.Errorf("truncated IPS record at %d", pos)
+			}
+			chunk = patch[pos : pos+size]
+			pos += size
+		}
+		if offset+size > len(out) {
+			out = append(out, make([]byte, offset+size-len(out))...)
+		}
+		copy(out[offset:], chunk)
+	}
+}
+
+// BPS actions
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+func bpsEncode(buf *bytes.Buffer, n uint64) {
+	for {
+		x := n & 0x7F
+		n >>= 7
+		if n == 0 {
+			buf.WriteByte(0x80 | byte(x))
+			return
+		}
+		buf.WriteByte(byte(x))
+		n--
+	}
+}
+
+func bpsDecode(patch []byte, pos *int) (uint64, error) {
+	var n, shift uint64 = 0, 1
+	for {
+		if *pos >= len(patch) {
+			return 0, fmt.Errorf("truncated BPS number")
+		}
+		x := patch[*pos]
+		*pos++
+		n += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return n, nil
+		}
+		shift <<= 7
+		n += shift
+	}
+}
+
+// CreateBPS builds a BPS patch turning source into target, using source
+// reads for unchanged bytes and target reads for the rest
+func CreateBPS(source, target []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("BPS1")
+	bpsEncode(&buf, uint64(len(source)))
+	bpsEncode(&buf, uint64(len(target)))
+	bpsEncode(&buf, 0) // no metadata
+
+	same := func(i int) bool { return i < len(source) && source[i] == target[i] }
+	for i := 0; i < len(target); {
+		start := i
+		if same(i) {
+			for i < len(target) && same(i) {
+				i++
+			}
+			bpsEncode(&buf, uint64(i-start-1)<<2|bpsSourceRead)
+			continue
+		}
+		for i < len(target) && !same(i) {
+			i++
+		}
+		bpsEncode(&buf, uint64(i-start-1)<<2|bpsTargetRead)
+		buf.Write(target[start:i])
+	}
+
+	var footer [8]byte
+	binary.LittleEndian.PutUint32(footer[0:], crc32.ChecksumIEEE(source))
+	binary.LittleEndian.PutUint32(footer[4:], crc32.ChecksumIEEE(target))
+	buf.Write(footer[:])
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(sum[:])
+	return buf.Bytes()
+}
+
+// ApplyBPS applies a BPS patch to source, checking all three CRC32s
+func ApplyBPS(source, patch []byte) ([]byte, error) {
+	if len(patch) < 4+3+12 || string(patch[:4]) != "BPS1" {
+		return nil, fmt.Errorf("not a BPS patch")
+	}
+	body := len(patch) - 12
+	if crc32.ChecksumIEEE(patch[:body+8]) != binary.LittleEndian.Uint32(patch[body+8:]) {
+		return nil, fmt.Errorf("BPS patch is corrupt")
+	}
+	if crc32.ChecksumIEEE(source) != binary.LittleEndian.Uint32(patch[body:]) {
+		return nil, fmt.Errorf("BPS patch is for a different source file")
+	}
+
+	pos := 4
+	sourceSize, err := bpsDecode(patch, &pos)
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != uint64(len(source)) {
+		return nil, fmt.Errorf("BPS source size %d, file is %d", sourceSize, len(source))
+	}
+	targetSize, err := bpsDecode(patch, &pos)
+	if err != nil {
+		return nil, err
+	}
+	metaSize, err := bpsDecode(patch, &pos)
+	if err != nil {
+		return nil, err
+	}
+	pos += int(metaSize)
+
+	target := make([]byte, 0, targetSize)
+	var sourceRel, targetRel int
+	for pos < body {
+		n, err := bpsDecode(patch, &pos)
+		if err != nil {
+			return nil, err
+		}
+		length := int(n>>2) + 1
+		switch n & 3 {
+		case bpsSourceRead:
+			at := len(target)
+			if at+length > len(source) {
+				return nil, fmt.Errorf("BPS source read past end of source")
+			}
+			target = append(target, source[at:at+length]...)
+		case bpsTargetRead:
+			if pos+length > body {
+				return nil, fmt.Errorf("BPS target read past end of patch")
+			}
+			target = append(target, patch[pos:pos+length]...)
+			pos += length
+		case bpsSourceCopy, bpsTargetCopy:
+			d, err := bpsDecode(patch, &pos)
+			if err != nil {
+				return nil, err
+			}
+			delta := int(d >> 1)
+			if d&1 != 0 {
+				delta = -delta
+			}
+			if n&3 == bpsSourceCopy {
+				sourceRel += delta
+				if sourceRel < 0 || sourceRel+length > len(source) {
+					return nil, fmt.Errorf("BPS source copy out of range")
+				}
+				target = append(target, source[sourceRel:sourceRel+length]...)
+				sourceRel += length
+			} else {
+				targetRel += delta
+				if targetRel < 0 || targetRel >= len(target) {
+					return nil, fmt.Errorf("BPS target copy out of range")
+				}
+				for i := 0; i < length; i++ {
+					target = append(target, target[targetRel])
+					targetRel++
+				}
+			}
+		}
+	}
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("BPS produced %d bytes, want %d", len(target), targetSize)
+	}
+	if crc32.ChecksumIEEE(target) != binary.LittleEndian.Uint32(patch[body+4:]) {
+		return nil, fmt.Errorf("BPS result does not match the target checksum")
+	}
+	return target, nil
+}
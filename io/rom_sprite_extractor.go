@@ -3,6 +3,7 @@ package io
 import (
 	"ffvi_editor/models"
 	"fmt"
+	"hash/crc32"
 	"sync"
 	"time"
 )
@@ -10,6 +11,7 @@ import (
 // ROMSpriteExtractor extracts sprite data from FF6 ROM files
 type ROMSpriteExtractor struct {
 	rom               *ROMLoader
+	assets            *AssetTable
 	decompressor      *LZ77Decompressor
 	mu                sync.RWMutex
 	paletteCache      map[int]*models.Palette   // Cached palettes (all 14 characters)
@@ -36,70 +38,183 @@ func NewROMSpriteExtractor(romPath string) (*ROMSpriteExtractor, error) {
 		}
 	}
 
+	return NewROMSpriteExtractorFromLoader(rom)
+}
+
+// NewROMSpriteExtractorFromLoader creates an extractor for an already loaded
+// ROM, using the asset table for its identified revision
+func NewROMSpriteExtractorFromLoader(rom *ROMLoader) (*ROMSpriteExtractor, error) {
+	assets, err := AssetTableFor(rom.Info())
+	if err != nil {
+		return nil, err
+	}
+	return NewROMSpriteExtractorWithTable(rom, assets), nil
+}
+
+// NewROMSpriteExtractorWithTable creates an extractor reading a loaded ROM
+// through the given asset table, e.g. a DraftAssetTable being checked
+func NewROMSpriteExtractorWithTable(rom *ROMLoader, assets *AssetTable) *ROMSpriteExtractor {
 	return &ROMSpriteExtractor{
 		rom:               rom,
+		assets:            assets,
 		decompressor:      NewLZ77Decompressor(),
 		paletteCache:      make(map[int]*models.Palette),
 		battleSpriteCache: make(map[int]*models.FF6Sprite),
 		fieldSpriteCache:  make(map[int]*models.FF6Sprite),
-	}, nil
+	}
 }
 
-// ExtractCharacterSprite extracts a character's field sprite from ROM
-func (e *ROMSpriteExtractor) ExtractCharacterSprite(characterID int) (*models.FF6Sprite, error) {
-	// Get sprite offset for this character
-	offset, ok := CharacterSpriteOffsets[characterID]
+// ROMInfo returns the identification of the ROM being read
+func (e *ROMSpriteExtractor) ROMInfo() *ROMInfo {
+	return e.rom.Info()
+}
+
+// Assets returns the asset table the extractor reads from
+func (e *ROMSpriteExtractor) Assets() *AssetTable {
+	return e.assets
+}
+
+// readAsset reads an asset's bytes, decompressing if needed, and checks them
+// against the asset's size and checksum
+func (e *ROMSpriteExtractor) readAsset(a *ROMAsset) ([]byte, error) {
+	var data []byte
+	if a.Compressed {
+		rom := e.rom.GetData()
+		if a.Offset >= len(rom) {
+			return nil, fmt.Errorf("%s: offset 0x%X out of bounds (ROM size: %d)", a.ID, a.Offset, len(rom))
+		}
+		var err error
+		if data, _, err = e.decompressor.DecompressSize(rom[a.Offset:], a.Size); err != nil {
+			return nil, fmt.Errorf("%s: %w", a.ID, err)
+		}
+	} else {
+		raw, err := e.rom.ReadBytes(a.Offset, a.Size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.ID, err)
+		}
+		data = append([]byte(nil), raw...)
+	}
+	// The data is returned on a checksum failure so reports can show it
+	return data, a.Check(data)
+}
+
+// ExtractAsset reads an asset by ID from the revision's table
+func (e *ROMSpriteExtractor) ExtractAsset(id string) ([]byte, *ROMAsset, error) {
+	a, ok := e.assets.Asset(id)
 	if !ok {
-		return nil, fmt.Errorf("unknown character ID: %d", characterID)
+		return nil, nil, fmt.Errorf("no asset %q for revision %s", id, e.assets.Revision)
 	}
+	data, err := e.readAsset(a)
+	return data, a, err
+}
+
+// AssetStatus is the result of reading one asset for AssetReport
+type AssetStatus struct {
+	Asset ROMAsset
+	CRC32 uint32 // of the data actually read, 0 if it could not be read
+	Err   error
+}
+
+// AssetReport reads every asset in the table. Entries without a recorded
+// checksum report what the ROM holds, which is how tables get verified.
+func (e *ROMSpriteExtractor) AssetReport() []AssetStatus {
+	report := make([]AssetStatus, 0, len(e.assets.Assets))
+	for i := range e.assets.Assets {
+		a := &e.assets.Assets[i]
+		st := AssetStatus{Asset: *a}
+		data, err := e.readAsset(a)
+		if data != nil {
+			st.CRC32 = crc32.ChecksumIEEE(data)
+		}
+		st.Err = err
+		report = append(report, st)
+	}
+	return report
+}
+
+// spriteFromAsset builds an FF6Sprite from a sprite asset's data
+func spriteFromAsset(a *ROMAsset, data []byte, palette *models.Palette) *models.FF6Sprite {
+	spriteType := models.SpriteTypeCharacter
+	switch a.Kind {
+	case AssetBattleSprite:
+		spriteType = models.SpriteTypeBattle
+	case AssetPortrait:
+		spriteType = models.SpriteTypePortrait
+	case AssetNPCSprite:
+		spriteType = models.SpriteTypeNPC
+	case AssetMonsterSprite:
+		spriteType = models.SpriteTypeEnemy
+	}
+	return &models.FF6Sprite{
+		ID:           a.ID,
+		Name:         a.Name,
+		Type:         spriteType,
+		Width:        a.Width,
+		Height:       a.Height,
+		Frames:       a.Frames,
+		Data:         data,
+		Palette:      palette,
+		IsCompressed: false,
+		Checksum:     fmt.Sprintf("%08X", crc32.ChecksumIEEE(data)),
+	}
+}
 
-	// Read compressed sprite data from ROM
-	// Typical field sprite is ~1-2KB compressed
-	compressedData, err := e.rom.ReadBytes(offset.FieldOffset, 2048)
+// ExtractSprite reads any sprite asset by ID. paletteID names the palette
+// asset to attach; empty uses the default palette.
+func (e *ROMSpriteExtractor) ExtractSprite(id, paletteID string) (*models.FF6Sprite, error) {
+	data, a, err := e.ExtractAsset(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read sprite data: %w", err)
+		return nil, err
+	}
+	if a.Kind == AssetPalette {
+		return nil, fmt.Errorf("%s is a palette, not a sprite", id)
 	}
 
-	// Decompress if needed
-	var spriteData []byte
-	if offset.Compressed {
-		spriteData, err = e.decompressor.Decompress(compressedData)
+	palette := e.extractDefaultPalette(a.Character)
+	if paletteID != "" {
+		raw, p, err := e.ExtractAsset(paletteID)
 		if err != nil {
-			// If decompression fails, try to use raw data
-			spriteData = compressedData
+			return nil, err
 		}
-	} else {
-		spriteData = compressedData
+		palette = decodePalette(raw, p.Name)
 	}
+	return spriteFromAsset(a, data, palette), nil
+}
 
-	// Field sprites are 16x24 pixels, 3 frames
-	// Each frame is 16*24/2 = 192 bytes (4bpp = 0.5 bytes per pixel)
-	expectedSize := 16 * 24 * 3 / 2 // 576 bytes for 3 frames
-
-	// If we got more data, truncate to expected size
-	if len(spriteData) > expectedSize {
-		spriteData = spriteData[:expectedSize]
+// extractCharacterSprite reads a playable character's sprite of one kind
+// with the character's palette
+func (e *ROMSpriteExtractor) extractCharacterSprite(characterID int, kind AssetKind) (*models.FF6Sprite, error) {
+	a, ok := e.assets.CharacterAsset(characterID, kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown character ID: %d", characterID)
 	}
-
-	// If we got less data, pad with zeros
-	if len(spriteData) < expectedSize {
-		padding := make([]byte, expectedSize-len(spriteData))
-		spriteData = append(spriteData, padding...)
+	data, err := e.readAsset(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sprite data: %w", err)
+	}
+	palette, err := e.ExtractCharacterPalette(characterID)
+	if err != nil {
+		return nil, err
 	}
+	return spriteFromAsset(a, data, palette), nil
+}
 
-	// Extract palette (usually stored near sprite data or use default)
-	palette := e.extractDefaultPalette(characterID)
+// ExtractCharacterSprite extracts a character's field sprite from ROM
+func (e *ROMSpriteExtractor) ExtractCharacterSprite(characterID int) (*models.FF6Sprite, error) {
+	return e.extractCharacterSprite(characterID, AssetFieldSprite)
+}
 
-	// Create FF6Sprite
-	sprite := &models.FF6Sprite{
-		Width:   16,
-		Height:  24,
-		Frames:  3,
-		Data:    spriteData,
-		Palette: palette,
+// ExtractPortrait extracts a character's menu portrait with its palette
+func (e *ROMSpriteExtractor) ExtractPortrait(characterID int) (*models.FF6Sprite, error) {
+	a, ok := e.assets.CharacterAsset(characterID, AssetPortrait)
+	if !ok {
+		return nil, fmt.Errorf("unknown character ID: %d", characterID)
 	}
-
-	return sprite, nil
+	p, ok := e.assets.PaletteFor(a)
+	if !ok {
+		return nil, fmt.Errorf("%s has no palette asset", a.ID)
+	}
+	return e.ExtractSprite(a.ID, p.ID)
 }
 
 // extractDefaultPalette returns a default palette for a character
@@ -139,94 +254,27 @@ func (e *ROMSpriteExtractor) IsROMLoaded() bool {
 // ExtractBattleSprite extracts a character's battle sprite from ROM
 // Battle sprites are 32x32 pixels with 6 frames (idle, attack, magic, damage, victory, dead)
 func (e *ROMSpriteExtractor) ExtractBattleSprite(characterID int) (*models.FF6Sprite, error) {
-	// Get sprite offset for this character
-	offset, ok := CharacterSpriteOffsets[characterID]
-	if !ok {
-		return nil, fmt.Errorf("unknown character ID: %d", characterID)
-	}
-
-	// Read compressed battle sprite data from ROM
-	// Battle sprites are typically 3-8KB compressed
-	compressedData, err := e.rom.ReadBytes(offset.BattleOffset, 8192)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read battle sprite data: %w", err)
-	}
-
-	// Decompress if needed
-	var spriteData []byte
-	if offset.Compressed {
-		spriteData, err = e.decompressor.Decompress(compressedData)
-		if err != nil {
-			// If decompression fails, try to use raw data
-			spriteData = compressedData
-		}
-	} else {
-		spriteData = compressedData
-	}
-
-	// Battle sprites are 32x32 pixels, 6 frames
-	// Each frame: 32*32/2 = 512 bytes (4bpp = 0.5 bytes per pixel)
-	// Total: 512 * 6 = 3072 bytes for 6 frames
-	expectedSize := 32 * 32 * 6 / 2 // 3072 bytes for 6 frames
-
-	// If we got more data, truncate to expected size
-	if len(spriteData) > expectedSize {
-		spriteData = spriteData[:expectedSize]
-	}
-
-	// If we got less data, pad with zeros
-	if len(spriteData) < expectedSize {
-		padding := make([]byte, expectedSize-len(spriteData))
-		spriteData = append(spriteData, padding...)
-	}
-
-	// Extract palette for battle sprite
-	palette, err := e.ExtractCharacterPalette(characterID)
-	if err != nil {
-		// Fall back to default if extraction fails
-		palette = e.extractDefaultPalette(characterID)
-	}
-
-	// Create FF6Sprite for battle sprite
-	sprite := &models.FF6Sprite{
-		Width:   32,
-		Height:  32,
-		Frames:  6,
-		Data:    spriteData,
-		Palette: palette,
-		Type:    models.SpriteTypeBattle,
-	}
-
-	return sprite, nil
+	return e.extractCharacterSprite(characterID, AssetBattleSprite)
 }
 
-// ExtractCharacterPalette extracts the 16-color palette for a character from ROM
-// FF6 stores palettes in a separate location from sprite data
-// Palette locations typically start at 0x2C0000 in USA version
+// ExtractCharacterPalette extracts the 16-color palette for a character from
+// ROM. Several characters share a palette, which the asset table records.
 func (e *ROMSpriteExtractor) ExtractCharacterPalette(characterID int) (*models.Palette, error) {
-	if characterID < 0 || characterID > 13 {
+	a, ok := e.assets.CharacterAsset(characterID, AssetPalette)
+	if !ok {
 		return nil, fmt.Errorf("invalid character ID: %d", characterID)
 	}
-
-	// FF6 character palettes are stored in sequence
-	// Each palette is 16 colors × 2 bytes (5-bit RGB) = 32 bytes
-	paletteBaseOffset := 0x2C0000 // Character palette base in ROM
-	paletteSize := 32             // 16 colors × 2 bytes
-	paletteOffset := paletteBaseOffset + (characterID * paletteSize)
-
-	// Read palette data from ROM
-	paletteData, err := e.rom.ReadBytes(paletteOffset, paletteSize)
+	paletteData, err := e.readAsset(a)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read palette data: %w", err)
 	}
+	return decodePalette(paletteData, a.Name), nil
+}
 
-	if len(paletteData) < paletteSize {
-		return nil, fmt.Errorf("insufficient palette data read: got %d, expected %d", len(paletteData), paletteSize)
-	}
-
-	// Parse palette data (5-bit RGB, little-endian 16-bit values)
+// decodePalette parses 16 SNES colors (5-bit RGB, little-endian 16-bit values)
+func decodePalette(paletteData []byte, name string) *models.Palette {
 	var colors [16]models.RGB555
-	for i := 0; i < 16; i++ {
+	for i := 0; i < 16 && i*2+1 < len(paletteData); i++ {
 		// Each color is 2 bytes, little-endian
 		// Format: GGGRRRRR XBBBBBGG (where X is unused)
 		low := paletteData[i*2]
@@ -240,19 +288,12 @@ func (e *ROMSpriteExtractor) ExtractCharacterPalette(characterID int) (*models.P
 		colors[i] = models.RGB555{R: r, G: g, B: b}
 	}
 
-	character, ok := CharacterSpriteOffsets[characterID]
-	if !ok {
-		character.Name = fmt.Sprintf("Character %d", characterID)
-	}
-
-	palette := &models.Palette{
+	return &models.Palette{
 		Colors:   colors,
-		Name:     fmt.Sprintf("%s Palette", character.Name),
+		Name:     name,
 		Created:  time.Now(),
 		Modified: time.Now(),
 	}
-
-	return palette, nil
 }
 
 // ExtractAllCharacterPalettes extracts palettes for all 14 playable characters
@@ -0,0 +1,393 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	"ffvi_editor/models"
+)
+
+// LZ77 stream limits, matching what LZ77Decompressor reads: a back reference
+// is 4 bits of length (3-18) and 12 bits of distance (1-4096)
+const (
+	lzMinMatch = 3
+	lzMaxMatch = 18
+	lzWindow   = 4096
+)
+
+// LZ77Compressor produces streams LZ77Decompressor reads back unchanged
+type LZ77Compressor struct{}
+
+// NewLZ77Compressor creates a new compressor
+func NewLZ77Compressor() *LZ77Compressor {
+	return &LZ77Compressor{}
+}
+
+// Compress encodes data with greedy longest-match search over the window
+func (c *LZ77Compressor) Compress(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/8+1)
+	pos := 0
+	for pos < len(data) {
+		controlAt := len(out)
+		out = append(out, 0)
+		for bit := 0; bit < 8 && pos < len(data); bit++ {
+			length, distance := longestMatch(data, pos)
+			if length < lzMinMatch {
+				out = append(out, data[pos])
+				pos++
+				continue
+			}
+			out[controlAt] |= 1 << bit
+			d := distance - 1
+			out = append(out, byte((length-lzMinMatch)<<4|d>>8), byte(d))
+			pos += length
+		}
+	}
+	return out
+}
+
+// longestMatch finds the longest earlier run matching data[pos:]. Matches may
+// overlap pos, as the decompressor copies byte by byte.
+func longestMatch(data []byte, pos int) (int, int) {
+	bestLen, bestDist := 0, 0
+	start := pos - lzWindow
+	if start < 0 {
+		start = 0
+	}
+	limit := len(data) - pos
+	if limit > lzMaxMatch {
+		limit = lzMaxMatch
+	}
+	for i := pos - 1; i >= start; i-- {
+		n := 0
+		for n < limit && data[i+n] == data[pos+n] {
+			n++
+		}
+		if n > bestLen {
+			bestLen, bestDist = n, pos-i
+			if n == limit {
+				break
+			}
+		}
+	}
+	return bestLen, bestDist
+}
+
+// ErrSharedPalette is returned when a palette other characters are also
+// drawn with would change and shared palettes have not been allowed
+var ErrSharedPalette = errors.New("palette is shared")
+
+// InjectResult reports where an injected asset ended up
+type InjectResult struct {
+	Asset     ROMAsset // with its offset after injection
+	Relocated bool
+	// StoredSize is the number of bytes written to the ROM, compressed or not
+	StoredSize int
+	// PaletteUsers names everything drawn with a shared palette that changed
+	PaletteUsers []string
+}
+
+// ROMPatcher writes edited assets back into a copy of a loaded ROM and
+// produces the patched image and IPS/BPS patches against the original
+type ROMPatcher struct {
+	rom          *ROMLoader
+	original     []byte
+	data         []byte
+	assets       *AssetTable
+	compressor   *LZ77Compressor
+	decompressor *LZ77Decompressor
+	mapping      ROMMapping
+	allowShared  bool
+}
+
+// NewROMPatcher creates a patcher for a loaded ROM. It works on a copy of the
+// revision's asset table, so relocations do not leak into AssetTables, and
+// refuses tables whose assets overlap.
+func NewROMPatcher(rom *ROMLoader) (*ROMPatcher, error) {
+	if !rom.IsValid() {
+		return nil, fmt.Errorf("ROM is not loaded")
+	}
+	assets, err := AssetTableFor(rom.Info())
+	if err != nil {
+		return nil, err
+	}
+	if err = assets.Validate(); err != nil {
+		return nil, err
+	}
+	mapping := rom.Info().Mapping
+	if mapping == MappingUnknown {
+		mapping = MappingHiROM
+	}
+	return &ROMPatcher{
+		rom:          rom,
+		original:     rom.GetData(),
+		data:         append([]byte(nil), rom.GetData()...),
+		assets:       assets.Clone(),
+		compressor:   NewLZ77Compressor(),
+		decompressor: NewLZ77Decompressor(),
+		mapping:      mapping,
+	}, nil
+}
+
+// Assets returns the patcher's asset table, including relocated offsets
+func (p *ROMPatcher) Assets() *AssetTable {
+	return p.assets
+}
+
+// AllowSharedPalettes lets palette injection change palettes that other
+// characters are drawn with too. Results list everything affected.
+func (p *ROMPatcher) AllowSharedPalettes(allow bool) {
+	p.allowShared = allow
+}
+
+// checkPalette refuses a shared palette unless shared palettes are allowed,
+// and returns the names of everything drawn with it when it is shared
+func (p *ROMPatcher) checkPalette(a *ROMAsset) ([]string, error) {
+	users := p.assets.PaletteUsers(a)
+	if len(users) < 2 {
+		return nil, nil
+	}
+	if !p.allowShared {
+		return nil, fmt.Errorf("%s: %w by %s; allow shared palettes to change all of them",
+			a.ID, ErrSharedPalette, strings.Join(users, ", "))
+	}
+	return users, nil
+}
+
+// Data returns the patched image without a copier header
+func (p *ROMPatcher) Data() []byte {
+	return p.data
+}
+
+// InjectAsset replaces an asset's data. Uncompressed assets are written in
+// place. Compressed assets are recompressed and written in place when they
+// still fit, otherwise moved to free space with their pointer updated.
+func (p *ROMPatcher) InjectAsset(id string, data []byte) (*InjectResult, error) {
+	a, ok := p.assets.Asset(id)
+	if !ok {
+		return nil, fmt.Errorf("no asset %q for revision %s", id, p.assets.Revision)
+	}
+	if len(data) != a.Size {
+		return nil, fmt.Errorf("%s: got %d bytes, want %d", id, len(data), a.Size)
+	}
+	var users []string
+	if a.Kind == AssetPalette {
+		var err error
+		if users, err = p.checkPalette(a); err != nil {
+			return nil, err
+		}
+	}
+
+	if !a.Compressed {
+		if a.Offset+a.Size > len(p.data) {
+			return nil, fmt.Errorf("%s: offset 0x%X out of bounds (ROM size: %d)", id, a.Offset, len(p.data))
+		}
+		copy(p.data[a.Offset:], data)
+		a.CRC32 = 0
+		return &InjectResult{Asset: *a, StoredSize: len(data), PaletteUsers: users}, nil
+	}
+
+	_, oldSize, err := p.decompressor.DecompressSize(p.data[a.Offset:], a.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading current data: %w", id, err)
+	}
+	packed := p.compressor.Compress(data)
+	result := &InjectResult{StoredSize: len(packed), PaletteUsers: users}
+
+	if len(packed) <= oldSize {
+		copy(p.data[a.Offset:], packed)
+		fill(p.data[a.Offset+len(packed):a.Offset+oldSize], 0xFF)
+	} else {
+		if a.Pointer <= 0 {
+			return nil, fmt.Errorf("%s: recompressed data is %d bytes, %d available, and the asset has no known pointer to relocate it",
+				id, len(packed), oldSize)
+		}
+		// Free the old copy first so the search can reuse it
+		previous := append([]byte(nil), p.data[a.Offset:a.Offset+oldSize]...)
+		fill(p.data[a.Offset:a.Offset+oldSize], 0xFF)
+		offset, err := p.findFreeSpace(len(packed))
+		if err != nil {
+			copy(p.data[a.Offset:], previous)
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		addr, err := FileToSNES(p.mapping, offset)
+		if err != nil {
+			return nil, err
+		}
+		copy(p.data[offset:], packed)
+		p.data[a.Pointer], p.data[a.Pointer+1], p.data[a.Pointer+2] = byte(addr), byte(addr>>8), byte(addr>>16)
+		a.Offset = offset
+		result.Relocated = true
+	}
+	// The recorded checksum was for the original data
+	a.CRC32 = 0
+	result.Asset = *a
+	return result, nil
+}
+
+// InjectSprite writes a sprite's tile data over a sprite asset
+func (p *ROMPatcher) InjectSprite(id string, sprite *models.FF6Sprite) (*InjectResult, error) {
+	a, ok := p.assets.Asset(id)
+	if !ok {
+		return nil, fmt.Errorf("no asset %q for revision %s", id, p.assets.Revision)
+	}
+	if a.Kind == AssetPalette {
+		return nil, fmt.Errorf("%s is a palette, not a sprite", id)
+	}
+	if sprite.IsCompressed {
+		return nil, fmt.Errorf("%s: sprite data must be uncompressed tiles", id)
+	}
+	return p.InjectAsset(id, sprite.Data)
+}
+
+// InjectPalette writes a palette over a palette asset
+func (p *ROMPatcher) InjectPalette(id string, palette *models.Palette) (*InjectResult, error) {
+	a, ok := p.assets.Asset(id)
+	if !ok {
+		return nil, fmt.Errorf("no asset %q for revision %s", id, p.assets.Revision)
+	}
+	if a.Kind != AssetPalette {
+		return nil, fmt.Errorf("%s is not a palette", id)
+	}
+	return p.InjectAsset(id, encodePalette(palette))
+}
+
+// Palette decodes a palette asset from the patched image
+func (p *ROMPatcher) Palette(id string) (*models.Palette, error) {
+	a, ok := p.assets.Asset(id)
+	if !ok || a.Kind != AssetPalette || a.Compressed {
+		return nil, fmt.Errorf("no uncompressed palette asset %q", id)
+	}
+	if a.Offset+a.Size > len(p.data) {
+		return nil, fmt.Errorf("%s: offset 0x%X out of bounds (ROM size: %d)", id, a.Offset, len(p.data))
+	}
+	return decodePalette(p.data[a.Offset:a.Offset+a.Size], a.Name), nil
+}
+
+// InjectImage encodes an image with the sprite's frames side by side and
+// injects it. The pixels are matched to the sprite's palette in the ROM, or
+// with withPalette the image's own colors replace that palette, which is
+// refused for shared palettes unless AllowSharedPalettes is set.
+func (p *ROMPatcher) InjectImage(id string, img image.Image, withPalette bool) (*InjectResult, error) {
+	a, ok := p.assets.Asset(id)
+	if !ok {
+		return nil, fmt.Errorf("no asset %q for revision %s", id, p.assets.Revision)
+	}
+	pa, ok := p.assets.PaletteFor(a)
+	if !ok {
+		return nil, fmt.Errorf("%s has no palette asset to match colors against; inject raw tile data instead", id)
+	}
+
+	var palette *models.Palette
+	var err error
+	if withPalette {
+		// Check before the sprite is written so a refusal changes nothing
+		if _, err = p.checkPalette(pa); err != nil {
+			return nil, err
+		}
+		if palette, err = NewPaletteExtractor().Extract(img, 16); err != nil {
+			return nil, err
+		}
+	} else if palette, err = p.Palette(pa.ID); err != nil {
+		return nil, err
+	}
+
+	data, err := NewFF6SpriteConverter().EncodeFrames(img, palette, a.Width, a.Height, a.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	result, err := p.InjectAsset(id, data)
+	if err != nil {
+		return nil, err
+	}
+	if withPalette {
+		paletteResult, err := p.InjectPalette(pa.ID, palette)
+		if err != nil {
+			return nil, err
+		}
+		result.PaletteUsers = paletteResult.PaletteUsers
+	}
+	return result, nil
+}
+
+// encodePalette packs 16 colors into SNES format, the inverse of decodePalette
+func encodePalette(palette *models.Palette) []byte {
+	data := make([]byte, 32)
+	for i, c := range palette.Colors {
+		v := uint16(c.R&0x1F) | uint16(c.G&0x1F)<<5 | uint16(c.B&0x1F)<<10
+		data[i*2], data[i*2+1] = byte(v), byte(v>>8)
+	}
+	return data
+}
+
+// findFreeSpace returns the first run of 0xFF bytes of the given size that
+// stays inside one 64KB bank and overlaps no asset in the table
+func (p *ROMPatcher) findFreeSpace(size int) (int, error) {
+	run := 0
+	for i := 0; i < len(p.data); i++ {
+		if i%0x10000 == 0 {
+			run = 0
+		}
+		if p.data[i] != 0xFF {
+			run = 0
+			continue
+		}
+		run++
+		if run < size {
+			continue
+		}
+		start := i - size + 1
+		if !p.overlapsAsset(start, size) {
+			return start, nil
+		}
+	}
+	return 0, fmt.Errorf("no %d byte run of free space (0xFF) in the ROM", size)
+}
+
+func (p *ROMPatcher) overlapsAsset(offset, size int) bool {
+	for _, a := range p.assets.Assets {
+		if !a.Compressed && offset < a.Offset+a.Size && a.Offset < offset+size {
+			return true
+		}
+	}
+	return false
+}
+
+// FixChecksum recomputes the internal checksum and complement and returns
+// the new checksum
+func (p *ROMPatcher) FixChecksum() uint16 {
+	offset := headerOffset(p.mapping)
+	h := p.data[offset:]
+	h[snesComplementOffset], h[snesComplementOffset+1] = 0xFF, 0xFF
+	h[snesChecksumOffset], h[snesChecksumOffset+1] = 0, 0
+	sum := SNESChecksum(p.data)
+	h[snesChecksumOffset], h[snesChecksumOffset+1] = byte(sum), byte(sum>>8)
+	h[snesComplementOffset], h[snesComplementOffset+1] = byte(^sum), byte(^sum>>8)
+	return sum
+}
+
+// WriteROM saves the patched image, restoring the copier header if the
+// source file had one
+func (p *ROMPatcher) WriteROM(path string) error {
+	out := append(append([]byte(nil), p.rom.CopierHeader()...), p.data...)
+	return os.WriteFile(path, out, 0o644)
+}
+
+// IPS returns an IPS patch from the original headerless image to the patched one
+func (p *ROMPatcher) IPS() ([]byte, error) {
+	return CreateIPS(p.original, p.data)
+}
+
+// BPS returns a BPS patch from the original headerless image to the patched one
+func (p *ROMPatcher) BPS() []byte {
+	return CreateBPS(p.original, p.data)
+}
+
+func fill(b []byte, v byte) {
+	for i := range b {
+		b[i] = v
+	}
+}
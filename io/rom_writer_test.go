@@ -0,0 +1,277 @@
+package io
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"ffvi_editor/models"
+)
+
+func TestLZ77CompressRoundTrip(t *testing.T) {
+	repetitive := bytes.Repeat([]byte{0, 0, 0x11, 0x22, 0xFF, 0x11}, 200)
+	mixed := make([]byte, 3000)
+	for i := range mixed {
+		mixed[i] = byte(i*i + i/7)
+	}
+	c, d := NewLZ77Compressor(), NewLZ77Decompressor()
+	for name, data := range map[string][]byte{"repetitive": repetitive, "mixed": mixed, "short": {1, 2}} {
+		packed := c.Compress(data)
+		got, used, err := d.DecompressSize(packed, len(data))
+		if err != nil || !bytes.Equal(got, data) || used != len(packed) {
+			t.Errorf("%s: round trip failed (used %d of %d): %v", name, used, len(packed), err)
+		}
+		if whole, err := d.Decompress(packed); err != nil || !bytes.Equal(whole, data) {
+			t.Errorf("%s: Decompress disagrees with DecompressSize: %v", name, err)
+		}
+	}
+	if packed := c.Compress(repetitive); len(packed) >= len(repetitive)/4 {
+		t.Errorf("repetitive data compressed to %d of %d bytes", len(packed), len(repetitive))
+	}
+}
+
+func newTestPatcher(t *testing.T, rom []byte) *ROMPatcher {
+	t.Helper()
+	loader := &ROMLoader{}
+	if err := loader.LoadData(rom); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewROMPatcher(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInjectSpriteAndPalette(t *testing.T) {
+	table := useTestAssetTable(t)
+	rom := buildAssetROM(t, table)
+	p := newTestPatcher(t, rom)
+
+	sprite := models.NewSprite("terra", "Terra", models.SpriteTypeCharacter)
+	sprite.Data = bytes.Repeat([]byte{0x5A}, 576)
+	if _, err := p.InjectSprite("field/terra", sprite); err != nil {
+		t.Fatal(err)
+	}
+	palette := models.NewPalette("Fire Terra")
+	palette.Colors[1] = models.RGB555{R: 31, G: 4, B: 2}
+	if _, err := p.InjectPalette("palette/terra", palette); err != nil {
+		t.Fatal(err)
+	}
+	sprite.Data = sprite.Data[:100]
+	if _, err := p.InjectSprite("field/terra", sprite); err == nil {
+		t.Error("injecting a sprite of the wrong size should fail")
+	}
+
+	p.FixChecksum()
+	info := IdentifyROM(p.Data())
+	if !info.ChecksumOK {
+		t.Error("checksum not fixed after injection")
+	}
+
+	e := newTestExtractor(t, p.Data())
+	got, err := e.ExtractCharacterSprite(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Data[0] != 0x5A || got.Palette.Colors[1] != palette.Colors[1] {
+		t.Errorf("extracted sprite does not hold the injected data: %X %+v", got.Data[0], got.Palette.Colors[1])
+	}
+
+	// The original image is untouched
+	if rom[table.Assets[0].Offset] == 0x5A {
+		t.Error("patcher modified the loaded ROM")
+	}
+}
+
+func TestInjectCompressedRelocates(t *testing.T) {
+	defer ResetAssetTables()
+	rom := buildTestROM("FINAL FANTASY 3")
+	const at, pointer, free = 0x200000, 0x210000, 0x2F0000
+	original := bytes.Repeat([]byte{7}, 256)
+	copy(rom[at:], NewLZ77Compressor().Compress(original))
+	fill(rom[free:free+0x1000], 0xFF)
+
+	AssetTables[RevisionUS10] = &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{{
+		ID: "monster/test", Name: "Test", Kind: AssetMonsterSprite, Character: -1,
+		Offset: at, Size: len(original), Compressed: true, Pointer: pointer,
+	}}}
+	p := newTestPatcher(t, rom)
+
+	// Still compresses well, so it stays in place
+	res, err := p.InjectAsset("monster/test", bytes.Repeat([]byte{9}, 256))
+	if err != nil || res.Relocated || res.Asset.Offset != at {
+		t.Fatalf("small edit: %+v, %v", res, err)
+	}
+
+	// Incompressible data no longer fits and moves to free space
+	noisy := make([]byte, 256)
+	for i := range noisy {
+		noisy[i] = byte(i * 37)
+	}
+	res, err = p.InjectAsset("monster/test", noisy)
+	if err != nil || !res.Relocated {
+		t.Fatalf("large edit: %+v, %v", res, err)
+	}
+	addr := uint32(p.Data()[pointer]) | uint32(p.Data()[pointer+1])<<8 | uint32(p.Data()[pointer+2])<<16
+	if off, _ := SNESToFile(MappingHiROM, addr); off != res.Asset.Offset {
+		t.Errorf("pointer $%06X -> 0x%X, asset moved to 0x%X", addr, off, res.Asset.Offset)
+	}
+	got, _, err := NewLZ77Decompressor().DecompressSize(p.Data()[res.Asset.Offset:], 256)
+	if err != nil || !bytes.Equal(got, noisy) {
+		t.Errorf("relocated data does not decompress to the edit: %v", err)
+	}
+	if p.Data()[at] != 0xFF {
+		t.Error("old location was not freed")
+	}
+
+	// Without a known pointer growing data cannot move
+	if _, err := p.InjectAsset("monster/test", bytes.Repeat([]byte{9}, 256)); err != nil {
+		t.Fatal(err)
+	}
+	p.Assets().Assets[0].Pointer = 0
+	if _, err := p.InjectAsset("monster/test", noisy); err == nil {
+		t.Error("relocating an asset without a pointer should fail")
+	}
+}
+
+func TestIPSAndBPSPatches(t *testing.T) {
+	source := make([]byte, 0x460000)
+	for i := range source {
+		source[i] = byte(i)
+	}
+	target := append([]byte(nil), source...)
+	target[10] ^= 0xFF
+	for i := 0x1000; i < 0x1000+70000; i++ {
+		target[i] = 0
+	}
+	target[ipsEOFOffset] ^= 0xFF
+
+	ips, err := CreateIPS(source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ApplyIPS(source, ips); err != nil || !bytes.Equal(got, target) {
+		t.Errorf("IPS round trip failed: %v", err)
+	}
+	if _, err := CreateIPS(target, target[:100]); err == nil {
+		t.Error("IPS should refuse to truncate")
+	}
+
+	bps := CreateBPS(source, target)
+	if got, err := ApplyBPS(source, bps); err != nil || !bytes.Equal(got, target) {
+		t.Errorf("BPS round trip failed: %v", err)
+	}
+	if _, err := ApplyBPS(target, bps); err == nil {
+		t.Error("BPS should refuse the wrong source")
+	}
+	if len(bps) > 72000 {
+		t.Errorf("BPS patch is %d bytes for ~70KB of changes", len(bps))
+	}
+}
+
+func TestInjectImage(t *testing.T) {
+	p := newTestPatcher(t, buildAssetROM(t, useTestAssetTable(t)))
+	palette, err := p.Palette("palette/terra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three 16x24 frames painted with palette colours 1, 2 and 3
+	img := image.NewRGBA(image.Rect(0, 0, 48, 24))
+	for x := 0; x < 48; x++ {
+		for y := 0; y < 24; y++ {
+			img.Set(x, y, palette.Colors[1+x/16].ToColor())
+		}
+	}
+	if _, err := p.InjectImage("field/terra", img, false); err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestExtractor(t, p.Data())
+	sprite, err := e.ExtractCharacterSprite(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewFF6SpriteConverter().DecodeFF6Sprite(&models.FF6Sprite{
+		Width: 16, Height: 24, Frames: 1, Data: sprite.Data[192*2:], Palette: sprite.Palette,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, _ := decoded.At(3, 5).RGBA()
+	wr, wg, wb, _ := palette.Colors[3].ToColor().RGBA()
+	if r != wr || g != wg || b != wb {
+		t.Errorf("third frame pixel = %d,%d,%d want colour 3 %d,%d,%d", r>>8, g>>8, b>>8, wr>>8, wg>>8, wb>>8)
+	}
+
+	if _, err := p.InjectImage("field/terra", image.NewRGBA(image.Rect(0, 0, 16, 24)), false); err == nil {
+		t.Error("an image without all frames should be rejected")
+	}
+	if _, err := p.InjectImage("monster/guard", img, false); err == nil {
+		t.Error("sprites without a palette asset need raw data")
+	}
+}
+
+// TestInjectSharedPalette tests palettes several characters use are only
+// changed when allowed, and that a refusal leaves the ROM untouched
+func TestInjectSharedPalette(t *testing.T) {
+	defer ResetAssetTables()
+	table := &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{
+		{ID: "field/edgar", Name: "Edgar", Kind: AssetFieldSprite, Character: 4, Offset: 0x200000, Size: 576, Width: 16, Height: 24, Frames: 3},
+		{ID: "field/sabin", Name: "Sabin", Kind: AssetFieldSprite, Character: 5, Offset: 0x201000, Size: 576, Width: 16, Height: 24, Frames: 3},
+		{ID: "palette/edgar", Name: "Edgar Palette", Kind: AssetPalette, Character: 4, Offset: 0x203000, Size: paletteSize},
+		{ID: "palette/sabin", Name: "Sabin Palette", Kind: AssetPalette, Character: 5, Offset: 0x203000, Size: paletteSize},
+	}}
+	AssetTables[RevisionUS10] = table
+	rom := buildAssetROM(t, table)
+	p := newTestPatcher(t, rom)
+
+	palette := models.NewPalette("Red")
+	palette.Colors[1] = models.RGB555{R: 31}
+	if _, err := p.InjectPalette("palette/edgar", palette); !errors.Is(err, ErrSharedPalette) || !strings.Contains(err.Error(), "Edgar, Sabin") {
+		t.Errorf("InjectPalette on a shared palette = %v, want ErrSharedPalette naming Edgar and Sabin", err)
+	}
+	if _, err := p.InjectAsset("palette/sabin", encodePalette(palette)); !errors.Is(err, ErrSharedPalette) {
+		t.Errorf("raw palette data = %v, want ErrSharedPalette", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 48, 24))
+	for x := 0; x < 48; x++ {
+		img.Set(x, x%24, palette.Colors[1].ToColor())
+	}
+	if _, err := p.InjectImage("field/edgar", img, true); !errors.Is(err, ErrSharedPalette) {
+		t.Errorf("InjectImage with a shared palette = %v, want ErrSharedPalette", err)
+	}
+	if !bytes.Equal(p.Data(), rom) {
+		t.Error("a refused injection changed the ROM")
+	}
+
+	p.AllowSharedPalettes(true)
+	res, err := p.InjectImage("field/edgar", img, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.PaletteUsers) != 2 || res.PaletteUsers[0] != "Edgar" || res.PaletteUsers[1] != "Sabin" {
+		t.Errorf("PaletteUsers = %v, want Edgar and Sabin", res.PaletteUsers)
+	}
+}
+
+// TestNewROMPatcherRejectsOverlap tests a table whose assets share bytes is
+// refused, since injecting one would corrupt the other
+func TestNewROMPatcherRejectsOverlap(t *testing.T) {
+	defer ResetAssetTables()
+	AssetTables[RevisionUS10] = &AssetTable{Revision: RevisionUS10, Assets: []ROMAsset{
+		{ID: "field/terra", Kind: AssetFieldSprite, Character: 0, Offset: 0x200000, Size: 576},
+		{ID: "battle/terra", Kind: AssetBattleSprite, Character: 0, Offset: 0x200000, Size: 3072},
+	}}
+	loader := &ROMLoader{}
+	if err := loader.LoadData(buildTestROM("FINAL FANTASY 3")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewROMPatcher(loader); err == nil {
+		t.Error("NewROMPatcher should refuse overlapping assets")
+	}
+}
@@ -49,6 +49,24 @@ func (c *FF6SpriteConverter) ToFF6Format(img image.Image, palette *models.Palett
 	return sprite
 }
 
+// EncodeFrames converts an image holding frames side by side into tile data
+// for a sprite of the given frame size, matching pixels to palette
+func (c *FF6SpriteConverter) EncodeFrames(img image.Image, palette *models.Palette, width, height, frames int) ([]byte, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() != width*frames || bounds.Dy() != height {
+		return nil, fmt.Errorf("image is %dx%d, want %dx%d (%d frames of %dx%d side by side)",
+			bounds.Dx(), bounds.Dy(), width*frames, height, frames, width, height)
+	}
+
+	var data []byte
+	for f := 0; f < frames; f++ {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(frame, frame.Bounds(), img, image.Point{X: bounds.Min.X + f*width, Y: bounds.Min.Y}, draw.Src)
+		data = append(data, c.encodeToTiles(c.imageToIndexed(frame, palette), width, height)...)
+	}
+	return data, nil
+}
+
 // fitImage resizes or pads image to target dimensions (optimized with draw.Draw)
 func (c *FF6SpriteConverter) fitImage(img image.Image, targetWidth, targetHeight int) image.Image {
 	bounds := img.Bounds()
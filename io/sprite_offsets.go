@@ -4,37 +4,8 @@ import (
 	"fmt"
 )
 
-// FF6 character sprite offsets in ROM (USA version)
-// These are SNES addresses, need to convert to PC offsets
-
-// CharacterSpriteOffsets maps character IDs to their sprite data offsets in ROM
-var CharacterSpriteOffsets = map[int]SpriteDataOffset{
-	0:  {Name: "Terra", FieldOffset: 0x150000, BattleOffset: 0x268000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	1:  {Name: "Locke", FieldOffset: 0x151000, BattleOffset: 0x26A000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	2:  {Name: "Cyan", FieldOffset: 0x152000, BattleOffset: 0x26C000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	3:  {Name: "Shadow", FieldOffset: 0x153000, BattleOffset: 0x26E000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	4:  {Name: "Edgar", FieldOffset: 0x154000, BattleOffset: 0x270000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	5:  {Name: "Sabin", FieldOffset: 0x155000, BattleOffset: 0x272000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	6:  {Name: "Celes", FieldOffset: 0x156000, BattleOffset: 0x274000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	7:  {Name: "Strago", FieldOffset: 0x157000, BattleOffset: 0x276000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	8:  {Name: "Relm", FieldOffset: 0x158000, BattleOffset: 0x278000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	9:  {Name: "Setzer", FieldOffset: 0x159000, BattleOffset: 0x27A000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	10: {Name: "Mog", FieldOffset: 0x15A000, BattleOffset: 0x27C000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	11: {Name: "Gau", FieldOffset: 0x15B000, BattleOffset: 0x27E000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	12: {Name: "Gogo", FieldOffset: 0x15C000, BattleOffset: 0x280000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-	13: {Name: "Umaro", FieldOffset: 0x15D000, BattleOffset: 0x282000, Compressed: true, FieldSize: 576, BattleSize: 1536},
-}
-
-// SpriteDataOffset contains ROM offsets for a character's sprites
-type SpriteDataOffset struct {
-	Name          string
-	FieldOffset   int  // Field/walking sprite data offset
-	BattleOffset  int  // Battle sprite data offset
-	Compressed    bool // Whether data is LZ77 compressed
-	PaletteOffset int  // Palette data offset (if separate)
-	FieldSize     int  // Expected size of field sprite data (bytes)
-	BattleSize    int  // Expected size of battle sprite data (bytes)
-}
+// Sprite and palette locations live in the per-revision AssetTables
+// (rom_assets.go); this file describes the sprite formats and compression.
 
 // SpriteFrameInfo contains metadata about sprite animation frames
 type SpriteFrameInfo struct {
@@ -71,6 +42,45 @@ func NewLZ77Decompressor() *LZ77Decompressor {
 	return &LZ77Decompressor{}
 }
 
+// DecompressSize decompresses until size bytes have been produced, so a
+// stream can be read straight out of a ROM without knowing its length. It
+// returns the data and how many compressed bytes were consumed.
+func (d *LZ77Decompressor) DecompressSize(compressed []byte, size int) ([]byte, int, error) {
+	out := make([]byte, 0, size)
+	pos := 0
+	for len(out) < size {
+		if pos >= len(compressed) {
+			return nil, pos, fmt.Errorf("compressed data ends after %d of %d bytes", len(out), size)
+		}
+		control := compressed[pos]
+		pos++
+		for bit := 0; bit < 8 && len(out) < size; bit++ {
+			if control&(1<<bit) == 0 {
+				if pos >= len(compressed) {
+					return nil, pos, fmt.Errorf("compressed data ends after %d of %d bytes", len(out), size)
+				}
+				out = append(out, compressed[pos])
+				pos++
+				continue
+			}
+			if pos+1 >= len(compressed) {
+				return nil, pos, fmt.Errorf("truncated back reference at pos %d", pos)
+			}
+			b1, b2 := int(compressed[pos]), int(compressed[pos+1])
+			pos += 2
+			length := ((b1 >> 4) & 0x0F) + 3
+			start := len(out) - ((((b1 & 0x0F) << 8) | b2) + 1)
+			if start < 0 {
+				return nil, pos, fmt.Errorf("invalid back reference at pos %d", pos)
+			}
+			for i := 0; i < length && len(out) < size; i++ {
+				out = append(out, out[start+i])
+			}
+		}
+	}
+	return out, pos, nil
+}
+
 // Decompress decompresses LZ77 compressed data with optimized buffer allocation
 func (d *LZ77Decompressor) Decompress(compressed []byte) ([]byte, error) {
 	if len(compressed) == 0 {
@@ -5,6 +5,7 @@ import (
 
 	"ffvi_editor/io/pr"
 	"ffvi_editor/models"
+	pri "ffvi_editor/models/pr"
 )
 
 // Rule defines a single validation rule
@@ -160,6 +161,21 @@ func (v *Validator) registerDefaultRules() {
 		Severity: models.SeverityWarning,
 		Fixable:  false,
 	})
+
+	// Vehicle placement validation. The map size bound is unverified, so
+	// this only warns and never changes the save.
+	v.registerRule(Rule{
+		Name:        "vehicle_placement",
+		Description: "Enabled vehicles should be parked on a world map",
+		Check: func(data *pr.PR) (bool, string) {
+			if err := pri.ValidateTransportations(data.Models().Transportations()); err != nil {
+				return false, err.Error()
+			}
+			return true, ""
+		},
+		Severity: models.SeverityWarning,
+		Fixable:  false,
+	})
 }
 
 // registerRule adds a validation rule
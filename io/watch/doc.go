@@ -0,0 +1,34 @@
+// Package watch monitors a save directory and reacts when the game writes a slot.
+//
+// The watch package handles:
+//   - Detecting save slot writes via fsnotify
+//   - Snapshotting each new save into io/backup
+//   - Running the validator on the freshly written save
+//   - Firing a save:open style hook for plugins
+//   - Optionally re-applying a recipe (e.g. infinite resources) to the save
+//
+// Writes made by the watcher itself (when a recipe is re-applied) are
+// recognised by content hash and ignored, so a recipe never loops.
+//
+// Each write is loaded inside models/pr.Isolated, so the save open in the
+// editor is left alone; recipes edit the save through PR.Models. Isolated
+// swaps the package-level models while a write is processed, so the editor
+// loads and saves through models/pr.WithCurrent and waits for it to finish.
+//
+// Example usage:
+//
+//	bm, _ := backup.NewManager(backupDir, 20)
+//	w, err := watch.New(watch.Options{
+//	    Dir:     saveDir,
+//	    Backups: bm,
+//	    Recipe:  watch.InfiniteResourcesRecipe,
+//	    OnEvent: func(e watch.Event) { log.Println(e) },
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	if err := w.Start(ctx); err != nil {
+//	    return err
+//	}
+//	defer w.Stop()
+package watch
@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/scripting"
+)
+
+// Recipe modifies a freshly written save before it is written back
+type Recipe func(ctx context.Context, save *pr.PR) error
+
+// Infinite resources limits, matching the infinite-resources-mode plugin
+const (
+	maxGil          = 9999999
+	maxItemQuantity = 99
+)
+
+// InfiniteResourcesRecipe maxes gil and tops up every owned item to 99
+func InfiniteResourcesRecipe(ctx context.Context, save *pr.PR) error {
+	m := save.Models()
+	m.Misc().GP = maxGil
+	for _, row := range m.Inventory().GetRows() {
+		if row != nil && row.ItemID > 0 {
+			row.Count = maxItemQuantity
+		}
+	}
+	return nil
+}
+
+// builtInRecipes maps recipe names accepted by RecipeByName
+var builtInRecipes = map[string]Recipe{
+	"infinite-resources": InfiniteResourcesRecipe,
+}
+
+// RecipeNames returns the names of the built-in recipes
+func RecipeNames() []string {
+	names := make([]string, 0, len(builtInRecipes))
+	for name := range builtInRecipes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LuaRecipe runs Lua code against the save using the scripting save bindings
+func LuaRecipe(code string) Recipe {
+	return func(ctx context.Context, save *pr.PR) error {
+		_, err := scripting.RunSnippetWithSave(ctx, code, save)
+		return err
+	}
+}
+
+// RecipeByName resolves a built-in recipe name or a path to a Lua script
+func RecipeByName(name string) (Recipe, error) {
+	if r, ok := builtInRecipes[name]; ok {
+		return r, nil
+	}
+	if strings.EqualFold(filepath.Ext(name), ".lua") {
+		code, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipe script: %w", err)
+		}
+		return LuaRecipe(string(code)), nil
+	}
+	return nil, fmt.Errorf("unknown recipe: %s (valid: %s, or a .lua script)", name, strings.Join(RecipeNames(), ", "))
+}
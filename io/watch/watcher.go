@@ -0,0 +1,347 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ffvi_editor/global"
+	"ffvi_editor/io/backup"
+	"ffvi_editor/io/pr"
+	"ffvi_editor/io/validation"
+	"ffvi_editor/models"
+	pri "ffvi_editor/models/pr"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long the watcher waits after the last write to a
+// slot before processing it. The game writes saves in several chunks.
+const DefaultDebounce = 500 * time.Millisecond
+
+// Hook is called after a new save has been snapshotted and validated
+type Hook func(ctx context.Context, path string) error
+
+// Event describes the outcome of processing one save write
+type Event struct {
+	Path          string
+	Timestamp     time.Time
+	Backup        *models.BackupMetadata
+	Validation    *models.ValidationResult
+	RecipeApplied bool
+	HookErr       error
+	Err           error
+}
+
+// String returns a one-line summary of the event
+func (e Event) String() string {
+	var b strings.Builder
+	b.WriteString(filepath.Base(e.Path))
+	if e.Backup != nil {
+		b.WriteString(fmt.Sprintf(" backup=%s", e.Backup.ID))
+	}
+	if e.Validation != nil {
+		b.WriteString(fmt.Sprintf(" valid=%t errors=%d warnings=%d",
+			e.Validation.Valid, len(e.Validation.Errors), len(e.Validation.Warnings)))
+	}
+	if e.RecipeApplied {
+		b.WriteString(" recipe=applied")
+	}
+	if e.HookErr != nil {
+		b.WriteString(fmt.Sprintf(" hook-error=%q", e.HookErr.Error()))
+	}
+	if e.Err != nil {
+		b.WriteString(fmt.Sprintf(" error=%q", e.Err.Error()))
+	}
+	return b.String()
+}
+
+// Options configures a Watcher
+type Options struct {
+	Dir       string
+	SaveType  global.SaveFileType
+	Backups   *backup.Manager       // Optional: snapshot each write
+	Validator *validation.Validator // Optional: defaults to validation.NewValidator()
+	Hook      Hook                  // Optional: e.g. plugins.Manager.CallHook(HookSaveOpen)
+	Recipe    Recipe                // Optional: re-applied to every new save
+	Debounce  time.Duration         // Defaults to DefaultDebounce
+	Filter    func(name string) bool
+	OnEvent   func(Event)
+}
+
+// Watcher reacts to the game writing save slots in a directory
+type Watcher struct {
+	opts        Options
+	fileWatcher *fsnotify.Watcher
+	pending     map[string]*time.Timer
+	written     map[string]string // path -> hash of the last write made by the watcher
+	mu          sync.Mutex
+	processMu   sync.Mutex // save loading uses package-level model state
+	stopCh      chan struct{}
+	isRunning   bool
+}
+
+// New creates a watcher for the configured directory
+func New(opts Options) (*Watcher, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("watch directory is required")
+	}
+	info, err := os.Stat(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access watch directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("watch path is not a directory: %s", opts.Dir)
+	}
+	if opts.Validator == nil {
+		opts.Validator = validation.NewValidator()
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Filter == nil {
+		opts.Filter = DefaultFilter(opts.SaveType)
+	}
+
+	return &Watcher{
+		opts:    opts,
+		pending: make(map[string]*time.Timer),
+		written: make(map[string]string),
+		stopCh:  make(chan struct{}),
+	}, nil
+}
+
+// DefaultFilter returns the slot file filter for a save type. PlayStation
+// slots are named slotN.sav; PC slots are extensionless base64 identifiers.
+func DefaultFilter(saveType global.SaveFileType) func(name string) bool {
+	return func(name string) bool {
+		base := filepath.Base(name)
+		if base == "" || strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") {
+			return false
+		}
+		if saveType == global.PS {
+			return strings.HasPrefix(base, "slot") && strings.HasSuffix(base, ".sav")
+		}
+		return filepath.Ext(base) == ""
+	}
+}
+
+// Start begins watching the directory
+func (w *Watcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isRunning {
+		return fmt.Errorf("watcher already running")
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := fw.Add(w.opts.Dir); err != nil {
+		fw.Close()
+		return fmt.Errorf("failed to watch directory: %w", err)
+	}
+
+	w.fileWatcher = fw
+	w.stopCh = make(chan struct{})
+	w.isRunning = true
+
+	go w.watchLoop(ctx)
+	return nil
+}
+
+// Stop stops watching and cancels any pending work
+func (w *Watcher) Stop() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.isRunning {
+		return fmt.Errorf("watcher not running")
+	}
+
+	for path, t := range w.pending {
+		t.Stop()
+		delete(w.pending, path)
+	}
+
+	close(w.stopCh)
+	w.isRunning = false
+	return w.fileWatcher.Close()
+}
+
+// IsRunning reports whether the watcher is active
+func (w *Watcher) IsRunning() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.isRunning
+}
+
+// Dir returns the watched directory
+func (w *Watcher) Dir() string {
+	return w.opts.Dir
+}
+
+// watchLoop debounces write events per slot and dispatches them
+func (w *Watcher) watchLoop(ctx context.Context) {
+	for {
+		select {
+		case event, ok := <-w.fileWatcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if !w.opts.Filter(event.Name) {
+				continue
+			}
+			w.schedule(ctx, event.Name)
+
+		case err, ok := <-w.fileWatcher.Errors:
+			if !ok {
+				return
+			}
+			global.Log("[Watch] File watcher error: %v", err)
+
+		case <-ctx.Done():
+			_ = w.Stop()
+			return
+
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// schedule (re)starts the debounce timer for a path
+func (w *Watcher) schedule(ctx context.Context, path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t, ok := w.pending[path]; ok {
+		t.Stop()
+	}
+	w.pending[path] = time.AfterFunc(w.opts.Debounce, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+
+		event, handled := w.Process(ctx, path)
+		if handled && w.opts.OnEvent != nil {
+			w.opts.OnEvent(event)
+		}
+	})
+}
+
+// Process handles a single save write. It returns false when the write was
+// ignored, either because the file vanished or because the watcher wrote it.
+func (w *Watcher) Process(ctx context.Context, path string) (Event, bool) {
+	w.processMu.Lock()
+	defer w.processMu.Unlock()
+
+	event := Event{Path: path, Timestamp: time.Now()}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return event, false
+		}
+		event.Err = fmt.Errorf("failed to read save: %w", err)
+		return event, true
+	}
+
+	hash := models.CalculateHash(data)
+	w.mu.Lock()
+	ownWrite := w.written[path] == hash
+	delete(w.written, path)
+	w.mu.Unlock()
+	if ownWrite {
+		return event, false
+	}
+
+	// Snapshot before anything else touches the file
+	if w.opts.Backups != nil {
+		meta, err := w.opts.Backups.CreateBackup(path, data, "watch: game wrote "+filepath.Base(path))
+		if err != nil {
+			event.Err = fmt.Errorf("failed to snapshot save: %w", err)
+			return event, true
+		}
+		event.Backup = meta
+	}
+
+	// Load into models of its own, so the save the editor has open is
+	// left as it was
+	err = pri.Isolated(func() error {
+		save := pr.New()
+		if err := save.Load(path, w.opts.SaveType); err != nil {
+			return fmt.Errorf("failed to load save: %w", err)
+		}
+
+		result := w.opts.Validator.Validate(save)
+		event.Validation = &result
+
+		if w.opts.Hook != nil {
+			event.HookErr = w.opts.Hook(ctx, path)
+		}
+
+		if w.opts.Recipe != nil {
+			if err := w.applyRecipe(ctx, save, path); err != nil {
+				return err
+			}
+			event.RecipeApplied = true
+		}
+		return nil
+	})
+	if err != nil {
+		event.Err = err
+	}
+
+	return event, true
+}
+
+// applyRecipe runs the recipe and writes the save back in place
+func (w *Watcher) applyRecipe(ctx context.Context, save *pr.PR, path string) error {
+	if err := w.opts.Recipe(ctx, save); err != nil {
+		return fmt.Errorf("recipe failed: %w", err)
+	}
+	if err := save.Save(slotOf(save), path, w.opts.SaveType); err != nil {
+		return fmt.Errorf("failed to write recipe result: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read recipe result: %w", err)
+	}
+	w.mu.Lock()
+	w.written[path] = models.CalculateHash(data)
+	w.mu.Unlock()
+	return nil
+}
+
+// slotOf returns the slot id stored in the save so it can be written back unchanged
+func slotOf(save *pr.PR) int {
+	if save.Base == nil {
+		return 0
+	}
+	v, ok := save.Base.GetValue("id")
+	if !ok {
+		return 0
+	}
+	switch id := v.(type) {
+	case json.Number:
+		n, _ := id.Int64()
+		return int(n)
+	case float64:
+		return int(id)
+	case int:
+		return id
+	}
+	return 0
+}
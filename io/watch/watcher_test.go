@@ -0,0 +1,205 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ffvi_editor/global"
+	"ffvi_editor/io/backup"
+	"ffvi_editor/io/pr"
+	"ffvi_editor/models"
+	pri "ffvi_editor/models/pr"
+)
+
+// TestNewRequiresDirectory tests watcher construction errors
+func TestNewRequiresDirectory(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("New() should fail without a directory")
+	}
+
+	file := filepath.Join(t.TempDir(), "slot1.sav")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := New(Options{Dir: file}); err == nil {
+		t.Error("New() should fail when path is a file")
+	}
+}
+
+// TestDefaultFilter tests slot file name matching for both save types
+func TestDefaultFilter(t *testing.T) {
+	pc := DefaultFilter(global.PC)
+	ps := DefaultFilter(global.PS)
+
+	tests := []struct {
+		name   string
+		pc, ps bool
+	}{
+		{"ookrbATYovG3tEOXIH4HqWnsv8TrUlRWzM8AlCmW2mk=", true, false},
+		{"slot1.sav", false, true},
+		{".hidden", false, false},
+		{"backups.json", false, false},
+		{"slot1.sav~", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := pc(tt.name); got != tt.pc {
+			t.Errorf("PC filter(%q) = %v, want %v", tt.name, got, tt.pc)
+		}
+		if got := ps(tt.name); got != tt.ps {
+			t.Errorf("PS filter(%q) = %v, want %v", tt.name, got, tt.ps)
+		}
+	}
+}
+
+// TestProcessSnapshotsBeforeLoading tests that a write is backed up even when it cannot be decoded
+func TestProcessSnapshotsBeforeLoading(t *testing.T) {
+	dir := t.TempDir()
+	bm, err := backup.NewManager(filepath.Join(t.TempDir(), "backups"), 5)
+	if err != nil {
+		t.Fatalf("failed to create backup manager: %v", err)
+	}
+
+	w, err := New(Options{Dir: dir, SaveType: global.PS, Backups: bm})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	path := filepath.Join(dir, "slot1.sav")
+	if err := os.WriteFile(path, []byte("not a save"), 0644); err != nil {
+		t.Fatalf("failed to write save: %v", err)
+	}
+
+	event, handled := w.Process(context.Background(), path)
+	if !handled {
+		t.Fatal("Process() should handle a new write")
+	}
+	if event.Backup == nil {
+		t.Fatal("Process() should snapshot the save")
+	}
+	if event.Err == nil {
+		t.Error("Process() should report the load failure")
+	}
+	if bm.BackupCount() != 1 {
+		t.Errorf("BackupCount() = %d, want 1", bm.BackupCount())
+	}
+}
+
+// TestProcessIgnoresOwnWrites tests that writes made by the watcher are skipped
+func TestProcessIgnoresOwnWrites(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(Options{Dir: dir, SaveType: global.PS})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	path := filepath.Join(dir, "slot2.sav")
+	data := []byte("recipe output")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write save: %v", err)
+	}
+	w.written[path] = models.CalculateHash(data)
+
+	if _, handled := w.Process(context.Background(), path); handled {
+		t.Error("Process() should ignore the watcher's own write")
+	}
+	if _, handled := w.Process(context.Background(), path); !handled {
+		t.Error("Process() should handle a later write with the same content")
+	}
+}
+
+// TestProcessMissingFile tests that a deleted slot is ignored
+func TestProcessMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if _, handled := w.Process(context.Background(), filepath.Join(dir, "gone")); handled {
+		t.Error("Process() should ignore missing files")
+	}
+}
+
+// TestWatcherDetectsWrite tests the fsnotify loop end to end
+func TestWatcherDetectsWrite(t *testing.T) {
+	dir := t.TempDir()
+	events := make(chan Event, 1)
+
+	w, err := New(Options{
+		Dir:      dir,
+		SaveType: global.PS,
+		Debounce: 20 * time.Millisecond,
+		OnEvent:  func(e Event) { events <- e },
+	})
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer w.Stop()
+
+	if err := w.Start(context.Background()); err == nil {
+		t.Error("Start() should fail when already running")
+	}
+
+	path := filepath.Join(dir, "slot3.sav")
+	if err := os.WriteFile(path, []byte("written by game"), 0644); err != nil {
+		t.Fatalf("failed to write save: %v", err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Path != path {
+			t.Errorf("event path = %s, want %s", e.Path, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+}
+
+// TestRecipeByName tests recipe resolution
+func TestRecipeByName(t *testing.T) {
+	if _, err := RecipeByName("infinite-resources"); err != nil {
+		t.Errorf("RecipeByName(infinite-resources) error: %v", err)
+	}
+	if _, err := RecipeByName("nope"); err == nil {
+		t.Error("RecipeByName should reject unknown recipes")
+	}
+
+	script := filepath.Join(t.TempDir(), "recipe.lua")
+	if err := os.WriteFile(script, []byte("return {}"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	if _, err := RecipeByName(script); err != nil {
+		t.Errorf("RecipeByName(script) error: %v", err)
+	}
+}
+
+// TestInfiniteResourcesRecipeEditsItsSave tests the recipe edits the models
+// of the save it is given, not the editor's
+func TestInfiniteResourcesRecipeEditsItsSave(t *testing.T) {
+	models.GetMisc().GP = 100
+	defer func() { models.GetMisc().GP = 0 }()
+
+	err := pri.Isolated(func() error {
+		save := pr.New()
+		save.Models().Inventory().Set(0, pri.Row{ItemID: 7, Count: 1})
+		if err := InfiniteResourcesRecipe(context.Background(), save); err != nil {
+			return err
+		}
+		if save.Models().Misc().GP != maxGil || save.Models().Inventory().GetRows()[0].Count != maxItemQuantity {
+			t.Error("recipe did not edit the save it was given")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if models.GetMisc().GP != 100 {
+		t.Errorf("editor gil = %d, want it untouched", models.GetMisc().GP)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"ffvi_editor/plugins"
 )
 
 // Preset represents a shared preset in the marketplace
@@ -122,8 +125,19 @@ type Client struct {
 	cacheTTL  time.Duration
 	userAgent string
 	registry  *Registry
+	installer PackageInstaller
+}
+
+// PackageInstaller verifies and unpacks downloaded plugin packages.
+// plugins.Manager satisfies it.
+type PackageInstaller interface {
+	InstallPackage(ctx context.Context, data []byte) (*plugins.PackageManifest, error)
 }
 
+// ErrNoInstaller is returned by InstallPlugin when no PackageInstaller was
+// set, since nothing could verify or write the package
+var ErrNoInstaller = errors.New("marketplace client has no package installer")
+
 // NewClient creates a new marketplace client
 func NewClient(baseURL, apiKey string) *Client {
 	return &Client{
@@ -384,13 +398,35 @@ func (c *Client) DownloadPlugin(ctx context.Context, pluginID, version string) (
 	return data, nil
 }
 
-// InstallPlugin downloads and installs a plugin
+// SetInstaller sets the installer used by InstallPlugin, which fails
+// without one
+func (c *Client) SetInstaller(installer PackageInstaller) {
+	c.installer = installer
+}
+
+// InstallPlugin downloads and installs a plugin. The package signature is
+// checked by the installer before anything is written to the plugin directory.
 func (c *Client) InstallPlugin(ctx context.Context, pluginID, version string) error {
-	_, err := c.DownloadPlugin(ctx, pluginID, version)
+	if c.installer == nil {
+		return ErrNoInstaller
+	}
+	data, err := c.DownloadPlugin(ctx, pluginID, version)
 	if err != nil {
 		return err
 	}
 
+	// Refuse a package published under another plugin's ID before it can
+	// overwrite anything
+	if pkg, err := plugins.ReadPackage(data); err == nil && pkg.Manifest.PluginID != pluginID {
+		return fmt.Errorf("package contains plugin %s, expected %s", pkg.Manifest.PluginID, pluginID)
+	}
+
+	manifest, err := c.installer.InstallPackage(ctx, data)
+	if err != nil {
+		return fmt.Errorf("failed to install %s: %w", pluginID, err)
+	}
+	version = manifest.Version
+
 	// Track installation if registry is available
 	if c.registry != nil {
 		if err := c.registry.TrackInstallation(pluginID, version); err != nil {
@@ -401,6 +437,43 @@ func (c *Client) InstallPlugin(ctx context.Context, pluginID, version string) er
 	return nil
 }
 
+// AvailableVersions lists the published versions of a plugin, newest first.
+// Together with FetchPackage it lets the client act as a plugins.PackageSource.
+func (c *Client) AvailableVersions(ctx context.Context, pluginID string) ([]string, error) {
+	plugin, err := c.GetPluginDetails(ctx, pluginID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(plugin.VersionHistory))
+	for _, v := range plugin.VersionHistory {
+		versions = append(versions, v.Version)
+	}
+	if len(versions) == 0 && plugin.Version != "" {
+		versions = append(versions, plugin.Version)
+	}
+	return versions, nil
+}
+
+// FetchPackage downloads one version of a plugin package
+func (c *Client) FetchPackage(ctx context.Context, pluginID, version string) ([]byte, error) {
+	return c.DownloadPlugin(ctx, pluginID, version)
+}
+
+// ApplyPlan applies an install plan through the plugin manager and records
+// the result in the registry
+func (c *Client) ApplyPlan(ctx context.Context, manager *plugins.Manager, plan *plugins.InstallPlan) error {
+	if err := manager.ApplyPlan(ctx, plan); err != nil {
+		return err
+	}
+	if c.registry != nil {
+		if err := c.registry.RecordPlan(plan); err != nil {
+			return fmt.Errorf("failed to record installation: %w", err)
+		}
+	}
+	return nil
+}
+
 // SubmitRating submits a rating and review for a plugin
 func (c *Client) SubmitRating(ctx context.Context, rating *PluginRating) error {
 	if rating.Rating < 1 || rating.Rating > 5 {
@@ -19,18 +19,38 @@
 //   - SubmitRating, GetPluginRatings                 - FULLY IMPLEMENTED
 //   - CheckForUpdates                                - FULLY IMPLEMENTED
 //
-// Preset Operations (Local-only):
-//   - UploadPreset, UpdatePreset, DeletePreset       - LOCAL STORAGE ONLY
-//   - GetPreset, DownloadPreset                      - LOCAL STORAGE ONLY
-//   - RatePreset, AddReview, GetReviews              - LOCAL STORAGE ONLY
-//   - GetPopular, GetRecent                          - LOCAL STORAGE ONLY
-//
-// Server-based preset sharing requires a marketplace backend that is not
-// yet available. Presets can be exported/imported via files for sharing.
+// Preset Operations (Server-based, served by a local mirror):
+//   - UploadPreset, UpdatePreset, DeletePreset       - MIRROR SERVER
+//   - GetPreset, DownloadPreset                      - MIRROR SERVER
+//   - RatePreset, AddReview, GetReviews              - MIRROR SERVER
+//   - GetPopular, GetRecent                          - MIRROR SERVER
+//
+// Offline Mirror:
+//
+// ExportMirror packages installed plugins into a mirror directory
+// (index.json plus packages/<id>-<version>.zip) and Server hosts that
+// directory with the same endpoints the Client calls, including presets,
+// ratings and reviews. Pointing a Client's baseURL at a Server gives a
+// fully working marketplace without network access.
+// ExportMirrorWithOptions signs packages so clients can verify them.
+//
+// Installation:
+//
+// InstallPlugin hands downloaded packages to a PackageInstaller (usually a
+// plugins.Manager set with SetInstaller), which verifies the package
+// signature before anything is written to the plugin directory; without
+// one it fails with ErrNoInstaller. The Client
+// is also a plugins.PackageSource, so plugins.Manager.PlanInstall can pull
+// dependencies from it; Client.ApplyPlan applies the plan and records it in
+// the registry.
 //
 // Usage:
 //
 //	client, _ := marketplace.NewClientWithRegistry(baseURL, apiKey, registryPath)
 //	plugins, _ := client.SearchPlugins(ctx, "combat")
-//	preset, _ := client.GetPreset("my-preset")  // Local only
+//	preset, _ := client.GetPreset("my-preset")
+//
+//	marketplace.ExportMirror("plugins/", "mirror/")
+//	srv, _ := marketplace.NewServer("mirror/")
+//	http.ListenAndServe(":8080", srv)
 package marketplace
@@ -0,0 +1,268 @@
+package marketplace
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"ffvi_editor/plugins"
+)
+
+// Mirror layout
+//
+//	<root>/index.json                  MirrorIndex (plugins and presets)
+//	<root>/packages/<id>-<version>.zip plugin packages
+//	<root>/ratings.json                plugin ratings submitted to the server
+//	<root>/reviews.json                preset reviews submitted to the server
+const (
+	mirrorIndexFile   = "index.json"
+	mirrorPackagesDir = "packages"
+	mirrorRatingsFile = "ratings.json"
+	mirrorReviewsFile = "reviews.json"
+
+	// MirrorSchemaVersion is the current mirror index format version
+	MirrorSchemaVersion = "1.0.0"
+)
+
+// MirrorIndex is the catalogue hosted by a mirror
+type MirrorIndex struct {
+	SchemaVersion string         `json:"schemaVersion"`
+	Generated     time.Time      `json:"generated"`
+	Plugins       []RemotePlugin `json:"plugins"`
+	Presets       []*Preset      `json:"presets"`
+}
+
+// pluginMetadata is the subset of a plugin's metadata.json used for mirroring
+type pluginMetadata struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Version          string   `json:"version"`
+	Author           string   `json:"author"`
+	Description      string   `json:"description"`
+	Category         string   `json:"category"`
+	Tags             []string `json:"tags"`
+	License          string   `json:"license"`
+	Homepage         string   `json:"homepage"`
+	EditorVersion    string   `json:"ff6_editor_version"`
+	MinEditorVersion string   `json:"min_editor_version"`
+}
+
+// LoadMirrorIndex reads the index of a mirror directory
+func LoadMirrorIndex(root string) (*MirrorIndex, error) {
+	data, err := os.ReadFile(filepath.Join(root, mirrorIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror index: %w", err)
+	}
+
+	var index MirrorIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mirror index: %w", err)
+	}
+	return &index, nil
+}
+
+// SaveMirrorIndex writes the index of a mirror directory
+func SaveMirrorIndex(root string, index *MirrorIndex) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mirror index: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, mirrorIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write mirror index: %w", err)
+	}
+	return nil
+}
+
+// MirrorOptions controls how packages are built during export
+type MirrorOptions struct {
+	// Signer signs every exported package when set; it must hold a private key
+	Signer *plugins.SecurityManager
+	// SignerID names the key in the package signature, matching the
+	// <signer>.pem file clients trust
+	SignerID string
+}
+
+// ExportMirror packages every plugin under pluginDir into a mirror at root.
+// Existing versions in the mirror are kept so repeated exports build up
+// version history.
+func ExportMirror(pluginDir, root string) (*MirrorIndex, error) {
+	return ExportMirrorWithOptions(pluginDir, root, MirrorOptions{})
+}
+
+// ExportMirrorWithOptions is ExportMirror with package signing
+func ExportMirrorWithOptions(pluginDir, root string, opts MirrorOptions) (*MirrorIndex, error) {
+	if opts.Signer != nil && opts.SignerID == "" {
+		return nil, fmt.Errorf("signer ID is required when signing packages")
+	}
+
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	index, err := LoadMirrorIndex(root)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		index = &MirrorIndex{}
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, mirrorPackagesDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create packages directory: %w", err)
+	}
+
+	byID := make(map[string]int, len(index.Plugins))
+	for i, p := range index.Plugins {
+		byID[p.ID] = i
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(pluginDir, entry.Name())
+		meta, err := readPluginMetadata(dir)
+		if err != nil {
+			// Not a plugin directory
+			continue
+		}
+
+		plugin, err := exportPlugin(dir, root, meta, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", meta.ID, err)
+		}
+
+		if i, ok := byID[plugin.ID]; ok {
+			index.Plugins[i] = mergePluginVersions(index.Plugins[i], plugin)
+		} else {
+			byID[plugin.ID] = len(index.Plugins)
+			index.Plugins = append(index.Plugins, plugin)
+		}
+	}
+
+	sort.Slice(index.Plugins, func(i, j int) bool { return index.Plugins[i].ID < index.Plugins[j].ID })
+	index.SchemaVersion = MirrorSchemaVersion
+	index.Generated = time.Now().UTC()
+
+	if err := SaveMirrorIndex(root, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// exportPlugin writes the package for one plugin directory and returns its entry
+func exportPlugin(dir, root string, meta *pluginMetadata, opts MirrorOptions) (RemotePlugin, error) {
+	pkg, err := PackPluginDir(dir)
+	if err != nil {
+		return RemotePlugin{}, err
+	}
+	if opts.Signer != nil {
+		if pkg, err = opts.Signer.SignPackage(pkg, opts.SignerID); err != nil {
+			return RemotePlugin{}, err
+		}
+	}
+
+	name := packageFileName(meta.ID, meta.Version)
+	if err := os.WriteFile(filepath.Join(root, mirrorPackagesDir, name), pkg, 0644); err != nil {
+		return RemotePlugin{}, fmt.Errorf("failed to write package: %w", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return RemotePlugin{}, err
+	}
+
+	minVersion := meta.MinEditorVersion
+	if minVersion == "" {
+		minVersion = meta.EditorVersion
+	}
+
+	now := time.Now().UTC()
+	return RemotePlugin{
+		ID:            meta.ID,
+		Name:          meta.Name,
+		Description:   meta.Description,
+		Author:        meta.Author,
+		Version:       meta.Version,
+		MinVersion:    minVersion,
+		Category:      meta.Category,
+		Tags:          meta.Tags,
+		License:       meta.License,
+		RepositoryURL: meta.Homepage,
+		VersionHistory: []VersionInfo{{
+			Version:     meta.Version,
+			ReleaseDate: info.ModTime().UTC(),
+			DownloadURL: path.Join(mirrorPackagesDir, name),
+			Size:        int64(len(pkg)),
+			Checksum:    fmt.Sprintf("sha256:%x", sha256.Sum256(pkg)),
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// mergePluginVersions folds a freshly exported plugin into an existing entry,
+// keeping ratings, download counts and older versions
+func mergePluginVersions(existing, fresh RemotePlugin) RemotePlugin {
+	merged := fresh
+	merged.Rating = existing.Rating
+	merged.RatingCount = existing.RatingCount
+	merged.Downloads = existing.Downloads
+	merged.CreatedAt = existing.CreatedAt
+
+	history := fresh.VersionHistory
+	for _, v := range existing.VersionHistory {
+		if v.Version != fresh.Version {
+			history = append(history, v)
+		}
+	}
+	// Newest first, as DownloadPlugin treats the first entry as latest
+	sort.SliceStable(history, func(i, j int) bool {
+		return compareVersions(history[i].Version, history[j].Version) > 0
+	})
+	merged.VersionHistory = history
+	merged.Version = history[0].Version
+	return merged
+}
+
+// readPluginMetadata reads metadata.json from a plugin directory
+func readPluginMetadata(dir string) (*pluginMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var meta pluginMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid metadata.json: %w", err)
+	}
+	if meta.ID == "" || meta.Version == "" {
+		return nil, fmt.Errorf("metadata.json must have id and version")
+	}
+	return &meta, nil
+}
+
+// PackPluginDir builds an unsigned plugin package (files plus
+// MANIFEST.json) from a plugin directory. The same directory always
+// produces the same bytes.
+func PackPluginDir(dir string) ([]byte, error) {
+	return plugins.BuildPackage(dir)
+}
+
+// packageFileName returns the mirror file name for a plugin version
+func packageFileName(id, version string) string {
+	return fmt.Sprintf("%s-%s.zip", id, version)
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"ffvi_editor/plugins"
 )
 
 // Registry manages local plugin installation records and metadata
@@ -128,6 +130,37 @@ func (r *Registry) UpdatePlugin(pluginID, newVersion string) error {
 	return r.save()
 }
 
+// RecordPlan records every step of an applied install plan in one save
+func (r *Registry) RecordPlan(plan *plugins.InstallPlan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, step := range plan.Steps {
+		if step.Action == plugins.PlanUninstall {
+			delete(r.installedPlugins, step.PluginID)
+			delete(r.updateChecks, step.PluginID)
+			continue
+		}
+
+		if record, ok := r.installedPlugins[step.PluginID]; ok {
+			record.Version = step.ToVersion
+			record.UpdatedAt = now
+			continue
+		}
+		r.installedPlugins[step.PluginID] = &InstallRecord{
+			PluginID:    step.PluginID,
+			Version:     step.ToVersion,
+			InstalledAt: now,
+			UpdatedAt:   now,
+			Enabled:     true,
+			AutoUpdate:  true,
+		}
+	}
+
+	return r.save()
+}
+
 // SetPluginEnabled enables or disables a plugin
 func (r *Registry) SetPluginEnabled(pluginID string, enabled bool) error {
 	r.mu.Lock()
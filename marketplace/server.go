@@ -0,0 +1,626 @@
+package marketplace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server hosts a mirror directory over the same HTTP API the Client uses, so
+// an offline catalogue can be used by pointing the client's baseURL at it
+type Server struct {
+	root     string
+	readOnly bool
+	mu       sync.RWMutex
+	index    *MirrorIndex
+	ratings  map[string][]*PluginRating
+	reviews  map[string][]*Review
+	mux      *http.ServeMux
+}
+
+// NewServer creates a server for the mirror at root
+func NewServer(root string) (*Server, error) {
+	index, err := LoadMirrorIndex(root)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		root:    root,
+		index:   index,
+		ratings: make(map[string][]*PluginRating),
+		reviews: make(map[string][]*Review),
+	}
+	if err := readJSONFile(filepath.Join(root, mirrorRatingsFile), &s.ratings); err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %w", err)
+	}
+	if err := readJSONFile(filepath.Join(root, mirrorReviewsFile), &s.reviews); err != nil {
+		return nil, fmt.Errorf("failed to load reviews: %w", err)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("GET /plugins", s.handleListPlugins)
+	s.mux.HandleFunc("GET /plugins/search", s.handleSearchPlugins)
+	s.mux.HandleFunc("GET /plugins/{id}", s.handleGetPlugin)
+	s.mux.HandleFunc("GET /plugins/{id}/ratings", s.handleGetRatings)
+	s.mux.HandleFunc("POST /plugins/{id}/ratings", s.handleSubmitRating)
+	s.mux.HandleFunc("GET /packages/{file}", s.handlePackage)
+	s.mux.HandleFunc("GET /presets/search", s.handleSearchPresets)
+	s.mux.HandleFunc("GET /presets/popular", s.handlePopularPresets)
+	s.mux.HandleFunc("GET /presets/recent", s.handleRecentPresets)
+	s.mux.HandleFunc("POST /presets", s.handleCreatePreset)
+	s.mux.HandleFunc("GET /presets/{id}", s.handleGetPreset)
+	s.mux.HandleFunc("PUT /presets/{id}", s.handleUpdatePreset)
+	s.mux.HandleFunc("DELETE /presets/{id}", s.handleDeletePreset)
+	s.mux.HandleFunc("POST /presets/{id}/ratings", s.handleRatePreset)
+	s.mux.HandleFunc("GET /presets/{id}/reviews", s.handleGetReviews)
+	s.mux.HandleFunc("POST /presets/{id}/reviews", s.handleAddReview)
+
+	return s, nil
+}
+
+// SetReadOnly rejects ratings, reviews and preset changes when enabled
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.mu.Lock()
+	s.readOnly = readOnly
+	s.mu.Unlock()
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Plugin handlers
+
+func (s *Server) handleListPlugins(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.RLock()
+	plugins := append([]RemotePlugin(nil), s.index.Plugins...)
+	s.mu.RUnlock()
+
+	category := q.Get("category")
+	tags := q["tags"]
+	minRating, _ := strconv.ParseFloat(q.Get("minRating"), 32)
+
+	filtered := plugins[:0]
+	for _, p := range plugins {
+		if category != "" && !strings.EqualFold(p.Category, category) {
+			continue
+		}
+		if float64(p.Rating) < minRating {
+			continue
+		}
+		if !hasAllTags(p.Tags, tags) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+
+	sortPlugins(filtered, q.Get("sortBy"), q.Get("sortOrder") == "asc")
+	total := len(filtered)
+	page := paginate(len(filtered), q.Get("offset"), q.Get("limit"))
+	result := filtered[page.start:page.end]
+	for i := range result {
+		s.absoluteURLs(r, &result[i])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"plugins": result, "total": total})
+}
+
+func (s *Server) handleSearchPlugins(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("q"))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []RemotePlugin{}
+	for _, p := range s.index.Plugins {
+		if matchesQuery(query, p.ID, p.Name, p.Description, strings.Join(p.Tags, " ")) {
+			s.absoluteURLs(r, &p)
+			result = append(result, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"plugins": result})
+}
+
+func (s *Server) handleGetPlugin(w http.ResponseWriter, r *http.Request) {
+	plugin, ok := s.findPlugin(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "plugin not found", http.StatusNotFound)
+		return
+	}
+	s.absoluteURLs(r, &plugin)
+	writeJSON(w, http.StatusOK, plugin)
+}
+
+func (s *Server) handleGetRatings(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	ratings := s.ratings[r.PathValue("id")]
+	s.mu.RUnlock()
+	if ratings == nil {
+		ratings = []*PluginRating{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ratings": ratings})
+}
+
+func (s *Server) handleSubmitRating(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.findPlugin(id); !ok {
+		http.Error(w, "plugin not found", http.StatusNotFound)
+		return
+	}
+
+	var rating PluginRating
+	if err := json.NewDecoder(r.Body).Decode(&rating); err != nil {
+		http.Error(w, "invalid rating", http.StatusBadRequest)
+		return
+	}
+	if rating.Rating < 1 || rating.Rating > 5 {
+		http.Error(w, "rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	rating.PluginID = id
+	if rating.ID == "" {
+		rating.ID = newServerID()
+	}
+	if rating.Timestamp.IsZero() {
+		rating.Timestamp = time.Now().UTC()
+	}
+
+	err := s.update(w, func() error {
+		s.ratings[id] = append(s.ratings[id], &rating)
+		for i := range s.index.Plugins {
+			if s.index.Plugins[i].ID == id {
+				p := &s.index.Plugins[i]
+				p.Rating = (p.Rating*float32(p.RatingCount) + rating.Rating) / float32(p.RatingCount+1)
+				p.RatingCount++
+			}
+		}
+		return s.saveAll()
+	})
+	if err == nil {
+		writeJSON(w, http.StatusCreated, rating)
+	}
+}
+
+func (s *Server) handlePackage(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.Error(w, "invalid package name", http.StatusBadRequest)
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.root, mirrorPackagesDir, name))
+	if err != nil {
+		http.Error(w, "package not found", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	counted := false
+	for i := range s.index.Plugins {
+		for _, v := range s.index.Plugins[i].VersionHistory {
+			if filepath.Base(v.DownloadURL) == name {
+				s.index.Plugins[i].Downloads++
+				counted = true
+			}
+		}
+	}
+	// A read-only mirror keeps counts for this run only. Failing to save
+	// them does not fail the download.
+	if counted && !s.readOnly {
+		SaveMirrorIndex(s.root, s.index)
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Write(data)
+}
+
+// Preset handlers
+
+func (s *Server) handleSearchPresets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.ToLower(q.Get("q"))
+	presetType := q.Get("type")
+	tags := q["tags"]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []*Preset{}
+	for _, p := range s.index.Presets {
+		if presetType != "" && p.Type != presetType {
+			continue
+		}
+		if !hasAllTags(p.Tags, tags) {
+			continue
+		}
+		if matchesQuery(query, p.ID, p.Name, p.Description, strings.Join(p.Tags, " ")) {
+			result = append(result, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"presets": result})
+}
+
+func (s *Server) handlePopularPresets(w http.ResponseWriter, r *http.Request) {
+	s.writePresetList(w, r, func(a, b *Preset) bool {
+		if a.Downloads != b.Downloads {
+			return a.Downloads > b.Downloads
+		}
+		return a.Rating > b.Rating
+	})
+}
+
+func (s *Server) handleRecentPresets(w http.ResponseWriter, r *http.Request) {
+	s.writePresetList(w, r, func(a, b *Preset) bool {
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+}
+
+func (s *Server) handleGetPreset(w http.ResponseWriter, r *http.Request) {
+	preset, ok := s.findPreset(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, preset)
+}
+
+func (s *Server) handleCreatePreset(w http.ResponseWriter, r *http.Request) {
+	var preset Preset
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		http.Error(w, "invalid preset", http.StatusBadRequest)
+		return
+	}
+	if preset.ID == "" {
+		preset.ID = newServerID()
+	}
+	if _, exists := s.findPreset(preset.ID); exists {
+		http.Error(w, "preset already exists", http.StatusConflict)
+		return
+	}
+	now := time.Now().UTC()
+	if preset.CreatedAt.IsZero() {
+		preset.CreatedAt = now
+	}
+	preset.UpdatedAt = now
+
+	err := s.update(w, func() error {
+		s.index.Presets = append(s.index.Presets, &preset)
+		return s.saveAll()
+	})
+	if err == nil {
+		writeJSON(w, http.StatusCreated, preset)
+	}
+}
+
+func (s *Server) handleUpdatePreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var preset Preset
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		http.Error(w, "invalid preset", http.StatusBadRequest)
+		return
+	}
+	preset.ID = id
+
+	found := false
+	err := s.update(w, func() error {
+		for i, p := range s.index.Presets {
+			if p.ID == id {
+				preset.CreatedAt = p.CreatedAt
+				s.index.Presets[i] = &preset
+				found = true
+				return s.saveAll()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if !found {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeletePreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	found := false
+	err := s.update(w, func() error {
+		for i, p := range s.index.Presets {
+			if p.ID == id {
+				s.index.Presets = append(s.index.Presets[:i], s.index.Presets[i+1:]...)
+				delete(s.reviews, id)
+				found = true
+				return s.saveAll()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if !found {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRatePreset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body struct {
+		Rating int `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Rating < 1 || body.Rating > 5 {
+		http.Error(w, "rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+
+	found := false
+	err := s.update(w, func() error {
+		for _, p := range s.index.Presets {
+			if p.ID == id {
+				p.Rating = (p.Rating*float64(p.Reviews) + float64(body.Rating)) / float64(p.Reviews+1)
+				p.Reviews++
+				found = true
+				return s.saveAll()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if !found {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) handleGetReviews(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.findPreset(id); !ok {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+	s.mu.RLock()
+	reviews := s.reviews[id]
+	s.mu.RUnlock()
+	if reviews == nil {
+		reviews = []*Review{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reviews": reviews})
+}
+
+func (s *Server) handleAddReview(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.findPreset(id); !ok {
+		http.Error(w, "preset not found", http.StatusNotFound)
+		return
+	}
+
+	var review Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Rating < 1 || review.Rating > 5 {
+		http.Error(w, "invalid review", http.StatusBadRequest)
+		return
+	}
+	review.PresetID = id
+	if review.ID == "" {
+		review.ID = newServerID()
+	}
+	if review.CreatedAt.IsZero() {
+		review.CreatedAt = time.Now().UTC()
+	}
+
+	err := s.update(w, func() error {
+		s.reviews[id] = append(s.reviews[id], &review)
+		return s.saveAll()
+	})
+	if err == nil {
+		writeJSON(w, http.StatusCreated, review)
+	}
+}
+
+// Helpers
+
+// update runs a mutation under the write lock, rejecting it in read-only
+// mode and reporting failures to the client. It returns non-nil if a
+// response has already been written.
+func (s *Server) update(w http.ResponseWriter, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readOnly {
+		http.Error(w, "mirror is read-only", http.StatusForbidden)
+		return errors.New("read-only")
+	}
+	if err := fn(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+// saveAll persists the index, ratings and reviews. Caller must hold the lock.
+func (s *Server) saveAll() error {
+	if err := SaveMirrorIndex(s.root, s.index); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(s.root, mirrorRatingsFile), s.ratings); err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(s.root, mirrorReviewsFile), s.reviews)
+}
+
+func (s *Server) findPlugin(id string) (RemotePlugin, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.index.Plugins {
+		if p.ID == id {
+			p.VersionHistory = append([]VersionInfo(nil), p.VersionHistory...)
+			return p, true
+		}
+	}
+	return RemotePlugin{}, false
+}
+
+func (s *Server) findPreset(id string) (*Preset, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.index.Presets {
+		if p.ID == id {
+			presetCopy := *p
+			return &presetCopy, true
+		}
+	}
+	return nil, false
+}
+
+// absoluteURLs rewrites relative download URLs against the request host so
+// Client.DownloadPlugin can fetch them as-is
+func (s *Server) absoluteURLs(r *http.Request, p *RemotePlugin) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	history := make([]VersionInfo, len(p.VersionHistory))
+	for i, v := range p.VersionHistory {
+		if v.DownloadURL != "" && !strings.Contains(v.DownloadURL, "://") {
+			v.DownloadURL = fmt.Sprintf("%s://%s/%s", scheme, r.Host, strings.TrimPrefix(v.DownloadURL, "/"))
+		}
+		history[i] = v
+	}
+	p.VersionHistory = history
+}
+
+func (s *Server) writePresetList(w http.ResponseWriter, r *http.Request, less func(a, b *Preset) bool) {
+	s.mu.RLock()
+	presets := append([]*Preset(nil), s.index.Presets...)
+	s.mu.RUnlock()
+
+	sort.SliceStable(presets, func(i, j int) bool { return less(presets[i], presets[j]) })
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if len(presets) > limit {
+		presets = presets[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"presets": presets})
+}
+
+// sortPlugins orders plugins the way ListOptions.SortBy describes
+func sortPlugins(plugins []RemotePlugin, sortBy string, asc bool) {
+	less := func(a, b RemotePlugin) bool { return a.Rating < b.Rating }
+	switch sortBy {
+	case "downloads":
+		less = func(a, b RemotePlugin) bool { return a.Downloads < b.Downloads }
+	case "recent":
+		less = func(a, b RemotePlugin) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case "name":
+		less = func(a, b RemotePlugin) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if asc {
+			return less(plugins[i], plugins[j])
+		}
+		return less(plugins[j], plugins[i])
+	})
+}
+
+type pageRange struct{ start, end int }
+
+// paginate converts offset/limit query values into slice bounds
+func paginate(n int, offsetStr, limitStr string) pageRange {
+	offset, _ := strconv.Atoi(offsetStr)
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if end > n {
+		end = n
+	}
+	return pageRange{offset, end}
+}
+
+func hasAllTags(have, want []string) bool {
+	for _, t := range want {
+		found := false
+		for _, h := range have {
+			if strings.EqualFold(h, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesQuery(query string, fields ...string) bool {
+	if query == "" {
+		return true
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func newServerID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
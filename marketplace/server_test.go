@@ -0,0 +1,246 @@
+package marketplace
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+// writeTestPlugin creates a plugin directory with metadata.json and plugin.lua
+func writeTestPlugin(t *testing.T, pluginDir, id, version, category string) {
+	t.Helper()
+	dir := filepath.Join(pluginDir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create plugin dir: %v", err)
+	}
+	meta := `{"id":"` + id + `","name":"` + id + ` plugin","version":"` + version +
+		`","author":"tester","description":"test plugin","category":"` + category + `","tags":["test"]}`
+	if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(meta), 0644); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plugin.lua"), []byte("-- "+version), 0644); err != nil {
+		t.Fatalf("failed to write plugin.lua: %v", err)
+	}
+}
+
+// startMirror exports pluginDir to a mirror and serves it
+func startMirror(t *testing.T, pluginDir string) (*httptest.Server, string) {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "mirror")
+	if _, err := ExportMirror(pluginDir, root); err != nil {
+		t.Fatalf("ExportMirror() error: %v", err)
+	}
+	srv, err := NewServer(root)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts, root
+}
+
+// TestExportMirror tests packaging plugins and accumulating version history
+func TestExportMirror(t *testing.T) {
+	pluginDir := t.TempDir()
+	writeTestPlugin(t, pluginDir, "alpha", "1.0.0", "utility")
+	writeTestPlugin(t, pluginDir, "beta", "2.1.0", "combat")
+	os.MkdirAll(filepath.Join(pluginDir, "not-a-plugin"), 0755)
+
+	root := t.TempDir()
+	index, err := ExportMirror(pluginDir, root)
+	if err != nil {
+		t.Fatalf("ExportMirror() error: %v", err)
+	}
+	if len(index.Plugins) != 2 {
+		t.Fatalf("exported %d plugins, want 2", len(index.Plugins))
+	}
+
+	pkg, err := os.ReadFile(filepath.Join(root, "packages", "alpha-1.0.0.zip"))
+	if err != nil {
+		t.Fatalf("package missing: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatalf("package is not a zip: %v", err)
+	}
+	if len(zr.File) != 3 {
+		t.Errorf("package has %d files, want 3 (with manifest)", len(zr.File))
+	}
+
+	// Re-export with a new version keeps the old one
+	writeTestPlugin(t, pluginDir, "alpha", "1.1.0", "utility")
+	index, err = ExportMirror(pluginDir, root)
+	if err != nil {
+		t.Fatalf("second ExportMirror() error: %v", err)
+	}
+	alpha := index.Plugins[0]
+	if alpha.Version != "1.1.0" || len(alpha.VersionHistory) != 2 || alpha.VersionHistory[0].Version != "1.1.0" {
+		t.Errorf("alpha = %s with history %+v, want 1.1.0 then 1.0.0", alpha.Version, alpha.VersionHistory)
+	}
+
+	again, _ := PackPluginDir(filepath.Join(pluginDir, "beta"))
+	first, _ := os.ReadFile(filepath.Join(root, "packages", "beta-2.1.0.zip"))
+	if !bytes.Equal(again, first) {
+		t.Error("PackPluginDir should be deterministic")
+	}
+}
+
+// TestServerWithClient tests the unchanged client against a served mirror
+func TestServerWithClient(t *testing.T) {
+	pluginDir := t.TempDir()
+	writeTestPlugin(t, pluginDir, "alpha", "1.0.0", "utility")
+	writeTestPlugin(t, pluginDir, "beta", "2.1.0", "combat")
+	ts, root := startMirror(t, pluginDir)
+
+	ctx := context.Background()
+	client := NewClient(ts.URL, "")
+
+	all, err := client.ListPlugins(ctx, &ListOptions{SortBy: "name", SortOrder: "asc"})
+	if err != nil {
+		t.Fatalf("ListPlugins() error: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != "alpha" {
+		t.Errorf("ListPlugins() = %+v, want alpha then beta", all)
+	}
+
+	combat, err := client.ListPlugins(ctx, &ListOptions{Category: "combat"})
+	if err != nil || len(combat) != 1 || combat[0].ID != "beta" {
+		t.Errorf("ListPlugins(category=combat) = %+v, %v", combat, err)
+	}
+
+	found, err := client.SearchPlugins(ctx, "beta")
+	if err != nil || len(found) != 1 {
+		t.Errorf("SearchPlugins(beta) = %+v, %v", found, err)
+	}
+
+	if _, err := client.GetPluginDetails(ctx, "missing"); err == nil {
+		t.Error("GetPluginDetails should fail for an unknown plugin")
+	}
+
+	data, err := client.DownloadPlugin(ctx, "alpha", "")
+	if err != nil {
+		t.Fatalf("DownloadPlugin() error: %v", err)
+	}
+	want, _ := PackPluginDir(filepath.Join(pluginDir, "alpha"))
+	if !bytes.Equal(data, want) {
+		t.Error("downloaded package does not match source")
+	}
+	// The download count survives a restart
+	if reloaded, err := LoadMirrorIndex(root); err != nil || reloaded.Plugins[0].Downloads != 1 {
+		t.Errorf("saved index = %+v, %v, want alpha downloaded once", reloaded, err)
+	}
+
+	if err := client.SubmitRating(ctx, &PluginRating{PluginID: "alpha", Rating: 4}); err != nil {
+		t.Fatalf("SubmitRating() error: %v", err)
+	}
+	ratings, err := client.GetPluginRatings(ctx, "alpha")
+	if err != nil || len(ratings) != 1 || ratings[0].Rating != 4 {
+		t.Errorf("GetPluginRatings() = %+v, %v", ratings, err)
+	}
+}
+
+// TestServerPresets tests the preset endpoints and persistence
+func TestServerPresets(t *testing.T) {
+	ts, root := startMirror(t, t.TempDir())
+	client := NewClient(ts.URL, "")
+
+	preset := &Preset{ID: "tank-party", Type: "party", Name: "Tank Party", Tags: []string{"defense"}}
+	if err := client.UploadPreset(preset); err != nil {
+		t.Fatalf("UploadPreset() error: %v", err)
+	}
+	if err := client.RatePreset("tank-party", 5); err != nil {
+		t.Fatalf("RatePreset() error: %v", err)
+	}
+	if err := client.AddReview(&Review{PresetID: "tank-party", Rating: 5, Comment: "solid"}); err != nil {
+		t.Fatalf("AddReview() error: %v", err)
+	}
+
+	results, err := client.Search("tank", "party", []string{"defense"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search() = %+v, %v", results, err)
+	}
+
+	got, err := client.GetPreset("tank-party")
+	if err != nil {
+		t.Fatalf("GetPreset() error: %v", err)
+	}
+	if got.Rating != 5 || got.Reviews != 1 {
+		t.Errorf("preset rating = %.1f (%d), want 5.0 (1)", got.Rating, got.Reviews)
+	}
+
+	// State survives a restart
+	srv, err := NewServer(root)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	restarted := httptest.NewServer(srv)
+	defer restarted.Close()
+	reviews, err := NewClient(restarted.URL, "").GetReviews("tank-party")
+	if err != nil || len(reviews) != 1 {
+		t.Errorf("GetReviews() after restart = %+v, %v", reviews, err)
+	}
+
+	srv.SetReadOnly(true)
+	if err := NewClient(restarted.URL, "").DeletePreset("tank-party"); err == nil {
+		t.Error("DeletePreset should fail on a read-only mirror")
+	}
+}
+
+// TestInstallPluginVerifiesPackage tests that InstallPlugin goes through the
+// plugin manager, which refuses unsigned packages and installs signed ones
+func TestInstallPluginVerifiesPackage(t *testing.T) {
+	pluginDir := t.TempDir()
+	writeTestPlugin(t, pluginDir, "alpha", "1.0.0", "utility")
+
+	signer := plugins.NewSecurityManager()
+	if err := signer.GenerateKeyPair(); err != nil {
+		t.Fatalf("GenerateKeyPair() error: %v", err)
+	}
+	pub, _ := signer.PublicKeyPEM()
+
+	unsignedTS, _ := startMirror(t, pluginDir)
+
+	signedRoot := filepath.Join(t.TempDir(), "signed")
+	if _, err := ExportMirrorWithOptions(pluginDir, signedRoot, MirrorOptions{Signer: signer, SignerID: "publisher"}); err != nil {
+		t.Fatalf("ExportMirrorWithOptions() error: %v", err)
+	}
+	srv, err := NewServer(signedRoot)
+	if err != nil {
+		t.Fatalf("NewServer() error: %v", err)
+	}
+	signedTS := httptest.NewServer(srv)
+	t.Cleanup(signedTS.Close)
+
+	installDir := t.TempDir()
+	manager := plugins.NewManager(installDir, nil)
+	manager.GetSecurityManager().AddTrustedKey("publisher", pub)
+	ctx := context.Background()
+
+	client := NewClient(unsignedTS.URL, "")
+	if err := client.InstallPlugin(ctx, "alpha", ""); !errors.Is(err, ErrNoInstaller) {
+		t.Errorf("InstallPlugin without an installer = %v, want ErrNoInstaller", err)
+	}
+	client.SetInstaller(manager)
+	if err := client.InstallPlugin(ctx, "alpha", ""); !errors.Is(err, plugins.ErrPackageUnsigned) {
+		t.Errorf("InstallPlugin(unsigned) = %v, want ErrPackageUnsigned", err)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "alpha")); !os.IsNotExist(err) {
+		t.Error("unsigned package should not be installed")
+	}
+
+	client = NewClient(signedTS.URL, "")
+	client.SetInstaller(manager)
+	if err := client.InstallPlugin(ctx, "alpha", ""); err != nil {
+		t.Fatalf("InstallPlugin(signed) error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "alpha", "plugin.lua")); err != nil {
+		t.Errorf("signed package not installed: %v", err)
+	}
+}
@@ -5,6 +5,7 @@
 //   - Spell IDs and names
 //   - Character and job constants
 //   - Map and location data
+//   - Treasure chest table (Treasures, joined to Maps by map ID)
 //   - Experience tables
 //
 // Subpackages:
@@ -314,6 +314,18 @@ var (
 	ItemsByID            = make(map[int]string)
 	ImportantItemsByName = make(map[string]int)
 	ImportantItemsByID   = make(map[int]string)
+	// ItemCategoryByID maps every regular item to its inventory category
+	ItemCategoryByID = make(map[int]string)
+)
+
+// Item categories, one per item list
+const (
+	ItemCategoryItem   = "item"
+	ItemCategoryWeapon = "weapon"
+	ItemCategoryShield = "shield"
+	ItemCategoryHelmet = "helmet"
+	ItemCategoryArmor  = "armor"
+	ItemCategoryRelic  = "relic"
 )
 
 func init() {
@@ -326,6 +338,26 @@ func init() {
 	loadItems(RelicText1, ItemsByName, ItemsByID)
 	loadItems(RelicText2, ItemsByName, ItemsByID)
 	loadItems(ImportantItemsText, ImportantItemsByName, ImportantItemsByID)
+
+	for category, texts := range map[string][]string{
+		ItemCategoryItem:   {ItemsText},
+		ItemCategoryWeapon: {WeaponShieldText1},
+		ItemCategoryShield: {WeaponShieldText2},
+		ItemCategoryHelmet: {HelmetArmorText1},
+		ItemCategoryArmor:  {HelmetArmorText2},
+		ItemCategoryRelic:  {RelicText1, RelicText2},
+	} {
+		for _, text := range texts {
+			byID := make(map[int]string)
+			loadItems(text, make(map[string]int), byID)
+			for id, name := range byID {
+				// loadItems always adds the empty slot IDs
+				if name != "Empty" {
+					ItemCategoryByID[id] = category
+				}
+			}
+		}
+	}
 }
 
 func loadItems(s string, byName map[string]int, byID map[int]string) {
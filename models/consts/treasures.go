@@ -0,0 +1,75 @@
+package consts
+
+// Treasure is a chest or pickup whose opened state the save tracks
+type Treasure struct {
+	ID       int
+	MapID    uint16
+	Contents string
+	World    int // 1 = Balance, 2 = Ruin
+	// Missable treasures can no longer be reached once the story moves on
+	Missable bool
+	// Verified treasures have had their ID, map and contents checked
+	// against saves from the game; the rest are provisional
+	Verified bool
+}
+
+// MapName returns the name of the map the treasure is on
+func (t Treasure) MapName() string {
+	if int(t.MapID) < len(Maps) && Maps[t.MapID] != nil {
+		return Maps[t.MapID].Name
+	}
+	return "Unknown"
+}
+
+// Treasures is the treasure table, indexed by flag ID. IDs are the bit
+// positions in the save's treasure flags. No entry has been checked against
+// saves from the game yet, so every row is provisional (Verified false) and
+// the editor labels it as such.
+var Treasures = []Treasure{
+	// World of Balance
+	{0, 4, "Elixir", 1, false, false},
+	{1, 317, "Sleeping Bag", 1, false, false},
+	{2, 321, "Phoenix Down", 1, false, false},
+	{3, 21, "Ether", 1, false, false},
+	{4, 329, "Hi-Potion", 1, false, false},
+	{5, 66, "Gauntlet", 1, false, false},
+	{6, 334, "Phoenix Down", 1, false, false},
+	{7, 345, "Potion", 1, false, false},
+	{8, 356, "Earrings", 1, true, false},
+	{9, 358, "Ether", 1, true, false},
+	{10, 379, "Green Beret", 1, true, false},
+	{11, 383, "Tent", 1, false, false},
+	{12, 396, "Flame Shield", 1, true, false},
+	{13, 397, "Ice Shield", 1, true, false},
+	{14, 421, "Gold Hairpin", 1, true, false},
+	{15, 165, "Elixir", 1, true, false},
+	{16, 431, "Genji Glove", 1, true, false},
+
+	// World of Ruin
+	{32, 3, "Ribbon", 2, false, false},
+	{33, 318, "Elixir", 2, false, false},
+	{34, 435, "Megalixir", 2, false, false},
+	{35, 443, "Rainbow Brush", 2, false, false},
+	{36, 456, "Dark Gear", 2, false, false},
+	{37, 477, "Ribbon", 2, false, false},
+	{38, 488, "Cat-Ear Hood", 2, false, false},
+	{39, 493, "Gold Hairpin", 2, false, false},
+	{40, 498, "Dragon Horn", 2, false, false},
+	{41, 512, "Blood Sword", 2, false, false},
+	{42, 530, "Lump of Metal", 2, true, false},
+	{43, 539, "Hermes Sandals", 2, false, false},
+	{44, 542, "Crystal Orb", 2, false, false},
+	{45, 546, "Dragon Claws", 2, false, false},
+	{46, 550, "Force Armor", 2, false, false},
+}
+
+// TreasuresByMap returns the treasures on a map
+func TreasuresByMap(mapID uint16) []Treasure {
+	var list []Treasure
+	for _, t := range Treasures {
+		if t.MapID == mapID {
+			list = append(list, t)
+		}
+	}
+	return list
+}
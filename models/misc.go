@@ -24,3 +24,11 @@ func GetMisc() *Misc {
 	}
 	return misc
 }
+
+// SwapMisc makes m the current misc data and returns the previous one; nil
+// starts afresh on the next GetMisc
+func SwapMisc(m *Misc) *Misc {
+	previous := misc
+	misc = m
+	return previous
+}
@@ -12,7 +12,12 @@ import (
 var Characters []*models.Character
 
 func init() {
-	Characters = make([]*models.Character, len(pr.Characters))
+	Characters = newCharacters()
+}
+
+// newCharacters returns every character with default stats and commands
+func newCharacters() []*models.Character {
+	characters := make([]*models.Character, len(pr.Characters))
 	// defaultCommand := consts.CommandLookupByValue[0xFF]
 	for i, name := range pr.Characters {
 		o, ok := CharacterOffsetByName[name]
@@ -41,8 +46,9 @@ func init() {
 			},
 		}
 		c.SpellsByIndex, c.SpellsSorted, c.SpellsByID = NewSpells()
-		Characters[i] = c
+		characters[i] = c
 	}
+	return characters
 }
 
 func GetCharacter(name string) (c *models.Character) {
@@ -0,0 +1,125 @@
+package pr
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Arrays the game keeps in the dataStorage block
+const (
+	DataStorageGlobal   = "global"
+	DataStorageScenario = "scenario"
+	DataStorageArea     = "area"
+)
+
+// DataStorage is the decoded dataStorage block of a save: named arrays of
+// integer slots holding story/event flags and counters. Arrays other than
+// global, scenario and area are kept so they survive a round trip.
+type DataStorage struct {
+	Arrays map[string][]int
+}
+
+var dataStorage *DataStorage
+
+func GetDataStorage() *DataStorage {
+	if dataStorage == nil {
+		dataStorage = &DataStorage{Arrays: make(map[string][]int)}
+	}
+	return dataStorage
+}
+
+// Clear removes all arrays
+func (d *DataStorage) Clear() {
+	d.Arrays = make(map[string][]int)
+}
+
+// Get returns a slot value
+func (d *DataStorage) Get(array string, index int) (int, error) {
+	values, ok := d.Arrays[array]
+	if !ok {
+		return 0, fmt.Errorf("dataStorage has no %s array", array)
+	}
+	if index < 0 || index >= len(values) {
+		return 0, fmt.Errorf("%s[%d] is out of range (length %d)", array, index, len(values))
+	}
+	return values[index], nil
+}
+
+// Set changes a slot value. Arrays are not grown; the game sizes them.
+func (d *DataStorage) Set(array string, index, value int) error {
+	values, ok := d.Arrays[array]
+	if !ok {
+		return fmt.Errorf("dataStorage has no %s array", array)
+	}
+	if index < 0 || index >= len(values) {
+		return fmt.Errorf("%s[%d] is out of range (length %d)", array, index, len(values))
+	}
+	values[index] = value
+	return nil
+}
+
+// Names returns the array names in sorted order
+func (d *DataStorage) Names() []string {
+	names := make([]string, 0, len(d.Arrays))
+	for name := range d.Arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a deep copy, e.g. to compare two saves loaded one after another
+func (d *DataStorage) Clone() *DataStorage {
+	c := &DataStorage{Arrays: make(map[string][]int, len(d.Arrays))}
+	for name, values := range d.Arrays {
+		c.Arrays[name] = append([]int(nil), values...)
+	}
+	return c
+}
+
+// SlotDiff is one slot that differs between two DataStorage blocks
+type SlotDiff struct {
+	Array    string
+	Index    int
+	OldValue int
+	NewValue int
+}
+
+// DiffSlots lists every slot that differs from other. Slots missing on one
+// side compare as 0.
+func (d *DataStorage) DiffSlots(other *DataStorage) []SlotDiff {
+	names := make(map[string]bool)
+	for name := range d.Arrays {
+		names[name] = true
+	}
+	for name := range other.Arrays {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []SlotDiff
+	for _, name := range sorted {
+		a, b := d.Arrays[name], other.Arrays[name]
+		n := len(a)
+		if len(b) > n {
+			n = len(b)
+		}
+		for i := 0; i < n; i++ {
+			var av, bv int
+			if i < len(a) {
+				av = a[i]
+			}
+			if i < len(b) {
+				bv = b[i]
+			}
+			if av != bv {
+				diffs = append(diffs, SlotDiff{Array: name, Index: i, OldValue: av, NewValue: bv})
+			}
+		}
+	}
+	return diffs
+}
@@ -11,6 +11,37 @@
 //	Inventory - Item storage with normal and important item lists
 //	MapData   - Player position and map-related information
 //	Veldt     - Rage encounter tracking for Gau
+//	DataStorage - Decoded dataStorage flag arrays (global, scenario, area)
+//
+// Story Flags:
+//
+// StoryFlags is a catalogue naming dataStorage slots ("Floating Continent
+// cleared", "Shadow waited for", ...) and ordering story checkpoints.
+// ApplyCheckpoint sets every main-story flag up to a checkpoint and clears
+// the rest, leaving optional side-content flags alone. Flags not marked
+// Verified are experimental (all built-in ones, for now): UnverifiedWrites
+// lists them so callers can refuse to write them without confirmation, and
+// LoadStoryFlagCatalog replaces the catalogue with a corrected JSON one.
+//
+// Treasures:
+//
+// Treasure flags are the bits of the dataStorage "treasure" array, one per
+// consts.Treasures ID. State.SetTreasureOpened and SetTreasuresOpened open or
+// reset chests by ID, map, world or missability and keep that save's
+// Cheats.OpenedChestCount consistent with the flags they change. Treasure
+// entries not marked Verified (all of them, for now) are provisional:
+// CheckTreasureVerified rejects them and UnverifiedTreasures lists them so
+// callers can refuse to write them without confirmation.
+//
+// Travel State:
+//
+// Transportations holds every vehicle record; Validate refuses enabled
+// vehicles parked off the world maps and CheckPosition warns about positions
+// past the assumed map size. Only the Blackjack and Falcon records are
+// identified; the chocobo and any other records are edited by index.
+// GetTimer is the running story countdown (Floating Continent escape, ...,
+// IDs provisional until TimerVerified reports them) and GetTeleportCache the point Warp returns to. Both are
+// marked Present only when the save stores them.
 //
 // Singleton Access:
 //
@@ -0,0 +1,137 @@
+package pr
+
+import (
+	"sync"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts"
+	"ffvi_editor/models/consts/pr"
+)
+
+// State is one set of the save models the loader fills: characters,
+// inventories, party, map and story data, and which espers and skills are
+// known. The package variables hold the current State.
+type State struct {
+	characters         []*models.Character
+	transportations    []*Transportation
+	misc               *models.Misc
+	inventory          *Inventory
+	importantInventory *Inventory
+	party              *Party
+	cheats             *Cheats
+	dataStorage        *DataStorage
+	mapData            *MapData
+	teleportCache      *TeleportCache
+	timer              *Timer
+	veldt              *Veldt
+	checked            [][]bool // see checkedLists
+}
+
+// checkedLists are the shared lists whose Checked flags record what a save
+// has learned
+func checkedLists() [][]*consts.NameValueChecked {
+	return [][]*consts.NameValueChecked{pr.Espers, pr.Blitzes, pr.Bushidos, pr.Dances, pr.Lores, pr.Rages}
+}
+
+// isolateMu keeps one Isolated call at a time, and WithCurrent out of them
+var isolateMu sync.Mutex
+
+// CurrentState returns the current save models
+func CurrentState() *State {
+	return &State{
+		characters:         Characters,
+		transportations:    Transportations,
+		misc:               models.GetMisc(),
+		inventory:          GetInventory(),
+		importantInventory: GetImportantInventory(),
+		party:              GetParty(),
+		cheats:             GetCheats(),
+		dataStorage:        GetDataStorage(),
+		mapData:            GetMapData(),
+		teleportCache:      GetTeleportCache(),
+		timer:              GetTimer(),
+		veldt:              GetVeldt(),
+	}
+}
+
+// Isolated runs fn with fresh save models in place of the current ones and
+// puts the current ones back afterwards, so a save can be loaded, edited and
+// written without touching the one the editor has open. Code running on
+// other goroutines sees the fresh models until fn returns, so the editor
+// loads and saves through WithCurrent, which waits for fn to finish.
+func Isolated(fn func() error) error {
+	isolateMu.Lock()
+	defer isolateMu.Unlock()
+
+	saved := CurrentState()
+	for _, list := range checkedLists() {
+		flags := make([]bool, len(list))
+		for i, v := range list {
+			flags[i] = v.Checked
+			v.Checked = false
+		}
+		saved.checked = append(saved.checked, flags)
+	}
+	defer saved.install()
+
+	Characters = newCharacters()
+	Transportations = nil
+	models.SwapMisc(nil)
+	inventory, importantInventory, party = nil, nil, nil
+	cheats, dataStorage, mapData, teleportCache, timer, veldt = nil, nil, nil, nil, nil, nil
+	return fn()
+}
+
+// WithCurrent runs fn once no Isolated call is running, and keeps one from
+// starting until fn returns. Loading a save into the current models or
+// writing them out must go through it, or a save being processed in
+// isolation could be read into or written out of the wrong file.
+func WithCurrent(fn func() error) error {
+	isolateMu.Lock()
+	defer isolateMu.Unlock()
+	return fn()
+}
+
+// install makes s the current state
+func (s *State) install() {
+	Characters = s.characters
+	Transportations = s.transportations
+	models.SwapMisc(s.misc)
+	inventory, importantInventory, party = s.inventory, s.importantInventory, s.party
+	cheats, dataStorage, mapData = s.cheats, s.dataStorage, s.mapData
+	teleportCache, timer, veldt = s.teleportCache, s.timer, s.veldt
+	for i, list := range checkedLists() {
+		if i < len(s.checked) {
+			for j, v := range list {
+				v.Checked = s.checked[i][j]
+			}
+		}
+	}
+}
+
+// Characters returns the state's characters
+func (s *State) Characters() []*models.Character { return s.characters }
+
+// Transportations returns the state's vehicle records
+func (s *State) Transportations() []*Transportation { return s.transportations }
+
+// Misc returns the state's gil, step and battle counters
+func (s *State) Misc() *models.Misc { return s.misc }
+
+// Inventory returns the state's item inventory
+func (s *State) Inventory() *Inventory { return s.inventory }
+
+// ImportantInventory returns the state's key item inventory
+func (s *State) ImportantInventory() *Inventory { return s.importantInventory }
+
+// Party returns the state's party
+func (s *State) Party() *Party { return s.party }
+
+// Cheats returns the state's counters and toggles
+func (s *State) Cheats() *Cheats { return s.cheats }
+
+// DataStorage returns the state's story/event flag arrays
+func (s *State) DataStorage() *DataStorage { return s.dataStorage }
+
+// MapData returns the state's map position
+func (s *State) MapData() *MapData { return s.mapData }
@@ -0,0 +1,83 @@
+package pr
+
+import (
+	"testing"
+	"time"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts/pr"
+)
+
+// TestIsolated tests fresh models replace the current ones inside Isolated
+// and the current ones are back afterwards
+func TestIsolated(t *testing.T) {
+	models.GetMisc().GP = 1234
+	GetInventory().Set(0, Row{ItemID: 5, Count: 3})
+	pr.Espers[0].Checked = true
+	terra := GetCharacter("Terra")
+	defer func() {
+		models.GetMisc().GP = 0
+		GetInventory().Reset()
+		pr.Espers[0].Checked = false
+	}()
+
+	err := Isolated(func() error {
+		s := CurrentState()
+		if s.Misc().GP != 0 || s.Inventory().GetRows()[0].ItemID != 0 || pr.Espers[0].Checked {
+			t.Error("Isolated kept the current models")
+		}
+		if GetCharacter("Terra") == terra {
+			t.Error("Isolated kept the current characters")
+		}
+		s.Misc().GP = 99
+		pr.Espers[0].Checked = false
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if models.GetMisc().GP != 1234 || GetInventory().GetRows()[0].Count != 3 || !pr.Espers[0].Checked {
+		t.Error("models not restored after Isolated")
+	}
+	if GetCharacter("Terra") != terra {
+		t.Error("characters not restored after Isolated")
+	}
+}
+
+// TestWithCurrentWaitsForIsolated tests WithCurrent does not run while an
+// Isolated call has the current models swapped out
+func TestWithCurrentWaitsForIsolated(t *testing.T) {
+	terra := GetCharacter("Terra")
+	inside, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Isolated(func() error {
+			close(inside)
+			<-release
+			return nil
+		})
+	}()
+	<-inside
+
+	ran := make(chan bool)
+	go func() {
+		_ = WithCurrent(func() error {
+			ran <- GetCharacter("Terra") == terra
+			return nil
+		})
+	}()
+	select {
+	case <-ran:
+		t.Fatal("WithCurrent ran while Isolated was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if current := <-ran; !current {
+		t.Error("WithCurrent did not see the current models")
+	}
+}
@@ -0,0 +1,290 @@
+package pr
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StoryFlag names one event flag in the dataStorage block. A zero Mask
+// means the whole slot is the flag (non-zero is set); otherwise the flag is
+// the masked bits of the slot.
+type StoryFlag struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Array       string `json:"array"`
+	Index       int    `json:"index"`
+	Mask        int    `json:"mask,omitempty"`
+	// Optional flags are side content that checkpoints leave alone
+	Optional bool `json:"optional,omitempty"`
+	// Verified flags have been confirmed against saves from the game. The
+	// rest are experimental; see UnverifiedWrites.
+	Verified bool `json:"verified,omitempty"`
+}
+
+// StoryCheckpoint is a point in the main story. Applying it sets the
+// non-optional flags of this and every earlier checkpoint and clears those
+// of later checkpoints, so the flag set is always consistent.
+type StoryCheckpoint struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Flags       []string `json:"flags"`
+}
+
+// StoryFlagCatalog is the set of known flags and checkpoints, in story order
+type StoryFlagCatalog struct {
+	Flags       []StoryFlag       `json:"flags"`
+	Checkpoints []StoryCheckpoint `json:"checkpoints"`
+}
+
+// StoryFlags is the active catalogue. None of the built-in slot positions
+// has been checked against real saves yet, so every built-in flag is
+// experimental; a corrected catalogue can be loaded with LoadStoryFlagCatalog.
+var StoryFlags = defaultStoryFlagCatalog()
+
+func defaultStoryFlagCatalog() *StoryFlagCatalog {
+	flag := func(id, name, desc string, index int, optional bool) StoryFlag {
+		return StoryFlag{ID: id, Name: name, Description: desc, Array: DataStorageScenario, Index: index, Optional: optional}
+	}
+	return &StoryFlagCatalog{
+		Flags: []StoryFlag{
+			flag("narshe-escaped", "Escaped Narshe", "Terra escaped the Narshe mines with Locke", 0, false),
+			flag("figaro-escaped", "Figaro Castle escape", "Edgar's castle submerged to escape Kefka", 1, false),
+			flag("returners-joined", "Joined the Returners", "Terra agreed to help Banon", 2, false),
+			flag("doma-poisoned", "Doma poisoned", "Kefka poisoned the river at Doma", 3, false),
+			flag("phantom-train", "Phantom Train cleared", "Sabin and Cyan escaped the Phantom Train", 4, false),
+			flag("narshe-defended", "Narshe defended", "The Empire's assault on Narshe was repelled", 5, false),
+			flag("opera-completed", "Opera performed", "Celes performed Maria's role at the opera", 6, false),
+			flag("magitek-factory-escaped", "Magitek Factory escaped", "The party fled Vector with the freed espers' remains", 7, false),
+			flag("imperial-banquet-done", "Imperial banquet attended", "The Emperor's banquet in Vector is over", 8, false),
+			flag("floating-continent-cleared", "Floating Continent cleared", "The continent fell and the world was ruined", 9, false),
+			flag("shadow-waited-for", "Shadow waited for", "The party waited for Shadow before leaving the Floating Continent", 10, true),
+			flag("world-of-ruin-reached", "World of Ruin reached", "Celes woke on the Solitary Island", 11, false),
+			flag("falcon-raised", "Falcon raised", "The Falcon was raised from Daryl's Tomb", 12, false),
+			flag("cyans-nightmare-done", "Cyan's nightmare done", "Cyan's dream in Mt. Zozo's aftermath was resolved", 13, true),
+			flag("phoenix-cave-cleared", "Phoenix Cave cleared", "Locke found the Phoenix magicite", 14, true),
+			flag("kefka-tower-opened", "Kefka's Tower reachable", "The Falcon can land at Kefka's Tower", 15, false),
+		},
+		Checkpoints: []StoryCheckpoint{
+			{ID: "wob-start", Name: "New game", Description: "Before the Narshe escape", Flags: nil},
+			{ID: "returners", Name: "Returners", Description: "At the Returner hideout", Flags: []string{"narshe-escaped", "figaro-escaped", "returners-joined"}},
+			{ID: "scenarios", Name: "Split scenarios done", Description: "Back in Narshe after the three scenarios", Flags: []string{"doma-poisoned", "phantom-train", "narshe-defended"}},
+			{ID: "opera", Name: "Opera", Description: "Blackjack acquired after the opera", Flags: []string{"opera-completed"}},
+			{ID: "vector", Name: "Vector", Description: "After the imperial banquet", Flags: []string{"magitek-factory-escaped", "imperial-banquet-done"}},
+			{ID: "world-of-ruin", Name: "World of Ruin", Description: "Start of the World of Ruin", Flags: []string{"floating-continent-cleared", "world-of-ruin-reached"}},
+			{ID: "falcon", Name: "Falcon", Description: "Airship regained", Flags: []string{"falcon-raised"}},
+			{ID: "final-dungeon", Name: "Kefka's Tower", Description: "Ready for the final dungeon", Flags: []string{"kefka-tower-opened"}},
+		},
+	}
+}
+
+// LoadStoryFlagCatalog replaces the active catalogue with one read from JSON
+func LoadStoryFlagCatalog(data []byte) error {
+	var catalog StoryFlagCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return fmt.Errorf("invalid flag catalogue: %w", err)
+	}
+	if err := catalog.Validate(); err != nil {
+		return err
+	}
+	StoryFlags = &catalog
+	return nil
+}
+
+// ResetStoryFlagCatalog restores the built-in catalogue
+func ResetStoryFlagCatalog() {
+	StoryFlags = defaultStoryFlagCatalog()
+}
+
+// Validate checks for duplicate IDs and checkpoints naming unknown flags
+func (c *StoryFlagCatalog) Validate() error {
+	ids := make(map[string]bool, len(c.Flags))
+	for _, f := range c.Flags {
+		if f.ID == "" || f.Array == "" || f.Index < 0 {
+			return fmt.Errorf("flag %q needs an id, array and non-negative index", f.ID)
+		}
+		if ids[f.ID] {
+			return fmt.Errorf("duplicate flag %s", f.ID)
+		}
+		ids[f.ID] = true
+	}
+	seen := make(map[string]bool, len(c.Checkpoints))
+	for _, cp := range c.Checkpoints {
+		if seen[cp.ID] {
+			return fmt.Errorf("duplicate checkpoint %s", cp.ID)
+		}
+		seen[cp.ID] = true
+		for _, id := range cp.Flags {
+			if !ids[id] {
+				return fmt.Errorf("checkpoint %s names unknown flag %s", cp.ID, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Flag returns a flag by ID
+func (c *StoryFlagCatalog) Flag(id string) (StoryFlag, bool) {
+	for _, f := range c.Flags {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return StoryFlag{}, false
+}
+
+// UnverifiedWrites returns the IDs of the unverified flags that applying
+// checkpoint (if not empty) and then setting or clearing ids would write.
+// Unknown IDs are left for SetFlag and ApplyCheckpoint to report.
+func (c *StoryFlagCatalog) UnverifiedWrites(checkpoint string, ids []string) []string {
+	var touched []string
+	if checkpoint != "" {
+		for _, cp := range c.Checkpoints {
+			for _, id := range cp.Flags {
+				if f, ok := c.Flag(id); ok && !f.Optional {
+					touched = append(touched, id)
+				}
+			}
+		}
+	}
+	touched = append(touched, ids...)
+
+	var unverified []string
+	seen := make(map[string]bool, len(touched))
+	for _, id := range touched {
+		if f, ok := c.Flag(id); ok && !f.Verified && !seen[id] {
+			seen[id] = true
+			unverified = append(unverified, id)
+		}
+	}
+	return unverified
+}
+
+// UnverifiedCheckpointFlags returns the IDs of the unverified flags that
+// decide whether checkpoint has been reached: its own and every earlier
+// checkpoint's. An empty checkpoint means all of them, which is what
+// CurrentCheckpoint reads.
+func (c *StoryFlagCatalog) UnverifiedCheckpointFlags(checkpoint string) []string {
+	var unverified []string
+	for _, cp := range c.Checkpoints {
+		for _, id := range cp.Flags {
+			if f, ok := c.Flag(id); ok && !f.Verified {
+				unverified = append(unverified, id)
+			}
+		}
+		if cp.ID == checkpoint {
+			break
+		}
+	}
+	return unverified
+}
+
+// IsSet reports whether the flag is set in d
+func (f StoryFlag) IsSet(d *DataStorage) (bool, error) {
+	v, err := d.Get(f.Array, f.Index)
+	if err != nil {
+		return false, err
+	}
+	if f.Mask == 0 {
+		return v != 0, nil
+	}
+	return v&f.Mask == f.Mask, nil
+}
+
+// Apply sets or clears the flag in d
+func (f StoryFlag) Apply(d *DataStorage, set bool) error {
+	v, err := d.Get(f.Array, f.Index)
+	if err != nil {
+		return err
+	}
+	switch {
+	case f.Mask == 0 && set:
+		if v == 0 {
+			v = 1
+		}
+	case f.Mask == 0:
+		v = 0
+	case set:
+		v |= f.Mask
+	default:
+		v &^= f.Mask
+	}
+	return d.Set(f.Array, f.Index, v)
+}
+
+// SetFlag sets or clears a catalogued flag by ID
+func (d *DataStorage) SetFlag(id string, set bool) error {
+	f, ok := StoryFlags.Flag(id)
+	if !ok {
+		return fmt.Errorf("unknown flag: %s", id)
+	}
+	return f.Apply(d, set)
+}
+
+// ApplyCheckpoint sets the story flags up to and including the checkpoint
+// and clears the ones after it. Optional flags are not changed.
+func (d *DataStorage) ApplyCheckpoint(id string) error {
+	index := -1
+	for i, cp := range StoryFlags.Checkpoints {
+		if cp.ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("unknown checkpoint: %s", id)
+	}
+
+	for i, cp := range StoryFlags.Checkpoints {
+		for _, flagID := range cp.Flags {
+			f, _ := StoryFlags.Flag(flagID)
+			if f.Optional {
+				continue
+			}
+			if err := f.Apply(d, i <= index); err != nil {
+				return fmt.Errorf("checkpoint %s: %s: %w", cp.ID, flagID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CurrentCheckpoint returns the latest checkpoint whose flags are all set,
+// or false if not even the first one is complete
+func (d *DataStorage) CurrentCheckpoint() (StoryCheckpoint, bool) {
+	var current StoryCheckpoint
+	found := false
+	for _, cp := range StoryFlags.Checkpoints {
+		for _, flagID := range cp.Flags {
+			f, _ := StoryFlags.Flag(flagID)
+			if set, err := f.IsSet(d); err != nil || !set {
+				return current, found
+			}
+		}
+		current, found = cp, true
+	}
+	return current, found
+}
+
+// StoryFlagDiff is a catalogued flag that differs between two saves
+type StoryFlagDiff struct {
+	Flag StoryFlag
+	Old  bool
+	New  bool
+}
+
+// DiffStoryFlags compares the catalogued flags of d against other. Flags
+// whose slot is missing on either side are skipped.
+func (d *DataStorage) DiffStoryFlags(other *DataStorage) []StoryFlagDiff {
+	var diffs []StoryFlagDiff
+	for _, f := range StoryFlags.Flags {
+		a, errA := f.IsSet(d)
+		b, errB := f.IsSet(other)
+		if errA != nil || errB != nil || a == b {
+			continue
+		}
+		diffs = append(diffs, StoryFlagDiff{Flag: f, Old: a, New: b})
+	}
+	return diffs
+}
@@ -0,0 +1,148 @@
+package pr
+
+import (
+	"testing"
+)
+
+// newTestDataStorage returns a data storage block with zeroed arrays
+func newTestDataStorage() *DataStorage {
+	return &DataStorage{Arrays: map[string][]int{
+		DataStorageGlobal:   make([]int, 10),
+		DataStorageScenario: make([]int, 32),
+		DataStorageArea:     make([]int, 8),
+	}}
+}
+
+// TestApplyCheckpoint tests that checkpoints set earlier flags and clear later ones
+func TestApplyCheckpoint(t *testing.T) {
+	ResetStoryFlagCatalog()
+	d := newTestDataStorage()
+
+	if err := d.SetFlag("shadow-waited-for", true); err != nil {
+		t.Fatalf("SetFlag failed: %v", err)
+	}
+	if err := d.ApplyCheckpoint("world-of-ruin"); err != nil {
+		t.Fatalf("ApplyCheckpoint failed: %v", err)
+	}
+
+	for id, want := range map[string]bool{
+		"narshe-escaped":             true,
+		"opera-completed":            true,
+		"floating-continent-cleared": true,
+		"falcon-raised":              false,
+		"shadow-waited-for":          true,
+	} {
+		f, _ := StoryFlags.Flag(id)
+		if set, err := f.IsSet(d); err != nil || set != want {
+			t.Errorf("%s = %v, %v, want %v", id, set, err, want)
+		}
+	}
+	if cp, ok := d.CurrentCheckpoint(); !ok || cp.ID != "world-of-ruin" {
+		t.Errorf("CurrentCheckpoint() = %s, %v, want world-of-ruin", cp.ID, ok)
+	}
+
+	// Going back clears the later story flags but keeps optional ones
+	if err := d.ApplyCheckpoint("returners"); err != nil {
+		t.Fatalf("ApplyCheckpoint(returners) failed: %v", err)
+	}
+	f, _ := StoryFlags.Flag("floating-continent-cleared")
+	if set, _ := f.IsSet(d); set {
+		t.Error("floating-continent-cleared should be cleared by an earlier checkpoint")
+	}
+	f, _ = StoryFlags.Flag("shadow-waited-for")
+	if set, _ := f.IsSet(d); !set {
+		t.Error("optional flags should not be changed by checkpoints")
+	}
+
+	if err := d.ApplyCheckpoint("missing"); err == nil {
+		t.Error("ApplyCheckpoint should reject unknown checkpoints")
+	}
+}
+
+// TestStoryFlagMask tests flags stored as bits of a shared slot
+func TestStoryFlagMask(t *testing.T) {
+	d := newTestDataStorage()
+	d.Arrays[DataStorageArea][2] = 0x10
+	f := StoryFlag{ID: "bit", Array: DataStorageArea, Index: 2, Mask: 0x4}
+
+	if set, _ := f.IsSet(d); set {
+		t.Error("bit should start clear")
+	}
+	f.Apply(d, true)
+	if v, _ := d.Get(DataStorageArea, 2); v != 0x14 {
+		t.Errorf("slot = %#x after set, want 0x14", v)
+	}
+	f.Apply(d, false)
+	if v, _ := d.Get(DataStorageArea, 2); v != 0x10 {
+		t.Errorf("slot = %#x after clear, want 0x10", v)
+	}
+}
+
+// TestDiffStoryFlags tests comparing two data storage blocks
+func TestDiffStoryFlags(t *testing.T) {
+	ResetStoryFlagCatalog()
+	a := newTestDataStorage()
+	b := a.Clone()
+	b.SetFlag("cyans-nightmare-done", true)
+	b.Arrays[DataStorageArea][7] = 3
+
+	flags := a.DiffStoryFlags(b)
+	if len(flags) != 1 || flags[0].Flag.ID != "cyans-nightmare-done" || flags[0].Old || !flags[0].New {
+		t.Errorf("DiffStoryFlags() = %+v, want cyans-nightmare-done false -> true", flags)
+	}
+	if slots := a.DiffSlots(b); len(slots) != 2 {
+		t.Errorf("DiffSlots() = %+v, want 2 entries", slots)
+	}
+	if a.Arrays[DataStorageArea][7] != 0 {
+		t.Error("Clone should not share slices")
+	}
+}
+
+// TestLoadStoryFlagCatalog tests catalogue overrides and validation
+func TestLoadStoryFlagCatalog(t *testing.T) {
+	defer ResetStoryFlagCatalog()
+
+	err := LoadStoryFlagCatalog([]byte(`{"flags":[{"id":"a","name":"A","array":"global","index":1,"verified":true}],
+		"checkpoints":[{"id":"start","name":"Start","flags":["a"]}]}`))
+	if err != nil {
+		t.Fatalf("LoadStoryFlagCatalog failed: %v", err)
+	}
+	if f, ok := StoryFlags.Flag("a"); !ok || !f.Verified || f.Index != 1 {
+		t.Errorf("Flag(a) = %+v, %v", f, ok)
+	}
+
+	if err := LoadStoryFlagCatalog([]byte(`{"flags":[],"checkpoints":[{"id":"x","flags":["missing"]}]}`)); err == nil {
+		t.Error("checkpoints naming unknown flags should be rejected")
+	}
+	if _, ok := StoryFlags.Flag("a"); !ok {
+		t.Error("a rejected catalogue should leave the active one in place")
+	}
+}
+
+// TestUnverifiedWrites tests which experimental flags a write would touch
+func TestUnverifiedWrites(t *testing.T) {
+	defer ResetStoryFlagCatalog()
+
+	ResetStoryFlagCatalog()
+	if got := StoryFlags.UnverifiedWrites("", []string{"shadow-waited-for", "shadow-waited-for", "nope"}); len(got) != 1 || got[0] != "shadow-waited-for" {
+		t.Errorf("UnverifiedWrites(set) = %v", got)
+	}
+	got := StoryFlags.UnverifiedWrites("opera", nil)
+	for _, id := range got {
+		if id == "shadow-waited-for" {
+			t.Error("checkpoints leave optional flags alone and should not list them")
+		}
+	}
+	if len(got) == 0 {
+		t.Error("the built-in checkpoints write unverified flags")
+	}
+
+	err := LoadStoryFlagCatalog([]byte(`{"flags":[{"id":"a","name":"A","array":"global","index":1,"verified":true}],
+		"checkpoints":[{"id":"start","name":"Start","flags":["a"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := StoryFlags.UnverifiedWrites("start", []string{"a"}); len(got) != 0 {
+		t.Errorf("verified flags reported as unverified: %v", got)
+	}
+}
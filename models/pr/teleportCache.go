@@ -0,0 +1,27 @@
+package pr
+
+// TeleportCache is where Warp and Warp Stones return the party to: the last
+// entrance used on a world map
+type TeleportCache struct {
+	// Present is false when the save has no telepoCacheData
+	Present   bool
+	MapID     int
+	PointIn   int
+	Position  V3
+	Direction int
+}
+
+var teleportCache *TeleportCache
+
+func GetTeleportCache() *TeleportCache {
+	if teleportCache == nil {
+		teleportCache = &TeleportCache{}
+	}
+	return teleportCache
+}
+
+// Clear empties the cache so Warp has no return point
+func (c *TeleportCache) Clear() {
+	present := c.Present
+	*c = TeleportCache{Present: present}
+}
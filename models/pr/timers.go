@@ -0,0 +1,90 @@
+package pr
+
+import "fmt"
+
+// TimerKind is a story countdown the game can run
+type TimerKind struct {
+	ID             int
+	Name           string
+	DefaultSeconds float64
+	// Verified countdowns have had their ID checked against a save taken
+	// while it runs; the rest are provisional
+	Verified bool
+}
+
+// TimerKinds lists the known countdowns. No ID has been checked against
+// saves taken while each countdown runs yet, so every one is provisional
+// and the editor labels them as such.
+var TimerKinds = []TimerKind{
+	{1, "Opera House rafters", 300, false},
+	{2, "Floating Continent escape", 360, false},
+	{3, "Narshe cliffs (Lone Wolf)", 300, false},
+	{4, "Phoenix Cave", 600, false},
+}
+
+// TimerVerified reports whether id is a known countdown whose ID is verified
+func TimerVerified(id int) bool {
+	for _, k := range TimerKinds {
+		if k.ID == id {
+			return k.Verified
+		}
+	}
+	return false
+}
+
+// Timer is the countdown state in the save's timerData. Only one countdown
+// runs at a time.
+type Timer struct {
+	// Present is false when the save has no timerData
+	Present   bool
+	Active    bool
+	ID        int
+	Remaining float64 // seconds
+}
+
+var timer *Timer
+
+func GetTimer() *Timer {
+	if timer == nil {
+		timer = &Timer{}
+	}
+	return timer
+}
+
+// Name returns the countdown's name
+func (t *Timer) Name() string {
+	for _, k := range TimerKinds {
+		if k.ID == t.ID {
+			return k.Name
+		}
+	}
+	return fmt.Sprintf("Timer %d", t.ID)
+}
+
+// Start runs the countdown id with the given seconds left. Zero seconds
+// uses the countdown's default length.
+func (t *Timer) Start(id int, seconds float64) error {
+	if seconds < 0 {
+		return fmt.Errorf("timer seconds must not be negative")
+	}
+	if seconds == 0 {
+		for _, k := range TimerKinds {
+			if k.ID == id {
+				seconds = k.DefaultSeconds
+			}
+		}
+		if seconds == 0 {
+			return fmt.Errorf("unknown timer %d needs explicit seconds", id)
+		}
+	}
+	t.Active = true
+	t.ID = id
+	t.Remaining = seconds
+	return nil
+}
+
+// Stop ends the running countdown
+func (t *Timer) Stop() {
+	t.Active = false
+	t.Remaining = 0
+}
@@ -1,5 +1,11 @@
 package pr
 
+import (
+	"fmt"
+
+	"ffvi_editor/models/consts"
+)
+
 var Transportations []*Transportation
 
 type Transportation struct {
@@ -12,3 +18,88 @@ type Transportation struct {
 	Direction      int
 	TimeStampTicks uint64
 }
+
+// World map IDs vehicles can be parked on, and the assumed size of those
+// maps. WorldMapSize has not been checked against the game, so positions
+// outside it are only reported by CheckPosition, never refused or fixed.
+const (
+	WorldOfBalanceMapID = 1
+	WorldOfRuinMapID    = 2
+	WorldMapSize        = 256
+)
+
+// transportationNames names the vehicle records by their position in the
+// save's transportation list. Only the airships are identified; the other
+// records, the chocobo among them, are shown by index until their positions
+// are confirmed.
+var transportationNames = map[int]string{
+	3: "Blackjack",
+	4: "Falcon",
+}
+
+// TransportationName returns the vehicle name for a list index, or
+// "Vehicle <index>" for records that are not identified
+func TransportationName(index int) string {
+	if name, ok := transportationNames[index]; ok {
+		return name
+	}
+	return fmt.Sprintf("Vehicle %d", index)
+}
+
+// SetEnabled enables the vehicle on the next save, or removes it from the world map
+func (t *Transportation) SetEnabled(enabled bool) {
+	t.Enabled = enabled
+	t.ForcedEnabled = enabled
+	t.ForcedDisabled = !enabled
+}
+
+// active reports whether the vehicle will be on the world map
+func (t *Transportation) active() bool {
+	return !t.ForcedDisabled && (t.Enabled || t.ForcedEnabled)
+}
+
+// Validate checks that an enabled vehicle is parked on a world map.
+// Disabled vehicles are not checked.
+func (t *Transportation) Validate() error {
+	if !t.active() {
+		return nil
+	}
+	if t.MapID != WorldOfBalanceMapID && t.MapID != WorldOfRuinMapID {
+		name := "unknown map"
+		if t.MapID > 0 && t.MapID < len(consts.Maps) && consts.Maps[t.MapID] != nil {
+			name = consts.Maps[t.MapID].Name
+		}
+		return fmt.Errorf("vehicle %d is on map %d (%s); vehicles can only be placed on the world maps", t.ID, t.MapID, name)
+	}
+	return nil
+}
+
+// CheckPosition reports an enabled vehicle whose position lies outside the
+// assumed WorldMapSize. The bound is unverified, so this is a warning only.
+func (t *Transportation) CheckPosition() error {
+	if !t.active() {
+		return nil
+	}
+	if t.Position.X < 0 || t.Position.X >= WorldMapSize || t.Position.Y < 0 || t.Position.Y >= WorldMapSize {
+		return fmt.Errorf("vehicle %d position (%.1f, %.1f) is outside the assumed %dx%d world map", t.ID, t.Position.X, t.Position.Y, WorldMapSize, WorldMapSize)
+	}
+	return nil
+}
+
+// ValidateTransportations runs Validate and CheckPosition on every vehicle
+// record in list and returns the first problem
+func ValidateTransportations(list []*Transportation) error {
+	for i, t := range list {
+		if t == nil {
+			continue
+		}
+		err := t.Validate()
+		if err == nil {
+			err = t.CheckPosition()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", TransportationName(i), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package pr
+
+import (
+	"testing"
+)
+
+// TestTransportationValidate tests vehicle placement validation
+func TestTransportationValidate(t *testing.T) {
+	falcon := &Transportation{ID: 5, Enabled: true, MapID: WorldOfRuinMapID, Position: V3{X: 120, Y: 80, Z: 1}}
+	if err := falcon.Validate(); err != nil {
+		t.Errorf("valid placement rejected: %v", err)
+	}
+
+	falcon.MapID = 4
+	if err := falcon.Validate(); err == nil {
+		t.Error("vehicles in towns should be rejected")
+	}
+	falcon.MapID = WorldOfBalanceMapID
+	falcon.Position.Y = WorldMapSize
+	if err := falcon.Validate(); err != nil {
+		t.Errorf("the map size is unverified and should not be refused: %v", err)
+	}
+	if err := falcon.CheckPosition(); err == nil {
+		t.Error("positions outside the assumed world map should be reported")
+	}
+	if err := ValidateTransportations(nil); err != nil {
+		t.Errorf("no vehicles loaded: %v", err)
+	}
+
+	falcon.SetEnabled(false)
+	if err := falcon.CheckPosition(); err != nil || !falcon.ForcedDisabled {
+		t.Errorf("disabled vehicles should not be checked: %v", err)
+	}
+
+	if err := ValidateTransportations([]*Transportation{nil, {MapID: 9, ForcedEnabled: true}}); err == nil {
+		t.Error("ValidateTransportations should report the bad vehicle")
+	}
+}
+
+// TestTimerStart tests starting and stopping countdowns
+func TestTimerStart(t *testing.T) {
+	timer := &Timer{Present: true}
+	if err := timer.Start(2, 0); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !timer.Active || timer.Remaining != 360 || timer.Name() != "Floating Continent escape" {
+		t.Errorf("timer = %+v (%s), want Floating Continent with the default 360s", timer, timer.Name())
+	}
+	if err := timer.Start(99, 0); err == nil {
+		t.Error("unknown timers need explicit seconds")
+	}
+	if err := timer.Start(2, -1); err == nil {
+		t.Error("negative seconds should be rejected")
+	}
+	timer.Stop()
+	if timer.Active || timer.Remaining != 0 {
+		t.Errorf("timer still running after Stop: %+v", timer)
+	}
+}
@@ -0,0 +1,163 @@
+package pr
+
+import (
+	"errors"
+	"fmt"
+
+	"ffvi_editor/models/consts"
+)
+
+// DataStorageTreasure is the dataStorage array holding treasure flags, one
+// bit per treasure ID and TreasureFlagBits treasures per slot. The array name
+// and bit layout are provisional, like the treasure table itself.
+const (
+	DataStorageTreasure = "treasure"
+	TreasureFlagBits    = 32
+)
+
+// TreasureState is a treasure joined with its opened flag from the save
+type TreasureState struct {
+	consts.Treasure
+	MapName string
+	Opened  bool
+	// Provisional is set when the treasure's entry has not been verified
+	Provisional bool
+}
+
+// TreasureFilter selects treasures. Zero values match everything.
+type TreasureFilter struct {
+	MapID    uint16
+	World    int
+	Missable bool
+	Unopened bool
+}
+
+func (f TreasureFilter) match(t consts.Treasure) bool {
+	return (f.MapID == 0 || t.MapID == f.MapID) &&
+		(f.World == 0 || t.World == f.World) &&
+		(!f.Missable || t.Missable)
+}
+
+func treasureSlot(id int) (int, int) {
+	return id / TreasureFlagBits, 1 << (id % TreasureFlagBits)
+}
+
+// TreasureOpened reports whether a treasure's flag is set
+func (d *DataStorage) TreasureOpened(id int) (bool, error) {
+	index, mask := treasureSlot(id)
+	v, err := d.Get(DataStorageTreasure, index)
+	if err != nil {
+		return false, err
+	}
+	return v&mask != 0, nil
+}
+
+// Treasures returns the catalogued treasures matching the filter with their
+// opened state. Treasures whose flag slot the save lacks are skipped.
+func (d *DataStorage) Treasures(filter TreasureFilter) []TreasureState {
+	var list []TreasureState
+	for _, t := range consts.Treasures {
+		if !filter.match(t) {
+			continue
+		}
+		opened, err := d.TreasureOpened(t.ID)
+		if err != nil || (filter.Unopened && opened) {
+			continue
+		}
+		list = append(list, TreasureState{Treasure: t, MapName: t.MapName(), Opened: opened, Provisional: !t.Verified})
+	}
+	return list
+}
+
+// ErrTreasureUnverified is returned by CheckTreasureVerified for treasures
+// whose table entry has not been checked against saves from the game
+var ErrTreasureUnverified = errors.New("treasure entry is not verified")
+
+// CheckTreasureVerified returns ErrTreasureUnverified unless id is a
+// catalogued treasure whose entry is verified. Plugins and scripts may only
+// write verified treasures; the CLI writes the rest with --force.
+func CheckTreasureVerified(id int) error {
+	for _, t := range consts.Treasures {
+		if t.ID == id {
+			if !t.Verified {
+				return fmt.Errorf("treasure %d: %w", id, ErrTreasureUnverified)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown treasure %d", id)
+}
+
+// UnverifiedTreasures returns the listed IDs, or the IDs of the treasures
+// matching filter when ids is empty, whose entries are not verified
+func UnverifiedTreasures(filter TreasureFilter, ids []int) []int {
+	var list []int
+	if len(ids) > 0 {
+		for _, id := range ids {
+			if errors.Is(CheckTreasureVerified(id), ErrTreasureUnverified) {
+				list = append(list, id)
+			}
+		}
+		return list
+	}
+	for _, t := range consts.Treasures {
+		if filter.match(t) && !t.Verified {
+			list = append(list, t.ID)
+		}
+	}
+	return list
+}
+
+// SetTreasureOpened sets or clears a treasure's flag in the state's
+// dataStorage. It returns whether the flag changed and keeps the state's
+// Cheats.OpenedChestCount in step with it.
+func (s *State) SetTreasureOpened(id int, opened bool) (bool, error) {
+	d := s.dataStorage
+	if d == nil {
+		return false, fmt.Errorf("save has no %s flags in dataStorage", DataStorageTreasure)
+	}
+	index, mask := treasureSlot(id)
+	v, err := d.Get(DataStorageTreasure, index)
+	if err != nil {
+		return false, err
+	}
+	if (v&mask != 0) == opened {
+		return false, nil
+	}
+
+	if opened {
+		v |= mask
+	} else {
+		v &^= mask
+	}
+	if err := d.Set(DataStorageTreasure, index, v); err != nil {
+		return false, err
+	}
+	if c := s.cheats; c != nil {
+		if opened {
+			c.OpenedChestCount++
+		} else if c.OpenedChestCount > 0 {
+			c.OpenedChestCount--
+		}
+	}
+	return true, nil
+}
+
+// SetTreasuresOpened opens or resets every catalogued treasure matching the
+// filter and returns how many flags changed
+func (s *State) SetTreasuresOpened(filter TreasureFilter, opened bool) (int, error) {
+	if s.dataStorage == nil {
+		return 0, fmt.Errorf("save has no %s flags in dataStorage", DataStorageTreasure)
+	}
+	changed := 0
+	for _, t := range s.dataStorage.Treasures(filter) {
+		ok, err := s.SetTreasureOpened(t.ID, opened)
+		if err != nil {
+			return changed, fmt.Errorf("treasure %d: %w", t.ID, err)
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
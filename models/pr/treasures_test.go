@@ -0,0 +1,112 @@
+package pr
+
+import (
+	"errors"
+	"testing"
+
+	"ffvi_editor/models/consts"
+)
+
+// TestSetTreasureOpened tests treasure flags and the opened chest count
+func TestSetTreasureOpened(t *testing.T) {
+	d := &DataStorage{Arrays: map[string][]int{DataStorageTreasure: make([]int, 2)}}
+	c := &Cheats{OpenedChestCount: 10}
+	s := &State{dataStorage: d, cheats: c}
+	GetCheats().OpenedChestCount = 0
+
+	changed, err := s.SetTreasureOpened(33, true)
+	if err != nil || !changed {
+		t.Fatalf("SetTreasureOpened(33) = %v, %v", changed, err)
+	}
+	if d.Arrays[DataStorageTreasure][1] != 1<<1 {
+		t.Errorf("treasure[1] = %#x, want bit 1 set", d.Arrays[DataStorageTreasure][1])
+	}
+	if changed, _ := s.SetTreasureOpened(33, true); changed {
+		t.Error("opening an opened treasure should not change anything")
+	}
+	if c.OpenedChestCount != 11 {
+		t.Errorf("OpenedChestCount = %d, want 11", c.OpenedChestCount)
+	}
+	if GetCheats().OpenedChestCount != 0 {
+		t.Error("the current save's opened chest count should be untouched")
+	}
+
+	s.SetTreasureOpened(33, false)
+	if opened, _ := d.TreasureOpened(33); opened || c.OpenedChestCount != 10 {
+		t.Errorf("after reset opened = %v, count = %d", opened, c.OpenedChestCount)
+	}
+
+	if _, err := s.SetTreasureOpened(64, true); err == nil {
+		t.Error("IDs beyond the flag array should fail")
+	}
+}
+
+// TestSetTreasuresOpened tests bulk open and reset by world and map
+func TestSetTreasuresOpened(t *testing.T) {
+	d := &DataStorage{Arrays: map[string][]int{DataStorageTreasure: make([]int, 2)}}
+	c := &Cheats{}
+	s := &State{dataStorage: d, cheats: c}
+
+	wob := 0
+	for _, tr := range consts.Treasures {
+		if tr.World == 1 {
+			wob++
+		}
+	}
+	changed, err := s.SetTreasuresOpened(TreasureFilter{World: 1}, true)
+	if err != nil || changed != wob {
+		t.Fatalf("SetTreasuresOpened(WoB) = %d, %v, want %d", changed, err, wob)
+	}
+	if c.OpenedChestCount != wob {
+		t.Errorf("OpenedChestCount = %d, want %d", c.OpenedChestCount, wob)
+	}
+	if left := d.Treasures(TreasureFilter{World: 1, Unopened: true}); len(left) != 0 {
+		t.Errorf("%d WoB treasures still unopened", len(left))
+	}
+	if ruin := d.Treasures(TreasureFilter{World: 2, Unopened: true}); len(ruin) == 0 {
+		t.Error("WoR treasures should be untouched")
+	}
+
+	first := consts.Treasures[0]
+	changed, _ = s.SetTreasuresOpened(TreasureFilter{MapID: first.MapID}, false)
+	if changed != len(consts.TreasuresByMap(first.MapID)) {
+		t.Errorf("reset map %d changed %d treasures", first.MapID, changed)
+	}
+
+	for _, tr := range d.Treasures(TreasureFilter{Missable: true}) {
+		if !tr.Missable || tr.MapName == "" {
+			t.Errorf("unexpected treasure in missable view: %+v", tr)
+		}
+		if tr.Provisional == tr.Verified {
+			t.Errorf("treasure %d: Provisional should be the opposite of Verified", tr.ID)
+		}
+	}
+}
+
+// TestUnverifiedTreasures tests that provisional treasures are reported and
+// rejected
+func TestUnverifiedTreasures(t *testing.T) {
+	first := consts.Treasures[0]
+	if first.Verified {
+		t.Skip("the first treasure is verified")
+	}
+	if err := CheckTreasureVerified(first.ID); !errors.Is(err, ErrTreasureUnverified) {
+		t.Errorf("CheckTreasureVerified(%d) = %v, want ErrTreasureUnverified", first.ID, err)
+	}
+	if err := CheckTreasureVerified(-1); err == nil || errors.Is(err, ErrTreasureUnverified) {
+		t.Errorf("CheckTreasureVerified(-1) = %v, want an unknown treasure error", err)
+	}
+
+	if ids := UnverifiedTreasures(TreasureFilter{}, []int{first.ID, -1}); len(ids) != 1 || ids[0] != first.ID {
+		t.Errorf("UnverifiedTreasures(ids) = %v, want [%d]", ids, first.ID)
+	}
+	want := 0
+	for _, tr := range consts.TreasuresByMap(first.MapID) {
+		if !tr.Verified {
+			want++
+		}
+	}
+	if ids := UnverifiedTreasures(TreasureFilter{MapID: first.MapID}, nil); len(ids) != want {
+		t.Errorf("UnverifiedTreasures(map %d) = %v, want %d IDs", first.MapID, ids, want)
+	}
+}
@@ -11,6 +11,20 @@
 //   - Characters
 //   - Enemies
 //
+// Structured queries select rows from the loaded save instead of matching
+// names. A query names a source (characters, items, keyitems, espers,
+// spells), an optional where clause with =, !=, <, <=, >, >=, ~ (contains),
+// and/or/not and source functions such as knows("Ultima"), then optional
+// order by and limit clauses:
+//
+//	characters where level < 30 and knows("Ultima")
+//	items where count < 5 and category = relic
+//	espers not owned
+//
+// Query.RunFiles runs the same query over several saves, tagging each row
+// with the file it came from. ParseTableQuery runs the same language over
+// a caller-supplied Table, which is how io/catalog searches its index.
+//
 // Example usage:
 //
 //	// Create search index
@@ -0,0 +1,308 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Query is a parsed structured query over one source of save data
+type Query struct {
+	Text       string
+	Source     string
+	OrderBy    string
+	Descending bool
+	Limit      int // 0 for no limit
+
+	src   *querySource
+	where queryExpr
+}
+
+// QueryResult holds the rows a query matched. Columns lists the source's
+// fields in display order, with "save" first when the query ran over files.
+type QueryResult struct {
+	Source  string
+	Columns []string
+	Rows    []QueryRow
+	// Skipped holds one error per file RunFiles could not load
+	Skipped []error
+}
+
+// QueryRow is one matched record
+type QueryRow struct {
+	Save   string // file the row came from, empty for the loaded save
+	Values map[string]interface{}
+	Data   interface{} // the TableRow's Data for queries over a Table
+}
+
+// Records returns the rows as plain maps, including the save column when set
+func (r *QueryResult) Records() []map[string]interface{} {
+	out := make([]map[string]interface{}, len(r.Rows))
+	for i, row := range r.Rows {
+		m := make(map[string]interface{}, len(row.Values)+1)
+		for k, v := range row.Values {
+			m[k] = v
+		}
+		if row.Save != "" {
+			m["save"] = row.Save
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// IsQuery reports whether text starts with a query source, which is how
+// search boxes tell a structured query from a plain name search
+func IsQuery(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	_, ok := lookupSource(fields[0])
+	return ok
+}
+
+// Run evaluates the query against the loaded save
+func (q *Query) Run() (*QueryResult, error) {
+	rows, err := q.filter(q.src.records(), "")
+	if err != nil {
+		return nil, err
+	}
+	return q.finish(rows, false), nil
+}
+
+// RunFiles evaluates the query against each save in turn. load must make
+// the save at path the loaded one; rows are tagged with the file's base name.
+// Files that fail to load are skipped and reported in the result.
+func (q *Query) RunFiles(paths []string, load func(path string) error) (*QueryResult, error) {
+	var rows []QueryRow
+	var skipped []error
+	for _, path := range paths {
+		if err := load(path); err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		matched, err := q.filter(q.src.records(), filepath.Base(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rows = append(rows, matched...)
+	}
+	res := q.finish(rows, true)
+	res.Skipped = skipped
+	return res, nil
+}
+
+func (q *Query) filter(records []*queryRecord, save string) ([]QueryRow, error) {
+	var rows []QueryRow
+	for _, r := range records {
+		if q.where != nil {
+			v, err := q.where.eval(r)
+			if err != nil {
+				return nil, err
+			}
+			ok, isBool := v.(bool)
+			if !isBool {
+				return nil, fmt.Errorf("query: where clause is %s, not true or false", describeValue(v))
+			}
+			if !ok {
+				continue
+			}
+		}
+		row := QueryRow{Save: save, Values: r.fields}
+		if tr, ok := r.extra.(*TableRow); ok {
+			row.Data = tr.Data
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (q *Query) finish(rows []QueryRow, withSave bool) *QueryResult {
+	if q.OrderBy != "" {
+		sort.SliceStable(rows, func(i, j int) bool {
+			c := compareValues(rows[i].Values[q.OrderBy], rows[j].Values[q.OrderBy])
+			if q.Descending {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	if q.Limit > 0 && len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+	}
+	columns := q.src.fields
+	if withSave {
+		columns = append([]string{"save"}, columns...)
+	}
+	return &QueryResult{Source: q.Source, Columns: columns, Rows: rows}
+}
+
+// compareValues orders values of the same type; mixed types order by type
+func compareValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case int:
+		if y, ok := b.(int); ok {
+			return x - y
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case y:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b))
+}
+
+func describeValue(v interface{}) string {
+	switch v.(type) {
+	case int:
+		return fmt.Sprintf("the number %v", v)
+	case string:
+		return fmt.Sprintf("the text %q", v)
+	case bool:
+		return fmt.Sprintf("%v", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// queryExpr is a compiled where clause node
+type queryExpr interface {
+	eval(r *queryRecord) (interface{}, error)
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e *literalExpr) eval(*queryRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+type fieldExpr struct {
+	name string
+}
+
+func (e *fieldExpr) eval(r *queryRecord) (interface{}, error) {
+	return r.fields[e.name], nil
+}
+
+type notExpr struct {
+	x queryExpr
+}
+
+func (e *notExpr) eval(r *queryRecord) (interface{}, error) {
+	v, err := e.x.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("query: not applied to %s", describeValue(v))
+	}
+	return !b, nil
+}
+
+type logicExpr struct {
+	and         bool
+	left, right queryExpr
+}
+
+func (e *logicExpr) eval(r *queryRecord) (interface{}, error) {
+	for _, side := range []queryExpr{e.left, e.right} {
+		v, err := side.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			op := "or"
+			if e.and {
+				op = "and"
+			}
+			return nil, fmt.Errorf("query: %s applied to %s", op, describeValue(v))
+		}
+		// Short-circuit: false for and, true for or decides the result
+		if b != e.and {
+			return b, nil
+		}
+	}
+	return e.and, nil
+}
+
+type compareExpr struct {
+	op          string
+	left, right queryExpr
+	pos         int
+}
+
+func (e *compareExpr) eval(r *queryRecord) (interface{}, error) {
+	a, err := e.left.eval(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := e.right.eval(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "~" {
+		as, aok := a.(string)
+		bs, bok := b.(string)
+		if !aok || !bok {
+			return nil, &QueryError{e.pos, "~ (contains) needs text on both sides"}
+		}
+		return strings.Contains(strings.ToLower(as), strings.ToLower(bs)), nil
+	}
+	if fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b) {
+		return nil, &QueryError{e.pos, fmt.Sprintf("cannot compare %s with %s", describeValue(a), describeValue(b))}
+	}
+	c := compareValues(a, b)
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, &QueryError{e.pos, fmt.Sprintf("unknown operator %q", e.op)}
+}
+
+type callExpr struct {
+	name string
+	fn   queryFunc
+	args []queryExpr
+}
+
+func (e *callExpr) eval(r *queryRecord) (interface{}, error) {
+	args := make([]interface{}, len(e.args))
+	for i, a := range e.args {
+		v, err := a.eval(r)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := e.fn(r, args)
+	if err != nil {
+		return nil, fmt.Errorf("query: %s: %w", e.name, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,351 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query grammar:
+//
+//	query   = source [ "where" ] [ expr ] [ "order" "by" field [ "asc" | "desc" ] ] [ "limit" number ]
+//	expr    = and { "or" and }
+//	and     = unary { "and" unary }
+//	unary   = "not" unary | compare
+//	compare = operand [ ( "=" | "!=" | "<" | "<=" | ">" | ">=" | "~" ) operand ]
+//	operand = number | string | "true" | "false" | field | func "(" [ operand { "," operand } ] ")" | "(" expr ")"
+//
+// On the right of a comparison a word that is not a field is read as a
+// string, so category = relic needs no quotes.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based column
+}
+
+// QueryError reports a malformed query and where in it the problem is
+type QueryError struct {
+	Column  int
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query: %s at column %d", e.Message, e.Column)
+}
+
+func lexQuery(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i + 1})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, &QueryError{i + 1, "unterminated string"}
+			}
+			tokens = append(tokens, token{tokString, s[i+1 : i+1+end], i + 1})
+			i += end + 2
+		case strings.ContainsRune("=!<>~", rune(c)):
+			raw := s[i : i+1]
+			if i+1 < len(s) && (s[i+1] == '=' || c == '<' && s[i+1] == '>') {
+				raw = s[i : i+2]
+			}
+			op := raw
+			switch raw {
+			case "==":
+				op = "="
+			case "<>":
+				op = "!="
+			case "!", "=<", "=>", "~=":
+				return nil, &QueryError{i + 1, fmt.Sprintf("unknown operator %q", raw)}
+			}
+			tokens = append(tokens, token{tokOp, op, i + 1})
+			i += len(raw)
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			start := i
+			i++
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, s[start:i], start + 1})
+		case isIdentRune(rune(c)):
+			start := i
+			for i < len(s) && (isIdentRune(rune(s[i])) || s[i] >= '0' && s[i] <= '9' || s[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, s[start:i], start + 1})
+		default:
+			return nil, &QueryError{i + 1, fmt.Sprintf("unexpected %q", c)}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(s) + 1}), nil
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+	src    *querySource
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given keyword
+func (p *queryParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) errorf(t token, format string, args ...interface{}) error {
+	return &QueryError{t.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *queryParser) expr() (queryExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicExpr{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (queryExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicExpr{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) unary() (queryExpr, error) {
+	if p.keyword("not") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{x: x}, nil
+	}
+	return p.compare()
+}
+
+func (p *queryParser) compare() (queryExpr, error) {
+	left, err := p.operand(false)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokOp {
+		p.next()
+		right, err := p.operand(true)
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpr{op: t.text, left: left, right: right, pos: t.pos}, nil
+	}
+	return left, nil
+}
+
+func (p *queryParser) operand(rhs bool) (queryExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t.text)
+		}
+		return &literalExpr{value: n}, nil
+	case tokString:
+		return &literalExpr{value: t.text}, nil
+	case tokLParen:
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokRParen {
+			return nil, p.errorf(c, "expected )")
+		}
+		return x, nil
+	case tokIdent:
+		name := strings.ToLower(t.text)
+		switch name {
+		case "true":
+			return &literalExpr{value: true}, nil
+		case "false":
+			return &literalExpr{value: false}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.call(t)
+		}
+		if p.src.hasField(name) {
+			return &fieldExpr{name: name}, nil
+		}
+		if rhs {
+			return &literalExpr{value: t.text}, nil
+		}
+		return nil, p.errorf(t, "unknown field %q for %s (fields: %s)", t.text, p.src.name, strings.Join(p.src.fields, ", "))
+	}
+	if t.kind == tokEOF {
+		return nil, p.errorf(t, "unexpected end of query")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+func (p *queryParser) call(name token) (queryExpr, error) {
+	fn, ok := p.src.funcs[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s for %s", name.text, p.src.name)
+	}
+	p.next() // (
+	call := &callExpr{name: strings.ToLower(name.text), fn: fn}
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.operand(true)
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if t := p.next(); t.kind != tokRParen {
+		return nil, p.errorf(t, "expected ) after arguments to %s", name.text)
+	}
+	return call, nil
+}
+
+// ParseQuery parses a structured query such as
+//
+//	characters where level < 30 and knows("Ultima")
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	return parseQuery(tokens, text, lookupSource, QuerySources())
+}
+
+// ParseTableQuery parses a structured query over a caller-supplied table,
+// which must be named as the query's source
+//
+//	saves where alive("Shadow") and partyavg < 25
+func ParseTableQuery(text string, t *Table) (*Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	src := t.source()
+	lookup := func(name string) (*querySource, bool) {
+		return src, strings.EqualFold(name, src.name)
+	}
+	return parseQuery(tokens, text, lookup, []string{src.name})
+}
+
+func parseQuery(tokens []token, text string, lookup func(string) (*querySource, bool), sources []string) (*Query, error) {
+	var err error
+	p := &queryParser{tokens: tokens}
+	first := p.next()
+	if first.kind != tokIdent {
+		return nil, p.errorf(first, "expected a source (%s)", strings.Join(sources, ", "))
+	}
+	src, ok := lookup(first.text)
+	if !ok {
+		return nil, p.errorf(first, "unknown source %q (%s)", first.text, strings.Join(sources, ", "))
+	}
+	p.src = src
+	q := &Query{Text: text, Source: src.name, src: src}
+
+	hasWhere := p.keyword("where")
+	if t := p.peek(); hasWhere || t.kind != tokEOF && !isKeyword(t, "order") && !isKeyword(t, "limit") {
+		if q.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("order") {
+		if !p.keyword("by") {
+			return nil, p.errorf(p.peek(), `expected "by" after "order"`)
+		}
+		t := p.next()
+		if t.kind != tokIdent || !src.hasField(strings.ToLower(t.text)) {
+			return nil, p.errorf(t, "order by needs a field of %s", src.name)
+		}
+		q.OrderBy = strings.ToLower(t.text)
+		if p.keyword("desc") {
+			q.Descending = true
+		} else {
+			p.keyword("asc")
+		}
+	}
+	if p.keyword("limit") {
+		t := p.next()
+		n, err := strconv.Atoi(t.text)
+		if t.kind != tokNumber || err != nil || n < 0 {
+			return nil, p.errorf(t, "limit needs a non-negative number")
+		}
+		q.Limit = n
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return q, nil
+}
+
+func isKeyword(t token, word string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, word)
+}
@@ -0,0 +1,301 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts/pr"
+	pri "ffvi_editor/models/pr"
+)
+
+// queryRecord is one row of a source. Fields hold copies of the save data so
+// records stay valid after another save is loaded.
+type queryRecord struct {
+	fields map[string]interface{}
+	// extra carries data for the source's functions, e.g. a character's spells
+	extra interface{}
+}
+
+type queryFunc func(r *queryRecord, args []interface{}) (interface{}, error)
+
+// querySource is a kind of save data a query can select from
+type querySource struct {
+	name    string
+	aliases []string
+	fields  []string // in display order
+	funcs   map[string]queryFunc
+	records func() []*queryRecord
+}
+
+func (s *querySource) hasField(name string) bool {
+	for _, f := range s.fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+var querySourceList = []*querySource{
+	{
+		name:    "characters",
+		aliases: []string{"character", "chars"},
+		fields: []string{"id", "name", "level", "exp", "hp", "maxhp", "mp", "maxmp",
+			"vigor", "speed", "stamina", "magic", "enabled", "spells",
+			"weapon", "shield", "armor", "helmet", "relic1", "relic2"},
+		funcs: map[string]queryFunc{
+			"knows":    characterSpellFunc(func(p int) bool { return p >= 100 }),
+			"learning": characterSpellFunc(func(p int) bool { return p > 0 && p < 100 }),
+			"has":      characterHasCommand,
+			"equips":   characterEquips,
+		},
+		records: characterRecords,
+	},
+	{
+		name:    "items",
+		aliases: []string{"item", "inventory"},
+		fields:  []string{"id", "name", "count", "category"},
+		records: itemRecords,
+	},
+	{
+		name:    "keyitems",
+		aliases: []string{"key-items", "important"},
+		fields:  []string{"id", "name"},
+		records: keyItemRecords,
+	},
+	{
+		name:    "espers",
+		aliases: []string{"esper"},
+		fields:  []string{"id", "name", "owned"},
+		records: esperRecords,
+	},
+	{
+		name:    "spells",
+		aliases: []string{"spell"},
+		fields:  []string{"id", "name", "known", "learning"},
+		records: spellRecords,
+	},
+}
+
+// QuerySources lists the sources a query can start with
+func QuerySources() []string {
+	names := make([]string, len(querySourceList))
+	for i, s := range querySourceList {
+		names[i] = s.name
+	}
+	return names
+}
+
+// QueryFields lists a source's fields in display order
+func QueryFields(source string) ([]string, bool) {
+	s, ok := lookupSource(source)
+	if !ok {
+		return nil, false
+	}
+	return s.fields, true
+}
+
+func lookupSource(name string) (*querySource, bool) {
+	name = strings.ToLower(name)
+	for _, s := range querySourceList {
+		if s.name == name {
+			return s, true
+		}
+		for _, a := range s.aliases {
+			if a == name {
+				return s, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// characterExtra holds what the character functions look at, by lower-cased name
+type characterExtra struct {
+	spells    map[string]int
+	commands  map[string]bool
+	equipment []string
+}
+
+// characterRecords lists the characters present in the save in ID order.
+// Characters the save has never loaded are still at level 0 and are left out.
+func characterRecords() []*queryRecord {
+	var characters []*models.Character
+	for _, c := range pri.Characters {
+		if c != nil && c.Level > 0 {
+			characters = append(characters, c)
+		}
+	}
+	sort.SliceStable(characters, func(i, j int) bool { return characters[i].ID < characters[j].ID })
+
+	var records []*queryRecord
+	for _, c := range characters {
+		extra := &characterExtra{spells: make(map[string]int), commands: make(map[string]bool)}
+		known := 0
+		for _, s := range c.SpellsByIndex {
+			extra.spells[strings.ToLower(s.Name)] = s.Value
+			if s.Value >= 100 {
+				known++
+			}
+		}
+		for _, cmd := range c.Commands {
+			if cmd != nil {
+				extra.commands[strings.ToLower(cmd.Name)] = true
+			}
+		}
+		eq := equipmentNames(c.Equipment)
+		for _, name := range eq {
+			extra.equipment = append(extra.equipment, strings.ToLower(name))
+		}
+		records = append(records, &queryRecord{
+			fields: map[string]interface{}{
+				"id": c.ID, "name": c.Name, "level": c.Level, "exp": c.Exp,
+				"hp": c.HP.Current, "maxhp": c.HP.Max, "mp": c.MP.Current, "maxmp": c.MP.Max,
+				"vigor": c.Vigor, "speed": c.Speed, "stamina": c.Stamina, "magic": c.Magic,
+				"enabled": c.IsEnabled, "spells": known,
+				"weapon": eq[0], "shield": eq[1], "armor": eq[2],
+				"helmet": eq[3], "relic1": eq[4], "relic2": eq[5],
+			},
+			extra: extra,
+		})
+	}
+	return records
+}
+
+func equipmentNames(e models.Equipment) []string {
+	ids := []int{e.WeaponID, e.ShieldID, e.ArmorID, e.HelmetID, e.Relic1ID, e.Relic2ID}
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = pr.ItemsByID[id]
+		if names[i] == "" {
+			names[i] = fmt.Sprintf("#%d", id)
+		}
+	}
+	return names
+}
+
+// stringArg checks a function was given exactly one text argument
+func stringArg(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("takes 1 argument, got %d", len(args))
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("argument must be a name, got %s", describeValue(args[0]))
+	}
+	return strings.ToLower(s), nil
+}
+
+func characterSpellFunc(test func(progress int) bool) queryFunc {
+	return func(r *queryRecord, args []interface{}) (interface{}, error) {
+		name, err := stringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		progress, ok := r.extra.(*characterExtra).spells[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown spell %q", args[0])
+		}
+		return test(progress), nil
+	}
+}
+
+func characterHasCommand(r *queryRecord, args []interface{}) (interface{}, error) {
+	name, err := stringArg(args)
+	if err != nil {
+		return nil, err
+	}
+	return r.extra.(*characterExtra).commands[name], nil
+}
+
+func characterEquips(r *queryRecord, args []interface{}) (interface{}, error) {
+	name, err := stringArg(args)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range r.extra.(*characterExtra).equipment {
+		if e == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// itemRecords lists the held items, one row per item ID
+func itemRecords() []*queryRecord {
+	counts := make(map[int]int)
+	var order []int
+	for _, row := range pri.GetInventory().GetRows() {
+		if row == nil || row.ItemID == 0 || row.Count <= 0 {
+			continue
+		}
+		if _, seen := counts[row.ItemID]; !seen {
+			order = append(order, row.ItemID)
+		}
+		counts[row.ItemID] += row.Count
+	}
+	records := make([]*queryRecord, len(order))
+	for i, id := range order {
+		records[i] = &queryRecord{fields: map[string]interface{}{
+			"id": id, "name": pr.ItemsByID[id], "count": counts[id], "category": pr.ItemCategoryByID[id],
+		}}
+	}
+	return records
+}
+
+func keyItemRecords() []*queryRecord {
+	var records []*queryRecord
+	for _, row := range pri.GetImportantInventory().GetRows() {
+		if row == nil || row.ItemID == 0 || row.Count <= 0 {
+			continue
+		}
+		records = append(records, &queryRecord{fields: map[string]interface{}{
+			"id": row.ItemID, "name": pr.ImportantItemsByID[row.ItemID],
+		}})
+	}
+	return records
+}
+
+func esperRecords() []*queryRecord {
+	records := make([]*queryRecord, len(pr.Espers))
+	for i, e := range pr.Espers {
+		records[i] = &queryRecord{fields: map[string]interface{}{
+			"id": e.Value, "name": e.Name, "owned": e.Checked,
+		}}
+	}
+	return records
+}
+
+// spellRecords counts how many of the characters in the save know or are
+// learning each spell
+func spellRecords() []*queryRecord {
+	known := make(map[int]int)
+	learning := make(map[int]int)
+	for _, c := range pri.Characters {
+		if c == nil || c.Level == 0 {
+			continue
+		}
+		for id, s := range c.SpellsByID {
+			switch {
+			case s.Value >= 100:
+				known[id]++
+			case s.Value > 0:
+				learning[id]++
+			}
+		}
+	}
+	ids := make([]int, 0, len(pr.SpellLookupByID))
+	for id := range pr.SpellLookupByID {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	records := make([]*queryRecord, len(ids))
+	for i, id := range ids {
+		records[i] = &queryRecord{fields: map[string]interface{}{
+			"id": id, "name": pr.SpellLookupByID[id].Name, "known": known[id], "learning": learning[id],
+		}}
+	}
+	return records
+}
@@ -0,0 +1,55 @@
+package search
+
+import "strings"
+
+// Table is a caller-supplied query source, for data that does not come from
+// the loaded save, such as a catalogue of many saves
+type Table struct {
+	Name   string
+	Fields []string // in display order
+	Funcs  map[string]TableFunc
+	Rows   func() []*TableRow
+}
+
+// TableRow is one row of a Table. Values may hold ints, strings and bools;
+// Data is passed through to the table's functions.
+type TableRow struct {
+	Values map[string]interface{}
+	Data   interface{}
+}
+
+// TableFunc is a function a query over a Table can call
+type TableFunc func(row *TableRow, args []interface{}) (interface{}, error)
+
+func (t *Table) source() *querySource {
+	funcs := make(map[string]queryFunc, len(t.Funcs))
+	for name, fn := range t.Funcs {
+		fn := fn
+		funcs[strings.ToLower(name)] = func(r *queryRecord, args []interface{}) (interface{}, error) {
+			return fn(r.extra.(*TableRow), args)
+		}
+	}
+	fields := make([]string, len(t.Fields))
+	for i, f := range t.Fields {
+		fields[i] = strings.ToLower(f)
+	}
+	return &querySource{
+		name:   strings.ToLower(t.Name),
+		fields: fields,
+		funcs:  funcs,
+		records: func() []*queryRecord {
+			rows := t.Rows()
+			records := make([]*queryRecord, len(rows))
+			for i, row := range rows {
+				records[i] = &queryRecord{fields: row.Values, extra: row}
+			}
+			return records
+		},
+	}
+}
+
+// StringArg checks a TableFunc was given exactly one text argument and
+// returns it lower-cased
+func StringArg(args []interface{}) (string, error) {
+	return stringArg(args)
+}
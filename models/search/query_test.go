@@ -0,0 +1,173 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"ffvi_editor/models/consts/pr"
+	pri "ffvi_editor/models/pr"
+)
+
+// setupQuerySave loads a small save into the model singletons: Terra at
+// level 25 knowing Ultima, Locke at 40, a few items and one esper
+func setupQuerySave(t *testing.T) {
+	t.Helper()
+	for _, c := range pri.Characters {
+		c.Level = 0
+		for _, s := range c.SpellsByID {
+			s.Value = 0
+		}
+	}
+	terra, locke := pri.GetCharacter("Terra"), pri.GetCharacter("Locke")
+	terra.Level, terra.Vigor = 25, 31
+	for _, s := range terra.SpellsByIndex {
+		if s.Name == "Ultima" {
+			s.Value = 100
+		}
+	}
+	locke.Level = 40
+	terra.Equipment.Relic1ID = pr.ItemsByName["Hero Ring"]
+
+	inv := pri.GetInventory()
+	inv.Reset()
+	inv.Set(0, pri.Row{ItemID: pr.ItemsByName["Potion"], Count: 50})
+	inv.Set(1, pri.Row{ItemID: pr.ItemsByName["Hero Ring"], Count: 2})
+	inv.Set(2, pri.Row{ItemID: pr.ItemsByName["Ribbon"], Count: 9})
+
+	for _, e := range pr.Espers {
+		e.Checked = e.Name == "Ramuh"
+	}
+	t.Cleanup(func() {
+		terra.Level, locke.Level = 0, 0
+		inv.Reset()
+		for _, e := range pr.Espers {
+			e.Checked = false
+		}
+	})
+}
+
+func queryNames(t *testing.T, text string) []string {
+	t.Helper()
+	q, err := ParseQuery(text)
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+	res, err := q.Run()
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+	names := []string{}
+	for _, row := range res.Rows {
+		names = append(names, row.Values["name"].(string))
+	}
+	return names
+}
+
+// TestQueryExamples tests the queries from the feature request
+func TestQueryExamples(t *testing.T) {
+	setupQuerySave(t)
+	for text, want := range map[string][]string{
+		`characters where level < 30 and knows("Ultima")`:    {"Terra"},
+		`characters where level < 30 and not knows(Ultima)`:  {},
+		`characters order by level desc`:                     {"Locke", "Terra"},
+		`characters where equips("hero ring") or level = 40`: {"Terra", "Locke"},
+		`items where count < 5 and category = relic`:         {"Hero Ring"},
+		`items where name ~ "rib" or count >= 50`:            {"Potion", "Ribbon"},
+		`items order by count limit 1`:                       {"Hero Ring"},
+		`espers owned`:                                       {"Ramuh"},
+		`spells where known > 0 and name = ultima`:           {"Ultima"},
+	} {
+		if got := queryNames(t, text); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want %v", text, got, want)
+		}
+	}
+	if got := queryNames(t, "espers not owned"); len(got) != len(pr.Espers)-1 {
+		t.Errorf("espers not owned matched %d, want %d", len(got), len(pr.Espers)-1)
+	}
+}
+
+// TestParseQueryErrors tests malformed queries report a column
+func TestParseQueryErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"monsters",
+		"espers where",
+		"characters where levl < 30",
+		"characters where level <",
+		"characters where (level < 30",
+		`characters where knows("Ultima"`,
+		"characters where teleports(1)",
+		`characters where name = "Terra`,
+		"items order count",
+		"items limit many",
+		"items where count ! 5",
+	} {
+		_, err := ParseQuery(text)
+		var qe *QueryError
+		if !errors.As(err, &qe) || qe.Column < 1 {
+			t.Errorf("%q: got %v, want a QueryError", text, err)
+		}
+	}
+}
+
+// TestQueryTypeErrors tests mismatched comparisons fail instead of matching nothing
+func TestQueryTypeErrors(t *testing.T) {
+	setupQuerySave(t)
+	for _, text := range []string{
+		"characters where level = Terra",
+		"characters where level",
+		`characters where knows("Not A Spell")`,
+		"items where count ~ 5",
+	} {
+		q, err := ParseQuery(text)
+		if err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		if _, err = q.Run(); err == nil {
+			t.Errorf("%s should fail to evaluate", text)
+		}
+	}
+}
+
+// TestQueryRunFiles tests a query over several saves tags rows by file
+func TestQueryRunFiles(t *testing.T) {
+	setupQuerySave(t)
+	q, err := ParseQuery("characters where level >= 25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func(path string) error {
+		pri.GetCharacter("Locke").Level = map[string]int{"a.json": 40, "b.json": 10}[path]
+		return nil
+	}
+	res, err := q.RunFiles([]string{"dir/a.json", "dir/b.json"}, func(p string) error { return load(p[4:]) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range res.Records() {
+		got = append(got, r["save"].(string)+":"+r["name"].(string))
+	}
+	want := []string{"a.json:Terra", "a.json:Locke", "b.json:Terra"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %v, want %v", got, want)
+	}
+	if res.Columns[0] != "save" {
+		t.Errorf("columns %v should start with save", res.Columns)
+	}
+}
+
+// TestIsQuery tests structured queries are told apart from name searches
+func TestIsQuery(t *testing.T) {
+	for text, want := range map[string]bool{
+		"characters where level < 30": true,
+		"Espers not owned":            true,
+		"fire":                        false,
+		"":                            false,
+	} {
+		if got := IsQuery(text); got != want {
+			t.Errorf("IsQuery(%q) = %v", text, got)
+		}
+	}
+}
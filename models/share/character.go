@@ -0,0 +1,128 @@
+package share
+
+import (
+	"fmt"
+	"sort"
+
+	"ffvi_editor/models"
+	"ffvi_editor/models/consts/pr"
+)
+
+// BuildFromCharacter captures a character's build. Only spells with some
+// learning progress are listed; the rest are implied to be unlearned.
+func BuildFromCharacter(c *models.Character) *CharacterBuild {
+	b := &CharacterBuild{
+		CharacterID: c.ID,
+		Name:        c.Name,
+		Level:       c.Level,
+		Exp:         c.Exp,
+		HP:          Pool{Current: c.HP.Current, Max: c.HP.Max},
+		MP:          Pool{Current: c.MP.Current, Max: c.MP.Max},
+		Stats: CharacterStats{
+			Vigor:    c.Vigor,
+			Speed:    c.Speed,
+			Stamina:  c.Stamina,
+			MagicPwr: c.Magic,
+		},
+		Equipment: CharacterEquipment{
+			Weapon: c.Equipment.WeaponID,
+			Shield: c.Equipment.ShieldID,
+			Armor:  c.Equipment.ArmorID,
+			Helmet: c.Equipment.HelmetID,
+			Relic1: c.Equipment.Relic1ID,
+			Relic2: c.Equipment.Relic2ID,
+		},
+	}
+	for id, s := range c.SpellsByID {
+		if s.Value > 0 {
+			b.Spells = append(b.Spells, SpellProgress{ID: id, Progress: s.Value})
+		}
+	}
+	sort.Slice(b.Spells, func(i, j int) bool { return b.Spells[i].ID < b.Spells[j].ID })
+	for _, cmd := range c.Commands {
+		if cmd != nil {
+			b.Commands = append(b.Commands, cmd.Value)
+		}
+	}
+	return b
+}
+
+// Validate checks the build against the game's ranges and ID tables
+func (b *CharacterBuild) Validate() error {
+	switch {
+	case b.Level < 1 || b.Level > 99:
+		return fmt.Errorf("level %d is outside 1-99", b.Level)
+	case b.HP.Max > 9999 || b.HP.Current > b.HP.Max:
+		return fmt.Errorf("HP %d/%d is invalid", b.HP.Current, b.HP.Max)
+	case b.MP.Max > 999 || b.MP.Current > b.MP.Max:
+		return fmt.Errorf("MP %d/%d is invalid", b.MP.Current, b.MP.Max)
+	}
+	for name, v := range map[string]int{
+		"vigor": b.Stats.Vigor, "speed": b.Stats.Speed,
+		"stamina": b.Stats.Stamina, "magic": b.Stats.MagicPwr,
+	} {
+		if v > 255 {
+			return fmt.Errorf("%s %d is above 255", name, v)
+		}
+	}
+	for _, s := range b.Spells {
+		if int64(s.ID) < pr.SpellFrom || int64(s.ID) > pr.SpellTo {
+			return fmt.Errorf("unknown spell ID %d", s.ID)
+		}
+		if s.Progress > 100 {
+			return fmt.Errorf("spell %d progress %d is above 100", s.ID, s.Progress)
+		}
+	}
+	if len(b.Commands) > 9 {
+		return fmt.Errorf("%d commands, a character has at most 9", len(b.Commands))
+	}
+	for _, id := range b.Commands {
+		if _, ok := pr.CommandLookupByValue[id]; !ok {
+			return fmt.Errorf("unknown command ID %d", id)
+		}
+	}
+	return nil
+}
+
+// Apply writes the build onto a character, keeping the character's identity
+// (ID and name). Spells missing from the build are unlearned.
+func (b *CharacterBuild) Apply(c *models.Character) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	c.Level = b.Level
+	c.Exp = b.Exp
+	c.HP = models.CurrentMax{Current: b.HP.Current, Max: b.HP.Max}
+	c.MP = models.CurrentMax{Current: b.MP.Current, Max: b.MP.Max}
+	c.Vigor = b.Stats.Vigor
+	c.Speed = b.Stats.Speed
+	c.Stamina = b.Stats.Stamina
+	c.Magic = b.Stats.MagicPwr
+	c.Equipment = models.Equipment{
+		WeaponID: b.Equipment.Weapon,
+		ShieldID: b.Equipment.Shield,
+		ArmorID:  b.Equipment.Armor,
+		HelmetID: b.Equipment.Helmet,
+		Relic1ID: b.Equipment.Relic1,
+		Relic2ID: b.Equipment.Relic2,
+	}
+
+	for _, s := range c.SpellsByID {
+		s.Value = 0
+	}
+	for _, s := range b.Spells {
+		if spell, ok := c.SpellsByID[s.ID]; ok {
+			spell.Value = s.Progress
+		}
+	}
+
+	if len(b.Commands) > 0 {
+		commands := make([]*models.Command, len(b.Commands))
+		for i, id := range b.Commands {
+			commands[i] = pr.CommandLookupByValue[id]
+		}
+		c.Commands = commands
+		c.EnableCommandsSave = true
+	}
+	return nil
+}
@@ -0,0 +1,502 @@
+package share
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// FormatVersion is the version byte written at the start of every payload.
+// Decoders reject payloads from newer versions rather than guess at them.
+const FormatVersion = 1
+
+// Payload kinds, the byte after the version
+const (
+	kindCharacter byte = 1
+	kindParty     byte = 2
+)
+
+// MaxPartySize is the most characters a party code holds
+const MaxPartySize = 4
+
+// codePrefix starts every textual share code
+const codePrefix = "FF6"
+
+var (
+	// ErrChecksum is returned when a code's CRC does not match its payload,
+	// usually a typo or a truncated copy
+	ErrChecksum = errors.New("share code checksum mismatch")
+	// ErrVersion is returned for payloads written by a newer format version
+	ErrVersion = errors.New("unsupported share code version")
+)
+
+// Encoding is the text alphabet a binary payload is rendered in
+type Encoding int
+
+const (
+	// EncodingBase58 is the shortest form, for pasting into chat and forums
+	EncodingBase58 Encoding = iota
+	// EncodingBase32 is lower-case Crockford base32, safe in URLs and file names
+	EncodingBase32
+	// EncodingQR is upper-case Crockford base32. Every character is in the QR
+	// alphanumeric set, so QR codes can use the denser alphanumeric mode.
+	EncodingQR
+)
+
+// String returns the tag used for the encoding in a code
+func (e Encoding) String() string {
+	switch e {
+	case EncodingBase58:
+		return "B58"
+	case EncodingBase32:
+		return "B32"
+	case EncodingQR:
+		return "QR"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// ParseEncoding parses an encoding tag or name, case-insensitively
+func ParseEncoding(s string) (Encoding, error) {
+	switch strings.ToLower(s) {
+	case "b58", "base58":
+		return EncodingBase58, nil
+	case "b32", "base32":
+		return EncodingBase32, nil
+	case "qr":
+		return EncodingQR, nil
+	}
+	return 0, fmt.Errorf("unknown encoding %q (valid: base58, base32, qr)", s)
+}
+
+// EncodeText renders a payload as a code: FF6-<tag>-<data>
+func EncodeText(payload []byte, enc Encoding) (string, error) {
+	var data string
+	switch enc {
+	case EncodingBase58:
+		data = base58Encode(payload)
+	case EncodingBase32:
+		data = base32Encode(payload, base32Lower)
+	case EncodingQR:
+		data = base32Encode(payload, base32Upper)
+	default:
+		return "", fmt.Errorf("unknown encoding %d", int(enc))
+	}
+	return codePrefix + "-" + enc.String() + "-" + data, nil
+}
+
+// DecodeText parses a code written by EncodeText and returns its payload.
+// Surrounding whitespace is ignored, as is the case of base32 data.
+func DecodeText(code string) ([]byte, Encoding, error) {
+	parts := strings.SplitN(strings.TrimSpace(code), "-", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[0], codePrefix) {
+		return nil, 0, fmt.Errorf("not a share code: expected %s-<encoding>-<data>", codePrefix)
+	}
+	enc, err := ParseEncoding(parts[1])
+	if err != nil {
+		return nil, 0, err
+	}
+	var payload []byte
+	if enc == EncodingBase58 {
+		payload, err = base58Decode(parts[2])
+	} else {
+		payload, err = base32Decode(parts[2])
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return payload, enc, nil
+}
+
+// MarshalCharacterBuild packs a build into a versioned, checksummed payload
+func MarshalCharacterBuild(build *CharacterBuild) ([]byte, error) {
+	w := newPayloadWriter(kindCharacter)
+	if err := w.character(build); err != nil {
+		return nil, err
+	}
+	return w.finish(), nil
+}
+
+// UnmarshalCharacterBuild reads a payload written by MarshalCharacterBuild
+func UnmarshalCharacterBuild(payload []byte) (*CharacterBuild, error) {
+	r, err := newPayloadReader(payload, kindCharacter)
+	if err != nil {
+		return nil, err
+	}
+	build, err := r.character()
+	if err != nil {
+		return nil, err
+	}
+	return build, r.end()
+}
+
+// MarshalPartyBuild packs up to MaxPartySize character builds into a payload
+func MarshalPartyBuild(party *PartyBuild) ([]byte, error) {
+	if len(party.Characters) > MaxPartySize {
+		return nil, fmt.Errorf("party has %d characters, at most %d can be shared", len(party.Characters), MaxPartySize)
+	}
+	w := newPayloadWriter(kindParty)
+	w.uvarint(uint64(len(party.Characters)))
+	for i := range party.Characters {
+		if err := w.character(&party.Characters[i]); err != nil {
+			return nil, fmt.Errorf("party member %d: %w", i+1, err)
+		}
+	}
+	return w.finish(), nil
+}
+
+// UnmarshalPartyBuild reads a payload written by MarshalPartyBuild
+func UnmarshalPartyBuild(payload []byte) (*PartyBuild, error) {
+	r, err := newPayloadReader(payload, kindParty)
+	if err != nil {
+		return nil, err
+	}
+	n, err := r.count(MaxPartySize)
+	if err != nil {
+		return nil, err
+	}
+	party := &PartyBuild{Characters: make([]CharacterBuild, n)}
+	for i := range party.Characters {
+		c, err := r.character()
+		if err != nil {
+			return nil, fmt.Errorf("party member %d: %w", i+1, err)
+		}
+		party.Characters[i] = *c
+	}
+	return party, r.end()
+}
+
+// payloadWriter builds version | kind | body | CRC32 (big-endian)
+type payloadWriter struct {
+	buf []byte
+}
+
+func newPayloadWriter(kind byte) *payloadWriter {
+	return &payloadWriter{buf: []byte{FormatVersion, kind}}
+}
+
+func (w *payloadWriter) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+// int writes a non-negative value; negative values have no meaning in a build
+func (w *payloadWriter) int(field string, v int) error {
+	if v < 0 {
+		return fmt.Errorf("%s is negative (%d)", field, v)
+	}
+	w.uvarint(uint64(v))
+	return nil
+}
+
+func (w *payloadWriter) character(b *CharacterBuild) error {
+	w.uvarint(uint64(len(b.Name)))
+	w.buf = append(w.buf, b.Name...)
+	for _, f := range []struct {
+		name  string
+		value int
+	}{
+		{"character ID", b.CharacterID},
+		{"level", b.Level},
+		{"experience", b.Exp},
+		{"current HP", b.HP.Current},
+		{"max HP", b.HP.Max},
+		{"current MP", b.MP.Current},
+		{"max MP", b.MP.Max},
+		{"vigor", b.Stats.Vigor},
+		{"speed", b.Stats.Speed},
+		{"stamina", b.Stats.Stamina},
+		{"magic", b.Stats.MagicPwr},
+		{"weapon", b.Equipment.Weapon},
+		{"shield", b.Equipment.Shield},
+		{"armor", b.Equipment.Armor},
+		{"helmet", b.Equipment.Helmet},
+		{"relic 1", b.Equipment.Relic1},
+		{"relic 2", b.Equipment.Relic2},
+	} {
+		if err := w.int(f.name, f.value); err != nil {
+			return err
+		}
+	}
+
+	w.uvarint(uint64(len(b.Spells)))
+	for _, s := range b.Spells {
+		if err := w.int("spell ID", s.ID); err != nil {
+			return err
+		}
+		if err := w.int("spell progress", s.Progress); err != nil {
+			return err
+		}
+	}
+	w.uvarint(uint64(len(b.Commands)))
+	for _, id := range b.Commands {
+		if err := w.int("command ID", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *payloadWriter) finish() []byte {
+	return binary.BigEndian.AppendUint32(w.buf, crc32.ChecksumIEEE(w.buf))
+}
+
+// payloadReader reads a body after checking the version, kind and CRC
+type payloadReader struct {
+	buf []byte
+	pos int
+}
+
+func newPayloadReader(payload []byte, kind byte) (*payloadReader, error) {
+	if len(payload) < 6 {
+		return nil, fmt.Errorf("share code is too short")
+	}
+	body := payload[:len(payload)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(payload[len(body):]) {
+		return nil, ErrChecksum
+	}
+	if body[0] == 0 || body[0] > FormatVersion {
+		return nil, fmt.Errorf("%w %d (this editor reads up to %d)", ErrVersion, body[0], FormatVersion)
+	}
+	if body[1] != kind {
+		return nil, fmt.Errorf("share code holds a %s, not a %s", kindName(body[1]), kindName(kind))
+	}
+	return &payloadReader{buf: body, pos: 2}, nil
+}
+
+func kindName(kind byte) string {
+	switch kind {
+	case kindCharacter:
+		return "character build"
+	case kindParty:
+		return "party build"
+	}
+	return fmt.Sprintf("build of unknown kind %d", kind)
+}
+
+func (r *payloadReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("malformed share code at byte %d", r.pos)
+	}
+	r.pos += n
+	return v, nil
+}
+
+// int reads a value that must fit an int32, the range of any save field
+func (r *payloadReader) int() (int, error) {
+	v, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if v > 1<<31-1 {
+		return 0, fmt.Errorf("value %d at byte %d is out of range", v, r.pos)
+	}
+	return int(v), nil
+}
+
+// count reads a list length, bounded so a corrupt code cannot allocate much
+func (r *payloadReader) count(max int) (int, error) {
+	n, err := r.int()
+	if err != nil {
+		return 0, err
+	}
+	if n > max {
+		return 0, fmt.Errorf("list of %d entries exceeds the limit of %d", n, max)
+	}
+	return n, nil
+}
+
+// Bounds on list lengths, well above anything in the game
+const (
+	maxNameLength = 64
+	maxSpells     = 256
+	maxCommands   = 16
+)
+
+func (r *payloadReader) character() (*CharacterBuild, error) {
+	n, err := r.count(maxNameLength)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos+n > len(r.buf) {
+		return nil, fmt.Errorf("share code is truncated")
+	}
+	b := &CharacterBuild{Name: string(r.buf[r.pos : r.pos+n])}
+	r.pos += n
+
+	for _, p := range []*int{
+		&b.CharacterID, &b.Level, &b.Exp,
+		&b.HP.Current, &b.HP.Max, &b.MP.Current, &b.MP.Max,
+		&b.Stats.Vigor, &b.Stats.Speed, &b.Stats.Stamina, &b.Stats.MagicPwr,
+		&b.Equipment.Weapon, &b.Equipment.Shield, &b.Equipment.Armor,
+		&b.Equipment.Helmet, &b.Equipment.Relic1, &b.Equipment.Relic2,
+	} {
+		if *p, err = r.int(); err != nil {
+			return nil, err
+		}
+	}
+
+	if n, err = r.count(maxSpells); err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		b.Spells = make([]SpellProgress, n)
+	}
+	for i := range b.Spells {
+		if b.Spells[i].ID, err = r.int(); err != nil {
+			return nil, err
+		}
+		if b.Spells[i].Progress, err = r.int(); err != nil {
+			return nil, err
+		}
+	}
+
+	if n, err = r.count(maxCommands); err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		b.Commands = make([]int, n)
+	}
+	for i := range b.Commands {
+		if b.Commands[i], err = r.int(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (r *payloadReader) end() error {
+	if r.pos != len(r.buf) {
+		return fmt.Errorf("share code has %d unexpected trailing bytes", len(r.buf)-r.pos)
+	}
+	return nil
+}
+
+// Crockford base32 leaves out I, L, O and U so codes read back unambiguously
+const (
+	base32Lower = "0123456789abcdefghjkmnpqrstvwxyz"
+	base32Upper = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// base32Encode packs 5 bits per character, zero-padding the last one
+func base32Encode(data []byte, alphabet string) string {
+	var sb strings.Builder
+	var acc uint32
+	bits := 0
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			sb.WriteByte(alphabet[acc>>uint(bits)&0x1F])
+		}
+	}
+	if bits > 0 {
+		sb.WriteByte(alphabet[acc<<uint(5-bits)&0x1F])
+	}
+	return sb.String()
+}
+
+// base32Decode reads either case and Crockford's look-alikes (I and L for 1,
+// O for 0). Hyphens are ignored so long codes can be grouped.
+func base32Decode(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s)*5/8)
+	var acc uint32
+	bits := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '-' {
+			continue
+		}
+		v := base32Value(c)
+		if v < 0 {
+			return nil, fmt.Errorf("invalid base32 character %q", c)
+		}
+		acc = acc<<5 | uint32(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>uint(bits)))
+		}
+	}
+	return out, nil
+}
+
+func base32Value(c byte) int {
+	switch {
+	case c >= 'a' && c <= 'z':
+		c -= 'a' - 'A'
+	}
+	switch c {
+	case 'I', 'L':
+		return 1
+	case 'O':
+		return 0
+	}
+	return strings.IndexByte(base32Upper, c)
+}
+
+// base58Alphabet is the Bitcoin alphabet, without 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Encode treats data as a big-endian number; each leading zero byte
+// becomes a leading '1' so the length survives the round trip
+func base58Encode(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	// Little-endian base58 digits
+	var digits []byte
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+	out := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = base58Alphabet[0]
+	}
+	for i, d := range digits {
+		out[len(out)-1-i] = base58Alphabet[d]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	// Little-endian bytes
+	var bytes []byte
+	for i := zeros; i < len(s); i++ {
+		carry := strings.IndexByte(base58Alphabet, s[i])
+		if carry < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", s[i])
+		}
+		for j := range bytes {
+			carry += int(bytes[j]) * 58
+			bytes[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			bytes = append(bytes, byte(carry))
+			carry >>= 8
+		}
+	}
+	out := make([]byte, zeros+len(bytes))
+	for i, b := range bytes {
+		out[len(out)-1-i] = b
+	}
+	return out, nil
+}
@@ -8,19 +8,28 @@
 // Share codes allow users to share:
 //   - Character builds
 //   - Party presets
-//   - Configuration settings
+//
+// Equipped espers are not part of a build; the save model does not track
+// them.
+//
+// A code is FF6-<encoding>-<data>. The data is a binary payload of a format
+// version byte, a kind byte, the build as unsigned varints (game IDs for
+// items, spells and commands, stats stored in full) and a CRC32, so
+// decoding gives back exactly the build that was encoded. The payload is
+// written in base58 (shortest), lower-case base32 (URL safe) or upper-case
+// base32, which stays within the QR alphanumeric character set.
 //
 // Example usage:
 //
-//	// Generate share code
-//	code, err := share.GenerateCode(data)
+//	g := share.NewCodeGenerator()
+//	code, err := g.GenerateCharacterCode(share.BuildFromCharacter(c), 0)
 //	if err != nil {
 //	    return err
 //	}
 //
-//	// Parse share code
-//	data, err := share.ParseCode(code)
+//	build, err := g.DecodeCharacterCode(code)
 //	if err != nil {
 //	    return err
 //	}
+//	err = build.Apply(other)
 package share
@@ -0,0 +1,179 @@
+package share
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	pri "ffvi_editor/models/pr"
+)
+
+func testBuild() *CharacterBuild {
+	return &CharacterBuild{
+		CharacterID: 1,
+		Name:        "Terra",
+		Level:       99,
+		Exp:         2637112,
+		HP:          Pool{Current: 8123, Max: 9999},
+		MP:          Pool{Current: 0, Max: 999},
+		Stats:       CharacterStats{Vigor: 128, Speed: 77, Stamina: 255, MagicPwr: 99},
+		Equipment:   CharacterEquipment{Weapon: 30, Shield: 94, Armor: 160, Helmet: 130, Relic1: 213, Relic2: 200},
+		Spells:      []SpellProgress{{ID: 31, Progress: 100}, {ID: 55, Progress: 42}, {ID: 84, Progress: 1}},
+		Commands:    []int{1, 2, 30, 4},
+	}
+}
+
+// TestCharacterBuildRoundTrip tests decode(encode(x)) == x in every alphabet
+func TestCharacterBuildRoundTrip(t *testing.T) {
+	builds := []*CharacterBuild{
+		testBuild(),
+		{Name: "", Level: 1},
+		{Name: "ティナ", Level: 12, HP: Pool{Current: 1, Max: 1}},
+	}
+	for _, enc := range []Encoding{EncodingBase58, EncodingBase32, EncodingQR} {
+		g := NewCodeGenerator()
+		g.SetEncoding(enc)
+		for _, want := range builds {
+			code, err := g.GenerateCharacterCode(want, 0)
+			if err != nil {
+				t.Fatalf("%s: %v", enc, err)
+			}
+			got, err := g.DecodeCharacterCode(code)
+			if err != nil {
+				t.Fatalf("%s: decoding %s: %v", enc, code, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: round trip of %q\n got %+v\nwant %+v", enc, want.Name, got, want)
+			}
+		}
+	}
+}
+
+// TestPartyBuildRoundTrip tests party codes keep every member's build
+func TestPartyBuildRoundTrip(t *testing.T) {
+	second := testBuild()
+	second.Name, second.CharacterID = "Locke", 2
+	want := &PartyBuild{Characters: []CharacterBuild{*testBuild(), *second}}
+
+	g := NewCodeGenerator()
+	code, err := g.GeneratePartyCode(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsPartyCode(code) {
+		t.Errorf("%s should be recognised as a party code", code)
+	}
+	got, err := g.DecodePartyCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("party round trip\n got %+v\nwant %+v", got, want)
+	}
+	if _, err = g.DecodeCharacterCode(code); err == nil {
+		t.Error("a party code should not decode as a character build")
+	}
+}
+
+// TestQRAlphabet tests QR codes only use the QR alphanumeric character set
+// and decode regardless of case
+func TestQRAlphabet(t *testing.T) {
+	g := NewCodeGenerator()
+	g.SetEncoding(EncodingQR)
+	code, err := g.GenerateCharacterCode(testBuild(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const qrAlphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+	for _, r := range code {
+		if !strings.ContainsRune(qrAlphanumeric, r) {
+			t.Fatalf("%s contains %q, outside the QR alphanumeric set", code, r)
+		}
+	}
+	if _, err = g.DecodeCharacterCode(strings.ToLower(code)); err != nil {
+		t.Errorf("lower-cased QR code: %v", err)
+	}
+}
+
+// TestDecodeRejectsDamage tests typos, truncation and future versions are
+// reported instead of yielding a different build
+func TestDecodeRejectsDamage(t *testing.T) {
+	payload, err := MarshalCharacterBuild(testBuild())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte(nil), payload...)
+	flipped[5] ^= 0x01
+	if _, err = UnmarshalCharacterBuild(flipped); !errors.Is(err, ErrChecksum) {
+		t.Errorf("flipped bit: got %v, want ErrChecksum", err)
+	}
+	if _, err = UnmarshalCharacterBuild(payload[:len(payload)-3]); err == nil {
+		t.Error("truncated payload should fail")
+	}
+
+	// A newer version with a valid CRC
+	w := newPayloadWriter(kindCharacter)
+	w.buf[0] = FormatVersion + 1
+	if _, err = UnmarshalCharacterBuild(w.finish()); !errors.Is(err, ErrVersion) {
+		t.Errorf("future version: got %v, want ErrVersion", err)
+	}
+
+	for _, code := range []string{"", "TERR-BLD-0123", "FF6-XYZ-abc", "FF6-B58-0OIl"} {
+		if _, err = NewCodeGenerator().DecodeCharacterCode(code); err == nil {
+			t.Errorf("%q should not decode", code)
+		}
+	}
+}
+
+// TestGenerateCharacterCodeMaxLength tests long codes fail rather than truncate
+func TestGenerateCharacterCodeMaxLength(t *testing.T) {
+	if _, err := NewCodeGenerator().GenerateCharacterCode(testBuild(), 20); err == nil {
+		t.Error("a code over the length limit should be an error")
+	}
+	b := testBuild()
+	b.Level = -1
+	if _, err := NewCodeGenerator().GenerateCharacterCode(b, 0); err == nil {
+		t.Error("negative fields should not encode")
+	}
+}
+
+// TestBaseEncodings tests the alphabets round-trip leading zeros and
+// arbitrary lengths
+func TestBaseEncodings(t *testing.T) {
+	for _, data := range [][]byte{{}, {0}, {0, 0, 1}, {0xFF}, []byte("share codes"), {1, 0, 0, 0, 0, 0, 0, 255}} {
+		if got, err := base58Decode(base58Encode(data)); err != nil || string(got) != string(data) {
+			t.Errorf("base58 %v: got %v, %v", data, got, err)
+		}
+		if got, err := base32Decode(base32Encode(data, base32Lower)); err != nil || string(got) != string(data) {
+			t.Errorf("base32 %v: got %v, %v", data, got, err)
+		}
+	}
+}
+
+// TestApplyBuild tests a build taken from one character recreates it on another
+func TestApplyBuild(t *testing.T) {
+	want := testBuild()
+	target := pri.Characters[1]
+	id, name := target.ID, target.Name
+	target.SpellsByID[40].Value = 100 // not in the build, so unlearned by Apply
+
+	if err := want.Apply(target); err != nil {
+		t.Fatal(err)
+	}
+	if target.ID != id || target.Name != name {
+		t.Error("Apply should keep the target's identity")
+	}
+	got := BuildFromCharacter(target)
+	got.CharacterID, got.Name = want.CharacterID, want.Name
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applied build\n got %+v\nwant %+v", got, want)
+	}
+
+	bad := testBuild()
+	bad.Commands = []int{9999}
+	if err := bad.Apply(target); err == nil {
+		t.Error("unknown command IDs should be rejected")
+	}
+}
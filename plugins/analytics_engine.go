@@ -50,6 +50,7 @@ type AnalyticsEngine struct {
 	pluginStats     map[string]*PluginStats      // Per-plugin statistics
 	windows         []*AnalyticsWindow           // Time window snapshots
 	maxEventsStored int                          // Maximum events to keep
+	store           *EventStore                  // optional; see SetEventStore
 	mu              sync.RWMutex
 }
 
@@ -88,6 +89,13 @@ func (a *AnalyticsEngine) RecordEvent(event *AnalyticsEvent) {
 
 	// Add to main events list
 	a.events = append(a.events, event)
+	if a.store != nil {
+		a.store.Append(StoredEvent{
+			Time: event.Timestamp, Source: EventSourceAnalytics, Type: event.EventType,
+			PluginID: event.PluginID, Status: event.Status, Duration: event.Duration,
+			Error: event.Error, Data: event.Metadata,
+		})
+	}
 
 	// Limit events storage
 	if len(a.events) > a.maxEventsStored {
@@ -110,6 +118,15 @@ func (a *AnalyticsEngine) RecordEvent(event *AnalyticsEvent) {
 	a.updatePluginStats(event)
 }
 
+// SetEventStore makes the engine also append every event to store, where
+// EventStore.Query and EventStore.Rollups reach them across restarts; nil
+// stops it
+func (a *AnalyticsEngine) SetEventStore(store *EventStore) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.store = store
+}
+
 // updatePluginStats updates statistics for a plugin
 func (a *AnalyticsEngine) updatePluginStats(event *AnalyticsEvent) {
 	stats, exists := a.pluginStats[event.PluginID]
@@ -5,6 +5,7 @@ import (
 	ioPR "ffvi_editor/io/pr"
 	"ffvi_editor/models"
 	modelsPR "ffvi_editor/models/pr"
+	"ffvi_editor/models/search"
 )
 
 // PluginAPI provides safe access to save editor functionality for plugins
@@ -25,10 +26,17 @@ type PluginAPI interface {
 	// Query Operations
 	FindCharacter(ctx context.Context, predicate func(*models.Character) bool) *models.Character
 	FindItems(ctx context.Context, predicate func(*modelsPR.Row) bool) []*modelsPR.Row
+	Query(ctx context.Context, query string) (*search.QueryResult, error)
+
+	// Treasure Tracking
+	GetTreasures(ctx context.Context, filter modelsPR.TreasureFilter) ([]modelsPR.TreasureState, error)
+	SetTreasureOpened(ctx context.Context, id int, opened bool) error
 
 	// Events
 	RegisterHook(event string, callback func(interface{}) error) error
 	FireEvent(ctx context.Context, event string, data interface{}) error
+	Subscribe(ctx context.Context, pattern string, replay bool, handler EventHandler) (*Subscription, error)
+	Publish(ctx context.Context, topic string, payload interface{}) error
 
 	// UI
 	ShowDialog(ctx context.Context, title, message string) error
@@ -42,18 +50,26 @@ type PluginAPI interface {
 	GetSetting(key string) interface{}
 	SetSetting(key string, value interface{}) error
 
+	// Storage
+	Storage(ctx context.Context, perSave bool) (*KVStore, error)
+
 	// Permissions
 	HasPermission(permission string) bool
 }
 
 // APIImpl provides a default implementation of PluginAPI
 type APIImpl struct {
-	prData        *ioPR.PR
-	hooks         map[string][]func(interface{}) error
-	settings      map[string]interface{}
-	permissions   map[string]bool
-	logger        func(level, msg string)
-	showDialogFn  func(title, message string) error
-	showConfirmFn func(title, message string) bool
-	showInputFn   func(prompt string) (string, error)
+	prData         *ioPR.PR
+	hooks          map[string][]func(interface{}) error
+	settings       map[string]interface{}
+	pluginSettings settingsBackend // a plugin's own settings, when set
+	grants         *Grants
+	pluginID       string       // plugin the API was scoped to, empty for the editor
+	audit          *AuditLogger // records each capability check when set
+	storage        *StorageManager
+	bus            *EventBus
+	logger         func(level, msg string)
+	showDialogFn   func(title, message string) error
+	showConfirmFn  func(title, message string) bool
+	showInputFn    func(prompt string) (string, error)
 }
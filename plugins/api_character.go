@@ -101,8 +101,8 @@ func (a *APIImpl) findCharacterMap(name string) *jo.OrderedMap {
 
 // GetCharacter retrieves a character by name
 func (a *APIImpl) GetCharacter(ctx context.Context, name string) (*models.Character, error) {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
-		return nil, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.CharactersRead); err != nil {
+		return nil, err
 	}
 
 	if a.prData == nil {
@@ -221,8 +221,8 @@ func updateCharacterParams(paramMap *jo.OrderedMap, ch *models.Character, baseOf
 
 // SetCharacter updates a character with full data modification
 func (a *APIImpl) SetCharacter(ctx context.Context, name string, ch *models.Character) error {
-	if !a.HasPermission(CommonPermissions.WriteSave) {
-		return ErrInsufficientPermissions
+	if err := a.Require(Capabilities.CharactersWrite); err != nil {
+		return err
 	}
 
 	if a.prData == nil {
@@ -264,12 +264,13 @@ func (a *APIImpl) SetCharacter(ctx context.Context, name string, ch *models.Char
 		}
 	}
 
+	a.emit(ctx, TopicCharacterEdited, map[string]interface{}{"id": charID, "name": name, "by": a.pluginID})
 	return nil
 }
 
 // FindCharacter finds a character matching a predicate
 func (a *APIImpl) FindCharacter(ctx context.Context, predicate func(*models.Character) bool) *models.Character {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
+	if a.Require(Capabilities.CharactersRead) != nil {
 		return nil
 	}
 
@@ -5,15 +5,10 @@ import ioPR "ffvi_editor/io/pr"
 // NewAPIImpl creates a new API implementation
 func NewAPIImpl(prData *ioPR.PR, permissions []string) *APIImpl {
 	api := &APIImpl{
-		prData:      prData,
-		hooks:       make(map[string][]func(interface{}) error),
-		settings:    make(map[string]interface{}),
-		permissions: make(map[string]bool),
-	}
-
-	// Set permissions
-	for _, perm := range permissions {
-		api.permissions[perm] = true
+		prData:   prData,
+		hooks:    make(map[string][]func(interface{}) error),
+		settings: make(map[string]interface{}),
+		grants:   NewGrants(permissions),
 	}
 
 	// Set default logger
@@ -59,18 +54,84 @@ func (a *APIImpl) SetDialogFunctions(
 	}
 }
 
-// HasPermission checks if the API has a specific permission
+// ForPlugin returns an API scoped to one plugin: it shares the save,
+// hooks and UI functions but only has the plugin's declared permissions,
+// and every capability check is recorded in audit
+func (a *APIImpl) ForPlugin(pluginID string, permissions []string, audit *AuditLogger) *APIImpl {
+	scoped := *a
+	scoped.grants = NewGrants(permissions)
+	scoped.pluginID = pluginID
+	scoped.audit = audit
+	return &scoped
+}
+
+// PluginID returns the plugin the API was scoped to with ForPlugin
+func (a *APIImpl) PluginID() string {
+	return a.pluginID
+}
+
+// SaveData returns the save the API reads and writes, or nil
+func (a *APIImpl) SaveData() *ioPR.PR {
+	return a.prData
+}
+
+// HasPermission checks if the API has a specific permission or capability
 func (a *APIImpl) HasPermission(permission string) bool {
-	return a.permissions[permission]
+	return a.grants.Allows(permission)
 }
 
-// GetSetting retrieves a setting value
+// Require checks the API was granted capability, logging the use or the
+// denial, and returns ErrInsufficientPermissions when it was not
+func (a *APIImpl) Require(capability string) error {
+	if a.grants.Allows(capability) {
+		if a.audit != nil {
+			a.audit.LogPermissionUsed(a.pluginID, capability)
+		}
+		return nil
+	}
+	if a.audit != nil {
+		a.audit.LogPermissionDenied(a.pluginID, capability, "not declared in metadata.json")
+	}
+	return ErrInsufficientPermissions
+}
+
+// GetSetting retrieves a setting value. A plugin's API reads the plugin's
+// own settings, defaults included.
 func (a *APIImpl) GetSetting(key string) interface{} {
+	if a.pluginSettings != nil && a.pluginID != "" {
+		_, values, err := a.pluginSettings.PluginSettings(a.pluginID)
+		if err != nil {
+			return nil
+		}
+		return values[key]
+	}
 	return a.settings[key]
 }
 
-// SetSetting stores a setting value
+// SetSetting stores a setting value. A plugin's API checks it against the
+// plugin's settings schema and saves it.
 func (a *APIImpl) SetSetting(key string, value interface{}) error {
+	if a.pluginSettings != nil && a.pluginID != "" {
+		_, values, err := a.pluginSettings.PluginSettings(a.pluginID)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+		_, err = a.pluginSettings.UpdateSettings(a.pluginID, values)
+		return err
+	}
 	a.settings[key] = value
 	return nil
 }
+
+// settingsBackend keeps the settings of plugins; the Manager is one
+type settingsBackend interface {
+	PluginSettings(pluginID string) (SettingsSchema, map[string]interface{}, error)
+	UpdateSettings(pluginID string, settings map[string]interface{}) (map[string]interface{}, error)
+}
+
+// SetSettingsBackend sets where a plugin's API keeps its settings. The
+// Manager sets itself on every plugin's API.
+func (a *APIImpl) SetSettingsBackend(b settingsBackend) {
+	a.pluginSettings = b
+}
@@ -9,8 +9,8 @@ import (
 
 // GetEquipment retrieves equipment data
 func (a *APIImpl) GetEquipment(ctx context.Context) (*models.Equipment, error) {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
-		return nil, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.EquipmentRead); err != nil {
+		return nil, err
 	}
 
 	// Get first character's equipment as representative
@@ -45,8 +45,8 @@ func (a *APIImpl) GetEquipment(ctx context.Context) (*models.Equipment, error) {
 
 // SetEquipment updates equipment data
 func (a *APIImpl) SetEquipment(ctx context.Context, eq *models.Equipment) error {
-	if !a.HasPermission(CommonPermissions.WriteSave) {
-		return ErrInsufficientPermissions
+	if err := a.Require(Capabilities.EquipmentWrite); err != nil {
+		return err
 	}
 
 	if a.prData == nil {
@@ -91,8 +91,8 @@ func (a *APIImpl) SetEquipment(ctx context.Context, eq *models.Equipment) error
 
 // ApplyBatchOperation applies a batch operation
 func (a *APIImpl) ApplyBatchOperation(ctx context.Context, op string, params map[string]interface{}) (int, error) {
-	if !a.HasPermission(CommonPermissions.WriteSave) {
-		return 0, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.CharactersWrite); err != nil {
+		return 0, err
 	}
 
 	// TODO: Implement batch operations
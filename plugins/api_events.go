@@ -2,8 +2,21 @@ package plugins
 
 import "context"
 
-// RegisterHook registers an event hook
+// RegisterHook registers an event hook. With an event bus the hook is a
+// subscription to the event topic and receives the payload.
 func (a *APIImpl) RegisterHook(event string, callback func(interface{}) error) error {
+	if err := a.Require(Capabilities.EventsSubscribe); err != nil {
+		return err
+	}
+	if callback == nil {
+		return ErrNilCallback
+	}
+	if a.bus != nil {
+		_, err := a.bus.Subscribe(a.pluginID, event, false, func(ctx context.Context, ev *Event) error {
+			return callback(ev.Payload)
+		})
+		return err
+	}
 	if a.hooks == nil {
 		a.hooks = make(map[string][]func(interface{}) error)
 	}
@@ -13,6 +26,12 @@ func (a *APIImpl) RegisterHook(event string, callback func(interface{}) error) e
 
 // FireEvent triggers an event
 func (a *APIImpl) FireEvent(ctx context.Context, event string, data interface{}) error {
+	if err := a.Require(Capabilities.EventsPublish); err != nil {
+		return err
+	}
+	if a.bus != nil {
+		return a.bus.Publish(ctx, a.pluginID, event, data)
+	}
 	if a.hooks == nil {
 		return nil
 	}
@@ -29,3 +48,53 @@ func (a *APIImpl) FireEvent(ctx context.Context, event string, data interface{})
 	}
 	return nil
 }
+
+// Subscribe subscribes to topics matching pattern on the shared event bus,
+// optionally replaying recent matching events first
+func (a *APIImpl) Subscribe(ctx context.Context, pattern string, replay bool, handler EventHandler) (*Subscription, error) {
+	if err := a.Require(Capabilities.EventsSubscribe); err != nil {
+		return nil, err
+	}
+	if a.bus == nil {
+		return nil, ErrEventBusUnavailable
+	}
+	return a.bus.Subscribe(a.pluginID, pattern, replay, handler)
+}
+
+// Publish publishes payload on topic on the shared event bus
+func (a *APIImpl) Publish(ctx context.Context, topic string, payload interface{}) error {
+	if err := a.Require(Capabilities.EventsPublish); err != nil {
+		return err
+	}
+	if a.bus == nil {
+		return ErrEventBusUnavailable
+	}
+	return a.bus.Publish(ctx, a.pluginID, topic, payload)
+}
+
+// RecentEvents returns the bus's buffered events matching pattern
+func (a *APIImpl) RecentEvents(pattern string) ([]*Event, error) {
+	if err := a.Require(Capabilities.EventsSubscribe); err != nil {
+		return nil, err
+	}
+	if a.bus == nil {
+		return nil, ErrEventBusUnavailable
+	}
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	return a.bus.Replay(pattern), nil
+}
+
+// SetEventBus connects the API to a shared event bus. The Manager sets its
+// own on every plugin's API.
+func (a *APIImpl) SetEventBus(bus *EventBus) {
+	a.bus = bus
+}
+
+// emit publishes a built-in editor event when a bus is connected
+func (a *APIImpl) emit(ctx context.Context, topic string, payload interface{}) {
+	if a.bus != nil {
+		a.bus.Publish(ctx, EditorSource, topic, payload)
+	}
+}
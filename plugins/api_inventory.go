@@ -8,8 +8,8 @@ import (
 
 // GetInventory retrieves the current inventory
 func (a *APIImpl) GetInventory(ctx context.Context) (*modelsPR.Inventory, error) {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
-		return nil, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.InventoryRead); err != nil {
+		return nil, err
 	}
 
 	inv := modelsPR.GetInventory()
@@ -22,8 +22,8 @@ func (a *APIImpl) GetInventory(ctx context.Context) (*modelsPR.Inventory, error)
 
 // SetInventory updates the inventory
 func (a *APIImpl) SetInventory(ctx context.Context, inv *modelsPR.Inventory) error {
-	if !a.HasPermission(CommonPermissions.WriteSave) {
-		return ErrInsufficientPermissions
+	if err := a.Require(Capabilities.InventoryWrite); err != nil {
+		return err
 	}
 
 	if a.prData == nil || a.prData.UserData == nil {
@@ -58,12 +58,13 @@ func (a *APIImpl) SetInventory(ctx context.Context, inv *modelsPR.Inventory) err
 		}
 	}
 
+	a.emit(ctx, TopicInventoryChanged, map[string]interface{}{"by": a.pluginID})
 	return nil
 }
 
 // FindItems finds inventory items matching a predicate
 func (a *APIImpl) FindItems(ctx context.Context, predicate func(*modelsPR.Row) bool) []*modelsPR.Row {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
+	if a.Require(Capabilities.InventoryRead) != nil {
 		return nil
 	}
 
@@ -8,8 +8,8 @@ import (
 
 // GetParty retrieves the current party composition
 func (a *APIImpl) GetParty(ctx context.Context) (*modelsPR.Party, error) {
-	if !a.HasPermission(CommonPermissions.ReadSave) {
-		return nil, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.PartyRead); err != nil {
+		return nil, err
 	}
 
 	party := modelsPR.GetParty()
@@ -22,8 +22,8 @@ func (a *APIImpl) GetParty(ctx context.Context) (*modelsPR.Party, error) {
 
 // SetParty updates the party composition
 func (a *APIImpl) SetParty(ctx context.Context, party *modelsPR.Party) error {
-	if !a.HasPermission(CommonPermissions.WriteSave) {
-		return ErrInsufficientPermissions
+	if err := a.Require(Capabilities.PartyWrite); err != nil {
+		return err
 	}
 
 	if a.prData == nil || a.prData.UserData == nil {
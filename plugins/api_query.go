@@ -0,0 +1,20 @@
+package plugins
+
+import (
+	"context"
+
+	"ffvi_editor/models/search"
+)
+
+// Query runs a structured query, e.g. "espers not owned", against the loaded save
+func (a *APIImpl) Query(ctx context.Context, query string) (*search.QueryResult, error) {
+	q, err := search.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	// The capability depends on what the query reads
+	if err := a.Require(QueryCapability(q.Source)); err != nil {
+		return nil, err
+	}
+	return q.Run()
+}
@@ -0,0 +1,29 @@
+package plugins
+
+import "context"
+
+// Storage opens the plugin's durable key-value store. With perSave the
+// store belongs to the save file named by StorageManager.SetSaveScope.
+func (a *APIImpl) Storage(ctx context.Context, perSave bool) (*KVStore, error) {
+	if a.storage == nil || a.pluginID == "" {
+		return nil, ErrStorageUnavailable
+	}
+	scope := ""
+	if perSave {
+		if scope = a.storage.SaveScope(); scope == "" {
+			return nil, ErrNoSaveScope
+		}
+	}
+	store, err := a.storage.Open(a.pluginID, scope)
+	if err != nil {
+		return nil, err
+	}
+	store.require = a.Require
+	return store, nil
+}
+
+// SetStorageManager sets where Storage opens stores. The Manager sets its
+// own on every plugin's API.
+func (a *APIImpl) SetStorageManager(sm *StorageManager) {
+	a.storage = sm
+}
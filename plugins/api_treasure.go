@@ -0,0 +1,30 @@
+package plugins
+
+import (
+	"context"
+
+	modelsPR "ffvi_editor/models/pr"
+)
+
+// GetTreasures returns the catalogued treasures matching filter with their opened state
+func (a *APIImpl) GetTreasures(ctx context.Context, filter modelsPR.TreasureFilter) ([]modelsPR.TreasureState, error) {
+	if err := a.Require(Capabilities.TreasuresRead); err != nil {
+		return nil, err
+	}
+
+	return modelsPR.GetDataStorage().Treasures(filter), nil
+}
+
+// SetTreasureOpened opens or resets a treasure, keeping the opened chest count
+// consistent. Treasures whose entries are unverified are rejected.
+func (a *APIImpl) SetTreasureOpened(ctx context.Context, id int, opened bool) error {
+	if err := a.Require(Capabilities.TreasuresWrite); err != nil {
+		return err
+	}
+
+	if err := modelsPR.CheckTreasureVerified(id); err != nil {
+		return err
+	}
+	_, err := modelsPR.CurrentState().SetTreasureOpened(id, opened)
+	return err
+}
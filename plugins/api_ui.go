@@ -4,24 +4,24 @@ import "context"
 
 // ShowDialog shows a dialog message
 func (a *APIImpl) ShowDialog(ctx context.Context, title, message string) error {
-	if !a.HasPermission(CommonPermissions.UIDisplay) {
-		return ErrInsufficientPermissions
+	if err := a.Require(Capabilities.UIDialog); err != nil {
+		return err
 	}
 	return a.showDialogFn(title, message)
 }
 
 // ShowConfirm shows a confirmation dialog
 func (a *APIImpl) ShowConfirm(ctx context.Context, title, message string) (bool, error) {
-	if !a.HasPermission(CommonPermissions.UIDisplay) {
-		return false, ErrInsufficientPermissions
+	if err := a.Require(Capabilities.UIDialog); err != nil {
+		return false, err
 	}
 	return a.showConfirmFn(title, message), nil
 }
 
 // ShowInput shows an input dialog
 func (a *APIImpl) ShowInput(ctx context.Context, prompt string) (string, error) {
-	if !a.HasPermission(CommonPermissions.UIDisplay) {
-		return "", ErrInsufficientPermissions
+	if err := a.Require(Capabilities.UIDialog); err != nil {
+		return "", err
 	}
 	return a.showInputFn(prompt)
 }
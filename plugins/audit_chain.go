@@ -0,0 +1,605 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// auditChainDirName holds the hash-chained audit log under the plugin
+	// directory, hidden like storageDirName
+	auditChainDirName  = ".audit"
+	auditChainFileName = "audit.jsonl"
+	auditKeyFileName   = "audit-key.pem"
+	auditKeysDirName   = "keys"
+
+	// DefaultCheckpointInterval is the number of audit records between
+	// signed checkpoints
+	DefaultCheckpointInterval = 100
+
+	// DefaultAuditSigner is the signer ID of checkpoints signed by the
+	// editor's own key
+	DefaultAuditSigner = "editor"
+)
+
+// Kinds of audit chain problems found by VerifyAuditChain
+const (
+	AuditIssueEdited     = "edited"
+	AuditIssueDeleted    = "deleted"
+	AuditIssueReordered  = "reordered"
+	AuditIssueCheckpoint = "bad_checkpoint"
+	AuditIssueMalformed  = "malformed"
+)
+
+// AuditRecord is one line of the audit chain: an audit event or a signed
+// checkpoint. Hash covers the sequence number, the previous record's hash
+// and the payload, so changing, removing or moving any record breaks the
+// links after it.
+type AuditRecord struct {
+	Seq        uint64          `json:"seq"`
+	PrevHash   string          `json:"prev"`
+	Hash       string          `json:"hash"`
+	Event      json.RawMessage `json:"event,omitempty"`
+	Checkpoint json.RawMessage `json:"checkpoint,omitempty"`
+}
+
+// AuditCheckpoint is a signature over the chain up to the record before it
+type AuditCheckpoint struct {
+	SignerID  string    `json:"signer"`
+	Algorithm string    `json:"algorithm"`
+	SignedAt  time.Time `json:"signed_at"`
+	Signature string    `json:"signature"`
+}
+
+// auditChainEvent is the payload of an event record
+type auditChainEvent struct {
+	EventID      string                 `json:"event_id"`
+	PluginID     string                 `json:"plugin"`
+	EventType    string                 `json:"type"`
+	Action       string                 `json:"action"`
+	PermissionID string                 `json:"permission,omitempty"`
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	Timestamp    time.Time              `json:"time"`
+	Duration     int64                  `json:"duration_us,omitempty"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+	SaveHash     string                 `json:"save,omitempty"`
+}
+
+// AuditChain appends audit events to a hash-chained log file and seals it
+// with a checkpoint signed by a SecurityManager every interval records
+type AuditChain struct {
+	path     string
+	file     *os.File
+	security *SecurityManager
+	signerID string
+	interval int
+	seq      uint64
+	head     string
+	pending  int // records since the last checkpoint
+	closed   bool
+	mu       sync.Mutex
+}
+
+// OpenAuditChain opens the chain at path, creating it if needed, and
+// continues it from its last record. Checkpoints are signed by sm as
+// signerID; interval <= 0 means DefaultCheckpointInterval.
+func OpenAuditChain(path string, sm *SecurityManager, signerID string, interval int) (*AuditChain, error) {
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit chain: %w", err)
+	}
+	c := &AuditChain{path: path, security: sm, signerID: signerID, interval: interval}
+
+	// Drop a line left unfinished by a crash, so verification does not
+	// report it as tampering, then find where the chain continues
+	end, err := c.trimTornLine()
+	if err != nil {
+		return nil, err
+	}
+	if err := readAuditRecords(path, func(line int, rec *AuditRecord, err error) {
+		if err != nil {
+			return
+		}
+		c.seq, c.head = rec.Seq, rec.Hash
+		if rec.Checkpoint != nil {
+			c.pending = 0
+		} else {
+			c.pending++
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit chain: %w", err)
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open audit chain: %w", err)
+	}
+	c.file = f
+	return c, nil
+}
+
+// trimTornLine truncates the file after its last complete line and returns
+// the new size
+func (c *AuditChain) trimTornLine() (int64, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	end := int64(bytes.LastIndexByte(data, '\n') + 1)
+	if end < int64(len(data)) {
+		if err := os.Truncate(c.path, end); err != nil {
+			return 0, fmt.Errorf("failed to repair audit chain: %w", err)
+		}
+	}
+	return end, nil
+}
+
+// Path returns the chain's file
+func (c *AuditChain) Path() string {
+	return c.path
+}
+
+// Head returns the sequence number and hash of the last record
+func (c *AuditChain) Head() (uint64, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq, c.head
+}
+
+// Append chains event after the last record, setting its Seq, PrevHash and
+// Hash, and writes a checkpoint when one is due
+func (c *AuditChain) Append(event *AuditLog) error {
+	payload, err := json.Marshal(&auditChainEvent{
+		EventID:      event.EventID,
+		PluginID:     event.PluginID,
+		EventType:    event.EventType,
+		Action:       event.Action,
+		PermissionID: event.PermissionID,
+		Status:       event.Status,
+		Error:        event.Error,
+		Timestamp:    event.Timestamp,
+		Duration:     event.Duration,
+		Details:      event.Details,
+		SaveHash:     event.SaveHash,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrAuditChainClosed
+	}
+	rec, err := c.write(&AuditRecord{Event: payload})
+	if err != nil {
+		return err
+	}
+	event.Seq, event.PrevHash, event.Hash = rec.Seq, rec.PrevHash, rec.Hash
+
+	c.pending++
+	if c.pending >= c.interval {
+		return c.checkpoint()
+	}
+	return nil
+}
+
+// Checkpoint signs the chain up to its last record, if any were added since
+// the last checkpoint. Without a private key it does nothing.
+func (c *AuditChain) Checkpoint() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrAuditChainClosed
+	}
+	return c.checkpoint()
+}
+
+func (c *AuditChain) checkpoint() error {
+	if c.pending == 0 || c.security == nil || !c.security.hasPrivateKey() {
+		return nil
+	}
+	cp, err := c.security.signAuditCheckpoint(c.seq+1, c.head, c.signerID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit checkpoint: %w", err)
+	}
+	if _, err := c.write(&AuditRecord{Checkpoint: payload}); err != nil {
+		return err
+	}
+	c.pending = 0
+	return c.file.Sync()
+}
+
+// write numbers, links and hashes rec and appends it to the file
+func (c *AuditChain) write(rec *AuditRecord) (*AuditRecord, error) {
+	rec.Seq = c.seq + 1
+	rec.PrevHash = c.head
+	rec.Hash = rec.computeHash()
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	c.seq, c.head = rec.Seq, rec.Hash
+	return rec, nil
+}
+
+// Close writes a final checkpoint and closes the file; further appends fail
+// with ErrAuditChainClosed
+func (c *AuditChain) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	err := c.checkpoint()
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// computeHash hashes the record's position, link and payload
+func (r *AuditRecord) computeHash() string {
+	h := sha256.New()
+	kind, payload := "event", r.Event
+	if r.Checkpoint != nil {
+		kind, payload = "checkpoint", r.Checkpoint
+	}
+	fmt.Fprintf(h, "%d\n%s\n%s\n", r.Seq, r.PrevHash, kind)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditCheckpointDigest is what a checkpoint at seq signs: the hash of the
+// record before it, and with it every record before that
+func auditCheckpointDigest(seq uint64, head, signerID string, signedAt time.Time) []byte {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("ffvi-audit-checkpoint\n%d\n%s\n%s\n%s",
+		seq, head, signerID, signedAt.UTC().Format(time.RFC3339Nano))))
+	return digest[:]
+}
+
+// hasPrivateKey reports whether the manager can sign
+func (sm *SecurityManager) hasPrivateKey() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.privateKey != nil
+}
+
+// signAuditCheckpoint signs the chain ending in head for a checkpoint at seq
+func (sm *SecurityManager) signAuditCheckpoint(seq uint64, head, signerID string) (*AuditCheckpoint, error) {
+	sm.mu.RLock()
+	key := sm.privateKey
+	sm.mu.RUnlock()
+	if key == nil {
+		return nil, fmt.Errorf("no private key available for signing")
+	}
+
+	signedAt := time.Now().UTC()
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, auditCheckpointDigest(seq, head, signerID, signedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign audit checkpoint: %w", err)
+	}
+	return &AuditCheckpoint{
+		SignerID:  signerID,
+		Algorithm: PackageSignatureAlgorithm,
+		SignedAt:  signedAt,
+		Signature: hex.EncodeToString(sig),
+	}, nil
+}
+
+// verifyAuditCheckpoint checks a checkpoint at seq against the trusted keys
+func (sm *SecurityManager) verifyAuditCheckpoint(seq uint64, head string, cp *AuditCheckpoint) error {
+	if cp.Algorithm != PackageSignatureAlgorithm {
+		return fmt.Errorf("unsupported algorithm %q", cp.Algorithm)
+	}
+	sm.mu.RLock()
+	keyPEM, trusted := sm.trustedKeys[cp.SignerID]
+	sm.mu.RUnlock()
+	if !trusted {
+		return fmt.Errorf("%w: %s", ErrUntrustedSigner, cp.SignerID)
+	}
+	pub, err := parseRSAPublicKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(cp.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature")
+	}
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, auditCheckpointDigest(seq, head, cp.SignerID, cp.SignedAt), sig); err != nil {
+		return fmt.Errorf("signature does not match the chain")
+	}
+	return nil
+}
+
+// AuditIssue is one problem found in an audit chain
+type AuditIssue struct {
+	Seq     uint64 // record the problem was found at; 0 for malformed lines
+	Line    int    // line of the file, from 1
+	Kind    string // one of the AuditIssue constants
+	Message string
+}
+
+// AuditVerifyReport is the result of VerifyAuditChain
+type AuditVerifyReport struct {
+	Events        int    // event records read
+	Checkpoints   int    // valid checkpoints
+	SignedThrough uint64 // last record covered by a valid checkpoint
+	Unsigned      int    // event records after the last valid checkpoint
+	Signers       []string
+	Issues        []AuditIssue
+}
+
+// OK reports whether the chain verified without issues
+func (r *AuditVerifyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// VerifyAuditChain reads an audit chain and reports every record that was
+// edited, deleted or moved, and every checkpoint whose signature is not
+// from a key trusted by sm. Records after the last checkpoint can be
+// removed without trace; they are counted in Unsigned.
+func VerifyAuditChain(r io.Reader, sm *SecurityManager) (*AuditVerifyReport, error) {
+	report := &AuditVerifyReport{}
+	type entry struct {
+		line int
+		rec  *AuditRecord
+	}
+	var records []entry
+	present := make(map[uint64]bool)
+	if err := scanAuditRecords(r, func(line int, rec *AuditRecord, err error) {
+		if err != nil {
+			report.Issues = append(report.Issues, AuditIssue{Line: line, Kind: AuditIssueMalformed, Message: err.Error()})
+			return
+		}
+		records = append(records, entry{line, rec})
+		present[rec.Seq] = true
+	}); err != nil {
+		return nil, err
+	}
+
+	issue := func(e entry, kind, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, AuditIssue{Seq: e.rec.Seq, Line: e.line, Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+	signers := make(map[string]bool)
+	edited := make(map[uint64]bool)
+	for i, e := range records {
+		rec := e.rec
+		if rec.computeHash() != rec.Hash {
+			edited[rec.Seq] = true
+			issue(e, AuditIssueEdited, "record %d does not match its hash", rec.Seq)
+		}
+
+		if i == 0 {
+			if rec.Seq > 1 || rec.PrevHash != "" {
+				issue(e, AuditIssueDeleted, "records before %d are missing", rec.Seq)
+			}
+		} else {
+			prev := records[i-1]
+			switch {
+			case rec.Seq <= prev.rec.Seq:
+				issue(e, AuditIssueReordered, "record %d follows record %d", rec.Seq, prev.rec.Seq)
+			case rec.Seq > prev.rec.Seq+1:
+				if missing := missingSeqs(present, prev.rec.Seq+1, rec.Seq); missing != "" {
+					issue(e, AuditIssueDeleted, "records %s are missing", missing)
+				}
+			case rec.PrevHash != prev.rec.Hash && !edited[prev.rec.Seq]:
+				// The previous record was rewritten along with its own hash
+				edited[prev.rec.Seq] = true
+				issue(prev, AuditIssueEdited, "record %d does not match the hash record %d was chained to", prev.rec.Seq, rec.Seq)
+			}
+		}
+
+		if rec.Checkpoint == nil {
+			report.Events++
+			report.Unsigned++
+			continue
+		}
+		var cp AuditCheckpoint
+		if err := json.Unmarshal(rec.Checkpoint, &cp); err != nil {
+			issue(e, AuditIssueCheckpoint, "checkpoint %d is malformed: %v", rec.Seq, err)
+			continue
+		}
+		if sm == nil {
+			issue(e, AuditIssueCheckpoint, "checkpoint %d cannot be checked without trusted keys", rec.Seq)
+			continue
+		}
+		if err := sm.verifyAuditCheckpoint(rec.Seq, rec.PrevHash, &cp); err != nil {
+			issue(e, AuditIssueCheckpoint, "checkpoint %d: %v", rec.Seq, err)
+			continue
+		}
+		report.Checkpoints++
+		report.SignedThrough = rec.Seq
+		report.Unsigned = 0
+		if !signers[cp.SignerID] {
+			signers[cp.SignerID] = true
+			report.Signers = append(report.Signers, cp.SignerID)
+		}
+	}
+	sort.Strings(report.Signers)
+	return report, nil
+}
+
+// VerifyAuditChainFile runs VerifyAuditChain on the chain at path
+func VerifyAuditChainFile(path string, sm *SecurityManager) (*AuditVerifyReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit chain: %w", err)
+	}
+	defer f.Close()
+	return VerifyAuditChain(f, sm)
+}
+
+// missingSeqs describes the sequence numbers in [from, to) not in present
+func missingSeqs(present map[uint64]bool, from, to uint64) string {
+	var first, last uint64
+	count := 0
+	for seq := from; seq < to; seq++ {
+		if present[seq] {
+			continue
+		}
+		if count == 0 {
+			first = seq
+		}
+		last = seq
+		count++
+	}
+	switch {
+	case count == 0:
+		return ""
+	case first == last:
+		return fmt.Sprintf("%d", first)
+	default:
+		return fmt.Sprintf("%d-%d", first, last)
+	}
+}
+
+// readAuditRecords calls fn for each line of the chain at path
+func readAuditRecords(path string, fn func(line int, rec *AuditRecord, err error)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	defer f.Close()
+	return scanAuditRecords(f, fn)
+}
+
+// scanAuditRecords decodes each non-empty line of r, passing fn the error
+// for lines that are not records
+func scanAuditRecords(r io.Reader, fn func(line int, rec *AuditRecord, err error)) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var rec AuditRecord
+			if jerr := json.Unmarshal(data, &rec); jerr != nil {
+				fn(line, nil, fmt.Errorf("line %d is not an audit record", line))
+			} else {
+				fn(line, &rec, nil)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit chain: %w", err)
+		}
+	}
+}
+
+// OpenAuditChain opens the audit chain under <pluginDir>/.audit and makes
+// the audit logger write to it. Checkpoints are signed with a key pair of
+// the chain's own, kept in keyDir as audit-key.pem and generated on first
+// use, never the one the security manager signs packages with. keyDir must
+// be outside the plugin directory: a key next to the chain would let
+// whoever can edit the chain re-sign it. The public key is written to
+// AuditTrustedKeysDir(keyDir) for VerifyAuditChain.
+func (m *Manager) OpenAuditChain(keyDir string) (*AuditChain, error) {
+	if keyDir == "" {
+		return nil, fmt.Errorf("audit chain needs a key directory")
+	}
+	pluginDir, err := filepath.Abs(m.pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	if keyDir, err = filepath.Abs(keyDir); err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(pluginDir, keyDir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("audit key directory %s is inside the plugin directory %s", keyDir, pluginDir)
+	}
+	if err := os.MkdirAll(AuditTrustedKeysDir(keyDir), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit key directory: %w", err)
+	}
+
+	signer := NewSecurityManager()
+	keyPath := filepath.Join(keyDir, auditKeyFileName)
+	if err := signer.LoadPrivateKey(keyPath); err != nil {
+		if _, statErr := os.Stat(keyPath); !os.IsNotExist(statErr) {
+			return nil, err
+		}
+		if err := signer.GenerateKeyPair(); err != nil {
+			return nil, err
+		}
+		if err := signer.SavePrivateKey(keyPath); err != nil {
+			return nil, err
+		}
+	}
+	pubPEM, err := signer.PublicKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(AuditTrustedKeysDir(keyDir), DefaultAuditSigner+".pem"), []byte(pubPEM)); err != nil {
+		return nil, err
+	}
+
+	chain, err := OpenAuditChain(AuditChainPath(m.pluginDir), signer, DefaultAuditSigner, 0)
+	if err != nil {
+		return nil, err
+	}
+	m.SetAuditChain(chain)
+	return chain, nil
+}
+
+// AuditChainPath returns where Manager.OpenAuditChain keeps the chain for
+// the plugins in pluginDir
+func AuditChainPath(pluginDir string) string {
+	return filepath.Join(pluginDir, auditChainDirName, auditChainFileName)
+}
+
+// AuditTrustedKeysDir returns where Manager.OpenAuditChain publishes the
+// public key of the pair kept in keyDir
+func AuditTrustedKeysDir(keyDir string) string {
+	return filepath.Join(keyDir, auditKeysDirName)
+}
+
+// SetAuditChain makes the audit logger chain every event into chain; nil
+// stops it
+func (m *Manager) SetAuditChain(chain *AuditChain) {
+	m.mu.Lock()
+	m.auditChain = chain
+	m.mu.Unlock()
+	m.auditLogger.SetAuditChain(chain)
+}
+
+// GetAuditChain returns the chain set with SetAuditChain, or nil
+func (m *Manager) GetAuditChain() *AuditChain {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.auditChain
+}
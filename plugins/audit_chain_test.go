@@ -0,0 +1,250 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newAuditTestKeys returns a manager that signs as "tester" and one that
+// trusts it
+func newAuditTestKeys(t *testing.T) (signer, verifier *SecurityManager) {
+	t.Helper()
+	signer = NewSecurityManager()
+	if err := signer.GenerateKeyPair(); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := signer.PublicKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier = NewSecurityManager()
+	if err := verifier.AddTrustedKey("tester", pub); err != nil {
+		t.Fatal(err)
+	}
+	return signer, verifier
+}
+
+// writeTestAuditChain writes events audit events, checkpointing every
+// interval, and returns the chain's lines
+func writeTestAuditChain(t *testing.T, sm *SecurityManager, events, interval int) (string, []string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	chain, err := OpenAuditChain(path, sm, "tester", interval)
+	if err != nil {
+		t.Fatalf("OpenAuditChain failed: %v", err)
+	}
+	logger := NewAuditLogger(100)
+	logger.SetAuditChain(chain)
+	for i := 0; i < events; i++ {
+		logger.LogPluginExecution("finder", int64(i), true, "")
+	}
+	if err := chain.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func verifyAuditLines(t *testing.T, lines []string, sm *SecurityManager) *AuditVerifyReport {
+	t.Helper()
+	report, err := VerifyAuditChain(strings.NewReader(strings.Join(lines, "\n")+"\n"), sm)
+	if err != nil {
+		t.Fatalf("VerifyAuditChain failed: %v", err)
+	}
+	return report
+}
+
+func auditIssueKinds(report *AuditVerifyReport) []string {
+	var kinds []string
+	for _, issue := range report.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	return kinds
+}
+
+// TestAuditChainVerify tests an untouched chain verifies and the logger's
+// events carry their chain position
+func TestAuditChainVerify(t *testing.T) {
+	signer, verifier := newAuditTestKeys(t)
+	path, lines := writeTestAuditChain(t, signer, 5, 2)
+	// 5 events, checkpoints after 2 and 4, and a final one on Close
+	if len(lines) != 8 {
+		t.Fatalf("got %d records, want 8", len(lines))
+	}
+
+	report, err := VerifyAuditChainFile(path, verifier)
+	if err != nil {
+		t.Fatalf("VerifyAuditChainFile failed: %v", err)
+	}
+	if !report.OK() || report.Events != 5 || report.Checkpoints != 3 || report.SignedThrough != 8 || report.Unsigned != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Signers) != 1 || report.Signers[0] != "tester" {
+		t.Errorf("Signers = %v", report.Signers)
+	}
+
+	// Reopening continues the chain where it ended
+	chain, err := OpenAuditChain(path, signer, "tester", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := &AuditLog{PluginID: "finder", EventType: "load"}
+	if err := chain.Append(event); err != nil {
+		t.Fatal(err)
+	}
+	chain.Close()
+	if event.Seq != 9 || event.PrevHash == "" || event.Hash == "" {
+		t.Errorf("appended event = seq %d prev %q hash %q", event.Seq, event.PrevHash, event.Hash)
+	}
+	if err := chain.Append(event); !errors.Is(err, ErrAuditChainClosed) {
+		t.Errorf("Append after Close = %v, want ErrAuditChainClosed", err)
+	}
+	if report, _ := VerifyAuditChainFile(path, verifier); !report.OK() || report.SignedThrough != 10 {
+		t.Errorf("after reopening: %+v", report)
+	}
+}
+
+// TestAuditChainTampering tests edits, deletions and reordering are found
+func TestAuditChainTampering(t *testing.T) {
+	signer, verifier := newAuditTestKeys(t)
+	_, lines := writeTestAuditChain(t, signer, 5, 2)
+
+	edit := func(i int) []string {
+		out := append([]string(nil), lines...)
+		out[i] = strings.Replace(out[i], `"status":"success"`, `"status":"error"`, 1)
+		return out
+	}
+	without := func(i int) []string {
+		return append(append([]string(nil), lines[:i]...), lines[i+1:]...)
+	}
+	swapped := append([]string(nil), lines...)
+	swapped[3], swapped[4] = swapped[4], swapped[3]
+
+	for name, tc := range map[string]struct {
+		lines []string
+		want  string
+	}{
+		"edited":         {edit(0), AuditIssueEdited},
+		"deleted":        {without(3), AuditIssueDeleted},
+		"first deleted":  {without(0), AuditIssueDeleted},
+		"reordered":      {swapped, AuditIssueReordered},
+		"malformed":      {append(append([]string(nil), lines[:2]...), append([]string{"{not json"}, lines[2:]...)...), AuditIssueMalformed},
+		"bad checkpoint": {append([]string(nil), lines...), AuditIssueCheckpoint},
+	} {
+		sm := verifier
+		if name == "bad checkpoint" {
+			sm = NewSecurityManager() // trusts nobody
+		}
+		report := verifyAuditLines(t, tc.lines, sm)
+		kinds := auditIssueKinds(report)
+		if len(kinds) == 0 || kinds[0] != tc.want {
+			t.Errorf("%s: issues = %+v, want %s first", name, report.Issues, tc.want)
+		}
+	}
+}
+
+// TestAuditChainRehashedEdit tests an edit whose hash was recomputed is
+// still found through the next record and the checkpoint
+func TestAuditChainRehashedEdit(t *testing.T) {
+	signer, verifier := newAuditTestKeys(t)
+	_, lines := writeTestAuditChain(t, signer, 3, 10)
+
+	var rec AuditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	rec.Event = bytes.Replace(rec.Event, []byte(`"status":"success"`), []byte(`"status":"error"`), 1)
+	rec.Hash = rec.computeHash()
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := append([]string(nil), lines...)
+	forged[1] = string(data)
+
+	report := verifyAuditLines(t, forged, verifier)
+	if len(report.Issues) != 1 || report.Issues[0].Kind != AuditIssueEdited || report.Issues[0].Seq != 2 {
+		t.Errorf("issues = %+v, want record 2 edited", report.Issues)
+	}
+}
+
+// TestManagerAuditChain tests the manager chains its audit events with the
+// open save's hash and signs them with a key of its own, kept and published
+// outside the plugin directory
+func TestManagerAuditChain(t *testing.T) {
+	m, pluginDir := installSettingsPlugin(t)
+	root := filepath.Dir(pluginDir)
+	if _, err := m.OpenAuditChain(filepath.Join(root, "keys")); err == nil {
+		t.Error("OpenAuditChain accepted a key directory inside the plugin directory")
+	}
+
+	keyDir := t.TempDir()
+	chain, err := m.OpenAuditChain(keyDir)
+	if err != nil {
+		t.Fatalf("OpenAuditChain failed: %v", err)
+	}
+	if m.GetSecurityManager().hasPrivateKey() {
+		t.Error("OpenAuditChain gave the shared security manager a key pair")
+	}
+
+	savePath := filepath.Join(t.TempDir(), "slot1.json")
+	if err := os.WriteFile(savePath, []byte(`{"gil":1200}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := m.CallHook(ctx, HookSaveOpen, savePath); err != nil {
+		t.Fatalf("CallHook failed: %v", err)
+	}
+	if _, err := m.LoadPlugin(ctx, pluginDir); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	logs := m.GetAuditLogger().GetEventsByType("load")
+	if len(logs) != 1 || logs[0].Hash == "" {
+		t.Fatalf("audit event not chained: %+v", logs)
+	}
+	sum := sha256.Sum256([]byte(`{"gil":1200}`))
+	saveHash := hex.EncodeToString(sum[:])
+	if logs[0].SaveHash != saveHash {
+		t.Errorf("SaveHash = %q, want %q", logs[0].SaveHash, saveHash)
+	}
+	if err := chain.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(AuditChainPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"save":"`+saveHash+`"`) {
+		t.Error("chained events do not carry the save hash")
+	}
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && filepath.Ext(path) == ".pem" {
+			t.Errorf("key %s written inside the plugin directory", path)
+		}
+		return nil
+	})
+
+	verifier := NewSecurityManager()
+	if n, err := verifier.LoadTrustedKeys(AuditTrustedKeysDir(keyDir)); err != nil || n != 1 {
+		t.Fatalf("LoadTrustedKeys = %d, %v", n, err)
+	}
+	report, err := VerifyAuditChainFile(AuditChainPath(root), verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Events == 0 || report.Unsigned != 0 || report.Signers[0] != DefaultAuditSigner {
+		t.Errorf("report = %+v", report)
+	}
+}
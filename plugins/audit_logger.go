@@ -1,7 +1,9 @@
 package plugins
 
 import (
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -22,6 +24,10 @@ type AuditLog struct {
 	Timestamp    time.Time              // When event occurred
 	Duration     int64                  // Duration in microseconds (if applicable)
 	Details      map[string]interface{} // Additional context
+	SaveHash     string                 // SHA-256 of the open save file, see SetSaveHash
+	Seq          uint64                 // Position in the audit chain, if one is set
+	PrevHash     string                 // Hash of the previous chain record
+	Hash         string                 // Hash of this record, see AuditChain
 }
 
 // AuditLogger tracks all plugin operations and permissions
@@ -34,6 +40,9 @@ type AuditLogger struct {
 	maxLogs        int
 	autoCleanup    bool
 	retentionDays  int
+	store          *EventStore // optional; see SetEventStore
+	chain          *AuditChain // optional; see SetAuditChain
+	saveHash       string      // stamped on every event; see SetSaveHash
 }
 
 // NewAuditLogger creates a new audit logger
@@ -65,9 +74,16 @@ func (al *AuditLogger) LogEvent(pluginID, eventType, action, permissionID, statu
 		Timestamp:    time.Now(),
 		Duration:     duration,
 		Details:      details,
+		SaveHash:     al.saveHash,
 	}
 
+	if al.chain != nil {
+		al.chain.Append(event)
+	}
 	al.logs = append(al.logs, event)
+	if al.store != nil {
+		al.store.Append(auditStoredEvent(event))
+	}
 
 	// Track permission usage
 	if permissionID != "" {
@@ -88,6 +104,56 @@ func (al *AuditLogger) LogEvent(pluginID, eventType, action, permissionID, statu
 	}
 }
 
+// SetEventStore makes the logger also append every event to store, so the
+// audit trail outlives the process; nil stops it
+func (al *AuditLogger) SetEventStore(store *EventStore) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.store = store
+}
+
+// SetAuditChain makes the logger chain every event into chain, so edits,
+// deletions and reordering can be detected with VerifyAuditChain; nil
+// stops it
+func (al *AuditLogger) SetAuditChain(chain *AuditChain) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.chain = chain
+}
+
+// SetSaveHash stamps every event logged from now on with hash, the SHA-256
+// of the save file the plugins are working on, so the chain shows which
+// save each event happened to; "" stops it
+func (al *AuditLogger) SetSaveHash(hash string) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.saveHash = hash
+}
+
+// auditStoredEvent converts an audit event for the event store
+func auditStoredEvent(event *AuditLog) StoredEvent {
+	data := map[string]interface{}{"event_id": event.EventID, "action": event.Action}
+	if event.PermissionID != "" {
+		data["permission"] = event.PermissionID
+	}
+	if event.SaveHash != "" {
+		data["save"] = event.SaveHash
+	}
+	for k, v := range event.Details {
+		data[k] = v
+	}
+	return StoredEvent{
+		Time:     event.Timestamp,
+		Source:   EventSourceAudit,
+		Type:     event.EventType,
+		PluginID: event.PluginID,
+		Status:   event.Status,
+		Duration: time.Duration(event.Duration) * time.Microsecond,
+		Error:    event.Error,
+		Data:     data,
+	}
+}
+
 // LogPermissionUsed logs permission usage
 func (al *AuditLogger) LogPermissionUsed(pluginID, permission string) {
 	al.LogEvent(pluginID, "permission_used", "READ", permission, "success", "", 0, map[string]interface{}{
@@ -125,6 +191,23 @@ func (al *AuditLogger) LogPluginExecution(pluginID string, duration int64, succe
 	})
 }
 
+// LogSaveFile hashes the save file at path after it was opened or written,
+// makes it the save hash of the events that follow and logs the action
+// ("OPEN" or "WRITE") with it. A file that cannot be read clears the hash.
+func (al *AuditLogger) LogSaveFile(path, action string) {
+	status, errMsg, hash := "success", "", ""
+	if data, err := os.ReadFile(path); err != nil {
+		status, errMsg = "error", err.Error()
+	} else {
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+	al.SetSaveHash(hash)
+	al.LogEvent(EditorSource, "save", action, "", status, errMsg, 0, map[string]interface{}{
+		"path": path,
+	})
+}
+
 // LogSecurityViolation logs security violations
 func (al *AuditLogger) LogSecurityViolation(pluginID, violationType, description string) {
 	al.LogEvent(pluginID, "security_violation", "SECURITY", "", "denied", description, 0, map[string]interface{}{
@@ -0,0 +1,207 @@
+package plugins
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Capabilities are the scoped permissions a plugin declares in the
+// "permissions" list of metadata.json. Each PluginAPI method and Lua binding
+// requires one of them.
+var Capabilities = struct {
+	CharactersRead  string
+	CharactersWrite string
+	InventoryRead   string
+	InventoryWrite  string
+	PartyRead       string
+	PartyWrite      string
+	EquipmentRead   string
+	EquipmentWrite  string
+	EspersRead      string
+	GilRead         string
+	GilWrite        string
+	TreasuresRead   string
+	TreasuresWrite  string
+	PluginDataRead  string
+	PluginDataWrite string
+	UIDialog        string
+	EventsSubscribe string
+	EventsPublish   string
+}{
+	CharactersRead:  "save.characters:read",
+	CharactersWrite: "save.characters:write",
+	InventoryRead:   "save.inventory:read",
+	InventoryWrite:  "save.inventory:write",
+	PartyRead:       "save.party:read",
+	PartyWrite:      "save.party:write",
+	EquipmentRead:   "save.equipment:read",
+	EquipmentWrite:  "save.equipment:write",
+	EspersRead:      "save.espers:read",
+	GilRead:         "save.gil:read",
+	GilWrite:        "save.gil:write",
+	TreasuresRead:   "save.treasures:read",
+	TreasuresWrite:  "save.treasures:write",
+	PluginDataRead:  "fs.plugin-data:read",
+	PluginDataWrite: "fs.plugin-data:write",
+	UIDialog:        "ui.dialog",
+	EventsSubscribe: "events:subscribe",
+	EventsPublish:   "events:publish",
+}
+
+// capabilityDescriptions is what the user is shown when asked to consent
+var capabilityDescriptions = map[string]string{
+	Capabilities.CharactersRead:  "read character stats, spells and commands",
+	Capabilities.CharactersWrite: "change character stats, spells and commands",
+	Capabilities.InventoryRead:   "read the inventory and key items",
+	Capabilities.InventoryWrite:  "add, remove and change inventory items",
+	Capabilities.PartyRead:       "read the active party",
+	Capabilities.PartyWrite:      "change the active party",
+	Capabilities.EquipmentRead:   "read equipped weapons, armor and relics",
+	Capabilities.EquipmentWrite:  "change equipped weapons, armor and relics",
+	Capabilities.EspersRead:      "read which espers are owned",
+	Capabilities.GilRead:         "read the gil total",
+	Capabilities.GilWrite:        "change the gil total",
+	Capabilities.TreasuresRead:   "read which treasure chests are opened",
+	Capabilities.TreasuresWrite:  "open or reset treasure chests",
+	Capabilities.PluginDataRead:  "read files in its own data folder",
+	Capabilities.PluginDataWrite: "write files in its own data folder",
+	Capabilities.UIDialog:        "show dialogs and ask for input",
+	Capabilities.EventsSubscribe: "listen to editor and plugin events",
+	Capabilities.EventsPublish:   "send events to other plugins",
+}
+
+// legacyPermissions expands the original coarse permissions into
+// capability patterns so plugins that declare them keep working
+var legacyPermissions = map[string][]string{
+	CommonPermissions.ReadSave:  {"save.*:read"},
+	CommonPermissions.WriteSave: {"save.*:write"},
+	CommonPermissions.UIDisplay: {Capabilities.UIDialog},
+	CommonPermissions.Events:    {"events:*"},
+	"file_io":                   {"fs.plugin-data:*"},
+}
+
+// CapabilityList returns every known capability, sorted
+func CapabilityList() []string {
+	caps := make([]string, 0, len(capabilityDescriptions))
+	for c := range capabilityDescriptions {
+		caps = append(caps, c)
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// IsLegacyPermission reports whether perm is one of the original coarse
+// permissions, such as read_save
+func IsLegacyPermission(perm string) bool {
+	_, ok := legacyPermissions[perm]
+	return ok
+}
+
+// ValidatePermissions checks each declared permission is a capability, a
+// legacy permission or a pattern matching at least one capability
+func ValidatePermissions(perms []string) error {
+	var unknown []string
+	for _, p := range perms {
+		if _, ok := legacyPermissions[p]; ok {
+			continue
+		}
+		if len(expandPattern(p)) == 0 {
+			unknown = append(unknown, p)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown permission(s): %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// DescribePermissions lists what the declared permissions allow, one line
+// per capability, for the install consent prompt
+func DescribePermissions(perms []string) []string {
+	seen := make(map[string]bool)
+	var caps []string
+	for _, p := range perms {
+		for _, c := range NewGrants([]string{p}).Capabilities() {
+			if !seen[c] {
+				seen[c] = true
+				caps = append(caps, c)
+			}
+		}
+	}
+	sort.Strings(caps)
+	lines := make([]string, len(caps))
+	for i, c := range caps {
+		lines[i] = fmt.Sprintf("%s: %s", c, capabilityDescriptions[c])
+	}
+	return lines
+}
+
+func expandPattern(pattern string) []string {
+	var caps []string
+	for c := range capabilityDescriptions {
+		if ok, _ := path.Match(pattern, c); ok {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// Grants is the set of capabilities a plugin was given. Declared entries
+// may be exact capabilities, patterns such as "save.*:read" or legacy
+// permissions such as "read_save".
+type Grants struct {
+	declared map[string]bool
+	patterns []string
+}
+
+// NewGrants builds grants from declared permissions
+func NewGrants(perms []string) *Grants {
+	g := &Grants{declared: make(map[string]bool)}
+	for _, p := range perms {
+		g.declared[p] = true
+		if expanded, ok := legacyPermissions[p]; ok {
+			g.patterns = append(g.patterns, expanded...)
+		} else {
+			g.patterns = append(g.patterns, p)
+		}
+	}
+	return g
+}
+
+// Allows reports whether a capability, or a legacy permission that was
+// declared verbatim, is granted
+func (g *Grants) Allows(capability string) bool {
+	if g.declared[capability] {
+		return true
+	}
+	for _, p := range g.patterns {
+		if ok, _ := path.Match(p, capability); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities lists the known capabilities the grants allow, sorted
+func (g *Grants) Capabilities() []string {
+	var caps []string
+	for _, c := range CapabilityList() {
+		if g.Allows(c) {
+			caps = append(caps, c)
+		}
+	}
+	return caps
+}
+
+// QueryCapability is the capability a structured query over source needs
+func QueryCapability(source string) string {
+	switch source {
+	case "items", "keyitems":
+		return Capabilities.InventoryRead
+	case "espers":
+		return Capabilities.EspersRead
+	}
+	return Capabilities.CharactersRead
+}
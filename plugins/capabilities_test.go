@@ -0,0 +1,107 @@
+package plugins
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGrantsAllows tests exact, pattern and legacy grants
+func TestGrantsAllows(t *testing.T) {
+	tests := []struct {
+		perms []string
+		cap   string
+		want  bool
+	}{
+		{[]string{Capabilities.CharactersRead}, Capabilities.CharactersRead, true},
+		{[]string{Capabilities.CharactersRead}, Capabilities.CharactersWrite, false},
+		{[]string{"save.*:read"}, Capabilities.GilRead, true},
+		{[]string{"save.*:read"}, Capabilities.GilWrite, false},
+		{[]string{"save.*:read"}, Capabilities.PluginDataRead, false},
+		{[]string{CommonPermissions.ReadSave}, Capabilities.TreasuresRead, true},
+		{[]string{CommonPermissions.ReadSave}, CommonPermissions.ReadSave, true},
+		{[]string{CommonPermissions.WriteSave}, Capabilities.InventoryWrite, true},
+		{[]string{CommonPermissions.WriteSave}, Capabilities.InventoryRead, false},
+		{[]string{CommonPermissions.UIDisplay}, Capabilities.UIDialog, true},
+		{[]string{CommonPermissions.Events}, Capabilities.EventsPublish, true},
+		{nil, Capabilities.CharactersRead, false},
+	}
+	for _, tt := range tests {
+		if got := NewGrants(tt.perms).Allows(tt.cap); got != tt.want {
+			t.Errorf("%v allows %s = %v, want %v", tt.perms, tt.cap, got, tt.want)
+		}
+	}
+}
+
+// TestValidatePermissions tests unknown permissions are rejected
+func TestValidatePermissions(t *testing.T) {
+	if err := ValidatePermissions([]string{"read_save", "save.*:write", Capabilities.UIDialog}); err != nil {
+		t.Errorf("valid permissions rejected: %v", err)
+	}
+	err := ValidatePermissions([]string{"save.gil:read", "network:*", "root"})
+	if err == nil {
+		t.Fatal("unknown permissions accepted")
+	}
+	if !strings.Contains(err.Error(), "network:*") || !strings.Contains(err.Error(), "root") {
+		t.Errorf("error %q should name each unknown permission", err)
+	}
+}
+
+// TestDescribePermissions tests the consent lines for declared permissions
+func TestDescribePermissions(t *testing.T) {
+	lines := DescribePermissions([]string{"save.gil:*", Capabilities.GilRead})
+	want := []string{
+		"save.gil:read: " + capabilityDescriptions[Capabilities.GilRead],
+		"save.gil:write: " + capabilityDescriptions[Capabilities.GilWrite],
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("DescribePermissions = %q, want %q", lines, want)
+	}
+}
+
+// TestNewCapabilities tests upgrades only report capabilities not held before
+func TestNewCapabilities(t *testing.T) {
+	installed := &PluginMetadata{Permissions: []string{"read_save"}}
+	added := newCapabilities(installed, []string{"read_save", Capabilities.GilWrite})
+	if !reflect.DeepEqual(added, []string{Capabilities.GilWrite}) {
+		t.Errorf("added = %v, want only %s", added, Capabilities.GilWrite)
+	}
+	if added := newCapabilities(nil, []string{Capabilities.UIDialog}); len(added) != 1 {
+		t.Errorf("a fresh install should list every capability, got %v", added)
+	}
+}
+
+// TestAPIRequireAudits tests scoped APIs check and log their capabilities
+func TestAPIRequireAudits(t *testing.T) {
+	audit := NewAuditLogger(100)
+	base := NewAPIImpl(nil, []string{CommonPermissions.ReadSave, CommonPermissions.WriteSave})
+	api := base.ForPlugin("gil-only", []string{Capabilities.GilRead}, audit)
+
+	if api.PluginID() != "gil-only" {
+		t.Errorf("PluginID = %q", api.PluginID())
+	}
+	if err := api.Require(Capabilities.GilRead); err != nil {
+		t.Errorf("granted capability denied: %v", err)
+	}
+	if err := api.Require(Capabilities.CharactersWrite); err != ErrInsufficientPermissions {
+		t.Errorf("Require = %v, want ErrInsufficientPermissions", err)
+	}
+	if _, err := api.GetCharacter(context.Background(), "Terra"); err != ErrInsufficientPermissions {
+		t.Errorf("GetCharacter = %v, want ErrInsufficientPermissions", err)
+	}
+	if !base.HasPermission(Capabilities.CharactersWrite) {
+		t.Error("scoping a copy changed the original API's grants")
+	}
+
+	logs := audit.GetPluginAuditTrail("gil-only")
+	if len(logs) != 3 {
+		t.Fatalf("got %d audit entries, want 3", len(logs))
+	}
+	if logs[0].Status != "success" || logs[0].PermissionID != Capabilities.GilRead {
+		t.Errorf("first entry = %+v, want granted %s", logs[0], Capabilities.GilRead)
+	}
+	if logs[2].Status != "denied" || logs[2].PermissionID != Capabilities.CharactersRead {
+		t.Errorf("last entry = %+v, want denied %s", logs[2], Capabilities.CharactersRead)
+	}
+}
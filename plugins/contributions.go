@@ -0,0 +1,199 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TopicEditorCommand is published when the user runs a command a plugin
+// contributed; the payload names the plugin and the command
+const TopicEditorCommand = "editor.command"
+
+// Menus plugins may add items to
+var contributionMenus = map[string]bool{
+	"file":    true,
+	"edit":    true,
+	"tools":   true,
+	"plugins": true,
+}
+
+// Contributions declares what a plugin adds to the editor's interface in
+// metadata.json. The GUI renders them; nothing here depends on it.
+type Contributions struct {
+	Commands []CommandContribution `json:"commands,omitempty"`
+	Menus    []MenuContribution    `json:"menus,omitempty"`
+	Panels   []PanelContribution   `json:"panels,omitempty"`
+}
+
+// CommandContribution is a command shown in the command palette
+type CommandContribution struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+	// Shortcut binds the command to a key combination in the editor window,
+	// e.g. "Ctrl+Shift+K". It needs Ctrl, Alt or Super; combinations the
+	// editor or an earlier plugin already uses are not bound.
+	Shortcut string `json:"shortcut,omitempty"`
+}
+
+// MenuContribution adds a command to one of the main menus
+type MenuContribution struct {
+	Menu    string `json:"menu"`
+	Command string `json:"command"`
+	Label   string `json:"label,omitempty"` // defaults to the command's title
+}
+
+// PanelContribution is a side panel grouping some of the plugin's settings
+// and commands
+type PanelContribution struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Settings    []string `json:"settings,omitempty"`
+	Commands    []string `json:"commands,omitempty"`
+}
+
+// Empty reports whether nothing is contributed
+func (c *Contributions) Empty() bool {
+	return len(c.Commands) == 0 && len(c.Menus) == 0 && len(c.Panels) == 0
+}
+
+// Command returns the command with id, or nil
+func (c *Contributions) Command(id string) *CommandContribution {
+	for i := range c.Commands {
+		if c.Commands[i].ID == id {
+			return &c.Commands[i]
+		}
+	}
+	return nil
+}
+
+// Check validates the contributions against each other and the plugin's
+// settings: unique IDs, known menus, and references that resolve
+func (c *Contributions) Check(settings SettingsSchema) error {
+	seen := make(map[string]bool)
+	for _, cmd := range c.Commands {
+		if cmd.ID == "" || strings.ContainsAny(cmd.ID, " \t") {
+			return fmt.Errorf("invalid command ID %q", cmd.ID)
+		}
+		if seen[cmd.ID] {
+			return fmt.Errorf("command %s is declared twice", cmd.ID)
+		}
+		seen[cmd.ID] = true
+		if cmd.Title == "" {
+			return fmt.Errorf("command %s has no title", cmd.ID)
+		}
+	}
+	for _, item := range c.Menus {
+		if !contributionMenus[item.Menu] {
+			return fmt.Errorf("unknown menu %q (valid: %s)", item.Menu, strings.Join(ContributionMenus(), ", "))
+		}
+		if !seen[item.Command] {
+			return fmt.Errorf("menu item in %s refers to unknown command %q", item.Menu, item.Command)
+		}
+	}
+	panels := make(map[string]bool)
+	for _, panel := range c.Panels {
+		if panel.ID == "" {
+			return fmt.Errorf("panel %q has no ID", panel.Title)
+		}
+		if panels[panel.ID] {
+			return fmt.Errorf("panel %s is declared twice", panel.ID)
+		}
+		panels[panel.ID] = true
+		if panel.Title == "" {
+			return fmt.Errorf("panel %s has no title", panel.ID)
+		}
+		for _, key := range panel.Settings {
+			if settings.Field(key) == nil {
+				return fmt.Errorf("panel %s refers to unknown setting %q", panel.ID, key)
+			}
+		}
+		for _, id := range panel.Commands {
+			if !seen[id] {
+				return fmt.Errorf("panel %s refers to unknown command %q", panel.ID, id)
+			}
+		}
+	}
+	return nil
+}
+
+// ContributionMenus returns the menus plugins may add items to
+func ContributionMenus() []string {
+	menus := make([]string, 0, len(contributionMenus))
+	for menu := range contributionMenus {
+		menus = append(menus, menu)
+	}
+	sort.Strings(menus)
+	return menus
+}
+
+// checkDeclarations validates the settings schema and contributions in a
+// plugin's metadata
+func checkDeclarations(metadata *PluginMetadata) error {
+	if err := metadata.Settings.Check(); err != nil {
+		return fmt.Errorf("plugin %s settings: %w", metadata.ID, err)
+	}
+	if err := metadata.Contributes.Check(metadata.Settings); err != nil {
+		return fmt.Errorf("plugin %s contributions: %w", metadata.ID, err)
+	}
+	return nil
+}
+
+// PluginContributions is what one loaded plugin contributes
+type PluginContributions struct {
+	PluginID   string
+	PluginName string
+	Contributions
+}
+
+// Contributions returns the contributions of the enabled plugins, sorted by
+// plugin ID
+func (m *Manager) Contributions() []PluginContributions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var list []PluginContributions
+	for id, plugin := range m.plugins {
+		if !plugin.Enabled {
+			continue
+		}
+		meta := plugin.GetMetadata()
+		if meta.Contributes.Empty() {
+			continue
+		}
+		list = append(list, PluginContributions{PluginID: id, PluginName: meta.Name, Contributions: meta.Contributes})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PluginID < list[j].PluginID })
+	return list
+}
+
+// InvokeCommand runs a contributed command. With a runner set the plugin's
+// module function named after the command is called under its sandbox
+// policy; TopicEditorCommand is then published for Go subscribers.
+func (m *Manager) InvokeCommand(ctx context.Context, pluginID, commandID string) error {
+	m.mu.RLock()
+	plugin, exists := m.plugins[pluginID]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrPluginNotFound
+	}
+	if !plugin.Enabled {
+		return fmt.Errorf("plugin %s is disabled", pluginID)
+	}
+	meta := plugin.GetMetadata()
+	if meta.Contributes.Command(commandID) == nil {
+		return fmt.Errorf("plugin %s has no command %q", pluginID, commandID)
+	}
+	if runner := m.getRunner(); runner != nil {
+		if err := runner(ctx, plugin, commandID); err != nil {
+			return fmt.Errorf("command %s: %w", commandID, err)
+		}
+	}
+	return m.Emit(ctx, TopicEditorCommand, map[string]interface{}{
+		"plugin":  pluginID,
+		"command": commandID,
+	})
+}
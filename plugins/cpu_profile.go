@@ -0,0 +1,316 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultProfileInterval is how many Lua instructions pass between stack
+// samples when a CPUProfile does not set its own
+const DefaultProfileInterval = 1000
+
+// ProfileFrame is one function in a sampled Lua call stack
+type ProfileFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"` // where the function is defined
+}
+
+// String renders the frame as "name (file:line)", without the semicolons
+// the collapsed format uses as separators
+func (f ProfileFrame) String() string {
+	s := f.Name
+	if f.File != "" {
+		if f.Line > 0 {
+			s = fmt.Sprintf("%s (%s:%d)", f.Name, f.File, f.Line)
+		} else {
+			s = fmt.Sprintf("%s (%s)", f.Name, f.File)
+		}
+	}
+	return strings.ReplaceAll(s, ";", ",")
+}
+
+// ProfileStack is a distinct call stack, root first, with the time and
+// number of samples attributed to it
+type ProfileStack struct {
+	Frames  []ProfileFrame `json:"frames"`
+	Weight  time.Duration  `json:"weight_ns"`
+	Samples int            `json:"samples"`
+}
+
+// FunctionCost is the time spent in one function: Self while it was the
+// running function, Total while it was anywhere on the stack
+type FunctionCost struct {
+	Frame   ProfileFrame
+	Self    time.Duration
+	Total   time.Duration
+	Samples int
+}
+
+// CPUProfile aggregates samples of the Lua call stack taken while a plugin
+// runs. Each sample is weighted by the wall-clock time since the previous
+// one, so the weights add up to the time the run spent executing Lua. The
+// zero value is an empty profile sampled every DefaultProfileInterval
+// instructions.
+type CPUProfile struct {
+	PluginID string
+	Start    time.Time
+	Duration time.Duration
+	// Interval is the number of instructions between samples; <= 0 means
+	// DefaultProfileInterval
+	Interval int64
+
+	stacks map[string]*ProfileStack
+}
+
+// NewCPUProfile creates an empty profile; interval <= 0 means
+// DefaultProfileInterval
+func NewCPUProfile(pluginID string, interval int64) *CPUProfile {
+	if interval <= 0 {
+		interval = DefaultProfileInterval
+	}
+	return &CPUProfile{
+		PluginID: pluginID,
+		Start:    time.Now(),
+		Interval: interval,
+		stacks:   make(map[string]*ProfileStack),
+	}
+}
+
+// AddSample records one sample of a stack, root first
+func (p *CPUProfile) AddSample(frames []ProfileFrame, weight time.Duration) {
+	if len(frames) == 0 {
+		return
+	}
+	if p.stacks == nil {
+		p.stacks = make(map[string]*ProfileStack)
+	}
+	key := collapsedKey(frames)
+	s, ok := p.stacks[key]
+	if !ok {
+		s = &ProfileStack{Frames: append([]ProfileFrame(nil), frames...)}
+		p.stacks[key] = s
+	}
+	s.Weight += weight
+	s.Samples++
+}
+
+// Merge adds the samples of other to p
+func (p *CPUProfile) Merge(other *CPUProfile) {
+	if p.stacks == nil {
+		p.stacks = make(map[string]*ProfileStack)
+	}
+	for key, s := range other.stacks {
+		mine, ok := p.stacks[key]
+		if !ok {
+			mine = &ProfileStack{Frames: s.Frames}
+			p.stacks[key] = mine
+		}
+		mine.Weight += s.Weight
+		mine.Samples += s.Samples
+	}
+	p.Duration += other.Duration
+}
+
+func (p *CPUProfile) clone() *CPUProfile {
+	c := NewCPUProfile(p.PluginID, p.Interval)
+	c.Start = p.Start
+	c.Merge(p)
+	return c
+}
+
+// Stacks returns the distinct stacks, sorted by their collapsed form
+func (p *CPUProfile) Stacks() []ProfileStack {
+	keys := make([]string, 0, len(p.stacks))
+	for key := range p.stacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]ProfileStack, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, *p.stacks[key])
+	}
+	return list
+}
+
+// Samples returns the number of samples taken
+func (p *CPUProfile) Samples() int {
+	n := 0
+	for _, s := range p.stacks {
+		n += s.Samples
+	}
+	return n
+}
+
+// SampledTime is the sum of the sample weights
+func (p *CPUProfile) SampledTime() time.Duration {
+	var total time.Duration
+	for _, s := range p.stacks {
+		total += s.Weight
+	}
+	return total
+}
+
+// Functions returns the cost of every function seen, most self time first.
+// A recursive function counts once per sample towards its total.
+func (p *CPUProfile) Functions() []FunctionCost {
+	costs := make(map[string]*FunctionCost)
+	get := func(f ProfileFrame) *FunctionCost {
+		key := f.String()
+		c, ok := costs[key]
+		if !ok {
+			c = &FunctionCost{Frame: f}
+			costs[key] = c
+		}
+		return c
+	}
+	for _, s := range p.stacks {
+		seen := make(map[string]bool)
+		for _, f := range s.Frames {
+			if key := f.String(); !seen[key] {
+				seen[key] = true
+				c := get(f)
+				c.Total += s.Weight
+				c.Samples += s.Samples
+			}
+		}
+		get(s.Frames[len(s.Frames)-1]).Self += s.Weight
+	}
+	list := make([]FunctionCost, 0, len(costs))
+	for _, c := range costs {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Self != list[j].Self {
+			return list[i].Self > list[j].Self
+		}
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Frame.String() < list[j].Frame.String()
+	})
+	return list
+}
+
+// FlameGraph merges the stacks into a tree rooted at the plugin
+func (p *CPUProfile) FlameGraph() *FlameGraphNode {
+	root := &FlameGraphNode{Name: p.PluginID}
+	for _, s := range p.Stacks() {
+		root.Duration += s.Weight
+		node := root
+		for _, f := range s.Frames {
+			name := f.String()
+			var child *FlameGraphNode
+			for _, c := range node.Children {
+				if c.Name == name {
+					child = c
+					break
+				}
+			}
+			if child == nil {
+				child = &FlameGraphNode{Name: name}
+				node.Children = append(node.Children, child)
+			}
+			child.Duration += s.Weight
+			node = child
+		}
+	}
+	return root
+}
+
+// WriteCollapsed writes the profile in the collapsed-stack format read by
+// flamegraph.pl, speedscope and most flame graph tools: one line per stack,
+// frames root first separated by semicolons, then the time in microseconds
+func (p *CPUProfile) WriteCollapsed(w io.Writer) error {
+	for _, s := range p.Stacks() {
+		us := s.Weight.Microseconds()
+		if us < 1 {
+			us = 1
+		}
+		if _, err := fmt.Fprintf(w, "%s %d\n", collapsedKey(s.Frames), us); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// speedscope file format, see https://www.speedscope.app/file-format-schema.json
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+// WriteSpeedscope writes the profile as a speedscope "sampled" profile,
+// which https://www.speedscope.app opens directly
+func (p *CPUProfile) WriteSpeedscope(w io.Writer) error {
+	frameIndex := make(map[ProfileFrame]int)
+	file := speedscopeFile{
+		Schema:   "https://www.speedscope.app/file-format-schema.json",
+		Name:     p.PluginID,
+		Exporter: "ffvi_editor",
+		Shared:   speedscopeShared{Frames: []speedscopeFrame{}},
+	}
+	prof := speedscopeProfile{
+		Type:    "sampled",
+		Name:    p.PluginID,
+		Unit:    "nanoseconds",
+		Samples: [][]int{},
+		Weights: []int64{},
+	}
+	for _, s := range p.Stacks() {
+		sample := make([]int, len(s.Frames))
+		for i, f := range s.Frames {
+			idx, ok := frameIndex[f]
+			if !ok {
+				idx = len(file.Shared.Frames)
+				frameIndex[f] = idx
+				file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{Name: f.Name, File: f.File, Line: f.Line})
+			}
+			sample[i] = idx
+		}
+		prof.Samples = append(prof.Samples, sample)
+		prof.Weights = append(prof.Weights, int64(s.Weight))
+		prof.EndValue += int64(s.Weight)
+	}
+	file.Profiles = []speedscopeProfile{prof}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+func collapsedKey(frames []ProfileFrame) string {
+	names := make([]string, len(frames))
+	for i, f := range frames {
+		names[i] = f.String()
+	}
+	return strings.Join(names, ";")
+}
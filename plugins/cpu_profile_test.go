@@ -0,0 +1,145 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCPUProfile() *CPUProfile {
+	main := ProfileFrame{Name: "main", File: "plugin.lua"}
+	run := ProfileFrame{Name: "run", File: "plugin.lua", Line: 10}
+	find := ProfileFrame{Name: "find", File: "plugin.lua", Line: 3}
+
+	p := NewCPUProfile("finder", 0)
+	p.AddSample([]ProfileFrame{main, run, find}, 30*time.Millisecond)
+	p.AddSample([]ProfileFrame{main, run, find}, 30*time.Millisecond)
+	p.AddSample([]ProfileFrame{main, run}, 20*time.Millisecond)
+	p.AddSample([]ProfileFrame{main}, 5*time.Millisecond)
+	return p
+}
+
+func TestCPUProfile_Functions(t *testing.T) {
+	p := testCPUProfile()
+	if p.Interval != DefaultProfileInterval {
+		t.Errorf("Interval = %d, want %d", p.Interval, DefaultProfileInterval)
+	}
+	if p.Samples() != 4 || p.SampledTime() != 85*time.Millisecond {
+		t.Errorf("Samples = %d, SampledTime = %s", p.Samples(), p.SampledTime())
+	}
+
+	funcs := p.Functions()
+	want := []struct {
+		name        string
+		self, total time.Duration
+	}{
+		{"find", 60 * time.Millisecond, 60 * time.Millisecond},
+		{"run", 20 * time.Millisecond, 80 * time.Millisecond},
+		{"main", 5 * time.Millisecond, 85 * time.Millisecond},
+	}
+	if len(funcs) != len(want) {
+		t.Fatalf("got %d functions, want %d", len(funcs), len(want))
+	}
+	for i, w := range want {
+		if f := funcs[i]; f.Frame.Name != w.name || f.Self != w.self || f.Total != w.total {
+			t.Errorf("function %d = %s self %s total %s, want %s self %s total %s",
+				i, f.Frame.Name, f.Self, f.Total, w.name, w.self, w.total)
+		}
+	}
+
+	merged := NewCPUProfile("finder", 0)
+	merged.Merge(p)
+	merged.Merge(p)
+	if merged.Samples() != 8 || merged.SampledTime() != 170*time.Millisecond {
+		t.Errorf("after merging twice: Samples = %d, SampledTime = %s", merged.Samples(), merged.SampledTime())
+	}
+}
+
+func TestCPUProfile_ZeroValue(t *testing.T) {
+	var p CPUProfile
+	p.AddSample([]ProfileFrame{{Name: "main"}}, time.Millisecond)
+	p.Merge(testCPUProfile())
+	if p.Samples() != 5 {
+		t.Errorf("Samples = %d, want 5", p.Samples())
+	}
+}
+
+func TestCPUProfile_FlameGraph(t *testing.T) {
+	root := testCPUProfile().FlameGraph()
+	if root.Name != "finder" || root.Duration != 85*time.Millisecond {
+		t.Fatalf("root = %s %s", root.Name, root.Duration)
+	}
+	if len(root.Children) != 1 || len(root.Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree shape: %+v", root.Children)
+	}
+	run := root.Children[0].Children[0]
+	if run.Name != "run (plugin.lua:10)" || run.Duration != 80*time.Millisecond {
+		t.Errorf("run node = %s %s", run.Name, run.Duration)
+	}
+}
+
+func TestCPUProfile_WriteCollapsed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testCPUProfile().WriteCollapsed(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"main (plugin.lua) 5000",
+		"main (plugin.lua);run (plugin.lua:10) 20000",
+		"main (plugin.lua);run (plugin.lua:10);find (plugin.lua:3) 60000",
+	}, "\n") + "\n"
+	if buf.String() != want {
+		t.Errorf("collapsed output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestCPUProfile_WriteSpeedscope(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testCPUProfile().WriteSpeedscope(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var file speedscopeFile
+	if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(file.Shared.Frames) != 3 || len(file.Profiles) != 1 {
+		t.Fatalf("got %d frames and %d profiles", len(file.Shared.Frames), len(file.Profiles))
+	}
+	prof := file.Profiles[0]
+	if prof.Type != "sampled" || len(prof.Samples) != 3 || len(prof.Weights) != 3 {
+		t.Errorf("unexpected profile %+v", prof)
+	}
+	if prof.EndValue != int64(85*time.Millisecond) {
+		t.Errorf("EndValue = %d, want %d", prof.EndValue, int64(85*time.Millisecond))
+	}
+	for _, sample := range prof.Samples {
+		for _, idx := range sample {
+			if idx < 0 || idx >= len(file.Shared.Frames) {
+				t.Errorf("sample refers to frame %d", idx)
+			}
+		}
+	}
+}
+
+func TestProfiler_CPUProfile(t *testing.T) {
+	profiler := NewPluginProfiler(100, 1.0)
+	profiler.RecordExecution("finder", 100*time.Millisecond, true, nil)
+	profiler.RecordCPUProfile(testCPUProfile())
+	profiler.RecordCPUProfile(testCPUProfile())
+
+	profile := profiler.GetCPUProfile("finder")
+	if profile == nil || profile.Samples() != 8 {
+		t.Fatalf("GetCPUProfile = %+v, want 8 samples", profile)
+	}
+	graph := profiler.GenerateFlameGraph("finder")
+	if graph == nil || len(graph.Children) != 1 || graph.Children[0].Name != "main (plugin.lua)" {
+		t.Errorf("GenerateFlameGraph did not use the CPU profile: %+v", graph)
+	}
+
+	profiler.ClearMetrics("finder")
+	if profiler.GetCPUProfile("finder") != nil {
+		t.Error("ClearMetrics kept the CPU profile")
+	}
+}
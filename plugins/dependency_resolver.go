@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -48,52 +49,86 @@ func NewDependencyResolver() *DependencyResolver {
 
 // AddPlugin adds a plugin to the dependency graph
 func (d *DependencyResolver) AddPlugin(plugin *Plugin) error {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	metadata := plugin.GetMetadata()
+	return d.addNode(plugin.ID, plugin.Version, metadata.Dependencies)
+}
 
-	version, err := ParseVersion(plugin.Version)
+// AddMetadata adds an installed or planned plugin to the dependency graph
+// without loading it
+func (d *DependencyResolver) AddMetadata(metadata *PluginMetadata) error {
+	return d.addNode(metadata.ID, metadata.Version, metadata.Dependencies)
+}
+
+// addNode adds or replaces a node. Dependencies use the "pluginID" or
+// "pluginID@constraint" format; a bare ID accepts any version.
+func (d *DependencyResolver) addNode(pluginID, versionStr string, dependencies []string) error {
+	version, err := ParseVersion(versionStr)
 	if err != nil {
 		return fmt.Errorf("invalid plugin version: %w", err)
 	}
 
 	node := &PluginNode{
-		ID:           plugin.ID,
+		ID:           pluginID,
 		Version:      version,
 		Dependencies: make(map[string]*VersionConstraint),
 	}
-
-	// Get dependencies from metadata if available
-	metadata := plugin.GetMetadata()
-	if len(metadata.Dependencies) > 0 {
-		for _, dep := range metadata.Dependencies {
-			// Parse dependency string (format: "pluginID@constraint")
-			// For now, assume Dependencies is a list of plugin IDs
-			// We'll need to enhance this to support version constraints
-			// For now, just mark as required with wildcard constraint
-			constraint := &VersionConstraint{
-				Operator: "*",
-			}
-			node.Dependencies[dep] = constraint
+	for _, dep := range dependencies {
+		depID, constraint, err := ParseDependency(dep)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", pluginID, err)
 		}
+		node.Dependencies[depID] = constraint
 	}
 
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.pluginGraph.mu.Lock()
-	d.pluginGraph.nodes[plugin.ID] = node
-	d.pluginGraph.mu.Unlock()
+	// Drop edges from a previous version of this node
+	if old, ok := d.pluginGraph.nodes[pluginID]; ok {
+		for depID := range old.Dependencies {
+			d.pluginGraph.edges[depID] = removeString(d.pluginGraph.edges[depID], pluginID)
+		}
+	}
+	d.pluginGraph.nodes[pluginID] = node
 
 	// Build edges
 	for depID := range node.Dependencies {
-		d.pluginGraph.mu.Lock()
-		d.pluginGraph.edges[depID] = append(d.pluginGraph.edges[depID], plugin.ID)
-		d.pluginGraph.mu.Unlock()
+		d.pluginGraph.edges[depID] = append(d.pluginGraph.edges[depID], pluginID)
 	}
+	d.pluginGraph.mu.Unlock()
 
 	// Cache version
-	d.versionCache[plugin.ID] = append(d.versionCache[plugin.ID], version)
+	d.versionCache[pluginID] = append(d.versionCache[pluginID], version)
 
 	return nil
 }
 
+// ParseDependency splits a metadata dependency entry into a plugin ID and
+// version constraint, e.g. "core-lib@^1.2.0"
+func ParseDependency(dep string) (string, *VersionConstraint, error) {
+	id, constraintStr, _ := strings.Cut(strings.TrimSpace(dep), "@")
+	if id == "" {
+		return "", nil, fmt.Errorf("invalid dependency %q", dep)
+	}
+	constraint, err := ParseConstraint(constraintStr)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid dependency %q: %w", dep, err)
+	}
+	return id, constraint, nil
+}
+
+// removeString returns list without any occurrence of s
+func removeString(list []string, s string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 // RemovePlugin removes a plugin from the dependency graph
 func (d *DependencyResolver) RemovePlugin(pluginID string) {
 	d.mu.Lock()
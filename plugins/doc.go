@@ -21,6 +21,111 @@
 //   - Network: Network access
 //   - UIDisplay: Show UI dialogs
 //
+// Each plugin's metadata.json "permissions" declares scoped capabilities
+// such as save.characters:read, save.gil:write, fs.plugin-data:write,
+// ui.dialog or events:publish; patterns like "save.*:read" match several.
+// The legacy read_save, write_save, ui_display, events and file_io entries
+// expand to the matching capabilities. The Manager hands every plugin an
+// APIImpl scoped with ForPlugin, whose methods and the Lua save bindings
+// call Require and log each use or denial to the AuditLogger. Install plans
+// list the capabilities a plugin would newly gain so the user can consent.
+//
+// Resource limits in a SandboxPolicy (instructions, memory, timeout, CPU
+// share) are enforced inside the Lua runtime. The editor installs
+// scripting.NewPluginRunner with Manager.SetRunner, so ExecutePlugin and
+// InvokeCommand run plugin.lua under those limits, kill a runaway script
+// and record an enforced SecurityViolation.
+//
+// Storage:
+//
+// APIImpl.Storage opens a plugin's durable key-value store, either
+// plugin-wide or for the save file named with StorageManager.SetSaveScope.
+// Stores live under <pluginDir>/.data/<pluginID> as JSON written on every
+// change, support prefix listing and all-or-nothing transactions, and share
+// a per-plugin byte quota. Reads need fs.plugin-data:read and writes
+// fs.plugin-data:write. HotReloadManager snapshots them with the rest of a
+// plugin's state.
+//
+// Events:
+//
+// The Manager's EventBus is shared by every plugin. Topics are
+// dot-separated and subscriptions may use "*" for one segment or "**" for
+// any number, as in "save.*". Payloads are copied through JSON and checked
+// against the schema the publishing plugin declares under "events" in
+// metadata.json. Each subscriber sees events in publish order, a failing
+// handler is logged without affecting the others, and subscribing with
+// replay first delivers the recent events the bus keeps. The editor
+// publishes save.opened, save.saved, character.edited and
+// inventory.changed; those namespaces are reserved for it.
+//
+// Settings and Contributions:
+//
+// A plugin declares typed settings (string, integer, number, boolean,
+// enum) with ranges, options and defaults under "settings" in metadata.json,
+// and the commands, menu items and panels it adds to the GUI under
+// "contributes". Both are checked when a package is planned and when a
+// plugin loads. SetConfig and UpdateSettings reject values the schema does
+// not accept and save the rest to <pluginDir>/.data/<pluginID>/settings.json,
+// outside the storage quota; PluginSettings also works for plugins that are
+// installed but not loaded. Running a contributed command publishes
+// editor.command with the plugin and command IDs; a command's shortcut is
+// bound in the editor window while the plugin is loaded.
+//
+// Packages:
+//
+// A plugin package is a zip of metadata.json, Lua sources and assets plus
+// MANIFEST.json (SHA-256 of every file) and a detached MANIFEST.sig over the
+// manifest. InstallPackage verifies the signature against keys added with
+// AddTrustedKey, and LoadPlugin re-verifies installed directories on every
+// load. Unsigned or tampered packages are refused, quarantined under
+// <pluginDir>/.quarantine or installed with a warning depending on the
+// SignaturePolicy; each outcome is recorded in the AuditLogger.
+//
+// Scaffold starts a new plugin directory from one of ScaffoldTemplates
+// (hook, tool, database) with metadata.json, plugin.lua, CHANGELOG.md and
+// a Lua test; BuildPackage turns a directory into an unsigned package for
+// SignPackage.
+//
+// Profiling:
+//
+// PluginProfiler records execution times per plugin. A CPUProfile holds
+// samples of a plugin's Lua call stack, weighted by the time between them;
+// RecordCPUProfile merges them per plugin, GenerateFlameGraph builds its
+// tree from them, and WriteCollapsed and WriteSpeedscope export them for
+// flamegraph.pl and https://www.speedscope.app.
+//
+// Event Store:
+//
+// An EventStore keeps what AnalyticsEngine, AuditLogger and PluginProfiler
+// record beyond their in-memory history: every event is appended as a line
+// of JSON to segments under <pluginDir>/.events, rotated by size. Query
+// selects events by plugin, source, type and time range across restarts;
+// Rollups returns hourly and daily counts, failures and durations for the
+// dashboards. A RetentionPolicy removes old segments while the rollups,
+// which outlive them, are pruned separately. Manager.OpenEventStore
+// attaches a store with DefaultRetention.
+//
+// Audit Chain:
+//
+// Manager.OpenAuditChain makes AuditLogger also append every event to
+// <pluginDir>/.audit/audit.jsonl, where each record carries the hash of the
+// one before it and of the save file last opened or written. Every
+// DefaultCheckpointInterval records, on each sync and on Stop, a checkpoint
+// signed with the chain's own key pair seals the chain so far; the key is
+// kept in a directory outside the plugin directory, which also receives
+// its public key under keys/. VerifyAuditChain reports records that were
+// edited, deleted or reordered and checkpoints not signed by a trusted key.
+//
+// Install Transactions:
+//
+// PlanInstall and PlanUninstall compute every plugin change up front using
+// DependencyResolver and the "id@constraint" entries in metadata
+// dependencies; upgrades stay within what installed dependents accept and
+// plugins others depend on cannot be removed. ApplyPlan verifies and
+// unpacks all packages into <pluginDir>/.staging before swapping any
+// directory, keeps each replaced version under <pluginDir>/.previous for
+// HotReloadManager.RollbackPlugin, and undoes every swap on failure.
+//
 // Usage:
 //
 //	api := plugins.NewAPIImpl(prData, []string{
@@ -23,4 +23,21 @@ var (
 	ErrMaxPluginsExceeded      = fmt.Errorf("maximum number of plugins exceeded")
 	ErrPluginDependencyMissing = fmt.Errorf("required plugin dependency is missing")
 	ErrPluginVersionMismatch   = fmt.Errorf("plugin version is incompatible with editor")
+	ErrInvalidPackage          = fmt.Errorf("invalid plugin package")
+	ErrPackageUnsigned         = fmt.Errorf("plugin package is not signed")
+	ErrPackageTampered         = fmt.Errorf("plugin package has been tampered with")
+	ErrUntrustedSigner         = fmt.Errorf("plugin package signer is not trusted")
+	ErrPluginQuarantined       = fmt.Errorf("plugin package was quarantined")
+	ErrDependencyConflict      = fmt.Errorf("plugin dependencies cannot be satisfied")
+	ErrPluginHasDependents     = fmt.Errorf("plugin is required by other plugins")
+	ErrStorageUnavailable      = fmt.Errorf("plugin storage is not available")
+	ErrStorageQuotaExceeded    = fmt.Errorf("plugin storage quota exceeded")
+	ErrNoSaveScope             = fmt.Errorf("no save file is open for per-save storage")
+	ErrEventBusUnavailable     = fmt.Errorf("plugin event bus is not available")
+	ErrTopicReserved           = fmt.Errorf("event topic is reserved")
+	ErrInvalidEventPayload     = fmt.Errorf("event payload does not match its schema")
+	ErrInvalidSettings         = fmt.Errorf("plugin settings do not match their schema")
+	ErrEventStoreClosed        = fmt.Errorf("event store is closed")
+	ErrAuditChainClosed        = fmt.Errorf("audit chain is closed")
+	ErrAuditChainBroken        = fmt.Errorf("audit chain failed verification")
 )
@@ -0,0 +1,374 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EditorSource is the source of events the editor itself publishes
+const EditorSource = "editor"
+
+// DefaultReplaySize is how many recent events an EventBus keeps for late
+// subscribers
+const DefaultReplaySize = 256
+
+// Built-in topics the editor publishes
+const (
+	TopicSaveOpened       = "save.opened"
+	TopicSaveSaved        = "save.saved"
+	TopicCharacterEdited  = "character.edited"
+	TopicInventoryChanged = "inventory.changed"
+)
+
+// reservedNamespaces are the first topic segments only the editor may
+// publish under
+var reservedNamespaces = map[string]bool{
+	"save":      true,
+	"character": true,
+	"inventory": true,
+	"party":     true,
+	"editor":    true,
+}
+
+// builtinSchemas describe the payloads of the built-in topics
+var builtinSchemas = map[string]string{
+	TopicSaveOpened:       `{"type":"object","required":["path"],"properties":{"path":{"type":"string"}}}`,
+	TopicSaveSaved:        `{"type":"object","required":["path"],"properties":{"path":{"type":"string"}}}`,
+	TopicCharacterEdited:  `{"type":"object","properties":{"id":{"type":"integer"},"name":{"type":"string"},"by":{"type":"string"}}}`,
+	TopicInventoryChanged: `{"type":"object","properties":{"by":{"type":"string"}}}`,
+	TopicEditorCommand:    `{"type":"object","required":["plugin","command"],"properties":{"plugin":{"type":"string"},"command":{"type":"string"}}}`,
+}
+
+// Event is one message on the bus. Payloads are copied through JSON, so
+// each subscriber gets its own maps, slices, strings, numbers and bools.
+type Event struct {
+	Topic   string
+	Source  string // publishing plugin ID, or EditorSource
+	Seq     uint64 // increases by one per published event
+	Time    time.Time
+	Payload interface{}
+}
+
+// EventHandler receives events. An error or panic is reported to the bus's
+// error handler and does not affect other subscribers.
+type EventHandler func(ctx context.Context, ev *Event) error
+
+// Subscription is a handler registered for a topic pattern
+type Subscription struct {
+	ID         uint64
+	Subscriber string
+	Pattern    string
+
+	handler EventHandler
+	since   uint64 // events up to this sequence number come from replay
+	closed  bool
+}
+
+// delivery is a queued event; to is nil for every matching subscriber
+type delivery struct {
+	ev *Event
+	to *Subscription
+}
+
+// EventBus delivers events between plugins and from the editor. Topics are
+// dot-separated; in subscription patterns "*" matches one segment and "**"
+// any number. Every subscriber sees events in publish order, including
+// events published from inside handlers.
+type EventBus struct {
+	subs       []*Subscription
+	schemas    map[string]*EventSchema
+	replay     []*Event
+	replaySize int
+	queue      []delivery
+	draining   bool
+	seq        uint64
+	nextSubID  uint64
+	onError    func(sub *Subscription, ev *Event, err error)
+	mu         sync.Mutex
+}
+
+// NewEventBus creates an event bus keeping replaySize recent events, with
+// schemas for the built-in topics registered
+func NewEventBus(replaySize int) *EventBus {
+	b := &EventBus{
+		schemas:    make(map[string]*EventSchema),
+		replaySize: replaySize,
+	}
+	for topic, raw := range builtinSchemas {
+		schema, err := ParseEventSchema([]byte(raw))
+		if err != nil {
+			panic(fmt.Sprintf("built-in schema for %s: %v", topic, err))
+		}
+		b.schemas[topic] = schema
+	}
+	return b
+}
+
+// SetErrorHandler sets what is told about failing handlers
+func (b *EventBus) SetErrorHandler(fn func(sub *Subscription, ev *Event, err error)) {
+	b.mu.Lock()
+	b.onError = fn
+	b.mu.Unlock()
+}
+
+// RegisterSchema checks every later payload published on topic against
+// schema. A topic's schema cannot be replaced by a different one.
+func (b *EventBus) RegisterSchema(topic string, schema *EventSchema) error {
+	if err := validateTopic(topic); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.schemas[topic]; ok && !existing.equal(schema) {
+		return fmt.Errorf("event topic %s already has a different schema", topic)
+	}
+	b.schemas[topic] = schema
+	return nil
+}
+
+// Subscribe registers handler for topics matching pattern. With replay,
+// buffered events matching the pattern are delivered first, oldest first.
+func (b *EventBus) Subscribe(subscriber, pattern string, replay bool, handler EventHandler) (*Subscription, error) {
+	if handler == nil {
+		return nil, ErrNilCallback
+	}
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.nextSubID++
+	sub := &Subscription{
+		ID:         b.nextSubID,
+		Subscriber: subscriber,
+		Pattern:    pattern,
+		handler:    handler,
+		since:      b.seq,
+	}
+	b.subs = append(b.subs, sub)
+	if !replay {
+		b.mu.Unlock()
+		return sub, nil
+	}
+	for _, ev := range b.replay {
+		if MatchTopic(pattern, ev.Topic) {
+			b.queue = append(b.queue, delivery{ev: ev, to: sub})
+		}
+	}
+	start := !b.draining
+	b.draining = true
+	b.mu.Unlock()
+
+	if start {
+		b.drain(context.Background())
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes a subscription; queued events are not delivered to it
+func (b *EventBus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subs {
+		if s == sub {
+			s.closed = true
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// UnsubscribeAll removes every subscription of a subscriber, such as a
+// plugin being unloaded
+func (b *EventBus) UnsubscribeAll(subscriber string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.subs[:0:0]
+	for _, s := range b.subs {
+		if s.Subscriber == subscriber {
+			s.closed = true
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	b.subs = kept
+}
+
+// Subscriptions returns the current subscriptions
+func (b *EventBus) Subscriptions() []*Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*Subscription(nil), b.subs...)
+}
+
+// Publish sends payload on topic. The payload must encode to JSON and match
+// the topic's schema; only the editor may publish in reserved namespaces.
+// Handler errors are isolated, so Publish only fails on a rejected event.
+func (b *EventBus) Publish(ctx context.Context, source, topic string, payload interface{}) error {
+	if err := validateTopic(topic); err != nil {
+		return err
+	}
+	if source != EditorSource && reservedNamespaces[strings.SplitN(topic, ".", 2)[0]] {
+		return fmt.Errorf("%w: %s is reserved for the editor", ErrTopicReserved, topic)
+	}
+	normalized, err := normalizePayload(payload)
+	if err != nil {
+		return fmt.Errorf("event %s: %w", topic, err)
+	}
+
+	b.mu.Lock()
+	if schema, ok := b.schemas[topic]; ok {
+		if err := schema.Validate(normalized); err != nil {
+			b.mu.Unlock()
+			return fmt.Errorf("%w: %s: %v", ErrInvalidEventPayload, topic, err)
+		}
+	}
+	b.seq++
+	ev := &Event{Topic: topic, Source: source, Seq: b.seq, Time: time.Now(), Payload: normalized}
+	if b.replaySize > 0 {
+		b.replay = append(b.replay, ev)
+		if len(b.replay) > b.replaySize {
+			b.replay = append(b.replay[:0:0], b.replay[len(b.replay)-b.replaySize:]...)
+		}
+	}
+	b.queue = append(b.queue, delivery{ev: ev})
+	// A publish from inside a handler is queued behind the event being
+	// delivered, which keeps the order the same for every subscriber
+	if b.draining {
+		b.mu.Unlock()
+		return nil
+	}
+	b.draining = true
+	b.mu.Unlock()
+
+	b.drain(ctx)
+	return nil
+}
+
+// Replay returns the buffered events matching pattern, oldest first
+func (b *EventBus) Replay(pattern string) []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var events []*Event
+	for _, ev := range b.replay {
+		if MatchTopic(pattern, ev.Topic) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// drain delivers queued events until the queue is empty
+func (b *EventBus) drain(ctx context.Context) {
+	for {
+		b.mu.Lock()
+		if len(b.queue) == 0 {
+			b.draining = false
+			b.mu.Unlock()
+			return
+		}
+		d := b.queue[0]
+		b.queue = b.queue[1:]
+		var targets []*Subscription
+		if d.to != nil {
+			targets = []*Subscription{d.to}
+		} else {
+			for _, s := range b.subs {
+				if d.ev.Seq > s.since && MatchTopic(s.Pattern, d.ev.Topic) {
+					targets = append(targets, s)
+				}
+			}
+		}
+		b.mu.Unlock()
+
+		for _, s := range targets {
+			b.deliver(ctx, s, d.ev)
+		}
+	}
+}
+
+func (b *EventBus) deliver(ctx context.Context, sub *Subscription, ev *Event) {
+	b.mu.Lock()
+	closed := sub.closed
+	onError := b.onError
+	b.mu.Unlock()
+	if closed {
+		return
+	}
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("handler panicked: %v", r)
+			}
+		}()
+		// Each handler gets its own copy, so one cannot change what the next sees
+		copied := *ev
+		copied.Payload, _ = normalizePayload(ev.Payload)
+		return sub.handler(ctx, &copied)
+	}()
+	if err != nil && onError != nil {
+		onError(sub, ev, err)
+	}
+}
+
+// MatchTopic reports whether topic matches a subscription pattern
+func MatchTopic(pattern, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchSegments(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(topic); i++ {
+				if matchSegments(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(topic) == 0 || (pattern[0] != "*" && pattern[0] != topic[0]) {
+			return false
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
+
+func validateTopic(topic string) error {
+	for _, seg := range strings.Split(topic, ".") {
+		if seg == "" || strings.ContainsAny(seg, "* \t") {
+			return fmt.Errorf("invalid event topic %q", topic)
+		}
+	}
+	return nil
+}
+
+func validatePattern(pattern string) error {
+	for _, seg := range strings.Split(pattern, ".") {
+		if seg == "" || (strings.Contains(seg, "*") && seg != "*" && seg != "**") || strings.ContainsAny(seg, " \t") {
+			return fmt.Errorf("invalid event pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// normalizePayload copies a payload through JSON
+func normalizePayload(payload interface{}) (interface{}, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("payload cannot be encoded as JSON: %w", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,197 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMatchTopic tests single and multi-segment wildcards
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"save.opened", "save.opened", true},
+		{"save.*", "save.opened", true},
+		{"save.*", "save", false},
+		{"save.*", "save.slot.opened", false},
+		{"save.**", "save.slot.opened", true},
+		{"save.**", "save", true},
+		{"**", "tracker.quest.done", true},
+		{"*.edited", "character.edited", true},
+		{"tracker.**.done", "tracker.quest.narshe.done", true},
+		{"tracker.**.done", "tracker.quest.started", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+// TestEventBusOrderAndIsolation tests delivery order, nested publishes and failing handlers
+func TestEventBusOrderAndIsolation(t *testing.T) {
+	bus := NewEventBus(10)
+	var failures []string
+	bus.SetErrorHandler(func(sub *Subscription, ev *Event, err error) {
+		failures = append(failures, sub.Subscriber+":"+ev.Topic)
+	})
+
+	var first, second []string
+	bus.Subscribe("a", "tracker.*", false, func(ctx context.Context, ev *Event) error {
+		first = append(first, ev.Topic)
+		if ev.Topic == "tracker.start" {
+			// Published from a handler: every subscriber must still see start first
+			bus.Publish(ctx, "a", "tracker.next", nil)
+		}
+		return errors.New("a always fails")
+	})
+	bus.Subscribe("b", "tracker.*", false, func(ctx context.Context, ev *Event) error {
+		second = append(second, ev.Topic)
+		if ev.Topic == "tracker.next" {
+			panic("b panics")
+		}
+		return nil
+	})
+
+	if err := bus.Publish(context.Background(), "a", "tracker.start", nil); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	want := []string{"tracker.start", "tracker.next"}
+	if !reflect.DeepEqual(first, want) || !reflect.DeepEqual(second, want) {
+		t.Errorf("deliveries = %v and %v, want %v for both", first, second, want)
+	}
+	if len(failures) != 3 {
+		t.Errorf("failures = %v, want a's two errors and b's panic", failures)
+	}
+}
+
+// TestEventBusReplay tests late subscribers get buffered events first
+func TestEventBusReplay(t *testing.T) {
+	bus := NewEventBus(2)
+	ctx := context.Background()
+	bus.Publish(ctx, EditorSource, TopicSaveOpened, map[string]interface{}{"path": "a"})
+	bus.Publish(ctx, EditorSource, TopicSaveSaved, map[string]interface{}{"path": "b"})
+	bus.Publish(ctx, EditorSource, TopicSaveSaved, map[string]interface{}{"path": "c"})
+
+	var paths []string
+	handler := func(ctx context.Context, ev *Event) error {
+		paths = append(paths, ev.Payload.(map[string]interface{})["path"].(string))
+		return nil
+	}
+	bus.Subscribe("late", "save.*", true, handler)
+	bus.Publish(ctx, EditorSource, TopicSaveOpened, map[string]interface{}{"path": "d"})
+	if !reflect.DeepEqual(paths, []string{"b", "c", "d"}) {
+		t.Errorf("paths = %v, want the last two buffered then d", paths)
+	}
+
+	paths = nil
+	bus.Subscribe("live", "save.*", false, handler)
+	if len(paths) != 0 {
+		t.Errorf("subscriber without replay got %v", paths)
+	}
+}
+
+// TestEventBusRejects tests schemas, reserved namespaces and bad payloads
+func TestEventBusRejects(t *testing.T) {
+	bus := NewEventBus(10)
+	ctx := context.Background()
+	schema, err := ParseEventSchema([]byte(`{"type":"object","required":["quest"],"properties":{"quest":{"type":"string"},"step":{"type":"integer","minimum":1}}}`))
+	if err != nil {
+		t.Fatalf("ParseEventSchema failed: %v", err)
+	}
+	if err := bus.RegisterSchema("tracker.quest", schema); err != nil {
+		t.Fatalf("RegisterSchema failed: %v", err)
+	}
+
+	if err := bus.Publish(ctx, "tracker", "tracker.quest", map[string]interface{}{"quest": "narshe", "step": 2}); err != nil {
+		t.Errorf("valid payload rejected: %v", err)
+	}
+	for _, payload := range []interface{}{
+		map[string]interface{}{"step": 2},
+		map[string]interface{}{"quest": "narshe", "step": 1.5},
+		map[string]interface{}{"quest": "narshe", "step": 0},
+		"narshe",
+	} {
+		if err := bus.Publish(ctx, "tracker", "tracker.quest", payload); !errors.Is(err, ErrInvalidEventPayload) {
+			t.Errorf("Publish(%v) = %v, want ErrInvalidEventPayload", payload, err)
+		}
+	}
+	if err := bus.Publish(ctx, "tracker", TopicSaveOpened, map[string]interface{}{"path": "x"}); !errors.Is(err, ErrTopicReserved) {
+		t.Errorf("plugin publishing save.opened = %v, want ErrTopicReserved", err)
+	}
+	if err := bus.Publish(ctx, "tracker", "tracker.fn", func() {}); err == nil {
+		t.Error("payload that is not JSON was accepted")
+	}
+	if _, err := bus.Subscribe("tracker", "save.op*", false, func(context.Context, *Event) error { return nil }); err == nil {
+		t.Error("partial wildcard pattern was accepted")
+	}
+}
+
+// TestManagerEventBus tests plugins share the manager's bus and get editor events
+func TestManagerEventBus(t *testing.T) {
+	m := NewManager(t.TempDir(), NewAPIImpl(nil, nil))
+	ctx := context.Background()
+	load := func(id string, events map[string]string) *Plugin {
+		meta := &PluginMetadata{ID: id, Name: id, Version: "1.0.0", Author: "t", Permissions: []string{"events:*"}}
+		if events != nil {
+			meta.Events = make(map[string]json.RawMessage)
+			for topic, schema := range events {
+				meta.Events[topic] = json.RawMessage(schema)
+			}
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		p, err := m.loadPluginLocked("", meta)
+		if err != nil {
+			t.Fatalf("loading %s failed: %v", id, err)
+		}
+		return p
+	}
+	hub := load("hub", map[string]string{"hub.notice": `{"type":"object","required":["text"]}`})
+	listener := load("listener", nil)
+
+	var got []string
+	listener.API.Subscribe(ctx, "**", false, func(ctx context.Context, ev *Event) error {
+		got = append(got, ev.Source+":"+ev.Topic)
+		return nil
+	})
+	if err := hub.API.Publish(ctx, "hub.notice", map[string]interface{}{"text": "hi"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := hub.API.Publish(ctx, "hub.notice", map[string]interface{}{}); !errors.Is(err, ErrInvalidEventPayload) {
+		t.Errorf("payload against the declared schema = %v", err)
+	}
+	m.CallHook(ctx, HookSaveOpen, "/saves/slot1.json")
+	m.CallHook(ctx, HookCharEdit, 1, "Terra")
+
+	want := []string{"hub:hub.notice", "editor:save.opened", "editor:character.edited"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listener saw %v, want %v", got, want)
+	}
+	recent := m.GetEventBus().Replay(TopicCharacterEdited)
+	if len(recent) != 1 || recent[0].Payload.(map[string]interface{})["name"] != "Terra" {
+		t.Errorf("character.edited events = %+v, want one naming Terra", recent)
+	}
+	if scope := m.GetStorageManager().SaveScope(); scope != "/saves/slot1.json" {
+		t.Errorf("save scope = %q after opening a save", scope)
+	}
+
+	if err := m.UnloadPlugin(ctx, "listener"); err != nil {
+		t.Fatalf("UnloadPlugin failed: %v", err)
+	}
+	for _, sub := range m.GetEventBus().Subscriptions() {
+		if sub.Subscriber == "listener" {
+			t.Error("unloaded plugin is still subscribed")
+		}
+	}
+
+	err := m.registerEventSchemas(&PluginMetadata{ID: "bad", Events: map[string]json.RawMessage{"save.hacked": json.RawMessage(`{}`)}})
+	if !errors.Is(err, ErrTopicReserved) || !strings.Contains(err.Error(), "save.hacked") {
+		t.Errorf("declaring a reserved topic = %v", err)
+	}
+}
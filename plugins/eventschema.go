@@ -0,0 +1,163 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EventSchema is the subset of JSON Schema used to check event payloads:
+// type, properties, required, additionalProperties (as a boolean), items,
+// enum, minimum and maximum
+type EventSchema struct {
+	Type                 string                  `json:"type,omitempty"`
+	Properties           map[string]*EventSchema `json:"properties,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	AdditionalProperties *bool                   `json:"additionalProperties,omitempty"`
+	Items                *EventSchema            `json:"items,omitempty"`
+	Enum                 []interface{}           `json:"enum,omitempty"`
+	Minimum              *float64                `json:"minimum,omitempty"`
+	Maximum              *float64                `json:"maximum,omitempty"`
+}
+
+var schemaTypes = map[string]bool{
+	"": true, "object": true, "array": true, "string": true,
+	"number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// ParseEventSchema parses and checks a schema
+func ParseEventSchema(data []byte) (*EventSchema, error) {
+	var s EventSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid event schema: %w", err)
+	}
+	if err := s.check(""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *EventSchema) check(path string) error {
+	if !schemaTypes[s.Type] {
+		return fmt.Errorf("invalid event schema: %sunknown type %q", atPath(path), s.Type)
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("invalid event schema: %sproperty %s is null", atPath(path), name)
+		}
+		if err := prop.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate checks a JSON-decoded value against the schema
+func (s *EventSchema) Validate(v interface{}) error {
+	return s.validate(v, "")
+}
+
+func (s *EventSchema) validate(v interface{}, path string) error {
+	if s.Type != "" && !matchesType(s.Type, v) {
+		return fmt.Errorf("%sexpected %s, got %s", atPath(path), s.Type, jsonType(v))
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s%v is not one of the allowed values", atPath(path), v)
+		}
+	}
+	if n, ok := v.(float64); ok {
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s%v is below the minimum %v", atPath(path), n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Errorf("%s%v is above the maximum %v", atPath(path), n, *s.Maximum)
+		}
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := x[name]; !ok {
+				return fmt.Errorf("%smissing required property %s", atPath(path), name)
+			}
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%sunexpected property %s", atPath(path), k)
+				}
+				continue
+			}
+			if err := prop.validate(x[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range x {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *EventSchema) equal(other *EventSchema) bool {
+	return reflect.DeepEqual(s, other)
+}
+
+func matchesType(t string, v interface{}) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return jsonType(v) == t
+	}
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func atPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimPrefix(path, ".") + ": "
+}
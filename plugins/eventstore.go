@@ -0,0 +1,662 @@
+package plugins
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventStoreDirName holds the event store under the plugin directory,
+	// hidden like storageDirName
+	eventStoreDirName = ".events"
+
+	// DefaultSegmentBytes is the size at which the store starts a new segment
+	DefaultSegmentBytes = 4 << 20
+
+	segmentPattern  = "events-%012d.jsonl"
+	rollupsFileName = "rollups.json"
+	rollupsFormat   = 1
+)
+
+// Sources of stored events
+const (
+	EventSourceAnalytics = "analytics"
+	EventSourceAudit     = "audit"
+	EventSourceProfiler  = "profiler"
+)
+
+// Rollup periods
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// StoredEvent is one line of the event store
+type StoredEvent struct {
+	Seq      int64                  `json:"seq"`
+	Time     time.Time              `json:"time"`
+	Source   string                 `json:"source"`
+	Type     string                 `json:"type"`
+	PluginID string                 `json:"plugin"`
+	Status   string                 `json:"status,omitempty"`
+	Duration time.Duration          `json:"duration_ns,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// Failed reports whether the event records an error, timeout or denial
+func (e *StoredEvent) Failed() bool {
+	switch e.Status {
+	case "error", "timeout", "denied":
+		return true
+	}
+	return false
+}
+
+// EventQuery selects stored events and rollups. Zero fields match
+// everything; the time range is [Since, Until).
+type EventQuery struct {
+	PluginID string
+	Source   string
+	Types    []string
+	Since    time.Time
+	Until    time.Time
+	// Limit keeps only the newest events; 0 returns all
+	Limit int
+}
+
+func (q EventQuery) matches(source, pluginID, eventType string, t time.Time) bool {
+	if q.Source != "" && source != q.Source {
+		return false
+	}
+	if q.PluginID != "" && pluginID != q.PluginID {
+		return false
+	}
+	if len(q.Types) > 0 {
+		found := false
+		for _, typ := range q.Types {
+			if typ == eventType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Rollup counts the events of one source, type and plugin in an hour or a
+// day (local time)
+type Rollup struct {
+	Period        string        `json:"period"`
+	Start         time.Time     `json:"start"`
+	Source        string        `json:"source"`
+	Type          string        `json:"type"`
+	PluginID      string        `json:"plugin"`
+	Count         int           `json:"count"`
+	Failures      int           `json:"failures"`
+	TotalDuration time.Duration `json:"total_ns"`
+	MaxDuration   time.Duration `json:"max_ns"`
+}
+
+// AverageDuration is the mean duration of the events counted
+func (r *Rollup) AverageDuration() time.Duration {
+	if r.Count == 0 {
+		return 0
+	}
+	return r.TotalDuration / time.Duration(r.Count)
+}
+
+type rollupKey struct {
+	start                 int64
+	source, typ, pluginID string
+}
+
+// RetentionPolicy bounds what the store keeps. Raw events are removed a
+// whole segment at a time, never the one being written; rollups outlive
+// them. Zero fields keep everything.
+type RetentionPolicy struct {
+	MaxAge       time.Duration // raw events
+	MaxBytes     int64         // raw events, all segments together
+	HourlyMaxAge time.Duration
+	DailyMaxAge  time.Duration
+}
+
+// DefaultRetention keeps a month of raw events up to 64 MiB, two weeks of
+// hourly rollups and a year of daily ones
+var DefaultRetention = RetentionPolicy{
+	MaxAge:       30 * 24 * time.Hour,
+	MaxBytes:     64 << 20,
+	HourlyMaxAge: 14 * 24 * time.Hour,
+	DailyMaxAge:  365 * 24 * time.Hour,
+}
+
+// rollupsFile is the on-disk form of the rollups. Through is the last
+// event counted; events after it are counted again when the store opens.
+type rollupsFile struct {
+	Format  int       `json:"format"`
+	Through int64     `json:"through"`
+	Hourly  []*Rollup `json:"hourly"`
+	Daily   []*Rollup `json:"daily"`
+}
+
+// EventStore is an append-only log of analytics, audit and profiler events
+// kept as JSONL segments, events-<first seq>.jsonl, in one directory. A
+// segment is closed once it passes the segment size, and the store keeps
+// hourly and daily rollups of everything appended for dashboards.
+type EventStore struct {
+	dir          string
+	segmentBytes int64
+	retention    RetentionPolicy
+
+	file    *os.File // segment being written
+	size    int64
+	nextSeq int64
+	hourly  map[rollupKey]*Rollup
+	daily   map[rollupKey]*Rollup
+	closed  bool
+	mu      sync.Mutex
+}
+
+// OpenEventStore opens the store in dir, creating it if needed. segmentBytes
+// <= 0 means DefaultSegmentBytes. Retention is applied on open and every
+// time a segment is closed.
+func OpenEventStore(dir string, segmentBytes int64, retention RetentionPolicy) (*EventStore, error) {
+	if segmentBytes <= 0 {
+		segmentBytes = DefaultSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event store: %w", err)
+	}
+	s := &EventStore{
+		dir:          dir,
+		segmentBytes: segmentBytes,
+		retention:    retention,
+		nextSeq:      1,
+		hourly:       make(map[rollupKey]*Rollup),
+		daily:        make(map[rollupKey]*Rollup),
+	}
+
+	through, err := s.loadRollups()
+	if err != nil {
+		return nil, err
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	// Count the events appended after the rollups were last saved, and
+	// find where the sequence continues
+	for i, seg := range segments {
+		if i+1 < len(segments) && segments[i+1].first-1 <= through {
+			continue
+		}
+		if err := readSegment(seg.path, func(ev *StoredEvent) bool {
+			if ev.Seq > through {
+				s.addRollups(ev)
+			}
+			if ev.Seq >= s.nextSeq {
+				s.nextSeq = ev.Seq + 1
+			}
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if through >= s.nextSeq {
+		s.nextSeq = through + 1
+	}
+
+	if n := len(segments); n > 0 && segments[n-1].size < segmentBytes {
+		if err := s.openSegment(segments[n-1].path); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.saveRollups(); err != nil {
+		return nil, err
+	}
+	if _, err := s.applyRetention(time.Now()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the directory holding the store
+func (s *EventStore) Dir() string {
+	return s.dir
+}
+
+// Append adds an event, numbering it and stamping it with the current time
+// when it has none
+func (s *EventStore) Append(ev StoredEvent) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrEventStoreClosed
+	}
+
+	ev.Seq = s.nextSeq
+	line, err := json.Marshal(&ev)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	if s.file != nil && s.size > 0 && s.size+int64(len(line)) > s.segmentBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if s.file == nil {
+		if err := s.openSegment(filepath.Join(s.dir, fmt.Sprintf(segmentPattern, ev.Seq))); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	s.size += int64(len(line))
+	s.nextSeq++
+	s.addRollups(&ev)
+	return nil
+}
+
+// Query returns the stored events q selects, oldest first
+func (s *EventStore) Query(q EventQuery) ([]StoredEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	var events []StoredEvent
+	for _, seg := range segments {
+		// A segment is last written after its newest event
+		if !q.Since.IsZero() && seg.modified.Before(q.Since) {
+			continue
+		}
+		if err := readSegment(seg.path, func(ev *StoredEvent) bool {
+			if q.matches(ev.Source, ev.PluginID, ev.Type, ev.Time) {
+				events = append(events, *ev)
+			}
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[len(events)-q.Limit:]
+	}
+	return events, nil
+}
+
+// Rollups returns the hourly or daily rollups q selects, by start time then
+// plugin and type. The time range applies to the start of each period.
+func (s *EventStore) Rollups(period string, q EventQuery) ([]Rollup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var table map[rollupKey]*Rollup
+	switch period {
+	case RollupHour:
+		table = s.hourly
+	case RollupDay:
+		table = s.daily
+	default:
+		return nil, fmt.Errorf("unknown rollup period %q (valid: %s, %s)", period, RollupHour, RollupDay)
+	}
+	var list []Rollup
+	for _, r := range table {
+		if q.matches(r.Source, r.PluginID, r.Type, r.Start) {
+			list = append(list, *r)
+		}
+	}
+	sortRollups(list)
+	if q.Limit > 0 && len(list) > q.Limit {
+		list = list[len(list)-q.Limit:]
+	}
+	return list, nil
+}
+
+// ApplyRetention removes the segments and rollups the retention policy no
+// longer keeps, returning how many segments were removed
+func (s *EventStore) ApplyRetention(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyRetention(now)
+}
+
+// Flush saves the rollups and syncs the segment being written
+func (s *EventStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrEventStoreClosed
+	}
+	if s.file != nil {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync event store: %w", err)
+		}
+	}
+	return s.saveRollups()
+}
+
+// Close flushes the store; further appends fail with ErrEventStoreClosed
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.saveRollups()
+	if s.file != nil {
+		if cerr := s.file.Close(); err == nil {
+			err = cerr
+		}
+		s.file = nil
+	}
+	return err
+}
+
+func (s *EventStore) openSegment(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open event segment: %w", err)
+	}
+	s.file, s.size = f, info.Size()
+	if s.size > 0 {
+		// End a line left unfinished by a crash so the next event starts
+		// on its own line
+		last := make([]byte, 1)
+		if r, err := os.Open(path); err == nil {
+			_, err = r.ReadAt(last, s.size-1)
+			r.Close()
+			if err == nil && last[0] != '\n' {
+				if _, err := f.Write([]byte{'\n'}); err != nil {
+					return fmt.Errorf("failed to open event segment: %w", err)
+				}
+				s.size++
+			}
+		}
+	}
+	return nil
+}
+
+// rotate closes the segment being written; the next append starts another
+func (s *EventStore) rotate() error {
+	err := s.file.Close()
+	s.file, s.size = nil, 0
+	if err != nil {
+		return fmt.Errorf("failed to close event segment: %w", err)
+	}
+	if err := s.saveRollups(); err != nil {
+		return err
+	}
+	_, err = s.applyRetention(time.Now())
+	return err
+}
+
+func (s *EventStore) applyRetention(now time.Time) (int, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return 0, err
+	}
+	if s.file != nil && len(segments) > 0 {
+		// Never remove the segment being written
+		segments = segments[:len(segments)-1]
+	}
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+
+	removed := 0
+	for _, seg := range segments {
+		expired := s.retention.MaxAge > 0 && now.Sub(seg.modified) > s.retention.MaxAge
+		over := s.retention.MaxBytes > 0 && total+s.size > s.retention.MaxBytes
+		if !expired && !over {
+			break
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return removed, fmt.Errorf("failed to remove event segment: %w", err)
+		}
+		total -= seg.size
+		removed++
+	}
+
+	changed := pruneRollups(s.hourly, s.retention.HourlyMaxAge, now)
+	if pruneRollups(s.daily, s.retention.DailyMaxAge, now) {
+		changed = true
+	}
+	if changed {
+		return removed, s.saveRollups()
+	}
+	return removed, nil
+}
+
+func pruneRollups(table map[rollupKey]*Rollup, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	changed := false
+	for key, r := range table {
+		if now.Sub(r.Start) > maxAge {
+			delete(table, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s *EventStore) addRollups(ev *StoredEvent) {
+	local := ev.Time.Local()
+	hour := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, local.Location())
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	addRollup(s.hourly, RollupHour, hour, ev)
+	addRollup(s.daily, RollupDay, day, ev)
+}
+
+func addRollup(table map[rollupKey]*Rollup, period string, start time.Time, ev *StoredEvent) {
+	key := rollupKey{start.Unix(), ev.Source, ev.Type, ev.PluginID}
+	r, ok := table[key]
+	if !ok {
+		r = &Rollup{Period: period, Start: start, Source: ev.Source, Type: ev.Type, PluginID: ev.PluginID}
+		table[key] = r
+	}
+	r.Count++
+	if ev.Failed() {
+		r.Failures++
+	}
+	r.TotalDuration += ev.Duration
+	if ev.Duration > r.MaxDuration {
+		r.MaxDuration = ev.Duration
+	}
+}
+
+func (s *EventStore) loadRollups() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, rollupsFileName))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event rollups: %w", err)
+	}
+	var rf rollupsFile
+	if err := json.Unmarshal(data, &rf); err != nil {
+		return 0, fmt.Errorf("failed to read event rollups: %w", err)
+	}
+	if rf.Format > rollupsFormat {
+		return 0, fmt.Errorf("event rollups have format %d, newer than this editor supports", rf.Format)
+	}
+	for _, r := range rf.Hourly {
+		s.hourly[rollupKey{r.Start.Unix(), r.Source, r.Type, r.PluginID}] = r
+	}
+	for _, r := range rf.Daily {
+		s.daily[rollupKey{r.Start.Unix(), r.Source, r.Type, r.PluginID}] = r
+	}
+	return rf.Through, nil
+}
+
+func (s *EventStore) saveRollups() error {
+	rf := rollupsFile{Format: rollupsFormat, Through: s.nextSeq - 1}
+	for _, r := range s.hourly {
+		rf.Hourly = append(rf.Hourly, r)
+	}
+	for _, r := range s.daily {
+		rf.Daily = append(rf.Daily, r)
+	}
+	sortRollupPtrs(rf.Hourly)
+	sortRollupPtrs(rf.Daily)
+	data, err := json.MarshalIndent(rf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, rollupsFileName), data)
+}
+
+func rollupLess(a, b *Rollup) bool {
+	if !a.Start.Equal(b.Start) {
+		return a.Start.Before(b.Start)
+	}
+	if a.PluginID != b.PluginID {
+		return a.PluginID < b.PluginID
+	}
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	return a.Type < b.Type
+}
+
+func sortRollups(list []Rollup) {
+	sort.Slice(list, func(i, j int) bool { return rollupLess(&list[i], &list[j]) })
+}
+
+func sortRollupPtrs(list []*Rollup) {
+	sort.Slice(list, func(i, j int) bool { return rollupLess(list[i], list[j]) })
+}
+
+// segmentInfo describes one segment file
+type segmentInfo struct {
+	path     string
+	first    int64
+	size     int64
+	modified time.Time
+}
+
+// segments lists the segment files, oldest first
+func (s *EventStore) segments() ([]segmentInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event store: %w", err)
+	}
+	var list []segmentInfo
+	for _, entry := range entries {
+		var first int64
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		if _, err := fmt.Sscanf(entry.Name(), segmentPattern, &first); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event store: %w", err)
+		}
+		list = append(list, segmentInfo{
+			path:     filepath.Join(s.dir, entry.Name()),
+			first:    first,
+			size:     info.Size(),
+			modified: info.ModTime(),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].first < list[j].first })
+	return list, nil
+}
+
+// readSegment calls fn for every event in a segment until it returns
+// false. A line that does not decode, such as one cut short by a crash, is
+// skipped.
+func readSegment(path string, fn func(*StoredEvent) bool) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read event segment: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var ev StoredEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if !fn(&ev) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event segment: %w", err)
+	}
+	return nil
+}
+
+// OpenEventStore opens the event store under <pluginDir>/.events with
+// DefaultRetention and makes the analytics engine, audit logger and
+// profiler write to it
+func (m *Manager) OpenEventStore() (*EventStore, error) {
+	store, err := OpenEventStore(filepath.Join(m.pluginDir, eventStoreDirName), 0, DefaultRetention)
+	if err != nil {
+		return nil, err
+	}
+	m.SetEventStore(store)
+	return store, nil
+}
+
+// SetEventStore makes the analytics engine, audit logger and profiler write
+// to store; nil stops them. Their writes are best effort: an append that
+// fails, say on a full disk, is dropped rather than failing the plugin run
+// or operation that produced the event.
+func (m *Manager) SetEventStore(store *EventStore) {
+	m.mu.Lock()
+	m.eventStore = store
+	m.mu.Unlock()
+	m.analytics.SetEventStore(store)
+	m.auditLogger.SetEventStore(store)
+	m.profiler.SetEventStore(store)
+}
+
+// GetEventStore returns the store set with SetEventStore, or nil
+func (m *Manager) GetEventStore() *EventStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.eventStore
+}
@@ -0,0 +1,246 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestEventStore(t *testing.T, dir string, segmentBytes int64, retention RetentionPolicy) *EventStore {
+	t.Helper()
+	s, err := OpenEventStore(dir, segmentBytes, retention)
+	if err != nil {
+		t.Fatalf("OpenEventStore failed: %v", err)
+	}
+	return s
+}
+
+func appendTestEvent(t *testing.T, s *EventStore, ev StoredEvent) {
+	t.Helper()
+	if err := s.Append(ev); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+}
+
+// TestEventStoreQueryAcrossRestarts tests events survive reopening and are
+// selected by plugin, type, time and limit
+func TestEventStoreQueryAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+
+	s := openTestEventStore(t, dir, 0, RetentionPolicy{})
+	appendTestEvent(t, s, StoredEvent{Time: base, Source: EventSourceAnalytics, Type: "plugin_load", PluginID: "a"})
+	appendTestEvent(t, s, StoredEvent{Time: base.Add(time.Minute), Source: EventSourceAnalytics, Type: "plugin_execute", PluginID: "a", Status: "success", Duration: 20 * time.Millisecond})
+	appendTestEvent(t, s, StoredEvent{Time: base.Add(2 * time.Minute), Source: EventSourceAudit, Type: "execute", PluginID: "b", Status: "error", Error: "boom"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := s.Append(StoredEvent{Type: "late"}); !errors.Is(err, ErrEventStoreClosed) {
+		t.Errorf("Append after Close = %v, want ErrEventStoreClosed", err)
+	}
+
+	s = openTestEventStore(t, dir, 0, RetentionPolicy{})
+	defer s.Close()
+	appendTestEvent(t, s, StoredEvent{Time: base.Add(3 * time.Minute), Source: EventSourceAnalytics, Type: "plugin_execute", PluginID: "a", Status: "error"})
+
+	all, err := s.Query(EventQuery{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(all) != 4 || all[3].Seq != 4 {
+		t.Fatalf("got %d events (last seq %d), want 4 numbered on from the first session", len(all), all[len(all)-1].Seq)
+	}
+
+	for name, tc := range map[string]struct {
+		q    EventQuery
+		want int
+	}{
+		"plugin":  {EventQuery{PluginID: "a"}, 3},
+		"source":  {EventQuery{Source: EventSourceAudit}, 1},
+		"types":   {EventQuery{Types: []string{"plugin_execute"}}, 2},
+		"range":   {EventQuery{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)}, 2},
+		"limit":   {EventQuery{PluginID: "a", Limit: 2}, 2},
+		"nothing": {EventQuery{PluginID: "c"}, 0},
+	} {
+		got, err := s.Query(tc.q)
+		if err != nil {
+			t.Fatalf("%s: Query failed: %v", name, err)
+		}
+		if len(got) != tc.want {
+			t.Errorf("%s: got %d events, want %d", name, len(got), tc.want)
+		}
+	}
+	if got, _ := s.Query(EventQuery{PluginID: "a", Limit: 1}); len(got) != 1 || got[0].Seq != 4 {
+		t.Errorf("Limit did not keep the newest event: %+v", got)
+	}
+}
+
+// TestEventStoreRollups tests hourly and daily rollups, including events
+// appended after the rollups were last saved
+func TestEventStoreRollups(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 3, 1, 10, 15, 0, 0, time.Local)
+
+	s := openTestEventStore(t, dir, 0, RetentionPolicy{})
+	for i, status := range []string{"success", "success", "error"} {
+		appendTestEvent(t, s, StoredEvent{
+			Time: base.Add(time.Duration(i) * time.Hour), Source: EventSourceAnalytics,
+			Type: "plugin_execute", PluginID: "a", Status: status, Duration: time.Duration(i+1) * 10 * time.Millisecond,
+		})
+	}
+	// No Close: the rollups on disk only count what was there at open
+	s = openTestEventStore(t, dir, 0, RetentionPolicy{})
+	defer s.Close()
+
+	hourly, err := s.Rollups(RollupHour, EventQuery{PluginID: "a"})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	if hour := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local); len(hourly) != 3 || !hourly[0].Start.Equal(hour) {
+		t.Fatalf("hourly = %+v, want 3 rollups from %s", hourly, hour)
+	}
+	daily, err := s.Rollups(RollupDay, EventQuery{Source: EventSourceAnalytics})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	if len(daily) != 1 {
+		t.Fatalf("got %d daily rollups, want 1", len(daily))
+	}
+	d := daily[0]
+	if d.Count != 3 || d.Failures != 1 || d.MaxDuration != 30*time.Millisecond || d.AverageDuration() != 20*time.Millisecond {
+		t.Errorf("daily rollup = %+v", d)
+	}
+	if _, err := s.Rollups("week", EventQuery{}); err == nil {
+		t.Error("Rollups accepted an unknown period")
+	}
+}
+
+// TestEventStoreRollupsHalfHourZone tests hourly rollups start on the local
+// hour in a zone whose offset is not whole hours
+func TestEventStoreRollupsHalfHourZone(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("IST", 5*3600+30*60)
+	defer func() { time.Local = local }()
+
+	s := openTestEventStore(t, t.TempDir(), 0, RetentionPolicy{})
+	defer s.Close()
+	appendTestEvent(t, s, StoredEvent{
+		Time: time.Date(2026, 3, 1, 10, 45, 0, 0, time.Local), Source: EventSourceAnalytics, Type: "plugin_execute",
+	})
+
+	hourly, err := s.Rollups(RollupHour, EventQuery{})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	if want := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local); len(hourly) != 1 || !hourly[0].Start.Equal(want) {
+		t.Errorf("hourly = %+v, want one rollup from %s", hourly, want)
+	}
+}
+
+// TestEventStoreRotationAndRetention tests segments rotate by size and old
+// ones are removed while rollups are kept
+func TestEventStoreRotationAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestEventStore(t, dir, 200, RetentionPolicy{})
+	for i := 0; i < 10; i++ {
+		appendTestEvent(t, s, StoredEvent{Source: EventSourceAudit, Type: "load", PluginID: "a", Status: "success"})
+	}
+	segments, _ := s.segments()
+	if len(segments) < 3 {
+		t.Fatalf("got %d segments, want the log rotated", len(segments))
+	}
+	if all, _ := s.Query(EventQuery{}); len(all) != 10 {
+		t.Fatalf("got %d events across segments, want 10", len(all))
+	}
+
+	// Age every closed segment past MaxAge
+	old := time.Now().Add(-48 * time.Hour)
+	for _, seg := range segments[:len(segments)-1] {
+		os.Chtimes(seg.path, old, old)
+	}
+	s.retention = RetentionPolicy{MaxAge: 24 * time.Hour}
+	removed, err := s.ApplyRetention(time.Now())
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if removed != len(segments)-1 {
+		t.Errorf("removed %d segments, want %d", removed, len(segments)-1)
+	}
+	left, _ := s.Query(EventQuery{})
+	if len(left) == 0 || len(left) >= 10 {
+		t.Errorf("%d events left, want only those in the segment being written", len(left))
+	}
+	if daily, _ := s.Rollups(RollupDay, EventQuery{}); len(daily) != 1 || daily[0].Count != 10 {
+		t.Errorf("rollups lost with the raw events: %+v", daily)
+	}
+
+	s.retention = RetentionPolicy{HourlyMaxAge: time.Hour}
+	if _, err := s.ApplyRetention(time.Now().Add(3 * time.Hour)); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if hourly, _ := s.Rollups(RollupHour, EventQuery{}); len(hourly) != 0 {
+		t.Errorf("hourly rollups past HourlyMaxAge kept: %+v", hourly)
+	}
+	if daily, _ := s.Rollups(RollupDay, EventQuery{}); len(daily) != 1 {
+		t.Error("daily rollups removed without a DailyMaxAge")
+	}
+	s.Close()
+}
+
+// TestEventStoreTornLine tests a line cut short by a crash is skipped
+func TestEventStoreTornLine(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestEventStore(t, dir, 0, RetentionPolicy{})
+	appendTestEvent(t, s, StoredEvent{Source: EventSourceAudit, Type: "load", PluginID: "a"})
+	s.Close()
+
+	segments, _ := s.segments()
+	f, err := os.OpenFile(segments[0].path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":2,"time":"2026-`)
+	f.Close()
+
+	s = openTestEventStore(t, dir, 0, RetentionPolicy{})
+	defer s.Close()
+	appendTestEvent(t, s, StoredEvent{Source: EventSourceAudit, Type: "unload", PluginID: "a"})
+	all, _ := s.Query(EventQuery{})
+	if len(all) != 2 || all[1].Type != "unload" {
+		t.Errorf("got events %+v, want the one before the crash and the one after", all)
+	}
+}
+
+// TestManagerEventStore tests the manager's components write to the store
+func TestManagerEventStore(t *testing.T) {
+	m, pluginDir := installSettingsPlugin(t)
+	store, err := m.OpenEventStore()
+	if err != nil {
+		t.Fatalf("OpenEventStore failed: %v", err)
+	}
+	defer store.Close()
+	if filepath.Dir(store.Dir()) != filepath.Dir(pluginDir) {
+		t.Errorf("store opened in %s, want under the plugin directory", store.Dir())
+	}
+	if _, err := m.LoadPlugin(context.Background(), pluginDir); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	m.GetProfiler().RecordExecution("tracker", time.Millisecond, true, nil)
+
+	for _, source := range []string{EventSourceAnalytics, EventSourceAudit, EventSourceProfiler} {
+		events, err := store.Query(EventQuery{Source: source, PluginID: "tracker"})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(events) == 0 {
+			t.Errorf("no %s events stored", source)
+		}
+	}
+	audit, _ := store.Query(EventQuery{Source: EventSourceAudit, Types: []string{"load"}})
+	if len(audit) != 1 || audit[0].Data["action"] != "LOAD" || audit[0].Data["event_id"] == nil {
+		t.Errorf("stored audit event = %+v", audit)
+	}
+}
@@ -273,6 +273,15 @@ func (h *HotReloadManager) SnapshotState(pluginID string) (*PluginState, error)
 	}
 	h.pluginManager.mu.RUnlock()
 
+	// Keep the plugin's stored data so a reload or rollback cannot lose it
+	if storage := h.pluginManager.storage; storage != nil {
+		snap, err := storage.Snapshot(pluginID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot plugin storage: %w", err)
+		}
+		state.Storage = snap
+	}
+
 	return state, nil
 }
 
@@ -303,20 +312,46 @@ func (h *HotReloadManager) RestoreState(pluginID string, state *PluginState) err
 	}
 	h.pluginManager.mu.Unlock()
 
+	if storage := h.pluginManager.storage; storage != nil && state.Storage != nil {
+		if err := storage.Restore(pluginID, state.Storage); err != nil {
+			return fmt.Errorf("failed to restore plugin storage: %w", err)
+		}
+	}
+
 	// Note: execution history is not restored as it's append-only
 	return nil
 }
 
-// RollbackPlugin rolls back a plugin to a previous version
+// RollbackPlugin rolls back a plugin to a previous version. If an install
+// transaction kept previousVersion on disk, its files are swapped back in
+// before the state snapshot is restored.
 func (h *HotReloadManager) RollbackPlugin(pluginID, previousVersion string) error {
+	restoredFiles := false
+	if kept, ok := h.pluginManager.PreviousVersion(pluginID); ok && (previousVersion == "" || kept == previousVersion) {
+		if err := h.pluginManager.RestorePreviousVersion(pluginID); err != nil {
+			return fmt.Errorf("failed to restore previous version: %w", err)
+		}
+		restoredFiles = true
+	}
+
 	h.mu.RLock()
 	state, ok := h.stateSnapshots[pluginID]
 	h.mu.RUnlock()
 
 	if !ok {
+		if restoredFiles {
+			return nil
+		}
 		return fmt.Errorf("no snapshot available for rollback")
 	}
 
+	if restoredFiles {
+		// The plugin may not be loaded; the files are what matter then
+		if _, err := h.pluginManager.GetPlugin(pluginID); err != nil {
+			return nil
+		}
+	}
+
 	// Try to restore from snapshot
 	if err := h.RestoreState(pluginID, state); err != nil {
 		return fmt.Errorf("failed to restore state: %w", err)
@@ -0,0 +1,248 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SignaturePolicy controls what happens to unsigned or tampered packages
+type SignaturePolicy int
+
+const (
+	// SignaturePolicyRequire refuses packages that fail verification
+	SignaturePolicyRequire SignaturePolicy = iota
+	// SignaturePolicyQuarantine moves packages that fail verification to the
+	// quarantine directory instead of installing them
+	SignaturePolicyQuarantine
+	// SignaturePolicyWarn installs packages that fail verification but
+	// records the failure in the audit log
+	SignaturePolicyWarn
+)
+
+// String returns the policy name
+func (p SignaturePolicy) String() string {
+	switch p {
+	case SignaturePolicyRequire:
+		return "require"
+	case SignaturePolicyQuarantine:
+		return "quarantine"
+	case SignaturePolicyWarn:
+		return "warn"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSignaturePolicy converts a policy name to a SignaturePolicy
+func ParseSignaturePolicy(name string) (SignaturePolicy, error) {
+	switch name {
+	case "require", "":
+		return SignaturePolicyRequire, nil
+	case "quarantine":
+		return SignaturePolicyQuarantine, nil
+	case "warn":
+		return SignaturePolicyWarn, nil
+	default:
+		return SignaturePolicyRequire, fmt.Errorf("unknown signature policy: %s (valid: require, quarantine, warn)", name)
+	}
+}
+
+// quarantineDirName is where rejected packages are kept, inside the plugin directory
+const quarantineDirName = ".quarantine"
+
+// SetSignaturePolicy sets the policy for unsigned or tampered packages
+func (m *Manager) SetSignaturePolicy(policy SignaturePolicy) {
+	m.mu.Lock()
+	m.signaturePolicy = policy
+	m.mu.Unlock()
+}
+
+// GetSignaturePolicy returns the current signature policy
+func (m *Manager) GetSignaturePolicy() SignaturePolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.signaturePolicy
+}
+
+// QuarantineDir returns the directory holding quarantined packages
+func (m *Manager) QuarantineDir() string {
+	return filepath.Join(m.pluginDir, quarantineDirName)
+}
+
+// InstallPackage verifies a plugin package and unpacks it into the plugin
+// directory as <pluginDir>/<id>. Packages that fail verification are
+// refused, quarantined or installed with a warning depending on the policy.
+func (m *Manager) InstallPackage(ctx context.Context, data []byte) (*PackageManifest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pkg, verifyErr := m.verifyPackageData(data)
+	pluginID := "unknown"
+	if pkg != nil {
+		pluginID = pkg.Manifest.PluginID
+	}
+
+	if verifyErr != nil {
+		if err := m.applySignaturePolicy(pluginID, data, verifyErr); err != nil {
+			return nil, err
+		}
+		if pkg == nil {
+			// Warn policy, but the package could not even be parsed
+			return nil, verifyErr
+		}
+	}
+
+	dest := filepath.Join(m.pluginDir, pkg.Manifest.PluginID)
+	if err := os.RemoveAll(dest); err != nil {
+		return nil, fmt.Errorf("failed to remove previous install: %w", err)
+	}
+	if err := pkg.Extract(dest); err != nil {
+		return nil, err
+	}
+
+	m.logPackageEvent(pluginID, "INSTALL_PACKAGE", "success", "", map[string]interface{}{
+		"version": pkg.Manifest.Version,
+		"signed":  pkg.IsSigned(),
+	})
+	return pkg.Manifest, nil
+}
+
+// VerifyInstalledPlugin re-checks an installed plugin directory against its
+// manifest and signature
+func (m *Manager) VerifyInstalledPlugin(dir string) (*PackageManifest, error) {
+	pkg, err := ReadInstalledPackage(dir)
+	if err == nil {
+		err = m.securityMgr.VerifyPackage(pkg)
+	}
+
+	pluginID := filepath.Base(dir)
+	if pkg != nil {
+		pluginID = pkg.Manifest.PluginID
+	}
+	if err != nil {
+		m.logPackageEvent(pluginID, "VERIFY_INSTALLED", "denied", err.Error(), nil)
+		if pkg == nil {
+			return nil, err
+		}
+		return pkg.Manifest, err
+	}
+
+	m.logPackageEvent(pluginID, "VERIFY_INSTALLED", "success", "", map[string]interface{}{
+		"version": pkg.Manifest.Version,
+	})
+	return pkg.Manifest, nil
+}
+
+// verifyPackageData parses a package and checks its signature. The parsed
+// package is returned whenever parsing succeeded, even if verification failed.
+func (m *Manager) verifyPackageData(data []byte) (*PluginPackage, error) {
+	pkg, err := ReadPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.securityMgr.VerifyPackage(pkg); err != nil {
+		return pkg, err
+	}
+	return pkg, nil
+}
+
+// applySignaturePolicy handles a package that failed verification. It
+// returns nil only if the package may be installed anyway.
+func (m *Manager) applySignaturePolicy(pluginID string, data []byte, verifyErr error) error {
+	switch m.GetSignaturePolicy() {
+	case SignaturePolicyWarn:
+		m.logPackageEvent(pluginID, "VERIFY_PACKAGE", "warning", verifyErr.Error(), map[string]interface{}{
+			"policy": SignaturePolicyWarn.String(),
+		})
+		return nil
+
+	case SignaturePolicyQuarantine:
+		path, err := m.quarantine(pluginID, data)
+		if err != nil {
+			return fmt.Errorf("%w (quarantine failed: %v)", verifyErr, err)
+		}
+		m.logPackageEvent(pluginID, "QUARANTINE_PACKAGE", "denied", verifyErr.Error(), map[string]interface{}{
+			"policy": SignaturePolicyQuarantine.String(),
+			"path":   path,
+		})
+		return fmt.Errorf("%w: %s: %v", ErrPluginQuarantined, path, verifyErr)
+
+	default:
+		if m.auditLogger != nil {
+			m.auditLogger.LogSecurityViolation(pluginID, "package_verification_failed", verifyErr.Error())
+		}
+		return verifyErr
+	}
+}
+
+// quarantine stores a rejected package for later inspection
+func (m *Manager) quarantine(pluginID string, data []byte) (string, error) {
+	dir := m.QuarantineDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.zip", pluginID, time.Now().Format("20060102-150405.000000000")))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// verifyPluginDirForLoad checks an installed plugin before it is loaded,
+// applying the signature policy. Quarantine moves the directory aside.
+func (m *Manager) verifyPluginDirForLoad(dir string) (*PluginMetadata, error) {
+	pkg, err := ReadInstalledPackage(dir)
+	if err == nil {
+		err = m.securityMgr.VerifyPackage(pkg)
+	}
+	if err == nil {
+		return pkg.Metadata, nil
+	}
+
+	pluginID := filepath.Base(dir)
+	if pkg != nil {
+		pluginID = pkg.Manifest.PluginID
+	}
+
+	switch m.signaturePolicy {
+	case SignaturePolicyWarn:
+		m.logPackageEvent(pluginID, "VERIFY_INSTALLED", "warning", err.Error(), nil)
+		if pkg != nil {
+			return pkg.Metadata, nil
+		}
+		// No usable manifest; fall back to metadata.json alone
+		files, readErr := readPackageDir(dir)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return parsePackageMetadata(files)
+
+	case SignaturePolicyQuarantine:
+		target := filepath.Join(m.QuarantineDir(), fmt.Sprintf("%s-%s", pluginID, time.Now().Format("20060102-150405.000000000")))
+		if mkErr := os.MkdirAll(m.QuarantineDir(), 0755); mkErr == nil {
+			if mvErr := os.Rename(dir, target); mvErr == nil {
+				m.logPackageEvent(pluginID, "QUARANTINE_PACKAGE", "denied", err.Error(), map[string]interface{}{"path": target})
+				return nil, fmt.Errorf("%w: %s: %v", ErrPluginQuarantined, target, err)
+			}
+		}
+		fallthrough
+
+	default:
+		if m.auditLogger != nil {
+			m.auditLogger.LogSecurityViolation(pluginID, "package_verification_failed", err.Error())
+		}
+		return nil, fmt.Errorf("plugin package verification failed: %w", err)
+	}
+}
+
+// logPackageEvent records a package install or verification outcome
+func (m *Manager) logPackageEvent(pluginID, action, status, errMsg string, details map[string]interface{}) {
+	if m.auditLogger == nil {
+		return
+	}
+	m.auditLogger.LogEvent(pluginID, "package", action, "", status, errMsg, 0, details)
+}
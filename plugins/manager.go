@@ -3,6 +3,10 @@ package plugins
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -30,7 +34,22 @@ type Manager struct {
 	securityMgr        *SecurityManager
 	auditLogger        *AuditLogger
 	sandboxMgr         *SandboxManager
-}
+	storage            *StorageManager
+	events             *EventBus
+	eventStore         *EventStore
+	auditChain         *AuditChain
+	runner             PluginRunner
+	onChange           func()
+	signaturePolicy    SignaturePolicy
+	installMu          sync.Mutex // serializes install transactions
+	settingsMu         sync.Mutex // serializes settings writes
+}
+
+// PluginRunner runs a loaded plugin's Lua code, calling the entry function
+// of the module it returns when entry is set. scripting.NewPluginRunner
+// provides one that applies the plugin's SandboxPolicy; this package cannot
+// import the Lua runtime itself.
+type PluginRunner func(ctx context.Context, plugin *Plugin, entry string) error
 
 // NewManager creates a new plugin manager
 func NewManager(pluginDir string, api PluginAPI) *Manager {
@@ -56,7 +75,14 @@ func NewManager(pluginDir string, api PluginAPI) *Manager {
 		securityMgr:        NewSecurityManager(),
 		auditLogger:        NewAuditLogger(10000), // Store last 10000 audit events
 		sandboxMgr:         NewSandboxManager(),
+		storage:            NewStorageManager(filepath.Join(pluginDir, storageDirName)),
+		events:             NewEventBus(DefaultReplaySize),
 	}
+	m.events.SetErrorHandler(func(sub *Subscription, ev *Event, err error) {
+		if m.auditLogger != nil {
+			m.auditLogger.LogError(sub.Subscriber, fmt.Sprintf("event %s (#%d): %v", ev.Topic, ev.Seq, err))
+		}
+	})
 
 	// Initialize hot-reload manager
 	hotReload, err := NewHotReloadManager(m)
@@ -71,6 +97,15 @@ func NewManager(pluginDir string, api PluginAPI) *Manager {
 
 // LoadPlugin loads a plugin from file
 func (m *Manager) LoadPlugin(ctx context.Context, path string) (*Plugin, error) {
+	plugin, err := m.loadPlugin(path)
+	if err == nil {
+		m.notifyChange()
+	}
+	return plugin, err
+}
+
+// loadPlugin is LoadPlugin without the change notification
+func (m *Manager) loadPlugin(path string) (*Plugin, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -78,6 +113,16 @@ func (m *Manager) LoadPlugin(ctx context.Context, path string) (*Plugin, error)
 		return nil, ErrMaxPluginsExceeded
 	}
 
+	// Installed packages are directories carrying a signed manifest; they
+	// are re-verified on every load so edits after install are caught
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		metadata, err := m.verifyPluginDirForLoad(path)
+		if err != nil {
+			return nil, err
+		}
+		return m.loadPluginLocked(path, metadata)
+	}
+
 	// Parse plugin metadata (placeholder for now)
 	metadata := &PluginMetadata{
 		ID:      "plugin_" + fmt.Sprintf("%d", time.Now().Unix()),
@@ -113,8 +158,43 @@ func (m *Manager) LoadPlugin(ctx context.Context, path string) (*Plugin, error)
 		}
 	}
 
-	// Create plugin instance
-	plugin := NewPlugin(metadata, m.api)
+	return m.loadPluginLocked(path, metadata)
+}
+
+// loadPluginLocked creates and initializes a verified plugin; the caller must hold m.mu
+func (m *Manager) loadPluginLocked(path string, metadata *PluginMetadata) (*Plugin, error) {
+	if err := metadata.Validate(); err != nil {
+		return nil, err
+	}
+	if _, exists := m.plugins[metadata.ID]; exists {
+		return nil, ErrPluginAlreadyLoaded
+	}
+	if err := checkDeclarations(metadata); err != nil {
+		return nil, err
+	}
+	if err := m.registerEventSchemas(metadata); err != nil {
+		return nil, err
+	}
+	saved, err := m.readSavedSettings(metadata.ID)
+	if err != nil && m.auditLogger != nil {
+		m.auditLogger.LogError(metadata.ID, err.Error())
+	}
+
+	// Create plugin instance. The editor's API is narrowed to the
+	// permissions the plugin declared; plugins declaring none get the defaults.
+	api := m.api
+	if impl, ok := api.(*APIImpl); ok {
+		perms := metadata.Permissions
+		if len(perms) == 0 {
+			perms = m.defaultPerm
+		}
+		scoped := impl.ForPlugin(metadata.ID, perms, m.auditLogger)
+		scoped.SetStorageManager(m.storage)
+		scoped.SetEventBus(m.events)
+		scoped.SetSettingsBackend(m)
+		api = scoped
+	}
+	plugin := NewPlugin(metadata, api)
 
 	// Set the plugin path and metadata
 	plugin.SetPath(path)
@@ -137,7 +217,7 @@ func (m *Manager) LoadPlugin(ctx context.Context, path string) (*Plugin, error)
 		Author:      metadata.Author,
 		Description: metadata.Description,
 		Enabled:     true,
-		Settings:    make(map[string]interface{}),
+		Settings:    metadata.Settings.Resolve(saved),
 		Hooks:       metadata.Hooks,
 		Permissions: metadata.Permissions,
 	}
@@ -172,6 +252,7 @@ func (m *Manager) LoadPlugin(ctx context.Context, path string) (*Plugin, error)
 			MaxMemoryMB:        100,
 			MaxCPUPercent:      50,
 			TimeoutSeconds:     30,
+			MaxInstructions:    DefaultMaxInstructions,
 			IsolationLevel:     "basic",
 			IsActive:           true,
 		}
@@ -206,6 +287,9 @@ func (m *Manager) UnloadPlugin(ctx context.Context, pluginID string) error {
 		m.dependencyResolver.RemovePlugin(pluginID)
 	}
 
+	// Drop the plugin's event subscriptions
+	m.events.UnsubscribeAll(pluginID)
+
 	// Stop watching for hot-reload
 	if m.hotReloadManager != nil {
 		m.hotReloadManager.UnwatchPlugin(pluginID)
@@ -231,6 +315,7 @@ func (m *Manager) UnloadPlugin(ctx context.Context, pluginID string) error {
 		m.sandboxMgr.RemovePolicy(pluginID)
 	}
 
+	m.notifyChange()
 	return nil
 }
 
@@ -347,10 +432,14 @@ func (m *Manager) ExecutePlugin(ctx context.Context, pluginID string) error {
 	execCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	// Execute plugin with hook - timeout enforced via execCtx
-	// Note: CallHook signature could be updated to accept context parameter for cleaner async support
-	// Currently context enforced at callsite level via context.WithTimeout
-	if err := plugin.CallHook(HookLoad); err != nil {
+	// Lua plugins run through the runner, which enforces the sandbox
+	// policy's limits; without one only the plugin's Go hook is called
+	if runner := m.getRunner(); runner != nil {
+		err = runner(execCtx, plugin, "")
+	} else {
+		err = plugin.CallHook(HookLoad)
+	}
+	if err != nil {
 		// Track error if context was cancelled due to timeout
 		if execCtx.Err() == context.DeadlineExceeded {
 			record.Error = "plugin execution timeout"
@@ -365,6 +454,37 @@ func (m *Manager) ExecutePlugin(ctx context.Context, pluginID string) error {
 	return nil
 }
 
+// SetChangeHandler sets a function called after a plugin is loaded,
+// unloaded, enabled or disabled, so the GUI can rebuild what plugins
+// contribute. It is called without the manager's lock held.
+func (m *Manager) SetChangeHandler(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = fn
+}
+
+func (m *Manager) notifyChange() {
+	m.mu.RLock()
+	fn := m.onChange
+	m.mu.RUnlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// SetRunner sets how ExecutePlugin and InvokeCommand run plugin code
+func (m *Manager) SetRunner(runner PluginRunner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runner = runner
+}
+
+func (m *Manager) getRunner() PluginRunner {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.runner
+}
+
 // CallHook fires a hook for all enabled plugins
 func (m *Manager) CallHook(ctx context.Context, hookType HookType, args ...interface{}) error {
 	m.mu.RLock()
@@ -388,6 +508,65 @@ func (m *Manager) CallHook(ctx context.Context, hookType HookType, args ...inter
 		}
 	}
 
+	m.publishHookEvent(ctx, hookType, args)
+	return nil
+}
+
+// publishHookEvent publishes the built-in bus event for an editor hook.
+// Opening a save also makes it the scope of per-save plugin storage, and
+// opening or writing one records its hash in the audit log.
+func (m *Manager) publishHookEvent(ctx context.Context, hookType HookType, args []interface{}) {
+	var first interface{}
+	if len(args) > 0 {
+		first = args[0]
+	}
+	switch hookType {
+	case HookSaveOpen:
+		path, _ := first.(string)
+		m.storage.SetSaveScope(path)
+		m.auditLogger.LogSaveFile(path, "OPEN")
+		m.Emit(ctx, TopicSaveOpened, map[string]interface{}{"path": path})
+	case HookSaveSave:
+		path, _ := first.(string)
+		m.auditLogger.LogSaveFile(path, "WRITE")
+		m.Emit(ctx, TopicSaveSaved, map[string]interface{}{"path": path})
+	case HookCharEdit:
+		id, _ := first.(int)
+		payload := map[string]interface{}{"id": id, "by": EditorSource}
+		if len(args) > 1 {
+			if name, ok := args[1].(string); ok {
+				payload["name"] = name
+			}
+		}
+		m.Emit(ctx, TopicCharacterEdited, payload)
+	}
+}
+
+// Emit publishes an editor event to every subscribed plugin
+func (m *Manager) Emit(ctx context.Context, topic string, payload interface{}) error {
+	return m.events.Publish(ctx, EditorSource, topic, payload)
+}
+
+// registerEventSchemas registers the payload schemas a plugin declares for
+// the topics it publishes
+func (m *Manager) registerEventSchemas(metadata *PluginMetadata) error {
+	topics := make([]string, 0, len(metadata.Events))
+	for topic := range metadata.Events {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		schema, err := ParseEventSchema(metadata.Events[topic])
+		if err != nil {
+			return fmt.Errorf("plugin %s event %s: %w", metadata.ID, topic, err)
+		}
+		if reservedNamespaces[strings.SplitN(topic, ".", 2)[0]] {
+			return fmt.Errorf("plugin %s event %s: %w", metadata.ID, topic, ErrTopicReserved)
+		}
+		if err := m.events.RegisterSchema(topic, schema); err != nil {
+			return fmt.Errorf("plugin %s: %w", metadata.ID, err)
+		}
+	}
 	return nil
 }
 
@@ -404,19 +583,38 @@ func (m *Manager) GetConfig(pluginID string) (PluginConfig, error) {
 	return config, nil
 }
 
-// SetConfig sets plugin configuration
+// SetConfig sets plugin configuration. Settings are checked against the
+// plugin's settings schema and saved, so they survive a restart.
 func (m *Manager) SetConfig(pluginID string, config PluginConfig) error {
 	if err := ValidatePluginConfig(config); err != nil {
 		return err
 	}
 
-	m.mu.Lock()
-	if _, exists := m.plugins[pluginID]; !exists {
-		m.mu.Unlock()
+	m.mu.RLock()
+	plugin, exists := m.plugins[pluginID]
+	m.mu.RUnlock()
+	if !exists {
 		return ErrPluginNotFound
 	}
 
-	m.configs[pluginID] = config
+	schema := plugin.GetMetadata().Settings
+	settings, err := schema.Validate(config.Settings)
+	if err != nil {
+		return err
+	}
+
+	// The file is written without holding m.mu; settingsMu keeps the file
+	// and the in-memory config in the same order
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	if err := m.writeSavedSettings(pluginID, settings); err != nil {
+		return err
+	}
+	config.Settings = schema.Resolve(settings)
+	m.mu.Lock()
+	if _, exists := m.plugins[pluginID]; exists {
+		m.configs[pluginID] = config
+	}
 	m.mu.Unlock()
 
 	return nil
@@ -438,6 +636,7 @@ func (m *Manager) EnablePlugin(pluginID string) error {
 	m.configs[pluginID] = config
 	m.mu.Unlock()
 
+	m.notifyChange()
 	return nil
 }
 
@@ -457,6 +656,7 @@ func (m *Manager) DisablePlugin(pluginID string) error {
 	m.configs[pluginID] = config
 	m.mu.Unlock()
 
+	m.notifyChange()
 	return nil
 }
 
@@ -509,6 +709,16 @@ func (m *Manager) Stop(ctx context.Context) error {
 	m.mu.Unlock()
 
 	close(m.stopCh)
+	if store := m.GetEventStore(); store != nil {
+		if err := store.Flush(); err != nil && err != ErrEventStoreClosed {
+			return err
+		}
+	}
+	if chain := m.GetAuditChain(); chain != nil {
+		if err := chain.Checkpoint(); err != nil && err != ErrAuditChainClosed {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -519,8 +729,18 @@ func (m *Manager) syncLoop(ctx context.Context) {
 		case <-m.stopCh:
 			return
 		case <-m.syncTicker.C:
-			// Placeholder for periodic sync operations
-			// Could check for plugin updates, health checks, etc.
+			// Save the event store's rollups so a crash loses little
+			if store := m.GetEventStore(); store != nil {
+				if err := store.Flush(); err != nil && err != ErrEventStoreClosed {
+					fmt.Printf("Warning: Failed to flush plugin event store: %v\n", err)
+				}
+			}
+			// Sign what the audit chain gained since its last checkpoint
+			if chain := m.GetAuditChain(); chain != nil {
+				if err := chain.Checkpoint(); err != nil && err != ErrAuditChainClosed {
+					fmt.Printf("Warning: Failed to checkpoint plugin audit chain: %v\n", err)
+				}
+			}
 		}
 	}
 }
@@ -699,3 +919,13 @@ func (m *Manager) GetAuditLogger() *AuditLogger {
 func (m *Manager) GetSandboxManager() *SandboxManager {
 	return m.sandboxMgr
 }
+
+// GetStorageManager returns the per-plugin key-value storage
+func (m *Manager) GetStorageManager() *StorageManager {
+	return m.storage
+}
+
+// GetEventBus returns the event bus shared by all plugins
+func (m *Manager) GetEventBus() *EventBus {
+	return m.events
+}
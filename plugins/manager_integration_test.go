@@ -9,6 +9,7 @@ import (
 
 	"ffvi_editor/models"
 	modelsPR "ffvi_editor/models/pr"
+	"ffvi_editor/models/search"
 )
 
 // TestManagerIntegration tests the full plugin lifecycle with security, audit, and sandbox
@@ -399,6 +400,18 @@ func (api *testPluginAPI) FindItems(ctx context.Context, predicate func(*modelsP
 	return nil
 }
 
+func (api *testPluginAPI) Query(ctx context.Context, query string) (*search.QueryResult, error) {
+	return &search.QueryResult{}, nil
+}
+
+func (api *testPluginAPI) GetTreasures(ctx context.Context, filter modelsPR.TreasureFilter) ([]modelsPR.TreasureState, error) {
+	return nil, nil
+}
+
+func (api *testPluginAPI) SetTreasureOpened(ctx context.Context, id int, opened bool) error {
+	return nil
+}
+
 func (api *testPluginAPI) RegisterHook(event string, callback func(interface{}) error) error {
 	return nil
 }
@@ -431,6 +444,18 @@ func (api *testPluginAPI) SetSetting(key string, value interface{}) error {
 	return nil
 }
 
+func (api *testPluginAPI) Subscribe(ctx context.Context, pattern string, replay bool, handler EventHandler) (*Subscription, error) {
+	return nil, ErrEventBusUnavailable
+}
+
+func (api *testPluginAPI) Publish(ctx context.Context, topic string, payload interface{}) error {
+	return nil
+}
+
+func (api *testPluginAPI) Storage(ctx context.Context, perSave bool) (*KVStore, error) {
+	return nil, ErrStorageUnavailable
+}
+
 func (api *testPluginAPI) HasPermission(permission string) bool {
 	return true
 }
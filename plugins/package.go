@@ -0,0 +1,610 @@
+package plugins
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Plugin package layout
+//
+//	metadata.json   plugin metadata (id, version, dependencies, ...)
+//	*.lua           plugin sources
+//	assets/...      any other files
+//	MANIFEST.json   SHA-256 of every other file
+//	MANIFEST.sig    detached signature over MANIFEST.json (absent if unsigned)
+//
+// Installed plugins keep MANIFEST.json and MANIFEST.sig next to their files
+// so the same check runs again on every load.
+const (
+	PackageMetadataFile  = "metadata.json"
+	PackageManifestFile  = "MANIFEST.json"
+	PackageSignatureFile = "MANIFEST.sig"
+
+	// PackageSignatureAlgorithm is the only supported signature scheme
+	PackageSignatureAlgorithm = "rsa-pkcs1v15-sha256"
+
+	maxPackageSize     = 50 * 1024 * 1024
+	maxPackageFileSize = 10 * 1024 * 1024
+)
+
+// PackageManifest lists the files in a plugin package and their hashes
+type PackageManifest struct {
+	PluginID  string        `json:"pluginId"`
+	Version   string        `json:"version"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Files     []PackageFile `json:"files"`
+}
+
+// PackageFile is one manifest entry
+type PackageFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// PackageSignature is the detached signature over a manifest
+type PackageSignature struct {
+	SignerID  string    `json:"signerId"`
+	Algorithm string    `json:"algorithm"`
+	Signature string    `json:"signature"` // hex
+	SignedAt  time.Time `json:"signedAt"`
+}
+
+// PluginPackage is a parsed plugin package
+type PluginPackage struct {
+	Manifest     *PackageManifest
+	Signature    *PackageSignature
+	Metadata     *PluginMetadata
+	Files        map[string][]byte // path -> content, excluding manifest and signature
+	manifestData []byte
+}
+
+// BuildPackage creates an unsigned package from a plugin directory. Files
+// are sorted and zip timestamps fixed so the same directory always produces
+// the same bytes.
+func BuildPackage(dir string) ([]byte, error) {
+	files, err := readPackageDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := parsePackageMetadata(files)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &PackageManifest{
+		PluginID: meta.ID,
+		Version:  meta.Version,
+		Files:    manifestEntries(files),
+	}
+	if info, err := os.Stat(filepath.Join(dir, PackageMetadataFile)); err == nil {
+		manifest.CreatedAt = info.ModTime().UTC().Truncate(time.Second)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	files[PackageManifestFile] = manifestData
+
+	return writePackageZip(files)
+}
+
+// ReadPackage parses a package and checks the manifest against its files.
+// It does not check the signature; see SecurityManager.VerifyPackage.
+func ReadPackage(data []byte) (*PluginPackage, error) {
+	if len(data) > maxPackageSize {
+		return nil, fmt.Errorf("%w: package exceeds %d bytes", ErrInvalidPackage, maxPackageSize)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, err := cleanPackagePath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if f.UncompressedSize64 > maxPackageFileSize {
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidPackage, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxPackageFileSize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+		}
+		if _, dup := files[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate entry %s", ErrInvalidPackage, name)
+		}
+		files[name] = content
+	}
+
+	return parsePackageFiles(files)
+}
+
+// ReadInstalledPackage reads an installed plugin directory as a package
+func ReadInstalledPackage(dir string) (*PluginPackage, error) {
+	files, err := readPackageDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{PackageManifestFile, PackageSignatureFile} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			files[name] = content
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return parsePackageFiles(files)
+}
+
+// Extract writes the package, including its manifest and signature, to dest
+func (p *PluginPackage) Extract(dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	write := func(name string, content []byte) error {
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	}
+
+	for name, content := range p.Files {
+		if err := write(name, content); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+	}
+	if err := write(PackageManifestFile, p.manifestData); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if p.Signature != nil {
+		sigData, err := json.MarshalIndent(p.Signature, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := write(PackageSignatureFile, sigData); err != nil {
+			return fmt.Errorf("failed to write signature: %w", err)
+		}
+	}
+	return nil
+}
+
+// IsSigned reports whether the package carries a signature
+func (p *PluginPackage) IsSigned() bool {
+	return p.Signature != nil
+}
+
+// SignPackage adds or replaces the detached signature of a package using
+// the manager's private key
+func (sm *SecurityManager) SignPackage(data []byte, signerID string) ([]byte, error) {
+	pkg, err := ReadPackage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.privateKey == nil {
+		return nil, fmt.Errorf("no private key available for signing")
+	}
+
+	digest := sha256.Sum256(pkg.manifestData)
+	sigBytes, err := rsa.SignPKCS1v15(rand.Reader, sm.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		sm.logSecurityEvent(pkg.Manifest.PluginID, "sign_package", "failed", err.Error(), nil)
+		return nil, fmt.Errorf("failed to sign package: %w", err)
+	}
+
+	sig := &PackageSignature{
+		SignerID:  signerID,
+		Algorithm: PackageSignatureAlgorithm,
+		Signature: hex.EncodeToString(sigBytes),
+		SignedAt:  time.Now().UTC(),
+	}
+	sigData, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(pkg.Files)+2)
+	for name, content := range pkg.Files {
+		files[name] = content
+	}
+	files[PackageManifestFile] = pkg.manifestData
+	files[PackageSignatureFile] = sigData
+
+	sm.logSecurityEvent(pkg.Manifest.PluginID, "sign_package", "success", "", map[string]string{
+		"signer":  signerID,
+		"version": pkg.Manifest.Version,
+	})
+
+	return writePackageZip(files)
+}
+
+// VerifyPackage checks a package's signature against the trusted keys.
+// The manifest hashes are already checked by ReadPackage.
+func (sm *SecurityManager) VerifyPackage(pkg *PluginPackage) error {
+	pluginID := pkg.Manifest.PluginID
+
+	fail := func(status string, err error) error {
+		sm.mu.Lock()
+		sm.logSecurityEvent(pluginID, "verify_package", status, err.Error(), nil)
+		sm.mu.Unlock()
+		return err
+	}
+
+	if pkg.Signature == nil {
+		return fail("denied", ErrPackageUnsigned)
+	}
+	if pkg.Signature.Algorithm != PackageSignatureAlgorithm {
+		return fail("denied", fmt.Errorf("%w: unsupported algorithm %q", ErrPackageTampered, pkg.Signature.Algorithm))
+	}
+
+	sm.mu.RLock()
+	keyPEM, trusted := sm.trustedKeys[pkg.Signature.SignerID]
+	sm.mu.RUnlock()
+	if !trusted {
+		return fail("denied", fmt.Errorf("%w: %s", ErrUntrustedSigner, pkg.Signature.SignerID))
+	}
+
+	pub, err := parseRSAPublicKey(keyPEM)
+	if err != nil {
+		return fail("failed", err)
+	}
+	sigBytes, err := hex.DecodeString(pkg.Signature.Signature)
+	if err != nil {
+		return fail("denied", fmt.Errorf("%w: malformed signature", ErrPackageTampered))
+	}
+
+	digest := sha256.Sum256(pkg.manifestData)
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sigBytes); err != nil {
+		return fail("denied", fmt.Errorf("%w: signature does not match manifest", ErrPackageTampered))
+	}
+
+	sm.mu.Lock()
+	sm.logSecurityEvent(pluginID, "verify_package", "success", "", map[string]string{
+		"signer":  pkg.Signature.SignerID,
+		"version": pkg.Manifest.Version,
+	})
+	sm.mu.Unlock()
+	return nil
+}
+
+// PublicKeyPEM returns the manager's public key for use with AddTrustedKey
+func (sm *SecurityManager) PublicKeyPEM() (string, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if sm.publicKey == nil {
+		return "", fmt.Errorf("no key pair available")
+	}
+	der, err := x509.MarshalPKIXPublicKey(sm.publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// SavePrivateKey writes the signing key to a PEM file readable only by the owner
+func (sm *SecurityManager) SavePrivateKey(filePath string) error {
+	sm.mu.RLock()
+	key := sm.privateKey
+	sm.mu.RUnlock()
+
+	if key == nil {
+		return fmt.Errorf("no private key available")
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	return nil
+}
+
+// LoadPrivateKey reads a signing key written by SavePrivateKey
+func (sm *SecurityManager) LoadPrivateKey(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("invalid PEM format")
+	}
+
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return fmt.Errorf("private key is not RSA")
+		}
+		key = rsaKey
+	} else {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	sm.mu.Lock()
+	sm.privateKey = key
+	sm.publicKey = &key.PublicKey
+	sm.mu.Unlock()
+	return nil
+}
+
+// LoadTrustedKeys adds every <signer>.pem file in dir as a trusted key
+func (sm *SecurityManager) LoadTrustedKeys(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read trusted key directory: %w", err)
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return count, err
+		}
+		signer := strings.TrimSuffix(entry.Name(), ".pem")
+		if err := sm.AddTrustedKey(signer, string(data)); err != nil {
+			return count, fmt.Errorf("trusted key %s: %w", entry.Name(), err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// pluginIDPattern is the form every plugin ID takes: lower-case words joined
+// by hyphens, like the plugins shipped in plugins/. IDs become directory and
+// file names under the plugin directory, so nothing else is accepted.
+var pluginIDPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validatePluginID rejects IDs that could not safely name a plugin directory
+func validatePluginID(id string) error {
+	if !pluginIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q (use lower-case words separated by hyphens)", ErrInvalidPluginName, id)
+	}
+	return nil
+}
+
+// parsePackageFiles splits manifest and signature from the package files
+// and checks every file against the manifest
+func parsePackageFiles(files map[string][]byte) (*PluginPackage, error) {
+	manifestData, ok := files[PackageManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidPackage, PackageManifestFile)
+	}
+	delete(files, PackageManifestFile)
+
+	var manifest PackageManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %v", ErrPackageTampered, err)
+	}
+	if err := validatePluginID(manifest.PluginID); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+
+	pkg := &PluginPackage{Manifest: &manifest, Files: files, manifestData: manifestData}
+
+	if sigData, ok := files[PackageSignatureFile]; ok {
+		delete(files, PackageSignatureFile)
+		var sig PackageSignature
+		if err := json.Unmarshal(sigData, &sig); err != nil {
+			return nil, fmt.Errorf("%w: invalid signature file: %v", ErrPackageTampered, err)
+		}
+		pkg.Signature = &sig
+	}
+
+	if err := checkManifest(&manifest, files); err != nil {
+		return nil, err
+	}
+
+	meta, err := parsePackageMetadata(files)
+	if err != nil {
+		return nil, err
+	}
+	if meta.ID != manifest.PluginID || meta.Version != manifest.Version {
+		return nil, fmt.Errorf("%w: manifest is for %s %s but metadata.json says %s %s",
+			ErrPackageTampered, manifest.PluginID, manifest.Version, meta.ID, meta.Version)
+	}
+	pkg.Metadata = meta
+
+	return pkg, nil
+}
+
+// checkManifest verifies that files and manifest entries match exactly
+func checkManifest(manifest *PackageManifest, files map[string][]byte) error {
+	listed := make(map[string]bool, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		content, ok := files[entry.Path]
+		if !ok {
+			return fmt.Errorf("%w: %s is listed in the manifest but missing", ErrPackageTampered, entry.Path)
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 || int64(len(content)) != entry.Size {
+			return fmt.Errorf("%w: %s does not match the manifest", ErrPackageTampered, entry.Path)
+		}
+		listed[entry.Path] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return fmt.Errorf("%w: %s is not listed in the manifest", ErrPackageTampered, name)
+		}
+	}
+	return nil
+}
+
+// parsePackageMetadata decodes and validates metadata.json
+func parsePackageMetadata(files map[string][]byte) (*PluginMetadata, error) {
+	data, ok := files[PackageMetadataFile]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidPackage, PackageMetadataFile)
+	}
+	var meta PluginMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("%w: invalid %s: %v", ErrInvalidPackage, PackageMetadataFile, err)
+	}
+	if meta.ID == "" || meta.Version == "" {
+		return nil, fmt.Errorf("%w: %s must have id and version", ErrInvalidPackage, PackageMetadataFile)
+	}
+	if err := validatePluginID(meta.ID); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPackage, err)
+	}
+	return &meta, nil
+}
+
+// readPackageDir reads the files of a plugin directory, skipping dotfiles
+// and any existing manifest or signature
+func readPackageDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if name == PackageManifestFile || name == PackageSignatureFile {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+	return files, nil
+}
+
+// manifestEntries hashes files in sorted order
+func manifestEntries(files map[string][]byte) []PackageFile {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]PackageFile, 0, len(names))
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		entries = append(entries, PackageFile{
+			Path:   name,
+			Size:   int64(len(files[name])),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return entries
+}
+
+// writePackageZip writes files into a deterministic zip
+func writePackageZip(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cleanPackagePath rejects absolute paths and paths escaping the plugin directory
+func cleanPackagePath(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if cleaned == "." || path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(cleaned, ":") {
+		return "", fmt.Errorf("%w: unsafe path %q", ErrInvalidPackage, name)
+	}
+	return cleaned, nil
+}
+
+// parseRSAPublicKey decodes a PEM public key in PKIX or PKCS#1 form
+func parseRSAPublicKey(keyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM format")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("trusted key is not RSA")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return key, nil
+}
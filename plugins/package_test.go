@@ -0,0 +1,386 @@
+package plugins
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePackagePlugin creates a plugin directory with metadata, a script and an asset
+func writePackagePlugin(t *testing.T, id, version string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), id)
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0755); err != nil {
+		t.Fatalf("failed to create plugin dir: %v", err)
+	}
+	meta := `{"id":"` + id + `","name":"Test Plugin","version":"` + version + `","author":"tester"}`
+	files := map[string]string{
+		"metadata.json":    meta,
+		"plugin.lua":       "-- " + id + " " + version,
+		"assets/icon.txt":  "icon",
+		".hidden/skip.txt": "not packaged",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// signedTestPackage builds a package from dir and signs it as "tester"
+func signedTestPackage(t *testing.T, dir string) ([]byte, *SecurityManager) {
+	t.Helper()
+	signer := setupSecurityManagerWithKeys(t)
+	data, err := BuildPackage(dir)
+	if err != nil {
+		t.Fatalf("BuildPackage failed: %v", err)
+	}
+	signed, err := signer.SignPackage(data, "tester")
+	if err != nil {
+		t.Fatalf("SignPackage failed: %v", err)
+	}
+	return signed, signer
+}
+
+// trustSigner adds the signer's public key to sm
+func trustSigner(t *testing.T, sm, signer *SecurityManager) {
+	t.Helper()
+	pub, err := signer.PublicKeyPEM()
+	if err != nil {
+		t.Fatalf("PublicKeyPEM failed: %v", err)
+	}
+	if err := sm.AddTrustedKey("tester", pub); err != nil {
+		t.Fatalf("AddTrustedKey failed: %v", err)
+	}
+}
+
+// rewritePackage copies a package zip, replacing or adding entries
+func rewritePackage(t *testing.T, data []byte, replace map[string]string) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		w, _ := zw.Create(f.Name)
+		if content, ok := replace[f.Name]; ok {
+			w.Write([]byte(content))
+			delete(replace, f.Name)
+			continue
+		}
+		rc, _ := f.Open()
+		var b bytes.Buffer
+		b.ReadFrom(rc)
+		rc.Close()
+		w.Write(b.Bytes())
+	}
+	for name, content := range replace {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// TestBuildPackage tests package contents and determinism
+func TestBuildPackage(t *testing.T) {
+	dir := writePackagePlugin(t, "sample", "1.0.0")
+	data, err := BuildPackage(dir)
+	if err != nil {
+		t.Fatalf("BuildPackage failed: %v", err)
+	}
+
+	pkg, err := ReadPackage(data)
+	if err != nil {
+		t.Fatalf("ReadPackage failed: %v", err)
+	}
+	if pkg.Manifest.PluginID != "sample" || pkg.Manifest.Version != "1.0.0" {
+		t.Errorf("manifest = %s %s, want sample 1.0.0", pkg.Manifest.PluginID, pkg.Manifest.Version)
+	}
+	if len(pkg.Manifest.Files) != 3 {
+		t.Errorf("manifest has %d files, want 3 (dotfiles skipped)", len(pkg.Manifest.Files))
+	}
+	if _, ok := pkg.Files["assets/icon.txt"]; !ok {
+		t.Error("asset missing from package")
+	}
+	if pkg.IsSigned() {
+		t.Error("BuildPackage should produce an unsigned package")
+	}
+
+	again, _ := BuildPackage(dir)
+	if !bytes.Equal(data, again) {
+		t.Error("BuildPackage should be deterministic")
+	}
+}
+
+// TestReadPackageRejectsBadContents tests manifest and path checks
+func TestReadPackageRejectsBadContents(t *testing.T) {
+	data, _ := BuildPackage(writePackagePlugin(t, "sample", "1.0.0"))
+
+	tests := map[string]map[string]string{
+		"modified file": {"plugin.lua": "os.execute('rm -rf /')"},
+		"extra file":    {"extra.lua": "print('hi')"},
+		"path escape":   {"../evil.lua": "print('hi')"},
+	}
+	for name, replace := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadPackage(rewritePackage(t, data, replace))
+			if err == nil {
+				t.Fatal("ReadPackage should fail")
+			}
+			if !errors.Is(err, ErrInvalidPackage) && !errors.Is(err, ErrPackageTampered) {
+				t.Errorf("unexpected error type: %v", err)
+			}
+		})
+	}
+
+	if _, err := ReadPackage([]byte("not a zip")); !errors.Is(err, ErrInvalidPackage) {
+		t.Errorf("ReadPackage(garbage) = %v, want ErrInvalidPackage", err)
+	}
+}
+
+// TestInstallPackageRejectsEscapingID tests a package whose ID is not a
+// plain plugin name is refused before anything is written
+func TestInstallPackageRejectsEscapingID(t *testing.T) {
+	for _, id := range []string{"../escape", "a/b", "Sample", ".."} {
+		files := map[string][]byte{
+			"metadata.json": []byte(`{"id":"` + id + `","version":"1.0.0"}`),
+			"plugin.lua":    []byte("-- escape"),
+		}
+		manifest, _ := json.Marshal(&PackageManifest{PluginID: id, Version: "1.0.0", Files: manifestEntries(files)})
+		files[PackageManifestFile] = manifest
+		data, err := writePackageZip(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ReadPackage(data); !errors.Is(err, ErrInvalidPackage) || !errors.Is(err, ErrInvalidPluginName) {
+			t.Errorf("ReadPackage(%q) = %v, want ErrInvalidPluginName", id, err)
+		}
+
+		root := t.TempDir()
+		m := NewManager(filepath.Join(root, "plugins"), nil)
+		m.SetSignaturePolicy(SignaturePolicyWarn)
+		if _, err := m.InstallPackage(context.Background(), data); err == nil {
+			t.Errorf("InstallPackage(%q) should fail", id)
+		}
+		if entries, _ := os.ReadDir(root); len(entries) > 1 {
+			t.Errorf("InstallPackage(%q) wrote outside the plugin directory: %v", id, entries)
+		}
+	}
+
+	m := NewManager(t.TempDir(), nil)
+	if err := m.RestorePreviousVersion("../escape"); !errors.Is(err, ErrInvalidPluginName) {
+		t.Errorf("RestorePreviousVersion = %v, want ErrInvalidPluginName", err)
+	}
+}
+
+// TestVerifyPackage tests signature verification outcomes
+func TestVerifyPackage(t *testing.T) {
+	dir := writePackagePlugin(t, "sample", "1.0.0")
+	signed, signer := signedTestPackage(t, dir)
+
+	verifier := NewSecurityManager()
+	pkg, err := ReadPackage(signed)
+	if err != nil {
+		t.Fatalf("ReadPackage failed: %v", err)
+	}
+	if err := verifier.VerifyPackage(pkg); !errors.Is(err, ErrUntrustedSigner) {
+		t.Errorf("VerifyPackage with no trusted keys = %v, want ErrUntrustedSigner", err)
+	}
+
+	trustSigner(t, verifier, signer)
+	if err := verifier.VerifyPackage(pkg); err != nil {
+		t.Errorf("VerifyPackage failed for trusted signer: %v", err)
+	}
+
+	unsigned, _ := BuildPackage(dir)
+	pkg, _ = ReadPackage(unsigned)
+	if err := verifier.VerifyPackage(pkg); !errors.Is(err, ErrPackageUnsigned) {
+		t.Errorf("VerifyPackage(unsigned) = %v, want ErrPackageUnsigned", err)
+	}
+
+	// A rebuilt manifest for modified files no longer matches the signature
+	other := writePackagePlugin(t, "sample", "1.0.0")
+	os.WriteFile(filepath.Join(other, "plugin.lua"), []byte("-- changed"), 0644)
+	forged, _ := BuildPackage(other)
+	forgedPkg, _ := ReadPackage(forged)
+	signedPkg, _ := ReadPackage(signed)
+	forgedPkg.Signature = signedPkg.Signature
+	if err := verifier.VerifyPackage(forgedPkg); !errors.Is(err, ErrPackageTampered) {
+		t.Errorf("VerifyPackage(forged) = %v, want ErrPackageTampered", err)
+	}
+}
+
+// TestInstallPackage tests installing a signed package and load-time verification
+func TestInstallPackage(t *testing.T) {
+	signed, signer := signedTestPackage(t, writePackagePlugin(t, "sample", "1.0.0"))
+
+	m := NewManager(t.TempDir(), nil)
+	trustSigner(t, m.securityMgr, signer)
+
+	manifest, err := m.InstallPackage(context.Background(), signed)
+	if err != nil {
+		t.Fatalf("InstallPackage failed: %v", err)
+	}
+	if manifest.PluginID != "sample" {
+		t.Errorf("installed %s, want sample", manifest.PluginID)
+	}
+
+	installed := filepath.Join(m.pluginDir, "sample")
+	for _, name := range []string{"plugin.lua", "assets/icon.txt", PackageManifestFile, PackageSignatureFile} {
+		if _, err := os.Stat(filepath.Join(installed, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s not installed: %v", name, err)
+		}
+	}
+	if _, err := m.VerifyInstalledPlugin(installed); err != nil {
+		t.Errorf("VerifyInstalledPlugin failed: %v", err)
+	}
+
+	plugin, err := m.LoadPlugin(context.Background(), installed)
+	if err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if plugin.GetMetadata().ID != "sample" {
+		t.Errorf("loaded plugin ID = %s, want sample", plugin.GetMetadata().ID)
+	}
+
+	trail := m.auditLogger.GetPluginAuditTrail("sample")
+	found := false
+	for _, e := range trail {
+		if e.Action == "INSTALL_PACKAGE" && e.Status == "success" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("install not recorded in audit log")
+	}
+}
+
+// TestLoadPluginDetectsTampering tests that edits after install are refused on load
+func TestLoadPluginDetectsTampering(t *testing.T) {
+	signed, signer := signedTestPackage(t, writePackagePlugin(t, "sample", "1.0.0"))
+
+	m := NewManager(t.TempDir(), nil)
+	trustSigner(t, m.securityMgr, signer)
+	if _, err := m.InstallPackage(context.Background(), signed); err != nil {
+		t.Fatalf("InstallPackage failed: %v", err)
+	}
+
+	installed := filepath.Join(m.pluginDir, "sample")
+	if err := os.WriteFile(filepath.Join(installed, "plugin.lua"), []byte("-- edited"), 0644); err != nil {
+		t.Fatalf("failed to modify plugin: %v", err)
+	}
+
+	if _, err := m.LoadPlugin(context.Background(), installed); !errors.Is(err, ErrPackageTampered) {
+		t.Errorf("LoadPlugin(tampered) = %v, want ErrPackageTampered", err)
+	}
+	if len(m.auditLogger.GetEventsByType("security_violation")) == 0 {
+		t.Error("tampering not recorded as a security violation")
+	}
+
+	// Quarantine policy moves the directory aside
+	m.SetSignaturePolicy(SignaturePolicyQuarantine)
+	if _, err := m.LoadPlugin(context.Background(), installed); !errors.Is(err, ErrPluginQuarantined) {
+		t.Errorf("LoadPlugin(quarantine) = %v, want ErrPluginQuarantined", err)
+	}
+	if _, err := os.Stat(installed); !os.IsNotExist(err) {
+		t.Error("tampered plugin should be moved out of the plugin directory")
+	}
+	entries, _ := os.ReadDir(m.QuarantineDir())
+	if len(entries) != 1 {
+		t.Errorf("quarantine has %d entries, want 1", len(entries))
+	}
+}
+
+// TestInstallPackageSignaturePolicy tests refuse, quarantine and warn for unsigned packages
+func TestInstallPackageSignaturePolicy(t *testing.T) {
+	unsigned, err := BuildPackage(writePackagePlugin(t, "sample", "1.0.0"))
+	if err != nil {
+		t.Fatalf("BuildPackage failed: %v", err)
+	}
+	ctx := context.Background()
+
+	t.Run("require", func(t *testing.T) {
+		m := NewManager(t.TempDir(), nil)
+		if _, err := m.InstallPackage(ctx, unsigned); !errors.Is(err, ErrPackageUnsigned) {
+			t.Errorf("InstallPackage = %v, want ErrPackageUnsigned", err)
+		}
+		if _, err := os.Stat(filepath.Join(m.pluginDir, "sample")); !os.IsNotExist(err) {
+			t.Error("refused package should not be installed")
+		}
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		m := NewManager(t.TempDir(), nil)
+		m.SetSignaturePolicy(SignaturePolicyQuarantine)
+		if _, err := m.InstallPackage(ctx, unsigned); !errors.Is(err, ErrPluginQuarantined) {
+			t.Errorf("InstallPackage = %v, want ErrPluginQuarantined", err)
+		}
+		if _, err := os.Stat(filepath.Join(m.pluginDir, "sample")); !os.IsNotExist(err) {
+			t.Error("quarantined package should not be installed")
+		}
+		entries, _ := os.ReadDir(m.QuarantineDir())
+		if len(entries) != 1 {
+			t.Errorf("quarantine has %d entries, want 1", len(entries))
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		m := NewManager(t.TempDir(), nil)
+		m.SetSignaturePolicy(SignaturePolicyWarn)
+		if _, err := m.InstallPackage(ctx, unsigned); err != nil {
+			t.Fatalf("InstallPackage failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(m.pluginDir, "sample", "plugin.lua")); err != nil {
+			t.Error("package should be installed under the warn policy")
+		}
+		if len(m.auditLogger.GetEventsByStatus("warning")) == 0 {
+			t.Error("warning not recorded in audit log")
+		}
+	})
+}
+
+// TestParseSignaturePolicy tests policy name parsing
+func TestParseSignaturePolicy(t *testing.T) {
+	for _, p := range []SignaturePolicy{SignaturePolicyRequire, SignaturePolicyQuarantine, SignaturePolicyWarn} {
+		parsed, err := ParseSignaturePolicy(p.String())
+		if err != nil || parsed != p {
+			t.Errorf("ParseSignaturePolicy(%s) = %v, %v", p, parsed, err)
+		}
+	}
+	if _, err := ParseSignaturePolicy("bogus"); err == nil {
+		t.Error("ParseSignaturePolicy should reject unknown names")
+	}
+}
+
+// TestSaveLoadPrivateKey tests persisting a signing key
+func TestSaveLoadPrivateKey(t *testing.T) {
+	sm := setupSecurityManagerWithKeys(t)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := sm.SavePrivateKey(path); err != nil {
+		t.Fatalf("SavePrivateKey failed: %v", err)
+	}
+
+	loaded := NewSecurityManager()
+	if err := loaded.LoadPrivateKey(path); err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	want, _ := sm.PublicKeyPEM()
+	got, _ := loaded.PublicKeyPEM()
+	if want != got {
+		t.Error("loaded key does not match saved key")
+	}
+}
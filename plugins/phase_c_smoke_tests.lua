@@ -41,7 +41,7 @@ end
 function PhaseC_Tests.test_build_optimizer_ml_prediction()
   local build_optimizer = require("plugins.build-optimizer.v1_0_core")
   
-  local prediction = build_optimizer.Phase11Integration.predictBuildPerformance({
+  local prediction = build_optimizer.Phase11Analytics.predictBuildPerformance({
     character = "Terra",
     equipment = {"Illumina", "Crystal Shield", "Minerva"},
     stats = {hp = 8500, mp = 600, strength = 220, magic = 255}
@@ -57,7 +57,7 @@ end
 function PhaseC_Tests.test_build_optimizer_visualization()
   local build_optimizer = require("plugins.build-optimizer.v1_0_core")
   
-  local dashboard = build_optimizer.Phase11Integration.createProgressionDashboard({
+  local dashboard = build_optimizer.Phase11Visualization.createProgressionDashboard({
     character = "Edgar",
     progression_data = {{level = 10, power = 120}, {level = 20, power = 240}}
   })
@@ -72,7 +72,7 @@ end
 function PhaseC_Tests.test_build_optimizer_import_export()
   local build_optimizer = require("plugins.build-optimizer.v1_0_core")
   
-  local export_result = build_optimizer.Phase11Integration.exportBuildTemplate({
+  local export_result = build_optimizer.Phase11ImportExport.exportBuildTemplate({
     build_id = "TEST_BUILD_001",
     character = "Celes",
     equipment = {"Lightbringer", "Genji Glove"}
@@ -88,7 +88,7 @@ end
 function PhaseC_Tests.test_build_optimizer_pattern_analysis()
   local build_optimizer = require("plugins.build-optimizer.v1_0_core")
   
-  local patterns = build_optimizer.Phase11Integration.analyzeSuccessPatterns({
+  local patterns = build_optimizer.Phase11Analytics.analyzeSuccessPatterns({
     {build_id = "B1", success_rate = 85},
     {build_id = "B2", success_rate = 92},
     {build_id = "B3", success_rate = 78}
@@ -104,7 +104,7 @@ end
 function PhaseC_Tests.test_build_optimizer_automation()
   local build_optimizer = require("plugins.build-optimizer.v1_0_core")
   
-  local automation = build_optimizer.Phase11Integration.autoOptimizeBuild({
+  local automation = build_optimizer.Phase11Automation.autoOptimizeBuild({
     character = "Locke",
     goal = "maximize_damage"
   })
@@ -123,7 +123,7 @@ end
 function PhaseC_Tests.test_strategy_library_effectiveness()
   local strategy_library = require("plugins.strategy-library.v1_0_core")
   
-  local analysis = strategy_library.Phase11Integration.analyzeStrategyEffectiveness({
+  local analysis = strategy_library.Phase11Analytics.analyzeStrategyEffectiveness({
     strategy_id = "STRAT_001",
     usage_count = 45,
     success_rate = 88,
@@ -140,7 +140,7 @@ end
 function PhaseC_Tests.test_strategy_library_prediction()
   local strategy_library = require("plugins.strategy-library.v1_0_core")
   
-  local prediction = strategy_library.Phase11Integration.predictStrategySuccess({
+  local prediction = strategy_library.Phase11Analytics.predictStrategySuccess({
     strategy = "Quick Sketch + Relic Bug",
     boss = "Wrexsoul",
     party_level = 45
@@ -156,7 +156,7 @@ end
 function PhaseC_Tests.test_strategy_library_similarity()
   local strategy_library = require("plugins.strategy-library.v1_0_core")
   
-  local similar = strategy_library.Phase11Integration.findSimilarStrategies({
+  local similar = strategy_library.Phase11Analytics.findSimilarStrategies({
     strategy_id = "STRAT_BERSERK",
     tags = {"offensive", "status_effect"}
   }, {
@@ -174,7 +174,7 @@ end
 function PhaseC_Tests.test_strategy_library_export()
   local strategy_library = require("plugins.strategy-library.v1_0_core")
   
-  local export_result = strategy_library.Phase11Integration.exportStrategies({
+  local export_result = strategy_library.Phase11ImportExport.exportStrategies({
     {strategy_id = "S1", name = "Vanish-Doom"},
     {strategy_id = "S2", name = "Quick Ultima"}
   }, "json")
@@ -189,7 +189,7 @@ end
 function PhaseC_Tests.test_strategy_library_auto_suggest()
   local strategy_library = require("plugins.strategy-library.v1_0_core")
   
-  local suggestions = strategy_library.Phase11Integration.autoSuggestStrategies({
+  local suggestions = strategy_library.Phase11Automation.autoSuggestStrategies({
     boss = "Atma Weapon",
     party_composition = {"Terra", "Celes", "Edgar", "Sabin"}
   })
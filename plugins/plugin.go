@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -26,6 +27,8 @@ type Plugin struct {
 	OnSaveOpen func(savePath string) error
 	OnSaveSave func(savePath string) error
 	OnCharEdit func(charID int) error
+	OnUIRender func(view string) error
+	OnMenuAdd  func(menu string) error
 }
 
 // PluginMetadata contains plugin metadata from manifest
@@ -48,6 +51,12 @@ type PluginMetadata struct {
 	Downloads     int       `json:"downloads"`
 	Permissions   []string  `json:"permissions"`
 	Hooks         []string  `json:"hooks"`
+	// Events maps topics the plugin publishes to JSON schemas for their payloads
+	Events map[string]json.RawMessage `json:"events,omitempty"`
+	// Settings declares the plugin's typed settings and their defaults
+	Settings SettingsSchema `json:"settings,omitempty"`
+	// Contributes declares commands, menu items and panels for the GUI
+	Contributes Contributions `json:"contributes,omitempty"`
 }
 
 // PluginConfig contains plugin configuration
@@ -170,6 +179,18 @@ func (p *Plugin) CallHook(hookType HookType, args ...interface{}) error {
 				return p.OnCharEdit(charID)
 			}
 		}
+	case HookUIRender:
+		if p.OnUIRender != nil && len(args) > 0 {
+			if view, ok := args[0].(string); ok {
+				return p.OnUIRender(view)
+			}
+		}
+	case HookMenuAdd:
+		if p.OnMenuAdd != nil && len(args) > 0 {
+			if menu, ok := args[0].(string); ok {
+				return p.OnMenuAdd(menu)
+			}
+		}
 	}
 	return nil
 }
@@ -40,6 +40,8 @@ type PluginProfiler struct {
 	metrics       map[string][]*ExecutionMetrics  // pluginID -> metrics history
 	samples       map[string][]*PerformanceSample // pluginID -> performance samples
 	aggregates    map[string]*AggregateMetrics    // pluginID -> aggregate stats
+	cpuProfiles   map[string]*CPUProfile          // pluginID -> Lua stack samples
+	store         *EventStore                     // optional; see SetEventStore
 	maxHistoryLen int                             // Max metrics to keep per plugin
 	samplingRate  float64                         // 0-1, fraction of executions to sample
 	mu            sync.RWMutex
@@ -85,6 +87,7 @@ func NewPluginProfiler(maxHistoryLen int, samplingRate float64) *PluginProfiler
 		metrics:       make(map[string][]*ExecutionMetrics),
 		samples:       make(map[string][]*PerformanceSample),
 		aggregates:    make(map[string]*AggregateMetrics),
+		cpuProfiles:   make(map[string]*CPUProfile),
 		maxHistoryLen: maxHistoryLen,
 		samplingRate:  samplingRate,
 	}
@@ -131,6 +134,13 @@ func (p *PluginProfiler) RecordExecution(pluginID string, duration time.Duration
 	}
 
 	p.metrics[pluginID] = append(p.metrics[pluginID], metric)
+	if p.store != nil {
+		p.store.Append(StoredEvent{
+			Time: metric.Timestamp, Source: EventSourceProfiler, Type: "execution",
+			PluginID: pluginID, Status: status, Duration: duration, Error: errorMsg,
+			Data: map[string]interface{}{"memory_mb": metric.MemoryMB},
+		})
+	}
 
 	// Limit history size
 	if len(p.metrics[pluginID]) > p.maxHistoryLen {
@@ -374,11 +384,59 @@ func (p *PluginProfiler) GetBottlenecks() []string {
 	return bottlenecks
 }
 
-// GenerateFlameGraph generates a flame graph representation
+// RecordCPUProfile adds a run's Lua stack samples to the plugin's profile
+func (p *PluginProfiler) RecordCPUProfile(profile *CPUProfile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.store != nil {
+		data := map[string]interface{}{"samples": profile.Samples(), "interval": profile.Interval}
+		if funcs := profile.Functions(); len(funcs) > 0 {
+			data["hottest"] = funcs[0].Frame.String()
+		}
+		p.store.Append(StoredEvent{
+			Source: EventSourceProfiler, Type: "cpu_profile", PluginID: profile.PluginID,
+			Status: "success", Duration: profile.Duration, Data: data,
+		})
+	}
+	if existing, ok := p.cpuProfiles[profile.PluginID]; ok {
+		existing.Merge(profile)
+		return
+	}
+	p.cpuProfiles[profile.PluginID] = profile.clone()
+}
+
+// SetEventStore makes the profiler also append every execution and CPU
+// profile to store, so they outlive the process; nil stops it
+func (p *PluginProfiler) SetEventStore(store *EventStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store = store
+}
+
+// GetCPUProfile returns a copy of the Lua stack samples recorded for a
+// plugin, or nil
+func (p *PluginProfiler) GetCPUProfile(pluginID string) *CPUProfile {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if profile, ok := p.cpuProfiles[pluginID]; ok {
+		return profile.clone()
+	}
+	return nil
+}
+
+// GenerateFlameGraph generates a flame graph representation. With a
+// recorded CPU profile the tree holds the plugin's Lua functions; otherwise
+// it is a single node with the total execution time.
 func (p *PluginProfiler) GenerateFlameGraph(pluginID string) *FlameGraphNode {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	if profile, ok := p.cpuProfiles[pluginID]; ok {
+		return profile.FlameGraph()
+	}
+
 	samples, exists := p.samples[pluginID]
 	if !exists || len(samples) == 0 {
 		return nil
@@ -461,6 +519,7 @@ func (p *PluginProfiler) ClearMetrics(pluginID string) {
 	delete(p.metrics, pluginID)
 	delete(p.samples, pluginID)
 	delete(p.aggregates, pluginID)
+	delete(p.cpuProfiles, pluginID)
 }
 
 // ClearAllMetrics clears all metrics
@@ -471,6 +530,7 @@ func (p *PluginProfiler) ClearAllMetrics() {
 	p.metrics = make(map[string][]*ExecutionMetrics)
 	p.samples = make(map[string][]*PerformanceSample)
 	p.aggregates = make(map[string]*AggregateMetrics)
+	p.cpuProfiles = make(map[string]*CPUProfile)
 }
 
 // GetPerformanceReport generates a performance report
@@ -2,8 +2,11 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
+
+	modelsPR "ffvi_editor/models/pr"
 )
 
 // TestPluginCreation tests plugin creation and metadata
@@ -161,6 +164,26 @@ func TestAPIPermissions(t *testing.T) {
 	}
 }
 
+// TestAPIQuery tests queries need read access and report parse errors
+func TestAPIQuery(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewAPIImpl(nil, []string{}).Query(ctx, "espers"); err != ErrInsufficientPermissions {
+		t.Errorf("Query() without read_save error = %v, want ErrInsufficientPermissions", err)
+	}
+
+	api := NewAPIImpl(nil, []string{CommonPermissions.ReadSave})
+	res, err := api.Query(ctx, "espers order by name limit 3")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(res.Rows) != 3 {
+		t.Errorf("Query() returned %d rows, want 3", len(res.Rows))
+	}
+	if _, err = api.Query(ctx, "espers where"); err == nil {
+		t.Error("Query() should reject an incomplete query")
+	}
+}
+
 // TestAPILogging tests API logging
 func TestAPILogging(t *testing.T) {
 	api := NewAPIImpl(nil, []string{})
@@ -370,3 +393,33 @@ func TestPluginEnableDisable(t *testing.T) {
 		t.Error("Plugin should be enabled after EnablePlugin()")
 	}
 }
+
+// TestAPITreasures tests treasure access through the plugin API
+func TestAPITreasures(t *testing.T) {
+	ds := modelsPR.GetDataStorage()
+	ds.Clear()
+	ds.Arrays[modelsPR.DataStorageTreasure] = make([]int, 2)
+	defer ds.Clear()
+	ctx := context.Background()
+
+	api := NewAPIImpl(nil, []string{CommonPermissions.ReadSave})
+	if err := api.SetTreasureOpened(ctx, 1, true); err != ErrInsufficientPermissions {
+		t.Errorf("SetTreasureOpened without write_save = %v, want ErrInsufficientPermissions", err)
+	}
+
+	api = NewAPIImpl(nil, []string{CommonPermissions.ReadSave, CommonPermissions.WriteSave})
+	if err := api.SetTreasureOpened(ctx, 1, true); !errors.Is(err, modelsPR.ErrTreasureUnverified) {
+		t.Fatalf("SetTreasureOpened on a provisional treasure = %v, want ErrTreasureUnverified", err)
+	}
+	list, err := api.GetTreasures(ctx, modelsPR.TreasureFilter{Unopened: true})
+	if err != nil {
+		t.Fatalf("GetTreasures failed: %v", err)
+	}
+	found := false
+	for _, tr := range list {
+		found = found || tr.ID == 1
+	}
+	if !found {
+		t.Error("rejected treasure should still be unopened")
+	}
+}
@@ -14,6 +14,7 @@ type PluginState struct {
 	Config         interface{}
 	Enabled        bool
 	Data           map[string]interface{}
+	Storage        StorageSnapshot
 	LastExecutions []ExecutionRecord
 }
 
@@ -7,6 +7,10 @@ import (
 	"time"
 )
 
+// DefaultMaxInstructions is the Lua instruction budget of the default
+// policy, a few seconds of work on a desktop machine
+const DefaultMaxInstructions = 200_000_000
+
 // SandboxPolicy defines runtime constraints for a plugin
 type SandboxPolicy struct {
 	PluginID           string
@@ -15,6 +19,7 @@ type SandboxPolicy struct {
 	MaxMemoryMB        int      // Memory limit in MB (0 = unlimited)
 	MaxCPUPercent      int      // CPU usage limit as percentage (0 = unlimited)
 	TimeoutSeconds     int      // Execution timeout in seconds (0 = unlimited)
+	MaxInstructions    int64    // Lua VM instruction budget per run (0 = unlimited)
 	IsolationLevel     string   // "none", "basic", "strict"
 	CreatedAt          time.Time
 	ModifiedAt         time.Time
@@ -368,6 +373,24 @@ func (sm *SandboxManager) logViolation(pluginID, violationType, message, severit
 	}
 }
 
+// RecordViolation records a violation detected outside the manager, such as
+// a resource limit the Lua runtime enforced. ID and timestamp are filled in.
+func (sm *SandboxManager) RecordViolation(v *SecurityViolation) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	v.ViolationID = fmt.Sprintf("viol_%d", time.Now().UnixNano())
+	v.Timestamp = time.Now()
+	if v.Details == nil {
+		v.Details = make(map[string]interface{})
+	}
+	sm.violations = append(sm.violations, v)
+
+	if len(sm.violations) > sm.maxViolations {
+		sm.violations = sm.violations[len(sm.violations)-sm.maxViolations:]
+	}
+}
+
 // GenerateSecurityReport generates comprehensive security report
 func (sm *SandboxManager) GenerateSecurityReport() string {
 	sm.mu.RLock()
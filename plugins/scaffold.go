@@ -0,0 +1,354 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ScaffoldOptions describes the plugin Scaffold creates
+type ScaffoldOptions struct {
+	ID          string
+	Name        string // defaults to the ID in title case
+	Author      string
+	Description string // defaults to the template's
+	Template    string // see ScaffoldTemplates
+}
+
+// scaffoldTemplate is the starting point for a new plugin
+type scaffoldTemplate struct {
+	summary     string
+	category    string
+	permissions []string
+	hooks       []string
+	settings    SettingsSchema
+	contributes func(name string) *Contributions
+	source      string
+	test        string
+}
+
+// scaffoldMetadata is the metadata.json written for a new plugin. It keeps
+// to the fields a plugin author edits, in the order they read them.
+type scaffoldMetadata struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Version      string         `json:"version"`
+	Author       string         `json:"author"`
+	Description  string         `json:"description"`
+	Category     string         `json:"category"`
+	Permissions  []string       `json:"permissions"`
+	Dependencies []string       `json:"dependencies"`
+	Hooks        []string       `json:"hooks,omitempty"`
+	Settings     SettingsSchema `json:"settings,omitempty"`
+	Contributes  *Contributions `json:"contributes,omitempty"`
+}
+
+var (
+	scaffoldMinGil, scaffoldMaxGil = 0.0, 9999999.0
+
+	scaffoldKits = map[string]scaffoldTemplate{
+		"hook": {
+			summary:     "Reacts to save files being opened and saved.",
+			category:    "utility",
+			permissions: []string{Capabilities.EventsSubscribe, Capabilities.CharactersRead},
+			hooks:       []string{"on_load", "on_save"},
+			source:      hookSource,
+			test:        hookTest,
+		},
+		"tool": {
+			summary:     "A command that changes the open save, run from the Tools menu.",
+			category:    "utility",
+			permissions: []string{Capabilities.GilRead, Capabilities.GilWrite},
+			settings: SettingsSchema{{
+				Key: "amount", Type: SettingInteger, Label: "Gil to add",
+				Default: 10000.0, Min: &scaffoldMinGil, Max: &scaffoldMaxGil,
+			}},
+			contributes: func(name string) *Contributions {
+				return &Contributions{
+					Commands: []CommandContribution{{ID: "run", Title: name}},
+					Menus:    []MenuContribution{{Menu: "tools", Command: "run"}},
+				}
+			},
+			source: toolSource,
+			test:   toolTest,
+		},
+		"database": {
+			summary:  "A searchable reference table, with favourites kept in plugin storage.",
+			category: "database",
+			source:   databaseSource,
+			test:     databaseTest,
+		},
+	}
+)
+
+// ScaffoldTemplates returns the templates Scaffold knows, sorted
+func ScaffoldTemplates() []string {
+	names := make([]string, 0, len(scaffoldKits))
+	for name := range scaffoldKits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scaffold creates a new plugin directory under pluginDir from a template:
+// metadata.json, plugin.lua, CHANGELOG.md and a <id>_test.lua that
+// `plugin test` runs. It returns the new directory and refuses to touch an
+// existing one.
+func Scaffold(pluginDir string, opts ScaffoldOptions) (string, error) {
+	if !pluginIDPattern.MatchString(opts.ID) {
+		return "", fmt.Errorf("invalid plugin ID %q: use lower-case words separated by hyphens", opts.ID)
+	}
+	kit, ok := scaffoldKits[opts.Template]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q (valid: %s)", opts.Template, strings.Join(ScaffoldTemplates(), ", "))
+	}
+	if opts.Name == "" {
+		words := strings.Split(opts.ID, "-")
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+		opts.Name = strings.Join(words, " ")
+	}
+	if opts.Author == "" {
+		opts.Author = "unknown"
+	}
+	if opts.Description == "" {
+		opts.Description = kit.summary
+	}
+
+	dest := filepath.Join(pluginDir, opts.ID)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+
+	meta := scaffoldMetadata{
+		ID:           opts.ID,
+		Name:         opts.Name,
+		Version:      "0.1.0",
+		Author:       opts.Author,
+		Description:  opts.Description,
+		Category:     kit.category,
+		Permissions:  append([]string{}, kit.permissions...),
+		Dependencies: []string{},
+		Hooks:        kit.hooks,
+		Settings:     kit.settings,
+	}
+	if kit.contributes != nil {
+		meta.Contributes = kit.contributes(opts.Name)
+	}
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	sources := map[string]string{
+		"plugin.lua":   kit.source,
+		"CHANGELOG.md": changelogSource,
+		strings.ReplaceAll(opts.ID, "-", "_") + "_test.lua": kit.test,
+	}
+	data := struct {
+		ScaffoldOptions
+		Date string
+	}{opts, time.Now().Format("2006-01-02")}
+
+	rendered := map[string][]byte{PackageMetadataFile: append(metaData, '\n')}
+	for name, src := range sources {
+		tmpl, err := template.New(name).Parse(src)
+		if err != nil {
+			return "", fmt.Errorf("template %s/%s: %w", opts.Template, name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("template %s/%s: %w", opts.Template, name, err)
+		}
+		rendered[name] = buf.Bytes()
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	for name, content := range rendered {
+		if err := os.WriteFile(filepath.Join(dest, name), content, 0644); err != nil {
+			os.RemoveAll(dest)
+			return "", fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return dest, nil
+}
+
+const changelogSource = `# Changelog - {{.Name}}
+
+All notable changes to the {{.Name}} plugin will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+
+---
+
+## [0.1.0] - {{.Date}}
+
+### Added
+- Initial version, created with ` + "`plugin new --template {{.Template}}`" + `
+`
+
+const hookSource = `--[[
+  {{.Name}} Plugin
+  {{.Description}}
+
+  Version: 0.1.0
+]]
+
+local M = {}
+
+-- describe turns a bus event into one line of text
+function M.describe(ev)
+  local path = ev.payload and ev.payload.path or "?"
+  if ev.topic == "save.opened" then
+    return "Opened " .. path
+  elseif ev.topic == "save.saved" then
+    return "Saved " .. path
+  end
+  return ev.topic
+end
+
+-- party lists the names of the characters in the open save
+function M.party()
+  local names = {}
+  for i = 0, save.getCharacterCount() - 1 do
+    local name = save.getCharacterName(i)
+    if name then
+      table.insert(names, name)
+    end
+  end
+  return names
+end
+
+function M.run()
+  for _, ev in ipairs(events.recent("save.*")) do
+    print(M.describe(ev))
+  end
+  print("Party: " .. table.concat(M.party(), ", "))
+end
+
+return M
+`
+
+const hookTest = `-- Tests for {{.Name}}; run them with: ffvi_editor plugin test {{.ID}}
+local plugin = require("plugin")
+
+test("describes save events", function()
+  expect.equal(plugin.describe({topic = "save.opened", payload = {path = "a.json"}}), "Opened a.json")
+  expect.equal(plugin.describe({topic = "save.saved", payload = {path = "a.json"}}), "Saved a.json")
+  expect.equal(plugin.describe({topic = "other"}), "other")
+end)
+
+test("lists the party of the fixture", function()
+  if save == nil then
+    return -- needs --fixture
+  end
+  expect.type(plugin.party(), "table")
+end)
+`
+
+const toolSource = `--[[
+  {{.Name}} Plugin
+  {{.Description}}
+
+  Version: 0.1.0
+]]
+
+local M = {}
+
+local MAX_GIL = 9999999
+
+-- addGil returns gil plus amount, capped at what the game can hold
+function M.addGil(gil, amount)
+  return math.min(gil + amount, MAX_GIL)
+end
+
+function M.run()
+  local amount = settings.get("amount") or 10000
+  local before = save.getGil()
+  local after = M.addGil(before, amount)
+  save.setGil(after)
+  return string.format("Gil: %d -> %d", before, after)
+end
+
+return M
+`
+
+const toolTest = `-- Tests for {{.Name}}; run them with: ffvi_editor plugin test {{.ID}}
+local plugin = require("plugin")
+
+test("adds gil", function()
+  expect.equal(plugin.addGil(100, 50), 150)
+end)
+
+test("caps gil", function()
+  expect.equal(plugin.addGil(9999990, 50), 9999999)
+end)
+`
+
+const databaseSource = `--[[
+  {{.Name}} Plugin
+  {{.Description}}
+
+  Version: 0.1.0
+]]
+
+local M = {}
+
+-- Replace with the plugin's data
+M.ENTRIES = {
+  {id = 1, name = "Potion", category = "item"},
+  {id = 2, name = "Ether", category = "item"},
+  {id = 3, name = "Ramuh", category = "esper"},
+}
+
+-- find returns the entries whose name contains text, ignoring case, and
+-- optionally only those in category
+function M.find(text, category)
+  local results = {}
+  text = string.lower(text or "")
+  for _, entry in ipairs(M.ENTRIES) do
+    if string.find(string.lower(entry.name), text, 1, true)
+      and (category == nil or entry.category == category) then
+      table.insert(results, entry)
+    end
+  end
+  return results
+end
+
+-- favourite marks an entry in the plugin's own storage
+function M.favourite(id)
+  return kv.put("favourite." .. id, true)
+end
+
+function M.favourites()
+  return kv.list("favourite.")
+end
+
+return M
+`
+
+const databaseTest = `-- Tests for {{.Name}}; run them with: ffvi_editor plugin test {{.ID}}
+local plugin = require("plugin")
+
+test("finds by name", function()
+  local found = plugin.find("pot")
+  expect.equal(#found, 1)
+  expect.equal(found[1].name, "Potion")
+end)
+
+test("filters by category", function()
+  expect.equal(#plugin.find("", "esper"), 1)
+  expect.equal(#plugin.find("zzz"), 0)
+end)
+`
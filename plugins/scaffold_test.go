@@ -0,0 +1,50 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestScaffold tests every template produces a plugin that loads and packs
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range ScaffoldTemplates() {
+		dest, err := Scaffold(dir, ScaffoldOptions{ID: "new-" + name, Author: "tester", Template: name})
+		if err != nil {
+			t.Fatalf("Scaffold(%s) failed: %v", name, err)
+		}
+		meta, err := readInstalledMetadata(dest)
+		if err != nil {
+			t.Fatalf("%s: metadata unreadable: %v", name, err)
+		}
+		if err := meta.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := checkDeclarations(meta); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if !strings.HasPrefix(meta.Name, "New ") {
+			t.Errorf("%s: name = %q", name, meta.Name)
+		}
+		for _, file := range []string{"plugin.lua", "CHANGELOG.md", "new_" + name + "_test.lua"} {
+			if _, err := os.Stat(filepath.Join(dest, file)); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		if _, err := BuildPackage(dest); err != nil {
+			t.Errorf("%s: BuildPackage failed: %v", name, err)
+		}
+	}
+
+	if _, err := Scaffold(dir, ScaffoldOptions{ID: "new-tool", Template: "tool"}); err == nil {
+		t.Error("Scaffold overwrote an existing plugin")
+	}
+	if _, err := Scaffold(dir, ScaffoldOptions{ID: "Bad ID", Template: "tool"}); err == nil {
+		t.Error("Scaffold accepted an invalid ID")
+	}
+	if _, err := Scaffold(dir, ScaffoldOptions{ID: "other", Template: "widget"}); err == nil {
+		t.Error("Scaffold accepted an unknown template")
+	}
+}
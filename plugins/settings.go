@@ -0,0 +1,349 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Setting field types
+const (
+	SettingString  = "string"
+	SettingInteger = "integer"
+	SettingNumber  = "number"
+	SettingBoolean = "boolean"
+	SettingEnum    = "enum"
+)
+
+// settingsFileName holds a plugin's saved settings in its data directory
+const settingsFileName = "settings.json"
+
+// SettingField declares one plugin setting in metadata.json
+type SettingField struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"`
+	Label       string      `json:"label,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Options     []string    `json:"options,omitempty"` // enum values
+}
+
+// Title returns the label, or the key when there is none
+func (f *SettingField) Title() string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Key
+}
+
+// Validate checks a value for the field and returns it in canonical form:
+// float64 for numbers and integers, bool, or string
+func (f *SettingField) Validate(v interface{}) (interface{}, error) {
+	switch f.Type {
+	case SettingString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a string, got %T", f.Key, v)
+		}
+		return s, nil
+	case SettingEnum:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected one of %s, got %T", f.Key, strings.Join(f.Options, ", "), v)
+		}
+		for _, o := range f.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: %q is not one of %s", f.Key, s, strings.Join(f.Options, ", "))
+	case SettingBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected true or false, got %T", f.Key, v)
+		}
+		return b, nil
+	case SettingInteger, SettingNumber:
+		n, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a number, got %T", f.Key, v)
+		}
+		if f.Type == SettingInteger && n != math.Trunc(n) {
+			return nil, fmt.Errorf("%s: expected a whole number, got %v", f.Key, n)
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, fmt.Errorf("%s: %v is below the minimum %v", f.Key, n, *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, fmt.Errorf("%s: %v is above the maximum %v", f.Key, n, *f.Max)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("%s: unknown setting type %q", f.Key, f.Type)
+}
+
+// Parse converts text typed by a user, on the command line or in a form,
+// into a valid value for the field
+func (f *SettingField) Parse(text string) (interface{}, error) {
+	switch f.Type {
+	case SettingBoolean:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected true or false, got %q", f.Key, text)
+		}
+		return f.Validate(b)
+	case SettingInteger, SettingNumber:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a number, got %q", f.Key, text)
+		}
+		return f.Validate(n)
+	}
+	return f.Validate(text)
+}
+
+// Format renders a value the way Parse reads it
+func (f *SettingField) Format(v interface{}) string {
+	if n, ok := toFloat(v); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// SettingsSchema is the list of settings a plugin declares
+type SettingsSchema []SettingField
+
+// Check validates the schema itself: known types, unique keys, enums with
+// options, sensible ranges and valid defaults
+func (s SettingsSchema) Check() error {
+	seen := make(map[string]bool)
+	for i := range s {
+		f := &s[i]
+		if f.Key == "" {
+			return fmt.Errorf("setting %d has no key", i+1)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("setting %s is declared twice", f.Key)
+		}
+		seen[f.Key] = true
+		switch f.Type {
+		case SettingString, SettingInteger, SettingNumber, SettingBoolean:
+		case SettingEnum:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("setting %s: enum needs options", f.Key)
+			}
+		default:
+			return fmt.Errorf("setting %s: unknown type %q", f.Key, f.Type)
+		}
+		if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+			return fmt.Errorf("setting %s: min is above max", f.Key)
+		}
+		if f.Default != nil {
+			if _, err := f.Validate(f.Default); err != nil {
+				return fmt.Errorf("default of %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Field returns the field for key, or nil
+func (s SettingsSchema) Field(key string) *SettingField {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+// Defaults returns the declared default values
+func (s SettingsSchema) Defaults() map[string]interface{} {
+	values := make(map[string]interface{})
+	for i := range s {
+		if s[i].Default != nil {
+			if v, err := s[i].Validate(s[i].Default); err == nil {
+				values[s[i].Key] = v
+			}
+		}
+	}
+	return values
+}
+
+// Validate checks settings against the schema and returns them in
+// canonical form. Keys the schema does not declare are rejected; a plugin
+// without a schema accepts anything.
+func (s SettingsSchema) Validate(settings map[string]interface{}) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(settings))
+	if len(s) == 0 {
+		for k, v := range settings {
+			out[k] = v
+		}
+		return out, nil
+	}
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f := s.Field(k)
+		if f == nil {
+			return nil, fmt.Errorf("%w: unknown setting %s", ErrInvalidSettings, k)
+		}
+		v, err := f.Validate(settings[k])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// Resolve layers saved values over the defaults, dropping saved values the
+// schema no longer accepts
+func (s SettingsSchema) Resolve(saved map[string]interface{}) map[string]interface{} {
+	values := s.Defaults()
+	for k, v := range saved {
+		if len(s) == 0 {
+			values[k] = v
+			continue
+		}
+		if f := s.Field(k); f != nil {
+			if canonical, err := f.Validate(v); err == nil {
+				values[k] = canonical
+			}
+		}
+	}
+	return values
+}
+
+// settingsPath is where a plugin's saved settings live
+func (m *Manager) settingsPath(pluginID string) string {
+	return filepath.Join(m.storage.Dir(), pluginID, settingsFileName)
+}
+
+func (m *Manager) readSavedSettings(pluginID string) (map[string]interface{}, error) {
+	if err := validateStorageID(pluginID); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(m.settingsPath(pluginID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse saved settings of %s: %w", pluginID, err)
+	}
+	return saved, nil
+}
+
+func (m *Manager) writeSavedSettings(pluginID string, settings map[string]interface{}) error {
+	if err := validateStorageID(pluginID); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.settingsPath(pluginID), data)
+}
+
+// PluginSettings returns a plugin's settings schema and current values,
+// for loaded plugins and for plugins that are only installed
+func (m *Manager) PluginSettings(pluginID string) (SettingsSchema, map[string]interface{}, error) {
+	m.mu.RLock()
+	plugin, loaded := m.plugins[pluginID]
+	config := m.configs[pluginID]
+	m.mu.RUnlock()
+	if loaded {
+		values := make(map[string]interface{}, len(config.Settings))
+		for k, v := range config.Settings {
+			values[k] = v
+		}
+		return plugin.GetMetadata().Settings, values, nil
+	}
+
+	meta, err := m.installedMetadata(pluginID)
+	if err != nil {
+		return nil, nil, err
+	}
+	saved, err := m.readSavedSettings(pluginID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return meta.Settings, meta.Settings.Resolve(saved), nil
+}
+
+// UpdateSettings replaces a plugin's settings after checking them against
+// its schema, and returns the values now in effect. Installed plugins that
+// are not loaded pick the saved settings up when they are.
+func (m *Manager) UpdateSettings(pluginID string, settings map[string]interface{}) (map[string]interface{}, error) {
+	if config, err := m.GetConfig(pluginID); err == nil {
+		config.Settings = settings
+		if err := m.SetConfig(pluginID, config); err != nil {
+			return nil, err
+		}
+		_, values, err := m.PluginSettings(pluginID)
+		return values, err
+	}
+
+	meta, err := m.installedMetadata(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := meta.Settings.Validate(settings)
+	if err != nil {
+		return nil, err
+	}
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	if err := m.writeSavedSettings(pluginID, values); err != nil {
+		return nil, err
+	}
+	return meta.Settings.Resolve(values), nil
+}
+
+// installedMetadata reads the metadata of an installed plugin
+func (m *Manager) installedMetadata(pluginID string) (*PluginMetadata, error) {
+	if err := validateStorageID(pluginID); err != nil {
+		return nil, err
+	}
+	meta, err := readInstalledMetadata(filepath.Join(m.pluginDir, pluginID))
+	if os.IsNotExist(err) {
+		return nil, ErrPluginNotFound
+	}
+	return meta, err
+}
@@ -0,0 +1,246 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const settingsTestMetadata = `{
+	"id": "tracker", "name": "Tracker", "version": "1.0.0", "author": "tester",
+	"permissions": ["events:subscribe"],
+	"settings": [
+		{"key": "difficulty", "type": "enum", "options": ["easy", "hard"], "default": "easy"},
+		{"key": "max-enemies", "type": "integer", "min": 1, "max": 8, "default": 4},
+		{"key": "autosave", "type": "boolean"},
+		{"key": "note", "type": "string"}
+	],
+	"contributes": {
+		"commands": [{"id": "refresh", "title": "Refresh Tracker"}],
+		"menus": [{"menu": "tools", "command": "refresh"}],
+		"panels": [{"id": "main", "title": "Tracker", "settings": ["difficulty"], "commands": ["refresh"]}]
+	}
+}`
+
+// installSettingsPlugin writes the tracker plugin into a plugin directory
+// and returns a manager for it that accepts unsigned plugins
+func installSettingsPlugin(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "tracker")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatalf("Failed to create plugin dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, PackageMetadataFile), []byte(settingsTestMetadata), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+	m := NewManager(dir, NewAPIImpl(nil, nil))
+	m.SetSignaturePolicy(SignaturePolicyWarn)
+	return m, pluginDir
+}
+
+// TestSettingsSchemaValidate tests values are checked and made canonical
+func TestSettingsSchemaValidate(t *testing.T) {
+	min, max := 1.0, 8.0
+	schema := SettingsSchema{
+		{Key: "difficulty", Type: SettingEnum, Options: []string{"easy", "hard"}},
+		{Key: "max-enemies", Type: SettingInteger, Min: &min, Max: &max},
+		{Key: "autosave", Type: SettingBoolean},
+	}
+	if err := schema.Check(); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	values, err := schema.Validate(map[string]interface{}{"difficulty": "hard", "max-enemies": 3, "autosave": true})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if values["max-enemies"] != float64(3) {
+		t.Errorf("max-enemies = %#v, want float64(3)", values["max-enemies"])
+	}
+
+	for name, bad := range map[string]map[string]interface{}{
+		"enum":     {"difficulty": "nightmare"},
+		"range":    {"max-enemies": 9},
+		"fraction": {"max-enemies": 2.5},
+		"type":     {"autosave": "yes"},
+		"unknown":  {"colour": "red"},
+	} {
+		if _, err := schema.Validate(bad); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("%s: Validate = %v, want ErrInvalidSettings", name, err)
+		}
+	}
+
+	if v, err := schema.Field("autosave").Parse("false"); err != nil || v != false {
+		t.Errorf("Parse(false) = %v, %v", v, err)
+	}
+	if _, err := schema.Field("max-enemies").Parse("many"); err == nil {
+		t.Error("Parse accepted a non-number")
+	}
+}
+
+// TestSettingsSchemaCheck tests broken schemas are rejected
+func TestSettingsSchemaCheck(t *testing.T) {
+	for name, schema := range map[string]SettingsSchema{
+		"no key":      {{Type: SettingString}},
+		"duplicate":   {{Key: "a", Type: SettingString}, {Key: "a", Type: SettingBoolean}},
+		"bad type":    {{Key: "a", Type: "colour"}},
+		"no options":  {{Key: "a", Type: SettingEnum}},
+		"bad default": {{Key: "a", Type: SettingBoolean, Default: "yes"}},
+	} {
+		if err := schema.Check(); err == nil {
+			t.Errorf("%s: Check accepted %v", name, schema)
+		}
+	}
+}
+
+// TestContributionsCheck tests contributions must reference what exists
+func TestContributionsCheck(t *testing.T) {
+	schema := SettingsSchema{{Key: "difficulty", Type: SettingString}}
+	valid := Contributions{
+		Commands: []CommandContribution{{ID: "refresh", Title: "Refresh"}},
+		Menus:    []MenuContribution{{Menu: "tools", Command: "refresh"}},
+		Panels:   []PanelContribution{{ID: "main", Title: "Main", Settings: []string{"difficulty"}, Commands: []string{"refresh"}}},
+	}
+	if err := valid.Check(schema); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	unknownMenu := valid
+	unknownMenu.Menus = []MenuContribution{{Menu: "window", Command: "refresh"}}
+	unknownCommand := valid
+	unknownCommand.Menus = []MenuContribution{{Menu: "tools", Command: "missing"}}
+	unknownSetting := valid
+	unknownSetting.Panels = []PanelContribution{{ID: "main", Title: "Main", Settings: []string{"missing"}}}
+	for name, c := range map[string]Contributions{"menu": unknownMenu, "command": unknownCommand, "setting": unknownSetting} {
+		if err := c.Check(schema); err == nil {
+			t.Errorf("Check accepted an unknown %s", name)
+		}
+	}
+}
+
+// TestManagerSettingsPersist tests SetConfig validates, saves and reloads settings
+func TestManagerSettingsPersist(t *testing.T) {
+	m, pluginDir := installSettingsPlugin(t)
+	ctx := context.Background()
+	if _, err := m.LoadPlugin(ctx, pluginDir); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+
+	cfg, _ := m.GetConfig("tracker")
+	if cfg.Settings["difficulty"] != "easy" || cfg.Settings["max-enemies"] != float64(4) {
+		t.Fatalf("initial settings = %v, want the defaults", cfg.Settings)
+	}
+
+	cfg.Settings = map[string]interface{}{"difficulty": "nightmare"}
+	if err := m.SetConfig("tracker", cfg); !errors.Is(err, ErrInvalidSettings) {
+		t.Fatalf("SetConfig = %v, want ErrInvalidSettings", err)
+	}
+	cfg.Settings = map[string]interface{}{"difficulty": "hard", "autosave": true}
+	if err := m.SetConfig("tracker", cfg); err != nil {
+		t.Fatalf("SetConfig failed: %v", err)
+	}
+	if cfg, _ := m.GetConfig("tracker"); cfg.Settings["max-enemies"] != float64(4) {
+		t.Errorf("SetConfig dropped the defaults: %v", cfg.Settings)
+	}
+
+	reloaded := NewManager(filepath.Dir(pluginDir), NewAPIImpl(nil, nil))
+	reloaded.SetSignaturePolicy(SignaturePolicyWarn)
+	if _, err := reloaded.LoadPlugin(ctx, pluginDir); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if cfg, _ := reloaded.GetConfig("tracker"); cfg.Settings["difficulty"] != "hard" || cfg.Settings["autosave"] != true {
+		t.Errorf("settings after reload = %v", cfg.Settings)
+	}
+	if usage, _ := m.storage.Usage("tracker"); usage != 0 {
+		t.Errorf("saved settings count %d bytes against the storage quota", usage)
+	}
+}
+
+// TestPluginSettingsInstalled tests settings of a plugin that is not loaded
+func TestPluginSettingsInstalled(t *testing.T) {
+	m, _ := installSettingsPlugin(t)
+
+	values, err := m.UpdateSettings("tracker", map[string]interface{}{"max-enemies": 6})
+	if err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	if values["max-enemies"] != float64(6) || values["difficulty"] != "easy" {
+		t.Errorf("UpdateSettings = %v", values)
+	}
+	schema, values, err := m.PluginSettings("tracker")
+	if err != nil {
+		t.Fatalf("PluginSettings failed: %v", err)
+	}
+	if len(schema) != 4 || values["max-enemies"] != float64(6) {
+		t.Errorf("PluginSettings = %v, %v", schema, values)
+	}
+	if _, _, err := m.PluginSettings("missing"); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("PluginSettings(missing) = %v, want ErrPluginNotFound", err)
+	}
+}
+
+// TestInvokeCommand tests contributed commands reach the plugin as events
+func TestInvokeCommand(t *testing.T) {
+	m, pluginDir := installSettingsPlugin(t)
+	ctx := context.Background()
+	plugin, err := m.LoadPlugin(ctx, pluginDir)
+	if err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if list := m.Contributions(); len(list) != 1 || list[0].Command("refresh") == nil {
+		t.Fatalf("Contributions = %+v", list)
+	}
+
+	var got interface{}
+	if _, err := plugin.API.Subscribe(ctx, TopicEditorCommand, false, func(ctx context.Context, ev *Event) error {
+		got = ev.Payload
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := m.InvokeCommand(ctx, "tracker", "refresh"); err != nil {
+		t.Fatalf("InvokeCommand failed: %v", err)
+	}
+	if p, ok := got.(map[string]interface{}); !ok || p["command"] != "refresh" || p["plugin"] != "tracker" {
+		t.Errorf("plugin received %v", got)
+	}
+	if err := m.InvokeCommand(ctx, "tracker", "missing"); err == nil {
+		t.Error("InvokeCommand accepted an undeclared command")
+	}
+}
+
+// TestChangeHandler tests the GUI hears about plugins coming and going
+func TestChangeHandler(t *testing.T) {
+	m, pluginDir := installSettingsPlugin(t)
+	ctx := context.Background()
+	changes := 0
+	m.SetChangeHandler(func() {
+		changes++
+		m.Contributions() // the handler may call back into the manager
+	})
+
+	plugin, err := m.LoadPlugin(ctx, pluginDir)
+	if err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	var menus []string
+	plugin.OnMenuAdd = func(menu string) error {
+		menus = append(menus, menu)
+		return nil
+	}
+	if err := m.CallHook(ctx, HookMenuAdd, "tools"); err != nil || len(menus) != 1 || menus[0] != "tools" {
+		t.Errorf("menu hook saw %v (%v)", menus, err)
+	}
+	if err := m.DisablePlugin("tracker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnloadPlugin(ctx, "tracker"); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 3 {
+		t.Errorf("handler called %d times, want 3", changes)
+	}
+}
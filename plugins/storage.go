@@ -0,0 +1,513 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultStorageQuota is the number of bytes each plugin may store
+	// across all of its scopes unless SetQuota says otherwise
+	DefaultStorageQuota = 1 << 20
+
+	// storageDirName holds every plugin's data under the plugin directory.
+	// It is hidden so it is not mistaken for an installed plugin and
+	// survives upgrades and uninstalls.
+	storageDirName = ".data"
+
+	storeFileName   = "store.json"
+	saveStoresDir   = "saves"
+	storeFileFormat = 1
+)
+
+// StorageSnapshot holds every entry of a plugin's stores, keyed by scope
+// then by key; the plugin-wide store has the empty scope
+type StorageSnapshot map[string]map[string]json.RawMessage
+
+// storeFile is the on-disk form of one store
+type storeFile struct {
+	Format  int                        `json:"format"`
+	Scope   string                     `json:"scope,omitempty"`
+	Entries map[string]json.RawMessage `json:"entries"`
+}
+
+// StorageManager persists a key-value store per plugin, and optionally per
+// save file, under <pluginDir>/.data/<pluginID>. Every write goes straight
+// to disk, so stores survive restarts and hot reloads.
+type StorageManager struct {
+	dir          string
+	defaultQuota int64
+	quotas       map[string]int64
+	saveScope    string
+	stores       map[string]*kvFile // path -> open store
+	mu           sync.Mutex
+	writeMu      sync.Mutex // serializes quota checks with the writes they allow
+}
+
+// NewStorageManager creates a storage manager rooted at dir
+func NewStorageManager(dir string) *StorageManager {
+	return &StorageManager{
+		dir:          dir,
+		defaultQuota: DefaultStorageQuota,
+		quotas:       make(map[string]int64),
+		stores:       make(map[string]*kvFile),
+	}
+}
+
+// Dir returns the directory holding every plugin's data
+func (sm *StorageManager) Dir() string {
+	return sm.dir
+}
+
+// SetQuota sets how many bytes a plugin may store; 0 restores the default
+func (sm *StorageManager) SetQuota(pluginID string, bytes int64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if bytes <= 0 {
+		delete(sm.quotas, pluginID)
+		return
+	}
+	sm.quotas[pluginID] = bytes
+}
+
+// Quota returns how many bytes a plugin may store
+func (sm *StorageManager) Quota(pluginID string) int64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if q, ok := sm.quotas[pluginID]; ok {
+		return q
+	}
+	return sm.defaultQuota
+}
+
+// SetSaveScope names the open save file, usually by its path. Per-save
+// stores opened afterwards belong to it; "" means no save is open.
+func (sm *StorageManager) SetSaveScope(scope string) {
+	sm.mu.Lock()
+	sm.saveScope = scope
+	sm.mu.Unlock()
+}
+
+// SaveScope returns the scope set with SetSaveScope
+func (sm *StorageManager) SaveScope() string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.saveScope
+}
+
+// Usage returns the bytes a plugin's stores take on disk
+func (sm *StorageManager) Usage(pluginID string) (int64, error) {
+	if err := validateStorageID(pluginID); err != nil {
+		return 0, err
+	}
+	return sm.usage(pluginID, "")
+}
+
+// usage sums the plugin's store files, leaving out skip. Saved settings are
+// the editor's to manage and do not count.
+func (sm *StorageManager) usage(pluginID, skip string) (int64, error) {
+	var total int64
+	settings := filepath.Join(sm.dir, pluginID, settingsFileName)
+	err := filepath.WalkDir(filepath.Join(sm.dir, pluginID), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || p == skip || p == settings || filepath.Ext(p) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// Open returns a plugin's store for scope, "" being the plugin-wide store
+func (sm *StorageManager) Open(pluginID, scope string) (*KVStore, error) {
+	if err := validateStorageID(pluginID); err != nil {
+		return nil, err
+	}
+	path := sm.storePath(pluginID, scope)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	f, ok := sm.stores[path]
+	if !ok {
+		entries, err := readStoreFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f = &kvFile{sm: sm, pluginID: pluginID, scope: scope, path: path, entries: entries.Entries}
+		sm.stores[path] = f
+	}
+	return &KVStore{file: f}, nil
+}
+
+// Snapshot copies every store a plugin has on disk
+func (sm *StorageManager) Snapshot(pluginID string) (StorageSnapshot, error) {
+	if err := validateStorageID(pluginID); err != nil {
+		return nil, err
+	}
+	sm.writeMu.Lock()
+	defer sm.writeMu.Unlock()
+
+	snap := make(StorageSnapshot)
+	paths, err := sm.storePaths(pluginID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		sf, err := readStoreFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(sf.Entries) > 0 {
+			snap[sf.Scope] = sf.Entries
+		}
+	}
+	return snap, nil
+}
+
+// Restore replaces a plugin's stores with a snapshot. Stores that are not
+// in the snapshot are emptied.
+func (sm *StorageManager) Restore(pluginID string, snap StorageSnapshot) error {
+	if err := validateStorageID(pluginID); err != nil {
+		return err
+	}
+	sm.writeMu.Lock()
+	defer sm.writeMu.Unlock()
+
+	paths, err := sm.storePaths(pluginID)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear plugin storage: %w", err)
+		}
+	}
+	restored := make(map[string]map[string]json.RawMessage, len(snap))
+	for scope, entries := range snap {
+		path := sm.storePath(pluginID, scope)
+		if err := writeStoreFile(path, scope, entries); err != nil {
+			return err
+		}
+		restored[path] = entries
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for path, f := range sm.stores {
+		if f.pluginID != pluginID {
+			continue
+		}
+		f.mu.Lock()
+		f.entries = copyEntries(restored[path])
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// storePath is where the store for scope lives. Save scopes are usually
+// file paths, so they are hashed into a file name.
+func (sm *StorageManager) storePath(pluginID, scope string) string {
+	if scope == "" {
+		return filepath.Join(sm.dir, pluginID, storeFileName)
+	}
+	sum := sha256.Sum256([]byte(scope))
+	return filepath.Join(sm.dir, pluginID, saveStoresDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// storePaths lists the plugin's store files on disk
+func (sm *StorageManager) storePaths(pluginID string) ([]string, error) {
+	var paths []string
+	if _, err := os.Stat(sm.storePath(pluginID, "")); err == nil {
+		paths = append(paths, sm.storePath(pluginID, ""))
+	}
+	matches, err := filepath.Glob(filepath.Join(sm.dir, pluginID, saveStoresDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	return append(paths, matches...), nil
+}
+
+func validateStorageID(pluginID string) error {
+	if pluginID == "" || pluginID == "." || pluginID == ".." || strings.ContainsAny(pluginID, `/\`) {
+		return fmt.Errorf("invalid plugin ID for storage: %q", pluginID)
+	}
+	return nil
+}
+
+func readStoreFile(path string) (*storeFile, error) {
+	sf := &storeFile{Entries: make(map[string]json.RawMessage)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return sf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin storage: %w", err)
+	}
+	if err := json.Unmarshal(data, sf); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin storage %s: %w", path, err)
+	}
+	if sf.Entries == nil {
+		sf.Entries = make(map[string]json.RawMessage)
+	}
+	return sf, nil
+}
+
+func encodeStoreFile(scope string, entries map[string]json.RawMessage) ([]byte, error) {
+	return json.Marshal(&storeFile{Format: storeFileFormat, Scope: scope, Entries: entries})
+}
+
+// writeStoreFile replaces a store file atomically
+func writeStoreFile(path, scope string, entries map[string]json.RawMessage) error {
+	data, err := encodeStoreFile(scope, entries)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create plugin storage directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write plugin storage: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write plugin storage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write plugin storage: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write plugin storage: %w", err)
+	}
+	return nil
+}
+
+func copyEntries(entries map[string]json.RawMessage) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(entries))
+	for k, v := range entries {
+		out[k] = v
+	}
+	return out
+}
+
+// kvFile is the shared state of one open store
+type kvFile struct {
+	sm       *StorageManager
+	pluginID string
+	scope    string
+	path     string
+	entries  map[string]json.RawMessage
+	mu       sync.RWMutex
+}
+
+// commit applies changes, nil values being deletions, if the result fits
+// the plugin's quota, and writes the store before making them visible
+func (f *kvFile) commit(changes map[string]json.RawMessage) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	f.sm.writeMu.Lock()
+	defer f.sm.writeMu.Unlock()
+
+	f.mu.RLock()
+	next := copyEntries(f.entries)
+	f.mu.RUnlock()
+	for k, v := range changes {
+		if v == nil {
+			delete(next, k)
+		} else {
+			next[k] = v
+		}
+	}
+
+	data, err := encodeStoreFile(f.scope, next)
+	if err != nil {
+		return err
+	}
+	others, err := f.sm.usage(f.pluginID, f.path)
+	if err != nil {
+		return fmt.Errorf("failed to measure plugin storage: %w", err)
+	}
+	if quota := f.sm.Quota(f.pluginID); others+int64(len(data)) > quota {
+		return fmt.Errorf("%w: %d bytes needed, %d allowed", ErrStorageQuotaExceeded, others+int64(len(data)), quota)
+	}
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.entries = next
+	f.mu.Unlock()
+	return nil
+}
+
+// KVStore is a plugin's durable key-value store. Values are anything that
+// encodes to JSON and read back as decoded JSON.
+type KVStore struct {
+	file    *kvFile
+	require func(capability string) error // set when opened through a plugin's API
+}
+
+// Scope returns the save scope of the store, "" for the plugin-wide store
+func (s *KVStore) Scope() string {
+	return s.file.scope
+}
+
+func (s *KVStore) check(capability string) error {
+	if s.require == nil {
+		return nil
+	}
+	return s.require(capability)
+}
+
+// Get returns the value stored under key
+func (s *KVStore) Get(key string) (interface{}, bool, error) {
+	if err := s.check(Capabilities.PluginDataRead); err != nil {
+		return nil, false, err
+	}
+	s.file.mu.RLock()
+	raw, ok := s.file.entries[key]
+	s.file.mu.RUnlock()
+	return decodeStoreValue(raw, ok)
+}
+
+// Put stores value under key
+func (s *KVStore) Put(key string, value interface{}) error {
+	return s.Transaction(func(tx *KVTx) error {
+		return tx.Put(key, value)
+	})
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (s *KVStore) Delete(key string) error {
+	return s.Transaction(func(tx *KVTx) error {
+		return tx.Delete(key)
+	})
+}
+
+// List returns the keys starting with prefix, sorted
+func (s *KVStore) List(prefix string) ([]string, error) {
+	if err := s.check(Capabilities.PluginDataRead); err != nil {
+		return nil, err
+	}
+	s.file.mu.RLock()
+	defer s.file.mu.RUnlock()
+	return listKeys(s.file.entries, nil, prefix), nil
+}
+
+// Size returns the bytes the store takes on disk
+func (s *KVStore) Size() int64 {
+	s.file.mu.RLock()
+	defer s.file.mu.RUnlock()
+	data, _ := encodeStoreFile(s.file.scope, s.file.entries)
+	return int64(len(data))
+}
+
+// Transaction runs fn and commits its writes together. If fn returns an
+// error or the writes would exceed the quota, none of them are applied.
+func (s *KVStore) Transaction(fn func(tx *KVTx) error) error {
+	if err := s.check(Capabilities.PluginDataWrite); err != nil {
+		return err
+	}
+	tx := &KVTx{store: s, changes: make(map[string]json.RawMessage)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return s.file.commit(tx.changes)
+}
+
+// KVTx is an open transaction; reads see its own uncommitted writes
+type KVTx struct {
+	store   *KVStore
+	changes map[string]json.RawMessage
+}
+
+// Get returns the value under key as the transaction sees it
+func (tx *KVTx) Get(key string) (interface{}, bool, error) {
+	if raw, ok := tx.changes[key]; ok {
+		return decodeStoreValue(raw, raw != nil)
+	}
+	tx.store.file.mu.RLock()
+	raw, ok := tx.store.file.entries[key]
+	tx.store.file.mu.RUnlock()
+	return decodeStoreValue(raw, ok)
+}
+
+// Put stores value under key when the transaction commits
+func (tx *KVTx) Put(key string, value interface{}) error {
+	if key == "" {
+		return fmt.Errorf("storage key is empty")
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot store %q: %w", key, err)
+	}
+	tx.changes[key] = raw
+	return nil
+}
+
+// Delete removes key when the transaction commits
+func (tx *KVTx) Delete(key string) error {
+	tx.changes[key] = nil
+	return nil
+}
+
+// List returns the keys starting with prefix as the transaction sees them
+func (tx *KVTx) List(prefix string) ([]string, error) {
+	tx.store.file.mu.RLock()
+	defer tx.store.file.mu.RUnlock()
+	return listKeys(tx.store.file.entries, tx.changes, prefix), nil
+}
+
+func listKeys(entries, changes map[string]json.RawMessage, prefix string) []string {
+	keys := make([]string, 0)
+	for k := range entries {
+		if v, changed := changes[k]; changed && v == nil {
+			continue
+		}
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range changes {
+		if _, exists := entries[k]; !exists && v != nil && strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func decodeStoreValue(raw json.RawMessage, ok bool) (interface{}, bool, error) {
+	if !ok {
+		return nil, false, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stored value: %w", err)
+	}
+	return v, true, nil
+}
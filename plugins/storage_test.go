@@ -0,0 +1,215 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestStoragePersists tests put/get/delete/list survive a new manager
+func TestStoragePersists(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStorageManager(dir).Open("tracker", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for key, value := range map[string]interface{}{
+		"quest.narshe": map[string]interface{}{"done": true},
+		"quest.figaro": 2,
+		"runs":         "three",
+	} {
+		if err := store.Put(key, value); err != nil {
+			t.Fatalf("Put(%s) failed: %v", key, err)
+		}
+	}
+	if err := store.Delete("runs"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	reopened, err := NewStorageManager(dir).Open("tracker", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	keys, _ := reopened.List("quest.")
+	if !reflect.DeepEqual(keys, []string{"quest.figaro", "quest.narshe"}) {
+		t.Errorf("List = %v", keys)
+	}
+	if v, ok, _ := reopened.Get("quest.figaro"); !ok || v != float64(2) {
+		t.Errorf("Get(quest.figaro) = %v, %v", v, ok)
+	}
+	if v, _, _ := reopened.Get("quest.narshe"); !reflect.DeepEqual(v, map[string]interface{}{"done": true}) {
+		t.Errorf("Get(quest.narshe) = %v", v)
+	}
+	if _, ok, _ := reopened.Get("runs"); ok {
+		t.Error("deleted key is still stored")
+	}
+}
+
+// TestStorageTransaction tests transactions see their writes and roll back
+func TestStorageTransaction(t *testing.T) {
+	store, _ := NewStorageManager(t.TempDir()).Open("tracker", "")
+	store.Put("a", 1)
+
+	failed := errors.New("abort")
+	err := store.Transaction(func(tx *KVTx) error {
+		tx.Put("b", 2)
+		tx.Delete("a")
+		if keys, _ := tx.List(""); !reflect.DeepEqual(keys, []string{"b"}) {
+			t.Errorf("tx.List = %v, want [b]", keys)
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("Transaction = %v, want the callback's error", err)
+	}
+	if keys, _ := store.List(""); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("aborted transaction changed the store: %v", keys)
+	}
+
+	if err := store.Transaction(func(tx *KVTx) error {
+		tx.Put("b", 2)
+		return tx.Delete("a")
+	}); err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if keys, _ := store.List(""); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Errorf("committed transaction left %v, want [b]", keys)
+	}
+}
+
+// TestStorageQuota tests the quota covers every scope of a plugin
+func TestStorageQuota(t *testing.T) {
+	sm := NewStorageManager(t.TempDir())
+	sm.SetQuota("tracker", 200)
+	global, _ := sm.Open("tracker", "")
+	perSave, _ := sm.Open("tracker", "/saves/slot1.json")
+
+	if err := global.Put("notes", strings.Repeat("x", 80)); err != nil {
+		t.Fatalf("Put within quota failed: %v", err)
+	}
+	err := perSave.Put("notes", strings.Repeat("y", 80))
+	if !errors.Is(err, ErrStorageQuotaExceeded) {
+		t.Fatalf("Put = %v, want ErrStorageQuotaExceeded", err)
+	}
+	if _, ok, _ := perSave.Get("notes"); ok {
+		t.Error("rejected write was applied")
+	}
+	if usage, _ := sm.Usage("tracker"); usage > 200 {
+		t.Errorf("usage %d is over the quota", usage)
+	}
+
+	other, _ := sm.Open("other", "")
+	if err := other.Put("notes", strings.Repeat("z", 500)); err != nil {
+		t.Errorf("quota leaked into another plugin: %v", err)
+	}
+}
+
+// TestStorageSaveScopes tests per-save stores are kept apart
+func TestStorageSaveScopes(t *testing.T) {
+	sm := NewStorageManager(t.TempDir())
+	base := NewAPIImpl(nil, nil)
+	base.SetStorageManager(sm)
+	api := base.ForPlugin("tracker", []string{"fs.plugin-data:*"}, nil)
+	ctx := context.Background()
+
+	if _, err := api.Storage(ctx, true); err != ErrNoSaveScope {
+		t.Errorf("Storage(perSave) with no save = %v, want ErrNoSaveScope", err)
+	}
+	sm.SetSaveScope("slot1")
+	slot1, err := api.Storage(ctx, true)
+	if err != nil {
+		t.Fatalf("Storage failed: %v", err)
+	}
+	slot1.Put("seen", true)
+	sm.SetSaveScope("slot2")
+	slot2, _ := api.Storage(ctx, true)
+	if _, ok, _ := slot2.Get("seen"); ok {
+		t.Error("slot2 sees slot1's data")
+	}
+	global, _ := api.Storage(ctx, false)
+	if _, ok, _ := global.Get("seen"); ok {
+		t.Error("the plugin-wide store sees per-save data")
+	}
+}
+
+// TestStorageRequiresCapabilities tests stores opened through the API check fs.plugin-data
+func TestStorageRequiresCapabilities(t *testing.T) {
+	base := NewAPIImpl(nil, nil)
+	base.SetStorageManager(NewStorageManager(t.TempDir()))
+	api := base.ForPlugin("reader", []string{Capabilities.PluginDataRead}, nil)
+
+	store, err := api.Storage(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Storage failed: %v", err)
+	}
+	if _, _, err := store.Get("x"); err != nil {
+		t.Errorf("Get with read access failed: %v", err)
+	}
+	if err := store.Put("x", 1); err != ErrInsufficientPermissions {
+		t.Errorf("Put = %v, want ErrInsufficientPermissions", err)
+	}
+	if _, err := NewAPIImpl(nil, nil).Storage(context.Background(), false); err != ErrStorageUnavailable {
+		t.Errorf("unscoped API Storage = %v, want ErrStorageUnavailable", err)
+	}
+}
+
+// TestStorageSnapshotRestore tests a snapshot brings back every scope
+func TestStorageSnapshotRestore(t *testing.T) {
+	sm := NewStorageManager(t.TempDir())
+	global, _ := sm.Open("tracker", "")
+	perSave, _ := sm.Open("tracker", "slot1")
+	global.Put("a", 1)
+	perSave.Put("b", 2)
+
+	snap, err := sm.Snapshot("tracker")
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	global.Put("a", 99)
+	global.Put("c", 3)
+	perSave.Delete("b")
+
+	if err := sm.Restore("tracker", snap); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if v, _, _ := global.Get("a"); v != float64(1) {
+		t.Errorf("a = %v, want 1", v)
+	}
+	if _, ok, _ := global.Get("c"); ok {
+		t.Error("c was written after the snapshot and should be gone")
+	}
+	reopened, _ := NewStorageManager(sm.Dir()).Open("tracker", "slot1")
+	if v, _, _ := reopened.Get("b"); v != float64(2) {
+		t.Errorf("b = %v, want 2 on disk", v)
+	}
+}
+
+// TestHotReloadSnapshotIncludesStorage tests SnapshotState carries plugin data
+func TestHotReloadSnapshotIncludesStorage(t *testing.T) {
+	m := NewManager(t.TempDir(), NewAPIImpl(nil, nil))
+	m.plugins["tracker"] = NewPlugin(&PluginMetadata{ID: "tracker", Name: "tracker", Version: "1.0.0", Author: "t"}, nil)
+	store, _ := m.GetStorageManager().Open("tracker", "")
+	store.Put("runs", 3)
+
+	h, err := NewHotReloadManager(m)
+	if err != nil {
+		t.Skipf("file watcher unavailable: %v", err)
+	}
+	state, err := h.SnapshotState("tracker")
+	if err != nil {
+		t.Fatalf("SnapshotState failed: %v", err)
+	}
+	if string(state.Storage[""]["runs"]) != "3" {
+		t.Fatalf("snapshot storage = %v", state.Storage)
+	}
+
+	store.Delete("runs")
+	if err := h.RestoreState("tracker", state); err != nil {
+		t.Fatalf("RestoreState failed: %v", err)
+	}
+	if v, _, _ := store.Get("runs"); v != float64(3) {
+		t.Errorf("runs = %v after restore, want 3", v)
+	}
+}
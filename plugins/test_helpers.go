@@ -5,6 +5,7 @@ import (
 
 	"ffvi_editor/models"
 	modelsPR "ffvi_editor/models/pr"
+	"ffvi_editor/models/search"
 )
 
 // MockAPI is a mock implementation of the PluginAPI for testing
@@ -80,6 +81,21 @@ func (m *MockAPI) FindItems(ctx context.Context, predicate func(*modelsPR.Row) b
 	return nil
 }
 
+// Query mocks the Query function
+func (m *MockAPI) Query(ctx context.Context, query string) (*search.QueryResult, error) {
+	return &search.QueryResult{}, nil
+}
+
+// GetTreasures mocks the GetTreasures function
+func (m *MockAPI) GetTreasures(ctx context.Context, filter modelsPR.TreasureFilter) ([]modelsPR.TreasureState, error) {
+	return nil, nil
+}
+
+// SetTreasureOpened mocks the SetTreasureOpened function
+func (m *MockAPI) SetTreasureOpened(ctx context.Context, id int, opened bool) error {
+	return nil
+}
+
 // RegisterHook mocks the RegisterHook function
 func (m *MockAPI) RegisterHook(event string, callback func(interface{}) error) error {
 	return nil
@@ -120,6 +136,21 @@ func (m *MockAPI) SetSetting(key string, value interface{}) error {
 	return nil
 }
 
+// Subscribe mocks the Subscribe function
+func (m *MockAPI) Subscribe(ctx context.Context, pattern string, replay bool, handler EventHandler) (*Subscription, error) {
+	return nil, ErrEventBusUnavailable
+}
+
+// Publish mocks the Publish function
+func (m *MockAPI) Publish(ctx context.Context, topic string, payload interface{}) error {
+	return nil
+}
+
+// Storage mocks the Storage function
+func (m *MockAPI) Storage(ctx context.Context, perSave bool) (*KVStore, error) {
+	return nil, ErrStorageUnavailable
+}
+
 // HasPermission mocks the HasPermission function
 func (m *MockAPI) HasPermission(permission string) bool {
 	return true
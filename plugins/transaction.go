@@ -0,0 +1,617 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Directories used by install transactions, inside the plugin directory
+const (
+	stagingDirName  = ".staging"
+	previousDirName = ".previous"
+)
+
+// PackageSource supplies plugin packages for install and upgrade plans.
+// marketplace.Client satisfies it.
+type PackageSource interface {
+	AvailableVersions(ctx context.Context, pluginID string) ([]string, error)
+	FetchPackage(ctx context.Context, pluginID, version string) ([]byte, error)
+}
+
+// PlanAction is the change a plan step makes to one plugin
+type PlanAction string
+
+const (
+	PlanInstall   PlanAction = "install"
+	PlanUpgrade   PlanAction = "upgrade"
+	PlanDowngrade PlanAction = "downgrade"
+	PlanUninstall PlanAction = "uninstall"
+)
+
+// PlanStep is a single plugin change in an InstallPlan
+type PlanStep struct {
+	PluginID    string
+	Action      PlanAction
+	FromVersion string
+	ToVersion   string
+	Reason      string // "requested" or the dependent that needs it
+	// Permissions are those the new version declares; NewPermissions are
+	// the capabilities it gains over the installed version, which is what
+	// the user consents to when applying the plan
+	Permissions    []string
+	NewPermissions []string
+
+	data []byte
+}
+
+// InstallPlan is the full set of changes for an install, upgrade or
+// uninstall. Steps are ordered so dependencies come before dependents.
+type InstallPlan struct {
+	Target   string
+	Steps    []*PlanStep
+	Resolved map[string]string // dependency pluginID -> version after the plan
+}
+
+// Empty reports whether the plan changes nothing
+func (p *InstallPlan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// String formats the plan for display before it is applied
+func (p *InstallPlan) String() string {
+	if p.Empty() {
+		return fmt.Sprintf("%s: nothing to do\n", p.Target)
+	}
+
+	var sb strings.Builder
+	for _, step := range p.Steps {
+		switch step.Action {
+		case PlanInstall:
+			fmt.Fprintf(&sb, "  install    %-30s %s", step.PluginID, step.ToVersion)
+		case PlanUninstall:
+			fmt.Fprintf(&sb, "  uninstall  %-30s %s", step.PluginID, step.FromVersion)
+		default:
+			fmt.Fprintf(&sb, "  %-10s %-30s %s -> %s", step.Action, step.PluginID, step.FromVersion, step.ToVersion)
+		}
+		if step.Reason != "" {
+			fmt.Fprintf(&sb, " (%s)", step.Reason)
+		}
+		sb.WriteString("\n")
+		if len(step.NewPermissions) > 0 {
+			sb.WriteString("      will be allowed to:\n")
+			for _, line := range DescribePermissions(step.NewPermissions) {
+				fmt.Fprintf(&sb, "        %s\n", line)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// InstalledPlugins returns the metadata of every plugin directory under the
+// plugin directory, keyed by plugin ID
+func (m *Manager) InstalledPlugins() (map[string]*PluginMetadata, error) {
+	installed := make(map[string]*PluginMetadata)
+	entries, err := os.ReadDir(m.pluginDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return installed, nil
+		}
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		meta, err := readInstalledMetadata(filepath.Join(m.pluginDir, entry.Name()))
+		if err != nil {
+			// Not a packaged plugin
+			continue
+		}
+		installed[meta.ID] = meta
+	}
+	return installed, nil
+}
+
+// PlanInstall computes the steps to install or upgrade pluginID to version
+// ("" for the newest version every installed dependent accepts), pulling in
+// missing or outdated dependencies from source. Nothing is changed on disk.
+func (m *Manager) PlanInstall(ctx context.Context, source PackageSource, pluginID, version string) (*InstallPlan, error) {
+	installed, err := m.InstalledPlugins()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		ctx:       ctx,
+		source:    source,
+		installed: installed,
+		final:     make(map[string]*PluginMetadata, len(installed)),
+		steps:     make(map[string]*PlanStep),
+	}
+	for id, meta := range installed {
+		p.final[id] = meta
+	}
+
+	requested, err := ParseConstraint(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.require(pluginID, requested, "requested", true); err != nil {
+		return nil, err
+	}
+
+	// Check the resulting graph with the same resolver used for loaded plugins
+	resolver := NewDependencyResolver()
+	for _, meta := range p.final {
+		if err := resolver.AddMetadata(meta); err != nil {
+			return nil, err
+		}
+	}
+	resolved, err := resolver.ResolveDependencies(pluginID, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDependencyConflict, err)
+	}
+	var conflicts []string
+	for _, c := range resolver.DetectConflicts(nil) {
+		if p.steps[c.PluginID] != nil || p.steps[c.DependencyID] != nil {
+			conflicts = append(conflicts, c.Error())
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("%w: %s", ErrDependencyConflict, strings.Join(conflicts, "; "))
+	}
+
+	return &InstallPlan{
+		Target:   pluginID,
+		Steps:    orderSteps(p.steps, p.final),
+		Resolved: resolved,
+	}, nil
+}
+
+// PlanUninstall computes the steps to remove pluginID. Plugins that other
+// installed or loaded plugins depend on are refused.
+func (m *Manager) PlanUninstall(pluginID string) (*InstallPlan, error) {
+	installed, err := m.InstalledPlugins()
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := installed[pluginID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPluginNotFound, pluginID)
+	}
+
+	resolver := NewDependencyResolver()
+	for _, installedMeta := range installed {
+		if err := resolver.AddMetadata(installedMeta); err != nil {
+			return nil, err
+		}
+	}
+
+	dependents := make(map[string]bool)
+	for _, id := range resolver.GetDependents(pluginID) {
+		dependents[id] = true
+	}
+	if m.dependencyResolver != nil {
+		for _, id := range m.dependencyResolver.GetDependents(pluginID) {
+			dependents[id] = true
+		}
+	}
+	if len(dependents) > 0 {
+		ids := make([]string, 0, len(dependents))
+		for id := range dependents {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return nil, fmt.Errorf("%w: %s is required by %s", ErrPluginHasDependents, pluginID, strings.Join(ids, ", "))
+	}
+
+	return &InstallPlan{
+		Target: pluginID,
+		Steps: []*PlanStep{{
+			PluginID:    pluginID,
+			Action:      PlanUninstall,
+			FromVersion: meta.Version,
+			Reason:      "requested",
+		}},
+	}, nil
+}
+
+// ApplyPlan carries out a plan atomically. Every package is verified and
+// unpacked into a staging directory first; only then are the plugin
+// directories swapped in. The replaced version of each plugin is kept for
+// RestorePreviousVersion, and any failure puts all directories back.
+func (m *Manager) ApplyPlan(ctx context.Context, plan *InstallPlan) error {
+	for _, step := range plan.Steps {
+		if err := validatePluginID(step.PluginID); err != nil {
+			return err
+		}
+	}
+	m.installMu.Lock()
+	defer m.installMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Join(m.pluginDir, stagingDirName), 0755); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(filepath.Join(m.pluginDir, stagingDirName), "tx-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// Stage: verify and unpack everything before touching installed plugins
+	for _, step := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if step.Action == PlanUninstall {
+			continue
+		}
+
+		pkg, verifyErr := m.verifyPackageData(step.data)
+		if verifyErr != nil {
+			if err := m.applySignaturePolicy(step.PluginID, step.data, verifyErr); err != nil {
+				return fmt.Errorf("%s: %w", step.PluginID, err)
+			}
+			if pkg == nil {
+				return fmt.Errorf("%s: %w", step.PluginID, verifyErr)
+			}
+		}
+		if pkg.Manifest.PluginID != step.PluginID || pkg.Manifest.Version != step.ToVersion {
+			return fmt.Errorf("%w: expected %s %s, package contains %s %s", ErrInvalidPackage,
+				step.PluginID, step.ToVersion, pkg.Manifest.PluginID, pkg.Manifest.Version)
+		}
+		if err := pkg.Extract(filepath.Join(staging, "new", step.PluginID)); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", step.PluginID, err)
+		}
+	}
+
+	// Swap: move each current directory aside and the staged one in
+	if err := os.MkdirAll(filepath.Join(m.pluginDir, previousDirName), 0755); err != nil {
+		return fmt.Errorf("failed to create previous version directory: %w", err)
+	}
+	var done []*planSwap
+	for _, step := range plan.Steps {
+		swap := &planSwap{
+			step:     step,
+			current:  filepath.Join(m.pluginDir, step.PluginID),
+			previous: m.previousDir(step.PluginID),
+			backup:   filepath.Join(staging, "previous", step.PluginID),
+			staged:   filepath.Join(staging, "new", step.PluginID),
+		}
+		err := swap.apply()
+		if err == nil {
+			done = append(done, swap)
+			continue
+		}
+
+		// Undo in reverse order so the plugin directory is as it was
+		for i := len(done) - 1; i >= 0; i-- {
+			if undoErr := done[i].undo(); undoErr != nil {
+				err = fmt.Errorf("%v (rollback of %s failed: %v)", err, done[i].step.PluginID, undoErr)
+			}
+		}
+		m.logPackageEvent(step.PluginID, "ROLLBACK_TRANSACTION", "error", err.Error(), map[string]interface{}{
+			"target": plan.Target,
+		})
+		return fmt.Errorf("failed to apply %s of %s: %w", step.Action, step.PluginID, err)
+	}
+
+	for _, step := range plan.Steps {
+		m.logPackageEvent(step.PluginID, strings.ToUpper(string(step.Action))+"_PLUGIN", "success", "", map[string]interface{}{
+			"from":   step.FromVersion,
+			"to":     step.ToVersion,
+			"target": plan.Target,
+		})
+		if step.Action == PlanUninstall {
+			if _, err := m.GetPlugin(step.PluginID); err == nil {
+				if err := m.UnloadPlugin(ctx, step.PluginID); err != nil {
+					return fmt.Errorf("%s uninstalled but failed to unload: %w", step.PluginID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PreviousVersion returns the version kept by the last transaction that
+// replaced or removed pluginID
+func (m *Manager) PreviousVersion(pluginID string) (string, bool) {
+	meta, err := readInstalledMetadata(m.previousDir(pluginID))
+	if err != nil {
+		return "", false
+	}
+	return meta.Version, true
+}
+
+// RestorePreviousVersion swaps the kept previous version of a plugin back
+// into place. The version it replaces becomes the new previous version, so
+// a restore can itself be undone.
+func (m *Manager) RestorePreviousVersion(pluginID string) error {
+	if err := validatePluginID(pluginID); err != nil {
+		return err
+	}
+	m.installMu.Lock()
+	defer m.installMu.Unlock()
+
+	previous := m.previousDir(pluginID)
+	if _, err := os.Stat(previous); err != nil {
+		return fmt.Errorf("no previous version of %s", pluginID)
+	}
+
+	current := filepath.Join(m.pluginDir, pluginID)
+	aside := previous + ".swap"
+	hasCurrent := false
+	if _, err := os.Stat(current); err == nil {
+		if err := os.Rename(current, aside); err != nil {
+			return err
+		}
+		hasCurrent = true
+	}
+	if err := os.Rename(previous, current); err != nil {
+		if hasCurrent {
+			os.Rename(aside, current)
+		}
+		return err
+	}
+	if hasCurrent {
+		if err := os.Rename(aside, previous); err != nil {
+			return err
+		}
+	}
+
+	m.logPackageEvent(pluginID, "RESTORE_PREVIOUS", "success", "", nil)
+	return nil
+}
+
+// previousDir is where the replaced version of a plugin is kept
+func (m *Manager) previousDir(pluginID string) string {
+	return filepath.Join(m.pluginDir, previousDirName, pluginID)
+}
+
+// planSwap moves one plugin directory during ApplyPlan and can undo it
+type planSwap struct {
+	step                              *PlanStep
+	current, previous, backup, staged string
+	hadCurrent, hadPrevious, placed   bool
+}
+
+func (s *planSwap) apply() error {
+	if _, err := os.Stat(s.previous); err == nil {
+		if err := os.MkdirAll(filepath.Dir(s.backup), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(s.previous, s.backup); err != nil {
+			return err
+		}
+		s.hadPrevious = true
+	}
+	if _, err := os.Stat(s.current); err == nil {
+		if err := os.Rename(s.current, s.previous); err != nil {
+			return err
+		}
+		s.hadCurrent = true
+	}
+	if s.step.Action != PlanUninstall {
+		if err := os.Rename(s.staged, s.current); err != nil {
+			return err
+		}
+		s.placed = true
+	}
+	return nil
+}
+
+func (s *planSwap) undo() error {
+	if s.placed {
+		if err := os.RemoveAll(s.current); err != nil {
+			return err
+		}
+	}
+	if s.hadCurrent {
+		if err := os.Rename(s.previous, s.current); err != nil {
+			return err
+		}
+	}
+	if s.hadPrevious {
+		if err := os.Rename(s.backup, s.previous); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planner accumulates plan steps while walking dependencies
+type planner struct {
+	ctx       context.Context
+	source    PackageSource
+	installed map[string]*PluginMetadata
+	final     map[string]*PluginMetadata // state after the plan
+	steps     map[string]*PlanStep
+}
+
+// require makes sure the final state has a version of pluginID that
+// satisfies constraint, fetching one from the source if needed
+func (p *planner) require(pluginID string, constraint *VersionConstraint, reason string, requested bool) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	if meta, ok := p.final[pluginID]; ok {
+		current, err := ParseVersion(meta.Version)
+		if err != nil {
+			return err
+		}
+		if p.steps[pluginID] != nil {
+			if !constraint.Satisfies(current) {
+				return fmt.Errorf("%w: %s needs %s %s but the plan selects %s",
+					ErrDependencyConflict, reason, pluginID, constraint, meta.Version)
+			}
+			return nil
+		}
+		// An installed dependency that already fits is left alone; an
+		// explicit request always looks for the newest acceptable version
+		if !requested && constraint.Satisfies(current) {
+			return nil
+		}
+	}
+
+	constraints := append(p.dependentConstraints(pluginID), constraint)
+	available, err := p.source.AvailableVersions(p.ctx, pluginID)
+	if err != nil {
+		return fmt.Errorf("failed to list versions of %s: %w", pluginID, err)
+	}
+	versions := make([]*Version, 0, len(available))
+	for _, v := range available {
+		if parsed, err := ParseVersion(v); err == nil {
+			versions = append(versions, parsed)
+		}
+	}
+	best := FindCompatibleVersion(versions, constraints)
+	if best == nil {
+		parts := make([]string, 0, len(constraints))
+		for _, c := range constraints {
+			if c.String() != "" {
+				parts = append(parts, c.String())
+			}
+		}
+		return fmt.Errorf("%w: no version of %s satisfies %s (%s)", ErrDependencyConflict,
+			pluginID, strings.Join(parts, ", "), reason)
+	}
+
+	step := &PlanStep{PluginID: pluginID, Action: PlanInstall, ToVersion: best.String(), Reason: reason}
+	if meta, ok := p.installed[pluginID]; ok {
+		step.FromVersion = meta.Version
+		from, _ := ParseVersion(meta.Version)
+		switch {
+		case from != nil && from.Equal(best):
+			// Already at the best version
+			return nil
+		case from != nil && from.GreaterThan(best):
+			step.Action = PlanDowngrade
+		default:
+			step.Action = PlanUpgrade
+		}
+	}
+
+	data, err := p.source.FetchPackage(p.ctx, pluginID, step.ToVersion)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s %s: %w", pluginID, step.ToVersion, err)
+	}
+	pkg, err := ReadPackage(data)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", pluginID, step.ToVersion, err)
+	}
+	if pkg.Manifest.PluginID != pluginID || pkg.Manifest.Version != step.ToVersion {
+		return fmt.Errorf("%w: expected %s %s, package contains %s %s", ErrInvalidPackage,
+			pluginID, step.ToVersion, pkg.Manifest.PluginID, pkg.Manifest.Version)
+	}
+	step.data = data
+	if err := ValidatePermissions(pkg.Metadata.Permissions); err != nil {
+		return fmt.Errorf("%s %s: %w", pluginID, step.ToVersion, err)
+	}
+	if err := checkDeclarations(pkg.Metadata); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	step.Permissions = pkg.Metadata.Permissions
+	step.NewPermissions = newCapabilities(p.installed[pluginID], pkg.Metadata.Permissions)
+
+	p.steps[pluginID] = step
+	p.final[pluginID] = pkg.Metadata
+
+	for _, dep := range pkg.Metadata.Dependencies {
+		depID, depConstraint, err := ParseDependency(dep)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", pluginID, err)
+		}
+		if err := p.require(depID, depConstraint, "required by "+pluginID, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCapabilities lists the capabilities perms grants that the installed
+// version, if any, did not
+func newCapabilities(installed *PluginMetadata, perms []string) []string {
+	var before *Grants
+	if installed != nil {
+		before = NewGrants(installed.Permissions)
+	}
+	var added []string
+	for _, c := range NewGrants(perms).Capabilities() {
+		if before == nil || !before.Allows(c) {
+			added = append(added, c)
+		}
+	}
+	return added
+}
+
+// dependentConstraints returns the constraints other plugins in the final
+// state place on pluginID
+func (p *planner) dependentConstraints(pluginID string) []*VersionConstraint {
+	var constraints []*VersionConstraint
+	for id, meta := range p.final {
+		if id == pluginID {
+			continue
+		}
+		for _, dep := range meta.Dependencies {
+			depID, constraint, err := ParseDependency(dep)
+			if err == nil && depID == pluginID {
+				constraints = append(constraints, constraint)
+			}
+		}
+	}
+	return constraints
+}
+
+// orderSteps sorts steps so each plugin's dependencies are applied first
+func orderSteps(steps map[string]*PlanStep, final map[string]*PluginMetadata) []*PlanStep {
+	ids := make([]string, 0, len(steps))
+	for id := range steps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ordered := make([]*PlanStep, 0, len(steps))
+	visited := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		if meta, ok := final[id]; ok {
+			deps := make([]string, 0, len(meta.Dependencies))
+			for _, dep := range meta.Dependencies {
+				if depID, _, err := ParseDependency(dep); err == nil {
+					deps = append(deps, depID)
+				}
+			}
+			sort.Strings(deps)
+			for _, depID := range deps {
+				visit(depID)
+			}
+		}
+		if step, ok := steps[id]; ok {
+			ordered = append(ordered, step)
+		}
+	}
+	for _, id := range ids {
+		visit(id)
+	}
+	return ordered
+}
+
+// readInstalledMetadata reads metadata.json from an installed plugin directory
+func readInstalledMetadata(dir string) (*PluginMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PackageMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	return parsePackageMetadata(map[string][]byte{PackageMetadataFile: data})
+}
@@ -0,0 +1,296 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memorySource is a PackageSource backed by signed packages held in memory
+type memorySource struct {
+	signer   *SecurityManager
+	packages map[string]map[string][]byte // id -> version -> package
+}
+
+func newMemorySource(t *testing.T) *memorySource {
+	return &memorySource{
+		signer:   setupSecurityManagerWithKeys(t),
+		packages: make(map[string]map[string][]byte),
+	}
+}
+
+// publish builds and signs a package with the given dependencies
+func (s *memorySource) publish(t *testing.T, id, version string, deps ...string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), id)
+	os.MkdirAll(dir, 0755)
+	quoted := make([]string, len(deps))
+	for i, d := range deps {
+		quoted[i] = `"` + d + `"`
+	}
+	meta := fmt.Sprintf(`{"id":%q,"name":%q,"version":%q,"author":"tester","dependencies":[%s]}`,
+		id, id, version, strings.Join(quoted, ","))
+	os.WriteFile(filepath.Join(dir, PackageMetadataFile), []byte(meta), 0644)
+	os.WriteFile(filepath.Join(dir, "plugin.lua"), []byte("-- "+id+" "+version), 0644)
+
+	data, err := BuildPackage(dir)
+	if err != nil {
+		t.Fatalf("BuildPackage failed: %v", err)
+	}
+	if data, err = s.signer.SignPackage(data, "tester"); err != nil {
+		t.Fatalf("SignPackage failed: %v", err)
+	}
+	if s.packages[id] == nil {
+		s.packages[id] = make(map[string][]byte)
+	}
+	s.packages[id][version] = data
+}
+
+func (s *memorySource) AvailableVersions(ctx context.Context, pluginID string) ([]string, error) {
+	versions, ok := s.packages[pluginID]
+	if !ok {
+		return nil, ErrPluginNotFound
+	}
+	list := make([]string, 0, len(versions))
+	for v := range versions {
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (s *memorySource) FetchPackage(ctx context.Context, pluginID, version string) ([]byte, error) {
+	data, ok := s.packages[pluginID][version]
+	if !ok {
+		return nil, ErrPluginNotFound
+	}
+	return data, nil
+}
+
+// newTransactionManager creates a manager trusting the source's signer
+func newTransactionManager(t *testing.T, source *memorySource) *Manager {
+	m := NewManager(t.TempDir(), nil)
+	trustSigner(t, m.securityMgr, source.signer)
+	return m
+}
+
+// installedVersion returns the version in an installed plugin's metadata
+func installedVersion(t *testing.T, m *Manager, id string) string {
+	t.Helper()
+	meta, err := readInstalledMetadata(filepath.Join(m.pluginDir, id))
+	if err != nil {
+		return ""
+	}
+	return meta.Version
+}
+
+// TestPlanInstallPullsDependencies tests that missing dependencies are planned first
+func TestPlanInstallPullsDependencies(t *testing.T) {
+	source := newMemorySource(t)
+	source.publish(t, "core", "1.0.0")
+	source.publish(t, "core", "1.4.0")
+	source.publish(t, "core", "2.0.0")
+	source.publish(t, "ui", "1.0.0", "core@^1.2.0")
+	m := newTransactionManager(t, source)
+	ctx := context.Background()
+
+	plan, err := m.PlanInstall(ctx, source, "ui", "")
+	if err != nil {
+		t.Fatalf("PlanInstall failed: %v", err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[0].PluginID != "core" || plan.Steps[1].PluginID != "ui" {
+		t.Fatalf("plan = %s, want core then ui", plan)
+	}
+	if plan.Steps[0].ToVersion != "1.4.0" {
+		t.Errorf("core planned at %s, want 1.4.0 (newest matching ^1.2.0)", plan.Steps[0].ToVersion)
+	}
+	if plan.Resolved["core"] != "1.4.0" {
+		t.Errorf("Resolved = %v, want core 1.4.0", plan.Resolved)
+	}
+	if _, err := os.Stat(filepath.Join(m.pluginDir, "core")); !os.IsNotExist(err) {
+		t.Error("PlanInstall should not change the plugin directory")
+	}
+
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	if installedVersion(t, m, "core") != "1.4.0" || installedVersion(t, m, "ui") != "1.0.0" {
+		t.Error("plan was not applied")
+	}
+	if _, err := os.Stat(filepath.Join(m.pluginDir, stagingDirName)); err == nil {
+		entries, _ := os.ReadDir(filepath.Join(m.pluginDir, stagingDirName))
+		if len(entries) != 0 {
+			t.Error("staging directory should be cleaned up")
+		}
+	}
+
+	installed, _ := m.InstalledPlugins()
+	if len(installed) != 2 {
+		t.Errorf("InstalledPlugins() = %d entries, want 2", len(installed))
+	}
+}
+
+// TestPlanUpgradeRespectsDependents tests that upgrades stay within what dependents accept
+func TestPlanUpgradeRespectsDependents(t *testing.T) {
+	source := newMemorySource(t)
+	source.publish(t, "core", "1.0.0")
+	source.publish(t, "ui", "1.0.0", "core@^1.0.0")
+	m := newTransactionManager(t, source)
+	ctx := context.Background()
+
+	plan, _ := m.PlanInstall(ctx, source, "ui", "")
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+
+	source.publish(t, "core", "1.1.0")
+	source.publish(t, "core", "2.0.0")
+
+	plan, err := m.PlanInstall(ctx, source, "core", "")
+	if err != nil {
+		t.Fatalf("PlanInstall(upgrade) failed: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Action != PlanUpgrade || plan.Steps[0].ToVersion != "1.1.0" {
+		t.Fatalf("plan = %s, want upgrade core 1.0.0 -> 1.1.0", plan)
+	}
+
+	if _, err := m.PlanInstall(ctx, source, "core", "2.0.0"); !errors.Is(err, ErrDependencyConflict) {
+		t.Errorf("PlanInstall(core 2.0.0) = %v, want ErrDependencyConflict", err)
+	}
+
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan(upgrade) failed: %v", err)
+	}
+	if installedVersion(t, m, "core") != "1.1.0" {
+		t.Errorf("core = %s, want 1.1.0", installedVersion(t, m, "core"))
+	}
+	if v, ok := m.PreviousVersion("core"); !ok || v != "1.0.0" {
+		t.Errorf("PreviousVersion(core) = %s, %v, want 1.0.0", v, ok)
+	}
+
+	// Up to date plans are empty
+	plan, err = m.PlanInstall(ctx, source, "core", "")
+	if err != nil || !plan.Empty() {
+		t.Errorf("PlanInstall(current) = %v, %v, want empty plan", plan, err)
+	}
+}
+
+// TestApplyPlanIsAtomic tests that a bad package leaves the plugin directory untouched
+func TestApplyPlanIsAtomic(t *testing.T) {
+	source := newMemorySource(t)
+	source.publish(t, "core", "1.0.0")
+	source.publish(t, "ui", "1.0.0", "core")
+	m := newTransactionManager(t, source)
+	ctx := context.Background()
+
+	plan, err := m.PlanInstall(ctx, source, "ui", "")
+	if err != nil {
+		t.Fatalf("PlanInstall failed: %v", err)
+	}
+
+	// Replace ui with a package signed by a different key under the same name
+	other := newMemorySource(t)
+	other.publish(t, "ui", "1.0.0", "core")
+	for _, step := range plan.Steps {
+		if step.PluginID == "ui" {
+			step.data = other.packages["ui"]["1.0.0"]
+		}
+	}
+
+	if err := m.ApplyPlan(ctx, plan); !errors.Is(err, ErrPackageTampered) {
+		t.Fatalf("ApplyPlan = %v, want ErrPackageTampered", err)
+	}
+	for _, id := range []string{"core", "ui"} {
+		if _, err := os.Stat(filepath.Join(m.pluginDir, id)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be installed after a failed transaction", id)
+		}
+	}
+}
+
+// TestPlanUninstallRefusesDependents tests that required plugins cannot be removed
+func TestPlanUninstallRefusesDependents(t *testing.T) {
+	source := newMemorySource(t)
+	source.publish(t, "core", "1.0.0")
+	source.publish(t, "ui", "1.0.0", "core@>=1.0.0")
+	m := newTransactionManager(t, source)
+	ctx := context.Background()
+
+	plan, _ := m.PlanInstall(ctx, source, "ui", "")
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+
+	_, err := m.PlanUninstall("core")
+	if !errors.Is(err, ErrPluginHasDependents) || !strings.Contains(err.Error(), "ui") {
+		t.Errorf("PlanUninstall(core) = %v, want ErrPluginHasDependents naming ui", err)
+	}
+	if _, err := m.PlanUninstall("missing"); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("PlanUninstall(missing) = %v, want ErrPluginNotFound", err)
+	}
+
+	plan, err = m.PlanUninstall("ui")
+	if err != nil {
+		t.Fatalf("PlanUninstall(ui) failed: %v", err)
+	}
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan(uninstall) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(m.pluginDir, "ui")); !os.IsNotExist(err) {
+		t.Error("ui should be removed")
+	}
+	if _, err := m.PlanUninstall("core"); err != nil {
+		t.Errorf("core should be removable once ui is gone: %v", err)
+	}
+}
+
+// TestRollbackPluginRestoresPreviousVersion tests that hot-reload rollback uses the kept version
+func TestRollbackPluginRestoresPreviousVersion(t *testing.T) {
+	source := newMemorySource(t)
+	source.publish(t, "core", "1.0.0")
+	m := newTransactionManager(t, source)
+	if m.hotReloadManager == nil {
+		t.Skip("hot reload unavailable")
+	}
+	ctx := context.Background()
+
+	plan, _ := m.PlanInstall(ctx, source, "core", "")
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	source.publish(t, "core", "1.1.0")
+	plan, _ = m.PlanInstall(ctx, source, "core", "")
+	if err := m.ApplyPlan(ctx, plan); err != nil {
+		t.Fatalf("ApplyPlan(upgrade) failed: %v", err)
+	}
+
+	if err := m.hotReloadManager.RollbackPlugin("core", "1.0.0"); err != nil {
+		t.Fatalf("RollbackPlugin failed: %v", err)
+	}
+	if installedVersion(t, m, "core") != "1.0.0" {
+		t.Errorf("core = %s after rollback, want 1.0.0", installedVersion(t, m, "core"))
+	}
+	if v, _ := m.PreviousVersion("core"); v != "1.1.0" {
+		t.Errorf("PreviousVersion after rollback = %s, want 1.1.0", v)
+	}
+	if _, err := m.VerifyInstalledPlugin(filepath.Join(m.pluginDir, "core")); err != nil {
+		t.Errorf("restored version should still verify: %v", err)
+	}
+}
+
+// TestParseDependency tests dependency entry parsing
+func TestParseDependency(t *testing.T) {
+	id, c, err := ParseDependency("core@^1.2.0")
+	if err != nil || id != "core" || c.Operator != "^" {
+		t.Errorf("ParseDependency(core@^1.2.0) = %s, %+v, %v", id, c, err)
+	}
+	id, c, err = ParseDependency("core")
+	if err != nil || id != "core" || c.Operator != "*" {
+		t.Errorf("ParseDependency(core) = %s, %+v, %v", id, c, err)
+	}
+	if _, _, err := ParseDependency("@1.0.0"); err == nil {
+		t.Error("ParseDependency should reject an empty ID")
+	}
+}
@@ -45,6 +45,7 @@ local CONFIG = {
 local plugin_state = {
     initialized = false,
     treasure_map = {},
+    from_save = false,
     collection_history = {},
     operation_log = {}
 }
@@ -113,9 +114,45 @@ end
 -- TREASURE INITIALIZATION
 -- ============================================================================
 
+-- Reads real treasure state when the host exposes save.getTreasures
+local function load_treasures_from_save()
+    if type(save) ~= "table" or type(save.getTreasures) ~= "function" then
+        return false
+    end
+
+    local rows = save.getTreasures()
+    if type(rows) ~= "table" or #rows == 0 then
+        return false
+    end
+
+    plugin_state.treasure_map = {}
+    for _, row in ipairs(rows) do
+        plugin_state.treasure_map[row.id] = {
+            id = row.id,
+            location = row.map,
+            map_id = row.mapId,
+            world = row.world,
+            missable = row.missable,
+            type = CONFIG.TREASURE_TYPES.CHEST,
+            rarity = row.missable and CONFIG.RARITY.RARE or CONFIG.RARITY.COMMON,
+            contents = row.contents,
+            opened = row.opened,
+            provisional = row.provisional
+        }
+    end
+    plugin_state.from_save = true
+    return true
+end
+
 local function initialize_treasure_map()
     if plugin_state.initialized then return end
 
+    if load_treasures_from_save() then
+        plugin_state.initialized = true
+        log_operation("INIT", string.format("Treasure database loaded %d entries from the save", table_count(plugin_state.treasure_map)))
+        return
+    end
+
     plugin_state.treasure_map = {
         [0] = {id = 0, location = "Narshe Mines", type = CONFIG.TREASURE_TYPES.CHEST, rarity = CONFIG.RARITY.COMMON, contents = "Potion", opened = false},
         [5] = {id = 5, location = "South Figaro", type = CONFIG.TREASURE_TYPES.HIDDEN, rarity = CONFIG.RARITY.UNCOMMON, contents = "Phoenix Down", opened = false},
@@ -228,6 +265,13 @@ function markTreasureOpened(treasure_id)
         return false
     end
 
+    if plugin_state.from_save and type(save.setTreasureOpened) == "function" then
+        local ok, err = save.setTreasureOpened(treasure_id, true)
+        if not ok then
+            log_operation("ERROR", "Save rejected treasure update: " .. tostring(err))
+            return false
+        end
+    end
     treasure.opened = true
 
     local db = load_database_layer()
@@ -239,6 +283,26 @@ function markTreasureOpened(treasure_id)
     return true
 end
 
+function getMissableUnopened()
+    initialize_treasure_map()
+
+    local results = {}
+    for _, treasure in pairs(plugin_state.treasure_map) do
+        if treasure.missable and not treasure.opened then
+            table.insert(results, treasure)
+        end
+    end
+    table.sort(results, function(a, b) return a.id < b.id end)
+    return results
+end
+
+function reloadFromSave()
+    plugin_state.initialized = false
+    plugin_state.from_save = false
+    initialize_treasure_map()
+    return plugin_state.from_save
+end
+
 function getTreasureSummary()
     initialize_treasure_map()
 
@@ -246,13 +310,15 @@ function getTreasureSummary()
         total_treasure = table_count(plugin_state.treasure_map),
         by_type = {},
         by_rarity = {},
-        opened = 0
+        opened = 0,
+        provisional = 0
     }
 
     for _, treasure in pairs(plugin_state.treasure_map) do
         summary.by_type[treasure.type] = (summary.by_type[treasure.type] or 0) + 1
         summary.by_rarity[treasure.rarity] = (summary.by_rarity[treasure.rarity] or 0) + 1
         if treasure.opened then summary.opened = summary.opened + 1 end
+        if treasure.provisional then summary.provisional = summary.provisional + 1 end
     end
 
     return summary
@@ -422,6 +488,8 @@ return {
     getTreasureByRarity = getTreasureByRarity,
     addTreasure = addTreasure,
     markTreasureOpened = markTreasureOpened,
+    getMissableUnopened = getMissableUnopened,
+    reloadFromSave = reloadFromSave,
     getTreasureSummary = getTreasureSummary,
     recordCollection = recordCollection,
     analyzeCollectionProgress = analyzeCollectionProgress,
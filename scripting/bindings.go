@@ -53,6 +53,11 @@ func (b *Bindings) Register(ctx context.Context) error {
 	// Query functions
 	// b.vm.RegisterFunction("editor.findCharacter", func(predicate ...) ...)
 	// b.vm.RegisterFunction("editor.findItems", func(predicate ...) ...)
+	// b.vm.RegisterFunction("editor.query", func(query string) ...)
+
+	// Treasure functions
+	// b.vm.RegisterFunction("editor.getTreasures", func(mapID int, unopened bool) ...)
+	// b.vm.RegisterFunction("editor.setTreasureOpened", func(id int, opened bool) ...)
 
 	// Event functions
 	// b.vm.RegisterFunction("editor.registerHook", func(event string, callback ...) ...)
@@ -184,6 +189,37 @@ func (b *Bindings) BindSetSetting() error {
 	})
 }
 
+// BindQuery binds the Query API function. It returns the matched rows as
+// field maps, or nil when the query is invalid or not permitted.
+func (b *Bindings) BindQuery(ctx context.Context) error {
+	return b.vm.RegisterFunction("editor.query", func(query string) interface{} {
+		res, err := b.api.Query(ctx, query)
+		if err != nil {
+			return nil
+		}
+		return res.Records()
+	})
+}
+
+// BindGetTreasures binds the GetTreasures API function. The optional
+// argument is a map ID; 0 lists every map.
+func (b *Bindings) BindGetTreasures(ctx context.Context) error {
+	return b.vm.RegisterFunction("editor.getTreasures", func(mapID int, unopened bool) interface{} {
+		list, err := b.api.GetTreasures(ctx, modelsPR.TreasureFilter{MapID: uint16(mapID), Unopened: unopened})
+		if err != nil {
+			return nil
+		}
+		return list
+	})
+}
+
+// BindSetTreasureOpened binds the SetTreasureOpened API function
+func (b *Bindings) BindSetTreasureOpened(ctx context.Context) error {
+	return b.vm.RegisterFunction("editor.setTreasureOpened", func(id int, opened bool) error {
+		return b.api.SetTreasureOpened(ctx, id, opened)
+	})
+}
+
 // Character functions
 
 func (b *Bindings) getCharacter(charID int) (interface{}, error) {
@@ -19,6 +19,57 @@
 //	vm := scripting.NewVM()
 //	result, err := vm.Execute(ctx, script)
 //
+// Resource Limits:
+//
+// RunLimited enforces Limits while a script runs: an instruction budget,
+// wall-clock cancellation through the context, a CPU share enforced by
+// throttling, and a memory estimate taken by walking the values reachable
+// from globals, locals and upvalues. A script over a limit is stopped with a
+// *LimitError that pcall cannot swallow. RunPluginSnippet applies a plugin's
+// SandboxPolicy and records enforced violations with its SandboxManager.
+// NewPluginRunner wraps it for plugins.Manager, which runs plugin.lua and
+// contributed commands through it.
+//
+// With Limits.Profile set, the same instruction hook samples the Lua call
+// stack every Profile.Interval instructions into a plugins.CPUProfile.
+// ProfilePluginSnippet runs a plugin's file that way, calling its module's
+// entry function too.
+//
+// Plugin Storage:
+//
+// RunPluginSnippet binds the plugin's durable key-value store as kv:
+//
+//	kv.get(key), kv.put(key, value), kv.delete(key), kv.list(prefix)
+//	kv.transaction(function(tx) ... end) - all writes or none
+//	kv.forSave()                         - the open save file's store
+//
+// and the shared event bus as events:
+//
+//	events.publish(topic, payload), events.recent(pattern)
+//
+// and the plugin's declared settings as settings:
+//
+//	settings.get(key), settings.set(key, value)
+//
+// Plugin Tests:
+//
+// RunTests runs plugin test files (*_test.lua, *_tests.lua, *_smoke*.lua)
+// headlessly, each in its own limited state with the editor's dialogs
+// mocked and, when a fixture is given, a freshly loaded save bound as save
+// for every test:
+//
+//	test(name, fn)                       - register a test
+//	expect.equal(a, b), expect.error(fn) - assertions; see testPrelude
+//	mock.confirm(answer), mock.calls(name)
+//
+// Older smoke scripts that print [PASS]/[FAIL] lines, call os.exit or
+// return a run_all function are understood too. The report is written as
+// JUnit XML or JSON for CI.
+//
+// LintPlugin checks a plugin directory before it is packed: metadata.json,
+// dependency constraints, Lua syntax, ForbiddenGlobals, and whether the
+// permissions declared match the bindings the code calls.
+//
 // Combat Depth Pack:
 //
 // The package includes pre-built scripts for the Combat Depth Pack:
@@ -0,0 +1,48 @@
+package scripting
+
+import (
+	"context"
+
+	"ffvi_editor/plugins"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerEventBindings exposes the plugin event bus as the global events
+// table:
+//
+//	events.publish("tracker.quest.done", {quest = "narshe"})
+//	for _, ev in ipairs(events.recent("save.*")) do print(ev.topic, ev.payload.path) end
+//
+// A snippet cannot subscribe, since its state is gone once it returns;
+// recent gives it what a replaying subscription would have seen.
+func registerEventBindings(ctx context.Context, L *lua.LState, api *plugins.APIImpl) {
+	events := L.NewTable()
+	L.SetField(events, "publish", L.NewFunction(func(L *lua.LState) int {
+		topic := L.CheckString(1)
+		payload, err := luaToGo(L.Get(2))
+		if err == nil {
+			err = api.Publish(ctx, topic, payload)
+		}
+		return pushResult(L, err)
+	}))
+	L.SetField(events, "recent", L.NewFunction(func(L *lua.LState) int {
+		list, err := api.RecentEvents(L.OptString(1, "**"))
+		if err != nil {
+			return pushResult(L, err)
+		}
+		tbl := L.NewTable()
+		for _, ev := range list {
+			row := L.NewTable()
+			L.SetField(row, "topic", lua.LString(ev.Topic))
+			L.SetField(row, "source", lua.LString(ev.Source))
+			L.SetField(row, "seq", lua.LNumber(ev.Seq))
+			L.SetField(row, "time", lua.LNumber(ev.Time.Unix()))
+			L.SetField(row, "payload", goToLua(L, ev.Payload))
+			tbl.Append(row)
+		}
+		L.Push(tbl)
+		return 1
+	}))
+	L.SetGlobal("events", events)
+}
@@ -0,0 +1,42 @@
+package scripting
+
+import (
+	"context"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+func TestEventBindings(t *testing.T) {
+	bus := plugins.NewEventBus(10)
+	base := plugins.NewAPIImpl(nil, nil)
+	base.SetEventBus(bus)
+	api := base.ForPlugin("tracker", []string{"events:*"}, nil)
+	ctx := context.Background()
+
+	var got interface{}
+	bus.Subscribe("hub", "tracker.*", false, func(ctx context.Context, ev *plugins.Event) error {
+		got = ev.Payload
+		return nil
+	})
+	bus.Publish(ctx, plugins.EditorSource, plugins.TopicSaveOpened, map[string]interface{}{"path": "slot1"})
+
+	res, _, err := RunPluginSnippet(ctx, nil, api, `
+		assert(events.publish("tracker.done", {quest = "narshe"}))
+		local ok, err = events.publish("save.opened", {path = "x"})
+		local recent = events.recent("save.*")
+		return {reserved = not ok and err ~= nil, count = #recent, path = recent[1].payload.path, source = recent[1].source}
+	`, nil)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if m, ok := got.(map[string]interface{}); !ok || m["quest"] != "narshe" {
+		t.Errorf("subscriber got %v", got)
+	}
+	want := LuaResult{"reserved": true, "count": float64(1), "path": "slot1", "source": plugins.EditorSource}
+	for k, v := range want {
+		if res[k] != v {
+			t.Errorf("%s = %v, want %v", k, res[k], v)
+		}
+	}
+}
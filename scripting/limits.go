@@ -0,0 +1,473 @@
+package scripting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/plugins"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Kinds of LimitError
+const (
+	LimitInstructions = "instructions"
+	LimitMemory       = "memory"
+	LimitTimeout      = "timeout"
+)
+
+// Limits bounds what one Lua run may consume. Zero fields are unlimited.
+type Limits struct {
+	// MaxInstructions is the number of VM instructions the run may execute
+	MaxInstructions int64
+	// MaxMemoryBytes bounds the estimated size of everything reachable from
+	// globals, the call stack and upvalues
+	MaxMemoryBytes int64
+	// Timeout is the wall-clock budget, including time spent throttled
+	Timeout time.Duration
+	// MaxCPUPercent throttles the run so it is busy at most this share of
+	// wall-clock time; the run is slowed rather than stopped
+	MaxCPUPercent int
+	// NoRequire removes require and the package library
+	NoRequire bool
+	// Chunk names the code in error messages and profiles; empty means
+	// "<string>"
+	Chunk string
+	// Entry, when set and the chunk returns a module table, names the
+	// module function to call next; its result is the run's result
+	Entry string
+	// Profile, when set, receives a sample of the Lua call stack every
+	// Profile.Interval instructions, or DefaultProfileInterval when that
+	// is not positive
+	Profile *plugins.CPUProfile
+}
+
+// LimitError reports a run that was stopped for exceeding a limit. Lua code
+// cannot catch it: once raised, every further instruction raises it again.
+type LimitError struct {
+	Kind  string
+	Limit int64
+	Used  int64
+}
+
+func (e *LimitError) Error() string {
+	switch e.Kind {
+	case LimitTimeout:
+		return fmt.Sprintf("lua execution timeout: exceeded %s", time.Duration(e.Limit))
+	case LimitMemory:
+		return fmt.Sprintf("lua memory limit exceeded: about %d bytes in use, limit %d", e.Used, e.Limit)
+	}
+	return fmt.Sprintf("lua instruction limit exceeded: %d instructions, limit %d", e.Used, e.Limit)
+}
+
+// Usage reports what a run consumed
+type Usage struct {
+	Instructions int64
+	// PeakMemoryBytes is the largest estimate taken; only measured when
+	// MaxMemoryBytes is set
+	PeakMemoryBytes int64
+	Duration        time.Duration
+	Throttled       time.Duration
+}
+
+const (
+	// parentCheckInterval is how many instructions pass between checks of
+	// the caller's context and the CPU throttle
+	parentCheckInterval = 1024
+	// minMemoryCheckInterval is the least number of instructions between
+	// memory estimates; larger heaps are measured less often so the cost of
+	// walking them stays proportional to the instructions run
+	minMemoryCheckInterval = 4096
+)
+
+// limiter is installed as the LState's context. gopher-lua calls Done
+// before every instruction, which makes it the VM's instruction hook.
+type limiter struct {
+	context.Context
+	L      *lua.LState
+	limits Limits
+
+	count       int64
+	nextMemory  int64
+	sampleEvery int64
+	start       time.Time
+	lastSample  time.Time
+	usage       Usage
+
+	done chan struct{}
+	err  error
+}
+
+func newLimiter(parent context.Context, L *lua.LState, limits Limits) *limiter {
+	now := time.Now()
+	l := &limiter{
+		Context:    parent,
+		L:          L,
+		limits:     limits,
+		nextMemory: minMemoryCheckInterval,
+		start:      now,
+		lastSample: now,
+		done:       make(chan struct{}),
+	}
+	if p := limits.Profile; p != nil {
+		l.sampleEvery = p.Interval
+		if l.sampleEvery <= 0 {
+			l.sampleEvery = plugins.DefaultProfileInterval
+		}
+	}
+	return l
+}
+
+func (l *limiter) Done() <-chan struct{} {
+	if l.err != nil {
+		return l.done
+	}
+	l.count++
+	if max := l.limits.MaxInstructions; max > 0 && l.count > max {
+		l.stop(&LimitError{Kind: LimitInstructions, Limit: max, Used: l.count})
+		return l.done
+	}
+	if l.count%parentCheckInterval == 0 {
+		l.checkParent()
+		l.throttle()
+	}
+	if l.limits.MaxMemoryBytes > 0 && l.count >= l.nextMemory {
+		l.checkMemory(0)
+	}
+	if l.sampleEvery > 0 && l.count%l.sampleEvery == 0 {
+		l.sample()
+	}
+	return l.done
+}
+
+func (l *limiter) Err() error {
+	return l.err
+}
+
+func (l *limiter) stop(err error) {
+	if l.err == nil {
+		l.err = err
+		close(l.done)
+	}
+}
+
+func (l *limiter) checkParent() {
+	select {
+	case <-l.Context.Done():
+		if l.Context.Err() == context.DeadlineExceeded && l.limits.Timeout > 0 {
+			l.stop(&LimitError{Kind: LimitTimeout, Limit: int64(l.limits.Timeout), Used: int64(time.Since(l.start))})
+		} else {
+			l.stop(l.Context.Err())
+		}
+	default:
+	}
+}
+
+// throttle sleeps until the run's busy time is within MaxCPUPercent of
+// the wall-clock time since it started
+func (l *limiter) throttle() {
+	pct := l.limits.MaxCPUPercent
+	if pct <= 0 || pct >= 100 {
+		return
+	}
+	elapsed := time.Since(l.start)
+	busy := elapsed - l.usage.Throttled
+	wait := busy*100/time.Duration(pct) - elapsed
+	if wait < time.Millisecond {
+		return
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		l.usage.Throttled += wait
+	case <-l.Context.Done():
+		l.checkParent()
+	}
+}
+
+// checkMemory estimates the heap, adding extra bytes about to be allocated,
+// and stops the run when it is over the limit
+func (l *limiter) checkMemory(extra int64) bool {
+	max := l.limits.MaxMemoryBytes
+	size, entries := estimateMemory(l.L, max-extra)
+	size += extra
+	if size > l.usage.PeakMemoryBytes {
+		l.usage.PeakMemoryBytes = size
+	}
+	if size > max {
+		l.stop(&LimitError{Kind: LimitMemory, Limit: max, Used: size})
+		return false
+	}
+	interval := int64(entries)
+	if interval < minMemoryCheckInterval {
+		interval = minMemoryCheckInterval
+	}
+	l.nextMemory = l.count + interval
+	return true
+}
+
+// sample adds the current call stack to the profile, weighted by the time
+// since the previous sample
+func (l *limiter) sample() {
+	now := time.Now()
+	weight := now.Sub(l.lastSample)
+	l.lastSample = now
+
+	var frames []plugins.ProfileFrame
+	for level := 0; ; level++ {
+		dbg, ok := l.L.GetStack(level)
+		if !ok {
+			break
+		}
+		if _, err := l.L.GetInfo("nS", dbg, lua.LNil); err != nil {
+			continue
+		}
+		frames = append(frames, l.profileFrame(dbg))
+		if dbg.What == "main" {
+			// Levels past the root are an artefact of tail calls
+			break
+		}
+	}
+	// GetStack counts from the running function; profiles read root first
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	l.limits.Profile.AddSample(frames, weight)
+}
+
+func (l *limiter) profileFrame(dbg *lua.Debug) plugins.ProfileFrame {
+	f := plugins.ProfileFrame{Name: dbg.Name, File: dbg.Source, Line: dbg.LineDefined}
+	switch {
+	case dbg.What == "main" && dbg.LineDefined == 0:
+		f.Name = "main"
+	case dbg.What == "main":
+		// The Entry function, called after the chunk returned
+		f.Name = l.limits.Entry
+	case dbg.What == "G":
+		f.File, f.Line = "", 0
+	case f.Name == "" || strings.HasPrefix(f.Name, "<"):
+		// gopher-lua names functions it cannot name "<source:line>"
+		f.Name = "function"
+	}
+	return f
+}
+
+func (l *limiter) finish() Usage {
+	l.usage.Instructions = l.count
+	l.usage.Duration = time.Since(l.start)
+	if p := l.limits.Profile; p != nil {
+		p.Duration += l.usage.Duration
+	}
+	return l.usage
+}
+
+// Approximate sizes used by estimateMemory, close to gopher-lua's own
+const (
+	tableOverhead    = 64
+	tableSlotBytes   = 32
+	stringOverhead   = 16
+	functionOverhead = 64
+	userDataBytes    = 48
+)
+
+// estimateMemory approximates the bytes held by values reachable from the
+// globals, the registry, every call frame's locals and their functions'
+// upvalues. It returns early once the estimate passes stopAt, and also
+// reports how many values it visited.
+func estimateMemory(L *lua.LState, stopAt int64) (int64, int) {
+	w := &memoryWalker{seen: make(map[interface{}]bool), stopAt: stopAt}
+	w.walk(L.G.Global)
+	w.walk(L.G.Registry)
+	for level := 0; w.size <= stopAt; level++ {
+		dbg, ok := L.GetStack(level)
+		if !ok {
+			break
+		}
+		if fn, err := L.GetInfo("f", dbg, lua.LNil); err == nil {
+			w.walk(fn)
+		}
+		for n := 1; ; n++ {
+			name, v := L.GetLocal(dbg, n)
+			if name == "" {
+				break
+			}
+			w.walk(v)
+		}
+	}
+	return w.size, w.visited
+}
+
+type memoryWalker struct {
+	seen    map[interface{}]bool
+	size    int64
+	stopAt  int64
+	visited int
+}
+
+func (w *memoryWalker) walk(v lua.LValue) {
+	if w.size > w.stopAt {
+		return
+	}
+	w.visited++
+	switch x := v.(type) {
+	case lua.LString:
+		w.size += stringOverhead + int64(len(x))
+	case *lua.LTable:
+		if w.seen[x] {
+			return
+		}
+		w.seen[x] = true
+		w.size += tableOverhead
+		x.ForEach(func(k, val lua.LValue) {
+			w.size += tableSlotBytes
+			w.walk(k)
+			w.walk(val)
+		})
+		if x.Metatable != nil {
+			w.walk(x.Metatable)
+		}
+	case *lua.LFunction:
+		if w.seen[x] {
+			return
+		}
+		w.seen[x] = true
+		w.size += functionOverhead
+		for _, uv := range x.Upvalues {
+			if uv != nil {
+				w.walk(uv.Value())
+			}
+		}
+		if x.Env != nil {
+			w.walk(x.Env)
+		}
+	case *lua.LUserData:
+		if w.seen[x] {
+			return
+		}
+		w.seen[x] = true
+		w.size += userDataBytes
+		if x.Metatable != nil {
+			w.walk(x.Metatable)
+		}
+	}
+}
+
+// limitStringRep charges string.rep's result against the memory limit
+// before building it, since one call can allocate far more than the
+// instruction-driven checks would see in time
+func limitStringRep(L *lua.LState, l *limiter) {
+	str, ok := L.GetGlobal("string").(*lua.LTable)
+	if !ok {
+		return
+	}
+	orig, ok := str.RawGetString("rep").(*lua.LFunction)
+	if !ok {
+		return
+	}
+	str.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+		s := L.CheckString(1)
+		n := L.CheckInt64(2)
+		sep := L.OptString(3, "")
+		if n > 0 {
+			size := int64(len(s)+len(sep)) * n
+			if size/n != int64(len(s)+len(sep)) || !l.checkMemory(size) {
+				if l.err == nil {
+					l.stop(&LimitError{Kind: LimitMemory, Limit: l.limits.MaxMemoryBytes, Used: size})
+				}
+				L.RaiseError("%v", l.err)
+			}
+		}
+		return orig.GFunction(L)
+	}))
+}
+
+// RunLimited executes a Lua snippet, with save bindings when save is not
+// nil, and stops it the moment it exceeds limits. When a limit stops the
+// run the error is a *LimitError.
+func RunLimited(ctx context.Context, code string, save *pr.PR, limits Limits) (LuaResult, Usage, error) {
+	return runLimited(ctx, code, save, limits, nil)
+}
+
+// runLimited is RunLimited for a plugin when api is set: save bindings need
+// the plugin's capabilities, its key-value store is bound as kv and the
+// event bus as events
+func runLimited(ctx context.Context, code string, save *pr.PR, limits Limits, api *plugins.APIImpl) (LuaResult, Usage, error) {
+	if code == "" {
+		return nil, Usage{}, fmt.Errorf("code is empty")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+
+	openSafeLibs(L)
+	if limits.NoRequire {
+		L.SetGlobal("require", lua.LNil)
+		L.SetGlobal("package", lua.LNil)
+	} else {
+		applyPackagePathSandbox(L)
+	}
+	var require func(string) error
+	if api != nil {
+		require = api.Require
+		registerStoreBindings(ctx, L, api)
+		registerEventBindings(ctx, L, api)
+		registerSettingsBindings(L, api)
+	}
+	if save != nil {
+		registerSaveBindings(L, save, require)
+	}
+
+	l := newLimiter(ctx, L, limits)
+	if limits.MaxMemoryBytes > 0 {
+		limitStringRep(L, l)
+	}
+	L.SetContext(l)
+
+	chunk := limits.Chunk
+	if chunk == "" {
+		chunk = "<string>"
+	}
+	fn, err := L.Load(strings.NewReader(code), chunk)
+	if err == nil {
+		L.Push(fn)
+		err = L.PCall(0, lua.MultRet, nil)
+	}
+	if err == nil && limits.Entry != "" && L.GetTop() >= 1 {
+		if module, ok := L.Get(-1).(*lua.LTable); ok {
+			entry, ok := module.RawGetString(limits.Entry).(*lua.LFunction)
+			if !ok {
+				err = fmt.Errorf("module has no function %q", limits.Entry)
+			} else {
+				L.SetTop(0)
+				L.Push(entry)
+				err = L.PCall(0, lua.MultRet, nil)
+			}
+		}
+	}
+	usage := l.finish()
+	if l.err != nil {
+		// Whatever the script was doing, the limiter's error is the cause
+		return nil, usage, l.err
+	}
+	if err != nil {
+		return nil, usage, err
+	}
+	if L.GetTop() >= 1 {
+		if tbl, ok := L.Get(-1).(*lua.LTable); ok {
+			return tableToMap(tbl), usage, nil
+		}
+	}
+	return nil, usage, nil
+}
@@ -0,0 +1,301 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/plugins"
+)
+
+func limitKind(t *testing.T, err error) string {
+	t.Helper()
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected a *LimitError, got %v", err)
+	}
+	return limitErr.Kind
+}
+
+func TestRunLimitedInfiniteLoop(t *testing.T) {
+	_, usage, err := RunLimited(context.Background(), `while true do end`, nil, Limits{MaxInstructions: 100000})
+	if kind := limitKind(t, err); kind != LimitInstructions {
+		t.Errorf("kind = %s, want %s", kind, LimitInstructions)
+	}
+	if usage.Instructions != 100001 {
+		t.Errorf("stopped after %d instructions, want 100001", usage.Instructions)
+	}
+}
+
+// TestRunLimitedCannotBeCaught checks pcall does not swallow the kill
+func TestRunLimitedCannotBeCaught(t *testing.T) {
+	code := `
+		while true do
+			pcall(function() while true do end end)
+		end`
+	_, _, err := RunLimited(context.Background(), code, nil, Limits{MaxInstructions: 50000})
+	if kind := limitKind(t, err); kind != LimitInstructions {
+		t.Errorf("kind = %s, want %s", kind, LimitInstructions)
+	}
+}
+
+func TestRunLimitedTimeout(t *testing.T) {
+	start := time.Now()
+	_, _, err := RunLimited(context.Background(), `while true do end`, nil, Limits{Timeout: 100 * time.Millisecond})
+	if kind := limitKind(t, err); kind != LimitTimeout {
+		t.Errorf("kind = %s, want %s", kind, LimitTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("runaway script ran for %s after a 100ms timeout", elapsed)
+	}
+}
+
+func TestRunLimitedCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, _, err := RunLimited(ctx, `while true do end`, nil, Limits{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunLimitedTableGrowth(t *testing.T) {
+	code := `
+		local t = {}
+		local i = 0
+		while true do
+			i = i + 1
+			t[i] = {i, "entry" .. i}
+		end`
+	_, usage, err := RunLimited(context.Background(), code, nil, Limits{MaxMemoryBytes: 1 << 20})
+	if kind := limitKind(t, err); kind != LimitMemory {
+		t.Errorf("kind = %s, want %s", kind, LimitMemory)
+	}
+	if usage.PeakMemoryBytes <= 1<<20 {
+		t.Errorf("peak %d bytes should be over the 1MB limit", usage.PeakMemoryBytes)
+	}
+}
+
+func TestRunLimitedGlobalGrowth(t *testing.T) {
+	code := `
+		grow = {}
+		for i = 1, 10000000 do grow[#grow + 1] = string.format("%08d", i) end`
+	_, _, err := RunLimited(context.Background(), code, nil, Limits{MaxMemoryBytes: 1 << 20})
+	if kind := limitKind(t, err); kind != LimitMemory {
+		t.Errorf("kind = %s, want %s", kind, LimitMemory)
+	}
+}
+
+func TestRunLimitedStringRep(t *testing.T) {
+	_, _, err := RunLimited(context.Background(), `local s = string.rep("x", 1e9)`, nil, Limits{MaxMemoryBytes: 1 << 20})
+	if kind := limitKind(t, err); kind != LimitMemory {
+		t.Errorf("kind = %s, want %s", kind, LimitMemory)
+	}
+}
+
+func TestRunLimitedWithinLimits(t *testing.T) {
+	code := `
+		local t = {}
+		for i = 1, 1000 do t[i] = i * 2 end
+		return {last = t[1000], rep = string.rep("ab", 3)}`
+	limits := Limits{MaxInstructions: 1000000, MaxMemoryBytes: 8 << 20, Timeout: time.Second}
+	res, usage, err := RunLimited(context.Background(), code, nil, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["last"] != float64(2000) || res["rep"] != "ababab" {
+		t.Errorf("result = %v", res)
+	}
+	if usage.Instructions == 0 {
+		t.Error("usage should count instructions")
+	}
+}
+
+func TestRunLimitedCPUThrottle(t *testing.T) {
+	code := `local n = 0 for i = 1, 300000 do n = n + i end`
+	_, usage, err := RunLimited(context.Background(), code, nil, Limits{MaxCPUPercent: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := usage.Duration - usage.Throttled
+	if usage.Throttled < busy {
+		t.Errorf("throttled %s for %s busy, want at least as long at 25%% CPU", usage.Throttled, busy)
+	}
+}
+
+func TestRunLimitedStrictIsolation(t *testing.T) {
+	_, _, err := RunLimited(context.Background(), `require("anything")`, nil, Limits{NoRequire: true})
+	if err == nil {
+		t.Error("require should be unavailable")
+	}
+}
+
+func TestRunPluginSnippetRecordsViolation(t *testing.T) {
+	sm := plugins.NewSandboxManager()
+	if err := sm.SetPolicy(&plugins.SandboxPolicy{
+		PluginID:        "runaway",
+		MaxInstructions: 10000,
+		IsolationLevel:  "strict",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	api := plugins.NewAPIImpl(nil, nil).ForPlugin("runaway", nil, nil)
+	_, _, err := RunPluginSnippet(context.Background(), sm, api, `while true do end`, nil)
+	if kind := limitKind(t, err); kind != LimitInstructions {
+		t.Errorf("kind = %s, want %s", kind, LimitInstructions)
+	}
+	violations := sm.GetViolations("runaway")
+	if len(violations) != 1 {
+		t.Fatalf("got %d violations, want 1", len(violations))
+	}
+	v := violations[0]
+	if !v.Enforced || v.ViolationType != "cpu_exceeded" || v.Details["limit"] != int64(10000) {
+		t.Errorf("unexpected violation %+v", v)
+	}
+
+	if _, _, err := RunPluginSnippet(context.Background(), sm, api, `return {ok = true}`, nil); err != nil {
+		t.Errorf("a well-behaved run failed: %v", err)
+	}
+	if n := len(sm.GetViolations("runaway")); n != 1 {
+		t.Errorf("a well-behaved run recorded a violation (%d total)", n)
+	}
+}
+
+// TestManagerRunsPluginsUnderPolicy tests plugins run by the Manager get
+// their sandbox policy's limits
+func TestManagerRunsPluginsUnderPolicy(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "spinner")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		plugins.PackageMetadataFile: `{
+	"id": "spinner", "name": "Spinner", "version": "1.0.0", "author": "tester",
+	"contributes": {"commands": [{"id": "greet", "title": "Greet"}, {"id": "spin", "title": "Spin"}]}
+}`,
+		PluginMainFile: `
+local M = {}
+function M.greet() return {hello = true} end
+function M.spin() while true do end end
+return M
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(pluginDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := plugins.NewManager(dir, plugins.NewAPIImpl(nil, nil))
+	m.SetSignaturePolicy(plugins.SignaturePolicyWarn)
+	sm := m.GetSandboxManager()
+	m.SetRunner(NewPluginRunner(sm))
+	ctx := context.Background()
+	if _, err := m.LoadPlugin(ctx, pluginDir); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if err := sm.SetPolicy(&plugins.SandboxPolicy{PluginID: "spinner", MaxInstructions: 10000, IsolationLevel: "basic", IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.ExecutePlugin(ctx, "spinner"); err != nil {
+		t.Fatalf("ExecutePlugin failed: %v", err)
+	}
+	if err := m.InvokeCommand(ctx, "spinner", "greet"); err != nil {
+		t.Fatalf("greet failed: %v", err)
+	}
+	err := m.InvokeCommand(ctx, "spinner", "spin")
+	if kind := limitKind(t, err); kind != LimitInstructions {
+		t.Errorf("kind = %s, want %s", kind, LimitInstructions)
+	}
+	if v := sm.GetViolations("spinner"); len(v) != 1 || !v[0].Enforced {
+		t.Errorf("violations = %+v, want one enforced", v)
+	}
+}
+
+func TestRunPluginSnippetCapabilities(t *testing.T) {
+	save := pr.New()
+	save.UserData.Set("gil", float64(1200))
+	api := plugins.NewAPIImpl(save, nil).ForPlugin("reader", []string{plugins.Capabilities.GilRead}, nil)
+
+	res, _, err := RunPluginSnippet(context.Background(), nil, api, `return {gil = save.getGil()}`, save)
+	if err != nil {
+		t.Fatalf("granted read failed: %v", err)
+	}
+	if res["gil"] != float64(1200) {
+		t.Errorf("gil = %v, want 1200", res["gil"])
+	}
+
+	_, _, err = RunPluginSnippet(context.Background(), nil, api, `save.setGil(5)`, save)
+	if err == nil {
+		t.Fatal("setGil without save.gil:write should fail")
+	}
+	if !strings.Contains(err.Error(), plugins.Capabilities.GilWrite) {
+		t.Errorf("error %q does not name the missing capability", err)
+	}
+	if gil := save.UserData.Get("gil"); gil != float64(1200) {
+		t.Errorf("gil changed to %v", gil)
+	}
+}
+
+func TestProfilePluginSnippet(t *testing.T) {
+	api := plugins.NewAPIImpl(nil, nil).ForPlugin("hot", nil, nil)
+	profile := plugins.NewCPUProfile("hot", 100)
+	res, usage, err := ProfilePluginSnippet(context.Background(), nil, api, `
+local function spin(n)
+  local s = 0
+  for i = 1, n do s = s + i % 7 end
+  return s
+end
+local M = {}
+function M.run()
+  for i = 1, 200 do spin(1000) end
+  return {done = true}
+end
+return M
+`, "plugin.lua", "run", nil, profile)
+	if err != nil {
+		t.Fatalf("ProfilePluginSnippet failed: %v", err)
+	}
+	if res["done"] != true {
+		t.Errorf("result = %v, want the entry function's", res)
+	}
+	if want := int(usage.Instructions / 100); profile.Samples() != want {
+		t.Errorf("got %d samples for %d instructions, want %d", profile.Samples(), usage.Instructions, want)
+	}
+	if profile.Duration != usage.Duration {
+		t.Errorf("Duration = %s, want %s", profile.Duration, usage.Duration)
+	}
+
+	funcs := profile.Functions()
+	if len(funcs) == 0 {
+		t.Fatal("no functions sampled")
+	}
+	top := funcs[0].Frame
+	if top.Name != "spin" || top.File != "plugin.lua" || top.Line != 2 {
+		t.Errorf("hottest function = %+v, want spin (plugin.lua:2)", top)
+	}
+	for _, s := range profile.Stacks() {
+		if root := s.Frames[0].Name; root != "main" && root != "run" {
+			t.Errorf("stack %v does not start at the main chunk or the entry function", s.Frames)
+		}
+	}
+
+	// A literal profile samples at the default interval
+	literal := &plugins.CPUProfile{}
+	_, usage, err = ProfilePluginSnippet(context.Background(), nil, api, `
+local s = 0
+for i = 1, 5000 do s = s + i end
+`, "plugin.lua", "", nil, literal)
+	if err != nil {
+		t.Fatalf("ProfilePluginSnippet with a literal profile failed: %v", err)
+	}
+	if want := int(usage.Instructions / plugins.DefaultProfileInterval); literal.Samples() != want {
+		t.Errorf("got %d samples for %d instructions, want %d", literal.Samples(), usage.Instructions, want)
+	}
+}
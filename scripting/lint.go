@@ -0,0 +1,447 @@
+package scripting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+
+	"ffvi_editor/models/search"
+	"ffvi_editor/plugins"
+)
+
+// Lint severities. Errors mean the plugin will not load or will fail at
+// run time; warnings are worth a look.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is one problem LintPlugin found
+type LintIssue struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", loc, i.Severity, i.Message, i.Rule)
+}
+
+// LintReport is the result of linting one plugin directory
+type LintReport struct {
+	Dir    string      `json:"dir"`
+	Issues []LintIssue `json:"issues"`
+	// Uses lists the capabilities the plugin's Lua code needs
+	Uses []string `json:"uses"`
+}
+
+// Errors counts the issues of error severity
+func (r *LintReport) Errors() int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == LintError {
+			n++
+		}
+	}
+	return n
+}
+
+// bindingCapabilities maps the Lua bindings that need a capability to it.
+// save.query is resolved from its query text instead.
+var bindingCapabilities = map[string]string{
+	"save.getCharacterCount":   plugins.Capabilities.CharactersRead,
+	"save.getCharacterName":    plugins.Capabilities.CharactersRead,
+	"save.setCharacterLevel":   plugins.Capabilities.CharactersWrite,
+	"save.setCharacterHP":      plugins.Capabilities.CharactersWrite,
+	"save.setCharacterMP":      plugins.Capabilities.CharactersWrite,
+	"save.getGil":              plugins.Capabilities.GilRead,
+	"save.setGil":              plugins.Capabilities.GilWrite,
+	"save.getTreasures":        plugins.Capabilities.TreasuresRead,
+	"save.setTreasureOpened":   plugins.Capabilities.TreasuresWrite,
+	"editor.getCharacter":      plugins.Capabilities.CharactersRead,
+	"editor.setCharacter":      plugins.Capabilities.CharactersWrite,
+	"editor.getInventory":      plugins.Capabilities.InventoryRead,
+	"editor.setInventory":      plugins.Capabilities.InventoryWrite,
+	"editor.getTreasures":      plugins.Capabilities.TreasuresRead,
+	"editor.setTreasureOpened": plugins.Capabilities.TreasuresWrite,
+	"editor.showDialog":        plugins.Capabilities.UIDialog,
+	"editor.showConfirm":       plugins.Capabilities.UIDialog,
+	"editor.showInput":         plugins.Capabilities.UIDialog,
+	"events.publish":           plugins.Capabilities.EventsPublish,
+	"events.recent":            plugins.Capabilities.EventsSubscribe,
+}
+
+// LintPlugin checks a plugin directory before it is packed or installed:
+//   - metadata.json: required fields, version syntax, known permissions,
+//     settings schema and contributions
+//   - dependencies: each entry parses as id@constraint
+//   - Lua sources: syntax, no use of ForbiddenGlobals, and every binding
+//     used is covered by a declared permission; permissions nothing uses
+//     are warned about
+//
+// Test files are only checked for syntax, since the test harness gives them
+// require and friends. The error is for directories that cannot be read.
+func LintPlugin(dir string) (*LintReport, error) {
+	report := &LintReport{Dir: dir}
+	add := func(file string, line int, severity, rule, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, LintIssue{
+			File: file, Line: line, Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	meta := lintMetadata(dir, add)
+
+	uses := make(map[string]LintIssue) // capability -> first use
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(p) != ".lua" {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		code, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		chunk, err := parse.Parse(strings.NewReader(string(code)), rel)
+		if err != nil {
+			add(rel, 0, LintError, "syntax", "%s", strings.TrimSpace(err.Error()))
+			return nil
+		}
+		if testFilePattern.MatchString(d.Name()) {
+			return nil
+		}
+		w := &luaLintWalker{file: rel, add: add, uses: uses, forbidden: make(map[string]bool), reported: make(map[string]bool)}
+		for _, name := range ForbiddenGlobals() {
+			w.forbidden[name] = true
+		}
+		w.block(chunk, nil)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	for capability := range uses {
+		report.Uses = append(report.Uses, capability)
+	}
+	sort.Strings(report.Uses)
+
+	if meta != nil {
+		grants := plugins.NewGrants(meta.Permissions)
+		for _, capability := range report.Uses {
+			if !grants.Allows(capability) {
+				use := uses[capability]
+				add(use.File, use.Line, LintError, "undeclared-permission",
+					"%s needs %s, which metadata.json does not declare", use.Message, capability)
+			}
+		}
+		for _, perm := range meta.Permissions {
+			if plugins.IsLegacyPermission(perm) {
+				add(plugins.PackageMetadataFile, 0, LintWarning, "legacy-permission",
+					"permission %s grants %s; declare only the capabilities the plugin needs",
+					perm, strings.Join(plugins.NewGrants([]string{perm}).Capabilities(), ", "))
+				continue
+			}
+			used := false
+			for _, capability := range plugins.NewGrants([]string{perm}).Capabilities() {
+				if _, ok := uses[capability]; ok {
+					used = true
+					break
+				}
+			}
+			if !used {
+				add(plugins.PackageMetadataFile, 0, LintWarning, "unused-permission",
+					"permission %s is declared but no binding that needs it is used", perm)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// lintMetadata checks metadata.json and returns it, or nil when it cannot
+// be read
+func lintMetadata(dir string, add func(file string, line int, severity, rule, format string, args ...interface{})) *plugins.PluginMetadata {
+	const file = plugins.PackageMetadataFile
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		add(file, 0, LintError, "manifest", "cannot read %s: %v", file, err)
+		return nil
+	}
+	var meta plugins.PluginMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		add(file, 0, LintError, "manifest", "invalid JSON: %v", err)
+		return nil
+	}
+
+	if err := meta.Validate(); err != nil {
+		add(file, 0, LintError, "manifest", "%v", err)
+	}
+	if meta.ID != "" && meta.ID != filepath.Base(dir) {
+		add(file, 0, LintWarning, "manifest", "id %q does not match the directory name %q it installs to", meta.ID, filepath.Base(dir))
+	}
+	if meta.Version != "" {
+		if _, err := plugins.ParseVersion(meta.Version); err != nil {
+			add(file, 0, LintError, "manifest", "version: %v", err)
+		}
+	}
+	for field, v := range map[string]string{"minAppVersion": meta.MinAppVersion, "maxAppVersion": meta.MaxAppVersion} {
+		if v == "" {
+			continue
+		}
+		if _, err := plugins.ParseVersion(v); err != nil {
+			add(file, 0, LintError, "manifest", "%s: %v", field, err)
+		}
+	}
+	if err := plugins.ValidatePermissions(meta.Permissions); err != nil {
+		add(file, 0, LintError, "permissions", "%v", err)
+	}
+	if err := meta.Settings.Check(); err != nil {
+		add(file, 0, LintError, "settings", "%v", err)
+	} else if err := meta.Contributes.Check(meta.Settings); err != nil {
+		add(file, 0, LintError, "contributions", "%v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, dep := range meta.Dependencies {
+		id, _, err := plugins.ParseDependency(dep)
+		switch {
+		case err != nil:
+			add(file, 0, LintError, "dependencies", "%v", err)
+		case id == meta.ID:
+			add(file, 0, LintError, "dependencies", "plugin depends on itself")
+		case seen[id]:
+			add(file, 0, LintWarning, "dependencies", "%s is listed more than once", id)
+		}
+		seen[id] = true
+	}
+	return &meta
+}
+
+// luaLintWalker walks one file's syntax tree, tracking local names so
+// globals can be told apart from locals that shadow them
+type luaLintWalker struct {
+	file      string
+	add       func(file string, line int, severity, rule, format string, args ...interface{})
+	uses      map[string]LintIssue
+	forbidden map[string]bool
+	reported  map[string]bool // file:line:name, so one line reports once
+}
+
+// luaScope is one block's local names
+type luaScope struct {
+	parent *luaScope
+	names  map[string]bool
+}
+
+func (s *luaScope) local(name string) bool {
+	for ; s != nil; s = s.parent {
+		if s.names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func newScope(parent *luaScope, names ...string) *luaScope {
+	s := &luaScope{parent: parent, names: make(map[string]bool)}
+	for _, n := range names {
+		s.names[n] = true
+	}
+	return s
+}
+
+func (w *luaLintWalker) block(stmts []ast.Stmt, parent *luaScope) {
+	scope := newScope(parent)
+	for _, stmt := range stmts {
+		w.stmt(stmt, scope)
+	}
+}
+
+func (w *luaLintWalker) stmt(stmt ast.Stmt, scope *luaScope) {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		w.exprs(s.Lhs, scope)
+		w.exprs(s.Rhs, scope)
+	case *ast.LocalAssignStmt:
+		// local function f() can call itself
+		if len(s.Exprs) == 1 && len(s.Names) == 1 {
+			if _, ok := s.Exprs[0].(*ast.FunctionExpr); ok {
+				scope.names[s.Names[0]] = true
+			}
+		}
+		w.exprs(s.Exprs, scope)
+		for _, n := range s.Names {
+			scope.names[n] = true
+		}
+	case *ast.FuncCallStmt:
+		w.expr(s.Expr, scope)
+	case *ast.DoBlockStmt:
+		w.block(s.Stmts, scope)
+	case *ast.WhileStmt:
+		w.expr(s.Condition, scope)
+		w.block(s.Stmts, scope)
+	case *ast.RepeatStmt:
+		// until can see the body's locals
+		inner := newScope(scope)
+		for _, st := range s.Stmts {
+			w.stmt(st, inner)
+		}
+		w.expr(s.Condition, inner)
+	case *ast.IfStmt:
+		w.expr(s.Condition, scope)
+		w.block(s.Then, scope)
+		w.block(s.Else, scope)
+	case *ast.NumberForStmt:
+		w.expr(s.Init, scope)
+		w.expr(s.Limit, scope)
+		w.expr(s.Step, scope)
+		w.block(s.Stmts, newScope(scope, s.Name))
+	case *ast.GenericForStmt:
+		w.exprs(s.Exprs, scope)
+		w.block(s.Stmts, newScope(scope, s.Names...))
+	case *ast.FuncDefStmt:
+		if s.Name.Func != nil {
+			w.expr(s.Name.Func, scope)
+		}
+		if s.Name.Receiver != nil {
+			w.expr(s.Name.Receiver, scope)
+		}
+		if s.Name.Method != "" {
+			w.function(s.Func, scope, "self")
+		} else {
+			w.function(s.Func, scope)
+		}
+	case *ast.ReturnStmt:
+		w.exprs(s.Exprs, scope)
+	}
+}
+
+func (w *luaLintWalker) exprs(exprs []ast.Expr, scope *luaScope) {
+	for _, e := range exprs {
+		w.expr(e, scope)
+	}
+}
+
+func (w *luaLintWalker) expr(expr ast.Expr, scope *luaScope) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.IdentExpr:
+		key := fmt.Sprintf("%d:%s", e.Line(), e.Value)
+		if w.forbidden[e.Value] && !scope.local(e.Value) && !w.reported[key] {
+			w.reported[key] = true
+			w.add(w.file, e.Line(), LintError, "forbidden-global", "%s is not available to plugins", e.Value)
+		}
+	case *ast.AttrGetExpr:
+		if name := w.bindingName(e.Object, e.Key, scope); name != "" {
+			if capability, ok := bindingCapabilities[name]; ok {
+				w.use(capability, e.Line(), name)
+			}
+		}
+		w.expr(e.Object, scope)
+		w.expr(e.Key, scope)
+	case *ast.FuncCallExpr:
+		if get, ok := e.Func.(*ast.AttrGetExpr); ok && len(e.Args) > 0 {
+			if name := w.bindingName(get.Object, get.Key, scope); name == "save.query" || name == "editor.query" {
+				if text, ok := e.Args[0].(*ast.StringExpr); ok {
+					if q, err := search.ParseQuery(text.Value); err == nil {
+						w.use(plugins.QueryCapability(q.Source), e.Line(), name)
+					}
+				}
+			}
+		}
+		if e.Receiver != nil {
+			if ident, ok := e.Receiver.(*ast.IdentExpr); ok && !scope.local(ident.Value) {
+				if capability, ok := bindingCapabilities[ident.Value+"."+e.Method]; ok {
+					w.use(capability, e.Line(), ident.Value+"."+e.Method)
+				}
+			}
+		}
+		w.expr(e.Func, scope)
+		w.expr(e.Receiver, scope)
+		w.exprs(e.Args, scope)
+	case *ast.TableExpr:
+		for _, f := range e.Fields {
+			w.expr(f.Key, scope)
+			w.expr(f.Value, scope)
+		}
+	case *ast.LogicalOpExpr:
+		w.expr(e.Lhs, scope)
+		w.expr(e.Rhs, scope)
+	case *ast.RelationalOpExpr:
+		w.expr(e.Lhs, scope)
+		w.expr(e.Rhs, scope)
+	case *ast.StringConcatOpExpr:
+		w.expr(e.Lhs, scope)
+		w.expr(e.Rhs, scope)
+	case *ast.ArithmeticOpExpr:
+		w.expr(e.Lhs, scope)
+		w.expr(e.Rhs, scope)
+	case *ast.UnaryMinusOpExpr:
+		w.expr(e.Expr, scope)
+	case *ast.UnaryNotOpExpr:
+		w.expr(e.Expr, scope)
+	case *ast.UnaryLenOpExpr:
+		w.expr(e.Expr, scope)
+	case *ast.FunctionExpr:
+		w.function(e, scope)
+	}
+}
+
+func (w *luaLintWalker) function(fn *ast.FunctionExpr, scope *luaScope, implicit ...string) {
+	params := implicit
+	if fn.ParList != nil {
+		params = append(params, fn.ParList.Names...)
+	}
+	w.block(fn.Stmts, newScope(scope, params...))
+}
+
+// bindingName returns "global.key" for a constant field of a global table,
+// or "" for anything else
+func (w *luaLintWalker) bindingName(object, key ast.Expr, scope *luaScope) string {
+	ident, ok := object.(*ast.IdentExpr)
+	if !ok || scope.local(ident.Value) {
+		return ""
+	}
+	k, ok := key.(*ast.StringExpr)
+	if !ok {
+		return ""
+	}
+	return ident.Value + "." + k.Value
+}
+
+// use records the first place a capability is needed
+func (w *luaLintWalker) use(capability string, line int, binding string) {
+	if _, seen := w.uses[capability]; !seen {
+		w.uses[capability] = LintIssue{File: w.file, Line: line, Message: binding}
+	}
+}
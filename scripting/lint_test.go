@@ -0,0 +1,62 @@
+package scripting
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLintPlugin tests manifest, dependency, global and permission checks
+func TestLintPlugin(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tracker")
+	writeTestFile(t, dir, "metadata.json", `{
+		"id": "tracker", "name": "Tracker", "version": "one.two", "author": "tester",
+		"permissions": ["save.gil:read", "ui.dialog", "read_save"],
+		"dependencies": ["core-lib@^1.2.0", "broken@>>1", "tracker"]
+	}`)
+	writeTestFile(t, dir, "plugin.lua", `
+local function loadstring(s) return s end -- a local is fine
+print(loadstring("x"))
+local gil = save.getGil()
+save.setGil(gil + 1)
+local f = load("return 1")
+local rows = save.query("espers where owned")
+`)
+	writeTestFile(t, dir, "tracker_test.lua", `local plugin = require("plugin")`)
+	writeTestFile(t, dir, "broken.lua", `function (`)
+
+	report, err := LintPlugin(dir)
+	if err != nil {
+		t.Fatalf("LintPlugin failed: %v", err)
+	}
+	want := map[string]string{
+		"manifest":              "metadata.json",
+		"dependencies":          "metadata.json",
+		"legacy-permission":     "metadata.json",
+		"unused-permission":     "metadata.json", // ui.dialog
+		"forbidden-global":      "plugin.lua:6",
+		"undeclared-permission": "plugin.lua:",
+		"syntax":                "broken.lua",
+	}
+	got := make(map[string]bool)
+	for _, i := range report.Issues {
+		got[i.Rule] = true
+		if prefix, ok := want[i.Rule]; ok && !strings.HasPrefix(i.String(), prefix) {
+			t.Errorf("%s reported at %s, want %s", i.Rule, i, prefix)
+		}
+		if i.Rule == "forbidden-global" && !strings.Contains(i.Message, "load ") {
+			t.Errorf("unexpected forbidden global: %s", i)
+		}
+		if i.File == "tracker_test.lua" {
+			t.Errorf("test files are only syntax checked: %s", i)
+		}
+	}
+	for rule := range want {
+		if !got[rule] {
+			t.Errorf("missing %s issue in:\n%v", rule, report.Issues)
+		}
+	}
+	if strings.Join(report.Uses, ",") != "save.espers:read,save.gil:read,save.gil:write" {
+		t.Errorf("Uses = %v", report.Uses)
+	}
+}
@@ -3,6 +3,9 @@ package scripting
 import (
 	"context"
 	"ffvi_editor/io/pr"
+	prModels "ffvi_editor/models/pr"
+	"ffvi_editor/models/search"
+	"ffvi_editor/plugins"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,97 +18,20 @@ import (
 // LuaResult is a generic map result from Lua tables.
 type LuaResult map[string]interface{}
 
+// snippetTimeout bounds the editor's own snippets, which run unlimited
+// otherwise
+const snippetTimeout = 3 * time.Second
+
 // RunSnippet executes a Lua snippet with sandboxed VM and returns a LuaResult if a table is returned.
 func RunSnippet(ctx context.Context, code string) (LuaResult, error) {
-	if code == "" {
-		return nil, fmt.Errorf("code is empty")
-	}
-	// Context timeout safeguard
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	L := lua.NewState(lua.Options{SkipOpenLibs: true})
-	defer L.Close()
-
-	openSafeLibs(L)
-
-	applyPackagePathSandbox(L)
-
-	// TODO: Enforce memory cap (gopher-lua does not support this natively; consider custom allocator or monitor usage)
-	// TODO: Block all network access (gopher-lua does not provide network by default, but document and test)
-
-	done := make(chan error, 1)
-	go func() {
-		done <- L.DoString(code)
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			return nil, err
-		}
-		// If a value was returned, convert when table
-		if L.GetTop() >= 1 {
-			val := L.Get(-1)
-			if tbl, ok := val.(*lua.LTable); ok {
-				return tableToMap(tbl), nil
-			}
-		}
-		return nil, nil
-	case <-ctx.Done():
-		return nil, fmt.Errorf("lua execution timeout: %w", ctx.Err())
-	}
+	res, _, err := RunLimited(ctx, code, nil, Limits{Timeout: snippetTimeout})
+	return res, err
 }
 
 // RunSnippetWithSave executes a Lua snippet with save data bindings.
 func RunSnippetWithSave(ctx context.Context, code string, save *pr.PR) (LuaResult, error) {
-	if code == "" {
-		return nil, fmt.Errorf("code is empty")
-	}
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	L := lua.NewState(lua.Options{SkipOpenLibs: true})
-	defer L.Close()
-
-	openSafeLibs(L)
-
-	applyPackagePathSandbox(L)
-
-	// TODO: Enforce memory cap (gopher-lua does not support this natively; consider custom allocator or monitor usage)
-	// TODO: Block all network access (gopher-lua does not provide network by default, but document and test)
-
-	// Register save bindings if save provided
-	if save != nil {
-		registerSaveBindings(L, save)
-	}
-
-	done := make(chan error, 1)
-	go func() {
-		done <- L.DoString(code)
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			return nil, err
-		}
-		if L.GetTop() >= 1 {
-			val := L.Get(-1)
-			if tbl, ok := val.(*lua.LTable); ok {
-				return tableToMap(tbl), nil
-			}
-		}
-		return nil, nil
-	case <-ctx.Done():
-		return nil, fmt.Errorf("lua execution timeout: %w", ctx.Err())
-	}
+	res, _, err := RunLimited(ctx, code, save, Limits{Timeout: snippetTimeout})
+	return res, err
 }
 
 func tableToMap(tbl *lua.LTable) LuaResult {
@@ -252,18 +178,42 @@ func repoRoot() string {
 	return "."
 }
 
+// saveDataStorage returns the treasure flags decoded when save was loaded,
+// which are not the editor's when the save was loaded in isolation
+func saveDataStorage(save *pr.PR) *prModels.DataStorage {
+	if ds := save.Models().DataStorage(); ds != nil {
+		return ds
+	}
+	return prModels.GetDataStorage()
+}
+
 // registerSaveBindings registers Go functions for save data manipulation in Lua.
-func registerSaveBindings(L *lua.LState, save *pr.PR) {
+// When require is set, each function first checks its plugin capability.
+func registerSaveBindings(L *lua.LState, save *pr.PR, require func(capability string) error) {
 	// Create save table
 	saveTable := L.NewTable()
 
+	check := func(L *lua.LState, capability string) {
+		if require != nil {
+			if err := require(capability); err != nil {
+				L.RaiseError("%s: %v", capability, err)
+			}
+		}
+	}
+	guard := func(capability string, fn lua.LGFunction) *lua.LFunction {
+		return L.NewFunction(func(L *lua.LState) int {
+			check(L, capability)
+			return fn(L)
+		})
+	}
+
 	// Character access
-	L.SetField(saveTable, "getCharacterCount", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "getCharacterCount", guard(plugins.Capabilities.CharactersRead, func(L *lua.LState) int {
 		L.Push(lua.LNumber(len(save.Characters)))
 		return 1
 	}))
 
-	L.SetField(saveTable, "getCharacterName", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "getCharacterName", guard(plugins.Capabilities.CharactersRead, func(L *lua.LState) int {
 		idx := int(L.CheckNumber(1))
 		if idx < 0 || idx >= len(save.Characters) {
 			L.Push(lua.LNil)
@@ -286,7 +236,7 @@ func registerSaveBindings(L *lua.LState, save *pr.PR) {
 		return 1
 	}))
 
-	L.SetField(saveTable, "setCharacterLevel", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "setCharacterLevel", guard(plugins.Capabilities.CharactersWrite, func(L *lua.LState) int {
 		idx := int(L.CheckNumber(1))
 		level := int(L.CheckNumber(2))
 		if idx < 0 || idx >= len(save.Characters) || save.Characters[idx] == nil {
@@ -299,7 +249,7 @@ func registerSaveBindings(L *lua.LState, save *pr.PR) {
 		return 1
 	}))
 
-	L.SetField(saveTable, "setCharacterHP", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "setCharacterHP", guard(plugins.Capabilities.CharactersWrite, func(L *lua.LState) int {
 		idx := int(L.CheckNumber(1))
 		hp := int(L.CheckNumber(2))
 		if idx < 0 || idx >= len(save.Characters) || save.Characters[idx] == nil {
@@ -312,7 +262,7 @@ func registerSaveBindings(L *lua.LState, save *pr.PR) {
 		return 1
 	}))
 
-	L.SetField(saveTable, "setCharacterMP", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "setCharacterMP", guard(plugins.Capabilities.CharactersWrite, func(L *lua.LState) int {
 		idx := int(L.CheckNumber(1))
 		mp := int(L.CheckNumber(2))
 		if idx < 0 || idx >= len(save.Characters) || save.Characters[idx] == nil {
@@ -326,7 +276,7 @@ func registerSaveBindings(L *lua.LState, save *pr.PR) {
 	}))
 
 	// Inventory/party placeholder stubs
-	L.SetField(saveTable, "getGil", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "getGil", guard(plugins.Capabilities.GilRead, func(L *lua.LState) int {
 		val := save.UserData.Get("gil")
 		if val != nil {
 			if gil, ok := val.(float64); ok {
@@ -338,13 +288,84 @@ func registerSaveBindings(L *lua.LState, save *pr.PR) {
 		return 1
 	}))
 
-	L.SetField(saveTable, "setGil", L.NewFunction(func(L *lua.LState) int {
+	L.SetField(saveTable, "setGil", guard(plugins.Capabilities.GilWrite, func(L *lua.LState) int {
 		gil := int(L.CheckNumber(1))
 		save.UserData.Set("gil", float64(gil))
 		L.Push(lua.LBool(true))
 		return 1
 	}))
 
+	// Treasure flags, decoded from dataStorage when the save was loaded
+	L.SetField(saveTable, "getTreasures", guard(plugins.Capabilities.TreasuresRead, func(L *lua.LState) int {
+		filter := prModels.TreasureFilter{
+			MapID:    uint16(L.OptInt(1, 0)),
+			Unopened: L.OptBool(2, false),
+		}
+		list := L.NewTable()
+		for _, t := range saveDataStorage(save).Treasures(filter) {
+			row := L.NewTable()
+			L.SetField(row, "id", lua.LNumber(t.ID))
+			L.SetField(row, "mapId", lua.LNumber(t.MapID))
+			L.SetField(row, "map", lua.LString(t.MapName))
+			L.SetField(row, "contents", lua.LString(t.Contents))
+			L.SetField(row, "world", lua.LNumber(t.World))
+			L.SetField(row, "missable", lua.LBool(t.Missable))
+			L.SetField(row, "opened", lua.LBool(t.Opened))
+			L.SetField(row, "provisional", lua.LBool(t.Provisional))
+			list.Append(row)
+		}
+		L.Push(list)
+		return 1
+	}))
+
+	L.SetField(saveTable, "setTreasureOpened", guard(plugins.Capabilities.TreasuresWrite, func(L *lua.LState) int {
+		id := int(L.CheckNumber(1))
+		opened := L.OptBool(2, true)
+		err := prModels.CheckTreasureVerified(id)
+		if err == nil {
+			_, err = save.Models().SetTreasureOpened(id, opened)
+		}
+		if err != nil {
+			L.Push(lua.LBool(false))
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LBool(true))
+		return 1
+	}))
+
+	// Structured queries, e.g. save.query('characters where level < 30')
+	L.SetField(saveTable, "query", L.NewFunction(func(L *lua.LState) int {
+		q, err := search.ParseQuery(L.CheckString(1))
+		var res *search.QueryResult
+		if err == nil {
+			check(L, plugins.QueryCapability(q.Source))
+			res, err = q.Run()
+		}
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		list := L.NewTable()
+		for _, rec := range res.Records() {
+			row := L.NewTable()
+			for k, v := range rec {
+				switch v := v.(type) {
+				case int:
+					L.SetField(row, k, lua.LNumber(v))
+				case bool:
+					L.SetField(row, k, lua.LBool(v))
+				default:
+					L.SetField(row, k, lua.LString(fmt.Sprint(v)))
+				}
+			}
+			list.Append(row)
+		}
+		L.Push(list)
+		return 1
+	}))
+
 	L.SetField(saveTable, "log", L.NewFunction(func(L *lua.LState) int {
 		msg := L.CheckString(1)
 		fmt.Printf("[LUA] %s\n", msg)
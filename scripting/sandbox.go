@@ -0,0 +1,119 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/plugins"
+)
+
+// PolicyLimits converts a plugin's sandbox policy into runtime limits.
+// Strict isolation also removes require, so a plugin can only run its own code.
+func PolicyLimits(policy *plugins.SandboxPolicy) Limits {
+	if policy == nil {
+		return Limits{}
+	}
+	return Limits{
+		MaxInstructions: policy.MaxInstructions,
+		MaxMemoryBytes:  int64(policy.MaxMemoryMB) * 1024 * 1024,
+		Timeout:         time.Duration(policy.TimeoutSeconds) * time.Second,
+		MaxCPUPercent:   policy.MaxCPUPercent,
+		NoRequire:       policy.IsolationLevel == "strict",
+	}
+}
+
+// PluginMainFile is the Lua file a plugin directory runs
+const PluginMainFile = "plugin.lua"
+
+// PluginSource reads a loaded plugin's Lua code, returning it with the chunk
+// name to report it under. Plugins loaded from a directory run
+// PluginMainFile; plugins loaded from a single file run that file.
+func PluginSource(plugin *plugins.Plugin) (code, chunk string, err error) {
+	path := plugin.GetPath()
+	if filepath.Ext(path) != ".lua" {
+		path = filepath.Join(path, PluginMainFile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), filepath.Base(path), nil
+}
+
+// NewPluginRunner returns the Manager's PluginRunner: it runs a plugin's
+// source with RunPluginSnippet's capability checks and sm's limits, against
+// the save its API is bound to
+func NewPluginRunner(sm *plugins.SandboxManager) plugins.PluginRunner {
+	return func(ctx context.Context, plugin *plugins.Plugin, entry string) error {
+		api, ok := plugin.API.(*plugins.APIImpl)
+		if !ok {
+			return fmt.Errorf("plugin %s has no Lua API", plugin.ID)
+		}
+		code, chunk, err := PluginSource(plugin)
+		if err != nil {
+			return err
+		}
+		_, _, err = runPluginSnippet(ctx, sm, api, code, api.SaveData(), Limits{Chunk: chunk, Entry: entry})
+		return err
+	}
+}
+
+// violationTypes maps limit kinds to SecurityViolation types
+var violationTypes = map[string]string{
+	LimitInstructions: "cpu_exceeded",
+	LimitMemory:       "memory_exceeded",
+	LimitTimeout:      "timeout",
+}
+
+// RunPluginSnippet runs a plugin's Lua code as the plugin api is scoped to
+// (see APIImpl.ForPlugin): save bindings need the plugin's capabilities, its
+// store is bound as kv and its sandbox policy limits the run. A run stopped by a limit is recorded as
+// an enforced violation.
+func RunPluginSnippet(ctx context.Context, sm *plugins.SandboxManager, api *plugins.APIImpl, code string, save *pr.PR) (LuaResult, Usage, error) {
+	return runPluginSnippet(ctx, sm, api, code, save, Limits{})
+}
+
+// ProfilePluginSnippet runs a plugin's Lua code like RunPluginSnippet while
+// sampling its call stack into profile. chunk names the code in the
+// profile's frames, usually the file it was read from. When the code
+// returns a module and entry is set, the module's entry function is called
+// and profiled too.
+func ProfilePluginSnippet(ctx context.Context, sm *plugins.SandboxManager, api *plugins.APIImpl, code, chunk, entry string, save *pr.PR, profile *plugins.CPUProfile) (LuaResult, Usage, error) {
+	return runPluginSnippet(ctx, sm, api, code, save, Limits{Chunk: chunk, Entry: entry, Profile: profile})
+}
+
+// runPluginSnippet runs code under the plugin's policy limits, taking how
+// to run it from opts
+func runPluginSnippet(ctx context.Context, sm *plugins.SandboxManager, api *plugins.APIImpl, code string, save *pr.PR, opts Limits) (LuaResult, Usage, error) {
+	pluginID := api.PluginID()
+	var policy *plugins.SandboxPolicy
+	if sm != nil {
+		policy = sm.GetPolicy(pluginID)
+	}
+	limits := PolicyLimits(policy)
+	limits.Chunk, limits.Entry, limits.Profile = opts.Chunk, opts.Entry, opts.Profile
+	res, usage, err := runLimited(ctx, code, save, limits, api)
+
+	var limitErr *LimitError
+	if sm != nil && errors.As(err, &limitErr) {
+		sm.RecordViolation(&plugins.SecurityViolation{
+			PluginID:      pluginID,
+			ViolationType: violationTypes[limitErr.Kind],
+			Message:       limitErr.Error(),
+			Severity:      "CRITICAL",
+			Enforced:      true,
+			Details: map[string]interface{}{
+				"limit":        limitErr.Limit,
+				"used":         limitErr.Used,
+				"instructions": usage.Instructions,
+				"duration_ms":  usage.Duration.Milliseconds(),
+			},
+		})
+	}
+	return res, usage, err
+}
@@ -0,0 +1,31 @@
+package scripting
+
+import (
+	"ffvi_editor/plugins"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerSettingsBindings exposes the plugin's declared settings as the
+// global settings table:
+//
+//	settings.get("difficulty")    -- saved value, or the schema's default
+//	settings.set("difficulty", "hard")
+//
+// set returns true, or nil and a message when the schema rejects the value.
+func registerSettingsBindings(L *lua.LState, api *plugins.APIImpl) {
+	settings := L.NewTable()
+	L.SetField(settings, "get", L.NewFunction(func(L *lua.LState) int {
+		L.Push(goToLua(L, api.GetSetting(L.CheckString(1))))
+		return 1
+	}))
+	L.SetField(settings, "set", L.NewFunction(func(L *lua.LState) int {
+		key := L.CheckString(1)
+		value, err := luaToGo(L.CheckAny(2))
+		if err == nil {
+			err = api.SetSetting(key, value)
+		}
+		return pushResult(L, err)
+	}))
+	L.SetGlobal("settings", settings)
+}
@@ -0,0 +1,52 @@
+package scripting
+
+import (
+	"context"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+// memorySettings keeps one plugin's settings in memory
+type memorySettings struct {
+	schema plugins.SettingsSchema
+	saved  map[string]interface{}
+}
+
+func (s *memorySettings) PluginSettings(string) (plugins.SettingsSchema, map[string]interface{}, error) {
+	return s.schema, s.schema.Resolve(s.saved), nil
+}
+
+func (s *memorySettings) UpdateSettings(_ string, values map[string]interface{}) (map[string]interface{}, error) {
+	checked, err := s.schema.Validate(values)
+	if err != nil {
+		return nil, err
+	}
+	s.saved = checked
+	return s.schema.Resolve(checked), nil
+}
+
+func TestSettingsBindings(t *testing.T) {
+	backend := &memorySettings{schema: plugins.SettingsSchema{
+		{Key: "difficulty", Type: plugins.SettingEnum, Options: []string{"easy", "hard"}, Default: "easy"},
+	}}
+	base := plugins.NewAPIImpl(nil, nil)
+	base.SetSettingsBackend(backend)
+	api := base.ForPlugin("tracker", nil, nil)
+
+	res, _, err := RunPluginSnippet(context.Background(), nil, api, `
+		local before = settings.get("difficulty")
+		local ok, err = settings.set("difficulty", "nightmare")
+		assert(settings.set("difficulty", "hard"))
+		return {before = before, rejected = not ok and err ~= nil, after = settings.get("difficulty")}
+	`, nil)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	want := LuaResult{"before": "easy", "rejected": true, "after": "hard"}
+	for k, v := range want {
+		if res[k] != v {
+			t.Errorf("%s = %v, want %v", k, res[k], v)
+		}
+	}
+}
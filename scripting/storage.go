@@ -0,0 +1,185 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ffvi_editor/plugins"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerStoreBindings exposes the plugin's key-value store as the global
+// kv table:
+//
+//	kv.put("runs", 3)
+//	kv.get("runs")               -- 3
+//	kv.list("quest.")            -- sorted keys with the prefix
+//	kv.delete("runs")
+//	kv.transaction(function(tx) tx.put("a", 1); tx.put("b", 2) end)
+//	kv.forSave().put("seen", true) -- store of the open save file
+//
+// Writes return true, or nil and a message; a missing capability raises.
+func registerStoreBindings(ctx context.Context, L *lua.LState, api *plugins.APIImpl) {
+	store, err := api.Storage(ctx, false)
+	if err != nil {
+		return
+	}
+	kv := storeTable(L, store)
+	L.SetField(kv, "forSave", L.NewFunction(func(L *lua.LState) int {
+		saveStore, err := api.Storage(ctx, true)
+		if err != nil {
+			return pushResult(L, err)
+		}
+		L.Push(storeTable(L, saveStore))
+		return 1
+	}))
+	L.SetGlobal("kv", kv)
+}
+
+// kvOps is what both a store and an open transaction offer Lua
+type kvOps interface {
+	Get(key string) (interface{}, bool, error)
+	Put(key string, value interface{}) error
+	Delete(key string) error
+	List(prefix string) ([]string, error)
+}
+
+func storeTable(L *lua.LState, store *plugins.KVStore) *lua.LTable {
+	tbl := opsTable(L, store)
+	L.SetField(tbl, "transaction", L.NewFunction(func(L *lua.LState) int {
+		fn := L.CheckFunction(1)
+		err := store.Transaction(func(tx *plugins.KVTx) error {
+			return L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, opsTable(L, tx))
+		})
+		if err != nil {
+			return pushResult(L, err)
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}))
+	return tbl
+}
+
+func opsTable(L *lua.LState, ops kvOps) *lua.LTable {
+	tbl := L.NewTable()
+	L.SetField(tbl, "get", L.NewFunction(func(L *lua.LState) int {
+		v, ok, err := ops.Get(L.CheckString(1))
+		if err != nil {
+			return pushResult(L, err)
+		}
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(goToLua(L, v))
+		return 1
+	}))
+	L.SetField(tbl, "put", L.NewFunction(func(L *lua.LState) int {
+		key := L.CheckString(1)
+		value, err := luaToGo(L.CheckAny(2))
+		if err == nil {
+			err = ops.Put(key, value)
+		}
+		return pushResult(L, err)
+	}))
+	L.SetField(tbl, "delete", L.NewFunction(func(L *lua.LState) int {
+		return pushResult(L, ops.Delete(L.CheckString(1)))
+	}))
+	L.SetField(tbl, "list", L.NewFunction(func(L *lua.LState) int {
+		keys, err := ops.List(L.OptString(1, ""))
+		if err != nil {
+			return pushResult(L, err)
+		}
+		list := L.NewTable()
+		for _, k := range keys {
+			list.Append(lua.LString(k))
+		}
+		L.Push(list)
+		return 1
+	}))
+	return tbl
+}
+
+// pushResult pushes true, or nil and the error; permission errors raise
+func pushResult(L *lua.LState, err error) int {
+	if err == nil {
+		L.Push(lua.LTrue)
+		return 1
+	}
+	if errors.Is(err, plugins.ErrInsufficientPermissions) {
+		L.RaiseError("%v", err)
+	}
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// luaToGo converts a Lua value to what encoding/json stores. Tables whose
+// keys are exactly 1..n become lists, other tables maps.
+func luaToGo(v lua.LValue) (interface{}, error) {
+	switch x := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(x), nil
+	case lua.LNumber:
+		return float64(x), nil
+	case lua.LString:
+		return string(x), nil
+	case *lua.LTable:
+		if n := x.MaxN(); n > 0 && x.Len() == n && countKeys(x) == n {
+			list := make([]interface{}, n)
+			for i := 1; i <= n; i++ {
+				item, err := luaToGo(x.RawGetInt(i))
+				if err != nil {
+					return nil, err
+				}
+				list[i-1] = item
+			}
+			return list, nil
+		}
+		m := make(map[string]interface{})
+		var err error
+		x.ForEach(func(k, val lua.LValue) {
+			if err != nil {
+				return
+			}
+			m[k.String()], err = luaToGo(val)
+		})
+		return m, err
+	}
+	return nil, fmt.Errorf("cannot store a %s", v.Type())
+}
+
+func countKeys(tbl *lua.LTable) int {
+	n := 0
+	tbl.ForEach(func(lua.LValue, lua.LValue) { n++ })
+	return n
+}
+
+// goToLua converts a decoded JSON value to Lua
+func goToLua(L *lua.LState, v interface{}) lua.LValue {
+	switch x := v.(type) {
+	case bool:
+		return lua.LBool(x)
+	case float64:
+		return lua.LNumber(x)
+	case string:
+		return lua.LString(x)
+	case []interface{}:
+		tbl := L.NewTable()
+		for _, item := range x {
+			tbl.Append(goToLua(L, item))
+		}
+		return tbl
+	case map[string]interface{}:
+		tbl := L.NewTable()
+		for k, item := range x {
+			L.SetField(tbl, k, goToLua(L, item))
+		}
+		return tbl
+	}
+	return lua.LNil
+}
@@ -0,0 +1,64 @@
+package scripting
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ffvi_editor/plugins"
+)
+
+func storeAPI(t *testing.T, sm *plugins.StorageManager, perms ...string) *plugins.APIImpl {
+	t.Helper()
+	base := plugins.NewAPIImpl(nil, nil)
+	base.SetStorageManager(sm)
+	return base.ForPlugin("tracker", perms, nil)
+}
+
+func TestStoreBindings(t *testing.T) {
+	sm := plugins.NewStorageManager(t.TempDir())
+	api := storeAPI(t, sm, "fs.plugin-data:*")
+	ctx := context.Background()
+
+	_, _, err := RunPluginSnippet(ctx, nil, api, `
+		assert(kv.put("quest.narshe", {done = true, steps = {1, 2}}))
+		assert(kv.put("runs", 1))
+		local ok, err = kv.transaction(function(tx)
+			tx.put("runs", tx.get("runs") + 1)
+			error("abort")
+		end)
+		assert(not ok and err)
+		assert(kv.transaction(function(tx)
+			tx.put("runs", tx.get("runs") + 1)
+		end))
+	`, nil)
+	if err != nil {
+		t.Fatalf("write run failed: %v", err)
+	}
+
+	res, _, err := RunPluginSnippet(ctx, nil, api, `
+		local q = kv.get("quest.narshe")
+		return {runs = kv.get("runs"), done = q.done, step = q.steps[2], keys = #kv.list("quest."), missing = kv.get("none") == nil}
+	`, nil)
+	if err != nil {
+		t.Fatalf("read run failed: %v", err)
+	}
+	want := LuaResult{"runs": float64(2), "done": true, "step": float64(2), "keys": float64(1), "missing": true}
+	for k, v := range want {
+		if res[k] != v {
+			t.Errorf("%s = %v, want %v", k, res[k], v)
+		}
+	}
+
+	if _, _, err := RunPluginSnippet(ctx, nil, api, `local s, err = kv.forSave(); assert(s == nil and err)`, nil); err != nil {
+		t.Errorf("forSave without a save should return an error: %v", err)
+	}
+}
+
+func TestStoreBindingsNeedWrite(t *testing.T) {
+	api := storeAPI(t, plugins.NewStorageManager(t.TempDir()), plugins.Capabilities.PluginDataRead)
+	_, _, err := RunPluginSnippet(context.Background(), nil, api, `kv.put("x", 1)`, nil)
+	if err == nil || !strings.Contains(err.Error(), "insufficient permissions") {
+		t.Errorf("put without fs.plugin-data:write = %v, want a permission error", err)
+	}
+}
@@ -0,0 +1,253 @@
+package scripting
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const testFileType = "testfile"
+
+// testFile is a file opened by a test's io.open. The whole file is read at
+// open, so nothing is held open while the test runs.
+type testFile struct {
+	data   []byte
+	pos    int
+	closed bool
+}
+
+// registerTestIO gives test files a read-only io.open and io.lines plus
+// dofile and loadfile. Relative paths resolve against root, the directory
+// the suites are written to run from, and nothing outside it can be read.
+func registerTestIO(L *lua.LState, root string) {
+	read := func(L *lua.LState, name string) ([]byte, bool) {
+		data, err := readTestFile(root, name)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return nil, false
+		}
+		return data, true
+	}
+	load := func(L *lua.LState, name string) (*lua.LFunction, bool) {
+		data, ok := read(L, name)
+		if !ok {
+			return nil, false
+		}
+		fn, err := L.Load(bytes.NewReader(data), filepath.Base(name))
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return nil, false
+		}
+		return fn, true
+	}
+
+	mt := L.NewTypeMetatable(testFileType)
+	L.SetField(mt, "__index", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"read": func(L *lua.LState) int {
+			return checkTestFile(L).read(L, 2)
+		},
+		"lines": func(L *lua.LState) int {
+			f := checkTestFile(L)
+			L.Push(L.NewFunction(func(L *lua.LState) int {
+				return f.readLine(L, false)
+			}))
+			return 1
+		},
+		"write": func(L *lua.LState) int {
+			L.RaiseError("files are read-only in tests")
+			return 0
+		},
+		"close": func(L *lua.LState) int {
+			checkTestFile(L).closed = true
+			L.Push(lua.LTrue)
+			return 1
+		},
+	}))
+
+	io := L.NewTable()
+	L.SetField(io, "open", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		if mode := L.OptString(2, "r"); strings.ContainsAny(mode, "wa+") {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(name + ": files are read-only in tests"))
+			return 2
+		}
+		data, ok := read(L, name)
+		if !ok {
+			return 2
+		}
+		ud := L.NewUserData()
+		ud.Value = &testFile{data: data}
+		L.SetMetatable(ud, mt)
+		L.Push(ud)
+		return 1
+	}))
+	L.SetField(io, "lines", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		data, err := readTestFile(root, name)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+		}
+		f := &testFile{data: data}
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			return f.readLine(L, false)
+		}))
+		return 1
+	}))
+	L.SetGlobal("io", io)
+
+	L.SetGlobal("loadfile", L.NewFunction(func(L *lua.LState) int {
+		fn, ok := load(L, L.CheckString(1))
+		if !ok {
+			return 2
+		}
+		L.Push(fn)
+		return 1
+	}))
+	L.SetGlobal("dofile", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		fn, ok := load(L, name)
+		if !ok {
+			L.RaiseError("%s", L.Get(-1).String())
+		}
+		top := L.GetTop()
+		L.Push(fn)
+		L.Call(0, lua.MultRet)
+		return L.GetTop() - top
+	}))
+}
+
+// readTestFile reads name relative to root, refusing paths that leave root
+// directly or through a symbolic link
+func readTestFile(root, name string) ([]byte, error) {
+	path := filepath.FromSlash(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if !insideDir(root, path) {
+		return nil, fmt.Errorf("%s: outside the test root %s", name, root)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, errNotExistOr(err))
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	if !insideDir(realRoot, resolved) {
+		return nil, fmt.Errorf("%s: outside the test root %s", name, root)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, errNotExistOr(err))
+	}
+	return data, nil
+}
+
+// insideDir reports whether path is dir or below it
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// errNotExistOr shortens the not-found error Lua scripts usually print
+func errNotExistOr(err error) error {
+	if os.IsNotExist(err) {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+func checkTestFile(L *lua.LState) *testFile {
+	ud := L.CheckUserData(1)
+	f, ok := ud.Value.(*testFile)
+	if !ok {
+		L.ArgError(1, "file expected")
+	}
+	if f.closed {
+		L.RaiseError("attempt to use a closed file")
+	}
+	return f
+}
+
+// read implements file:read for the formats Lua 5.1 accepts, starting at
+// argument first; with none it reads a line
+func (f *testFile) read(L *lua.LState, first int) int {
+	top := L.GetTop()
+	if top < first {
+		return f.readLine(L, false)
+	}
+	for i := first; i <= top; i++ {
+		switch v := L.Get(i).(type) {
+		case lua.LNumber:
+			n := int(v)
+			if f.pos >= len(f.data) && n > 0 {
+				L.Push(lua.LNil)
+				return i - first + 1
+			}
+			end := min(f.pos+n, len(f.data))
+			L.Push(lua.LString(f.data[f.pos:end]))
+			f.pos = end
+		case lua.LString:
+			format := strings.TrimPrefix(string(v), "*")
+			switch {
+			case strings.HasPrefix(format, "a"):
+				L.Push(lua.LString(f.data[f.pos:]))
+				f.pos = len(f.data)
+			case strings.HasPrefix(format, "l"), strings.HasPrefix(format, "L"):
+				if f.readLine(L, format[0] == 'L') == 1 && L.Get(-1) == lua.LNil {
+					return i - first + 1
+				}
+			case strings.HasPrefix(format, "n"):
+				rest := f.data[f.pos:]
+				trimmed := bytes.TrimLeft(rest, " \t\r\n")
+				end := bytes.IndexAny(trimmed, " \t\r\n")
+				if end < 0 {
+					end = len(trimmed)
+				}
+				num, err := strconv.ParseFloat(string(trimmed[:end]), 64)
+				if err != nil {
+					L.Push(lua.LNil)
+					return i - first + 1
+				}
+				f.pos += len(rest) - len(trimmed) + end
+				L.Push(lua.LNumber(num))
+			default:
+				L.ArgError(i, "invalid format")
+			}
+		default:
+			L.ArgError(i, "invalid format")
+		}
+	}
+	return top - first + 1
+}
+
+// readLine pushes the next line, with its newline when keep is set, or nil
+// at the end of the file
+func (f *testFile) readLine(L *lua.LState, keep bool) int {
+	if f.pos >= len(f.data) {
+		L.Push(lua.LNil)
+		return 1
+	}
+	rest := f.data[f.pos:]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		end = len(rest)
+		f.pos = len(f.data)
+	} else {
+		f.pos += end + 1
+		if keep {
+			end++
+		}
+	}
+	L.Push(lua.LString(rest[:end]))
+	return 1
+}
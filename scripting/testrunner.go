@@ -0,0 +1,616 @@
+package scripting
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ffvi_editor/io/pr"
+	pri "ffvi_editor/models/pr"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// DefaultTestTimeout bounds one test file
+const DefaultTestTimeout = 30 * time.Second
+
+// TestOptions configure RunTests
+type TestOptions struct {
+	// Root is the directory require paths start from, so that
+	// require("plugins.x.y") finds <Root>/plugins/x/y.lua. Tests may read
+	// files under it with io.open, io.lines, dofile and loadfile; without
+	// it they may read the test file's own directory.
+	Root string
+	// Fixture loads the save bound as the save global. It is called for
+	// every test file and every test(), so each gets its own document, and
+	// runs with isolated save models so the ones already loaded are left
+	// alone. Without it save is nil.
+	Fixture func() (*pr.PR, error)
+	// Run keeps only tests whose name matches
+	Run *regexp.Regexp
+	// Limits applied to each file; Timeout defaults to DefaultTestTimeout
+	Limits Limits
+}
+
+// TestCase is the outcome of one test
+type TestCase struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Message  string        `json:"message,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// TestFileResult is the outcome of one test file. Err is set when the file
+// itself failed to run, as opposed to one of its tests failing.
+type TestFileResult struct {
+	File     string        `json:"file"`
+	Cases    []TestCase    `json:"cases"`
+	Err      string        `json:"error,omitempty"`
+	Output   string        `json:"output,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Failed counts the failing tests, counting a file error as one
+func (r *TestFileResult) Failed() int {
+	n := 0
+	for _, c := range r.Cases {
+		if !c.Passed {
+			n++
+		}
+	}
+	if r.Err != "" {
+		n++
+	}
+	return n
+}
+
+// TestReport is the outcome of a test run
+type TestReport struct {
+	Files    []TestFileResult `json:"files"`
+	Tests    int              `json:"tests"`
+	Failed   int              `json:"failed"`
+	Duration time.Duration    `json:"duration_ns"`
+}
+
+// OK reports whether every test passed
+func (r *TestReport) OK() bool {
+	return r.Failed == 0
+}
+
+// testFilePattern matches the file names DiscoverTests picks up
+var testFilePattern = regexp.MustCompile(`(_test|_tests|_smoke[^/]*)\.lua$`)
+
+// DiscoverTests finds Lua test files: *_test.lua, *_tests.lua and
+// *_smoke*.lua. With plugin IDs only those plugins' directories are
+// searched; otherwise all of dir is, skipping hidden directories such as
+// plugin data and quarantine.
+func DiscoverTests(dir string, pluginIDs []string) ([]string, error) {
+	roots := []string{dir}
+	if len(pluginIDs) > 0 {
+		roots = roots[:0]
+		for _, id := range pluginIDs {
+			root := filepath.Join(dir, id)
+			if info, err := os.Stat(root); err != nil || !info.IsDir() {
+				return nil, fmt.Errorf("plugin %s not found in %s", id, dir)
+			}
+			roots = append(roots, root)
+		}
+	}
+
+	var files []string
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if testFilePattern.MatchString(d.Name()) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// RunTests runs every file and collects the results
+func RunTests(ctx context.Context, files []string, opts TestOptions) *TestReport {
+	start := time.Now()
+	report := &TestReport{}
+	for _, file := range files {
+		res := RunTestFile(ctx, file, opts)
+		report.Files = append(report.Files, res)
+		report.Tests += len(res.Cases)
+		if res.Err != "" {
+			report.Tests++
+		}
+		report.Failed += res.Failed()
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+// errTestExit unwinds a file that called os.exit
+type errTestExit struct{ code int }
+
+func (e *errTestExit) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
+// testRun is the state of one test file while it runs
+type testRun struct {
+	opts   TestOptions
+	result *TestFileResult
+	output strings.Builder
+	tests  []namedTest
+	mark   time.Time // when the last test finished, for timing printed results
+}
+
+type namedTest struct {
+	name string
+	fn   *lua.LFunction
+}
+
+// RunTestFile runs one test file in its own Lua state. Tests come from:
+//
+//   - test(name, fn) calls, run after the file body with a fresh fixture each
+//   - printed "[PASS] name" / "[FAIL] name: message" or "✓ name" / "✗ name: message"
+//     lines, which is how the older smoke scripts report
+//   - a returned table with a run_all function, which is then called
+//
+// A file reporting nothing passes unless it errors or exits non-zero.
+func RunTestFile(ctx context.Context, file string, opts TestOptions) TestFileResult {
+	start := time.Now()
+	result := TestFileResult{File: file}
+	run := &testRun{opts: opts, result: &result, mark: start}
+	err := run.execute(ctx, file)
+	var exit *errTestExit
+	switch {
+	case errors.As(err, &exit):
+		if exit.code != 0 && result.Failed() == 0 {
+			result.Err = exit.Error()
+		}
+	case err != nil:
+		result.Err = err.Error()
+	}
+	result.Output = run.output.String()
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (r *testRun) execute(ctx context.Context, file string) error {
+	code, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	limits := r.opts.Limits
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultTestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+	defer cancel()
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	openSafeLibs(L)
+	r.setPackagePath(L, file)
+	root := r.opts.Root
+	if root == "" {
+		root = filepath.Dir(file)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+	registerTestIO(L, root)
+	r.registerHarness(L)
+	if err := r.bindFixture(L); err != nil {
+		return err
+	}
+	// Opening the libraries leaves their tables on the stack
+	L.SetTop(0)
+
+	l := newLimiter(ctx, L, limits)
+	if limits.MaxMemoryBytes > 0 {
+		limitStringRep(L, l)
+	}
+	L.SetContext(l)
+	defer l.finish()
+
+	fn, err := L.Load(strings.NewReader(string(code)), filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	L.Push(fn)
+	if err := r.call(L, l, 1); err != nil {
+		return err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	for _, t := range r.tests {
+		if err := r.runTest(L, l, t); err != nil {
+			return err
+		}
+	}
+
+	if tbl, ok := ret.(*lua.LTable); ok && len(r.result.Cases) == 0 {
+		if runAll, ok := tbl.RawGetString("run_all").(*lua.LFunction); ok {
+			L.Push(runAll)
+			if err := r.call(L, l, 1); err != nil {
+				return err
+			}
+			summary := L.Get(-1)
+			L.Pop(1)
+			if len(r.result.Cases) == 0 {
+				r.summaryCase(file, summary)
+			}
+		}
+	}
+	return nil
+}
+
+// call runs the function and arguments on the stack, turning a stop by the
+// limiter or os.exit into the matching Go error
+func (r *testRun) call(L *lua.LState, l *limiter, nret int) error {
+	err := L.PCall(L.GetTop()-1, nret, nil)
+	if l.err != nil {
+		return l.err
+	}
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		if exit, ok := apiErr.Object.(*lua.LUserData); ok {
+			if e, ok := exit.Value.(*errTestExit); ok {
+				return e
+			}
+		}
+	}
+	return err
+}
+
+// runTest runs one test() with its own fixture
+func (r *testRun) runTest(L *lua.LState, l *limiter, t namedTest) error {
+	if r.opts.Run != nil && !r.opts.Run.MatchString(t.name) {
+		return nil
+	}
+	if err := r.bindFixture(L); err != nil {
+		return err
+	}
+	start := time.Now()
+	L.Push(t.fn)
+	err := r.call(L, l, 0)
+	var exit *errTestExit
+	if l.err != nil || errors.As(err, &exit) {
+		return err
+	}
+	c := TestCase{Name: t.name, Passed: err == nil, Duration: time.Since(start)}
+	if err != nil {
+		c.Message = luaErrorMessage(err)
+	}
+	r.result.Cases = append(r.result.Cases, c)
+	r.mark = time.Now()
+	return nil
+}
+
+// summaryCase records a run_all that printed no per-test lines as one
+// test, failing when its summary counts failures
+func (r *testRun) summaryCase(file string, summary lua.LValue) {
+	c := TestCase{Name: filepath.Base(file), Passed: true, Duration: time.Since(r.mark)}
+	if tbl, ok := summary.(*lua.LTable); ok {
+		for _, key := range []string{"failed", "tests_failed"} {
+			if n, ok := tbl.RawGetString(key).(lua.LNumber); ok && n > 0 {
+				c.Passed = false
+				c.Message = fmt.Sprintf("%d test(s) failed", int(n))
+			}
+		}
+	}
+	r.result.Cases = append(r.result.Cases, c)
+}
+
+func (r *testRun) setPackagePath(L *lua.LState, file string) {
+	var paths []string
+	for _, dir := range []string{r.opts.Root, filepath.Dir(file)} {
+		if dir == "" {
+			continue
+		}
+		dir = filepath.ToSlash(dir)
+		paths = append(paths, dir+"/?.lua", dir+"/?/init.lua")
+	}
+	if pkg, ok := L.GetGlobal("package").(*lua.LTable); ok {
+		pkg.RawSetString("loadlib", lua.LNil)
+		pkg.RawSetString("cpath", lua.LString(""))
+		pkg.RawSetString("path", lua.LString(strings.Join(paths, ";")))
+	}
+}
+
+func (r *testRun) bindFixture(L *lua.LState) error {
+	if r.opts.Fixture == nil {
+		return nil
+	}
+	var save *pr.PR
+	err := pri.Isolated(func() (err error) {
+		save, err = r.opts.Fixture()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load fixture: %w", err)
+	}
+	registerSaveBindings(L, save, nil)
+	return nil
+}
+
+// legacyResult matches the result lines the smoke scripts print
+var legacyResult = regexp.MustCompile(`^\s*(\[PASS\]|\[FAIL\]|✓ PASS|✗ FAIL|✓|✗)\s+(?:Test \d+:\s*)?(.+?)(?:(?::| -)\s+(.*))?$`)
+
+// summaryLine matches the closing "✓ ALL TESTS PASSED" style lines, which
+// are not tests
+var summaryLine = regexp.MustCompile(`(?i)^(all|some) .*tests`)
+
+// record turns a printed line into a test case when it reports a result
+func (r *testRun) record(line string) {
+	m := legacyResult.FindStringSubmatch(line)
+	if m == nil || summaryLine.MatchString(m[2]) {
+		return
+	}
+	if r.opts.Run != nil && !r.opts.Run.MatchString(m[2]) {
+		return
+	}
+	passed := strings.Contains(m[1], "PASS") || m[1] == "✓"
+	c := TestCase{Name: m[2], Passed: passed, Duration: time.Since(r.mark)}
+	if !passed {
+		c.Message = m[3]
+	} else if m[3] != "" {
+		c.Name = m[2] + ": " + m[3]
+	}
+	r.result.Cases = append(r.result.Cases, c)
+	r.mark = time.Now()
+}
+
+// registerHarness installs print capture, os, test(), the expect
+// assertions and the editor UI mocks
+func (r *testRun) registerHarness(L *lua.LState) {
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, L.GetTop())
+		for i := range parts {
+			parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+		}
+		text := strings.Join(parts, "\t")
+		r.output.WriteString(text)
+		r.output.WriteByte('\n')
+		for _, line := range strings.Split(text, "\n") {
+			r.record(line)
+		}
+		return 0
+	}))
+
+	lua.OpenOs(L)
+	if osTable, ok := L.GetGlobal("os").(*lua.LTable); ok {
+		for _, name := range []string{"execute", "remove", "rename", "tmpname", "setlocale", "getenv"} {
+			osTable.RawSetString(name, lua.LNil)
+		}
+		osTable.RawSetString("exit", L.NewFunction(func(L *lua.LState) int {
+			code := 0
+			switch v := L.Get(1).(type) {
+			case lua.LNumber:
+				code = int(v)
+			case lua.LBool:
+				if !v {
+					code = 1
+				}
+			}
+			ud := L.NewUserData()
+			ud.Value = &errTestExit{code: code}
+			L.Error(ud, 0)
+			return 0
+		}))
+	}
+
+	L.SetGlobal("test", L.NewFunction(func(L *lua.LState) int {
+		r.tests = append(r.tests, namedTest{name: L.CheckString(1), fn: L.CheckFunction(2)})
+		return 0
+	}))
+
+	if err := L.DoString(testPrelude); err != nil {
+		panic(fmt.Sprintf("test prelude: %v", err))
+	}
+}
+
+// testPrelude defines the expect assertions and the editor UI mocks
+const testPrelude = `
+local function show(v)
+  if type(v) == "string" then return string.format("%q", v) end
+  return tostring(v)
+end
+
+local function deep_equal(a, b)
+  if a == b then return true end
+  if type(a) ~= "table" or type(b) ~= "table" then return false end
+  for k, v in pairs(a) do
+    if not deep_equal(v, b[k]) then return false end
+  end
+  for k in pairs(b) do
+    if a[k] == nil then return false end
+  end
+  return true
+end
+
+local function fail(msg, default)
+  error((msg and (msg .. ": ") or "") .. default, 4)
+end
+
+expect = {}
+function expect.equal(actual, expected, msg)
+  if not deep_equal(actual, expected) then fail(msg, "expected " .. show(expected) .. ", got " .. show(actual)) end
+end
+function expect.not_equal(actual, unexpected, msg)
+  if deep_equal(actual, unexpected) then fail(msg, "did not expect " .. show(actual)) end
+end
+function expect.truthy(v, msg)
+  if not v then fail(msg, "expected a truthy value, got " .. show(v)) end
+end
+function expect.falsy(v, msg)
+  if v then fail(msg, "expected a falsy value, got " .. show(v)) end
+end
+function expect.near(actual, expected, tolerance, msg)
+  if type(actual) ~= "number" or math.abs(actual - expected) > (tolerance or 1e-9) then
+    fail(msg, "expected " .. show(expected) .. " +/- " .. show(tolerance or 1e-9) .. ", got " .. show(actual))
+  end
+end
+function expect.type(v, name, msg)
+  if type(v) ~= name then fail(msg, "expected a " .. name .. ", got " .. type(v)) end
+end
+function expect.contains(haystack, needle, msg)
+  if type(haystack) == "string" then
+    if not string.find(haystack, needle, 1, true) then fail(msg, show(haystack) .. " does not contain " .. show(needle)) end
+    return
+  end
+  for _, v in pairs(haystack) do
+    if deep_equal(v, needle) then return end
+  end
+  fail(msg, "table does not contain " .. show(needle))
+end
+function expect.error(fn, pattern, msg)
+  local ok, err = pcall(fn)
+  if ok then fail(msg, "expected an error") end
+  if pattern and not string.find(tostring(err), pattern) then
+    fail(msg, "error " .. show(tostring(err)) .. " does not match " .. show(pattern))
+  end
+end
+
+local calls, confirms, inputs = {}, {}, {}
+local function recorder(name, reply)
+  return function(...)
+    table.insert(calls, {fn = name, args = {...}})
+    if reply then return reply() end
+  end
+end
+editor = {
+  showDialog = recorder("showDialog"),
+  showConfirm = recorder("showConfirm", function()
+    if #confirms > 0 then return table.remove(confirms, 1) end
+    return true
+  end),
+  showInput = recorder("showInput", function()
+    if #inputs > 0 then return table.remove(inputs, 1) end
+    return ""
+  end),
+  notify = recorder("notify"),
+  log = recorder("log"),
+}
+mock = {}
+function mock.confirm(answer) table.insert(confirms, answer) end
+function mock.input(text) table.insert(inputs, text) end
+function mock.calls(name)
+  local list = {}
+  for _, c in ipairs(calls) do
+    if name == nil or c.fn == name then table.insert(list, c) end
+  end
+  return list
+end
+function mock.reset() calls, confirms, inputs = {}, {}, {} end
+`
+
+func luaErrorMessage(err error) string {
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Object.String()
+	}
+	return err.Error()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *TestReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Cases     []junitCase `xml:"testcase"`
+	SystemOut string      `xml:"system-out,omitempty"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report as JUnit XML, one testsuite per file
+func (r *TestReport) WriteJUnit(w io.Writer) error {
+	out := junitSuites{Tests: r.Tests, Time: junitTime(r.Duration)}
+	for _, f := range r.Files {
+		suite := junitSuite{Name: f.File, Time: junitTime(f.Duration), SystemOut: f.Output}
+		class := strings.TrimSuffix(filepath.ToSlash(f.File), ".lua")
+		for _, c := range f.Cases {
+			jc := junitCase{Name: c.Name, Classname: class, Time: junitTime(c.Duration)}
+			if !c.Passed {
+				jc.Failure = &junitMessage{Message: c.Message, Text: c.Message}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, jc)
+		}
+		if f.Err != "" {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name: filepath.Base(f.File), Classname: class, Time: junitTime(f.Duration),
+				Error: &junitMessage{Message: f.Err, Text: f.Err},
+			})
+			suite.Errors++
+		}
+		suite.Tests = len(suite.Cases)
+		out.Failures += suite.Failures
+		out.Errors += suite.Errors
+		out.Suites = append(out.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,212 @@
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"ffvi_editor/io/pr"
+	"ffvi_editor/models"
+)
+
+// writeTestFile writes a Lua file into dir and returns its path
+func writeTestFile(t *testing.T, dir, name, source string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// TestRunTestFileCases tests test() and expect record passes and failures
+func TestRunTestFileCases(t *testing.T) {
+	file := writeTestFile(t, t.TempDir(), "calc_test.lua", `
+test("adds", function() expect.equal(1 + 1, 2) end)
+test("tables", function() expect.equal({a = {1, 2}}, {a = {1, 2}}) end)
+test("fails", function()
+  expect.equal(2 * 2, 5, "maths")
+end)
+test("errors", function() expect.error(function() error("boom") end, "boom") end)
+test("dialogs", function()
+  mock.confirm(false)
+  expect.falsy(editor.showConfirm("Sure?", "Really?"))
+  expect.equal(#mock.calls("showConfirm"), 1)
+end)
+`)
+	result := RunTestFile(context.Background(), file, TestOptions{})
+	if result.Err != "" {
+		t.Fatalf("file error: %s", result.Err)
+	}
+	if len(result.Cases) != 5 || result.Failed() != 1 {
+		t.Fatalf("cases = %+v, want 5 with 1 failure", result.Cases)
+	}
+	failed := result.Cases[2]
+	if failed.Passed || !strings.Contains(failed.Message, "maths") || !strings.Contains(failed.Message, "calc_test.lua:5") {
+		t.Errorf("failure = %+v, want the message and the caller's line", failed)
+	}
+
+	filtered := RunTestFile(context.Background(), file, TestOptions{Run: regexp.MustCompile("^add")})
+	if len(filtered.Cases) != 1 || filtered.Cases[0].Name != "adds" {
+		t.Errorf("--run filter kept %+v", filtered.Cases)
+	}
+}
+
+// TestRunTestFileLegacy tests smoke scripts that print results and exit
+func TestRunTestFileLegacy(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "old_smoke.lua", `
+print("[PASS] loads data")
+print("[FAIL] finds bosses: none found")
+print("SOME tests failed")
+os.exit(1)
+`)
+	result := RunTestFile(context.Background(), file, TestOptions{})
+	if len(result.Cases) != 2 || result.Failed() != 1 {
+		t.Fatalf("cases = %+v, want 2 with 1 failure", result.Cases)
+	}
+	if result.Err != "" {
+		t.Errorf("exit after reported failures should not add an error: %s", result.Err)
+	}
+	if !strings.Contains(result.Output, "[PASS] loads data") {
+		t.Errorf("output not captured: %q", result.Output)
+	}
+
+	silent := writeTestFile(t, dir, "silent_smoke.lua", `os.exit(2)`)
+	if result := RunTestFile(context.Background(), silent, TestOptions{}); result.Err != "exit status 2" {
+		t.Errorf("silent non-zero exit = %q, want exit status 2", result.Err)
+	}
+}
+
+// TestRunTestFileSandbox tests a test cannot escape the sandbox
+func TestRunTestFileSandbox(t *testing.T) {
+	file := writeTestFile(t, t.TempDir(), "escape_test.lua", `
+test("no shell", function() expect.equal(os.execute, nil) end)
+test("no files outside the root", function()
+  local f, err = io.open("../outside.txt")
+  expect.equal(f, nil)
+  expect.truthy(err:find("outside the test root"))
+end)
+`)
+	result := RunTestFile(context.Background(), file, TestOptions{})
+	if result.Err != "" || result.Failed() != 0 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// TestRunTestFileIO tests io.open, io.lines and dofile read under the test
+// root and nothing can be written
+func TestRunTestFileIO(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "data/values.txt", "first\nsecond\n42\n")
+	writeTestFile(t, root, "lib/helper.lua", `return {answer = 42}`)
+	writeTestFile(t, filepath.Dir(root), "secret.txt", "secret")
+	file := writeTestFile(t, root, "suite/io_test.lua", `
+test("reads", function()
+  local f = assert(io.open("data/values.txt", "r"))
+  expect.equal(f:read("*l"), "first")
+  expect.equal(f:read("*a"), "second\n42\n")
+  f:close()
+end)
+test("lines", function()
+  local n = 0
+  for _ in io.lines("data/values.txt") do n = n + 1 end
+  expect.equal(n, 3)
+end)
+test("dofile", function()
+  expect.equal(dofile("lib/helper.lua").answer, 42)
+  expect.equal(loadfile("lib/helper.lua")().answer, 42)
+end)
+test("read-only", function()
+  local f, err = io.open("data/out.txt", "w")
+  expect.equal(f, nil)
+  expect.truthy(err:find("read%-only"))
+  expect.error(function() assert(io.open("data/values.txt")):write("x") end, "read%-only")
+end)
+test("scoped", function()
+  expect.equal(io.open("../secret.txt"), nil)
+  expect.error(function() dofile("../secret.txt") end, "outside the test root")
+end)
+`)
+	result := RunTestFile(context.Background(), file, TestOptions{Root: root})
+	if result.Err != "" || len(result.Cases) != 5 || result.Failed() != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(root, "data", "out.txt")); !os.IsNotExist(err) {
+		t.Errorf("io.open in write mode created a file: %v", err)
+	}
+}
+
+// TestRunTestFileFixtureIsolated tests loading the fixture leaves the
+// editor's save models alone
+func TestRunTestFileFixtureIsolated(t *testing.T) {
+	models.GetMisc().GP = 1234
+	defer func() { models.GetMisc().GP = 0 }()
+
+	file := writeTestFile(t, t.TempDir(), "fixture_test.lua", `
+test("bound", function() expect.truthy(save) end)
+`)
+	result := RunTestFile(context.Background(), file, TestOptions{Fixture: func() (*pr.PR, error) {
+		models.GetMisc().GP = 99
+		return pr.New(), nil
+	}})
+	if result.Err != "" || result.Failed() != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if gp := models.GetMisc().GP; gp != 1234 {
+		t.Errorf("GP = %d after the fixture loaded, want 1234", gp)
+	}
+}
+
+// TestDiscoverTestsAndReports tests discovery and the JUnit and JSON reports
+func TestDiscoverTestsAndReports(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "alpha/alpha_test.lua", `test("ok", function() end)`)
+	writeTestFile(t, dir, "alpha/plugin.lua", `error("not a test")`)
+	writeTestFile(t, dir, "beta/tests/beta_tests.lua", `test("bad", function() expect.truthy(false) end)`)
+	writeTestFile(t, dir, ".hidden/x_test.lua", `error("skipped")`)
+
+	files, err := DiscoverTests(dir, nil)
+	if err != nil {
+		t.Fatalf("DiscoverTests failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("DiscoverTests = %v, want 2 files", files)
+	}
+	if only, _ := DiscoverTests(dir, []string{"alpha"}); len(only) != 1 {
+		t.Errorf("DiscoverTests(alpha) = %v", only)
+	}
+	if _, err := DiscoverTests(dir, []string{"missing"}); err == nil {
+		t.Error("DiscoverTests accepted a missing plugin")
+	}
+
+	report := RunTests(context.Background(), files, TestOptions{})
+	if report.OK() || report.Tests != 2 || report.Failed != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	var junit bytes.Buffer
+	if err := report.WriteJUnit(&junit); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+	var parsed junitSuites
+	if err := xml.Unmarshal(junit.Bytes(), &parsed); err != nil {
+		t.Fatalf("JUnit output is not valid XML: %v", err)
+	}
+	if parsed.Tests != 2 || parsed.Failures != 1 || len(parsed.Suites) != 2 {
+		t.Errorf("JUnit = %+v", parsed)
+	}
+
+	var js bytes.Buffer
+	if err := report.WriteJSON(&js); err != nil || !strings.Contains(js.String(), `"failed": 1`) {
+		t.Errorf("WriteJSON = %v:\n%s", err, js.String())
+	}
+}
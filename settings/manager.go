@@ -22,6 +22,9 @@ type Settings struct {
 	BackupsToKeep  int    `json:"backupsToKeep"`
 	BackupLocation string `json:"backupLocation"`
 
+	// Watch Mode
+	WatchRecipe string `json:"watchRecipe,omitempty"` // Recipe re-applied to saves written by the game
+
 	// Validation
 	ValidationLevel string `json:"validationLevel"` // "strict", "normal", "permissive"
 	AutoFix         bool   `json:"autoFix"`         // Auto-fix issues
@@ -52,7 +52,7 @@ func NewAnimationExportDialog(animation *models.AnimationData) *AnimationExportD
 func (aed *AnimationExportDialog) buildUI() {
 	// Export format selection
 	aed.formatSelect = widget.NewSelect(
-		[]string{"GIF (Animated)", "PNG (Frames)", "JSON (Metadata)"},
+		[]string{"GIF (Animated)", "PNG (Frames)", "JSON (Metadata)", "Pixel Remaster Mod"},
 		aed.onFormatChanged,
 	)
 	aed.formatSelect.SetSelected("GIF (Animated)")
@@ -175,6 +175,8 @@ func (aed *AnimationExportDialog) onExport(parent fyne.Window) {
 		aed.exportPNG(parent)
 	case "JSON (Metadata)":
 		aed.exportJSON(parent)
+	case "Pixel Remaster Mod":
+		aed.exportPRMod(parent)
 	}
 }
 
@@ -287,6 +289,40 @@ func (aed *AnimationExportDialog) exportJSON(parent fyne.Window) {
 	fdialog.Show()
 }
 
+func (aed *AnimationExportDialog) exportPRMod(parent fyne.Window) {
+	fdialog := dialog.NewFolderOpen(
+		func(uri fyne.ListableURI, err error) {
+			if err != nil {
+				dialog.ShowError(err, parent)
+				return
+			}
+
+			if uri == nil {
+				return
+			}
+
+			// The remaster's sprite scale is unverified, so use what the
+			// user entered rather than guessing
+			scale, _ := strconv.Atoi(aed.scaleEntry.Text)
+			columns, _ := strconv.Atoi(aed.columnsEntry.Text)
+
+			err = io.ExportPRMod(uri.Path(), aed.animation, io.PRModExportOptions{
+				Author:  aed.animation.Metadata.Author,
+				Scale:   scale,
+				Columns: columns,
+			})
+			if err != nil {
+				dialog.ShowError(err, parent)
+			} else {
+				dialog.ShowInformation("Success", fmt.Sprintf("Mod atlas exported to %s", uri.Name()), parent)
+			}
+		},
+		parent,
+	)
+
+	fdialog.Show()
+}
+
 // Helper methods
 
 func (aed *AnimationExportDialog) getBackgroundColor() [3]uint8 {
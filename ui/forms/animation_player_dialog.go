@@ -65,6 +65,34 @@ func NewAnimationPlayerDialog(animation *models.AnimationData) *AnimationPlayerD
 	return apd
 }
 
+// NewAnimationPlayerDialogFromPRMod previews an atlas from a Pixel Remaster
+// mod folder, given the path of its metadata JSON under io.PRModMetaDir
+func NewAnimationPlayerDialogFromPRMod(atlasPath string) (*AnimationPlayerDialog, error) {
+	animation, _, err := io.ImportPRModAtlas(atlasPath)
+	if err != nil {
+		return nil, err
+	}
+	apd := NewAnimationPlayerDialog(animation)
+	if apd == nil {
+		return nil, fmt.Errorf("%s has no playable frames", atlasPath)
+	}
+	return apd, nil
+}
+
+// NewAnimationPlayerDialogFromPRModTexture previews a PNG from a mod folder
+// made without the editor, cut into frames with grid
+func NewAnimationPlayerDialogFromPRModTexture(dir, texture string, grid io.PRModGrid) (*AnimationPlayerDialog, error) {
+	animation, _, err := io.ImportPRModTexture(dir, texture, grid)
+	if err != nil {
+		return nil, err
+	}
+	apd := NewAnimationPlayerDialog(animation)
+	if apd == nil {
+		return nil, fmt.Errorf("%s has no playable frames", texture)
+	}
+	return apd, nil
+}
+
 // buildUI constructs the dialog UI
 func (apd *AnimationPlayerDialog) buildUI() {
 	apd.previewWidget = apd.createPreviewWidget()
@@ -1,6 +1,7 @@
 package forms
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
+	"ffvi_editor/plugins"
 	"ffvi_editor/ui/shortcuts"
 )
 
@@ -183,6 +185,33 @@ func (cp *CommandPalette) AddCommand(cmd *CommandPaletteItem) {
 	}
 }
 
+// AddPluginCommands adds the commands of every enabled plugin to the
+// palette, under the plugin's category or name
+func (cp *CommandPalette) AddPluginCommands(m *plugins.Manager) {
+	for _, pc := range m.Contributions() {
+		pluginID := pc.PluginID
+		for _, cmd := range pc.Commands {
+			commandID := cmd.ID
+			category := cmd.Category
+			if category == "" {
+				category = pc.PluginName
+			}
+			cp.AddCommand(&CommandPaletteItem{
+				ID:          pluginID + "." + commandID,
+				Name:        cmd.Title,
+				Description: cmd.Description,
+				Category:    category,
+				Shortcut:    cmd.Shortcut,
+				Action: func() {
+					if err := m.InvokeCommand(context.Background(), pluginID, commandID); err != nil {
+						dialog.ShowError(err, cp.window)
+					}
+				},
+			})
+		}
+	}
+}
+
 // Show displays the command palette dialog
 func (cp *CommandPalette) Show() {
 	// Build search entry
@@ -92,6 +92,13 @@ func NewCharacter(c *models.Character) *Character {
 		initialMagic:     c.Magic}
 	e.ExtendBaseWidget(e)
 	e.statusEffects = e.createStatusEffects()
+	edited := func() { CharacterEdited(c) }
+	inputs.OnEdit(e.name, edited)
+	inputs.OnEdit(e.isEnabled, edited)
+	for _, b := range []inputs.IntEntryBinding{e.level, e.exp, e.currentHP, e.maxHP, e.currentMP,
+		e.maxMP, e.strength, e.stamina, e.agility, e.magic} {
+		b.OnEdit(edited)
+	}
 	fmt.Printf("[DEBUG NewCharacter] Editor created successfully for %s\n", c.Name)
 	return e
 }
@@ -186,6 +193,7 @@ func (e *Character) CreateRenderer() fyne.WidgetRenderer {
 					e.c.Equipment.Relic1ID = 301 // Ribbon
 					e.c.Equipment.Relic2ID = 305 // Celestriad
 					// Items will be added to inventory by saver
+					CharacterEdited(e.c)
 				}),
 				widget.NewButton("Magitek", func() {
 					// Set command to Magitek
@@ -232,6 +240,7 @@ func (e *Character) CreateRenderer() fyne.WidgetRenderer {
 					e.c.Equipment.ArmorID = 252  // Mythril Mail
 					e.c.Equipment.Relic1ID = 200 // Empty
 					e.c.Equipment.Relic2ID = 200
+					CharacterEdited(e.c)
 				}),
 				widget.NewButton("Reset Exp", func() {
 					e.exp.Set(0)
@@ -283,7 +292,10 @@ func (e *Character) createStatusEffects() fyne.CanvasObject {
 
 	for _, se := range e.c.StatusEffects {
 		check := widget.NewCheck(se.Name, func(checked bool) {
-			se.Checked = checked
+			if se.Checked != checked {
+				se.Checked = checked
+				CharacterEdited(e.c)
+			}
 		})
 		check.SetChecked(se.Checked)
 		container.Add(check)
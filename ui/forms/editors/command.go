@@ -41,7 +41,7 @@ func NewCommands(c *models.Character) *Commands {
 	}
 	slices.Sort(options)
 	for i, cmd := range c.Commands {
-		e.selections[i] = newCmdSelect(options, cmd)
+		e.selections[i] = newCmdSelect(options, cmd, func() { CharacterEdited(c) })
 	}
 	return e
 }
@@ -64,18 +64,20 @@ func (e *Commands) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(container.NewGridWithColumns(2, c))
 }
 
-func newCmdSelect(options []string, cmd *models.Command) (s cmdSelect) {
+func newCmdSelect(options []string, cmd *models.Command, edited func()) (s cmdSelect) {
 	s = cmdSelect{
 		selection: widget.NewSelect(options, func(v string) {
 			if i, found := cmdStoI[v]; found {
-				cmd.Value = i
 				s.entry.SetInt(i)
 			}
 		}),
 		entry: inputs.NewIntEntry(),
 	}
 	s.entry.OnChanged = func(v string) {
-		cmd.Value = s.entry.Int()
+		if i := s.entry.Int(); i != cmd.Value {
+			cmd.Value = i
+			edited()
+		}
 		if i, found := cmdItoS[cmd.Value]; found {
 			s.selection.Selected = i
 		} else {
@@ -0,0 +1,27 @@
+package editors
+
+import "ffvi_editor/models"
+
+// The editors write straight into the save models. The main window sets
+// these to tell plugins about the edits; either may be nil.
+var (
+	// OnCharacterEdited is called after a character's stats, spells,
+	// equipment, commands or status effects change
+	OnCharacterEdited func(c *models.Character)
+	// OnInventoryChanged is called after an inventory row changes
+	OnInventoryChanged func()
+)
+
+// CharacterEdited reports an edit to c through OnCharacterEdited
+func CharacterEdited(c *models.Character) {
+	if OnCharacterEdited != nil && c != nil {
+		OnCharacterEdited(c)
+	}
+}
+
+// InventoryChanged reports an inventory edit through OnInventoryChanged
+func InventoryChanged() {
+	if OnInventoryChanged != nil {
+		OnInventoryChanged()
+	}
+}
@@ -77,12 +77,17 @@ func (e *Equipment) CreateRenderer() fyne.WidgetRenderer {
 		return widget.NewSimpleRenderer(container.NewVBox(widget.NewLabel("Error: Character not found")))
 	}
 	fmt.Printf("[DEBUG Equipment.CreateRenderer] Rendering equipment for %s\n", e.c.Name)
-	e.weaponEntry = inputs.NewIntEntryWithData(&e.c.Equipment.WeaponID)
-	e.shieldEntry = inputs.NewIntEntryWithData(&e.c.Equipment.ShieldID)
-	e.helmetEntry = inputs.NewIntEntryWithData(&e.c.Equipment.HelmetID)
-	e.armorEntry = inputs.NewIntEntryWithData(&e.c.Equipment.ArmorID)
-	e.relic1Entry = inputs.NewIntEntryWithData(&e.c.Equipment.Relic1ID)
-	e.relic2Entry = inputs.NewIntEntryWithData(&e.c.Equipment.Relic2ID)
+	slot := func(id *int) *inputs.IntEntry {
+		b := inputs.NewIntEntryBinding(id)
+		b.OnEdit(func() { CharacterEdited(e.c) })
+		return inputs.NewIntEntryWithBinding(b)
+	}
+	e.weaponEntry = slot(&e.c.Equipment.WeaponID)
+	e.shieldEntry = slot(&e.c.Equipment.ShieldID)
+	e.helmetEntry = slot(&e.c.Equipment.HelmetID)
+	e.armorEntry = slot(&e.c.Equipment.ArmorID)
+	e.relic1Entry = slot(&e.c.Equipment.Relic1ID)
+	e.relic2Entry = slot(&e.c.Equipment.Relic2ID)
 
 	// Add validation
 	validateItemID := func(entry *inputs.IntEntry) {
@@ -32,8 +32,8 @@ func NewInventory() *Inventory {
 
 	for _, item := range inv {
 		e.items.Add(inputs.NewKeyValueIntEntryWithHint(
-			inputs.NewIntEntryWithData(&item.ItemID),
-			inputs.NewIntEntryWithData(&item.Count)))
+			inventoryEntry(&item.ItemID),
+			inventoryEntry(&item.Count)))
 	}
 
 	e.search.OnChanged = func(s string) {
@@ -55,6 +55,13 @@ func NewInventory() *Inventory {
 	return e
 }
 
+// inventoryEntry is an entry bound to an inventory value that reports edits
+func inventoryEntry(i *int) *inputs.IntEntry {
+	b := inputs.NewIntEntryBinding(i)
+	b.OnEdit(InventoryChanged)
+	return inputs.NewIntEntryWithBinding(b)
+}
+
 func (e *Inventory) CreateRenderer() fyne.WidgetRenderer {
 	l1 := widget.NewLabel("Item ID")
 	l1.Alignment = fyne.TextAlignCenter
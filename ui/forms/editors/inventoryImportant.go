@@ -2,7 +2,6 @@ package editors
 
 import (
 	"ffvi_editor/models/pr"
-	"ffvi_editor/ui/forms/inputs"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
@@ -24,8 +23,8 @@ func NewInventoryImportant() *InventoryImportant {
 
 	for _, item := range inv {
 		e.items.Add(container.NewGridWithColumns(2,
-			inputs.NewIntEntryWithData(&item.ItemID),
-			inputs.NewIntEntryWithData(&item.Count)))
+			inventoryEntry(&item.ItemID),
+			inventoryEntry(&item.Count)))
 	}
 	return e
 }
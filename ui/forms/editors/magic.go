@@ -48,6 +48,7 @@ func NewMagic(c *models.Character) *Magic {
 	include := make([]fyne.CanvasObject, len(c.SpellsSorted))
 	for i, s := range c.SpellsSorted {
 		b := inputs.NewIntEntryBinding(&s.Value)
+		b.OnEdit(func() { CharacterEdited(c) })
 		in := inputs.NewIntEntryWithBinding(b)
 		bindings[i] = b
 		spells[s.Name] = inputs.NewLabeledEntry(s.Name, in)
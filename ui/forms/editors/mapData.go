@@ -430,6 +430,43 @@ func (e *MapData) CreateRenderer() fyne.WidgetRenderer {
 				inputs.NewLabeledEntry("Position Z", inputs.NewFloatEntryWithData(&f.Position.Z)),
 				inputs.NewLabeledEntry("Facing Direction", inputs.NewIntEntryWithData(&f.Direction)),
 			)))
+		// The other records (the chocobo among them) are not identified yet
+		for i, v := range transport {
+			if i == 3 || i == 4 || v == nil {
+				continue
+			}
+			cards = append(cards,
+				widget.NewCard(pr.TransportationName(i), "Unidentified vehicle record", container.NewVBox(
+					inputs.NewLabeledEntry("Enabled", widget.NewCheckWithData("", binding.BindBool(&v.Enabled))),
+					inputs.NewLabeledEntry("World", e.newWorldSelectUnset(&v.MapID)),
+					inputs.NewLabeledEntry("Position X", inputs.NewFloatEntryWithData(&v.Position.X)),
+					inputs.NewLabeledEntry("Position Y", inputs.NewFloatEntryWithData(&v.Position.Y)),
+					inputs.NewLabeledEntry("Position Z", inputs.NewFloatEntryWithData(&v.Position.Z)),
+					inputs.NewLabeledEntry("Facing Direction", inputs.NewIntEntryWithData(&v.Direction)),
+				)))
+		}
+	}
+
+	if timer := pr.GetTimer(); timer.Present {
+		cards = append(cards,
+			widget.NewCard("Countdown Timer", "Timer IDs are provisional", container.NewVBox(
+				inputs.NewLabeledEntry("Running", widget.NewCheckWithData("", binding.BindBool(&timer.Active))),
+				inputs.NewLabeledEntry("Timer ID", inputs.NewIntEntryWithData(&timer.ID)),
+				inputs.NewLabeledEntry("Seconds Left", inputs.NewFloatEntryWithData(&timer.Remaining)),
+			)))
+	}
+	if cache := pr.GetTeleportCache(); cache.Present {
+		cards = append(cards,
+			widget.NewCard("Warp Return Point", "", container.NewVBox(
+				inputs.NewLabeledIntEntryWithHint("Map ID", inputs.NewIntEntryWithData(&cache.MapID), inputs.HintArgs{
+					Align: inputs.NewAlign(fyne.TextAlignTrailing),
+					Hints: &mapLookup,
+				}),
+				inputs.NewLabeledEntry("Entrance", inputs.NewIntEntryWithData(&cache.PointIn)),
+				inputs.NewLabeledEntry("Position X", inputs.NewFloatEntryWithData(&cache.Position.X)),
+				inputs.NewLabeledEntry("Position Y", inputs.NewFloatEntryWithData(&cache.Position.Y)),
+				inputs.NewLabeledEntry("Position Z", inputs.NewFloatEntryWithData(&cache.Position.Z)),
+			)))
 	}
 
 	// Combine mapSection and cards into a single slice for NewVBox
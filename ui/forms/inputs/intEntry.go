@@ -106,6 +106,24 @@ func (b IntEntryBinding) Set(i int) {
 	_ = b.s.Set(strconv.Itoa(i))
 }
 
+// OnEdit calls f each time the bound int changes
+func (b IntEntryBinding) OnEdit(f func()) {
+	OnEdit(b.i, f)
+}
+
+// OnEdit calls f each time data changes after this call. Bindings call a
+// new listener once straight away; that call is skipped.
+func OnEdit(data binding.DataItem, f func()) {
+	added := false
+	data.AddListener(binding.NewDataListener(func() {
+		if !added {
+			added = true
+			return
+		}
+		f()
+	}))
+}
+
 /*
 package widget
 
@@ -32,6 +32,19 @@ type AnalyticsStat struct {
 	TrendPercent   float64
 }
 
+// DailyActivity is one day of plugin activity, read from the daily rollups
+// of the manager's event store so it covers earlier sessions too
+type DailyActivity struct {
+	Day         time.Time
+	Loads       int
+	Executions  int
+	Errors      int
+	AvgExecTime time.Duration
+}
+
+// historyDays is how many days the activity history shows
+const historyDays = 14
+
 // PluginAnalyticsDashboard displays usage patterns and reliability metrics
 type PluginAnalyticsDashboard struct {
 	analytics   *plugins.AnalyticsEngine
@@ -39,6 +52,7 @@ type PluginAnalyticsDashboard struct {
 	window      fyne.Window
 	refreshRate time.Duration
 	stats       map[string]*AnalyticsStat
+	history     []*DailyActivity
 	mu          sync.RWMutex
 	stopChan    chan struct{}
 	running     bool
@@ -70,6 +84,8 @@ func (d *PluginAnalyticsDashboard) Show() fyne.CanvasObject {
 		widget.NewSeparator(),
 		d.createTrendAnalysisSection(),
 		widget.NewSeparator(),
+		d.createHistorySection(),
+		widget.NewSeparator(),
 		d.createExportSection(),
 	)
 
@@ -327,6 +343,67 @@ func (d *PluginAnalyticsDashboard) getTrendColor(trend string) color.Color {
 	}
 }
 
+// createHistorySection displays daily activity across sessions
+func (d *PluginAnalyticsDashboard) createHistorySection() *fyne.Container {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	box := container.NewVBox(
+		canvas.NewText(fmt.Sprintf("Activity History (last %d days)", historyDays), color.White),
+	)
+	if d.manager.GetEventStore() == nil {
+		box.Add(canvas.NewText("History is not being recorded", color.Gray{Y: 128}))
+		return box
+	}
+	if len(d.history) == 0 {
+		box.Add(canvas.NewText("No activity recorded yet", color.Gray{Y: 128}))
+		return box
+	}
+	for _, day := range d.history {
+		text := canvas.NewText(
+			fmt.Sprintf("%s: %d loads, %d executions, %d errors, %s avg",
+				day.Day.Format("Mon 2006-01-02"), day.Loads, day.Executions, day.Errors,
+				day.AvgExecTime.Round(time.Millisecond)),
+			color.Gray{Y: 200})
+		text.TextSize = 11
+		box.Add(text)
+	}
+	return box
+}
+
+// dailyActivity sums the analytics rollups of every plugin per day
+func dailyActivity(rollups []plugins.Rollup) []*DailyActivity {
+	var days []*DailyActivity
+	byDay := make(map[int64]*DailyActivity)
+	execTime := make(map[int64]time.Duration)
+	for _, r := range rollups {
+		key := r.Start.Unix()
+		day, ok := byDay[key]
+		if !ok {
+			day = &DailyActivity{Day: r.Start}
+			byDay[key] = day
+			days = append(days, day)
+		}
+		switch r.Type {
+		case "plugin_load":
+			day.Loads += r.Count
+		case "plugin_execute":
+			day.Executions += r.Count
+			day.Errors += r.Failures
+			execTime[key] += r.TotalDuration
+		case "plugin_error":
+			day.Errors += r.Count
+		}
+	}
+	for key, day := range byDay {
+		if day.Executions > 0 {
+			day.AvgExecTime = execTime[key] / time.Duration(day.Executions)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.After(days[j].Day) })
+	return days
+}
+
 // createExportSection creates export controls
 func (d *PluginAnalyticsDashboard) createExportSection() *fyne.Container {
 	exportContainer := container.NewHBox(
@@ -354,6 +431,16 @@ func (d *PluginAnalyticsDashboard) RefreshAnalytics() {
 	// Clear old stats
 	d.stats = make(map[string]*AnalyticsStat)
 
+	if store := d.manager.GetEventStore(); store != nil {
+		rollups, err := store.Rollups(plugins.RollupDay, plugins.EventQuery{
+			Source: plugins.EventSourceAnalytics,
+			Since:  time.Now().AddDate(0, 0, -historyDays),
+		})
+		if err == nil {
+			d.history = dailyActivity(rollups)
+		}
+	}
+
 	// Get all plugin stats
 	allStats := d.analytics.GetAllPluginStats()
 
@@ -457,6 +544,14 @@ func (d *PluginAnalyticsDashboard) GetStats() map[string]*AnalyticsStat {
 	return statsCopy
 }
 
+// GetHistory returns the daily activity, newest day first
+func (d *PluginAnalyticsDashboard) GetHistory() []*DailyActivity {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return append([]*DailyActivity(nil), d.history...)
+}
+
 // GetPluginStat returns stats for a specific plugin
 func (d *PluginAnalyticsDashboard) GetPluginStat(pluginID string) *AnalyticsStat {
 	d.mu.RLock()
@@ -2,6 +2,7 @@ package forms
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -49,8 +50,12 @@ type PluginBrowserDialog struct {
 	downloading     bool
 }
 
-// NewPluginBrowserDialog creates a new marketplace browser dialog
+// NewPluginBrowserDialog creates a new marketplace browser dialog. Plugins
+// are installed through mgr, which checks their signatures.
 func NewPluginBrowserDialog(w fyne.Window, mgr *plugins.Manager, marketplaceClient *marketplace.Client, reg *marketplace.Registry) *PluginBrowserDialog {
+	if marketplaceClient != nil && mgr != nil {
+		marketplaceClient.SetInstaller(mgr)
+	}
 	d := &PluginBrowserDialog{
 		window:        w,
 		pluginManager: mgr,
@@ -539,7 +544,8 @@ func (d *PluginBrowserDialog) filterAndSortPlugins() {
 	}
 }
 
-// installPlugin downloads and installs a plugin
+// installPlugin downloads and installs a plugin once the user has confirmed
+// the install plan and the permissions it grants
 func (d *PluginBrowserDialog) installPlugin(plugin *marketplace.RemotePlugin) {
 	if d.downloading {
 		dialog.ShowInformation("Busy", "Another download is in progress", d.window)
@@ -550,20 +556,19 @@ func (d *PluginBrowserDialog) installPlugin(plugin *marketplace.RemotePlugin) {
 	d.progressBar.Show()
 	d.progressBar.SetValue(0)
 	d.progressContainer.Refresh()
-	d.setStatus(fmt.Sprintf("Installing %s...", plugin.Name))
+	d.setStatus(fmt.Sprintf("Planning %s...", plugin.Name))
 
-	go func() {
+	confirmPluginInstall(d.window, d.pluginManager, d.marketplace, plugin.ID, plugin.Version, func(err error) {
 		defer func() {
 			d.downloading = false
 			d.progressBar.Hide()
 			d.progressContainer.Refresh()
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-
-		d.progressBar.SetValue(0.3)
-		err := d.marketplace.InstallPlugin(ctx, plugin.ID, plugin.Version)
+		if errors.Is(err, errInstallCancelled) {
+			d.setStatus("Installation cancelled")
+			return
+		}
 		if err != nil {
 			d.setStatus(fmt.Sprintf("Error: %v", err))
 			dialog.ShowError(fmt.Errorf("failed to install plugin: %w", err), d.window)
@@ -588,6 +593,69 @@ func (d *PluginBrowserDialog) installPlugin(plugin *marketplace.RemotePlugin) {
 		dialog.ShowInformation("Success",
 			fmt.Sprintf("Plugin '%s' installed successfully!\n\nRestart the editor to activate the plugin.", plugin.Name),
 			d.window)
+	})
+}
+
+// errInstallCancelled is passed to confirmPluginInstall's done when the
+// user declines the plan
+var errInstallCancelled = errors.New("installation cancelled")
+
+// confirmPluginInstall plans installing a marketplace plugin and its
+// dependencies through mgr, shows each change with the permissions it
+// gains, and applies the plan through client once the user confirms. done
+// gets the result, or errInstallCancelled when the user declines.
+func confirmPluginInstall(w fyne.Window, mgr *plugins.Manager, client *marketplace.Client, pluginID, version string, done func(error)) {
+	if mgr == nil || client == nil {
+		done(marketplace.ErrNoInstaller)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+
+	go func() {
+		plan, err := mgr.PlanInstall(ctx, client, pluginID, version)
+		if err != nil {
+			cancel()
+			done(err)
+			return
+		}
+		if plan.Empty() {
+			cancel()
+			done(nil)
+			return
+		}
+
+		var sb strings.Builder
+		for _, step := range plan.Steps {
+			fmt.Fprintf(&sb, "%s %s %s", step.Action, step.PluginID, step.ToVersion)
+			if step.Reason != "" && step.Reason != "requested" {
+				fmt.Fprintf(&sb, " (needed by %s)", step.Reason)
+			}
+			sb.WriteString("\n")
+			if len(step.NewPermissions) > 0 {
+				sb.WriteString("    will be allowed to:\n")
+				for _, line := range plugins.DescribePermissions(step.NewPermissions) {
+					fmt.Fprintf(&sb, "      %s\n", line)
+				}
+			}
+		}
+		details := widget.NewLabel(sb.String())
+		details.Wrapping = fyne.TextWrapWord
+		content := container.NewBorder(widget.NewLabel("Installing will make these changes:"), nil, nil, nil,
+			container.NewVScroll(details))
+
+		confirm := dialog.NewCustomConfirm("Install Plugin", "Install", "Cancel", content, func(ok bool) {
+			if !ok {
+				cancel()
+				done(errInstallCancelled)
+				return
+			}
+			go func() {
+				defer cancel()
+				done(client.ApplyPlan(ctx, mgr, plan))
+			}()
+		}, w)
+		confirm.Resize(fyne.NewSize(480, 320))
+		confirm.Show()
 	}()
 }
 
@@ -607,12 +675,7 @@ func (d *PluginBrowserDialog) updatePlugin(plugin *marketplace.RemotePlugin) {
 	msg := fmt.Sprintf("Update %s from v%s to v%s?", plugin.Name, installed.Version, plugin.Version)
 	dialog.ShowConfirm("Update Plugin", msg, func(confirmed bool) {
 		if confirmed {
-			// Uninstall old version first (best-effort)
-			if d.registry != nil {
-				_ = d.registry.UninstallPlugin(plugin.ID)
-			}
-
-			// Install new version
+			// The install plan upgrades the installed version in place
 			d.installPlugin(plugin)
 		}
 	}, d.window)
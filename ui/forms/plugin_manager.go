@@ -2,6 +2,7 @@ package forms
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -25,8 +26,12 @@ type PluginManagerDialog struct {
 	outputLog      []string
 }
 
-// NewPluginManagerDialog creates a new plugin manager dialog
+// NewPluginManagerDialog creates a new plugin manager dialog. Plugins are
+// installed through pluginManager, which checks their signatures.
 func NewPluginManagerDialog(window fyne.Window, pluginManager *plugins.Manager, marketplaceClient *marketplace.Client, registry *marketplace.Registry) *PluginManagerDialog {
+	if marketplaceClient != nil && pluginManager != nil {
+		marketplaceClient.SetInstaller(pluginManager)
+	}
 	return &PluginManagerDialog{
 		window:        window,
 		pluginManager: pluginManager,
@@ -423,40 +428,28 @@ func (p *PluginManagerDialog) createAvailablePluginCard(plugin marketplace.Remot
 	parentContainer.Add(card)
 }
 
-// installPluginFromMarketplace installs a plugin from the marketplace
+// installPluginFromMarketplace installs a plugin from the marketplace once
+// the user has confirmed the install plan and the permissions it grants
 func (p *PluginManagerDialog) installPluginFromMarketplace(plugin marketplace.RemotePlugin) {
-	ctx := context.Background()
-
-	// Show progress dialog
-	progressBar := widget.NewProgressBarInfinite()
-	progressContent := container.NewVBox(
-		widget.NewLabel(fmt.Sprintf("Installing %s...", plugin.Name)),
-		progressBar,
-	)
-	progressDialog := dialog.NewCustom("Installing Plugin", "Cancel", progressContent, p.window)
-	progressDialog.Show()
-
-	// Install in background
-	go func() {
-		// Download and install
-		err := p.marketplace.InstallPlugin(ctx, plugin.ID, plugin.Version)
+	confirmPluginInstall(p.window, p.pluginManager, p.marketplace, plugin.ID, plugin.Version, func(err error) {
+		if errors.Is(err, errInstallCancelled) {
+			return
+		}
 		if err != nil {
-			progressDialog.Hide()
 			dialog.ShowError(fmt.Errorf("installation failed: %w", err), p.window)
 			return
 		}
 
 		// Track installation
-		err = p.registry.TrackInstallation(plugin.ID, plugin.Version)
-		if err != nil {
-			progressDialog.Hide()
-			dialog.ShowError(fmt.Errorf("failed to track installation: %w", err), p.window)
-			return
+		if p.registry != nil {
+			if err := p.registry.TrackInstallation(plugin.ID, plugin.Version); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to track installation: %w", err), p.window)
+				return
+			}
 		}
 
-		progressDialog.Hide()
 		dialog.ShowInformation("Success", fmt.Sprintf("%s installed successfully!", plugin.Name), p.window)
-	}()
+	})
 }
 
 // buildPluginOutputTab creates the plugin output/logging tab
@@ -541,36 +534,33 @@ func (p *PluginManagerDialog) executePlugin(pluginID string) {
 	dialog.ShowInformation("Success", fmt.Sprintf("Plugin '%s' executed successfully", pluginID), p.window)
 }
 
-// showPluginSettings displays settings for a specific plugin
+// showPluginSettings displays settings for a specific plugin, rendered
+// from the settings schema in its metadata
 func (p *PluginManagerDialog) showPluginSettings(plugin *plugins.Plugin) {
-	cfg, err := p.pluginManager.GetConfig(plugin.ID)
+	schema, values, err := p.pluginManager.PluginSettings(plugin.ID)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("could not load plugin config: %w", err), p.window)
 		return
 	}
 
-	// Build settings form
 	settingsForm := container.NewVBox(
 		widget.NewLabel(fmt.Sprintf("Settings for: %s", plugin.Name)),
 		widget.NewSeparator(),
 	)
-
-	// Add settings entries (this would be dynamic based on plugin metadata)
-	for key := range cfg.Settings {
-		label := widget.NewLabel(fmt.Sprintf("%s:", key))
-		entry := widget.NewEntry()
-		entry.SetText(fmt.Sprintf("%v", cfg.Settings[key]))
-
-		settingsForm.Add(container.NewHBox(label, entry))
+	if len(schema) == 0 {
+		settingsForm.Add(widget.NewLabel("This plugin declares no settings."))
 	}
+	f, form := newSettingsForm(schema, values, nil)
+	settingsForm.Add(form)
 
 	content := container.NewVBox(
 		settingsForm,
 		widget.NewSeparator(),
 		container.NewHBox(
 			widget.NewButton("Save", func() {
-				p.pluginManager.SetConfig(plugin.ID, cfg)
-				dialog.ShowInformation("Success", "Plugin settings saved", p.window)
+				if saveSettingsForm(p.pluginManager, plugin.ID, f, p.window) {
+					dialog.ShowInformation("Success", "Plugin settings saved", p.window)
+				}
 			}),
 			widget.NewButton("Cancel", func() {
 				// Dialog will close
@@ -1,6 +1,7 @@
 package forms
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"ffvi_editor/plugins"
+	"ffvi_editor/scripting"
 )
 
 // PerformanceMetric represents cached metric data for efficient UI rendering
@@ -74,6 +76,8 @@ func (d *PluginPerformanceDashboard) Show() fyne.CanvasObject {
 		widget.NewSeparator(),
 		d.createBottleneckAlertsSection(),
 		widget.NewSeparator(),
+		d.createLuaProfileSection(),
+		widget.NewSeparator(),
 		d.createExportSection(),
 	)
 
@@ -284,6 +288,124 @@ func (d *PluginPerformanceDashboard) createBottleneckAlertsSection() *fyne.Conta
 	return alertBox
 }
 
+// luaProfileTop is how many functions the Lua profile section lists
+const luaProfileTop = 15
+
+// createLuaProfileSection shows where a plugin's Lua code spends its time:
+// the functions with the most self time in the samples recorded for it,
+// with exports for flame graph tools
+func (d *PluginPerformanceDashboard) createLuaProfileSection() *fyne.Container {
+	summary := widget.NewLabel("Select a plugin to see its Lua profile")
+	functions := widget.NewLabel("")
+	functions.TextStyle.Monospace = true
+
+	var selected string
+	show := func() {
+		profile := d.profiler.GetCPUProfile(selected)
+		if profile == nil || profile.Samples() == 0 {
+			summary.SetText(fmt.Sprintf("No Lua profile recorded for %s; press Profile to take one", selected))
+			functions.SetText("")
+			return
+		}
+		sampled := profile.SampledTime()
+		summary.SetText(fmt.Sprintf("%s: %d samples over %s, every %d instructions",
+			selected, profile.Samples(), profile.Duration.Round(time.Millisecond), profile.Interval))
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%10s %6s %10s %6s  %s\n", "SELF", "SELF%", "TOTAL", "TOTAL%", "FUNCTION")
+		for i, f := range profile.Functions() {
+			if i == luaProfileTop {
+				break
+			}
+			fmt.Fprintf(&b, "%10s %5.1f%% %10s %5.1f%%  %s\n",
+				f.Self.Round(time.Microsecond), 100*float64(f.Self)/float64(sampled),
+				f.Total.Round(time.Microsecond), 100*float64(f.Total)/float64(sampled), f.Frame)
+		}
+		functions.SetText(b.String())
+	}
+
+	var ids []string
+	for _, p := range d.manager.ListPlugins() {
+		ids = append(ids, p.ID)
+	}
+	sort.Strings(ids)
+	pluginSelect := widget.NewSelect(ids, func(id string) {
+		selected = id
+		show()
+	})
+	pluginSelect.PlaceHolder = "Plugin"
+
+	profileButton := widget.NewButton("Profile", func() {
+		if selected == "" {
+			return
+		}
+		if err := d.profilePlugin(selected); err != nil {
+			summary.SetText(fmt.Sprintf("Profiling %s failed: %v", selected, err))
+			return
+		}
+		show()
+	})
+	export := func(ext string, write func(*plugins.CPUProfile, *os.File) error) {
+		profile := d.profiler.GetCPUProfile(selected)
+		if profile == nil {
+			return
+		}
+		filename := fmt.Sprintf("%s_profile_%d.%s", selected, time.Now().Unix(), ext)
+		file, err := os.Create(filename)
+		if err != nil {
+			fmt.Printf("Error creating profile file: %v\n", err)
+			return
+		}
+		defer file.Close()
+		if err := write(profile, file); err != nil {
+			fmt.Printf("Error writing profile: %v\n", err)
+			return
+		}
+		fmt.Printf("✓ Exported Lua profile to %s\n", filename)
+	}
+
+	return container.NewVBox(
+		canvas.NewText("Lua CPU Profile", color.White),
+		container.NewHBox(
+			pluginSelect,
+			profileButton,
+			widget.NewButton("Export Collapsed", func() {
+				export("folded", func(p *plugins.CPUProfile, f *os.File) error { return p.WriteCollapsed(f) })
+			}),
+			widget.NewButton("Export Speedscope", func() {
+				export("speedscope.json", func(p *plugins.CPUProfile, f *os.File) error { return p.WriteSpeedscope(f) })
+			}),
+		),
+		summary,
+		functions,
+	)
+}
+
+// profilePlugin runs a loaded plugin's plugin.lua once with its own API and
+// records the Lua stack samples in the profiler
+func (d *PluginPerformanceDashboard) profilePlugin(pluginID string) error {
+	plugin, err := d.manager.GetPlugin(pluginID)
+	if err != nil {
+		return err
+	}
+	api, ok := plugin.API.(*plugins.APIImpl)
+	if !ok {
+		return fmt.Errorf("plugin %s has no Lua API", pluginID)
+	}
+	code, chunk, err := scripting.PluginSource(plugin)
+	if err != nil {
+		return err
+	}
+
+	profile := plugins.NewCPUProfile(pluginID, plugins.DefaultProfileInterval)
+	start := time.Now()
+	_, _, err = scripting.ProfilePluginSnippet(context.Background(), d.manager.GetSandboxManager(),
+		api, code, chunk, "run", api.SaveData(), profile)
+	d.profiler.RecordExecution(pluginID, time.Since(start), err == nil, err)
+	d.profiler.RecordCPUProfile(profile)
+	return err
+}
+
 // createExportSection creates export controls
 func (d *PluginPerformanceDashboard) createExportSection() *fyne.Container {
 	exportContainer := container.NewHBox(
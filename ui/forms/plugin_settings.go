@@ -0,0 +1,169 @@
+package forms
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"ffvi_editor/plugins"
+)
+
+// settingsForm renders a plugin's settings schema. Only the fields named
+// in keys are shown; with no keys, every field is.
+type settingsForm struct {
+	fields []*plugins.SettingField
+	read   map[string]func() (interface{}, error)
+}
+
+// newSettingsForm builds a form item per field: a check for booleans, a
+// select for enums and an entry for everything else
+func newSettingsForm(schema plugins.SettingsSchema, values map[string]interface{}, keys []string) (*settingsForm, *widget.Form) {
+	f := &settingsForm{read: make(map[string]func() (interface{}, error))}
+	form := widget.NewForm()
+
+	if len(keys) == 0 {
+		for i := range schema {
+			keys = append(keys, schema[i].Key)
+		}
+	}
+	for _, key := range keys {
+		field := schema.Field(key)
+		if field == nil {
+			continue
+		}
+		f.fields = append(f.fields, field)
+
+		var input fyne.CanvasObject
+		switch field.Type {
+		case plugins.SettingBoolean:
+			check := widget.NewCheck("", nil)
+			check.SetChecked(values[key] == true)
+			f.read[key] = func() (interface{}, error) { return check.Checked, nil }
+			input = check
+		case plugins.SettingEnum:
+			sel := widget.NewSelect(field.Options, nil)
+			if s, ok := values[key].(string); ok {
+				sel.SetSelected(s)
+			}
+			f.read[key] = func() (interface{}, error) {
+				if sel.Selected == "" {
+					return nil, nil
+				}
+				return field.Parse(sel.Selected)
+			}
+			input = sel
+		default:
+			entry := widget.NewEntry()
+			if v, ok := values[key]; ok {
+				entry.SetText(field.Format(v))
+			}
+			if field.Default != nil {
+				entry.SetPlaceHolder(field.Format(field.Default))
+			}
+			entry.Validator = func(text string) error {
+				if text == "" {
+					return nil
+				}
+				_, err := field.Parse(text)
+				return err
+			}
+			f.read[key] = func() (interface{}, error) {
+				if entry.Text == "" {
+					return nil, nil
+				}
+				return field.Parse(entry.Text)
+			}
+			input = entry
+		}
+
+		item := widget.NewFormItem(field.Title(), input)
+		item.HintText = field.Description
+		form.AppendItem(item)
+	}
+	return f, form
+}
+
+// apply writes the form's values over values; an empty input falls back to
+// the field's default
+func (f *settingsForm) apply(values map[string]interface{}) error {
+	for _, field := range f.fields {
+		v, err := f.read[field.Key]()
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			delete(values, field.Key)
+		} else {
+			values[field.Key] = v
+		}
+	}
+	return nil
+}
+
+// saveSettingsForm applies a form to the plugin's current settings and
+// saves them, reporting problems in window
+func saveSettingsForm(m *plugins.Manager, pluginID string, f *settingsForm, window fyne.Window) bool {
+	_, values, err := m.PluginSettings(pluginID)
+	if err == nil {
+		err = f.apply(values)
+	}
+	if err == nil {
+		_, err = m.UpdateSettings(pluginID, values)
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("could not save plugin settings: %w", err), window)
+		return false
+	}
+	return true
+}
+
+// NewPluginPanel renders a panel a plugin contributes: its settings, with a
+// save button, and a button per command
+func NewPluginPanel(m *plugins.Manager, pluginID string, panel plugins.PanelContribution, window fyne.Window) fyne.CanvasObject {
+	content := container.NewVBox()
+	if panel.Description != "" {
+		label := widget.NewLabel(panel.Description)
+		label.Wrapping = fyne.TextWrapWord
+		content.Add(label)
+	}
+
+	if len(panel.Settings) > 0 {
+		schema, values, err := m.PluginSettings(pluginID)
+		if err != nil {
+			content.Add(widget.NewLabel(fmt.Sprintf("Settings unavailable: %v", err)))
+		} else {
+			f, form := newSettingsForm(schema, values, panel.Settings)
+			form.SubmitText = "Save"
+			form.OnSubmit = func() {
+				saveSettingsForm(m, pluginID, f, window)
+			}
+			content.Add(form)
+		}
+	}
+
+	if len(panel.Commands) > 0 {
+		content.Add(widget.NewSeparator())
+		var meta plugins.PluginMetadata
+		if plugin, err := m.GetPlugin(pluginID); err == nil {
+			meta = plugin.GetMetadata()
+		}
+		for _, id := range panel.Commands {
+			id := id
+			title := id
+			if cmd := meta.Contributes.Command(id); cmd != nil {
+				title = cmd.Title
+			}
+			content.Add(widget.NewButton(title, func() {
+				if err := m.InvokeCommand(context.Background(), pluginID, id); err != nil {
+					dialog.ShowError(err, window)
+				}
+			}))
+		}
+	}
+
+	return widget.NewCard(panel.Title, "", container.NewVScroll(content))
+}
@@ -2,6 +2,7 @@ package forms
 
 import (
 	"fmt"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -36,7 +37,7 @@ func NewSearchDialog(parent fyne.Window, index *search.Index, resultsCallback fu
 
 	// Search input
 	searchEntry := widget.NewEntry()
-	searchEntry.SetPlaceHolder("Search characters, items, spells, espers...")
+	searchEntry.SetPlaceHolder("Search by name, or query: characters where level < 30")
 
 	// Filter options
 	filterGroup := container.NewVBox(
@@ -70,20 +71,32 @@ func NewSearchDialog(parent fyne.Window, index *search.Index, resultsCallback fu
 		func(id widget.ListItemID, item fyne.CanvasObject) {},
 	)
 
+	// Query errors and match counts
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
 	// Search results display
 	resultsBox := container.NewVBox(
 		widget.NewLabel("Results:"),
+		statusLabel,
 		resultsList,
 	)
 
 	// Search function
 	performSearch := func(query string) {
+		statusLabel.SetText("")
 		if query == "" {
 			resultsList.Length = func() int { return 0 }
 			resultsList.Refresh()
 			return
 		}
 
+		// Structured queries run against the loaded save
+		if search.IsQuery(query) {
+			sd.showQueryResults(query, resultsList, statusLabel)
+			return
+		}
+
 		// Get search results from index (using search results type)
 		results := sd.searchIndex.Search(query)
 		if results == nil {
@@ -180,6 +193,53 @@ func NewSearchDialog(parent fyne.Window, index *search.Index, resultsCallback fu
 	return sd
 }
 
+// showQueryResults runs a structured query and lists each matched row by
+// name with its other fields as details
+func (sd *SearchDialog) showQueryResults(text string, resultsList *widget.List, statusLabel *widget.Label) {
+	var results []*SearchResult
+	q, err := search.ParseQuery(text)
+	var res *search.QueryResult
+	if err == nil {
+		res, err = q.Run()
+	}
+	if err != nil {
+		statusLabel.SetText(err.Error())
+	} else {
+		for _, rec := range res.Records() {
+			var details []string
+			for _, col := range res.Columns {
+				if col != "name" && col != "id" {
+					details = append(details, fmt.Sprintf("%s %v", col, rec[col]))
+				}
+			}
+			results = append(results, &SearchResult{
+				ID:      fmt.Sprint(rec["id"]),
+				Name:    fmt.Sprint(rec["name"]),
+				Type:    res.Source,
+				Details: strings.Join(details, " • "),
+			})
+		}
+		statusLabel.SetText(fmt.Sprintf("%d %s", len(results), res.Source))
+	}
+
+	resultsList.Length = func() int { return len(results) }
+	resultsList.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
+		if id < len(results) {
+			box := item.(*fyne.Container)
+			if len(box.Objects) >= 2 {
+				box.Objects[0].(*widget.Label).SetText(results[id].Name)
+				box.Objects[1].(*widget.Label).SetText(results[id].Details)
+			}
+		}
+	}
+	resultsList.OnSelected = func(id widget.ListItemID) {
+		if id < len(results) && sd.resultsCallback != nil {
+			sd.resultsCallback(results[id])
+		}
+	}
+	resultsList.Refresh()
+}
+
 // Show displays the search dialog
 func (sd *SearchDialog) Show() {
 	sd.dialog.Show()
@@ -77,6 +77,7 @@ func NewCharacters() *Characters {
 					c.Stamina = 255
 					c.Speed = 255
 					c.Magic = 255
+					editors.CharacterEdited(c)
 				}
 			}),
 			widget.NewButton("Heal All", func() {
@@ -84,6 +85,7 @@ func NewCharacters() *Characters {
 					c := pr.GetCharacter(name)
 					c.HP.Current = c.HP.Max
 					c.MP.Current = c.MP.Max
+					editors.CharacterEdited(c)
 				}
 			}),
 			widget.NewButton("Reset All", func() {
@@ -99,6 +101,7 @@ func NewCharacters() *Characters {
 					c.Stamina = 0
 					c.Speed = 0
 					c.Magic = 0
+					editors.CharacterEdited(c)
 				}
 			}),
 		),
@@ -84,7 +84,7 @@ func (sd *ShareDialog) Show() {
 
 	// Code input
 	codeEntry := widget.NewEntry()
-	codeEntry.SetPlaceHolder("TERRA-BLD-XXXXX or PTY-XXXX")
+	codeEntry.SetPlaceHolder("FF6-B58-...")
 	content.Add(codeEntry)
 
 	// Import button
@@ -113,22 +113,22 @@ func (sd *ShareDialog) Show() {
 	d.Show()
 }
 
-// generateAndDisplay generates a share code and displays it
-func (sd *ShareDialog) generateAndDisplay(displayContainer *fyne.Container) {
+// generate encodes the dialog's build in the given alphabet
+func (sd *ShareDialog) generate(enc share.Encoding) (string, error) {
 	generator := share.NewCodeGenerator()
-
-	var code string
-	var err error
+	generator.SetEncoding(enc)
 
 	if sd.shareType == "character" && sd.charBuild != nil {
-		code, err = generator.GenerateCharacterCode(sd.charBuild, 0)
+		return generator.GenerateCharacterCode(sd.charBuild, 0)
 	} else if sd.shareType == "party" && sd.partyBuild != nil {
-		code, err = generator.GeneratePartyCode(sd.partyBuild)
-	} else {
-		dialog.ShowError(fmt.Errorf("no valid build to share"), sd.window)
-		return
+		return generator.GeneratePartyCode(sd.partyBuild)
 	}
+	return "", fmt.Errorf("no valid build to share")
+}
 
+// generateAndDisplay generates a share code and displays it
+func (sd *ShareDialog) generateAndDisplay(displayContainer *fyne.Container) {
+	code, err := sd.generate(share.EncodingBase58)
 	if err != nil {
 		dialog.ShowError(fmt.Errorf("failed to generate share code: %w", err), sd.window)
 		return
@@ -147,7 +147,12 @@ func (sd *ShareDialog) generateAndDisplay(displayContainer *fyne.Container) {
 	})
 
 	qrBtn := widget.NewButton("Show QR Code", func() {
-		sd.showQRCode(code)
+		qrCode, err := sd.generate(share.EncodingQR)
+		if err != nil {
+			dialog.ShowError(err, sd.window)
+			return
+		}
+		sd.showQRCode(qrCode)
 	})
 
 	displayContainer.Add(widget.NewLabel("Your share code:"))
@@ -173,8 +178,8 @@ func (sd *ShareDialog) generateAndDisplay(displayContainer *fyne.Container) {
 func (sd *ShareDialog) importBuild(code string) {
 	generator := share.NewCodeGenerator()
 
-	// Try to determine code type
-	if sd.shareType == "party" || (len(code) > 3 && code[0:3] == "PTY") {
+	// The payload records whether it holds a party
+	if sd.shareType == "party" || share.IsPartyCode(code) {
 		// Import party
 		partyBuild, err := generator.DecodePartyCode(code)
 		if err != nil {
@@ -200,7 +205,8 @@ func (sd *ShareDialog) importBuild(code string) {
 	}
 }
 
-// showQRCode shows a QR code for the share code
+// showQRCode shows a QR code for the share code. The code uses the QR
+// alphabet, which fits the scanner-friendly alphanumeric mode.
 func (sd *ShareDialog) showQRCode(code string) {
 	// Note: Full QR code generation would require an external library
 	// For now, show a dialog with the code in large text
@@ -3,13 +3,20 @@ package ui
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"ffvi_editor/achievements"
 	"ffvi_editor/cloud"
 	"ffvi_editor/global"
+	"ffvi_editor/io/backup"
 	"ffvi_editor/io/config"
+	"ffvi_editor/io/watch"
+	"ffvi_editor/models"
+	pri "ffvi_editor/models/pr"
 	"ffvi_editor/plugins"
+	"ffvi_editor/scripting"
+	"ffvi_editor/ui/forms/editors"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
@@ -102,7 +109,7 @@ func (g *gui) performAutoSave() {
 
 	// Create auto-save filename
 	autoSavePath := global.PWD + "/autosave.json"
-	if err := g.pr.Save(0, autoSavePath, 0); err != nil {
+	if err := pri.WithCurrent(func() error { return g.pr.Save(0, autoSavePath, 0) }); err != nil {
 		// Silently log error - don't disturb user with dialogs for auto-save
 		fmt.Printf("Auto-save failed: %v\n", err)
 	} else {
@@ -216,6 +223,15 @@ func (g *gui) initializePlugins() {
 	})
 
 	g.pluginManager = plugins.NewManager(global.PWD+"/plugins", api)
+	g.pluginManager.SetRunner(scripting.NewPluginRunner(g.pluginManager.GetSandboxManager()))
+	editors.OnCharacterEdited = g.notifyCharacterEdited
+	editors.OnInventoryChanged = g.notifyInventoryChanged
+	if _, err := g.pluginManager.OpenEventStore(); err != nil {
+		fmt.Printf("Warning: plugin analytics and audit events will not be kept: %v\n", err)
+	}
+	if _, err := g.pluginManager.OpenAuditChain(filepath.Join(global.PWD, "audit-keys")); err != nil {
+		fmt.Printf("Warning: plugin audit log will not be tamper-evident: %v\n", err)
+	}
 
 	// Start the plugin manager
 	ctx := context.Background()
@@ -223,14 +239,130 @@ func (g *gui) initializePlugins() {
 		fmt.Printf("Failed to start plugin manager: %v\n", err)
 		return
 	}
+	g.addPluginMenus()
+	g.pluginManager.SetChangeHandler(func() { fyne.Do(g.addPluginMenus) })
 
 	fmt.Println("Plugins initialized")
 }
 
+// toggleSaveWatch starts or stops watching the save directory for writes
+// made by the game
+func (g *gui) toggleSaveWatch() {
+	if g.saveWatcher != nil {
+		_ = g.saveWatcher.Stop()
+		g.saveWatcher = nil
+		g.setWatchSavesChecked(false)
+		return
+	}
+
+	s := g.settingsManager.Get()
+	dir := config.SaveDir()
+	if dir == "" {
+		dialog.ShowInformation("Watch Save Directory", "Open a save first so the editor knows which directory to watch.", g.window)
+		return
+	}
+
+	backupDir := filepath.Join(global.PWD, "backups")
+	keep := 10
+	if s != nil {
+		if s.BackupLocation != "" {
+			backupDir = s.BackupLocation
+		}
+		if s.BackupsToKeep > 0 {
+			keep = s.BackupsToKeep
+		}
+	}
+	bm, err := backup.NewManager(backupDir, keep)
+	if err != nil {
+		dialog.ShowError(err, g.window)
+		return
+	}
+
+	saveType := global.PC
+	if config.EnablePlayStation() {
+		saveType = global.PS
+	}
+
+	opts := watch.Options{
+		Dir:      dir,
+		SaveType: saveType,
+		Backups:  bm,
+		OnEvent: func(e watch.Event) {
+			global.Log("[Watch] %s", e)
+			g.app.SendNotification(fyne.NewNotification("Save written", e.String()))
+		},
+	}
+	if s != nil && s.WatchRecipe != "" {
+		if opts.Recipe, err = watch.RecipeByName(s.WatchRecipe); err != nil {
+			dialog.ShowError(err, g.window)
+			return
+		}
+	}
+	if g.pluginManager != nil {
+		opts.Hook = func(ctx context.Context, path string) error {
+			return g.pluginManager.CallHook(ctx, plugins.HookSaveOpen, path)
+		}
+	}
+
+	w, err := watch.New(opts)
+	if err == nil {
+		err = w.Start(context.Background())
+	}
+	if err != nil {
+		dialog.ShowError(err, g.window)
+		return
+	}
+
+	g.saveWatcher = w
+	g.setWatchSavesChecked(true)
+}
+
+// setWatchSavesChecked updates the Watch Save Directory checkmark and
+// redraws the menu so it shows
+func (g *gui) setWatchSavesChecked(checked bool) {
+	g.watchSaves.Checked = checked
+	if mainMenu := g.window.MainMenu(); mainMenu != nil {
+		mainMenu.Refresh()
+	}
+}
+
+// notifyPlugins runs a hook taking a save path or view name in every
+// plugin, which also publishes the matching editor event on the plugin
+// event bus
+func (g *gui) notifyPlugins(hook plugins.HookType, arg string) {
+	if g.pluginManager == nil {
+		return
+	}
+	if err := g.pluginManager.CallHook(context.Background(), hook, arg); err != nil {
+		global.Log("[Plugins] %s hook failed: %v", hook, err)
+	}
+}
+
+// notifyCharacterEdited runs the character edit hook for an edit made in
+// the editors, which publishes character.edited
+func (g *gui) notifyCharacterEdited(c *models.Character) {
+	if err := g.pluginManager.CallHook(context.Background(), plugins.HookCharEdit, c.ID, c.Name); err != nil {
+		global.Log("[Plugins] %s hook failed: %v", plugins.HookCharEdit, err)
+	}
+}
+
+// notifyInventoryChanged publishes inventory.changed for an edit made in
+// the editors
+func (g *gui) notifyInventoryChanged() {
+	payload := map[string]interface{}{"by": plugins.EditorSource}
+	if err := g.pluginManager.Emit(context.Background(), plugins.TopicInventoryChanged, payload); err != nil {
+		global.Log("[Plugins] %s event failed: %v", plugins.TopicInventoryChanged, err)
+	}
+}
+
 // cleanup performs cleanup before application exit
 func (g *gui) cleanup() {
 	g.stopAutoSaveTimer()
 
+	if g.saveWatcher != nil {
+		_ = g.saveWatcher.Stop()
+	}
+
 	if g.cloudManager != nil {
 		// Perform final cloud sync if needed
 	}
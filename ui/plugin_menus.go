@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"ffvi_editor/plugins"
+	"ffvi_editor/ui/forms"
+	"ffvi_editor/ui/shortcuts"
+)
+
+// pluginMenus remembers what addPluginMenus put in the main menu and bound
+// to the window so it can be taken out again when plugins change
+type pluginMenus struct {
+	menus     []*fyne.Menu                    // menus created for plugins
+	items     map[*fyne.Menu][]*fyne.MenuItem // items added to existing menus
+	shortcuts []fyne.Shortcut                 // command shortcuts bound to the window
+}
+
+// addPluginMenus adds the menu items and panels loaded plugins contribute
+// to the main menu, plus a command palette holding their commands, binds
+// their command shortcuts, then runs the menu hook for each menu that
+// changed. Menus a plugin names that
+// the window lacks are created after the existing ones. Items from an
+// earlier call are removed first, so it is also how menus are rebuilt
+// after plugins load or unload; it must run on the GUI goroutine.
+func (g *gui) addPluginMenus() {
+	mainMenu := g.window.MainMenu()
+	if mainMenu == nil {
+		return
+	}
+	g.removePluginMenus(mainMenu)
+	contributions := g.pluginManager.Contributions()
+	if len(contributions) == 0 {
+		mainMenu.Refresh()
+		return
+	}
+
+	g.pluginMenus.items = make(map[*fyne.Menu][]*fyne.MenuItem)
+	var changed []string
+	add := func(name string, items ...*fyne.MenuItem) {
+		label := strings.ToUpper(name[:1]) + name[1:]
+		var menu *fyne.Menu
+		for _, m := range mainMenu.Items {
+			if strings.EqualFold(m.Label, label) {
+				menu = m
+				break
+			}
+		}
+		if menu == nil {
+			menu = fyne.NewMenu(label)
+			mainMenu.Items = append(mainMenu.Items, menu)
+			g.pluginMenus.menus = append(g.pluginMenus.menus, menu)
+		}
+		if _, seen := g.pluginMenus.items[menu]; !seen {
+			changed = append(changed, name)
+		}
+		menu.Items = append(menu.Items, items...)
+		g.pluginMenus.items[menu] = append(g.pluginMenus.items[menu], items...)
+	}
+
+	taken := make(map[string]bool)
+	for _, sc := range shortcuts.NewKeyMap().GetAllShortcuts() {
+		taken[(&desktop.CustomShortcut{KeyName: sc.Keys, Modifier: sc.Modifiers}).ShortcutName()] = true
+	}
+	for _, pc := range contributions {
+		pluginID := pc.PluginID
+		for _, cmd := range pc.Commands {
+			g.bindPluginShortcut(pluginID, cmd, taken)
+		}
+		for _, item := range pc.Menus {
+			commandID := item.Command
+			label := item.Label
+			if label == "" {
+				label = pc.Command(commandID).Title
+			}
+			add(item.Menu, fyne.NewMenuItem(label, func() {
+				if err := g.pluginManager.InvokeCommand(context.Background(), pluginID, commandID); err != nil {
+					dialog.ShowError(err, g.window)
+				}
+			}))
+		}
+		for _, panel := range pc.Panels {
+			panel := panel
+			add("plugins", fyne.NewMenuItem(panel.Title, func() {
+				g.showPluginPanel(pluginID, panel.ID)
+			}))
+		}
+	}
+
+	add("plugins", fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Command Palette", func() {
+		palette := forms.NewCommandPalette(g.window, nil)
+		palette.AddPluginCommands(g.pluginManager)
+		palette.Show()
+	}))
+	mainMenu.Refresh()
+
+	for _, name := range changed {
+		g.notifyPlugins(plugins.HookMenuAdd, name)
+	}
+}
+
+// bindPluginShortcut binds a plugin command's shortcut to the window unless
+// it lacks Ctrl, Alt or Super, which would swallow typing, or is taken
+func (g *gui) bindPluginShortcut(pluginID string, cmd plugins.CommandContribution, taken map[string]bool) {
+	if cmd.Shortcut == "" {
+		return
+	}
+	key, modifiers, err := shortcuts.ParseKeyCombo(cmd.Shortcut)
+	if err != nil || modifiers&(fyne.KeyModifierControl|fyne.KeyModifierAlt|fyne.KeyModifierSuper) == 0 {
+		return
+	}
+	sc := &desktop.CustomShortcut{KeyName: key, Modifier: modifiers}
+	if taken[sc.ShortcutName()] {
+		return
+	}
+	taken[sc.ShortcutName()] = true
+	commandID := cmd.ID
+	g.window.Canvas().AddShortcut(sc, func(fyne.Shortcut) {
+		if err := g.pluginManager.InvokeCommand(context.Background(), pluginID, commandID); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	})
+	g.pluginMenus.shortcuts = append(g.pluginMenus.shortcuts, sc)
+}
+
+// removePluginMenus takes out everything an earlier addPluginMenus added
+func (g *gui) removePluginMenus(mainMenu *fyne.MainMenu) {
+	for _, sc := range g.pluginMenus.shortcuts {
+		g.window.Canvas().RemoveShortcut(sc)
+	}
+	for menu, added := range g.pluginMenus.items {
+		remove := make(map[*fyne.MenuItem]bool, len(added))
+		for _, item := range added {
+			remove[item] = true
+		}
+		kept := menu.Items[:0]
+		for _, item := range menu.Items {
+			if !remove[item] {
+				kept = append(kept, item)
+			}
+		}
+		menu.Items = kept
+	}
+	if len(g.pluginMenus.menus) > 0 {
+		created := make(map[*fyne.Menu]bool, len(g.pluginMenus.menus))
+		for _, menu := range g.pluginMenus.menus {
+			created[menu] = true
+		}
+		kept := mainMenu.Items[:0]
+		for _, menu := range mainMenu.Items {
+			if !created[menu] {
+				kept = append(kept, menu)
+			}
+		}
+		mainMenu.Items = kept
+	}
+	g.pluginMenus = pluginMenus{}
+}
+
+// showPluginPanel opens a plugin's panel in its own window beside the editor
+func (g *gui) showPluginPanel(pluginID, panelID string) {
+	for _, pc := range g.pluginManager.Contributions() {
+		if pc.PluginID != pluginID {
+			continue
+		}
+		for _, panel := range pc.Panels {
+			if panel.ID != panelID {
+				continue
+			}
+			w := g.app.NewWindow(panel.Title)
+			w.SetContent(forms.NewPluginPanel(g.pluginManager, pluginID, panel, w))
+			w.Resize(fyne.NewSize(320, 480))
+			w.Show()
+			return
+		}
+	}
+}
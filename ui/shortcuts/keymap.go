@@ -237,6 +237,35 @@ func (km *KeyMap) FormatKeyCombo(shortcut *Shortcut) string {
 	return strings.Join(parts, "+")
 }
 
+// ParseKeyCombo reads a key combination written the way FormatKeyCombo
+// writes one, e.g. "Ctrl+Shift+K". Modifier names are case-insensitive.
+func ParseKeyCombo(combo string) (fyne.KeyName, fyne.KeyModifier, error) {
+	parts := strings.Split(combo, "+")
+	var modifiers fyne.KeyModifier
+	for _, part := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "ctrl", "control":
+			modifiers |= fyne.KeyModifierControl
+		case "shift":
+			modifiers |= fyne.KeyModifierShift
+		case "alt":
+			modifiers |= fyne.KeyModifierAlt
+		case "super", "cmd":
+			modifiers |= fyne.KeyModifierSuper
+		default:
+			return "", 0, fmt.Errorf("unknown modifier %q in %q", part, combo)
+		}
+	}
+	key := strings.TrimSpace(parts[len(parts)-1])
+	if key == "" {
+		return "", 0, fmt.Errorf("no key in %q", combo)
+	}
+	if len(key) == 1 {
+		key = strings.ToUpper(key)
+	}
+	return fyne.KeyName(key), modifiers, nil
+}
+
 // ListAllShortcuts returns a formatted list of all shortcuts
 func (km *KeyMap) ListAllShortcuts() string {
 	var output strings.Builder
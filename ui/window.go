@@ -15,6 +15,8 @@ import (
 	"ffvi_editor/global"
 	"ffvi_editor/io/config"
 	"ffvi_editor/io/pr"
+	"ffvi_editor/io/watch"
+	pri "ffvi_editor/models/pr"
 	"ffvi_editor/plugins"
 	"ffvi_editor/settings"
 	"ffvi_editor/ui/forms"
@@ -50,6 +52,7 @@ type (
 		loadState          *fyne.MenuItem
 		quickSaveStates    []*fyne.MenuItem
 		quickLoadStates    []*fyne.MenuItem
+		watchSaves         *fyne.MenuItem
 		prev               fyne.CanvasObject
 		pr                 *pr.PR
 		background         *fyne.Container
@@ -58,6 +61,8 @@ type (
 		stopAutoSave   chan bool
 		cloudManager   *cloud.Manager
 		pluginManager  *plugins.Manager
+		pluginMenus    pluginMenus
+		saveWatcher    *watch.Watcher
 	}
 	MenuItem interface {
 		Item() *fyne.MenuItem
@@ -153,6 +158,9 @@ func New() Gui {
 	x, y := config.WindowSize()
 	g.window.Resize(fyne.NewSize(x, y))
 	g.window.SetFixedSize(false)
+	g.watchSaves = fyne.NewMenuItem("Watch Save Directory", func() {
+		g.toggleSaveWatch()
+	})
 	quickStatesMenu := fyne.NewMenuItem("Quick States", func() {})
 	quickStatesMenu.ChildMenu = fyne.NewMenu("", append(g.quickSaveStates, g.quickLoadStates...)...)
 	g.window.SetMainMenu(fyne.NewMainMenu(
@@ -165,6 +173,8 @@ func New() Gui {
 			g.loadState,
 			fyne.NewMenuItemSeparator(),
 			quickStatesMenu,
+			fyne.NewMenuItemSeparator(),
+			g.watchSaves,
 		)))
 	return g
 }
@@ -213,7 +223,8 @@ func (g *gui) Load() {
 			loadPath := filepath.Join(dir, file)
 			global.Log("[Load] Loading file: %s", loadPath)
 			fmt.Printf("[DEBUG Load] Loading file: %s\n", loadPath)
-			if err := p.Load(loadPath, saveType); err != nil {
+			// Waits while the save watcher processes a write, see pri.WithCurrent
+			if err := pri.WithCurrent(func() error { return p.Load(loadPath, saveType) }); err != nil {
 				global.Log("[Load] ERROR loading file: %v", err)
 				fmt.Printf("[DEBUG Load] ERROR loading file: %v\n", err)
 				if g.prev != nil {
@@ -246,6 +257,8 @@ func (g *gui) Load() {
 				global.Log("[Load] Editor added, refreshing...")
 				fmt.Println("[DEBUG Load] Editor added, refreshing...")
 				g.window.Content().Refresh()
+				g.notifyPlugins(plugins.HookSaveOpen, loadPath)
+				g.notifyPlugins(plugins.HookUIRender, "editor")
 				global.Log("[Load] Load complete")
 				fmt.Println("[DEBUG Load] Load complete")
 			}
@@ -275,7 +288,8 @@ func (g *gui) Save() {
 			}()
 			// Save file
 			config.SetSaveDir(dir)
-			if err := g.pr.Save(slot, filepath.Join(dir, file), saveType); err != nil {
+			savePath := filepath.Join(dir, file)
+			if err := pri.WithCurrent(func() error { return g.pr.Save(slot, savePath, saveType) }); err != nil {
 				if g.prev != nil {
 					g.canvas.RemoveAll()
 					g.canvas.Add(g.prev)
@@ -284,6 +298,7 @@ func (g *gui) Save() {
 				dialog.NewError(err, g.window).Show()
 			} else {
 				// Success
+				g.notifyPlugins(plugins.HookSaveSave, savePath)
 				if g.prev != nil {
 					g.canvas.RemoveAll()
 					g.canvas.Add(g.prev)